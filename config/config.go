@@ -2,12 +2,15 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	uuid "github.com/satori/go.uuid"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
+	"runtime"
 	"strings"
 	"time"
 
@@ -26,16 +29,32 @@ const (
 	SQLDialectPostgres = "postgres"
 	SQLDialectSqlite   = "sqlite3"
 
-	KeyLatestTotalTime  = "latest_total_time"
-	KeyLatestTotalUsers = "latest_total_users"
-	KeyLastImportImport = "last_import"
+	KeyLatestTotalTime   = "latest_total_time"
+	KeyLatestTotalUsers  = "latest_total_users"
+	KeyLastImportImport  = "last_import"
+	KeyMaintenance       = "maintenance"
+	KeyInstanceStats     = "instance_stats"
+	KeyMigration         = "migration"
+	KeyCodeStatsExport   = "codestats_export"
+	KeyLatestAggregation = "latest_aggregation"
+	KeyLanguageOverrides = "language_overrides"
+	KeyDemoUser          = "demo_user"
+	KeyImport            = "import"
 
 	SimpleDateFormat     = "2006-01-02"
 	SimpleDateTimeFormat = "2006-01-02 15:04:05"
 
 	ErrUnauthorized        = "401 unauthorized"
 	ErrBadRequest          = "400 bad request"
+	ErrForbidden           = "403 forbidden"
 	ErrInternalServerError = "500 internal server error"
+
+	HeaderRequestId   = "X-Request-Id"
+	HeaderApiVersion  = "X-Api-Version"
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+
+	ApiVersion = "1" // version of the native api, to be incremented on breaking changes, while the wakatime-compatible api follows wakatime's versioning
 )
 
 const (
@@ -46,16 +65,49 @@ const (
 	WakatimeApiHeartbeatsBulkUrl = "/users/current/heartbeats.bulk"
 	WakatimeApiUserAgentsUrl     = "/users/current/user_agents"
 	WakatimeApiMachineNamesUrl   = "/users/current/machine_names"
+	WakatimeApiSummariesUrl      = "/users/current/summaries"
+)
+
+const (
+	CodeStatsUrl          = "https://codestats.net"
+	CodeStatsGraphUrl     = "/profile-graph" // graphql api serving public profiles, including xp per day and language
+	CodeStatsApiPulsesUrl = "/api/my/pulses"
 )
 
 const (
 	MailProviderSmtp      = "smtp"
 	MailProviderMailWhale = "mailwhale"
+	MailProviderSendGrid  = "sendgrid"
+	MailProviderMailgun   = "mailgun"
+	MailProviderSES       = "ses"
 )
 
 var emailProviders = []string{
 	MailProviderSmtp,
 	MailProviderMailWhale,
+	MailProviderSendGrid,
+	MailProviderMailgun,
+	MailProviderSES,
+}
+
+const (
+	StorageProviderLocal = "local"
+	StorageProviderS3    = "s3"
+)
+
+var storageProviders = []string{
+	StorageProviderLocal,
+	StorageProviderS3,
+}
+
+const (
+	StorageQuotaPolicyReject = "reject"
+	StorageQuotaPolicyPrune  = "prune"
+)
+
+var storageQuotaPolicies = []string{
+	StorageQuotaPolicyReject,
+	StorageQuotaPolicyPrune,
 }
 
 var cfg *Config
@@ -63,26 +115,67 @@ var cFlag = flag.String("config", defaultConfigPath, "config file location")
 var env string
 
 type appConfig struct {
-	AggregationTime   string                       `yaml:"aggregation_time" default:"02:15" env:"WAKAPI_AGGREGATION_TIME"`
-	ReportTimeWeekly  string                       `yaml:"report_time_weekly" default:"fri,18:00" env:"WAKAPI_REPORT_TIME_WEEKLY"`
-	ImportBackoffMin  int                          `yaml:"import_backoff_min" default:"5" env:"WAKAPI_IMPORT_BACKOFF_MIN"`
-	ImportBatchSize   int                          `yaml:"import_batch_size" default:"50" env:"WAKAPI_IMPORT_BATCH_SIZE"`
-	InactiveDays      int                          `yaml:"inactive_days" default:"7" env:"WAKAPI_INACTIVE_DAYS"`
-	CountCacheTTLMin  int                          `yaml:"count_cache_ttl_min" default:"30" env:"WAKAPI_COUNT_CACHE_TTL_MIN"`
-	AvatarURLTemplate string                       `yaml:"avatar_url_template" default:"api/avatar/{username_hash}.svg"`
-	CustomLanguages   map[string]string            `yaml:"custom_languages"`
-	Colors            map[string]map[string]string `yaml:"-"`
+	AggregationTime        string                       `yaml:"aggregation_time" default:"02:15" env:"WAKAPI_AGGREGATION_TIME"`
+	AggregationWorkers     int                          `yaml:"aggregation_workers" default:"0" env:"WAKAPI_AGGREGATION_WORKERS"`
+	ReportTimeWeekly       string                       `yaml:"report_time_weekly" default:"fri,18:00" env:"WAKAPI_REPORT_TIME_WEEKLY"`
+	ImportBackoffMin       int                          `yaml:"import_backoff_min" default:"5" env:"WAKAPI_IMPORT_BACKOFF_MIN"`
+	ImportBatchSize        int                          `yaml:"import_batch_size" default:"50" env:"WAKAPI_IMPORT_BATCH_SIZE"`
+	InactiveDays           int                          `yaml:"inactive_days" default:"7" env:"WAKAPI_INACTIVE_DAYS"`
+	CountCacheTTLMin       int                          `yaml:"count_cache_ttl_min" default:"30" env:"WAKAPI_COUNT_CACHE_TTL_MIN"`
+	HeartbeatsMaxPastDays  int                          `yaml:"heartbeats_max_past_days" default:"0" env:"WAKAPI_HEARTBEATS_MAX_PAST_DAYS"`
+	HeartbeatsMaxFutureMin int                          `yaml:"heartbeats_max_future_min" default:"0" env:"WAKAPI_HEARTBEATS_MAX_FUTURE_MIN"`
+	IdempotencyWindowMin   int                          `yaml:"idempotency_window_min" default:"60" env:"WAKAPI_IDEMPOTENCY_WINDOW_MIN"`
+	StatsCacheTTLMin       int                          `yaml:"stats_cache_ttl_min" default:"10" env:"WAKAPI_STATS_CACHE_TTL_MIN"`               // -1 to disable
+	UndoWindowHours        int                          `yaml:"undo_window_hours" default:"24" env:"WAKAPI_UNDO_WINDOW_HOURS"`                   // -1 to disable
+	HeartbeatsQuotaPerHour int                          `yaml:"heartbeats_quota_per_hour" default:"0" env:"WAKAPI_HEARTBEATS_QUOTA_PER_HOUR"`    // per user, 0 = unlimited
+	ApiRateLimitPerMin     int                          `yaml:"api_rate_limit_per_min" default:"0" env:"WAKAPI_API_RATE_LIMIT_PER_MIN"`          // requests per client, 0 = unlimited
+	HeartbeatsMaxBodyKb    int                          `yaml:"heartbeats_max_body_kb" default:"10240" env:"WAKAPI_HEARTBEATS_MAX_BODY_KB"`      // size of heartbeat request bodies, 0 = unlimited
+	HeartbeatsMaxBatchSize int                          `yaml:"heartbeats_max_batch_size" default:"1000" env:"WAKAPI_HEARTBEATS_MAX_BATCH_SIZE"` // heartbeats per json array, 0 = unlimited
+	HeartbeatsMaxPerReq    int                          `yaml:"heartbeats_max_per_request" default:"0" env:"WAKAPI_HEARTBEATS_MAX_PER_REQUEST"`  // heartbeats per request including streams, 0 = unlimited
+	HeartbeatsPermissive   bool                         `yaml:"heartbeats_permissive" default:"false" env:"WAKAPI_HEARTBEATS_PERMISSIVE"`        // skip strict validation of incoming heartbeats
+	QuarantineAnomalies    bool                         `yaml:"quarantine_anomalies" default:"true" env:"WAKAPI_QUARANTINE_ANOMALIES"`           // flag suspicious heartbeats for admins to review
+	StorageQuotaHeartbeats int64                        `yaml:"storage_quota_heartbeats" default:"0" env:"WAKAPI_STORAGE_QUOTA_HEARTBEATS"`      // heartbeats stored per user, 0 = unlimited
+	StorageQuotaPolicy     string                       `yaml:"storage_quota_policy" default:"reject" env:"WAKAPI_STORAGE_QUOTA_POLICY"`         // what to do once a user exceeds their storage quota
+	PublicInstanceStats    bool                         `yaml:"public_instance_stats" default:"false" env:"WAKAPI_PUBLIC_INSTANCE_STATS"`
+	SummaryMaxItems        int                          `yaml:"summary_max_items" default:"0" env:"WAKAPI_SUMMARY_MAX_ITEMS"` // per type, 0 = unlimited
+	FiscalYearStart        int                          `yaml:"fiscal_year_start" default:"1" env:"WAKAPI_FISCAL_YEAR_START"` // month (1 - 12) in which the fiscal year starts
+	LeaderboardEnabled     bool                         `yaml:"leaderboard_enabled" default:"false" env:"WAKAPI_LEADERBOARD_ENABLED"`
+	LeaderboardSchedule    string                       `yaml:"leaderboard_schedule" default:"0 6,18 * * *" env:"WAKAPI_LEADERBOARD_SCHEDULE"` // cron expression
+	DemoMode               bool                         `yaml:"demo_mode" default:"false" env:"WAKAPI_DEMO_MODE"`                              // publicly accessible, read-only user with generated data
+	DemoResetHours         int                          `yaml:"demo_reset_hours" default:"24" env:"WAKAPI_DEMO_RESET_HOURS"`                   // interval in which the demo user is recreated
+	HeartbeatScript        string                       `yaml:"heartbeat_script" default:"" env:"WAKAPI_HEARTBEAT_SCRIPT"`
+	HeartbeatScriptTimeout int                          `yaml:"heartbeat_script_timeout_ms" default:"50" env:"WAKAPI_HEARTBEAT_SCRIPT_TIMEOUT_MS"`
+	UserHeartbeatScripts   bool                         `yaml:"user_heartbeat_scripts" default:"false" env:"WAKAPI_USER_HEARTBEAT_SCRIPTS"`
+	AvatarURLTemplate      string                       `yaml:"avatar_url_template" default:"api/avatar/{username_hash}.svg"`
+	AvatarGravatar         bool                         `yaml:"avatar_gravatar" default:"false" env:"WAKAPI_AVATAR_GRAVATAR"`
+	AvatarUploads          bool                         `yaml:"avatar_uploads" default:"false" env:"WAKAPI_AVATAR_UPLOADS"`
+	TelegramBotToken       string                       `yaml:"telegram_bot_token" env:"WAKAPI_TELEGRAM_BOT_TOKEN"` // bot to send notifications via telegram, channel is unavailable if empty
+	CustomLanguages        map[string]string            `yaml:"custom_languages"`
+	Colors                 map[string]map[string]string `yaml:"-"`
 }
 
 type securityConfig struct {
 	AllowSignup   bool `yaml:"allow_signup" default:"true" env:"WAKAPI_ALLOW_SIGNUP"`
 	ExposeMetrics bool `yaml:"expose_metrics" default:"false" env:"WAKAPI_EXPOSE_METRICS"`
+	ExposeDebug   bool `yaml:"expose_debug" default:"false" env:"WAKAPI_EXPOSE_DEBUG"` // pprof and runtime stats for admins
 	EnableProxy   bool `yaml:"enable_proxy" default:"false" env:"WAKAPI_ENABLE_PROXY"` // only intended for production instance at wakapi.dev
 	// this is actually a pepper (https://en.wikipedia.org/wiki/Pepper_(cryptography))
 	PasswordSalt    string                     `yaml:"password_salt" default:"" env:"WAKAPI_PASSWORD_SALT"`
 	InsecureCookies bool                       `yaml:"insecure_cookies" default:"false" env:"WAKAPI_INSECURE_COOKIES"`
 	CookieMaxAgeSec int                        `yaml:"cookie_max_age" default:"172800" env:"WAKAPI_COOKIE_MAX_AGE"`
 	SecureCookie    *securecookie.SecureCookie `yaml:"-"`
+	ScimToken       string                     `yaml:"scim_token" default:"" env:"WAKAPI_SCIM_TOKEN"`           // bearer token for identity providers to provision users via scim, endpoint is disabled if empty
+	TrustedProxies  string                     `yaml:"trusted_proxies" default:"" env:"WAKAPI_TRUSTED_PROXIES"` // comma-separated ip addresses or cidr ranges of reverse proxies, whose x-real-ip and x-forwarded-for headers are trusted
+	Headers         SecurityHeadersConfig      `yaml:"headers"`
+}
+
+// SecurityHeadersConfig holds the security headers sent along with every response, a value of '-' omits the respective header
+type SecurityHeadersConfig struct {
+	ContentSecurityPolicy string `yaml:"content_security_policy" default:"default-src 'self' 'unsafe-inline' 'unsafe-eval'; img-src 'self' https: data:; form-action 'self'; frame-ancestors 'none'; block-all-mixed-content;" env:"WAKAPI_SECURITY_CONTENT_SECURITY_POLICY"`
+	FrameOptions          string `yaml:"frame_options" default:"DENY" env:"WAKAPI_SECURITY_FRAME_OPTIONS"`
+	ReferrerPolicy        string `yaml:"referrer_policy" default:"strict-origin-when-cross-origin" env:"WAKAPI_SECURITY_REFERRER_POLICY"`
+	HstsMaxAgeSec         int    `yaml:"hsts_max_age_sec" default:"31536000" env:"WAKAPI_SECURITY_HSTS_MAX_AGE_SEC"`    // only sent along with https responses, -1 to disable
+	EmbedFrameAncestors   string `yaml:"embed_frame_ancestors" default:"*" env:"WAKAPI_SECURITY_EMBED_FRAME_ANCESTORS"` // origins allowed to embed badges in frames
 }
 
 type dbConfig struct {
@@ -96,6 +189,8 @@ type dbConfig struct {
 	Type                    string `yaml:"dialect" default:"sqlite3" env:"WAKAPI_DB_TYPE"`
 	MaxConn                 uint   `yaml:"max_conn" default:"2" env:"WAKAPI_DB_MAX_CONNECTIONS"`
 	Ssl                     bool   `default:"false" env:"WAKAPI_DB_SSL"`
+	SqliteWal               bool   `yaml:"sqlite_wal" default:"true" env:"WAKAPI_DB_SQLITE_WAL"`                         // write-ahead logging, so that reads don't block writes
+	SqliteBusyTimeoutMs     int    `yaml:"sqlite_busy_timeout_ms" default:"5000" env:"WAKAPI_DB_SQLITE_BUSY_TIMEOUT_MS"` // how long to wait for a locked database before failing
 	AutoMigrateFailSilently bool   `yaml:"automigrate_fail_silently" default:"false" env:"WAKAPI_DB_AUTOMIGRATE_FAIL_SILENTLY"`
 }
 
@@ -123,6 +218,9 @@ type mailConfig struct {
 	Provider  string              `env:"WAKAPI_MAIL_PROVIDER" default:"smtp"`
 	MailWhale MailwhaleMailConfig `yaml:"mailwhale"`
 	Smtp      SMTPMailConfig      `yaml:"smtp"`
+	SendGrid  SendGridMailConfig  `yaml:"sendgrid"`
+	Mailgun   MailgunMailConfig   `yaml:"mailgun"`
+	SES       SESMailConfig       `yaml:"ses"`
 	Sender    string              `env:"WAKAPI_MAIL_SENDER" yaml:"sender"`
 }
 
@@ -140,25 +238,78 @@ type SMTPMailConfig struct {
 	TLS      bool   `env:"WAKAPI_MAIL_SMTP_TLS"`
 }
 
+type SendGridMailConfig struct {
+	ApiKey string `yaml:"api_key" env:"WAKAPI_MAIL_SENDGRID_API_KEY"`
+}
+
+type MailgunMailConfig struct {
+	Url    string `default:"https://api.mailgun.net" env:"WAKAPI_MAIL_MAILGUN_URL"` // https://api.eu.mailgun.net for domains in the eu region
+	Domain string `env:"WAKAPI_MAIL_MAILGUN_DOMAIN"`
+	ApiKey string `yaml:"api_key" env:"WAKAPI_MAIL_MAILGUN_API_KEY"`
+}
+
+type SESMailConfig struct {
+	Region          string `default:"us-east-1" env:"WAKAPI_MAIL_SES_REGION"`
+	AccessKeyId     string `yaml:"access_key_id" env:"WAKAPI_MAIL_SES_ACCESS_KEY_ID"`
+	SecretAccessKey string `yaml:"secret_access_key" env:"WAKAPI_MAIL_SES_SECRET_ACCESS_KEY"`
+}
+
+type storageConfig struct {
+	Provider            string             `env:"WAKAPI_STORAGE_PROVIDER" default:"local"`
+	Local               LocalStorageConfig `yaml:"local"`
+	S3                  S3StorageConfig    `yaml:"s3"`
+	LinkExpirySec       int                `yaml:"link_expiry_sec" default:"3600" env:"WAKAPI_STORAGE_LINK_EXPIRY_SEC"`
+	Backups             bool               `default:"false" env:"WAKAPI_STORAGE_BACKUPS"`
+	BackupRetentionDays int                `yaml:"backup_retention_days" default:"7" env:"WAKAPI_STORAGE_BACKUP_RETENTION_DAYS"`
+}
+
+type LocalStorageConfig struct {
+	Path string `default:"data/storage" env:"WAKAPI_STORAGE_LOCAL_PATH"`
+}
+
+type S3StorageConfig struct {
+	Endpoint        string `env:"WAKAPI_STORAGE_S3_ENDPOINT"` // e.g. https://s3.eu-central-1.amazonaws.com or https://minio.example.org
+	Region          string `default:"us-east-1" env:"WAKAPI_STORAGE_S3_REGION"`
+	Bucket          string `env:"WAKAPI_STORAGE_S3_BUCKET"`
+	AccessKeyId     string `yaml:"access_key_id" env:"WAKAPI_STORAGE_S3_ACCESS_KEY_ID"`
+	SecretAccessKey string `yaml:"secret_access_key" env:"WAKAPI_STORAGE_S3_SECRET_ACCESS_KEY"`
+	PathStyle       bool   `yaml:"path_style" default:"true" env:"WAKAPI_STORAGE_S3_PATH_STYLE"` // address buckets as <endpoint>/<bucket> instead of <bucket>.<endpoint>
+}
+
+type integrationsConfig struct {
+	GoogleCalendar GoogleCalendarConfig `yaml:"google_calendar"`
+}
+
+type GoogleCalendarConfig struct {
+	ClientId      string `yaml:"client_id" env:"WAKAPI_GOOGLE_CALENDAR_CLIENT_ID"`
+	ClientSecret  string `yaml:"client_secret" env:"WAKAPI_GOOGLE_CALENDAR_CLIENT_SECRET"`
+	MinSessionMin int    `yaml:"min_session_min" default:"30" env:"WAKAPI_GOOGLE_CALENDAR_MIN_SESSION_MIN"`
+}
+
 type Config struct {
-	Env        string `default:"dev" env:"ENVIRONMENT"`
-	Version    string `yaml:"-"`
-	QuickStart bool   `yaml:"quick_start" env:"WAKAPI_QUICK_START"`
-	InstanceId string `yaml:"-"` // only temporary, changes between runs
-	App        appConfig
-	Security   securityConfig
-	Db         dbConfig
-	Server     serverConfig
-	Sentry     sentryConfig
-	Mail       mailConfig
+	Env            string `default:"dev" env:"ENVIRONMENT"`
+	Version        string `yaml:"-"`
+	QuickStart     bool   `yaml:"quick_start" env:"WAKAPI_QUICK_START"`
+	InstanceId     string `yaml:"-"` // only temporary, changes between runs
+	App            appConfig
+	Security       securityConfig
+	Db             dbConfig
+	Server         serverConfig
+	Sentry         sentryConfig
+	ErrorReporting errorReportingConfig `yaml:"error_reporting"`
+	Mail           mailConfig
+	Storage        storageConfig
+	Integrations   integrationsConfig
+	Proxy          proxyConfig
+	Theme          themeConfig
 }
 
 func (c *Config) CreateCookie(name, value string) *http.Cookie {
-	return c.createCookie(name, value, c.Server.BasePath, c.Security.CookieMaxAgeSec)
+	return c.createCookie(name, value, c.Server.GetCookiePath(), c.Security.CookieMaxAgeSec)
 }
 
 func (c *Config) GetClearCookie(name string) *http.Cookie {
-	return c.createCookie(name, "", c.Server.BasePath, -1)
+	return c.createCookie(name, "", c.Server.GetCookiePath(), -1)
 }
 
 func (c *Config) createCookie(name, value, path string, maxAge int) *http.Cookie {
@@ -209,9 +360,78 @@ func (c *Config) GetMigrationFunc(dbDialect string) models.MigrationFunc {
 			if err := db.AutoMigrate(&models.ProjectLabel{}); err != nil && !c.Db.AutoMigrateFailSilently {
 				return err
 			}
+			if err := db.AutoMigrate(&models.CalendarEvent{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.ProjectBudget{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.Goal{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.FilterSet{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.NotificationPreference{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.RelayTarget{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.RelayRule{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.DayOff{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.Achievement{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.ProjectRepo{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
 			if err := db.AutoMigrate(&models.Diagnostics{}); err != nil && !c.Db.AutoMigrateFailSilently {
 				return err
 			}
+			if err := db.AutoMigrate(&models.ManualTimeEntry{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.SummaryInvalidation{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.HeartbeatCount{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.JiraWorklog{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.ArchivedReport{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.Tombstone{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.TombstoneItem{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.RemoteAccount{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.LeaderboardItem{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.Duration{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.DurationDay{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.Quarantine{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
+			if err := db.AutoMigrate(&models.EmbedToken{}); err != nil && !c.Db.AutoMigrateFailSilently {
+				return err
+			}
 			return nil
 		}
 	}
@@ -225,6 +445,11 @@ func (c *appConfig) GetLanguageColors() map[string]string {
 	return cloneStringMap(c.Colors["languages"], true)
 }
 
+// GetLanguageColorsByName is like GetLanguageColors, but retains the languages' original names, e.g. 'JavaScript' instead of 'javascript'
+func (c *appConfig) GetLanguageColorsByName() map[string]string {
+	return cloneStringMap(c.Colors["languages"], false)
+}
+
 func (c *appConfig) GetEditorColors() map[string]string {
 	return cloneStringMap(c.Colors["editors"], true)
 }
@@ -233,6 +458,100 @@ func (c *appConfig) GetOSColors() map[string]string {
 	return cloneStringMap(c.Colors["operating_systems"], true)
 }
 
+// GetAggregationWorkers returns the number of users to generate summaries for concurrently, defaulting to the number of cpus.
+// With sqlite, which doesn't support concurrent writes, a single worker is used by default.
+func (c *Config) GetAggregationWorkers() int {
+	if c.App.AggregationWorkers > 0 {
+		return c.App.AggregationWorkers
+	}
+	if c.Db.Dialect == SQLDialectSqlite {
+		return 1
+	}
+	return runtime.NumCPU()
+}
+
+// GetLinkExpiry returns for how long download links to stored files remain valid
+func (c *storageConfig) GetLinkExpiry() time.Duration {
+	if c.LinkExpirySec <= 0 {
+		return 1 * time.Hour
+	}
+	return time.Duration(c.LinkExpirySec) * time.Second
+}
+
+// IsEnabled returns whether oauth client credentials for the google calendar integration are configured
+func (c *GoogleCalendarConfig) IsEnabled() bool {
+	return c.ClientId != "" && c.ClientSecret != ""
+}
+
+// GetMinSession returns the minimum length of a coding session to be written to google calendar
+func (c *GoogleCalendarConfig) GetMinSession() time.Duration {
+	if c.MinSessionMin <= 0 {
+		return 30 * time.Minute
+	}
+	return time.Duration(c.MinSessionMin) * time.Minute
+}
+
+// GetBackupRetention returns for how long database backups are kept in storage
+func (c *storageConfig) GetBackupRetention() time.Duration {
+	if c.BackupRetentionDays <= 0 {
+		return 7 * 24 * time.Hour
+	}
+	return time.Duration(c.BackupRetentionDays) * 24 * time.Hour
+}
+
+// GetHeartbeatScriptTimeout returns the maximum time a heartbeat script may run per heartbeat
+func (c *appConfig) GetHeartbeatScriptTimeout() time.Duration {
+	if c.HeartbeatScriptTimeout <= 0 {
+		return 50 * time.Millisecond
+	}
+	return time.Duration(c.HeartbeatScriptTimeout) * time.Millisecond
+}
+
+// GetIdempotencyWindow returns for how long responses to heartbeat requests with an idempotency key are replayed to retries (0 if disabled)
+func (c *appConfig) GetIdempotencyWindow() time.Duration {
+	if c.IdempotencyWindowMin <= 0 {
+		return 0
+	}
+	return time.Duration(c.IdempotencyWindowMin) * time.Minute
+}
+
+// GetStatsCacheTTL returns for how long responses of the wakatime-compatible stats endpoint are cached at most (0 if disabled)
+func (c *appConfig) GetStatsCacheTTL() time.Duration {
+	if c.StatsCacheTTLMin <= 0 {
+		return 0
+	}
+	return time.Duration(c.StatsCacheTTLMin) * time.Minute
+}
+
+// GetUndoWindow returns for how long deleted or reassigned heartbeats are kept to be restored, 0 if undo is disabled
+func (c *appConfig) GetUndoWindow() time.Duration {
+	if c.UndoWindowHours <= 0 {
+		return 0
+	}
+	return time.Duration(c.UndoWindowHours) * time.Hour
+}
+
+// GetHeartbeatsMaxBodySize returns the maximum size of heartbeat request bodies in bytes (0 if unlimited)
+func (c *appConfig) GetHeartbeatsMaxBodySize() int64 {
+	if c.HeartbeatsMaxBodyKb <= 0 {
+		return 0
+	}
+	return int64(c.HeartbeatsMaxBodyKb) * 1024
+}
+
+// GetStorageQuotaPolicy returns the server-wide default of what to do with users exceeding their storage quota, either rejecting their new heartbeats or pruning their oldest ones
+func (c *appConfig) GetStorageQuotaPolicy() string {
+	return findString(c.StorageQuotaPolicy, storageQuotaPolicies, StorageQuotaPolicyReject)
+}
+
+// GetFiscalYearStart returns the month in which the fiscal year starts, which is january, unless configured otherwise
+func (c *appConfig) GetFiscalYearStart() time.Month {
+	if c.FiscalYearStart < 1 || c.FiscalYearStart > 12 {
+		return time.January
+	}
+	return time.Month(c.FiscalYearStart)
+}
+
 func (c *appConfig) GetWeeklyReportDay() time.Weekday {
 	s := strings.Split(c.ReportTimeWeekly, ",")[0]
 	return parseWeekday(s)
@@ -242,6 +561,25 @@ func (c *appConfig) GetWeeklyReportTime() string {
 	return strings.Split(c.ReportTimeWeekly, ",")[1]
 }
 
+// IsTrustedProxy returns whether the given ip address belongs to one of the configured reverse proxies
+func (c *securityConfig) IsTrustedProxy(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if proxyAddr := net.ParseIP(proxy); proxyAddr != nil && proxyAddr.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *dbConfig) IsSQLite() bool {
 	return c.Dialect == "sqlite3"
 }
@@ -254,8 +592,22 @@ func (c *dbConfig) IsPostgres() bool {
 	return c.Dialect == "postgres"
 }
 
+// GetPublicUrl returns the url the instance is reachable at from outside without trailing slash, to build links in mails, notifications, badges, etc. from.
+// The base path is appended, unless public_url already ends with it, so that running behind a reverse proxy under a sub-path works either way.
 func (c *serverConfig) GetPublicUrl() string {
-	return strings.TrimSuffix(c.PublicUrl, "/")
+	publicUrl := strings.TrimSuffix(c.PublicUrl, "/")
+	if c.BasePath == "" || strings.HasSuffix(publicUrl, c.BasePath) {
+		return publicUrl
+	}
+	return publicUrl + c.BasePath
+}
+
+// GetCookiePath returns the path to scope cookies to, which must not be empty, as browsers would otherwise scope them to the path of the request setting them
+func (c *serverConfig) GetCookiePath() string {
+	if c.BasePath == "" {
+		return "/"
+	}
+	return c.BasePath
 }
 
 func (c *SMTPMailConfig) ConnStr() string {
@@ -289,13 +641,6 @@ func readColors() map[string]map[string]string {
 	return colors
 }
 
-func mustReadConfigLocation() string {
-	if _, err := os.Stat(*cFlag); err != nil {
-		logbuch.Fatal("failed to find config file at '%s'", *cFlag)
-	}
-	return *cFlag
-}
-
 func resolveDbDialect(dbType string) string {
 	if dbType == "cockroach" {
 		return "postgres"
@@ -346,13 +691,17 @@ func Get() *Config {
 	return cfg
 }
 
-func Load(version string) *Config {
+// Read reads the configuration from the config file and environment variables without validating it
+func Read(version string) (*Config, error) {
 	config := &Config{}
 
 	flag.Parse()
 
-	if err := configor.New(&configor.Config{}).Load(config, mustReadConfigLocation()); err != nil {
-		logbuch.Fatal("failed to read config: %v", err)
+	if _, err := os.Stat(*cFlag); err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to find config file at '%s'", *cFlag))
+	}
+	if err := configor.New(&configor.Config{}).Load(config, *cFlag); err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to read config: %v", err))
 	}
 
 	env = config.Env
@@ -368,6 +717,9 @@ func Load(version string) *Config {
 	if strings.HasSuffix(config.Server.BasePath, "/") {
 		config.Server.BasePath = config.Server.BasePath[:len(config.Server.BasePath)-1]
 	}
+	if config.Server.BasePath != "" && !strings.HasPrefix(config.Server.BasePath, "/") {
+		config.Server.BasePath = "/" + config.Server.BasePath
+	}
 
 	for k, v := range config.App.CustomLanguages {
 		if v == "" {
@@ -375,30 +727,27 @@ func Load(version string) *Config {
 		}
 	}
 
-	if config.Sentry.Dsn != "" {
-		logbuch.Info("enabling sentry integration")
-		initSentry(config.Sentry, config.IsDev())
+	return config, nil
+}
+
+func Load(version string) *Config {
+	config, err := Read(version)
+	if err != nil {
+		logbuch.Fatal(err.Error())
 	}
 
-	// some validation checks
-	if config.Server.ListenIpV4 == "" && config.Server.ListenIpV6 == "" && config.Server.ListenSocket == "" {
-		logbuch.Fatal("either of listen_ipv4 or listen_ipv6 or listen_socket must be set")
+	initErrorReporting(config)
+
+	errs, warnings := config.Validate()
+	for _, w := range warnings {
+		logbuch.Warn(w.Error())
 	}
-	if config.Db.MaxConn <= 0 {
-		logbuch.Fatal("you must allow at least one database connection")
+	if len(errs) > 0 {
+		logbuch.Fatal(errs[0].Error())
 	}
+
 	if config.Db.MaxConn > 1 && config.Db.IsSQLite() {
-		logbuch.Warn("with sqlite, only a single connection is supported") // otherwise 'PRAGMA foreign_keys=ON' would somehow have to be set for every connection in the pool
-		config.Db.MaxConn = 1
-	}
-	if config.Mail.Provider != "" && findString(config.Mail.Provider, emailProviders, "") == "" {
-		logbuch.Fatal("unknown mail provider '%s'", config.Mail.Provider)
-	}
-	if _, err := time.Parse("15:04", config.App.GetWeeklyReportTime()); err != nil {
-		logbuch.Fatal("invalid interval set for report_time_weekly")
-	}
-	if _, err := time.Parse("15:04", config.App.AggregationTime); err != nil {
-		logbuch.Fatal("invalid interval set for aggregation_time")
+		config.Db.MaxConn = 1 // sqlite only supports a single writer at a time anyway
 	}
 
 	Set(config)
@@ -2,6 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
@@ -81,6 +85,22 @@ func postgresConnectionString(config *dbConfig) string {
 	)
 }
 
+// sqliteConnectionString sets pragmas via connection parameters, so that they apply to every connection of the pool.
+// Transactions acquire the write lock immediately, because a deferred transaction failing to upgrade its lock is not retried within the busy timeout.
 func sqliteConnectionString(config *dbConfig) string {
-	return config.Name
+	params := url.Values{}
+	params.Set("_foreign_keys", "1")
+	params.Set("_txlock", "immediate")
+	if config.SqliteBusyTimeoutMs > 0 {
+		params.Set("_busy_timeout", strconv.Itoa(config.SqliteBusyTimeoutMs))
+	}
+	if config.SqliteWal {
+		params.Set("_journal_mode", "WAL")
+	}
+
+	separator := "?"
+	if strings.Contains(config.Name, "?") {
+		separator = "&"
+	}
+	return config.Name + separator + params.Encode()
 }
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/emvi/logbuch"
+)
+
+// How to: Logging
+// Use logbuch.[Debug|Info|Warn|Error|Fatal]() by default
+// Use config.Log().[Debug|Info|Warn|Error|Fatal]() when wanting the log to appear in the configured error reporting (e.g. sentry) as well
+
+const (
+	ErrorReportingSentry  = "sentry"
+	ErrorReportingWebhook = "webhook"
+	ErrorReportingNone    = "none"
+)
+
+// levels as understood by sentry
+const (
+	ErrorLevelDebug   = "debug"
+	ErrorLevelInfo    = "info"
+	ErrorLevelWarning = "warning"
+	ErrorLevelError   = "error"
+	ErrorLevelFatal   = "fatal"
+)
+
+var errorReporter ErrorReporter
+
+type errorReportingConfig struct {
+	Driver     string  `yaml:"driver" default:"sentry" env:"WAKAPI_ERROR_REPORTING_DRIVER"`      // one of ['sentry', 'webhook', 'none']
+	SampleRate float32 `yaml:"sample_rate" default:"1" env:"WAKAPI_ERROR_REPORTING_SAMPLE_RATE"` // probability of reporting an error
+	WebhookUrl string  `yaml:"webhook_url" env:"WAKAPI_ERROR_REPORTING_WEBHOOK_URL"`
+}
+
+// ErrorEvent is a single error or crash, optionally along with the request it occurred during
+type ErrorEvent struct {
+	Level      string             `json:"level"`
+	Message    string             `json:"message"`
+	Stacktrace string             `json:"stacktrace,omitempty"` // only present for panics
+	Time       time.Time          `json:"time"`
+	Instance   string             `json:"instance"`
+	Version    string             `json:"version"`
+	Request    *ErrorEventRequest `json:"request,omitempty"`
+}
+
+type ErrorEventRequest struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	RequestId string `json:"request_id,omitempty"`
+	UserId    string `json:"user_id,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// ErrorReporter is a driver to report errors and crashes to an external service. The request is nil for errors that did not occur while serving one.
+type ErrorReporter interface {
+	Report(event *ErrorEvent, req *http.Request)
+}
+
+// GetErrorReportingDriver returns the error reporting driver in effect or an empty string, if error reporting is disabled or incompletely configured
+func (c *Config) GetErrorReportingDriver() string {
+	switch c.ErrorReporting.Driver {
+	case ErrorReportingSentry:
+		if c.Sentry.Dsn != "" {
+			return ErrorReportingSentry
+		}
+	case ErrorReportingWebhook:
+		if c.ErrorReporting.WebhookUrl != "" {
+			return ErrorReportingWebhook
+		}
+	}
+	return ""
+}
+
+func initErrorReporting(config *Config) {
+	switch config.GetErrorReportingDriver() {
+	case ErrorReportingSentry:
+		logbuch.Info("enabling sentry integration")
+		initSentry(config.Sentry, config.IsDev())
+		errorReporter = &sentryReporter{}
+	case ErrorReportingWebhook:
+		logbuch.Info("enabling error reporting via webhook")
+		errorReporter = &webhookReporter{url: config.ErrorReporting.WebhookUrl}
+	}
+}
+
+// ReportPanic reports a recovered panic along with its stack trace, if error reporting is enabled
+func ReportPanic(recovered interface{}, stacktrace []byte, req *http.Request) {
+	report(&ErrorEvent{
+		Level:      ErrorLevelFatal,
+		Message:    fmt.Sprintf("panic: %v", recovered),
+		Stacktrace: string(stacktrace),
+	}, req)
+}
+
+func report(event *ErrorEvent, req *http.Request) {
+	if errorReporter == nil {
+		return
+	}
+
+	if c := Get(); c != nil {
+		if c.ErrorReporting.SampleRate < 1 && rand.Float32() >= c.ErrorReporting.SampleRate {
+			return
+		}
+		event.Instance = c.Server.GetPublicUrl()
+		event.Version = c.Version
+	}
+
+	event.Time = time.Now()
+	if req != nil {
+		event.Request = &ErrorEventRequest{
+			Method:    req.Method,
+			Path:      req.URL.Path,
+			RequestId: req.Header.Get(HeaderRequestId),
+			UserAgent: req.UserAgent(),
+		}
+		if u := getPrincipal(req); u != nil {
+			event.Request.UserId = u.ID
+		}
+	}
+
+	errorReporter.Report(event, req)
+}
+
+// webhookReporter posts errors as json to a generic http endpoint, e.g. of a chat or incident management tool
+type webhookReporter struct {
+	url string
+}
+
+func (r *webhookReporter) Report(event *ErrorEvent, req *http.Request) {
+	// unlike with sentry, mere debug, info and warning logs would only be noise here
+	if event.Level != ErrorLevelError && event.Level != ErrorLevelFatal {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		res, err := NewHttpClient(ProxyScopeNotifications, 10*time.Second).Post(r.url, "application/json", bytes.NewBuffer(data))
+		if err != nil {
+			logbuch.Warn("failed to report error via webhook - %v", err) // deliberately not reported itself
+			return
+		}
+		defer res.Body.Close()
+		if res.StatusCode >= 400 {
+			logbuch.Warn("failed to report error via webhook, got status %d", res.StatusCode)
+		}
+	}()
+}
+
+type capturingWriter struct {
+	Writer  io.Writer
+	Message string
+}
+
+func (c *capturingWriter) Clear() {
+	c.Message = ""
+}
+
+func (c *capturingWriter) Write(p []byte) (n int, err error) {
+	c.Message = string(p)
+	return c.Writer.Write(p)
+}
+
+// ReportingLogger is a wrapper around a logbuch.Logger that forwards events to the configured error reporting in addition and optionally allows to attach a request context
+type ReportingLogger struct {
+	*logbuch.Logger
+	req       *http.Request
+	outWriter *capturingWriter
+	errWriter *capturingWriter
+}
+
+func Log() *ReportingLogger {
+	ow, ew := &capturingWriter{Writer: os.Stdout}, &capturingWriter{Writer: os.Stderr}
+	return &ReportingLogger{
+		Logger:    logbuch.NewLogger(ow, ew),
+		outWriter: ow,
+		errWriter: ew,
+	}
+}
+
+func (l *ReportingLogger) Request(req *http.Request) *ReportingLogger {
+	l.req = req
+	return l
+}
+
+func (l *ReportingLogger) Debug(msg string, params ...interface{}) {
+	l.outWriter.Clear()
+	l.Logger.Debug(msg, params...)
+	l.log(l.errWriter.Message, ErrorLevelDebug)
+}
+
+func (l *ReportingLogger) Info(msg string, params ...interface{}) {
+	l.outWriter.Clear()
+	l.Logger.Info(msg, params...)
+	l.log(l.errWriter.Message, ErrorLevelInfo)
+}
+
+func (l *ReportingLogger) Warn(msg string, params ...interface{}) {
+	l.outWriter.Clear()
+	l.Logger.Warn(msg, params...)
+	l.log(l.errWriter.Message, ErrorLevelWarning)
+}
+
+func (l *ReportingLogger) Error(msg string, params ...interface{}) {
+	l.errWriter.Clear()
+	l.Logger.Error(msg, params...)
+	l.log(l.errWriter.Message, ErrorLevelError)
+}
+
+func (l *ReportingLogger) Fatal(msg string, params ...interface{}) {
+	l.errWriter.Clear()
+	l.Logger.Fatal(msg, params...)
+	l.log(l.errWriter.Message, ErrorLevelFatal)
+}
+
+func (l *ReportingLogger) log(msg string, level string) {
+	report(&ErrorEvent{Level: level, Message: msg}, l.req)
+}
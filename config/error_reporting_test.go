@@ -0,0 +1,48 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookReporter_Report(t *testing.T) {
+	events := make(chan *ErrorEvent, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event ErrorEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- &event
+	}))
+	defer server.Close()
+
+	cfg := &Config{}
+	cfg.ErrorReporting.SampleRate = 1
+	cfg.Server.PublicUrl = "https://wakapi.dev"
+	Set(cfg)
+
+	errorReporter = &webhookReporter{url: server.URL}
+	defer func() { errorReporter = nil }()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/heartbeat", nil)
+	req.Header.Set(HeaderRequestId, "some-request")
+
+	report(&ErrorEvent{Level: ErrorLevelInfo, Message: "not reported"}, req)
+	ReportPanic("something went wrong", []byte("goroutine 1 [running]"), req)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, ErrorLevelFatal, event.Level)
+		assert.Equal(t, "panic: something went wrong", event.Message)
+		assert.Equal(t, "goroutine 1 [running]", event.Stacktrace)
+		assert.Equal(t, "https://wakapi.dev", event.Instance)
+		assert.Equal(t, "/api/heartbeat", event.Request.Path)
+		assert.Equal(t, "some-request", event.Request.RequestId)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "error was not reported")
+	}
+	assert.Empty(t, events)
+}
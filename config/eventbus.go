@@ -8,17 +8,32 @@ type ApplicationEvent struct {
 }
 
 const (
-	TopicUser               = "user.*"
-	TopicHeartbeat          = "heartbeat.*"
-	TopicProjectLabel       = "project_label.*"
-	EventUserUpdate         = "user.update"
-	EventHeartbeatCreate    = "heartbeat.create"
-	EventProjectLabelCreate = "project_label.create"
-	EventProjectLabelDelete = "project_label.delete"
-	EventWakatimeFailure    = "wakatime.failure"
-	FieldPayload            = "payload"
-	FieldUser               = "user"
-	FieldUserId             = "user.id"
+	TopicUser                  = "user.*"
+	TopicHeartbeat             = "heartbeat.*"
+	TopicAlias                 = "alias.*"
+	TopicLanguageMapping       = "language_mapping.*"
+	TopicProjectLabel          = "project_label.*"
+	TopicManualTimeEntry       = "manual_time_entry.*"
+	TopicSummary               = "summary.*"
+	EventUserUpdate            = "user.update"
+	EventUserDelete            = "user.delete"
+	EventApiKeyRevoke          = "user.api_key_revoke"
+	EventHeartbeatCreate       = "heartbeat.create"
+	EventHeartbeatUpdate       = "heartbeat.update"
+	EventHeartbeatDelete       = "heartbeat.delete"
+	EventAliasCreate           = "alias.create"
+	EventAliasDelete           = "alias.delete"
+	EventLanguageMappingCreate = "language_mapping.create"
+	EventLanguageMappingDelete = "language_mapping.delete"
+	EventProjectLabelCreate    = "project_label.create"
+	EventProjectLabelDelete    = "project_label.delete"
+	EventManualTimeEntryCreate = "manual_time_entry.create"
+	EventManualTimeEntryDelete = "manual_time_entry.delete"
+	EventWakatimeFailure       = "wakatime.failure"
+	EventSummaryCreate         = "summary.create"
+	FieldPayload               = "payload"
+	FieldUser                  = "user"
+	FieldUserId                = "user.id"
 )
 
 var eventHub *hub.Hub
@@ -9,4 +9,8 @@ const (
 	ResetPasswordTemplate = "reset-password.tpl.html"
 	SettingsTemplate      = "settings.tpl.html"
 	SummaryTemplate       = "summary.tpl.html"
+	ReportsTemplate       = "reports.tpl.html"
+	TeamTemplate          = "team.tpl.html"
+	InstanceTemplate      = "instance.tpl.html"
+	LeaderboardTemplate   = "leaderboard.tpl.html"
 )
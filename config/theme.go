@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// colors and logo used, unless overridden by the instance's theme
+const (
+	ThemeDefaultPrimaryColor = "#2F855A"
+	ThemeDefaultAccentColor  = "#047857"
+	ThemeDefaultLogoUrl      = "assets/images/logo.svg"
+	ThemeDefaultMailLogoUrl  = "https://wakapi.dev/assets/images/android-chrome-192x192.png?utm_source=mail"
+)
+
+var hexColorRegex = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func IsHexColor(color string) bool {
+	return hexColorRegex.MatchString(color)
+}
+
+// themeConfig allows operators to brand (e.g. white-label) their instance, applied to both server-rendered pages and mails
+type themeConfig struct {
+	LogoUrl      string `yaml:"logo_url" env:"WAKAPI_THEME_LOGO_URL"`           // absolute url or path relative to the public url
+	PrimaryColor string `yaml:"primary_color" env:"WAKAPI_THEME_PRIMARY_COLOR"` // hex color of buttons, links and highlights
+	AccentColor  string `yaml:"accent_color" env:"WAKAPI_THEME_ACCENT_COLOR"`   // hex color of hovered buttons and notifications
+	FooterText   string `yaml:"footer_text" env:"WAKAPI_THEME_FOOTER_TEXT"`     // plain text, replaces the default footer
+}
+
+func (c *themeConfig) GetPrimaryColor() string {
+	if c.PrimaryColor == "" {
+		return ThemeDefaultPrimaryColor
+	}
+	return c.PrimaryColor
+}
+
+func (c *themeConfig) GetAccentColor() string {
+	if c.AccentColor == "" {
+		return ThemeDefaultAccentColor
+	}
+	return c.AccentColor
+}
+
+// GetLogoUrl returns the logo to show on pages, which are served relative to the base path
+func (c *themeConfig) GetLogoUrl() string {
+	if c.LogoUrl == "" {
+		return ThemeDefaultLogoUrl
+	}
+	return c.LogoUrl
+}
+
+// GetMailLogoUrl returns the logo to show in mails, which, unlike pages, require absolute urls
+func (c *themeConfig) GetMailLogoUrl(publicUrl string) string {
+	if c.LogoUrl == "" {
+		return ThemeDefaultMailLogoUrl
+	}
+	if u, err := url.Parse(c.LogoUrl); err == nil && u.IsAbs() {
+		return c.LogoUrl
+	}
+	return strings.TrimSuffix(publicUrl, "/") + "/" + strings.TrimPrefix(c.LogoUrl, "/")
+}
+
+func (c *themeConfig) Validate() error {
+	for name, color := range map[string]string{"primary_color": c.PrimaryColor, "accent_color": c.AccentColor} {
+		if color != "" && !IsHexColor(color) {
+			return errors.New(fmt.Sprintf("invalid theme %s '%s', must be a hex color like '#2F855A'", name, color))
+		}
+	}
+	if u, err := url.Parse(c.LogoUrl); err != nil || (u.IsAbs() && u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("invalid theme logo_url, must be an http(s) url or a path")
+	}
+	return nil
+}
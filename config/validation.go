@@ -0,0 +1,154 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Validate checks the configuration and returns errors, which prevent wakapi from starting, as well as warnings about settings, which are likely to cause problems at runtime only, e.g. when the first mail is sent
+func (c *Config) Validate() (errs []error, warnings []error) {
+	fail := func(format string, a ...interface{}) {
+		errs = append(errs, errors.New(fmt.Sprintf(format, a...)))
+	}
+	warn := func(format string, a ...interface{}) {
+		warnings = append(warnings, errors.New(fmt.Sprintf(format, a...)))
+	}
+
+	if c.Server.ListenIpV4 == "" && c.Server.ListenIpV6 == "" && c.Server.ListenSocket == "" {
+		fail("either of listen_ipv4 or listen_ipv6 or listen_socket must be set")
+	}
+	if c.Db.MaxConn <= 0 {
+		fail("you must allow at least one database connection")
+	}
+	if c.Storage.Backups && !c.Db.IsSQLite() {
+		fail("database backups are only supported for sqlite")
+	}
+	if c.Db.MaxConn > 1 && c.Db.IsSQLite() {
+		warn("with sqlite, only a single connection is supported, db.max_conn will be set to 1")
+	}
+	if c.Mail.Provider != "" && findString(c.Mail.Provider, emailProviders, "") == "" {
+		fail("unknown mail provider '%s'", c.Mail.Provider)
+	}
+	if c.Storage.Provider != "" && findString(c.Storage.Provider, storageProviders, "") == "" {
+		fail("unknown storage provider '%s'", c.Storage.Provider)
+	}
+	if c.Storage.Provider == StorageProviderS3 && (c.Storage.S3.Endpoint == "" || c.Storage.S3.Bucket == "") {
+		fail("endpoint and bucket must be set when using s3 storage")
+	}
+	if err := c.Proxy.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Theme.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateWeeklyTime(c.App.ReportTimeWeekly); err != nil {
+		fail("invalid interval set for report_time_weekly, %v", err)
+	} else if !isWeekday(strings.Split(c.App.ReportTimeWeekly, ",")[0]) {
+		warn("unknown weekday in report_time_weekly '%s', reports will be sent on mondays", c.App.ReportTimeWeekly)
+	}
+	if _, err := time.Parse("15:04", c.App.AggregationTime); err != nil {
+		fail("invalid interval set for aggregation_time, must be of the form 'hh:mm' (e.g. '02:15')")
+	}
+	if c.App.FiscalYearStart < 0 || c.App.FiscalYearStart > 12 {
+		fail("invalid month set for fiscal_year_start, must be between 1 (january) and 12 (december)")
+	}
+	if c.App.LeaderboardEnabled && len(strings.Fields(c.App.LeaderboardSchedule)) != 5 {
+		fail("invalid schedule set for leaderboard_schedule, must be a cron expression of five fields (e.g. '0 6,18 * * *')")
+	}
+	if c.App.HeartbeatsMaxPastDays < 0 || c.App.HeartbeatsMaxFutureMin < 0 {
+		fail("heartbeats_max_past_days and heartbeats_max_future_min must not be negative")
+	}
+	if c.App.HeartbeatsMaxBodyKb < 0 || c.App.HeartbeatsMaxBatchSize < 0 || c.App.HeartbeatsMaxPerReq < 0 {
+		fail("heartbeats_max_body_kb, heartbeats_max_batch_size and heartbeats_max_per_request must not be negative")
+	}
+	if c.App.HeartbeatsMaxPerReq > 0 && c.App.HeartbeatsMaxBatchSize > c.App.HeartbeatsMaxPerReq {
+		warn("heartbeats_max_batch_size exceeds heartbeats_max_per_request, batches will be limited to %d heartbeats", c.App.HeartbeatsMaxPerReq)
+	}
+	if c.App.ApiRateLimitPerMin < 0 {
+		fail("api_rate_limit_per_min must not be negative")
+	}
+	if c.App.StorageQuotaHeartbeats < 0 {
+		fail("storage_quota_heartbeats must not be negative")
+	}
+	if c.App.StorageQuotaPolicy != "" && findString(c.App.StorageQuotaPolicy, storageQuotaPolicies, "") == "" {
+		fail("unknown storage_quota_policy '%s', must be either of '%s' or '%s'", c.App.StorageQuotaPolicy, StorageQuotaPolicyReject, StorageQuotaPolicyPrune)
+	}
+	if c.App.DemoMode && c.App.DemoResetHours < 1 {
+		fail("demo_reset_hours must be at least 1")
+	}
+
+	if c.Mail.Enabled {
+		for _, err := range c.Mail.validate() {
+			warnings = append(warnings, err)
+		}
+	}
+
+	if !c.IsDev() && strings.Contains(c.Server.PublicUrl, "localhost") {
+		warn("server.public_url is '%s', links in mails and badges will not work for others", c.Server.PublicUrl)
+	}
+
+	return errs, warnings
+}
+
+// validate checks whether the settings of the selected mail provider are complete, so that mails can actually be sent
+func (c *mailConfig) validate() (errs []error) {
+	missing := func(key string) {
+		errs = append(errs, errors.New(fmt.Sprintf("mail is enabled, but mail.%s is not set (set mail.enabled to false to disable mails)", key)))
+	}
+
+	switch c.Provider {
+	case MailProviderSmtp:
+		if c.Smtp.Host == "" {
+			missing("smtp.host")
+		}
+		if c.Smtp.Port == 0 {
+			missing("smtp.port")
+		}
+	case MailProviderMailWhale:
+		if c.MailWhale.Url == "" {
+			missing("mailwhale.url")
+		}
+		if c.MailWhale.ClientId == "" || c.MailWhale.ClientSecret == "" {
+			missing("mailwhale.client_id or mail.mailwhale.client_secret")
+		}
+	case MailProviderSendGrid:
+		if c.SendGrid.ApiKey == "" {
+			missing("sendgrid.api_key")
+		}
+	case MailProviderMailgun:
+		if c.Mailgun.Domain == "" {
+			missing("mailgun.domain")
+		}
+		if c.Mailgun.ApiKey == "" {
+			missing("mailgun.api_key")
+		}
+	case MailProviderSES:
+		if c.SES.AccessKeyId == "" || c.SES.SecretAccessKey == "" {
+			missing("ses.access_key_id or mail.ses.secret_access_key")
+		}
+	}
+
+	if c.Sender == "" && c.Provider != MailProviderMailWhale {
+		missing("sender")
+	}
+
+	return errs
+}
+
+func validateWeeklyTime(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return errors.New("must be of the form 'weekday,hh:mm' (e.g. 'fri,18:00')")
+	}
+	if _, err := time.Parse("15:04", parts[1]); err != nil {
+		return errors.New("must be of the form 'weekday,hh:mm' (e.g. 'fri,18:00')")
+	}
+	return nil
+}
+
+func isWeekday(s string) bool {
+	s = strings.ToLower(s)
+	return s == "mon" || s == "monday" || parseWeekday(s) != time.Monday
+}
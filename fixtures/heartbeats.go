@@ -0,0 +1,142 @@
+// Package fixtures generates realistic looking, but entirely made up data, e.g. to populate demo users or to be used by tests
+package fixtures
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/muety/wakapi/models"
+)
+
+type fixtureFile struct {
+	entity   string
+	language string
+}
+
+type fixtureProject struct {
+	name     string
+	branches []string
+	files    []fixtureFile
+}
+
+// projects are ordered by how much time is spent on them, i.e. the first ones are picked more often
+var projects = []fixtureProject{
+	{"wakapi", []string{"master", "feature/demo-mode", "fix/timezones"}, []fixtureFile{
+		{"/home/demo/dev/wakapi/main.go", "Go"},
+		{"/home/demo/dev/wakapi/services/summary.go", "Go"},
+		{"/home/demo/dev/wakapi/routes/summary.go", "Go"},
+		{"/home/demo/dev/wakapi/views/summary.tpl.html", "HTML"},
+		{"/home/demo/dev/wakapi/static/assets/js/summary.js", "JavaScript"},
+		{"/home/demo/dev/wakapi/config.default.yml", "YAML"},
+		{"/home/demo/dev/wakapi/README.md", "Markdown"},
+	}},
+	{"anchr", []string{"main", "feature/collections"}, []fixtureFile{
+		{"/home/demo/dev/anchr/src/app.ts", "TypeScript"},
+		{"/home/demo/dev/anchr/src/components/Collection.vue", "Vue"},
+		{"/home/demo/dev/anchr/src/styles/main.css", "CSS"},
+		{"/home/demo/dev/anchr/package.json", "JSON"},
+	}},
+	{"telegram-bot", []string{"master"}, []fixtureFile{
+		{"/home/demo/dev/telegram-bot/bot/handlers.py", "Python"},
+		{"/home/demo/dev/telegram-bot/bot/storage.py", "Python"},
+		{"/home/demo/dev/telegram-bot/Dockerfile", "Docker"},
+	}},
+	{"dotfiles", []string{"master"}, []fixtureFile{
+		{"/home/demo/dotfiles/.zshrc", "Shell"},
+		{"/home/demo/dotfiles/init.lua", "Lua"},
+	}},
+}
+
+var editors = []string{"vscode", "goland", "neovim"}
+var operatingSystems = []string{"Linux", "Mac"}
+var machines = []string{"workstation", "laptop"}
+
+// coding sessions may start within these hours of a day, at most one per slot
+var sessionSlots = []struct {
+	from, to    int
+	maxDuration time.Duration
+}{
+	{8, 10, 3 * time.Hour},
+	{13, 15, 3 * time.Hour},
+	{19, 21, 90 * time.Minute},
+}
+
+// HeartbeatGenerator produces a plausible coding history with a couple of sessions on most weekdays and only occasional ones on weekends.
+// The same seed always yields the same heartbeats, so that tests can rely on them.
+type HeartbeatGenerator struct {
+	rand     *rand.Rand
+	interval time.Duration
+}
+
+func NewHeartbeatGenerator(seed int64) *HeartbeatGenerator {
+	return &HeartbeatGenerator{
+		rand:     rand.New(rand.NewSource(seed)),
+		interval: 2 * time.Minute,
+	}
+}
+
+// Generate returns heartbeats of the given user for every day within the given range in chronological order, with days starting in the location of from
+func (g *HeartbeatGenerator) Generate(user *models.User, from, to time.Time) []*models.Heartbeat {
+	heartbeats := make([]*models.Heartbeat, 0)
+
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location()); day.Before(to); day = day.AddDate(0, 0, 1) {
+		weekend := day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+
+		for i, slot := range sessionSlots {
+			chance := 0.9
+			if weekend {
+				chance = 0.2
+			} else if i == len(sessionSlots)-1 {
+				chance = 0.3
+			}
+			if g.rand.Float64() >= chance {
+				continue
+			}
+
+			start := day.Add(time.Duration(slot.from)*time.Hour + time.Duration(g.rand.Int63n(int64(time.Duration(slot.to-slot.from)*time.Hour))))
+			duration := slot.maxDuration/4 + time.Duration(g.rand.Int63n(int64(slot.maxDuration*3/4)))
+
+			for _, h := range g.session(user, start, duration) {
+				if t := h.Time.T(); !t.Before(from) && t.Before(to) {
+					heartbeats = append(heartbeats, h)
+				}
+			}
+		}
+	}
+
+	return heartbeats
+}
+
+func (g *HeartbeatGenerator) session(user *models.User, start time.Time, duration time.Duration) []*models.Heartbeat {
+	project := projects[g.rand.Intn(g.rand.Intn(len(projects))+1)] // skewed towards the first projects
+	branch := project.branches[g.rand.Intn(len(project.branches))]
+	editor := editors[g.rand.Intn(len(editors))]
+	machine := g.rand.Intn(len(machines))
+	file := project.files[g.rand.Intn(len(project.files))]
+
+	heartbeats := make([]*models.Heartbeat, 0, int(duration/g.interval)+1)
+	for t := start; t.Before(start.Add(duration)); t = t.Add(g.interval/2 + time.Duration(g.rand.Int63n(int64(g.interval)))) {
+		if g.rand.Float64() < 0.2 {
+			file = project.files[g.rand.Intn(len(project.files))]
+		}
+
+		heartbeats = append(heartbeats, (&models.Heartbeat{
+			User:            user,
+			UserID:          user.ID,
+			Entity:          file.entity,
+			Type:            "file",
+			Category:        "coding",
+			Project:         project.name,
+			Branch:          branch,
+			Language:        file.language,
+			IsWrite:         g.rand.Float64() < 0.4,
+			Lines:           50 + g.rand.Intn(450),
+			Editor:          editor,
+			OperatingSystem: operatingSystems[machine],
+			Machine:         machines[machine],
+			UserAgent:       "wakatime/v1.73.0 (fixtures) " + editor,
+			Time:            models.CustomTime(t),
+		}).Hashed())
+	}
+	return heartbeats
+}
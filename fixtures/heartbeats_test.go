@@ -0,0 +1,44 @@
+package fixtures
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatGenerator_Generate(t *testing.T) {
+	user := &models.User{ID: "demo"}
+	from := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC) // a monday
+	to := from.AddDate(0, 0, 14)
+
+	heartbeats := NewHeartbeatGenerator(42).Generate(user, from, to)
+
+	assert.NotEmpty(t, heartbeats)
+	for i, h := range heartbeats {
+		assert.True(t, h.Valid())
+		assert.NotEmpty(t, h.Hash)
+		assert.False(t, h.Time.T().Before(from))
+		assert.True(t, h.Time.T().Before(to))
+		if i > 0 {
+			assert.True(t, h.Time.T().After(heartbeats[i-1].Time.T()))
+		}
+	}
+
+	// deterministic for the same seed
+	assert.Equal(t, heartbeats, NewHeartbeatGenerator(42).Generate(user, from, to))
+	assert.NotEqual(t, heartbeats, NewHeartbeatGenerator(43).Generate(user, from, to))
+}
+
+func TestHeartbeatGenerator_Generate_PartialDay(t *testing.T) {
+	user := &models.User{ID: "demo"}
+	from := time.Date(2021, 2, 1, 12, 0, 0, 0, time.UTC)
+	to := from.Add(6 * time.Hour)
+
+	heartbeats := NewHeartbeatGenerator(42).Generate(user, from, to)
+
+	for _, h := range heartbeats {
+		assert.False(t, h.Time.T().Before(from))
+		assert.True(t, h.Time.T().Before(to))
+	}
+}
@@ -0,0 +1,50 @@
+package i18n
+
+import (
+	"encoding/json"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// JsonCatalog reads messages from one flat json object of message keys and translations per locale, e.g. 'de.json'
+type JsonCatalog struct {
+	messages map[string]map[string]string
+}
+
+func NewJsonCatalog(fsys fs.FS, dir string) (*JsonCatalog, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &JsonCatalog{messages: make(map[string]map[string]string)}
+	for _, f := range files {
+		data, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, err
+		}
+		catalog.messages[strings.TrimSuffix(path.Base(f), ".json")] = messages
+	}
+	return catalog, nil
+}
+
+func (c *JsonCatalog) Translate(locale, key string) (string, bool) {
+	if messages, ok := c.messages[locale]; ok {
+		message, ok := messages[key]
+		return message, ok
+	}
+	return "", false
+}
+
+func (c *JsonCatalog) Locales() []string {
+	locales := make([]string, 0, len(c.messages))
+	for l := range c.messages {
+		locales = append(locales, l)
+	}
+	return locales
+}
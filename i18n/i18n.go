@@ -0,0 +1,113 @@
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"sort"
+	"sync"
+)
+
+// DefaultLocale is used for users without a language preference and as a fallback for messages missing in other locales
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// Catalog provides translated messages by locale and message key
+type Catalog interface {
+	Translate(locale, key string) (string, bool)
+	Locales() []string
+}
+
+var (
+	catalogs []Catalog
+	mutex    sync.RWMutex
+)
+
+func init() {
+	catalog, err := NewJsonCatalog(localeFiles, "locales")
+	if err != nil {
+		panic(err)
+	}
+	Register(catalog)
+}
+
+// Register adds a catalog, whose messages take precedence over those of any previously registered one, e.g. to add a locale or override single messages
+func Register(catalog Catalog) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	catalogs = append(catalogs, catalog)
+}
+
+// T returns the message for the given key in the given locale, falling back to the default locale and eventually the key itself, formatted with the given arguments
+func T(locale, key string, args ...interface{}) string {
+	message, ok := lookup(locale, key)
+	if !ok {
+		if message, ok = lookup(DefaultLocale, key); !ok {
+			return key
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// HTML is like T, but for messages, which may contain markup, so only the arguments are escaped
+func HTML(locale, key string, args ...interface{}) template.HTML {
+	escaped := make([]interface{}, len(args))
+	for i, a := range args {
+		if s, ok := a.(string); ok {
+			escaped[i] = template.HTMLEscapeString(s)
+		} else {
+			escaped[i] = a
+		}
+	}
+	return template.HTML(T(locale, key, escaped...))
+}
+
+// Locales returns all locales available in any catalog
+func Locales() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	seen := make(map[string]bool)
+	locales := make([]string, 0)
+	for _, c := range catalogs {
+		for _, l := range c.Locales() {
+			if !seen[l] {
+				seen[l] = true
+				locales = append(locales, l)
+			}
+		}
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+func IsSupported(locale string) bool {
+	for _, l := range Locales() {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns the locale's name in its own language, e.g. 'Deutsch'
+func Name(locale string) string {
+	return T(locale, "locale.name")
+}
+
+func lookup(locale, key string) (string, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	for i := len(catalogs) - 1; i >= 0; i-- {
+		if message, ok := catalogs[i].Translate(locale, key); ok {
+			return message, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,63 @@
+package i18n
+
+import (
+	"github.com/stretchr/testify/assert"
+	"html/template"
+	"testing"
+)
+
+type mapCatalog map[string]map[string]string
+
+func (c mapCatalog) Translate(locale, key string) (string, bool) {
+	message, ok := c[locale][key]
+	return message, ok
+}
+
+func (c mapCatalog) Locales() []string {
+	locales := make([]string, 0, len(c))
+	for l := range c {
+		locales = append(locales, l)
+	}
+	return locales
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "Projects", T("en", "entity.projects"))
+	assert.Equal(t, "Projekte", T("de", "entity.projects"))
+	assert.Equal(t, "Projects", T("", "entity.projects"))
+	assert.Equal(t, "Projects", T("xx", "entity.projects"))
+	assert.Equal(t, "does.not.exist", T("de", "does.not.exist"))
+	assert.Equal(t, "Wakapi - Report from Oct 16, 2026", T("en", "mail.report.subject", "Oct 16, 2026"))
+}
+
+func TestHTML(t *testing.T) {
+	assert.Equal(t, template.HTML("This includes <strong>&lt;b&gt;</strong> of manually added time."), HTML("en", "mail.report.manual", "<b>"))
+}
+
+func TestRegister(t *testing.T) {
+	defer func(c []Catalog) { catalogs = c }(catalogs)
+
+	Register(mapCatalog{
+		"en": {"entity.projects": "Repositories"},
+		"fr": {"entity.projects": "Projets"},
+	})
+
+	assert.Equal(t, "Repositories", T("en", "entity.projects"))
+	assert.Equal(t, "Projets", T("fr", "entity.projects"))
+	assert.Equal(t, "Languages", T("fr", "entity.languages"))
+	assert.Equal(t, []string{"de", "en", "fr"}, Locales())
+	assert.True(t, IsSupported("fr"))
+}
+
+func TestCatalogs_Complete(t *testing.T) {
+	catalog, err := NewJsonCatalog(localeFiles, "locales")
+	assert.Nil(t, err)
+
+	for _, l := range catalog.Locales() {
+		assert.Len(t, catalog.messages[l], len(catalog.messages[DefaultLocale]), "locale '%s' is incomplete", l)
+		for key := range catalog.messages[DefaultLocale] {
+			_, ok := catalog.Translate(l, key)
+			assert.True(t, ok, "locale '%s' misses '%s'", l, key)
+		}
+	}
+}
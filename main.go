@@ -2,6 +2,10 @@ package main
 
 import (
 	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"io/fs"
 	"log"
 	"net"
@@ -21,6 +25,7 @@ import (
 	"github.com/muety/wakapi/repositories"
 	"github.com/muety/wakapi/routes/api"
 	"github.com/muety/wakapi/services/mail"
+	"github.com/muety/wakapi/services/storage"
 	fsutils "github.com/muety/wakapi/utils/fs"
 	"gorm.io/gorm/logger"
 
@@ -45,27 +50,69 @@ var version string
 var staticFiles embed.FS
 
 var (
-	db     *gorm.DB
-	config *conf.Config
+	db           *gorm.DB
+	config       *conf.Config
+	queryMetrics *repositories.QueryMetrics
+	routeMetrics *middlewares.RouteMetrics
 )
 
 var (
-	aliasRepository           repositories.IAliasRepository
-	heartbeatRepository       repositories.IHeartbeatRepository
-	userRepository            repositories.IUserRepository
-	languageMappingRepository repositories.ILanguageMappingRepository
-	projectLabelRepository    repositories.IProjectLabelRepository
-	summaryRepository         repositories.ISummaryRepository
-	keyValueRepository        repositories.IKeyValueRepository
-	diagnosticsRepository     repositories.IDiagnosticsRepository
+	aliasRepository               repositories.IAliasRepository
+	heartbeatRepository           repositories.IHeartbeatRepository
+	userRepository                repositories.IUserRepository
+	languageMappingRepository     repositories.ILanguageMappingRepository
+	projectLabelRepository        repositories.IProjectLabelRepository
+	projectRepoRepository         repositories.IProjectRepoRepository
+	projectBudgetRepository       repositories.IProjectBudgetRepository
+	goalRepository                repositories.IGoalRepository
+	filterSetRepository           repositories.IFilterSetRepository
+	embedTokenRepository          repositories.IEmbedTokenRepository
+	notificationRepository        repositories.INotificationPreferenceRepository
+	relayTargetRepository         repositories.IRelayTargetRepository
+	relayRuleRepository           repositories.IRelayRuleRepository
+	dayOffRepository              repositories.IDayOffRepository
+	achievementRepository         repositories.IAchievementRepository
+	summaryRepository             repositories.ISummaryRepository
+	keyValueRepository            repositories.IKeyValueRepository
+	diagnosticsRepository         repositories.IDiagnosticsRepository
+	backupRepository              repositories.IBackupRepository
+	jiraWorklogRepository         repositories.IJiraWorklogRepository
+	calendarEventRepository       repositories.ICalendarEventRepository
+	manualTimeEntryRepository     repositories.IManualTimeEntryRepository
+	summaryInvalidationRepository repositories.ISummaryInvalidationRepository
+	archivedReportRepository      repositories.IArchivedReportRepository
+	tombstoneRepository           repositories.ITombstoneRepository
+	remoteAccountRepository       repositories.IRemoteAccountRepository
+	leaderboardRepository         repositories.ILeaderboardRepository
+	durationRepository            repositories.IDurationRepository
+	quarantineRepository          repositories.IQuarantineRepository
 )
 
 var (
 	aliasService           services.IAliasService
 	heartbeatService       services.IHeartbeatService
+	heartbeatScriptService services.IHeartbeatScriptService
 	userService            services.IUserService
 	languageMappingService services.ILanguageMappingService
 	projectLabelService    services.IProjectLabelService
+	projectRepoService     services.IProjectRepoService
+	projectBudgetService   services.IProjectBudgetService
+	goalService            services.IGoalService
+	settingsService        services.ISettingsService
+	statsCacheService      services.IStatsCacheService
+	filterSetService       services.IFilterSetService
+	embedTokenService      services.IEmbedTokenService
+	notificationService    services.INotificationService
+	inactivityService      services.IInactivityService
+	relayTargetService     services.IRelayTargetService
+	relayRuleService       services.IRelayRuleService
+	dayOffService          services.IDayOffService
+	overtimeService        services.IOvertimeService
+	comparisonService      services.IComparisonService
+	remoteAccountService   services.IRemoteAccountService
+	leaderboardService     services.ILeaderboardService
+	achievementService     services.IAchievementService
+	yearReviewService      services.IYearReviewService
 	durationService        services.IDurationService
 	summaryService         services.ISummaryService
 	aggregationService     services.IAggregationService
@@ -74,6 +121,33 @@ var (
 	reportService          services.IReportService
 	diagnosticsService     services.IDiagnosticsService
 	miscService            services.IMiscService
+	manualTimeEntryService services.IManualTimeEntryService
+	doctorService          services.IDoctorService
+	userBatchService       services.IUserBatchService
+	quotaService           services.IQuotaService
+	languageMetaService    services.ILanguageMetaService
+	rateLimitService       services.IRateLimitService
+	storageQuotaService    services.IStorageQuotaService
+	clockSkewService       services.IClockSkewService
+	maintenanceService     services.IMaintenanceService
+	demoService            services.IDemoService
+	importService          services.IImportService
+	jobService             services.IJobService
+	storageService         services.IStorageService
+	exportService          services.IExportService
+	reassignmentService    services.IReassignmentService
+	undoService            services.IUndoService
+	backupService          services.IBackupService
+	avatarService          services.IAvatarService
+	ticketService          services.ITicketService
+	togglService           services.ITogglService
+	timesheetService       services.ITimesheetService
+	sessionService         services.ISessionService
+	quarantineService      services.IQuarantineService
+	migrationService       services.IMigrationService
+	jiraService            services.IJiraService
+	codeStatsService       services.ICodeStatsService
+	googleCalendarService  services.IGoogleCalendarService
 )
 
 // TODO: Refactor entire project to be structured after business domains
@@ -99,6 +173,14 @@ var (
 
 // @BasePath /api
 func main() {
+	// Validate the configuration instead of starting the server, if requested ('wakapi config validate').
+	// Has to happen before loading the config, which fails on the first error already.
+	flag.Parse()
+	if flag.Arg(0) == "config" {
+		runConfig(flag.Args()[1:])
+		return
+	}
+
 	config = conf.Load(version)
 
 	// Set log level
@@ -120,10 +202,7 @@ func main() {
 
 	// Connect to database
 	var err error
-	db, err = gorm.Open(config.Db.GetDialector(), &gorm.Config{Logger: gormLogger})
-	if config.Db.IsSQLite() {
-		db.Exec("PRAGMA foreign_keys = ON;")
-	}
+	db, err = gorm.Open(config.Db.GetDialector(), &gorm.Config{Logger: gormLogger}) // sqlite pragmas are set via connection string
 
 	if config.IsDev() {
 		db = db.Debug()
@@ -137,6 +216,17 @@ func main() {
 	}
 	defer sqlDb.Close()
 
+	// Measure queries per repository method, to be exposed along with other metrics
+	queryMetrics = repositories.NewQueryMetrics()
+	if config.Security.ExposeMetrics {
+		if err := db.Use(queryMetrics); err != nil {
+			logbuch.Error("failed to register query metrics - %v", err)
+		}
+	}
+
+	// Count requests per api route, e.g. to tell whether deprecated ones are still in use
+	routeMetrics = middlewares.NewRouteMetrics()
+
 	// Migrate database schema
 	migrations.Run(db, config)
 
@@ -146,58 +236,201 @@ func main() {
 	userRepository = repositories.NewUserRepository(db)
 	languageMappingRepository = repositories.NewLanguageMappingRepository(db)
 	projectLabelRepository = repositories.NewProjectLabelRepository(db)
+	projectRepoRepository = repositories.NewProjectRepoRepository(db)
+	projectBudgetRepository = repositories.NewProjectBudgetRepository(db)
+	goalRepository = repositories.NewGoalRepository(db)
+	filterSetRepository = repositories.NewFilterSetRepository(db)
+	embedTokenRepository = repositories.NewEmbedTokenRepository(db)
+	notificationRepository = repositories.NewNotificationPreferenceRepository(db)
+	relayTargetRepository = repositories.NewRelayTargetRepository(db)
+	relayRuleRepository = repositories.NewRelayRuleRepository(db)
+	dayOffRepository = repositories.NewDayOffRepository(db)
+	achievementRepository = repositories.NewAchievementRepository(db)
 	summaryRepository = repositories.NewSummaryRepository(db)
 	keyValueRepository = repositories.NewKeyValueRepository(db)
 	diagnosticsRepository = repositories.NewDiagnosticsRepository(db)
+	backupRepository = repositories.NewBackupRepository(db)
+	jiraWorklogRepository = repositories.NewJiraWorklogRepository(db)
+	calendarEventRepository = repositories.NewCalendarEventRepository(db)
+	manualTimeEntryRepository = repositories.NewManualTimeEntryRepository(db)
+	summaryInvalidationRepository = repositories.NewSummaryInvalidationRepository(db)
+	archivedReportRepository = repositories.NewArchivedReportRepository(db)
+	tombstoneRepository = repositories.NewTombstoneRepository(db)
+	remoteAccountRepository = repositories.NewRemoteAccountRepository(db)
+	leaderboardRepository = repositories.NewLeaderboardRepository(db)
+	durationRepository = repositories.NewDurationRepository(db)
+	quarantineRepository = repositories.NewQuarantineRepository(db)
 
 	// Services
 	mailService = mail.NewMailService()
+	jobService = services.NewJobService()
 	aliasService = services.NewAliasService(aliasRepository)
-	userService = services.NewUserService(mailService, userRepository)
+	notificationService = services.NewNotificationService(notificationRepository)
+	userService = services.NewUserService(mailService, notificationService, userRepository)
 	languageMappingService = services.NewLanguageMappingService(languageMappingRepository)
 	projectLabelService = services.NewProjectLabelService(projectLabelRepository)
-	heartbeatService = services.NewHeartbeatService(heartbeatRepository, languageMappingService)
-	durationService = services.NewDurationService(heartbeatService)
-	summaryService = services.NewSummaryService(summaryRepository, durationService, aliasService, projectLabelService)
-	aggregationService = services.NewAggregationService(userService, summaryService, heartbeatService)
+	projectRepoService = services.NewProjectRepoService(projectRepoRepository)
+	dayOffService = services.NewDayOffService(dayOffRepository)
+	undoService = services.NewUndoService(heartbeatRepository, tombstoneRepository, jobService)
+	heartbeatService = services.NewHeartbeatService(heartbeatRepository, languageMappingService, jobService, undoService)
+	heartbeatScriptService = services.NewHeartbeatScriptService()
+	durationService = services.NewMaterializedDurationService(services.NewDurationService(heartbeatService), durationRepository)
+	manualTimeEntryService = services.NewManualTimeEntryService(manualTimeEntryRepository)
+	summaryService = services.NewSummaryService(summaryRepository, durationService, aliasService, projectLabelService, manualTimeEntryService)
 	keyValueService = services.NewKeyValueService(keyValueRepository)
-	reportService = services.NewReportService(summaryService, userService, mailService)
+	languageMetaService = services.NewLanguageMetaService(keyValueService)
+	aggregationService = services.NewAggregationService(summaryInvalidationRepository, userService, summaryService, heartbeatService, jobService, keyValueService)
 	diagnosticsService = services.NewDiagnosticsService(diagnosticsRepository)
-	miscService = services.NewMiscService(userService, summaryService, keyValueService)
+	miscService = services.NewMiscService(userService, summaryService, keyValueService, jobService)
+	doctorService = services.NewDoctorService(userService, summaryService, aggregationService, summaryRepository, aliasRepository, jobService)
+	userBatchService = services.NewUserBatchService(userService, mailService)
+	storageService = storage.NewStorageService()
+	exportService = services.NewExportService(heartbeatService, storageService, jobService)
+	reassignmentService = services.NewReassignmentService(heartbeatRepository, jobService, undoService)
+	backupService = services.NewBackupService(backupRepository, storageService, jobService)
+	overtimeService = services.NewOvertimeService(summaryService, dayOffService)
+	comparisonService = services.NewComparisonService(userService, summaryService)
+	remoteAccountService = services.NewRemoteAccountService(remoteAccountRepository)
+	leaderboardService = services.NewLeaderboardService(leaderboardRepository, userService, summaryService, jobService)
+	achievementService = services.NewAchievementService(achievementRepository, summaryRepository, dayOffService)
+	yearReviewService = services.NewYearReviewService(summaryService, summaryRepository, durationService, dayOffService, languageMetaService)
+	reportService = services.NewReportService(summaryService, userService, mailService, notificationService, storageService, jobService, overtimeService, archivedReportRepository)
+	projectBudgetService = services.NewProjectBudgetService(projectBudgetRepository, userService, summaryService, mailService, notificationService)
+	goalService = services.NewGoalService(goalRepository, summaryService, dayOffService)
+	inactivityService = services.NewInactivityService(userService, heartbeatService, mailService, notificationService, jobService)
+	relayTargetService = services.NewRelayTargetService(relayTargetRepository, notificationService)
+	relayRuleService = services.NewRelayRuleService(relayRuleRepository, projectLabelService)
+	statsCacheService = services.NewStatsCacheService()
+	settingsService = services.NewSettingsService(aliasService, languageMappingService, projectLabelService, relayRuleService, goalService)
+	quotaService = services.NewQuotaService()
+	rateLimitService = services.NewRateLimitService()
+	storageQuotaService = services.NewStorageQuotaService(userService, heartbeatService, jobService)
+	clockSkewService = services.NewClockSkewService()
+	maintenanceService = services.NewMaintenanceService(keyValueService)
+	demoService = services.NewDemoService(userService, heartbeatService, aggregationService, keyValueService, jobService)
+	importService = services.NewImportService(heartbeatService, heartbeatScriptService, keyValueService)
+	filterSetService = services.NewFilterSetService(filterSetRepository)
+	embedTokenService = services.NewEmbedTokenService(embedTokenRepository)
+	avatarService = services.NewAvatarService(userService, storageService)
+	ticketService = services.NewTicketService(summaryService)
+	togglService = services.NewTogglService(durationService, aliasService)
+	timesheetService = services.NewTimesheetService(durationService, aliasService)
+	sessionService = services.NewSessionService(durationService, aliasService)
+	quarantineService = services.NewQuarantineService(quarantineRepository, heartbeatRepository, userService)
+	migrationService = services.NewMigrationService(userService, heartbeatService, settingsService, keyValueService, jobService)
+	jiraService = services.NewJiraService(jiraWorklogRepository, userService, ticketService, jobService)
+	codeStatsService = services.NewCodeStatsService(userService, summaryService, keyValueService, jobService)
+	googleCalendarService = services.NewGoogleCalendarService(calendarEventRepository, userService, sessionService, jobService)
+
+	// Run data integrity check instead of starting the server, if requested (e.g. 'wakapi doctor -repair')
+	if flag.Arg(0) == "doctor" {
+		runDoctor(flag.Args()[1:])
+		return
+	}
+
+	// Create users from a csv file instead of starting the server, if requested (e.g. 'wakapi create-users -file users.csv')
+	if flag.Arg(0) == "create-users" {
+		runCreateUsers(flag.Args()[1:])
+		return
+	}
+
+	// Run an admin command instead of starting the server, if requested (e.g. 'wakapi reset-password -user alice')
+	if flag.NArg() > 0 && runUserCommand(flag.Arg(0), flag.Args()[1:]) {
+		return
+	}
 
 	// Schedule background tasks
 	if !config.QuickStart {
 		go aggregationService.Schedule()
 		go miscService.ScheduleCountTotalTime()
 		go reportService.Schedule()
+		go exportService.Schedule()
+		go undoService.Schedule()
+		go backupService.Schedule()
+		go jiraService.Schedule()
+		go codeStatsService.Schedule()
+		go googleCalendarService.Schedule()
+		go projectBudgetService.Schedule()
+		go inactivityService.Schedule()
+		go leaderboardService.Schedule()
+		go storageQuotaService.Schedule()
+		go demoService.Schedule()
 	}
 
-	routes.Init()
+	routes.Init(maintenanceService)
 
 	// API Handlers
 	healthApiHandler := api.NewHealthApiHandler(db)
-	heartbeatApiHandler := api.NewHeartbeatApiHandler(userService, heartbeatService, languageMappingService)
-	summaryApiHandler := api.NewSummaryApiHandler(userService, summaryService)
-	metricsHandler := api.NewMetricsHandler(userService, summaryService, heartbeatService, keyValueService)
+	heartbeatApiHandler := api.NewHeartbeatApiHandler(userService, heartbeatService, languageMappingService, heartbeatScriptService, relayTargetService, relayRuleService, quotaService, storageQuotaService, clockSkewService, importService)
+	summaryApiHandler := api.NewSummaryApiHandler(userService, summaryService, aggregationService, filterSetService, remoteAccountService)
+	metricsHandler := api.NewMetricsHandler(userService, summaryService, heartbeatService, keyValueService, queryMetrics, routeMetrics)
 	diagnosticsHandler := api.NewDiagnosticsApiHandler(userService, diagnosticsService)
-	avatarHandler := api.NewAvatarHandler()
+	avatarHandler := api.NewAvatarHandler(avatarService)
+	manualTimeEntryApiHandler := api.NewManualTimeEntryApiHandler(userService, manualTimeEntryService)
+	doctorApiHandler := api.NewDoctorApiHandler(userService, doctorService)
+	userBatchApiHandler := api.NewUserBatchApiHandler(userService, userBatchService)
+	userMergeApiHandler := api.NewUserMergeApiHandler(userService)
+	quotaApiHandler := api.NewQuotaApiHandler(userService, quotaService, storageQuotaService)
+	heartbeatScriptApiHandler := api.NewHeartbeatScriptApiHandler(userService, heartbeatScriptService)
+	rateLimitApiHandler := api.NewRateLimitApiHandler(userService, quotaService)
+	languageApiHandler := api.NewLanguageApiHandler(userService, languageMetaService)
+	maintenanceApiHandler := api.NewMaintenanceApiHandler(userService, maintenanceService)
+	debugApiHandler := api.NewDebugApiHandler(userService, heartbeatService)
+	routeStatsApiHandler := api.NewRouteStatsApiHandler(userService, routeMetrics)
+	jobApiHandler := api.NewJobApiHandler(userService, jobService)
+	importApiHandler := api.NewImportApiHandler(userService, importService)
+	importScopeApiHandler := api.NewImportScopeApiHandler(userService)
+	ticketApiHandler := api.NewTicketApiHandler(userService, ticketService)
+	togglApiHandler := api.NewTogglApiHandler(userService, togglService)
+	budgetApiHandler := api.NewBudgetApiHandler(userService, projectBudgetService)
+	goalApiHandler := api.NewGoalApiHandler(userService, goalService)
+	relayTargetApiHandler := api.NewRelayTargetApiHandler(userService, relayTargetService)
+	relayRuleApiHandler := api.NewRelayRuleApiHandler(userService, relayRuleService)
+	settingsApiHandler := api.NewSettingsApiHandler(userService, settingsService)
+	migrationApiHandler := api.NewMigrationApiHandler(userService, migrationService)
+	reportApiHandler := api.NewReportApiHandler(userService, reportService)
+	reassignmentApiHandler := api.NewReassignmentApiHandler(userService, reassignmentService)
+	undoApiHandler := api.NewUndoApiHandler(userService, undoService)
+	filterSetApiHandler := api.NewFilterSetApiHandler(userService, filterSetService)
+	embedTokenApiHandler := api.NewEmbedTokenApiHandler(userService, embedTokenService)
+	preferencesApiHandler := api.NewPreferencesApiHandler(userService)
+	overtimeApiHandler := api.NewOvertimeApiHandler(userService, overtimeService)
+	comparisonApiHandler := api.NewComparisonApiHandler(userService, comparisonService)
+	instanceApiHandler := api.NewInstanceApiHandler(miscService)
+	leaderboardApiHandler := api.NewLeaderboardApiHandler(leaderboardService)
+	remoteAccountApiHandler := api.NewRemoteAccountApiHandler(userService, remoteAccountService)
+	timesheetApiHandler := api.NewTimesheetApiHandler(userService, timesheetService)
+	sessionApiHandler := api.NewSessionApiHandler(userService, sessionService)
+	quarantineApiHandler := api.NewQuarantineApiHandler(userService, quarantineService)
+	achievementApiHandler := api.NewAchievementApiHandler(userService, achievementService)
+	yearReviewApiHandler := api.NewYearReviewApiHandler(userService, yearReviewService)
+	storageApiHandler := api.NewStorageApiHandler(storageService)
+	scimApiHandler := api.NewScimApiHandler(userService)
 
 	// Compat Handlers
 	wakatimeV1StatusBarHandler := wtV1Routes.NewStatusBarHandler(userService, summaryService)
 	wakatimeV1AllHandler := wtV1Routes.NewAllTimeHandler(userService, summaryService)
-	wakatimeV1SummariesHandler := wtV1Routes.NewSummariesHandler(userService, summaryService)
-	wakatimeV1StatsHandler := wtV1Routes.NewStatsHandler(userService, summaryService)
+	wakatimeV1SummariesHandler := wtV1Routes.NewSummariesHandler(userService, summaryService, embedTokenService)
+	wakatimeV1StatsHandler := wtV1Routes.NewStatsHandler(userService, summaryService, dayOffService, filterSetService, statsCacheService, embedTokenService)
 	wakatimeV1UsersHandler := wtV1Routes.NewUsersHandler(userService, heartbeatService)
-	wakatimeV1ProjectsHandler := wtV1Routes.NewProjectsHandler(userService, heartbeatService)
+	wakatimeV1ProjectsHandler := wtV1Routes.NewProjectsHandler(userService, heartbeatService, projectRepoService)
 	wakatimeV1HeartbeatsHandler := wtV1Routes.NewHeartbeatHandler(userService, heartbeatService)
-	shieldV1BadgeHandler := shieldsV1Routes.NewBadgeHandler(summaryService, userService)
+	wakatimeV1DurationsHandler := wtV1Routes.NewDurationsHandler(userService, durationService)
+	wakatimeV1LeadersHandler := wtV1Routes.NewLeadersHandler(leaderboardService)
+	wakatimeV1GoalsHandler := wtV1Routes.NewGoalsHandler(userService, goalService)
+	wakatimeV1MetaHandler := wtV1Routes.NewMetaHandler(languageMetaService)
+	shieldV1BadgeHandler := shieldsV1Routes.NewBadgeHandler(summaryService, userService, embedTokenService)
 
 	// MVC Handlers
-	summaryHandler := routes.NewSummaryHandler(summaryService, userService)
-	settingsHandler := routes.NewSettingsHandler(userService, heartbeatService, summaryService, aliasService, aggregationService, languageMappingService, projectLabelService, keyValueService, mailService)
+	summaryHandler := routes.NewSummaryHandler(summaryService, userService, projectRepoService, achievementService, filterSetService, languageMetaService)
+	settingsHandler := routes.NewSettingsHandler(userService, heartbeatService, summaryService, aliasService, aggregationService, languageMappingService, projectLabelService, manualTimeEntryService, keyValueService, mailService, jobService, heartbeatScriptService, exportService, avatarService, jiraService, codeStatsService, projectRepoService, googleCalendarService, projectBudgetService, goalService, dayOffService, notificationService, relayTargetService, relayRuleService, quotaService, storageQuotaService, clockSkewService, maintenanceService, importService)
 	homeHandler := routes.NewHomeHandler(keyValueService)
-	loginHandler := routes.NewLoginHandler(userService, mailService)
+	loginHandler := routes.NewLoginHandler(userService, mailService, demoService)
 	imprintHandler := routes.NewImprintHandler(keyValueService)
+	reportsHandler := routes.NewReportsHandler(userService, reportService)
+	teamHandler := routes.NewTeamHandler(userService, comparisonService)
+	instanceHandler := routes.NewInstanceHandler(miscService)
+	leaderboardHandler := routes.NewLeaderboardHandler(leaderboardService)
 
 	// Other Handlers
 	relayHandler := relay.NewRelayHandler()
@@ -217,18 +450,31 @@ func main() {
 
 	// Globally used middlewares
 	router.Use(middlewares.NewPrincipalMiddleware())
+	router.Use(middlewares.NewRequestCacheMiddleware())
+	router.Use(middlewares.NewRequestIdMiddleware())
 	router.Use(middlewares.NewLoggingMiddleware(logbuch.Info, []string{"/assets", "/api/health"}))
 	router.Use(handlers.RecoveryHandler())
-	if config.Sentry.Dsn != "" {
+	router.Use(middlewares.NewMaintenanceMiddleware(maintenanceService, []string{"/login", "/logout", "/settings/maintenance", "/api/admin/maintenance"}))
+	router.Use(middlewares.NewDemoMiddleware(userService, demoService, []string{"/login", "/logout"}))
+	switch config.GetErrorReportingDriver() {
+	case conf.ErrorReportingSentry:
 		router.Use(middlewares.NewSentryMiddleware())
+	case conf.ErrorReportingWebhook:
+		router.Use(middlewares.NewErrorReportingMiddleware())
 	}
-	rootRouter.Use(middlewares.NewSecurityMiddleware())
+	router.Use(middlewares.NewSecurityMiddleware([]string{"/api/compat/shields/"})) // badges may be embedded into other websites
+	apiRouter.Use(routeMetrics.Handler)
+	apiRouter.Use(middlewares.NewRateLimitMiddleware(userService, rateLimitService, []string{"/api/health"}))
 
 	// Route registrations
 	homeHandler.RegisterRoutes(rootRouter)
 	loginHandler.RegisterRoutes(rootRouter)
 	imprintHandler.RegisterRoutes(rootRouter)
 	summaryHandler.RegisterRoutes(rootRouter)
+	reportsHandler.RegisterRoutes(rootRouter)
+	teamHandler.RegisterRoutes(rootRouter)
+	instanceHandler.RegisterRoutes(rootRouter)
+	leaderboardHandler.RegisterRoutes(rootRouter)
 	settingsHandler.RegisterRoutes(rootRouter)
 	relayHandler.RegisterRoutes(rootRouter)
 
@@ -239,6 +485,46 @@ func main() {
 	metricsHandler.RegisterRoutes(apiRouter)
 	diagnosticsHandler.RegisterRoutes(apiRouter)
 	avatarHandler.RegisterRoutes(apiRouter)
+	manualTimeEntryApiHandler.RegisterRoutes(apiRouter)
+	doctorApiHandler.RegisterRoutes(apiRouter)
+	userBatchApiHandler.RegisterRoutes(apiRouter)
+	userMergeApiHandler.RegisterRoutes(apiRouter)
+	quotaApiHandler.RegisterRoutes(apiRouter)
+	heartbeatScriptApiHandler.RegisterRoutes(apiRouter)
+	rateLimitApiHandler.RegisterRoutes(apiRouter)
+	languageApiHandler.RegisterRoutes(apiRouter)
+	maintenanceApiHandler.RegisterRoutes(apiRouter)
+	debugApiHandler.RegisterRoutes(apiRouter)
+	routeStatsApiHandler.RegisterRoutes(apiRouter)
+	jobApiHandler.RegisterRoutes(apiRouter)
+	importApiHandler.RegisterRoutes(apiRouter)
+	importScopeApiHandler.RegisterRoutes(apiRouter)
+	ticketApiHandler.RegisterRoutes(apiRouter)
+	togglApiHandler.RegisterRoutes(apiRouter)
+	budgetApiHandler.RegisterRoutes(apiRouter)
+	goalApiHandler.RegisterRoutes(apiRouter)
+	relayTargetApiHandler.RegisterRoutes(apiRouter)
+	relayRuleApiHandler.RegisterRoutes(apiRouter)
+	filterSetApiHandler.RegisterRoutes(apiRouter)
+	embedTokenApiHandler.RegisterRoutes(apiRouter)
+	preferencesApiHandler.RegisterRoutes(apiRouter)
+	settingsApiHandler.RegisterRoutes(apiRouter)
+	migrationApiHandler.RegisterRoutes(apiRouter)
+	reportApiHandler.RegisterRoutes(apiRouter)
+	reassignmentApiHandler.RegisterRoutes(apiRouter)
+	undoApiHandler.RegisterRoutes(apiRouter)
+	overtimeApiHandler.RegisterRoutes(apiRouter)
+	comparisonApiHandler.RegisterRoutes(apiRouter)
+	instanceApiHandler.RegisterRoutes(apiRouter)
+	leaderboardApiHandler.RegisterRoutes(apiRouter)
+	remoteAccountApiHandler.RegisterRoutes(apiRouter)
+	timesheetApiHandler.RegisterRoutes(apiRouter)
+	sessionApiHandler.RegisterRoutes(apiRouter)
+	quarantineApiHandler.RegisterRoutes(apiRouter)
+	achievementApiHandler.RegisterRoutes(apiRouter)
+	yearReviewApiHandler.RegisterRoutes(apiRouter)
+	storageApiHandler.RegisterRoutes(apiRouter)
+	scimApiHandler.RegisterRoutes(apiRouter)
 	wakatimeV1StatusBarHandler.RegisterRoutes(apiRouter)
 	wakatimeV1AllHandler.RegisterRoutes(apiRouter)
 	wakatimeV1SummariesHandler.RegisterRoutes(apiRouter)
@@ -246,6 +532,10 @@ func main() {
 	wakatimeV1UsersHandler.RegisterRoutes(apiRouter)
 	wakatimeV1ProjectsHandler.RegisterRoutes(apiRouter)
 	wakatimeV1HeartbeatsHandler.RegisterRoutes(apiRouter)
+	wakatimeV1DurationsHandler.RegisterRoutes(apiRouter)
+	wakatimeV1LeadersHandler.RegisterRoutes(apiRouter)
+	wakatimeV1GoalsHandler.RegisterRoutes(apiRouter)
+	wakatimeV1MetaHandler.RegisterRoutes(apiRouter)
 	shieldV1BadgeHandler.RegisterRoutes(apiRouter)
 
 	// Static Routes
@@ -386,3 +676,280 @@ func listen(handler http.Handler) {
 
 	<-make(chan interface{}, 1)
 }
+
+func runDoctor(args []string) {
+	doctorFlags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	repair := doctorFlags.Bool("repair", false, "delete orphaned data and regenerate inconsistent summaries")
+	days := doctorFlags.Int("days", 30, fmt.Sprintf("number of past days to check summaries for (at most %d)", services.DoctorMaxDays))
+	doctorFlags.Parse(args)
+
+	report, err := doctorService.Check(*days, *repair)
+	if err != nil {
+		logbuch.Fatal("data integrity check failed - %v", err)
+	}
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	os.Stdout.Write(append(out, '\n'))
+
+	if report.IsHealthy() {
+		logbuch.Info("no inconsistencies found")
+	} else if !*repair {
+		logbuch.Warn("found inconsistencies, run 'wakapi doctor -repair' to fix them")
+	}
+}
+
+func runCreateUsers(args []string) {
+	createFlags := flag.NewFlagSet("create-users", flag.ExitOnError)
+	file := createFlags.String("file", "", "csv file with rows of the form 'username,email[,api_key]'")
+	sendMail := createFlags.Bool("mail", true, "mail users their credentials")
+	createFlags.Parse(args)
+
+	if *file == "" {
+		logbuch.Fatal("no csv file given, run 'wakapi create-users -file users.csv'")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		logbuch.Fatal("failed to open '%s' - %v", *file, err)
+	}
+	defer f.Close()
+
+	entries, err := models.ParseUserBatchCsv(f)
+	if err != nil {
+		logbuch.Fatal("failed to parse '%s' - %v", *file, err)
+	}
+
+	report := userBatchService.Create(entries, *sendMail)
+
+	out, _ := json.MarshalIndent(report, "", "  ")
+	os.Stdout.Write(append(out, '\n'))
+}
+
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "validate" {
+		logbuch.Fatal("unknown command, run 'wakapi config validate'")
+	}
+
+	cfg, err := conf.Read(version)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	errs, warnings := cfg.Validate()
+
+	// check connectivity, as a misconfigured database or mail server would otherwise only be noticed at runtime
+	if err := pingDatabase(cfg); err != nil {
+		errs = append(errs, errors.New(fmt.Sprintf("failed to connect to %s database - %v", cfg.Db.Type, err)))
+	}
+
+	if cfg.Mail.Enabled && cfg.Mail.Provider == conf.MailProviderSmtp && cfg.Mail.Smtp.Host != "" {
+		addr := net.JoinHostPort(cfg.Mail.Smtp.Host, strconv.Itoa(int(cfg.Mail.Smtp.Port)))
+		if conn, err := net.DialTimeout("tcp", addr, 10*time.Second); err != nil {
+			errs = append(errs, errors.New(fmt.Sprintf("failed to connect to smtp server at %s - %v", addr, err)))
+		} else {
+			conn.Close()
+		}
+	}
+
+	for _, w := range warnings {
+		fmt.Printf("warning: %v\n", w)
+	}
+	for _, e := range errs {
+		fmt.Printf("error: %v\n", e)
+	}
+
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+	fmt.Printf("configuration is valid (%d warnings)\n", len(warnings))
+}
+
+func pingDatabase(cfg *conf.Config) error {
+	db, err := gorm.Open(cfg.Db.GetDialector(), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return err
+	}
+	sqlDb, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDb.Close()
+	return sqlDb.Ping()
+}
+
+// userCommand holds the flags of one of the user administration commands
+type userCommand struct {
+	name     string
+	user     string
+	email    string
+	password string
+	admin    bool
+	revoke   bool
+	purge    bool
+	from     string
+	into     string
+	keepKey  bool
+	dryRun   bool
+}
+
+// parseUserCommand parses and validates the flags of a user administration command, returning nil, if the given one is none of them
+func parseUserCommand(command string, args []string) (*userCommand, error) {
+	cmd := &userCommand{name: command}
+	cmdFlags := flag.NewFlagSet(command, flag.ContinueOnError)
+
+	switch command {
+	case "create-user":
+		cmdFlags.StringVar(&cmd.user, "user", "", "name of the user to create")
+		cmdFlags.StringVar(&cmd.email, "email", "", "e-mail address of the user (optional)")
+		cmdFlags.StringVar(&cmd.password, "password", "", "password of the user (random, if empty)")
+		cmdFlags.BoolVar(&cmd.admin, "admin", false, "whether to make the user an admin")
+	case "reset-password":
+		cmdFlags.StringVar(&cmd.user, "user", "", "name of the user")
+		cmdFlags.StringVar(&cmd.password, "password", "", "new password (random, if empty)")
+	case "regenerate-key":
+		cmdFlags.StringVar(&cmd.user, "user", "", "name of the user")
+	case "promote-admin":
+		cmdFlags.StringVar(&cmd.user, "user", "", "name of the user")
+		cmdFlags.BoolVar(&cmd.revoke, "revoke", false, "revoke admin privileges instead")
+	case "delete-user":
+		cmdFlags.StringVar(&cmd.user, "user", "", "name of the user")
+		cmdFlags.BoolVar(&cmd.purge, "purge", false, "delete the user along with all of its data instead of only deactivating it")
+	case "merge-users":
+		cmdFlags.StringVar(&cmd.from, "from", "", "name of the user to merge, deleted afterwards")
+		cmdFlags.StringVar(&cmd.into, "into", "", "name of the user to merge into")
+		cmdFlags.BoolVar(&cmd.keepKey, "keep-key", false, "have the remaining user take over the merged user's api key")
+		cmdFlags.BoolVar(&cmd.dryRun, "dry-run", false, "only report what would be done")
+	default:
+		return nil, nil
+	}
+
+	if err := cmdFlags.Parse(args); err != nil {
+		return nil, err
+	}
+	// flags following a positional argument are not parsed, e.g. -purge in 'delete-user alice -purge', and must not be ignored silently
+	if cmdFlags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument '%s', all arguments have to be passed as flags", cmdFlags.Arg(0))
+	}
+
+	if command == "merge-users" {
+		if cmd.from == "" || cmd.into == "" {
+			return nil, errors.New("no users given, pass them via -from and -into")
+		}
+		if cmd.from == cmd.into {
+			return nil, errors.New("cannot merge a user with itself")
+		}
+	} else if cmd.user == "" {
+		return nil, errors.New("no user given, pass it via -user")
+	}
+
+	return cmd, nil
+}
+
+// runUserCommand runs one of the user administration commands, returning false, if the given one is none of them
+func runUserCommand(command string, args []string) bool {
+	cmd, err := parseUserCommand(command, args)
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		logbuch.Fatal("invalid arguments - %v", err)
+	}
+	if cmd == nil {
+		return false
+	}
+
+	switch cmd.name {
+	case "create-user":
+		if cmd.password == "" {
+			cmd.password = services.GeneratePassword()
+			fmt.Printf("password: %s\n", cmd.password)
+		}
+
+		signup := &models.Signup{Username: cmd.user, Email: cmd.email, Password: cmd.password, PasswordRepeat: cmd.password, Location: "Local"}
+		if !signup.IsValid() {
+			logbuch.Fatal("invalid parameters, a user name is required and passwords must be at least 6 characters long")
+		}
+
+		user, created, err := userService.CreateOrGet(signup, cmd.admin)
+		if err != nil {
+			logbuch.Fatal("failed to create user '%s' - %v", cmd.user, err)
+		}
+		if !created {
+			logbuch.Fatal("user '%s' already exists", user.ID)
+		}
+		fmt.Printf("api key: %s\n", user.ApiKey)
+		logbuch.Info("created user '%s'", user.ID)
+
+	case "reset-password":
+		user := mustGetUser(cmd.user)
+		if cmd.password == "" {
+			cmd.password = services.GeneratePassword()
+			fmt.Printf("password: %s\n", cmd.password)
+		}
+		if _, err := userService.SetPassword(user, cmd.password); err != nil {
+			logbuch.Fatal("failed to reset password of user '%s' - %v", user.ID, err)
+		}
+		logbuch.Info("reset password of user '%s'", user.ID)
+
+	case "regenerate-key":
+		user, err := userService.ResetApiKey(mustGetUser(cmd.user))
+		if err != nil {
+			logbuch.Fatal("failed to regenerate api key of user '%s' - %v", cmd.user, err)
+		}
+		fmt.Printf("api key: %s\n", user.ApiKey)
+		logbuch.Info("regenerated api key of user '%s', the previous one is no longer valid", user.ID)
+
+	case "promote-admin":
+		user := mustGetUser(cmd.user)
+		if user.IsAdmin == !cmd.revoke {
+			logbuch.Info("nothing to do for user '%s'", user.ID)
+			break
+		}
+		if _, err := userService.SetAdmin(user, !cmd.revoke); err != nil {
+			logbuch.Fatal("failed to update admin privileges of user '%s' - %v", user.ID, err)
+		}
+		logbuch.Info("user '%s' is admin: %v", user.ID, user.IsAdmin)
+
+	case "delete-user":
+		user := mustGetUser(cmd.user)
+		if !cmd.purge {
+			if _, err := userService.SetDeactivated(user, true); err != nil {
+				logbuch.Fatal("failed to deactivate user '%s' - %v", user.ID, err)
+			}
+			logbuch.Info("deactivated user '%s', run with -purge to delete all of its data", user.ID)
+			break
+		}
+		if err := userService.Delete(user); err != nil {
+			logbuch.Fatal("failed to delete user '%s' - %v", user.ID, err)
+		}
+		time.Sleep(time.Second) // give asynchronous clean-ups (e.g. of avatars) a chance to finish before exiting
+		logbuch.Info("deleted user '%s' along with all of its data", user.ID)
+
+	case "merge-users":
+		source, target := mustGetUser(cmd.from), mustGetUser(cmd.into)
+		report, err := userService.Merge(&models.UserMerge{SourceId: source.ID, TargetId: target.ID, KeepApiKey: cmd.keepKey, DryRun: cmd.dryRun})
+		if err != nil {
+			logbuch.Fatal("failed to merge user '%s' into '%s' - %v", source.ID, target.ID, err)
+		}
+
+		out, _ := json.MarshalIndent(report, "", "  ")
+		os.Stdout.Write(append(out, '\n'))
+		if cmd.dryRun {
+			logbuch.Info("nothing changed, run without -dry-run to merge user '%s' into '%s'", source.ID, target.ID)
+			break
+		}
+		time.Sleep(time.Second) // give asynchronous clean-ups (e.g. of avatars) a chance to finish before exiting
+		logbuch.Info("merged user '%s' into '%s'", source.ID, target.ID)
+	}
+
+	return true
+}
+
+func mustGetUser(username string) *models.User {
+	user, err := userService.GetUserById(username)
+	if err != nil {
+		logbuch.Fatal("user '%s' not found", username)
+	}
+	return user
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserCommand_DeleteUser(t *testing.T) {
+	cmd, err := parseUserCommand("delete-user", []string{"-user", "alice"})
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", cmd.user)
+	assert.False(t, cmd.purge)
+
+	cmd, err = parseUserCommand("delete-user", []string{"-user", "alice", "-purge"})
+	assert.Nil(t, err)
+	assert.True(t, cmd.purge)
+
+	cmd, err = parseUserCommand("delete-user", []string{"-purge", "-user=alice"})
+	assert.Nil(t, err)
+	assert.True(t, cmd.purge)
+
+	// a purge must never be downgraded to a deactivation silently
+	_, err = parseUserCommand("delete-user", []string{"alice", "-purge"})
+	assert.Error(t, err)
+	_, err = parseUserCommand("delete-user", []string{"-user", "alice", "bob", "-purge"})
+	assert.Error(t, err)
+	_, err = parseUserCommand("delete-user", []string{"-user", "alice", "-purge=maybe"})
+	assert.Error(t, err)
+
+	_, err = parseUserCommand("delete-user", []string{"-purge"})
+	assert.Error(t, err)
+	_, err = parseUserCommand("delete-user", []string{})
+	assert.Error(t, err)
+}
+
+func TestParseUserCommand_RegenerateKey(t *testing.T) {
+	cmd, err := parseUserCommand("regenerate-key", []string{"-user", "alice"})
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", cmd.user)
+
+	_, err = parseUserCommand("regenerate-key", []string{})
+	assert.Error(t, err)
+	_, err = parseUserCommand("regenerate-key", []string{"-user", ""})
+	assert.Error(t, err)
+	_, err = parseUserCommand("regenerate-key", []string{"alice"})
+	assert.Error(t, err)
+	_, err = parseUserCommand("regenerate-key", []string{"-user", "alice", "-purge"}) // flag of another command
+	assert.Error(t, err)
+
+	_, err = parseUserCommand("regenerate-key", []string{"-h"})
+	assert.Equal(t, flag.ErrHelp, err)
+}
+
+func TestParseUserCommand_MergeUsers(t *testing.T) {
+	cmd, err := parseUserCommand("merge-users", []string{"-from", "legacy", "-into", "alice", "-dry-run"})
+	assert.Nil(t, err)
+	assert.Equal(t, "legacy", cmd.from)
+	assert.Equal(t, "alice", cmd.into)
+	assert.True(t, cmd.dryRun)
+	assert.False(t, cmd.keepKey)
+
+	_, err = parseUserCommand("merge-users", []string{"-from", "legacy"})
+	assert.Error(t, err)
+	_, err = parseUserCommand("merge-users", []string{"-from", "alice", "-into", "alice"})
+	assert.Error(t, err)
+}
+
+func TestParseUserCommand_Unknown(t *testing.T) {
+	cmd, err := parseUserCommand("doctor", []string{"-repair"})
+	assert.Nil(t, err)
+	assert.Nil(t, cmd)
+}
@@ -14,6 +14,8 @@ import (
 const (
 	// queryApiKey is the query parameter name for api key.
 	queryApiKey = "api_key"
+	// queryEmbedToken is the query parameter name for embed tokens, see models.EmbedToken.
+	queryEmbedToken = "embed_token"
 )
 
 var (
@@ -23,6 +25,9 @@ var (
 type AuthenticateMiddleware struct {
 	config           *conf.Config
 	userSrvc         services.IUserService
+	embedTokenSrvc   services.IEmbedTokenService // optional
+	embedScope       string
+	embedTokensOnly  bool // whether to ignore cookies and api keys, e.g. on endpoints meant to be embedded into other web pages
 	optionalForPaths []string
 	redirectTarget   string // optional
 }
@@ -40,6 +45,19 @@ func (m *AuthenticateMiddleware) WithOptionalFor(paths []string) *AuthenticateMi
 	return m
 }
 
+// WithEmbedTokens additionally accepts embed tokens for the given scope, which authenticate the token's owner
+func (m *AuthenticateMiddleware) WithEmbedTokens(embedTokenService services.IEmbedTokenService, scope string) *AuthenticateMiddleware {
+	m.embedTokenSrvc = embedTokenService
+	m.embedScope = scope
+	return m
+}
+
+// WithOnlyEmbedTokens is like WithEmbedTokens, but doesn't accept any other kind of authentication, so that a user's cookie or api key never grants access beyond what the token's scope does
+func (m *AuthenticateMiddleware) WithOnlyEmbedTokens(embedTokenService services.IEmbedTokenService, scope string) *AuthenticateMiddleware {
+	m.embedTokensOnly = true
+	return m.WithEmbedTokens(embedTokenService, scope)
+}
+
 func (m *AuthenticateMiddleware) WithRedirectTarget(path string) *AuthenticateMiddleware {
 	m.redirectTarget = path
 	return m
@@ -53,24 +71,29 @@ func (m *AuthenticateMiddleware) Handler(h http.Handler) http.Handler {
 
 func (m *AuthenticateMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	var user *models.User
-	user, err := m.tryGetUserByCookie(r)
+	err := errEmptyKey
 
-	if err != nil {
-		user, err = m.tryGetUserByApiKeyHeader(r)
+	if !m.embedTokensOnly {
+		user, err = m.tryGetUserByCookie(r)
+		if err != nil {
+			user, err = m.tryGetUserByApiKeyHeader(r)
+		}
+		if err != nil {
+			user, err = m.tryGetUserByApiKeyQuery(r)
+		}
 	}
-	if err != nil {
-		user, err = m.tryGetUserByApiKeyQuery(r)
+	if err != nil && m.embedTokenSrvc != nil {
+		user, err = m.tryGetUserByEmbedToken(r)
 	}
 
-	if err != nil || user == nil {
+	if err != nil || user == nil || user.Deactivated {
 		if m.isOptional(r.URL.Path) {
 			next(w, r)
 			return
 		}
 
 		if m.redirectTarget == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			w.Write([]byte(conf.ErrUnauthorized))
+			utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
 		} else {
 			http.SetCookie(w, m.config.GetClearCookie(models.AuthCookieKey))
 			http.Redirect(w, r, m.redirectTarget, http.StatusFound)
@@ -79,6 +102,7 @@ func (m *AuthenticateMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	}
 
 	SetPrincipal(r, user)
+	GetRequestCache(r).Set(userCacheKey(user.ID), user)
 	next(w, r)
 }
 
@@ -120,13 +144,31 @@ func (m *AuthenticateMiddleware) tryGetUserByApiKeyQuery(r *http.Request) (*mode
 	return user, nil
 }
 
+func (m *AuthenticateMiddleware) tryGetUserByEmbedToken(r *http.Request) (*models.User, error) {
+	token := strings.TrimSpace(r.URL.Query().Get(queryEmbedToken))
+	if token == "" {
+		return nil, errEmptyKey
+	}
+
+	referrer := r.Header.Get("Referer")
+	if referrer == "" {
+		referrer = r.Header.Get("Origin")
+	}
+
+	embedToken, err := m.embedTokenSrvc.Authorize(token, m.embedScope, referrer)
+	if err != nil {
+		return nil, err
+	}
+	return GetUserById(r, m.userSrvc, embedToken.UserID)
+}
+
 func (m *AuthenticateMiddleware) tryGetUserByCookie(r *http.Request) (*models.User, error) {
 	username, err := utils.ExtractCookieAuth(r, m.config)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := m.userSrvc.GetUserById(*username)
+	user, err := GetUserById(r, m.userSrvc, *username)
 	if err != nil {
 		return nil, err
 	}
@@ -1,15 +1,19 @@
 package middlewares
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
 	"github.com/muety/wakapi/mocks"
 	"github.com/muety/wakapi/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestAuthenticateMiddleware_tryGetUserByApiKeyHeader_Success(t *testing.T) {
@@ -100,4 +104,65 @@ func TestAuthenticateMiddleware_tryGetUserByApiKeyQuery_Invalid(t *testing.T) {
 	assert.Nil(t, result)
 }
 
+func TestAuthenticateMiddleware_tryGetUserByEmbedToken(t *testing.T) {
+	testToken := "1b2c8c4e-0f2a-4c7e-9d8e-5e3a6f1b2c3d"
+	testUser := &models.User{ID: "john"}
+
+	params := url.Values{}
+	params.Add("embed_token", testToken)
+	mockRequest := &http.Request{
+		URL: &url.URL{
+			RawQuery: params.Encode(),
+		},
+		Header: http.Header{
+			"Origin": []string{"https://john.github.io"},
+		},
+	}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserById", "john").Return(testUser, nil)
+	embedTokenServiceMock := new(mocks.EmbedTokenServiceMock)
+	embedTokenServiceMock.On("Authorize", testToken, models.EmbedScopeBadge, "https://john.github.io").Return(&models.EmbedToken{UserID: "john"}, nil)
+	embedTokenServiceMock.On("Authorize", testToken, models.EmbedScopeStats, mock.Anything).Return((*models.EmbedToken)(nil), errors.New("embed token not valid for this endpoint"))
+
+	sut := NewAuthenticateMiddleware(userServiceMock).WithEmbedTokens(embedTokenServiceMock, models.EmbedScopeBadge)
+	result, err := sut.tryGetUserByEmbedToken(mockRequest)
+	assert.Nil(t, err)
+	assert.Equal(t, testUser, result)
+
+	sut = NewAuthenticateMiddleware(userServiceMock).WithEmbedTokens(embedTokenServiceMock, models.EmbedScopeStats)
+	result, err = sut.tryGetUserByEmbedToken(mockRequest)
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestAuthenticateMiddleware_ServeHTTP_OnlyEmbedTokens(t *testing.T) {
+	testToken := "1b2c8c4e-0f2a-4c7e-9d8e-5e3a6f1b2c3d"
+	testApiKey := "z5uig69cn9ut93n"
+	testUser := &models.User{ID: "john"}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", testApiKey).Return(testUser, nil)
+	userServiceMock.On("GetUserById", "john").Return(testUser, nil)
+	embedTokenServiceMock := new(mocks.EmbedTokenServiceMock)
+	embedTokenServiceMock.On("Authorize", testToken, models.EmbedScopeBadge, mock.Anything).Return(&models.EmbedToken{UserID: "john"}, nil)
+
+	sut := NewAuthenticateMiddleware(userServiceMock).WithOnlyEmbedTokens(embedTokenServiceMock, models.EmbedScopeBadge).WithOptionalFor([]string{"/"})
+
+	serve := func(query string) *models.User {
+		var principal *models.User
+		r := httptest.NewRequest(http.MethodGet, "/compat/shields/v1/john?"+query, nil)
+		r = r.WithContext(context.WithValue(r.Context(), keyPrincipal, &PrincipalContainer{}))
+		sut.ServeHTTP(httptest.NewRecorder(), r, func(w http.ResponseWriter, r *http.Request) {
+			principal = GetPrincipal(r)
+		})
+		return principal
+	}
+
+	// api keys are ignored, so the request is handled as an anonymous one
+	assert.Nil(t, serve("api_key="+testApiKey))
+	assert.Equal(t, testUser, serve("embed_token="+testToken))
+	userServiceMock.AssertNotCalled(t, "GetUserByKey", testApiKey)
+}
+
 // TODO: somehow test cookie auth function
@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/muety/wakapi/utils"
+)
+
+// BodyLimitMiddleware rejects requests, whose announced content length exceeds the given number of bytes, with 413 right away
+// and makes reading oversized bodies without content length fail with utils.ErrBodyTooLarge, before any handler or other middleware holds them in memory.
+type BodyLimitMiddleware struct {
+	handler  http.Handler
+	maxBytes int64
+}
+
+func NewBodyLimitMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &BodyLimitMiddleware{
+			handler:  h,
+			maxBytes: maxBytes,
+		}
+	}
+}
+
+func (m *BodyLimitMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !utils.LimitBody(r, m.maxBytes) {
+		utils.RespondBodyTooLarge(w, r, m.maxBytes)
+		return
+	}
+	m.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimitMiddleware_ServeHTTP(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err == utils.ErrBodyTooLarge {
+			utils.RespondBodyTooLarge(w, r, 16)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	sut := NewBodyLimitMiddleware(16)(next)
+
+	serve := func(body string, contentLength int64) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/heartbeats", strings.NewReader(body))
+		r.ContentLength = contentLength
+		w := httptest.NewRecorder()
+		sut.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, serve("[]", 2).Code)
+	assert.Equal(t, http.StatusCreated, serve(strings.Repeat("a", 16), -1).Code)
+
+	w := serve(strings.Repeat("a", 17), 17)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), `"max_body_size":16`)
+
+	// chunked bodies without content length are cut off once exceeding the limit
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(strings.Repeat("a", 1024), -1).Code)
+}
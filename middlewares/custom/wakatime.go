@@ -7,36 +7,35 @@ import (
 	"errors"
 	"fmt"
 	"github.com/emvi/logbuch"
-	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
 	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
 	"github.com/patrickmn/go-cache"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 )
 
-const maxFailuresPerDay = 100
-
-// WakatimeRelayMiddleware is a middleware to conditionally relay heartbeats to Wakatime (and other compatible services)
+// WakatimeRelayMiddleware is a middleware to conditionally relay heartbeats to Wakatime and other compatible services, e.g. further Wakapi instances
 type WakatimeRelayMiddleware struct {
-	httpClient   *http.Client
-	hashCache    *cache.Cache
-	failureCache *cache.Cache
-	eventBus     *hub.Hub
+	httpClient *http.Client
+	hashCache  *cache.Cache
+	scriptSrvc services.IHeartbeatScriptService
+	relaySrvc  services.IRelayTargetService
+	ruleSrvc   services.IRelayRuleService
 }
 
-func NewWakatimeRelayMiddleware() *WakatimeRelayMiddleware {
+func NewWakatimeRelayMiddleware(heartbeatScriptService services.IHeartbeatScriptService, relayTargetService services.IRelayTargetService, relayRuleService services.IRelayRuleService) *WakatimeRelayMiddleware {
 	return &WakatimeRelayMiddleware{
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		hashCache:    cache.New(10*time.Minute, 10*time.Minute),
-		failureCache: cache.New(24*time.Hour, 1*time.Hour),
-		eventBus:     config.EventBus(),
+		httpClient: config.NewHttpClient(config.ProxyScopeRelay, 10*time.Second),
+		hashCache:  cache.New(10*time.Minute, 10*time.Minute),
+		scriptSrvc: heartbeatScriptService,
+		relaySrvc:  relayTargetService,
+		ruleSrvc:   relayRuleService,
 	}
 }
 
@@ -57,12 +56,21 @@ func (m *WakatimeRelayMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reque
 	}
 
 	user := middlewares.GetPrincipal(r)
-	if user == nil || user.WakatimeApiKey == "" {
+	if user == nil {
 		return
 	}
 
-	err := m.filterByCache(r)
-	if err != nil {
+	targets, err := m.relaySrvc.GetActive(user)
+	if err != nil || len(targets) == 0 {
+		return
+	}
+
+	// wakatime does not accept newline-delimited json and reading the body would defeat streaming it
+	if routeutils.IsNdjson(r) {
+		return
+	}
+
+	if err := m.filterByCache(r); err != nil {
 		logbuch.Warn("%v", err)
 		return
 	}
@@ -77,32 +85,36 @@ func (m *WakatimeRelayMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reque
 		downstreamInstanceId = originInstanceId
 	}
 
-	headers := http.Header{
-		"X-Machine-Name": r.Header.Values("X-Machine-Name"),
-		"Content-Type":   r.Header.Values("Content-Type"),
-		"Accept":         r.Header.Values("Accept"),
-		"User-Agent":     r.Header.Values("User-Agent"),
-		"X-Origin": []string{
-			fmt.Sprintf("wakapi v%s", config.Get().Version),
-		},
-		"X-Origin-Instance": []string{downstreamInstanceId},
-		"Authorization": []string{
-			fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(user.WakatimeApiKey))),
-		},
-	}
-
-	url := user.WakaTimeURL(config.WakatimeApiUrl) + config.WakatimeApiHeartbeatsBulkUrl
-
-	go m.send(
-		http.MethodPost,
-		url,
-		bytes.NewReader(body),
-		headers,
-		user,
-	)
+	// every target is sent its own copy of the request, so that failures of one do not affect the others
+	for _, target := range targets {
+		headers := http.Header{
+			"X-Machine-Name": r.Header.Values("X-Machine-Name"),
+			"Content-Type":   r.Header.Values("Content-Type"),
+			"Accept":         r.Header.Values("Accept"),
+			"User-Agent":     r.Header.Values("User-Agent"),
+			"X-Origin": []string{
+				fmt.Sprintf("wakapi v%s", config.Get().Version),
+			},
+			"X-Origin-Instance": []string{downstreamInstanceId},
+			"Authorization": []string{
+				fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(target.ApiKey))),
+			},
+		}
+
+		url := strings.TrimSuffix(target.ApiUrl, "/") + config.WakatimeApiHeartbeatsBulkUrl
+
+		go m.send(
+			http.MethodPost,
+			url,
+			bytes.NewReader(body),
+			headers,
+			user,
+			target,
+		)
+	}
 }
 
-func (m *WakatimeRelayMiddleware) send(method, url string, body io.Reader, headers http.Header, forUser *models.User) {
+func (m *WakatimeRelayMiddleware) send(method, url string, body io.Reader, headers http.Header, forUser *models.User, target *models.RelayTarget) {
 	request, err := http.NewRequest(method, url, body)
 	if err != nil {
 		logbuch.Warn("error constructing relayed request - %v", err)
@@ -117,31 +129,24 @@ func (m *WakatimeRelayMiddleware) send(method, url string, body io.Reader, heade
 
 	response, err := m.httpClient.Do(request)
 	if err != nil {
-		logbuch.Warn("error executing relayed request - %v", err)
+		m.relaySrvc.RecordDelivery(forUser, target, err)
 		return
 	}
+	defer response.Body.Close()
 
 	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		logbuch.Warn("failed to relay request for user %s, got status %d", forUser.ID, response.StatusCode)
-
-		// TODO: use leaky bucket instead of expiring cache?
-		if _, found := m.failureCache.Get(forUser.ID); !found {
-			m.failureCache.SetDefault(forUser.ID, 0)
-		}
-		if n, _ := m.failureCache.IncrementInt(forUser.ID, 1); n == maxFailuresPerDay {
-			m.eventBus.Publish(hub.Message{
-				Name:   config.EventWakatimeFailure,
-				Fields: map[string]interface{}{config.FieldUser: forUser, config.FieldPayload: n},
-			})
-		} else if n%10 == 0 {
-			logbuch.Warn("%d / %d failed wakatime heartbeat relaying attempts for user %s within last 24 hours", n, maxFailuresPerDay, forUser.ID)
-		}
+		m.relaySrvc.RecordDelivery(forUser, target, errors.New(fmt.Sprintf("got status %d", response.StatusCode)))
+		return
 	}
+
+	m.relaySrvc.RecordDelivery(forUser, target, nil)
 }
 
 // filterByCache takes an HTTP request, tries to parse the body contents as heartbeats, checks against a local cache for whether a heartbeat has already been relayed before according to its hash and in-place filters these from the request's raw json body.
 // This method operates on the raw body data (interface{}), because serialization of models.Heartbeat is not necessarily identical to what the CLI has actually sent.
 // Purpose of this mechanism is mainly to prevent cyclic relays / loops.
+// Heartbeat scripts are applied as well, so that rejected heartbeats are not relayed and transformed ones are relayed in their transformed shape.
+// Afterwards, heartbeats not passing the user's relay rules are filtered out.
 // Caution: this method does in-place changes to the request.
 func (m *WakatimeRelayMiddleware) filterByCache(r *http.Request) error {
 	heartbeats, err := routeutils.ParseHeartbeats(r)
@@ -160,15 +165,29 @@ func (m *WakatimeRelayMiddleware) filterByCache(r *http.Request) error {
 
 	newData := make([]interface{}, 0, len(heartbeats))
 
+	user := middlewares.GetPrincipal(r)
+
 	for i, hb := range heartbeats {
 		hb = hb.Hashed()
 
-		// we didn't see this particular heartbeat before
-		if _, found := m.hashCache.Get(hb.Hash); !found {
-			m.hashCache.SetDefault(hb.Hash, true)
-			newData = append(newData, rawData.([]interface{})[i])
+		// we did see this particular heartbeat before
+		if _, found := m.hashCache.Get(hb.Hash); found {
+			continue
+		}
+		m.hashCache.SetDefault(hb.Hash, true)
+
+		rawHeartbeat, ok := rawData.([]interface{})[i].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if ok, err := m.scriptSrvc.Apply(user, hb); err != nil || !ok {
 			continue
 		}
+		if !m.ruleSrvc.Accepts(user, hb) {
+			continue
+		}
+		applyToRaw(hb, rawHeartbeat)
+		newData = append(newData, rawHeartbeat)
 	}
 
 	if len(newData) == 0 {
@@ -176,7 +195,6 @@ func (m *WakatimeRelayMiddleware) filterByCache(r *http.Request) error {
 	}
 
 	if len(newData) != len(heartbeats) {
-		user := middlewares.GetPrincipal(r)
 		logbuch.Warn("only relaying %d of %d heartbeats for user %s", len(newData), len(heartbeats), user.ID)
 	}
 
@@ -188,3 +206,14 @@ func (m *WakatimeRelayMiddleware) filterByCache(r *http.Request) error {
 
 	return nil
 }
+
+// applyToRaw updates the raw json representation of a heartbeat by the fields, which might have been changed by a heartbeat script
+func applyToRaw(hb *models.Heartbeat, raw map[string]interface{}) {
+	raw["entity"] = hb.Entity
+	raw["type"] = hb.Type
+	raw["category"] = hb.Category
+	raw["project"] = hb.Project
+	raw["branch"] = hb.Branch
+	raw["language"] = hb.Language
+	raw["is_write"] = hb.IsWrite
+}
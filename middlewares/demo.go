@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+// DemoMiddleware keeps the publicly accessible demo user read-only by rejecting all of its write requests with 403, be it via session cookie or api key,
+// so that visitors can neither alter the showcased data nor lock each other out. Paths starting with one of the exempt prefixes, e.g. to log out, are always let through.
+type DemoMiddleware struct {
+	config         *conf.Config
+	handler        http.Handler
+	userSrvc       services.IUserService
+	demoSrvc       services.IDemoService
+	exemptPrefixes []string
+}
+
+func NewDemoMiddleware(userService services.IUserService, demoService services.IDemoService, exemptPrefixes []string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &DemoMiddleware{
+			config:         conf.Get(),
+			handler:        h,
+			userSrvc:       userService,
+			demoSrvc:       demoService,
+			exemptPrefixes: exemptPrefixes,
+		}
+	}
+}
+
+func (m *DemoMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !m.demoSrvc.IsEnabled() || isSafeMethod(r.Method) || m.isExempt(r.URL.Path) {
+		m.handler.ServeHTTP(w, r)
+		return
+	}
+
+	if m.demoSrvc.IsDemoUser(tryGetUser(r, m.userSrvc, m.config)) {
+		utils.RespondError(w, r, http.StatusForbidden, "the demo user is read-only")
+		return
+	}
+
+	m.handler.ServeHTTP(w, r)
+}
+
+func (m *DemoMiddleware) isExempt(requestPath string) bool {
+	path := strings.ToLower(requestPath)
+	for _, prefix := range m.exemptPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDemoMiddleware_ServeHTTP(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.App.DemoMode = true
+	config.Set(cfg)
+
+	keyValueServiceMock := new(mocks.KeyValueServiceMock)
+	keyValueServiceMock.On("MustGetString", config.KeyDemoUser).Return(&models.KeyStringValue{Key: config.KeyDemoUser, Value: services.DemoUserId})
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", "demo-key").Return(&models.User{ID: services.DemoUserId}, nil)
+	userServiceMock.On("GetUserByKey", "user-key").Return(&models.User{ID: "user1"}, nil)
+
+	demoService := services.NewDemoService(userServiceMock, nil, nil, keyValueServiceMock, nil)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	sut := NewDemoMiddleware(userServiceMock, demoService, []string{"/logout"})(next)
+
+	serve := func(method, path, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		r.Header.Set("Authorization", "Bearer "+base64.StdEncoding.EncodeToString([]byte(key)))
+		w := httptest.NewRecorder()
+		sut.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/api/heartbeats", "demo-key").Code)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, "/api/settings", "demo-key").Code)
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodGet, "/api/summary", "demo-key").Code)
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodPost, "/logout", "demo-key").Code)
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodPost, "/api/heartbeats", "user-key").Code)
+
+	cfg.App.DemoMode = false
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodPost, "/api/heartbeats", "demo-key").Code)
+}
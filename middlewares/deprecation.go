@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+// DeprecationMiddleware marks responses of routes, which are going to be removed, with a Deprecation header (RFC 9745), the date of their removal as Sunset header (RFC 8594)
+// and a link to the route to use instead. Clients can't be expected to read these headers, but they show up in proxies and api tools, while RouteMetrics tells how many requests the route still receives.
+type DeprecationMiddleware struct {
+	handler   http.Handler
+	since     time.Time
+	sunset    time.Time
+	successor string
+}
+
+func NewDeprecationMiddleware(since, sunset time.Time, successor string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &DeprecationMiddleware{
+			handler:   h,
+			since:     since,
+			sunset:    sunset,
+			successor: successor,
+		}
+	}
+}
+
+func (m *DeprecationMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(conf.HeaderDeprecation, fmt.Sprintf("@%d", m.since.Unix()))
+	w.Header().Set(conf.HeaderSunset, m.sunset.UTC().Format(http.TimeFormat))
+	if m.successor != "" {
+		w.Header().Add("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", conf.Get().Server.BasePath, m.successor))
+	}
+	m.handler.ServeHTTP(w, r)
+}
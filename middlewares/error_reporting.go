@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+// ErrorReportingMiddleware reports panics along with their request to the configured error reporting driver and re-panics afterwards, so that recovery is still left to outer handlers.
+// It is only needed for drivers other than sentry, which comes with a middleware of its own.
+type ErrorReportingMiddleware struct {
+	handler http.Handler
+}
+
+func NewErrorReportingMiddleware() func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &ErrorReportingMiddleware{handler: h}
+	}
+}
+
+func (m *ErrorReportingMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		if err := recover(); err != nil {
+			conf.ReportPanic(err, debug.Stack(), r)
+			panic(err)
+		}
+	}()
+	m.handler.ServeHTTP(w, r)
+}
@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"github.com/muety/wakapi/utils"
 	"net/http"
 	"strings"
 )
@@ -23,8 +24,7 @@ func (f *SuffixFilterMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	path := strings.ToLower(r.URL.Path)
 	for _, t := range f.filterTypes {
 		if strings.HasSuffix(path, strings.ToLower(t)) {
-			w.WriteHeader(http.StatusForbidden)
-			w.Write([]byte("403 forbidden"))
+			utils.RespondError(w, r, http.StatusForbidden, "403 forbidden")
 			return
 		}
 	}
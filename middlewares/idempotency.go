@@ -0,0 +1,96 @@
+package middlewares
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotentReplayed  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencyPendingMessage = "a request with the same idempotency key is still being processed"
+)
+
+type idempotentResponse struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// IdempotencyMiddleware remembers successful responses to requests carrying an Idempotency-Key header for the given window
+// and replays them to retries with the same key (per user) instead of processing the request again.
+// Requires the principal to be set by AuthenticateMiddleware before.
+type IdempotencyMiddleware struct {
+	cache *cache.Cache
+}
+
+func NewIdempotencyMiddleware(window time.Duration) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		cache: cache.New(window, window),
+	}
+}
+
+func (m *IdempotencyMiddleware) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.ServeHTTP(w, r, h.ServeHTTP)
+	})
+}
+
+func (m *IdempotencyMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	key := r.Header.Get(HeaderIdempotencyKey)
+	user := GetPrincipal(r)
+	if key == "" || user == nil {
+		next(w, r)
+		return
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		utils.RespondError(w, r, http.StatusBadRequest, fmt.Sprintf("idempotency key must not be longer than %d characters", maxIdempotencyKeyLength))
+		return
+	}
+
+	cacheKey := fmt.Sprintf("%s--%s", user.ID, key)
+
+	// claim the key, so that concurrent retries don't get processed in parallel
+	if err := m.cache.Add(cacheKey, nil, cache.DefaultExpiration); err != nil {
+		if cached, found := m.cache.Get(cacheKey); found && cached != nil {
+			response := cached.(*idempotentResponse)
+			w.Header().Set("Content-Type", response.contentType)
+			w.Header().Set(HeaderIdempotentReplayed, "true")
+			w.WriteHeader(response.status)
+			w.Write(response.body)
+			return
+		}
+		utils.RespondError(w, r, http.StatusConflict, idempotencyPendingMessage)
+		return
+	}
+
+	var stored bool
+	defer func() {
+		// only successful requests are remembered, failed ones may be retried with the same key
+		if !stored {
+			m.cache.Delete(cacheKey)
+		}
+	}()
+
+	var body bytes.Buffer
+	ww := wrapWriter(w)
+	ww.Tee(&body)
+
+	next(ww, r)
+
+	if status := ww.Status(); status >= 200 && status < 300 {
+		m.cache.SetDefault(cacheKey, &idempotentResponse{
+			status:      status,
+			contentType: ww.Header().Get("Content-Type"),
+			body:        body.Bytes(),
+		})
+		stored = true
+	}
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyMiddleware_Replay(t *testing.T) {
+	var calls int
+	next := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-Fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"responses":[[null,201]]}`))
+	}
+
+	newRequest := func(userId, key string, fail bool) *http.Request {
+		container := &PrincipalContainer{}
+		container.SetPrincipal(&models.User{ID: userId})
+		r := httptest.NewRequest(http.MethodPost, "/api/heartbeats", nil).WithContext(context.WithValue(context.Background(), keyPrincipal, container))
+		if key != "" {
+			r.Header.Set(HeaderIdempotencyKey, key)
+		}
+		if fail {
+			r.Header.Set("X-Fail", "true")
+		}
+		return r
+	}
+
+	sut := NewIdempotencyMiddleware(time.Minute)
+
+	// failed requests are not remembered
+	w := httptest.NewRecorder()
+	sut.ServeHTTP(w, newRequest("user1", "key1", true), next)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = httptest.NewRecorder()
+	sut.ServeHTTP(w, newRequest("user1", "key1", false), next)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(HeaderIdempotentReplayed))
+
+	// retry is replayed
+	w = httptest.NewRecorder()
+	sut.ServeHTTP(w, newRequest("user1", "key1", false), next)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"responses":[[null,201]]}`, w.Body.String())
+
+	// keys are scoped per user, requests without key are always processed
+	sut.ServeHTTP(httptest.NewRecorder(), newRequest("user2", "key1", false), next)
+	sut.ServeHTTP(httptest.NewRecorder(), newRequest("user1", "", false), next)
+	sut.ServeHTTP(httptest.NewRecorder(), newRequest("user1", "", false), next)
+
+	assert.Equal(t, 5, calls)
+}
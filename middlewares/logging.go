@@ -3,6 +3,7 @@ package middlewares
 // Borrowed from https://gist.github.com/elithrar/887d162dfd0c539b700ab4049c76e22b
 
 import (
+	conf "github.com/muety/wakapi/config"
 	"io"
 	"net/http"
 	"strings"
@@ -43,7 +44,7 @@ func (lg *LoggingMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	lg.logFunc(
-		"[request] status=%d, method=%s, uri=%s, duration=%v, bytes=%d, addr=%s, user=%s",
+		"[request] status=%d, method=%s, uri=%s, duration=%v, bytes=%d, addr=%s, user=%s, request_id=%s",
 		ww.Status(),
 		r.Method,
 		r.URL.String(),
@@ -51,6 +52,7 @@ func (lg *LoggingMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		ww.BytesWritten(),
 		readUserIP(r),
 		readUserID(r),
+		readRequestID(r),
 	)
 }
 
@@ -72,6 +74,13 @@ func readUserID(r *http.Request) string {
 	return "-"
 }
 
+func readRequestID(r *http.Request) string {
+	if id := r.Header.Get(conf.HeaderRequestId); id != "" {
+		return id
+	}
+	return "-"
+}
+
 // The below writer-wrapping code has been lifted from
 // https://github.com/zenazn/goji/blob/master/web/middleware/logger.go - because
 // it does exactly what is needed, and it's unlikely to change in any
@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+const maintenanceRetryAfterSec = 300
+
+// MaintenanceMiddleware rejects all write requests with 503 while the instance is in maintenance mode, so that backups and migrations can run safely.
+// Reads keep working. Paths starting with one of the exempt prefixes, e.g. those to log in or to disable maintenance mode again, are always let through.
+type MaintenanceMiddleware struct {
+	handler         http.Handler
+	maintenanceSrvc services.IMaintenanceService
+	exemptPrefixes  []string
+}
+
+func NewMaintenanceMiddleware(maintenanceService services.IMaintenanceService, exemptPrefixes []string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &MaintenanceMiddleware{
+			handler:         h,
+			maintenanceSrvc: maintenanceService,
+			exemptPrefixes:  exemptPrefixes,
+		}
+	}
+}
+
+func (m *MaintenanceMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !m.maintenanceSrvc.IsEnabled() || isSafeMethod(r.Method) || m.isExempt(r.URL.Path) {
+		m.handler.ServeHTTP(w, r)
+		return
+	}
+
+	message := "instance is in maintenance mode, please try again later"
+	if state := m.maintenanceSrvc.Get(); state.Message != "" {
+		message = message + " (" + state.Message + ")"
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(maintenanceRetryAfterSec))
+	utils.RespondError(w, r, http.StatusServiceUnavailable, message)
+}
+
+func (m *MaintenanceMiddleware) isExempt(requestPath string) bool {
+	path := strings.ToLower(requestPath)
+	for _, prefix := range m.exemptPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
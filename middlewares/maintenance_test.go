@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMaintenanceMiddleware_ServeHTTP(t *testing.T) {
+	keyValueServiceMock := new(mocks.KeyValueServiceMock)
+	keyValueServiceMock.On("MustGetString", config.KeyMaintenance).Return(&models.KeyStringValue{Key: config.KeyMaintenance})
+	keyValueServiceMock.On("PutString", mock.Anything).Return(nil)
+
+	maintenanceService := services.NewMaintenanceService(keyValueServiceMock)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	sut := NewMaintenanceMiddleware(maintenanceService, []string{"/api/admin/"})(next)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		sut.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodPost, "/api/heartbeats").Code)
+
+	maintenanceService.Enable("backup running")
+
+	w := serve(http.MethodPost, "/api/heartbeats")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "300", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusServiceUnavailable, serve(http.MethodPost, "/settings").Code)
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodGet, "/api/summary").Code)
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodPut, "/api/admin/maintenance").Code)
+}
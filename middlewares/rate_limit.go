@@ -0,0 +1,135 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+const keyRateLimitStatus = "rate_limit_status"
+
+// RateLimitMiddleware limits the number of api requests every client may send per minute and announces the remaining ones via X-RateLimit-* headers.
+// Clients are told apart by their user, if the request carries a valid api key or session cookie, or by their ip address otherwise, so that
+// sending made-up api keys doesn't get around the limit. The ip address is the connection's remote address, while x-real-ip and x-forwarded-for
+// headers are only taken into account if sent by one of the configured trusted proxies, as clients could send made-up ones otherwise.
+// Paths starting with one of the exempt prefixes, e.g. health checks, are never limited.
+type RateLimitMiddleware struct {
+	config         *conf.Config
+	handler        http.Handler
+	userSrvc       services.IUserService
+	rateLimitSrvc  services.IRateLimitService
+	exemptPrefixes []string
+}
+
+func NewRateLimitMiddleware(userService services.IUserService, rateLimitService services.IRateLimitService, exemptPrefixes []string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &RateLimitMiddleware{
+			config:         conf.Get(),
+			handler:        h,
+			userSrvc:       userService,
+			rateLimitSrvc:  rateLimitService,
+			exemptPrefixes: exemptPrefixes,
+		}
+	}
+}
+
+func (m *RateLimitMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !m.rateLimitSrvc.IsEnabled() || m.isExempt(r.URL.Path) {
+		m.handler.ServeHTTP(w, r)
+		return
+	}
+
+	status, ok := m.rateLimitSrvc.Consume(m.clientKey(r))
+	GetRequestCache(r).Set(keyRateLimitStatus, status)
+	utils.SetRateLimitHeaders(w, status, !ok)
+	if !ok {
+		utils.RespondError(w, r, http.StatusTooManyRequests, fmt.Sprintf("rate limit of %d requests per minute exceeded", status.Limit))
+		return
+	}
+
+	m.handler.ServeHTTP(w, r)
+}
+
+func (m *RateLimitMiddleware) clientKey(r *http.Request) string {
+	if user := tryGetUser(r, m.userSrvc, m.config); user != nil {
+		return "user_" + user.ID
+	}
+	return "ip_" + readClientIP(r, m.config)
+}
+
+// tryGetUser resolves the user a request is sent by from a valid api key or session cookie, if any, unlike AuthenticateMiddleware without responding to the client
+func tryGetUser(r *http.Request, userService services.IUserService, config *conf.Config) *models.User {
+	key, err := utils.ExtractBearerAuth(r)
+	if err != nil {
+		key = r.URL.Query().Get(queryApiKey)
+	}
+	if key = strings.TrimSpace(key); key != "" {
+		if user, err := userService.GetUserByKey(key); err == nil {
+			return user
+		}
+		return nil
+	}
+
+	if username, err := utils.ExtractCookieAuth(r, config); err == nil {
+		if user, err := GetUserById(r, userService, *username); err == nil {
+			return user
+		}
+	}
+	return nil
+}
+
+func (m *RateLimitMiddleware) isExempt(requestPath string) bool {
+	path := strings.ToLower(requestPath)
+	for _, prefix := range m.exemptPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRateLimitStatus returns the client's rate limit status as of the current request or nil, if requests are not limited
+func GetRateLimitStatus(r *http.Request) *models.QuotaStatus {
+	if status, ok := GetRequestCache(r).Get(keyRateLimitStatus); ok {
+		return status.(*models.QuotaStatus)
+	}
+	return nil
+}
+
+// readClientIP returns the ip address of the client a request is sent by. Forwarding headers are only respected if the request was sent by
+// a trusted proxy, in which case the first address of the x-forwarded-for chain, read from the right, that isn't a trusted proxy itself is used.
+func readClientIP(r *http.Request, config *conf.Config) string {
+	ip := stripPort(r.RemoteAddr)
+	if !config.Security.IsTrustedProxy(ip) {
+		return ip
+	}
+
+	if realIp := strings.TrimSpace(r.Header.Get("X-Real-Ip")); realIp != "" {
+		return stripPort(realIp)
+	}
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := stripPort(strings.TrimSpace(forwarded[i]))
+		if hop == "" {
+			continue
+		}
+		ip = hop
+		if !config.Security.IsTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
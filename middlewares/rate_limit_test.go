@@ -0,0 +1,113 @@
+package middlewares
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddleware_ServeHTTP(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.App.ApiRateLimitPerMin = 2
+	config.Set(cfg)
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserByKey", "valid-key").Return(&models.User{ID: "user1"}, nil)
+	userServiceMock.On("GetUserByKey", "made-up-key").Return((*models.User)(nil), errors.New("not found"))
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	sut := NewRateLimitMiddleware(userServiceMock, services.NewRateLimitService(), []string{"/api/health"})(next)
+
+	serve := func(path, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = "192.168.0.1:4711"
+		if key != "" {
+			r.Header.Set("Authorization", "Bearer "+base64.StdEncoding.EncodeToString([]byte(key)))
+		}
+		w := httptest.NewRecorder()
+		sut.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/api/summary", "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("X-RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusAccepted, serve("/api/summary", "").Code)
+
+	w = serve("/api/summary", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// made-up keys are counted by ip address
+	assert.Equal(t, http.StatusTooManyRequests, serve("/api/summary", "made-up-key").Code)
+
+	// authenticated users have their own limit
+	w = serve("/api/summary", "valid-key")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+	// exempt paths
+	w = serve("/api/health", "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestRateLimitMiddleware_ServeHTTP_ForwardedFor(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.App.ApiRateLimitPerMin = 2
+	cfg.Security.TrustedProxies = "10.0.0.1, 172.16.0.0/12"
+	config.Set(cfg)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	sut := NewRateLimitMiddleware(new(mocks.UserServiceMock), services.NewRateLimitService(), nil)(next)
+
+	serve := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		sut.ServeHTTP(w, r)
+		return w
+	}
+
+	// made-up headers of clients connecting directly are ignored
+	assert.Equal(t, http.StatusAccepted, serve("192.168.0.1:4711", "1.1.1.1").Code)
+	assert.Equal(t, http.StatusAccepted, serve("192.168.0.1:4711", "2.2.2.2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve("192.168.0.1:4711", "3.3.3.3").Code)
+
+	// behind trusted proxies, the client's address is taken from the header, but not what the client prepended to it
+	assert.Equal(t, http.StatusAccepted, serve("10.0.0.1:4711", "1.1.1.1, 192.168.0.2, 172.16.0.5").Code)
+	assert.Equal(t, http.StatusAccepted, serve("10.0.0.1:4711", "2.2.2.2, 192.168.0.2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve("10.0.0.1:4711", "3.3.3.3, 192.168.0.2").Code)
+	assert.Equal(t, http.StatusAccepted, serve("10.0.0.1:4711", "192.168.0.3").Code)
+}
+
+func TestRateLimitMiddleware_ServeHTTP_Disabled(t *testing.T) {
+	config.Set(&config.Config{})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	sut := NewRateLimitMiddleware(new(mocks.UserServiceMock), services.NewRateLimitService(), nil)(next)
+
+	w := httptest.NewRecorder()
+	sut.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/summary", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+}
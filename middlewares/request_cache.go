@@ -0,0 +1,106 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+)
+
+const keyRequestCache = "request_cache"
+
+// RequestCache holds values looked up while serving a single request, so that middlewares and handlers resolving the same
+// user (or other entities) don't each query the respective service again. Errors are not cached.
+// Sharing settings are part of the user, so they are covered by GetUserById. Aliases are not cached here, because AliasService
+// keeps all of a user's aliases in memory across requests anyway.
+// Like PrincipalContainer, it is injected as a stateful container by one of the outermost middlewares.
+type RequestCache struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+func NewRequestCache() *RequestCache {
+	return &RequestCache{values: make(map[string]interface{})}
+}
+
+// GetOrLoad returns the value cached for the given key or otherwise loads and caches it. A nil cache always loads.
+func (c *RequestCache) GetOrLoad(key string, load func() (interface{}, error)) (interface{}, error) {
+	if c == nil {
+		return load()
+	}
+
+	c.mu.Lock()
+	value, ok := c.values[key]
+	c.mu.Unlock()
+	if ok {
+		return value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.Set(key, value)
+	return value, nil
+}
+
+// Get returns the value cached for the given key, if any
+func (c *RequestCache) Get(key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.values[key]
+	return value, ok
+}
+
+func (c *RequestCache) Set(key string, value interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+}
+
+type RequestCacheMiddleware struct {
+	handler http.Handler
+}
+
+func NewRequestCacheMiddleware() func(handler http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &RequestCacheMiddleware{handler: h}
+	}
+}
+
+func (m *RequestCacheMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := context.WithValue(r.Context(), keyRequestCache, NewRequestCache())
+	m.handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetRequestCache returns the request's cache or nil, if the request did not pass the RequestCacheMiddleware
+func GetRequestCache(r *http.Request) *RequestCache {
+	if c := r.Context().Value(keyRequestCache); c != nil {
+		return c.(*RequestCache)
+	}
+	return nil
+}
+
+// GetUserById looks up a user once per request
+func GetUserById(r *http.Request, userService services.IUserService, userId string) (*models.User, error) {
+	user, err := GetRequestCache(r).GetOrLoad(userCacheKey(userId), func() (interface{}, error) {
+		return userService.GetUserById(userId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user.(*models.User), nil
+}
+
+func userCacheKey(userId string) string {
+	return fmt.Sprintf("user_%s", userId)
+}
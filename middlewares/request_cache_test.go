@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestCacheMiddleware_GetUserById(t *testing.T) {
+	testUser := &models.User{ID: "user1"}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserById", "user1").Return(testUser, nil)
+	userServiceMock.On("GetUserById", "user2").Return((*models.User)(nil), errors.New("not found"))
+
+	handler := NewRequestCacheMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			user, err := GetUserById(r, userServiceMock, "user1")
+			assert.Nil(t, err)
+			assert.Equal(t, testUser, user)
+
+			_, err = GetUserById(r, userServiceMock, "user2")
+			assert.NotNil(t, err)
+		}
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/summary", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/summary", nil))
+
+	userServiceMock.AssertNumberOfCalls(t, "GetUserById", 2+6) // once per request, errors are not cached
+}
+
+func TestRequestCache_Nil(t *testing.T) {
+	testUser := &models.User{ID: "user1"}
+
+	userServiceMock := new(mocks.UserServiceMock)
+	userServiceMock.On("GetUserById", "user1").Return(testUser, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+	assert.Nil(t, GetRequestCache(r))
+
+	GetUserById(r, userServiceMock, "user1")
+	GetUserById(r, userServiceMock, "user1")
+	userServiceMock.AssertNumberOfCalls(t, "GetUserById", 2)
+}
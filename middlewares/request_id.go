@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"net/http"
+	"regexp"
+
+	conf "github.com/muety/wakapi/config"
+	uuid "github.com/satori/go.uuid"
+)
+
+var requestIdPattern = regexp.MustCompile(`^[\w\-.]{1,64}$`)
+
+// RequestIdMiddleware tags every request with an id, which is included in error responses and logs to correlate them.
+// Ids passed by a reverse proxy in the X-Request-Id header are kept.
+type RequestIdMiddleware struct {
+	handler http.Handler
+}
+
+func NewRequestIdMiddleware() func(handler http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &RequestIdMiddleware{handler: h}
+	}
+}
+
+func (m *RequestIdMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestId := r.Header.Get(conf.HeaderRequestId)
+	if !requestIdPattern.MatchString(requestId) {
+		requestId = uuid.NewV4().String()
+		r.Header.Set(conf.HeaderRequestId, requestId)
+	}
+	w.Header().Set(conf.HeaderRequestId, requestId)
+	m.handler.ServeHTTP(w, r)
+}
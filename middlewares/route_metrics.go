@@ -0,0 +1,103 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	routeMetricsMaxClients = 20      // distinct clients to keep track of per route, further ones are counted as other
+	routeMetricsOther      = "other" // requests by further clients
+	routeMetricsUnknown    = "unknown"
+)
+
+// RouteMetrics counts requests per api route and client, to be exposed along with other metrics.
+// It is meant to be used as a router middleware, as it relies on the matched route's path template.
+type RouteMetrics struct {
+	lock  *sync.Mutex
+	stats map[string]*models.RouteStats
+}
+
+func NewRouteMetrics() *RouteMetrics {
+	return &RouteMetrics{
+		lock:  &sync.Mutex{},
+		stats: make(map[string]*models.RouteStats),
+	}
+}
+
+func (m *RouteMetrics) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(conf.HeaderApiVersion, conf.ApiVersion)
+		h.ServeHTTP(w, r)
+
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			return
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return
+		}
+		// deprecation headers are set by the route's own middleware, see DeprecationMiddleware
+		m.record(fmt.Sprintf("%s %s", r.Method, template), requestClient(r), w.Header().Get(conf.HeaderDeprecation) != "", time.Now())
+	})
+}
+
+// Snapshot returns a copy of the stats of all routes requested so far, sorted by route
+func (m *RouteMetrics) Snapshot() []*models.RouteStats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	stats := make([]*models.RouteStats, 0, len(m.stats))
+	for _, s := range m.stats {
+		statsCopy := *s
+		statsCopy.Clients = make(map[string]int64, len(s.Clients))
+		for k, v := range s.Clients {
+			statsCopy.Clients[k] = v
+		}
+		stats = append(stats, &statsCopy)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Route < stats[j].Route
+	})
+	return stats
+}
+
+func (m *RouteMetrics) record(route, client string, deprecated bool, t time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	s, ok := m.stats[route]
+	if !ok {
+		s = &models.RouteStats{Route: route, Clients: make(map[string]int64)}
+		m.stats[route] = s
+	}
+	if _, ok := s.Clients[client]; !ok && len(s.Clients) >= routeMetricsMaxClients {
+		client = routeMetricsOther
+	}
+	s.Count++
+	s.Clients[client]++
+	s.LastRequest = t
+	s.Deprecated = s.Deprecated || deprecated // requests rejected before reaching the route's handler, e.g. unauthorized ones, lack the header
+}
+
+// requestClient returns the editor of requests sent by wakatime plugins or the product name of the user agent otherwise, e.g. 'curl'
+func requestClient(r *http.Request) string {
+	userAgent := r.Header.Get("User-Agent")
+	if _, editor, err := utils.ParseUserAgent(userAgent); err == nil && editor != "" {
+		return strings.ToLower(editor)
+	}
+	if fields := strings.Fields(userAgent); len(fields) > 0 {
+		return strings.ToLower(strings.Split(fields[0], "/")[0])
+	}
+	return routeMetricsUnknown
+}
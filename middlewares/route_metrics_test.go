@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteMetrics_Handler(t *testing.T) {
+	config.Set(&config.Config{})
+
+	since := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 10, 1, 0, 0, 0, 0, time.UTC)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	sut := NewRouteMetrics()
+	router := mux.NewRouter()
+	apiRouter := router.PathPrefix("/api").Subrouter()
+	apiRouter.Use(sut.Handler)
+	apiRouter.Path("/heartbeat").Methods(http.MethodPost).Handler(NewDeprecationMiddleware(since, sunset, "/api/users/current/heartbeats.bulk")(next))
+	apiRouter.Path("/users/{user}/heartbeats.bulk").Methods(http.MethodPost).Handler(next)
+
+	serve := func(path, userAgent string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.Header.Set("User-Agent", userAgent)
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/api/heartbeat", "wakatime/13.0.7 (Linux-4.15.0-91-generic-x86_64-with-glibc2.4) Python3.8.0.final.0 vscode/1.42.1 vscode-wakatime/4.0.0")
+	assert.Equal(t, config.ApiVersion, w.Header().Get(config.HeaderApiVersion))
+	assert.Equal(t, "@1792108800", w.Header().Get(config.HeaderDeprecation))
+	assert.Equal(t, "Fri, 01 Oct 2027 00:00:00 GMT", w.Header().Get(config.HeaderSunset))
+	assert.Equal(t, "</api/users/current/heartbeats.bulk>; rel=\"successor-version\"", w.Header().Get("Link"))
+
+	w = serve("/api/users/current/heartbeats.bulk", "curl/7.81.0")
+	assert.Empty(t, w.Header().Get(config.HeaderDeprecation))
+	serve("/api/users/current/heartbeats.bulk", "curl/7.81.0")
+	serve("/api/users/current/heartbeats.bulk", "")
+
+	stats := sut.Snapshot()
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "POST /api/heartbeat", stats[0].Route)
+	assert.True(t, stats[0].Deprecated)
+	assert.Equal(t, int64(1), stats[0].Count)
+	assert.Equal(t, map[string]int64{"vscode": 1}, stats[0].Clients)
+	assert.Equal(t, "POST /api/users/{user}/heartbeats.bulk", stats[1].Route)
+	assert.False(t, stats[1].Deprecated)
+	assert.Equal(t, int64(3), stats[1].Count)
+	assert.Equal(t, map[string]int64{"curl": 2, "unknown": 1}, stats[1].Clients)
+}
@@ -1,32 +1,111 @@
 package middlewares
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+
+	conf "github.com/muety/wakapi/config"
 )
 
-var securityHeaders = map[string]string{
+const headerValueOmit = "-"
+
+var staticSecurityHeaders = map[string]string{
 	"Cross-Origin-Opener-Policy": "same-origin",
-	"Content-Security-Policy":    "default-src 'self' 'unsafe-inline' 'unsafe-eval'; img-src 'self' https: data:; form-action 'self'; block-all-mixed-content;",
-	"X-Frame-Options":            "DENY",
 	"X-Content-Type-Options":     "nosniff",
 }
 
-// SecurityMiddleware is a handler to add some basic security headers to responses
+// SecurityMiddleware is a handler to add some basic security headers to responses.
+// Content security policy, frame options, referrer policy and hsts are configurable. Paths starting with one of the embed prefixes (e.g. badges) get a relaxed frame policy, so that they can be embedded into other websites.
 type SecurityMiddleware struct {
-	handler http.Handler
+	handler       http.Handler
+	config        *conf.Config
+	headers       map[string]string
+	embedHeaders  map[string]string
+	embedPrefixes []string
 }
 
-func NewSecurityMiddleware() func(http.Handler) http.Handler {
+func NewSecurityMiddleware(embedPrefixes []string) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
-		return &SecurityMiddleware{h}
+		config := conf.Get()
+		return &SecurityMiddleware{
+			handler:       h,
+			config:        config,
+			headers:       buildSecurityHeaders(&config.Security.Headers, false),
+			embedHeaders:  buildSecurityHeaders(&config.Security.Headers, true),
+			embedPrefixes: embedPrefixes,
+		}
 	}
 }
 
 func (f *SecurityMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	for k, v := range securityHeaders {
+	headers := f.headers
+	if f.isEmbeddable(r.URL.Path) {
+		headers = f.embedHeaders
+	}
+
+	for k, v := range headers {
 		if w.Header().Get(k) == "" {
 			w.Header().Set(k, v)
 		}
 	}
+
+	if maxAge := f.config.Security.Headers.HstsMaxAgeSec; maxAge > 0 && f.isHttps(r) {
+		w.Header().Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", maxAge))
+	}
+
 	f.handler.ServeHTTP(w, r)
 }
+
+func (f *SecurityMiddleware) isEmbeddable(requestPath string) bool {
+	path := strings.ToLower(requestPath)
+	for _, prefix := range f.embedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *SecurityMiddleware) isHttps(r *http.Request) bool {
+	return r.TLS != nil || strings.HasPrefix(f.config.Server.PublicUrl, "https://")
+}
+
+func buildSecurityHeaders(config *conf.SecurityHeadersConfig, embeddable bool) map[string]string {
+	headers := make(map[string]string)
+	for k, v := range staticSecurityHeaders {
+		headers[k] = v
+	}
+
+	csp := config.ContentSecurityPolicy
+	if embeddable && csp != headerValueOmit {
+		csp = withFrameAncestors(csp, config.EmbedFrameAncestors)
+	}
+
+	setIfPresent := func(key, value string) {
+		if value != "" && value != headerValueOmit {
+			headers[key] = value
+		}
+	}
+	setIfPresent("Content-Security-Policy", csp)
+	setIfPresent("Referrer-Policy", config.ReferrerPolicy)
+	if !embeddable {
+		setIfPresent("X-Frame-Options", config.FrameOptions) // superseded by the csp's frame-ancestors directive for embeddable paths
+	}
+
+	return headers
+}
+
+// withFrameAncestors replaces the frame-ancestors directive of the given content security policy
+func withFrameAncestors(csp, ancestors string) string {
+	directives := make([]string, 0)
+	for _, d := range strings.Split(csp, ";") {
+		if d = strings.TrimSpace(d); d != "" && !strings.HasPrefix(d, "frame-ancestors") {
+			directives = append(directives, d)
+		}
+	}
+	if ancestors != "" && ancestors != headerValueOmit {
+		directives = append(directives, "frame-ancestors "+ancestors)
+	}
+	return strings.Join(directives, "; ") + ";"
+}
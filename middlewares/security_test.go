@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityMiddleware_ServeHTTP(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.PublicUrl = "https://wakapi.dev"
+	cfg.Security.Headers = config.SecurityHeadersConfig{
+		ContentSecurityPolicy: "default-src 'self'; frame-ancestors 'none';",
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "-",
+		HstsMaxAgeSec:         3600,
+		EmbedFrameAncestors:   "https://github.com",
+	}
+	config.Set(cfg)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	sut := NewSecurityMiddleware([]string{"/api/compat/shields/"})(next)
+
+	w := httptest.NewRecorder()
+	sut.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/summary", nil))
+	assert.Equal(t, "default-src 'self'; frame-ancestors 'none';", w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "max-age=3600; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+	assert.Empty(t, w.Header().Get("Referrer-Policy"))
+
+	w = httptest.NewRecorder()
+	sut.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/compat/shields/v1/user1/interval:today", nil))
+	assert.Equal(t, "default-src 'self'; frame-ancestors https://github.com;", w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("X-Frame-Options"))
+}
+
+func TestWithFrameAncestors(t *testing.T) {
+	assert.Equal(t, "default-src 'self'; frame-ancestors *;", withFrameAncestors("default-src 'self';", "*"))
+	assert.Equal(t, "default-src 'self'; img-src https:;", withFrameAncestors("default-src 'self'; frame-ancestors 'none'; img-src https:", "-"))
+}
@@ -1,6 +1,7 @@
 package migrations
 
 import (
+	"fmt"
 	"github.com/emvi/logbuch"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
@@ -21,20 +22,15 @@ func init() {
 			if err := db.Exec("UPDATE users SET share_data_max_days = 30 WHERE badges_enabled = TRUE").Error; err != nil {
 				return err
 			}
-			if err := db.Exec("UPDATE users SET share_editors = TRUE WHERE badges_enabled = TRUE").Error; err != nil {
-				return err
-			}
-			if err := db.Exec("UPDATE users SET share_languages = TRUE WHERE badges_enabled = TRUE").Error; err != nil {
-				return err
-			}
-			if err := db.Exec("UPDATE users SET share_projects = TRUE WHERE badges_enabled = TRUE").Error; err != nil {
-				return err
-			}
-			if err := db.Exec("UPDATE users SET share_oss = TRUE WHERE badges_enabled = TRUE").Error; err != nil {
-				return err
-			}
-			if err := db.Exec("UPDATE users SET share_machines = TRUE WHERE badges_enabled = TRUE").Error; err != nil {
-				return err
+			for _, c := range []string{"share_editors", "share_languages", "share_projects", "share_oss", "share_machines"} {
+				// flags were later replaced by per-dimension time ranges, see 20221016-sharing_ranges_per_dimension
+				query := fmt.Sprintf("UPDATE users SET %s_days = 30 WHERE badges_enabled = TRUE", c)
+				if migrator.HasColumn(&models.User{}, c) {
+					query = fmt.Sprintf("UPDATE users SET %s = TRUE WHERE badges_enabled = TRUE", c)
+				}
+				if err := db.Exec(query).Error; err != nil {
+					return err
+				}
 			}
 
 			if cfg.Db.Dialect == config.SQLDialectSqlite {
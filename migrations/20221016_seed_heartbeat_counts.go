@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"gorm.io/gorm"
+)
+
+func init() {
+	const name = "20221016-seed_heartbeat_counts"
+	f := migrationFunc{
+		name: name,
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			if hasRun(name, db) {
+				return nil
+			}
+
+			logbuch.Info("this may take a while!")
+
+			// counters are maintained incrementally from now on, so they only need to be computed from scratch once
+			if err := db.Exec(
+				"INSERT INTO heartbeat_counts (user_id, total) " +
+					"SELECT user_id, count(id) FROM heartbeats " +
+					"WHERE user_id NOT IN (SELECT user_id FROM heartbeat_counts) " +
+					"GROUP BY user_id",
+			).Error; err != nil {
+				logbuch.Error("failed to seed heartbeat counts")
+				return err
+			}
+
+			setHasRun(name, db)
+			return nil
+		},
+	}
+
+	registerPostMigration(f)
+}
@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"fmt"
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+func init() {
+	const name = "20221016-sharing_ranges_per_dimension"
+	f := migrationFunc{
+		name: name,
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			if hasRun(name, db) {
+				return nil
+			}
+
+			migrator := db.Migrator()
+			oldColumns := []string{"share_editors", "share_languages", "share_projects", "share_oss", "share_machines", "share_labels"}
+
+			// users, who had opted in to share a certain kind of data, keep sharing it for their previously configured time range
+			for _, c := range oldColumns {
+				if !migrator.HasColumn(&models.User{}, c) {
+					continue
+				}
+				query := fmt.Sprintf("UPDATE users SET %s_days = share_data_max_days WHERE %s = TRUE", c, c)
+				if err := db.Exec(query).Error; err != nil {
+					return err
+				}
+			}
+
+			if cfg.Db.Dialect == config.SQLDialectSqlite {
+				logbuch.Info("not attempting to drop sharing flag columns on sqlite")
+				setHasRun(name, db)
+				return nil
+			}
+
+			for _, c := range oldColumns {
+				if !migrator.HasColumn(&models.User{}, c) {
+					continue
+				}
+				if err := migrator.DropColumn(&models.User{}, c); err != nil {
+					return err
+				}
+			}
+			logbuch.Info("dropped sharing flag columns after substituting them by per-dimension time ranges")
+
+			setHasRun(name, db)
+			return nil
+		},
+	}
+
+	registerPostMigration(f)
+}
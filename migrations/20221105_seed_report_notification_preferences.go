@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	const name = "20221105-seed_report_notification_preferences"
+	f := migrationFunc{
+		name: name,
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			if hasRun(name, db) {
+				return nil
+			}
+
+			// users, who had opted in to weekly reports, keep receiving them via e-mail, everything else is covered by the defaults
+			var users []*models.User
+			if err := db.Where(&models.User{ReportsWeekly: true}).Find(&users).Error; err != nil {
+				return err
+			}
+
+			for _, u := range users {
+				if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.NotificationPreference{
+					UserID:  u.ID,
+					Event:   models.NotificationEventReport,
+					Channel: models.NotificationChannelEmail,
+					Enabled: true,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			logbuch.Info("migrated weekly report settings of %d users to notification preferences", len(users))
+
+			setHasRun(name, db)
+			return nil
+		},
+	}
+
+	registerPostMigration(f)
+}
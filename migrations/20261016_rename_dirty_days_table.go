@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+func init() {
+	f := migrationFunc{
+		name: "20261016-rename_dirty_days_table",
+		f: func(db *gorm.DB, cfg *config.Config) error {
+			migrator := db.Migrator()
+			oldTableName, newTableName := "dirty_days", "summary_invalidations"
+			oldIndexName, newIndexName := "idx_dirty_day_user_day", "idx_summary_invalidation_user_day"
+
+			if migrator.HasTable(oldTableName) {
+				logbuch.Info("renaming '%s' table to '%s'", oldTableName, newTableName)
+				if err := migrator.RenameTable(oldTableName, &models.SummaryInvalidation{}); err != nil {
+					return err
+				}
+
+				logbuch.Info("renaming '%s' index to '%s'", oldIndexName, newIndexName)
+				return migrator.RenameIndex(&models.SummaryInvalidation{}, oldIndexName, newIndexName)
+			}
+			return nil
+		},
+	}
+
+	registerPreMigration(f)
+}
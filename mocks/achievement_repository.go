@@ -0,0 +1,20 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type AchievementRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *AchievementRepositoryMock) GetByUser(s string) ([]*models.Achievement, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.Achievement), args.Error(1)
+}
+
+func (m *AchievementRepositoryMock) InsertBatch(a []*models.Achievement) error {
+	args := m.Called(a)
+	return args.Error(0)
+}
@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type AggregationServiceMock struct {
+	mock.Mock
+}
+
+func (m *AggregationServiceMock) Schedule() {
+	m.Called()
+}
+
+func (m *AggregationServiceMock) Run(userIds map[string]bool) error {
+	args := m.Called(userIds)
+	return args.Error(0)
+}
+
+func (m *AggregationServiceMock) Regenerate(user *models.User, t time.Time, t2 time.Time) (*models.RegenerationJob, error) {
+	args := m.Called(user, t, t2)
+	return args.Get(0).(*models.RegenerationJob), args.Error(1)
+}
+
+func (m *AggregationServiceMock) GetRegenerationJob(s string) *models.RegenerationJob {
+	args := m.Called(s)
+	return args.Get(0).(*models.RegenerationJob)
+}
+
+func (m *AggregationServiceMock) RegenerateDay(user *models.User, t time.Time, t2 time.Time) error {
+	args := m.Called(user, t, t2)
+	return args.Error(0)
+}
+
+// WithUserLock invokes the given function, unless the mock was set up to return an error
+func (m *AggregationServiceMock) WithUserLock(s string, f func() error) error {
+	args := m.Called(s, f)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return f()
+}
@@ -48,3 +48,8 @@ func (m *AliasRepositoryMock) DeleteBatch(u []uint) error {
 	args := m.Called(u)
 	return args.Error(0)
 }
+
+func (m *AliasRepositoryMock) GetOrphaned() ([]*models.Alias, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.Alias), args.Error(1)
+}
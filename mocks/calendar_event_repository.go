@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type CalendarEventRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *CalendarEventRepositoryMock) GetByUserWithin(userId string, from, to time.Time) ([]*models.CalendarEvent, error) {
+	args := m.Called(userId, from, to)
+	return args.Get(0).([]*models.CalendarEvent), args.Error(1)
+}
+
+func (m *CalendarEventRepositoryMock) Upsert(event *models.CalendarEvent) (*models.CalendarEvent, error) {
+	args := m.Called(event)
+	return args.Get(0).(*models.CalendarEvent), args.Error(1)
+}
+
+func (m *CalendarEventRepositoryMock) DeleteByUser(userId string) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type DayOffServiceMock struct {
+	mock.Mock
+}
+
+func (m *DayOffServiceMock) GetByUser(s string) ([]*models.DayOff, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.DayOff), args.Error(1)
+}
+
+func (m *DayOffServiceMock) GetByUserMapped(s string) (models.DaysOff, error) {
+	args := m.Called(s)
+	return args.Get(0).(models.DaysOff), args.Error(1)
+}
+
+func (m *DayOffServiceMock) Set(s string, t time.Time, t2 time.Time, s2 string) error {
+	args := m.Called(s, t, t2, s2)
+	return args.Error(0)
+}
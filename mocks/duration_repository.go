@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type DurationRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *DurationRepositoryMock) GetAllWithin(from time.Time, to time.Time, user *models.User) ([]*models.Duration, error) {
+	args := m.Called(from, to, user)
+	return args.Get(0).([]*models.Duration), args.Error(1)
+}
+
+func (m *DurationRepositoryMock) GetDaysWithin(from time.Time, to time.Time, user *models.User) ([]*models.DurationDay, error) {
+	args := m.Called(from, to, user)
+	return args.Get(0).([]*models.DurationDay), args.Error(1)
+}
+
+func (m *DurationRepositoryMock) ReplaceDay(user *models.User, day time.Time, strategy string, durations []*models.Duration) error {
+	args := m.Called(user, day, strategy, durations)
+	return args.Error(0)
+}
+
+func (m *DurationRepositoryMock) DeleteDaysByUser(userId string) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
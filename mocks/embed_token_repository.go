@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type EmbedTokenRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *EmbedTokenRepositoryMock) GetByUser(s string) ([]*models.EmbedToken, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.EmbedToken), args.Error(1)
+}
+
+func (m *EmbedTokenRepositoryMock) GetByToken(s string) (*models.EmbedToken, error) {
+	args := m.Called(s)
+	return args.Get(0).(*models.EmbedToken), args.Error(1)
+}
+
+func (m *EmbedTokenRepositoryMock) Insert(t *models.EmbedToken) (*models.EmbedToken, error) {
+	args := m.Called(t)
+	return args.Get(0).(*models.EmbedToken), args.Error(1)
+}
+
+func (m *EmbedTokenRepositoryMock) DeleteByUserAndId(s string, u uint) (int64, error) {
+	args := m.Called(s, u)
+	return args.Get(0).(int64), args.Error(1)
+}
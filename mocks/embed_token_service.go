@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type EmbedTokenServiceMock struct {
+	mock.Mock
+}
+
+func (m *EmbedTokenServiceMock) GetByUser(userId string) ([]*models.EmbedToken, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.EmbedToken), args.Error(1)
+}
+
+func (m *EmbedTokenServiceMock) Create(user *models.User, name string, scopes, referrers []string) (*models.EmbedToken, error) {
+	args := m.Called(user, name, scopes, referrers)
+	return args.Get(0).(*models.EmbedToken), args.Error(1)
+}
+
+func (m *EmbedTokenServiceMock) Delete(user *models.User, id uint) error {
+	args := m.Called(user, id)
+	return args.Error(0)
+}
+
+func (m *EmbedTokenServiceMock) Authorize(token, scope, referrer string) (*models.EmbedToken, error) {
+	args := m.Called(token, scope, referrer)
+	return args.Get(0).(*models.EmbedToken), args.Error(1)
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type FilterSetRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *FilterSetRepositoryMock) GetByUser(userId string) ([]*models.FilterSet, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.FilterSet), args.Error(1)
+}
+
+func (m *FilterSetRepositoryMock) GetByUserAndName(userId, name string) (*models.FilterSet, error) {
+	args := m.Called(userId, name)
+	return args.Get(0).(*models.FilterSet), args.Error(1)
+}
+
+func (m *FilterSetRepositoryMock) Insert(filterSet *models.FilterSet) (*models.FilterSet, error) {
+	args := m.Called(filterSet)
+	return args.Get(0).(*models.FilterSet), args.Error(1)
+}
+
+func (m *FilterSetRepositoryMock) DeleteByUserAndName(userId, name string) error {
+	args := m.Called(userId, name)
+	return args.Error(0)
+}
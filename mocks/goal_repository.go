@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type GoalRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *GoalRepositoryMock) GetById(id uint) (*models.Goal, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.Goal), args.Error(1)
+}
+
+func (m *GoalRepositoryMock) GetByUser(userId string) ([]*models.Goal, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.Goal), args.Error(1)
+}
+
+func (m *GoalRepositoryMock) Insert(goal *models.Goal) (*models.Goal, error) {
+	args := m.Called(goal)
+	return args.Get(0).(*models.Goal), args.Error(1)
+}
+
+func (m *GoalRepositoryMock) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
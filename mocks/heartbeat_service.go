@@ -20,6 +20,21 @@ func (m *HeartbeatServiceMock) InsertBatch(heartbeats []*models.Heartbeat) error
 	return args.Error(0)
 }
 
+func (m *HeartbeatServiceMock) GetById(id uint64) (*models.Heartbeat, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.Heartbeat), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) Update(heartbeat *models.Heartbeat) (*models.Heartbeat, error) {
+	args := m.Called(heartbeat)
+	return args.Get(0).(*models.Heartbeat), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) Delete(heartbeat *models.Heartbeat) (*models.Tombstone, error) {
+	args := m.Called(heartbeat)
+	return args.Get(0).(*models.Tombstone), args.Error(1)
+}
+
 func (m *HeartbeatServiceMock) Count() (int64, error) {
 	args := m.Called()
 	return int64(args.Int(0)), args.Error(1)
@@ -27,7 +42,7 @@ func (m *HeartbeatServiceMock) Count() (int64, error) {
 
 func (m *HeartbeatServiceMock) CountByUser(user *models.User) (int64, error) {
 	args := m.Called(user)
-	return args.Get(0).(int64), args.Error(0)
+	return args.Get(0).(int64), args.Error(1)
 }
 
 func (m *HeartbeatServiceMock) CountByUsers(users []*models.User) ([]*models.CountByUser, error) {
@@ -40,11 +55,35 @@ func (m *HeartbeatServiceMock) GetAllWithin(time time.Time, time2 time.Time, use
 	return args.Get(0).([]*models.Heartbeat), args.Error(1)
 }
 
+func (m *HeartbeatServiceMock) GetAllWithinOrdered(time time.Time, time2 time.Time, user *models.User, ordering *models.Ordering) ([]*models.Heartbeat, error) {
+	args := m.Called(time, time2, user, ordering)
+	return args.Get(0).([]*models.Heartbeat), args.Error(1)
+}
+
+// StreamAllWithin passes the mocked heartbeats to the callback as a single page
+func (m *HeartbeatServiceMock) StreamAllWithin(time time.Time, time2 time.Time, user *models.User, f func([]*models.Heartbeat) error) error {
+	args := m.Called(time, time2, user, f)
+	if err := f(args.Get(0).([]*models.Heartbeat)); err != nil {
+		return err
+	}
+	return args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) GetPageByUser(user *models.User, afterId uint64, limit int) ([]*models.Heartbeat, error) {
+	args := m.Called(user, afterId, limit)
+	return args.Get(0).([]*models.Heartbeat), args.Error(1)
+}
+
 func (m *HeartbeatServiceMock) GetFirstByUsers() ([]*models.TimeByUser, error) {
 	args := m.Called()
 	return args.Get(0).([]*models.TimeByUser), args.Error(1)
 }
 
+func (m *HeartbeatServiceMock) GetFirstByUser(user *models.User) (*models.Heartbeat, error) {
+	args := m.Called(user)
+	return args.Get(0).(*models.Heartbeat), args.Error(1)
+}
+
 func (m *HeartbeatServiceMock) GetLatestByUser(user *models.User) (*models.Heartbeat, error) {
 	args := m.Called(user)
 	return args.Get(0).(*models.Heartbeat), args.Error(1)
@@ -64,3 +103,28 @@ func (m *HeartbeatServiceMock) DeleteBefore(time time.Time) error {
 	args := m.Called(time)
 	return args.Error(0)
 }
+
+func (m *HeartbeatServiceMock) DeleteOldestByUser(user *models.User, n int64) (int64, error) {
+	args := m.Called(user, n)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) GetProjectActivityByUser(user *models.User, ordering *models.Ordering) ([]*models.ProjectActivity, error) {
+	args := m.Called(user, ordering)
+	return args.Get(0).([]*models.ProjectActivity), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) GetMachineActivityByUser(user *models.User) ([]*models.MachineActivity, error) {
+	args := m.Called(user)
+	return args.Get(0).([]*models.MachineActivity), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) GetOriginActivityByUser(user *models.User) ([]*models.OriginActivity, error) {
+	args := m.Called(user)
+	return args.Get(0).([]*models.OriginActivity), args.Error(1)
+}
+
+func (m *HeartbeatServiceMock) CountPending() int64 {
+	args := m.Called()
+	return args.Get(0).(int64)
+}
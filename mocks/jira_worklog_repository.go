@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type JiraWorklogRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *JiraWorklogRepositoryMock) GetByUser(userId string, limit int) ([]*models.JiraWorklog, error) {
+	args := m.Called(userId, limit)
+	return args.Get(0).([]*models.JiraWorklog), args.Error(1)
+}
+
+func (m *JiraWorklogRepositoryMock) GetByUserWithin(userId string, from, to string) ([]*models.JiraWorklog, error) {
+	args := m.Called(userId, from, to)
+	return args.Get(0).([]*models.JiraWorklog), args.Error(1)
+}
+
+func (m *JiraWorklogRepositoryMock) Upsert(worklog *models.JiraWorklog) (*models.JiraWorklog, error) {
+	args := m.Called(worklog)
+	return args.Get(0).(*models.JiraWorklog), args.Error(1)
+}
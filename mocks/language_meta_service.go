@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type LanguageMetaServiceMock struct {
+	mock.Mock
+}
+
+func (m *LanguageMetaServiceMock) GetAll() []*models.LanguageMeta {
+	args := m.Called()
+	return args.Get(0).([]*models.LanguageMeta)
+}
+
+func (m *LanguageMetaServiceMock) Get(s string) *models.LanguageMeta {
+	args := m.Called(s)
+	return args.Get(0).(*models.LanguageMeta)
+}
+
+func (m *LanguageMetaServiceMock) GetColors() map[string]string {
+	args := m.Called()
+	return args.Get(0).(map[string]string)
+}
+
+func (m *LanguageMetaServiceMock) GetOverrides() []*models.LanguageMeta {
+	args := m.Called()
+	return args.Get(0).([]*models.LanguageMeta)
+}
+
+func (m *LanguageMetaServiceMock) PutOverride(l *models.LanguageMeta) (*models.LanguageMeta, error) {
+	args := m.Called(l)
+	return args.Get(0).(*models.LanguageMeta), args.Error(1)
+}
+
+func (m *LanguageMetaServiceMock) DeleteOverride(s string) error {
+	args := m.Called(s)
+	return args.Error(0)
+}
@@ -0,0 +1,20 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type LeaderboardRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *LeaderboardRepositoryMock) GetAll() ([]*models.LeaderboardItem, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.LeaderboardItem), args.Error(1)
+}
+
+func (m *LeaderboardRepositoryMock) ReplaceAll(items []*models.LeaderboardItem) error {
+	args := m.Called(items)
+	return args.Error(0)
+}
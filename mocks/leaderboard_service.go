@@ -0,0 +1,24 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type LeaderboardServiceMock struct {
+	mock.Mock
+}
+
+func (m *LeaderboardServiceMock) Schedule() {
+	m.Called()
+}
+
+func (m *LeaderboardServiceMock) Generate() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *LeaderboardServiceMock) GetLeaderboard() ([]*models.LeaderboardItem, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.LeaderboardItem), args.Error(1)
+}
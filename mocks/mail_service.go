@@ -0,0 +1,51 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type MailServiceMock struct {
+	mock.Mock
+}
+
+func (m *MailServiceMock) SendPasswordReset(u *models.User, s string) error {
+	args := m.Called(u, s)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendWakatimeFailureNotification(u *models.User, i int) error {
+	args := m.Called(u, i)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendImportNotification(u *models.User, d time.Duration, i int) error {
+	args := m.Called(u, d, i)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendReport(u *models.User, r *models.Report) error {
+	args := m.Called(u, r)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendBudgetAlert(u *models.User, s *models.BudgetStatus) error {
+	args := m.Called(u, s)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendInactivityAlert(u *models.User, a *models.InactivityAlert) error {
+	args := m.Called(u, a)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendCredentials(u *models.User, password string) error {
+	args := m.Called(u, password)
+	return args.Error(0)
+}
+
+func (m *MailServiceMock) SendTestMail(u *models.User) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
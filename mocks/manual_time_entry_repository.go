@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type ManualTimeEntryRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ManualTimeEntryRepositoryMock) GetAll() ([]*models.ManualTimeEntry, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.ManualTimeEntry), args.Error(1)
+}
+
+func (m *ManualTimeEntryRepositoryMock) GetById(u uint) (*models.ManualTimeEntry, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.ManualTimeEntry), args.Error(1)
+}
+
+func (m *ManualTimeEntryRepositoryMock) GetByUser(s string) ([]*models.ManualTimeEntry, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.ManualTimeEntry), args.Error(1)
+}
+
+func (m *ManualTimeEntryRepositoryMock) GetByUserWithin(s string, t time.Time, t2 time.Time) ([]*models.ManualTimeEntry, error) {
+	args := m.Called(s, t, t2)
+	return args.Get(0).([]*models.ManualTimeEntry), args.Error(1)
+}
+
+func (m *ManualTimeEntryRepositoryMock) Insert(e *models.ManualTimeEntry) (*models.ManualTimeEntry, error) {
+	args := m.Called(e)
+	return args.Get(0).(*models.ManualTimeEntry), args.Error(1)
+}
+
+func (m *ManualTimeEntryRepositoryMock) Delete(u uint) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
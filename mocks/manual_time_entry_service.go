@@ -0,0 +1,36 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type ManualTimeEntryServiceMock struct {
+	mock.Mock
+}
+
+func (m *ManualTimeEntryServiceMock) GetById(u uint) (*models.ManualTimeEntry, error) {
+	args := m.Called(u)
+	return args.Get(0).(*models.ManualTimeEntry), args.Error(1)
+}
+
+func (m *ManualTimeEntryServiceMock) GetByUser(s string) ([]*models.ManualTimeEntry, error) {
+	args := m.Called(s)
+	return args.Get(0).([]*models.ManualTimeEntry), args.Error(1)
+}
+
+func (m *ManualTimeEntryServiceMock) GetByUserWithin(s string, t time.Time, t2 time.Time) ([]*models.ManualTimeEntry, error) {
+	args := m.Called(s, t, t2)
+	return args.Get(0).([]*models.ManualTimeEntry), args.Error(1)
+}
+
+func (m *ManualTimeEntryServiceMock) Create(e *models.ManualTimeEntry) (*models.ManualTimeEntry, error) {
+	args := m.Called(e)
+	return args.Get(0).(*models.ManualTimeEntry), args.Error(1)
+}
+
+func (m *ManualTimeEntryServiceMock) Delete(e *models.ManualTimeEntry) error {
+	args := m.Called(e)
+	return args.Error(0)
+}
@@ -0,0 +1,20 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type NotificationPreferenceRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *NotificationPreferenceRepositoryMock) GetByUser(userId string) ([]*models.NotificationPreference, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.NotificationPreference), args.Error(1)
+}
+
+func (m *NotificationPreferenceRepositoryMock) Upsert(preference *models.NotificationPreference) (*models.NotificationPreference, error) {
+	args := m.Called(preference)
+	return args.Get(0).(*models.NotificationPreference), args.Error(1)
+}
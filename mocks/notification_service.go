@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type NotificationServiceMock struct {
+	mock.Mock
+}
+
+func (m *NotificationServiceMock) GetPreferences(user *models.User) (models.NotificationPreferences, error) {
+	args := m.Called(user)
+	return args.Get(0).(models.NotificationPreferences), args.Error(1)
+}
+
+func (m *NotificationServiceMock) UpdatePreferences(user *models.User, prefs []*models.NotificationPreference) (models.NotificationPreferences, error) {
+	args := m.Called(user, prefs)
+	return args.Get(0).(models.NotificationPreferences), args.Error(1)
+}
+
+func (m *NotificationServiceMock) IsAvailable(user *models.User, channel string) bool {
+	args := m.Called(user, channel)
+	return args.Bool(0)
+}
+
+func (m *NotificationServiceMock) IsEnabled(user *models.User, event, channel string) bool {
+	args := m.Called(user, event, channel)
+	return args.Bool(0)
+}
+
+func (m *NotificationServiceMock) Notify(user *models.User, notification *models.Notification) error {
+	args := m.Called(user, notification)
+	return args.Error(0)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type ProjectBudgetRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ProjectBudgetRepositoryMock) GetAll() ([]*models.ProjectBudget, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.ProjectBudget), args.Error(1)
+}
+
+func (m *ProjectBudgetRepositoryMock) GetByUser(userId string) ([]*models.ProjectBudget, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.ProjectBudget), args.Error(1)
+}
+
+func (m *ProjectBudgetRepositoryMock) Upsert(budget *models.ProjectBudget) (*models.ProjectBudget, error) {
+	args := m.Called(budget)
+	return args.Get(0).(*models.ProjectBudget), args.Error(1)
+}
+
+func (m *ProjectBudgetRepositoryMock) UpdateNotified(budget *models.ProjectBudget) error {
+	args := m.Called(budget)
+	return args.Error(0)
+}
+
+func (m *ProjectBudgetRepositoryMock) DeleteByUserAndProject(userId, projectKey string) error {
+	args := m.Called(userId, projectKey)
+	return args.Error(0)
+}
@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type QuarantineRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *QuarantineRepositoryMock) GetAll() ([]*models.Quarantine, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.Quarantine), args.Error(1)
+}
+
+func (m *QuarantineRepositoryMock) GetById(id uint) (*models.Quarantine, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.Quarantine), args.Error(1)
+}
+
+func (m *QuarantineRepositoryMock) GetLatestByUserAndReason(userId string, reason string) (*models.Quarantine, error) {
+	args := m.Called(userId, reason)
+	return args.Get(0).(*models.Quarantine), args.Error(1)
+}
+
+func (m *QuarantineRepositoryMock) CountByUser(userId string) (int64, error) {
+	args := m.Called(userId)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *QuarantineRepositoryMock) Upsert(quarantine *models.Quarantine) (*models.Quarantine, error) {
+	args := m.Called(quarantine)
+	return args.Get(0).(*models.Quarantine), args.Error(1)
+}
+
+func (m *QuarantineRepositoryMock) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RelayRuleRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RelayRuleRepositoryMock) GetById(id uint) (*models.RelayRule, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.RelayRule), args.Error(1)
+}
+
+func (m *RelayRuleRepositoryMock) GetByUser(userId string) ([]*models.RelayRule, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.RelayRule), args.Error(1)
+}
+
+func (m *RelayRuleRepositoryMock) Insert(rule *models.RelayRule) (*models.RelayRule, error) {
+	args := m.Called(rule)
+	return args.Get(0).(*models.RelayRule), args.Error(1)
+}
+
+func (m *RelayRuleRepositoryMock) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
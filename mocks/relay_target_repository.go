@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RelayTargetRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RelayTargetRepositoryMock) GetById(id uint) (*models.RelayTarget, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetRepositoryMock) GetByUser(userId string) ([]*models.RelayTarget, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetRepositoryMock) Insert(target *models.RelayTarget) (*models.RelayTarget, error) {
+	args := m.Called(target)
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetRepositoryMock) UpdateEnabled(target *models.RelayTarget, enabled bool) (*models.RelayTarget, error) {
+	args := m.Called(target, enabled)
+	return args.Get(0).(*models.RelayTarget), args.Error(1)
+}
+
+func (m *RelayTargetRepositoryMock) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
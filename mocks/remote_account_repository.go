@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RemoteAccountRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RemoteAccountRepositoryMock) GetById(id uint) (*models.RemoteAccount, error) {
+	args := m.Called(id)
+	return args.Get(0).(*models.RemoteAccount), args.Error(1)
+}
+
+func (m *RemoteAccountRepositoryMock) GetByUser(userId string) ([]*models.RemoteAccount, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]*models.RemoteAccount), args.Error(1)
+}
+
+func (m *RemoteAccountRepositoryMock) Insert(account *models.RemoteAccount) (*models.RemoteAccount, error) {
+	args := m.Called(account)
+	return args.Get(0).(*models.RemoteAccount), args.Error(1)
+}
+
+func (m *RemoteAccountRepositoryMock) UpdateEnabled(account *models.RemoteAccount, enabled bool) (*models.RemoteAccount, error) {
+	args := m.Called(account, enabled)
+	return args.Get(0).(*models.RemoteAccount), args.Error(1)
+}
+
+func (m *RemoteAccountRepositoryMock) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
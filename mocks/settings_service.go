@@ -0,0 +1,20 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type SettingsServiceMock struct {
+	mock.Mock
+}
+
+func (m *SettingsServiceMock) Export(user *models.User) (*models.SettingsDocument, error) {
+	args := m.Called(user)
+	return args.Get(0).(*models.SettingsDocument), args.Error(1)
+}
+
+func (m *SettingsServiceMock) Import(user *models.User, doc *models.SettingsDocument, mode string) (*models.SettingsImportReport, error) {
+	args := m.Called(user, doc, mode)
+	return args.Get(0).(*models.SettingsImportReport), args.Error(1)
+}
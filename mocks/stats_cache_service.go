@@ -0,0 +1,24 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type StatsCacheServiceMock struct {
+	mock.Mock
+}
+
+func (m *StatsCacheServiceMock) IsEnabled() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
+
+func (m *StatsCacheServiceMock) GetOrCompute(user *models.User, s string, filters *models.Filters, f func() (*models.Summary, error)) (*models.Summary, error) {
+	args := m.Called(user, s, filters, f)
+	return args.Get(0).(*models.Summary), args.Error(1)
+}
+
+func (m *StatsCacheServiceMock) Invalidate(s string) {
+	m.Called(s)
+}
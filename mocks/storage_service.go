@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"io"
+	"time"
+)
+
+type StorageServiceMock struct {
+	mock.Mock
+}
+
+func (m *StorageServiceMock) Put(key string, data io.Reader, size int64, contentType string) error {
+	args := m.Called(key, data, size, contentType)
+	return args.Error(0)
+}
+
+func (m *StorageServiceMock) Open(key string) (io.ReadCloser, error) {
+	args := m.Called(key)
+	return args.Get(0).(io.ReadCloser), args.Error(1)
+}
+
+func (m *StorageServiceMock) Delete(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *StorageServiceMock) DeleteBefore(prefix string, t time.Time) (int, error) {
+	args := m.Called(prefix, t)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *StorageServiceMock) GetDownloadUrl(key string, expiry time.Duration) (string, error) {
+	args := m.Called(key, expiry)
+	return args.String(0), args.Error(1)
+}
+
+func (m *StorageServiceMock) ResolveDownloadToken(token string) (string, error) {
+	args := m.Called(token)
+	return args.String(0), args.Error(1)
+}
@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type SummaryInvalidationRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *SummaryInvalidationRepositoryMock) GetAll() ([]*models.SummaryInvalidation, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.SummaryInvalidation), args.Error(1)
+}
+
+func (m *SummaryInvalidationRepositoryMock) InsertBatch(invalidations []*models.SummaryInvalidation) error {
+	args := m.Called(invalidations)
+	return args.Error(0)
+}
+
+func (m *SummaryInvalidationRepositoryMock) DeleteBatchMarkedBefore(ids []uint, t time.Time) error {
+	args := m.Called(ids, t)
+	return args.Error(0)
+}
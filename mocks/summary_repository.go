@@ -15,6 +15,11 @@ func (m *SummaryRepositoryMock) Insert(summary *models.Summary) error {
 	return args.Error(0)
 }
 
+func (m *SummaryRepositoryMock) InsertBatch(summaries []*models.Summary) error {
+	args := m.Called(summaries)
+	return args.Error(0)
+}
+
 func (m *SummaryRepositoryMock) GetAll() ([]*models.Summary, error) {
 	args := m.Called()
 	return args.Get(0).([]*models.Summary), args.Error(1)
@@ -25,6 +30,11 @@ func (m *SummaryRepositoryMock) GetByUserWithin(user *models.User, time time.Tim
 	return args.Get(0).([]*models.Summary), args.Error(1)
 }
 
+func (m *SummaryRepositoryMock) GetByUsersWithin(users []*models.User, time time.Time, time2 time.Time) ([]*models.Summary, error) {
+	args := m.Called(users, time, time2)
+	return args.Get(0).([]*models.Summary), args.Error(1)
+}
+
 func (m *SummaryRepositoryMock) GetLastByUser() ([]*models.TimeByUser, error) {
 	args := m.Called()
 	return args.Get(0).([]*models.TimeByUser), args.Error(1)
@@ -34,3 +44,23 @@ func (m *SummaryRepositoryMock) DeleteByUser(s string) error {
 	args := m.Called(s)
 	return args.Error(0)
 }
+
+func (m *SummaryRepositoryMock) DeleteByUserWithin(s string, t time.Time, t2 time.Time) error {
+	args := m.Called(s, t, t2)
+	return args.Error(0)
+}
+
+func (m *SummaryRepositoryMock) GetOrphanedIds() ([]uint, error) {
+	args := m.Called()
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *SummaryRepositoryMock) DeleteByIds(ids []uint) error {
+	args := m.Called(ids)
+	return args.Error(0)
+}
+
+func (m *SummaryRepositoryMock) ReplaceByUserWithin(s string, t time.Time, t2 time.Time, summaries []*models.Summary) error {
+	args := m.Called(s, t, t2, summaries)
+	return args.Error(0)
+}
@@ -0,0 +1,61 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type SummaryServiceMock struct {
+	mock.Mock
+}
+
+func (m *SummaryServiceMock) Aliased(t time.Time, t2 time.Time, u *models.User, r func(time.Time, time.Time, *models.User, *models.Filters) (*models.Summary, error), f *models.Filters, b bool) (*models.Summary, error) {
+	args := m.Called(t, t2, u, r, f, b)
+	return args.Get(0).(*models.Summary), args.Error(1)
+}
+
+func (m *SummaryServiceMock) Retrieve(t time.Time, t2 time.Time, u *models.User, f *models.Filters) (*models.Summary, error) {
+	args := m.Called(t, t2, u, f)
+	return args.Get(0).(*models.Summary), args.Error(1)
+}
+
+func (m *SummaryServiceMock) RetrieveBatch(t time.Time, t2 time.Time, u []*models.User) (map[string]*models.Summary, error) {
+	args := m.Called(t, t2, u)
+	return args.Get(0).(map[string]*models.Summary), args.Error(1)
+}
+
+func (m *SummaryServiceMock) Summarize(t time.Time, t2 time.Time, u *models.User, f *models.Filters) (*models.Summary, error) {
+	args := m.Called(t, t2, u, f)
+	return args.Get(0).(*models.Summary), args.Error(1)
+}
+
+func (m *SummaryServiceMock) GetLatestByUser() ([]*models.TimeByUser, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.TimeByUser), args.Error(1)
+}
+
+func (m *SummaryServiceMock) DeleteByUser(s string) error {
+	args := m.Called(s)
+	return args.Error(0)
+}
+
+func (m *SummaryServiceMock) DeleteByUserWithin(s string, t time.Time, t2 time.Time) error {
+	args := m.Called(s, t, t2)
+	return args.Error(0)
+}
+
+func (m *SummaryServiceMock) ReplaceWithin(s string, t time.Time, t2 time.Time, summary *models.Summary) error {
+	args := m.Called(s, t, t2, summary)
+	return args.Error(0)
+}
+
+func (m *SummaryServiceMock) Insert(s *models.Summary) error {
+	args := m.Called(s)
+	return args.Error(0)
+}
+
+func (m *SummaryServiceMock) InsertBatch(s []*models.Summary) error {
+	args := m.Called(s)
+	return args.Error(0)
+}
@@ -0,0 +1,16 @@
+package mocks
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type TicketServiceMock struct {
+	mock.Mock
+}
+
+func (m *TicketServiceMock) GetWorklog(from, to time.Time, user *models.User) ([]*models.WorklogEntry, error) {
+	args := m.Called(from, to, user)
+	return args.Get(0).([]*models.WorklogEntry), args.Error(1)
+}
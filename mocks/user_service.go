@@ -64,11 +64,31 @@ func (m *UserServiceMock) Delete(user *models.User) error {
 	return args.Error(0)
 }
 
+func (m *UserServiceMock) Merge(merge *models.UserMerge) (*models.UserMergeReport, error) {
+	args := m.Called(merge)
+	return args.Get(0).(*models.UserMergeReport), args.Error(1)
+}
+
+func (m *UserServiceMock) SetPassword(user *models.User, password string) (*models.User, error) {
+	args := m.Called(user, password)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserServiceMock) SetAdmin(user *models.User, isAdmin bool) (*models.User, error) {
+	args := m.Called(user, isAdmin)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *UserServiceMock) ResetApiKey(user *models.User) (*models.User, error) {
 	args := m.Called(user)
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *UserServiceMock) SetDeactivated(user *models.User, deactivated bool) (*models.User, error) {
+	args := m.Called(user, deactivated)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *UserServiceMock) ToggleBadges(user *models.User) (*models.User, error) {
 	args := m.Called(user)
 	return args.Get(0).(*models.User), args.Error(1)
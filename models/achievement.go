@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+const (
+	AchievementHours100    = "hours_100"
+	AchievementStreak30    = "streak_30"
+	AchievementLanguages10 = "languages_10"
+)
+
+// AchievementStats are a user's accumulated coding statistics up to a certain day, against which achievements are evaluated
+type AchievementStats struct {
+	Total     time.Duration
+	Languages map[string]bool
+	Streak    *StreakCounter
+}
+
+type AchievementDefinition struct {
+	Key         string
+	Title       string
+	Description string
+	IsEarned    func(stats *AchievementStats) bool
+}
+
+var Achievements = []*AchievementDefinition{
+	{
+		Key:         AchievementHours100,
+		Title:       "Centurion",
+		Description: "Coded for 100 hours in total",
+		IsEarned:    func(s *AchievementStats) bool { return s.Total >= 100*time.Hour },
+	},
+	{
+		Key:         AchievementStreak30,
+		Title:       "On Fire",
+		Description: "Coded on 30 days in a row",
+		IsEarned:    func(s *AchievementStats) bool { return s.Streak.Longest >= 30 },
+	},
+	{
+		Key:         AchievementLanguages10,
+		Title:       "Polyglot",
+		Description: "Used 10 different languages",
+		IsEarned:    func(s *AchievementStats) bool { return len(s.Languages) >= 10 },
+	},
+}
+
+func GetAchievementDefinition(key string) *AchievementDefinition {
+	for _, d := range Achievements {
+		if d.Key == key {
+			return d
+		}
+	}
+	return nil
+}
+
+// Achievement is a badge earned by a user
+type Achievement struct {
+	ID       uint       `json:"-" gorm:"primary_key"`
+	User     *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID   string     `json:"-" gorm:"not null; uniqueIndex:idx_achievement_user_key"`
+	Key      string     `json:"key" gorm:"not null; size:64; uniqueIndex:idx_achievement_user_key"`
+	EarnedAt CustomTime `json:"earned_at" gorm:"not null; type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"` // end of the day, on which the achievement was reached
+}
+
+func (a *Achievement) Definition() *AchievementDefinition {
+	return GetAchievementDefinition(a.Key)
+}
+
+func (a *Achievement) Title() string {
+	if d := a.Definition(); d != nil {
+		return d.Title
+	}
+	return a.Key
+}
+
+func (a *Achievement) Description() string {
+	if d := a.Definition(); d != nil {
+		return d.Description
+	}
+	return ""
+}
+
+type AchievementViewModel struct {
+	Key         string    `json:"key"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	EarnedAt    time.Time `json:"earned_at"`
+}
+
+func NewAchievementViewModel(a *Achievement) *AchievementViewModel {
+	return &AchievementViewModel{
+		Key:         a.Key,
+		Title:       a.Title(),
+		Description: a.Description(),
+		EarnedAt:    a.EarnedAt.T(),
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// ActivityDeltaProject is the time spent on a single project within the delta's range
+type ActivityDeltaProject struct {
+	Name         string `json:"name"`
+	TotalSeconds int64  `json:"total_seconds"`
+}
+
+// ActivityDelta summarizes the time spent per project since a given point in time,
+// intended for ci jobs and bots to check, e.g., whether any time was logged on a project today
+type ActivityDelta struct {
+	Since        time.Time               `json:"since"`
+	Until        time.Time               `json:"until"`
+	TotalSeconds int64                   `json:"total_seconds"`
+	MinSeconds   int64                   `json:"min_seconds"`
+	Passed       bool                    `json:"passed"`   // whether at least min_seconds were logged in total
+	Projects     []*ActivityDeltaProject `json:"projects"` // most time spent first
+}
+
+// NewActivityDelta builds a delta from a summary covering the given range
+func NewActivityDelta(since, until time.Time, summary *Summary, minSeconds int64) *ActivityDelta {
+	delta := &ActivityDelta{
+		Since:      since,
+		Until:      until,
+		MinSeconds: minSeconds,
+		Projects:   make([]*ActivityDeltaProject, 0, len(summary.Projects)),
+	}
+
+	for _, item := range summary.Projects {
+		seconds := int64(item.TotalFixed().Seconds())
+		delta.Projects = append(delta.Projects, &ActivityDeltaProject{Name: item.Key, TotalSeconds: seconds})
+		delta.TotalSeconds += seconds
+	}
+
+	sort.SliceStable(delta.Projects, func(i, j int) bool {
+		return delta.Projects[i].TotalSeconds > delta.Projects[j].TotalSeconds
+	})
+
+	delta.Passed = delta.TotalSeconds >= minSeconds
+	return delta
+}
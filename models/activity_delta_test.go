@@ -0,0 +1,34 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewActivityDelta(t *testing.T) {
+	until := time.Now()
+	since := until.Add(-2 * time.Hour)
+	summary := &Summary{Projects: []*SummaryItem{
+		{Type: SummaryProject, Key: "wakapi", Total: 600},
+		{Type: SummaryProject, Key: "anchr", Total: 1200},
+	}}
+
+	sut := NewActivityDelta(since, until, summary, 1800)
+
+	assert.Equal(t, since, sut.Since)
+	assert.Equal(t, until, sut.Until)
+	assert.Equal(t, int64(1800), sut.TotalSeconds)
+	assert.True(t, sut.Passed)
+	assert.Len(t, sut.Projects, 2)
+	assert.Equal(t, "anchr", sut.Projects[0].Name)
+	assert.Equal(t, int64(1200), sut.Projects[0].TotalSeconds)
+
+	sut = NewActivityDelta(since, until, summary, 1801)
+	assert.False(t, sut.Passed)
+
+	sut = NewActivityDelta(since, until, &Summary{}, 0)
+	assert.Zero(t, sut.TotalSeconds)
+	assert.Empty(t, sut.Projects)
+	assert.True(t, sut.Passed)
+}
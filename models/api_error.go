@@ -0,0 +1,41 @@
+package models
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ApiError is the uniform body of error responses of the api
+type ApiError struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestId string      `json:"request_id,omitempty"`
+}
+
+// WakatimeApiError mimics error responses of WakaTime's api, see https://wakatime.com/developers#errors
+type WakatimeApiError struct {
+	Error string `json:"error"`
+}
+
+func NewApiError(status int, message string, details interface{}) *ApiError {
+	return &ApiError{
+		Code:    ErrorCode(status),
+		Message: message,
+		Details: details,
+	}
+}
+
+func (e *ApiError) WithRequestId(requestId string) *ApiError {
+	e.RequestId = requestId
+	return e
+}
+
+// ErrorCode derives a machine-readable error code from an http status, e.g. 'not_found' from 404
+func ErrorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "unknown_error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	ReportIntervalWeek  = "week"
+	ReportIntervalMonth = "month"
+)
+
+// ArchivedReport is a persisted copy of a generated weekly or monthly report, so that users can browse their past reports later on.
+// Only the aggregated numbers are kept, from which the report (including its pdf) can be reconstructed.
+type ArchivedReport struct {
+	ID           uint               `json:"id" gorm:"primary_key"`
+	User         *User              `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID       string             `json:"-" gorm:"not null; index:idx_archived_report_user"`
+	Interval     string             `json:"interval" gorm:"not null; size:8"`
+	FromTime     CustomTime         `json:"from" gorm:"not null; type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	ToTime       CustomTime         `json:"to" gorm:"not null; type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	TotalSeconds int64              `json:"total"`
+	Data         ArchivedReportData `json:"data" gorm:"type:text"`
+	CreatedAt    CustomTime         `json:"created_at" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+type ArchivedReportData struct {
+	Projects         SummaryItems `json:"projects"`
+	Languages        SummaryItems `json:"languages"`
+	Editors          SummaryItems `json:"editors"`
+	OperatingSystems SummaryItems `json:"operating_systems"`
+	Machines         SummaryItems `json:"machines"`
+	ManualProjects   SummaryItems `json:"manual_projects,omitempty"`
+	OvertimeTarget   *int64       `json:"overtime_target,omitempty"` // in seconds, only present if the user had a workday target
+	OvertimeActual   *int64       `json:"overtime_actual,omitempty"`
+}
+
+func NewArchivedReport(report *Report, interval string) *ArchivedReport {
+	archived := &ArchivedReport{
+		UserID:       report.User.ID,
+		Interval:     interval,
+		FromTime:     CustomTime(report.From),
+		ToTime:       CustomTime(report.To),
+		TotalSeconds: int64(report.Summary.TotalTime().Seconds()),
+		Data: ArchivedReportData{
+			Projects:         report.Summary.Projects,
+			Languages:        report.Summary.Languages,
+			Editors:          report.Summary.Editors,
+			OperatingSystems: report.Summary.OperatingSystems,
+			Machines:         report.Summary.Machines,
+			ManualProjects:   report.Summary.ManualProjects,
+		},
+	}
+	if o := report.Overtime; o != nil {
+		target, actual := int64(o.Target.Seconds()), int64(o.Actual.Seconds())
+		archived.Data.OvertimeTarget, archived.Data.OvertimeActual = &target, &actual
+	}
+	return archived
+}
+
+func (r *ArchivedReport) IsValid() bool {
+	return r.UserID != "" && (r.Interval == ReportIntervalWeek || r.Interval == ReportIntervalMonth) && r.FromTime.T().Before(r.ToTime.T())
+}
+
+// ToReport reconstructs the original report, e.g. to render it as pdf again
+func (r *ArchivedReport) ToReport(user *User) *Report {
+	summary := &Summary{
+		UserID:           user.ID,
+		FromTime:         r.FromTime,
+		ToTime:           r.ToTime,
+		Projects:         withType(r.Data.Projects, SummaryProject),
+		Languages:        withType(r.Data.Languages, SummaryLanguage),
+		Editors:          withType(r.Data.Editors, SummaryEditor),
+		OperatingSystems: withType(r.Data.OperatingSystems, SummaryOS),
+		Machines:         withType(r.Data.Machines, SummaryMachine),
+		ManualProjects:   withType(r.Data.ManualProjects, SummaryProject),
+	}
+
+	report := &Report{
+		From:    r.FromTime.T(),
+		To:      r.ToTime.T(),
+		User:    user,
+		Summary: summary,
+	}
+	if r.Data.OvertimeTarget != nil && r.Data.OvertimeActual != nil {
+		report.Overtime = &Overtime{
+			From:           r.FromTime.T(),
+			To:             r.ToTime.T(),
+			Target:         time.Duration(*r.Data.OvertimeTarget) * time.Second,
+			Actual:         time.Duration(*r.Data.OvertimeActual) * time.Second,
+			TargetSeconds:  *r.Data.OvertimeTarget,
+			ActualSeconds:  *r.Data.OvertimeActual,
+			BalanceSeconds: *r.Data.OvertimeActual - *r.Data.OvertimeTarget,
+		}
+	}
+	return report
+}
+
+// Title returns a human-readable name of the report's period, e.g. "Week 2022-10-24 – 2022-10-31" or "October 2022"
+func (r *ArchivedReport) Title() string {
+	if r.Interval == ReportIntervalMonth {
+		return r.FromTime.T().Format("January 2006")
+	}
+	return fmt.Sprintf("Week %s – %s", r.FromTime.T().Format("2006-01-02"), r.ToTime.T().Format("2006-01-02"))
+}
+
+func (r *ArchivedReport) Total() time.Duration {
+	return time.Duration(r.TotalSeconds) * time.Second
+}
+
+// TopProjects returns at most n projects with the most time spent on
+func (r *ArchivedReport) TopProjects(n int) SummaryItems {
+	if len(r.Data.Projects) <= n {
+		return r.Data.Projects
+	}
+	return r.Data.Projects[:n]
+}
+
+func (d *ArchivedReportData) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case nil:
+		*d = ArchivedReportData{}
+		return nil
+	default:
+		return errors.New(fmt.Sprintf("unsupported type: %T", value))
+	}
+	return json.Unmarshal(data, d)
+}
+
+func (d ArchivedReportData) Value() (driver.Value, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func withType(items SummaryItems, summaryType uint8) SummaryItems {
+	for _, item := range items {
+		item.Type = summaryType
+	}
+	return items
+}
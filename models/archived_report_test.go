@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchivedReport_RoundTrip(t *testing.T) {
+	user := &User{ID: "user1"}
+	from := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	report := &Report{
+		From: from,
+		To:   to,
+		User: user,
+		Summary: &Summary{
+			Projects:  SummaryItems{{Type: SummaryProject, Key: "wakapi", Total: 3600}, {Type: SummaryProject, Key: "anchr", Total: 1800}},
+			Languages: SummaryItems{{Type: SummaryLanguage, Key: "Go", Total: 5400}},
+		},
+		Overtime: &Overtime{Target: 2 * time.Hour, Actual: 90 * time.Minute},
+	}
+
+	archived := NewArchivedReport(report, ReportIntervalMonth)
+	assert.True(t, archived.IsValid())
+	assert.Equal(t, int64(5400), archived.TotalSeconds)
+	assert.Equal(t, "October 2022", archived.Title())
+	assert.Len(t, archived.TopProjects(1), 1)
+
+	value, err := archived.Data.Value()
+	assert.Nil(t, err)
+
+	var data ArchivedReportData
+	assert.Nil(t, data.Scan(value))
+	archived.Data = data
+
+	restored := archived.ToReport(user)
+	assert.Equal(t, from, restored.From)
+	assert.Equal(t, 90*time.Minute, restored.Summary.TotalTime())
+	assert.Equal(t, SummaryLanguage, restored.Summary.Languages[0].Type)
+	assert.False(t, restored.Overtime.IsOvertime())
+	assert.Equal(t, 30*time.Minute, restored.Overtime.BalanceAbs())
+}
+
+func TestArchivedReport_IsValid(t *testing.T) {
+	from := time.Date(2022, 10, 24, 0, 0, 0, 0, time.UTC)
+	assert.True(t, (&ArchivedReport{UserID: "user1", Interval: ReportIntervalWeek, FromTime: CustomTime(from), ToTime: CustomTime(from.AddDate(0, 0, 7))}).IsValid())
+	assert.False(t, (&ArchivedReport{UserID: "user1", Interval: "year", FromTime: CustomTime(from), ToTime: CustomTime(from.AddDate(0, 0, 7))}).IsValid())
+	assert.False(t, (&ArchivedReport{UserID: "user1", Interval: ReportIntervalWeek, FromTime: CustomTime(from), ToTime: CustomTime(from)}).IsValid())
+}
@@ -0,0 +1,36 @@
+package models
+
+import "strings"
+
+const (
+	CalendarTitlesGeneric  = ""         // events are titled 'Focus time', without revealing what was worked on
+	CalendarTitlesProjects = "projects" // events are titled with the projects worked on, e.g. 'Coding: wakapi, mailwhale'
+)
+
+const (
+	calendarGenericTitle     = "Focus time"
+	calendarMaxTitleProjects = 3
+)
+
+// CalendarEvent keeps track of a coding session written to a user's google calendar, so that later syncs update it instead of creating duplicates
+type CalendarEvent struct {
+	ID        uint       `json:"-" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; uniqueIndex:idx_calendar_event_user_start"`
+	StartTime CustomTime `json:"start" gorm:"not null; type:timestamp; uniqueIndex:idx_calendar_event_user_start" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	EndTime   CustomTime `json:"end" gorm:"not null; type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	EventID   string     `json:"event_id"` // id of the event in google calendar
+	SyncedAt  CustomTime `json:"synced_at" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// Title returns the session's calendar event title, revealing as much detail as the given level permits
+func (s *Session) Title(titles string) string {
+	if titles != CalendarTitlesProjects || len(s.Projects) == 0 {
+		return calendarGenericTitle
+	}
+	projects := s.Projects
+	if len(projects) > calendarMaxTitleProjects {
+		projects = projects[:calendarMaxTitleProjects]
+	}
+	return "Coding: " + strings.Join(projects, ", ")
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ClockSkew is the systematic deviation of a machine's clock from the server's one, as estimated from the delay between a heartbeat's timestamp and its arrival
+type ClockSkew struct {
+	Machine   string        `json:"machine"`
+	Offset    time.Duration `json:"offset" swaggertype:"primitive,integer"` // positive if the machine's clock is ahead of the server's
+	Samples   int           `json:"samples"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+func (s *ClockSkew) IsAhead() bool {
+	return s.Offset > 0
+}
+
+// Abs returns the absolute offset, e.g. for display
+func (s *ClockSkew) Abs() time.Duration {
+	if s.Offset < 0 {
+		return -s.Offset
+	}
+	return s.Offset
+}
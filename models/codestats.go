@@ -0,0 +1,71 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Code::Stats awards experience points (xp) instead of tracking time, roughly one per keystroke.
+// Xp and coding time are converted into one another at a fixed rate, which only approximates an average typing speed.
+const CodeStatsXpPerMinute = 60
+
+// languages named differently by code::stats and wakapi, others are assumed to be equal
+var codeStatsLanguages = map[string]string{
+	"Plain text":   "Text",
+	"Shell script": "Bash",
+	"Vue":          "Vue.js",
+	"Golang":       "Go",
+}
+
+var codeStatsLanguagesReverse = map[string]string{
+	"Text":   "Plain text",
+	"Bash":   "Shell script",
+	"Vue.js": "Vue",
+}
+
+// CodeStatsPulse is a batch of xp per language as sent to code::stats' api
+type CodeStatsPulse struct {
+	CodedAt time.Time      `json:"coded_at"`
+	Xps     []*CodeStatsXp `json:"xps"`
+}
+
+type CodeStatsXp struct {
+	Language string `json:"language"`
+	Xp       int    `json:"xp"`
+}
+
+// CodeStatsDayXp is a code::stats user's xp of a single language on a single day
+type CodeStatsDayXp struct {
+	Date     string `json:"date"`
+	Language string `json:"language"`
+	Xp       int    `json:"xp"`
+}
+
+// CodeStatsExport is a user's progress of exporting xp to code::stats, which doesn't allow to change pulses once sent.
+// Days before Since are not exported, but left to be imported from code::stats instead, so that time is never counted twice.
+type CodeStatsExport struct {
+	Since    string `json:"since"`     // first day to export, e.g. '2006-01-02'
+	LastDate string `json:"last_date"` // last day exported so far
+}
+
+func LanguageFromCodeStats(language string) string {
+	if l, ok := codeStatsLanguages[language]; ok {
+		return l
+	}
+	return language
+}
+
+func LanguageToCodeStats(language string) string {
+	if l, ok := codeStatsLanguagesReverse[language]; ok {
+		return l
+	}
+	return language
+}
+
+func XpToDuration(xp int) time.Duration {
+	return time.Duration(xp) * time.Minute / CodeStatsXpPerMinute
+}
+
+func DurationToXp(d time.Duration) int {
+	return int(math.Round(d.Minutes() * CodeStatsXpPerMinute))
+}
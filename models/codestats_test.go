@@ -0,0 +1,23 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeStats_Conversion(t *testing.T) {
+	assert.Equal(t, 30*time.Minute, XpToDuration(1800))
+	assert.Equal(t, 1800, DurationToXp(30*time.Minute))
+	assert.Equal(t, 1, DurationToXp(time.Second))
+	assert.Zero(t, DurationToXp(0))
+}
+
+func TestCodeStats_Languages(t *testing.T) {
+	assert.Equal(t, "Text", LanguageFromCodeStats("Plain text"))
+	assert.Equal(t, "Go", LanguageFromCodeStats("Golang"))
+	assert.Equal(t, "Rust", LanguageFromCodeStats("Rust"))
+	assert.Equal(t, "Plain text", LanguageToCodeStats("Text"))
+	assert.Equal(t, "Go", LanguageToCodeStats("Go"))
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// MaxComparisonUsers is the maximum number of users to be compared at once
+const MaxComparisonUsers = 50
+
+// ComparisonLanguage is the time a single user spent on a language within the compared range
+type ComparisonLanguage struct {
+	Name         string  `json:"name"`
+	TotalSeconds int64   `json:"total"`
+	Percentage   float64 `json:"percentage"` // share of the user's total time
+}
+
+// ComparisonUser is a single user's side of a comparison
+type ComparisonUser struct {
+	UserID       string                `json:"user_id"`
+	Total        time.Duration         `json:"-"`
+	TotalSeconds int64                 `json:"total"`
+	Languages    []*ComparisonLanguage `json:"languages"`
+}
+
+// Comparison puts summaries of multiple users for the same range side by side, e.g. for mentors to keep track of a cohort's activity
+type Comparison struct {
+	From            time.Time         `json:"from"`
+	To              time.Time         `json:"to"`
+	Users           []*ComparisonUser `json:"users"`
+	CommonLanguages []string          `json:"common_languages"` // languages used by every compared user, most used first
+}
+
+// NewComparison builds a comparison from the given users' summaries, which are expected in the same order as the users
+func NewComparison(from, to time.Time, users []*User, summaries []*Summary) *Comparison {
+	comparison := &Comparison{
+		From:            from,
+		To:              to,
+		Users:           make([]*ComparisonUser, len(users)),
+		CommonLanguages: []string{},
+	}
+
+	languageTotals := make(map[string]time.Duration)
+	languageUsers := make(map[string]int)
+
+	for i, user := range users {
+		summary := summaries[i]
+		total := summary.TotalTime()
+		entry := &ComparisonUser{
+			UserID:       user.ID,
+			Total:        total,
+			TotalSeconds: int64(total.Seconds()),
+			Languages:    make([]*ComparisonLanguage, 0, len(summary.Languages)),
+		}
+
+		sort.Sort(sort.Reverse(summary.Languages))
+		for _, item := range summary.Languages {
+			var percentage float64
+			if total > 0 {
+				percentage = float64(item.TotalFixed()) / float64(total) * 100
+			}
+			entry.Languages = append(entry.Languages, &ComparisonLanguage{
+				Name:         item.Key,
+				TotalSeconds: int64(item.TotalFixed().Seconds()),
+				Percentage:   percentage,
+			})
+			if item.Key != UnknownSummaryKey && item.Total > 0 {
+				languageTotals[item.Key] += item.TotalFixed()
+				languageUsers[item.Key]++
+			}
+		}
+
+		comparison.Users[i] = entry
+	}
+
+	for language, count := range languageUsers {
+		if count == len(users) {
+			comparison.CommonLanguages = append(comparison.CommonLanguages, language)
+		}
+	}
+	sort.Slice(comparison.CommonLanguages, func(i, j int) bool {
+		a, b := comparison.CommonLanguages[i], comparison.CommonLanguages[j]
+		if languageTotals[a] == languageTotals[b] {
+			return a < b
+		}
+		return languageTotals[a] > languageTotals[b]
+	})
+
+	return comparison
+}
+
+// MaxTotal returns the longest total time of all compared users, e.g. to scale bars relative to it
+func (c *Comparison) MaxTotal() (max time.Duration) {
+	for _, u := range c.Users {
+		if u.Total > max {
+			max = u.Total
+		}
+	}
+	return max
+}
+
+// ShareOfMax returns the user's total time in percent of the longest total time of all compared users
+func (c *Comparison) ShareOfMax(u *ComparisonUser) float64 {
+	max := c.MaxTotal()
+	if max == 0 {
+		return 0
+	}
+	return float64(u.Total) / float64(max) * 100
+}
+
+// TopLanguages returns at most n of the user's languages with the most time
+func (u *ComparisonUser) TopLanguages(n int) []*ComparisonLanguage {
+	if len(u.Languages) <= n {
+		return u.Languages
+	}
+	return u.Languages[:n]
+}
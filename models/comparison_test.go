@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewComparison(t *testing.T) {
+	users := []*User{{ID: "alice"}, {ID: "bob"}}
+	summaries := []*Summary{
+		{Languages: []*SummaryItem{
+			{Type: SummaryLanguage, Key: "Go", Total: 60},
+			{Type: SummaryLanguage, Key: "Python", Total: 20},
+			{Type: SummaryLanguage, Key: "Rust", Total: 20},
+		}},
+		{Languages: []*SummaryItem{
+			{Type: SummaryLanguage, Key: "Python", Total: 50},
+			{Type: SummaryLanguage, Key: "Go", Total: 10},
+			{Type: SummaryLanguage, Key: UnknownSummaryKey, Total: 40},
+		}},
+	}
+
+	sut := NewComparison(time.Now().Add(-24*time.Hour), time.Now(), users, summaries)
+
+	assert.Len(t, sut.Users, 2)
+	assert.Equal(t, "alice", sut.Users[0].UserID)
+	assert.Equal(t, int64(100), sut.Users[0].TotalSeconds)
+	assert.Equal(t, "Go", sut.Users[0].Languages[0].Name)
+	assert.Equal(t, 60.0, sut.Users[0].Languages[0].Percentage)
+	assert.Equal(t, "bob", sut.Users[1].UserID)
+	assert.Equal(t, "Python", sut.Users[1].Languages[0].Name)
+	assert.Equal(t, []string{"Go", "Python"}, sut.CommonLanguages)
+	assert.Equal(t, 100*time.Second, sut.MaxTotal())
+	assert.Equal(t, 100.0, sut.ShareOfMax(sut.Users[1]))
+	assert.Len(t, sut.Users[0].TopLanguages(1), 1)
+}
@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"github.com/muety/wakapi/models"
+)
+
+type DurationsViewModel struct {
+	Data     []*DurationEntry `json:"data"`
+	End      string           `json:"end"`
+	Start    string           `json:"start"`
+	Timezone string           `json:"timezone"`
+}
+
+// DurationEntry mimics https://wakatime.com/developers#durations, extended by some of wakapi's own attributes
+type DurationEntry struct {
+	Project         string  `json:"project"`
+	Language        string  `json:"language"`
+	Editor          string  `json:"editor"`
+	OperatingSystem string  `json:"operating_system"`
+	Machine         string  `json:"machine_name_id"`
+	Branch          string  `json:"branch"`
+	Time            float64 `json:"time"`
+	Duration        float64 `json:"duration"`
+}
+
+func DurationsToCompat(durations models.Durations) []*DurationEntry {
+	out := make([]*DurationEntry, len(durations))
+	for i, d := range durations {
+		out[i] = &DurationEntry{
+			Project:         d.Project,
+			Language:        d.Language,
+			Editor:          d.Editor,
+			OperatingSystem: d.OperatingSystem,
+			Machine:         d.Machine,
+			Branch:          d.Branch,
+			Time:            float64(d.Time.T().UnixNano()) / 1e9,
+			Duration:        d.Duration.Seconds(),
+		}
+	}
+	return out
+}
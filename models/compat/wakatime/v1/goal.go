@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"strconv"
+
+	"github.com/muety/wakapi/models"
+)
+
+// partially compatible with https://wakatime.com/developers#goals
+
+const (
+	GoalStatusSuccess = "success"
+	GoalStatusPending = "pending"
+)
+
+type GoalsViewModel struct {
+	Data []*Goal `json:"data"`
+	Pagination
+}
+
+type Goal struct {
+	ID                      string   `json:"id"`
+	Title                   string   `json:"title"`
+	Delta                   string   `json:"delta"`   // one of 'day', 'week' or 'month'
+	Seconds                 int64    `json:"seconds"` // target per interval
+	Languages               []string `json:"languages"`
+	Editors                 []string `json:"editors"`
+	IsEnabled               bool     `json:"is_enabled"`
+	Status                  string   `json:"status"` // of the current interval
+	StatusPercentCalculated int      `json:"status_percent_calculated"`
+}
+
+func NewGoal(progress *models.GoalProgress) *Goal {
+	goal := &Goal{
+		ID:                      strconv.Itoa(int(progress.Goal.ID)),
+		Title:                   progress.Goal.Title,
+		Delta:                   progress.Goal.Interval,
+		Seconds:                 progress.TargetSeconds,
+		Languages:               []string{},
+		Editors:                 []string{},
+		IsEnabled:               true,
+		Status:                  GoalStatusPending,
+		StatusPercentCalculated: int(progress.BarPercentage()),
+	}
+	if progress.Goal.Language != "" {
+		goal.Languages = append(goal.Languages, progress.Goal.Language)
+	}
+	if progress.Goal.Editor != "" {
+		goal.Editors = append(goal.Editors, progress.Goal.Editor)
+	}
+	if progress.IsReached() {
+		goal.Status = GoalStatusSuccess
+	}
+	return goal
+}
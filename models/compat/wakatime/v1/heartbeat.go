@@ -15,19 +15,22 @@ type HeartbeatsViewModel struct {
 // that is actually required for the import
 
 type HeartbeatEntry struct {
-	Id            string    `json:"id"`
-	Branch        string    `json:"branch"`
-	Category      string    `json:"category"`
-	Entity        string    `json:"entity"`
-	IsWrite       bool      `json:"is_write"`
-	Language      string    `json:"language"`
-	Project       string    `json:"project"`
-	Time          float64   `json:"time"`
-	Type          string    `json:"type"`
-	UserId        string    `json:"user_id"`
-	MachineNameId string    `json:"machine_name_id"`
-	UserAgentId   string    `json:"user_agent_id"`
-	CreatedAt     time.Time `json:"created_at"`
+	Id            string                   `json:"id"`
+	Branch        string                   `json:"branch"`
+	Category      string                   `json:"category"`
+	Entity        string                   `json:"entity"`
+	IsWrite       bool                     `json:"is_write"`
+	Lines         int                      `json:"lines"`
+	CursorPos     int                      `json:"cursorpos"`
+	Language      string                   `json:"language"`
+	Project       string                   `json:"project"`
+	Time          float64                  `json:"time"`
+	Type          string                   `json:"type"`
+	UserId        string                   `json:"user_id"`
+	MachineNameId string                   `json:"machine_name_id"`
+	UserAgentId   string                   `json:"user_agent_id"`
+	CreatedAt     time.Time                `json:"created_at"`
+	Metadata      models.HeartbeatMetadata `json:"metadata,omitempty"`
 }
 
 func HeartbeatsToCompat(entries []*models.Heartbeat) []*HeartbeatEntry {
@@ -40,6 +43,8 @@ func HeartbeatsToCompat(entries []*models.Heartbeat) []*HeartbeatEntry {
 			Category:      entry.Category,
 			Entity:        entry.Entity,
 			IsWrite:       entry.IsWrite,
+			Lines:         entry.Lines,
+			CursorPos:     entry.CursorPos,
 			Language:      entry.Language,
 			Project:       entry.Project,
 			Time:          float64(entry.Time.T().Unix()),
@@ -48,6 +53,7 @@ func HeartbeatsToCompat(entries []*models.Heartbeat) []*HeartbeatEntry {
 			MachineNameId: entry.Machine,
 			UserAgentId:   entry.UserAgent,
 			CreatedAt:     entry.CreatedAt.T(),
+			Metadata:      entry.Metadata,
 		}
 	}
 	return out
@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+// partially compatible with https://wakatime.com/developers#leaders
+
+type LeadersViewModel struct {
+	Data []*LeadersEntry `json:"data"`
+	Pagination
+}
+
+type LeadersEntry struct {
+	Rank         int                  `json:"rank"`
+	RunningTotal *LeadersRunningTotal `json:"running_total"`
+	User         *LeadersUser         `json:"user"`
+}
+
+type LeadersRunningTotal struct {
+	TotalSeconds       float64            `json:"total_seconds"`
+	HumanReadableTotal string             `json:"human_readable_total"`
+	Languages          []*LeadersLanguage `json:"languages"`
+}
+
+type LeadersLanguage struct {
+	Name string `json:"name"`
+}
+
+type LeadersUser struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	Username    string `json:"username"`
+}
+
+func NewLeadersEntry(item *models.LeaderboardItem) *LeadersEntry {
+	languages := make([]*LeadersLanguage, len(item.Languages))
+	for i, l := range item.Languages {
+		languages[i] = &LeadersLanguage{Name: l}
+	}
+
+	return &LeadersEntry{
+		Rank: item.Rank,
+		RunningTotal: &LeadersRunningTotal{
+			TotalSeconds:       item.Total().Seconds(),
+			HumanReadableTotal: utils.FmtWakatimeDuration(item.Total()),
+			Languages:          languages,
+		},
+		User: &LeadersUser{
+			ID:          item.UserID,
+			DisplayName: item.UserID,
+			Username:    item.UserID,
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/muety/wakapi/models"
+)
+
+// partially compatible with https://wakatime.com/developers#editors and https://wakatime.com/developers#program_languages
+
+type EditorsViewModel struct {
+	Data  []*Editor `json:"data"`
+	Total int       `json:"total"`
+}
+
+type Editor struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type ProgramLanguagesViewModel struct {
+	Data  []*ProgramLanguage `json:"data"`
+	Total int                `json:"total"`
+}
+
+type ProgramLanguage struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Color      string `json:"color"`
+	Icon       string `json:"icon"` // not part of wakatime's api
+	IsVerified bool   `json:"is_verified"`
+}
+
+// NewEditorsViewModel expects editor colors by name, which is their plugin's name, e.g. 'vscode', as used as editor key in summaries
+func NewEditorsViewModel(colors map[string]string) *EditorsViewModel {
+	editors := make([]*Editor, 0, len(colors))
+	for name, color := range colors {
+		editors = append(editors, &Editor{ID: strings.ToLower(name), Name: name, Color: color})
+	}
+	sort.Slice(editors, func(i, j int) bool {
+		return editors[i].ID < editors[j].ID
+	})
+	return &EditorsViewModel{Data: editors, Total: len(editors)}
+}
+
+// NewProgramLanguagesViewModel lists the given languages, of which only those of github-linguist count as verified
+func NewProgramLanguagesViewModel(meta []*models.LanguageMeta) *ProgramLanguagesViewModel {
+	languages := make([]*ProgramLanguage, len(meta))
+	for i, l := range meta {
+		languages[i] = &ProgramLanguage{ID: l.Key, Name: l.Name, Color: l.Color, Icon: l.Icon, IsVerified: !l.Custom && l.Color != ""}
+	}
+	return &ProgramLanguagesViewModel{Data: languages, Total: len(languages)}
+}
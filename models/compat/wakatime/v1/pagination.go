@@ -0,0 +1,9 @@
+package v1
+
+// Pagination is embedded into paginated list responses, see https://wakatime.com/developers#leaders
+type Pagination struct {
+	Total      int  `json:"total"`
+	TotalPages int  `json:"total_pages"`
+	Page       int  `json:"page"`
+	NextPage   *int `json:"next_page"` // null on the last page
+}
@@ -1,11 +1,15 @@
 package v1
 
+import "time"
+
 type ProjectsViewModel struct {
 	Data []*Project `json:"data"`
+	Pagination
 }
 
 type Project struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	Repository string `json:"repository"`
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	Repository      string     `json:"repository"`
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at,omitempty"`
 }
@@ -21,17 +21,27 @@ type StatsData struct {
 	TotalSeconds          float64           `json:"total_seconds"`
 	DailyAverage          float64           `json:"daily_average"`
 	DaysIncludingHolidays int               `json:"days_including_holidays"`
+	DaysMinusHolidays     int               `json:"days_minus_holidays"`
+	Holidays              int               `json:"holidays"`
+	WriteSeconds          float64           `json:"write_seconds"`
+	WritePercent          float64           `json:"write_percent"`
+	LinesChanged          int               `json:"lines_changed"`
 	Editors               []*SummariesEntry `json:"editors"`
 	Languages             []*SummariesEntry `json:"languages"`
 	Machines              []*SummariesEntry `json:"machines"`
 	Projects              []*SummariesEntry `json:"projects"`
 	OperatingSystems      []*SummariesEntry `json:"operating_systems"`
 	Branches              []*SummariesEntry `json:"branches,omitempty"`
+	Labels                []*SummariesEntry `json:"labels"` // not part of wakatime's api
 }
 
-func NewStatsFrom(summary *models.Summary, filters *models.Filters) *StatsViewModel {
+// NewStatsFrom creates stats from the given summary, whose daily average excludes the number of holidays (i.e. days off) within its interval
+func NewStatsFrom(summary *models.Summary, filters *models.Filters, holidays int) *StatsViewModel {
 	totalTime := summary.TotalTime()
 	numDays := int(summary.ToTime.T().Sub(summary.FromTime.T()).Hours() / 24)
+	if holidays > numDays {
+		holidays = numDays
+	}
 
 	data := &StatsData{
 		Username:              summary.UserID,
@@ -39,8 +49,16 @@ func NewStatsFrom(summary *models.Summary, filters *models.Filters) *StatsViewMo
 		Start:                 summary.FromTime.T(),
 		End:                   summary.ToTime.T(),
 		TotalSeconds:          totalTime.Seconds(),
-		DailyAverage:          totalTime.Seconds() / float64(numDays),
+		DailyAverage:          totalTime.Seconds() / float64(numDays-holidays),
 		DaysIncludingHolidays: numDays,
+		DaysMinusHolidays:     numDays - holidays,
+		Holidays:              holidays,
+		WriteSeconds:          summary.TotalWriteTime().Seconds(),
+		LinesChanged:          summary.TotalLinesChanged(),
+	}
+
+	if totalTime > 0 {
+		data.WritePercent = math.Round(summary.TotalWriteTime().Seconds()/totalTime.Seconds()*1e4) / 100
 	}
 
 	if math.IsInf(data.DailyAverage, 0) || math.IsNaN(data.DailyAverage) {
@@ -65,6 +83,8 @@ func NewStatsFrom(summary *models.Summary, filters *models.Filters) *StatsViewMo
 	projects := make([]*SummariesEntry, len(summary.Projects))
 	for i, e := range summary.Projects {
 		projects[i] = convertEntry(e, summary.TotalTimeBy(models.SummaryProject))
+		projects[i].WritePercent = e.WritePercentage()
+		projects[i].LinesChanged = e.Lines
 	}
 
 	oss := make([]*SummariesEntry, len(summary.OperatingSystems))
@@ -77,12 +97,19 @@ func NewStatsFrom(summary *models.Summary, filters *models.Filters) *StatsViewMo
 		branches[i] = convertEntry(e, summary.TotalTimeBy(models.SummaryBranch))
 	}
 
+	// projects can have multiple labels, so percentages refer to the total time instead of the sum of all labels
+	labels := make([]*SummariesEntry, len(summary.Labels))
+	for i, e := range summary.Labels {
+		labels[i] = convertEntry(e, totalTime)
+	}
+
 	data.Editors = editors
 	data.Languages = languages
 	data.Machines = machines
 	data.Projects = projects
 	data.OperatingSystems = oss
 	data.Branches = branches
+	data.Labels = labels
 
 	if summary.Branches == nil {
 		data.Branches = nil
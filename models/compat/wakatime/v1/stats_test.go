@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStatsFrom_Labels(t *testing.T) {
+	sut := NewStatsFrom(labeledSummary(), &models.Filters{}, 0)
+
+	// a project's time counts towards each of its labels, so label percentages don't add up to 100
+	assert.Len(t, sut.Data.Labels, 2)
+	assert.Equal(t, "oss", sut.Data.Labels[0].Name)
+	assert.Equal(t, 5400.0, sut.Data.Labels[0].TotalSeconds)
+	assert.Equal(t, 100.0, sut.Data.Labels[0].Percent)
+	assert.Equal(t, "work", sut.Data.Labels[1].Name)
+	assert.Equal(t, 3600.0, sut.Data.Labels[1].TotalSeconds)
+	assert.Equal(t, 66.67, sut.Data.Labels[1].Percent)
+
+	// projects themselves still add up to 100
+	assert.Equal(t, 66.67, sut.Data.Projects[0].Percent)
+	assert.Equal(t, 33.33, sut.Data.Projects[1].Percent)
+}
+
+func TestNewStatsFrom_NoLabels(t *testing.T) {
+	summary := labeledSummary()
+	summary.Labels = nil
+
+	sut := NewStatsFrom(summary, &models.Filters{}, 0)
+	assert.NotNil(t, sut.Data.Labels)
+	assert.Empty(t, sut.Data.Labels)
+}
+
+func TestNewSummariesFrom_Labels(t *testing.T) {
+	sut := NewSummariesFrom([]*models.Summary{labeledSummary()})
+
+	assert.Len(t, sut.Data, 1)
+	assert.Len(t, sut.Data[0].Labels, 2)
+	assert.Equal(t, "oss", sut.Data[0].Labels[0].Name)
+	assert.Equal(t, 100.0, sut.Data[0].Labels[0].Percent)
+	assert.Equal(t, "work", sut.Data[0].Labels[1].Name)
+	assert.Equal(t, 66.67, sut.Data[0].Labels[1].Percent)
+}
+
+func labeledSummary() *models.Summary {
+	from := time.Date(2022, 10, 10, 0, 0, 0, 0, time.Local)
+
+	// hack to work around the issue that the total time of a summary item is mistakenly represented in seconds
+	return &models.Summary{
+		UserID:   "muety",
+		FromTime: models.CustomTime(from),
+		ToTime:   models.CustomTime(from.AddDate(0, 0, 7)),
+		Projects: []*models.SummaryItem{
+			{Type: models.SummaryProject, Key: "wakapi", Total: 60 * time.Minute / time.Second},
+			{Type: models.SummaryProject, Key: "anchr", Total: 30 * time.Minute / time.Second},
+		},
+		Labels: []*models.SummaryItem{
+			{Type: models.SummaryLabel, Key: "oss", Total: 90 * time.Minute / time.Second},  // wakapi and anchr
+			{Type: models.SummaryLabel, Key: "work", Total: 60 * time.Minute / time.Second}, // wakapi only
+		},
+	}
+}
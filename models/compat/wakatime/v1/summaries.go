@@ -27,6 +27,7 @@ type SummariesData struct {
 	OperatingSystems []*SummariesEntry    `json:"operating_systems"`
 	Projects         []*SummariesEntry    `json:"projects"`
 	Branches         []*SummariesEntry    `json:"branches,omitempty"`
+	Labels           []*SummariesEntry    `json:"labels"` // not part of wakatime's api
 	GrandTotal       *SummariesGrandTotal `json:"grand_total"`
 	Range            *SummariesRange      `json:"range"`
 }
@@ -40,6 +41,8 @@ type SummariesEntry struct {
 	Seconds      int     `json:"seconds"`
 	Text         string  `json:"text"`
 	TotalSeconds float64 `json:"total_seconds"`
+	WritePercent float64 `json:"write_percent,omitempty"` // non-standard, only set for projects in stats
+	LinesChanged int     `json:"lines_changed,omitempty"` // non-standard, only set for projects in stats
 }
 
 type SummariesGrandTotal struct {
@@ -58,6 +61,41 @@ type SummariesRange struct {
 	Timezone string    `json:"timezone"`
 }
 
+// ToSummary adds up all days of the summaries, e.g. as retrieved from another instance, into a single summary
+func (m *SummariesViewModel) ToSummary() *models.Summary {
+	summary := &models.Summary{
+		FromTime: models.CustomTime(m.Start),
+		ToTime:   models.CustomTime(m.End),
+	}
+
+	for _, d := range m.Data {
+		entries := map[uint8][]*SummariesEntry{
+			models.SummaryProject:  d.Projects,
+			models.SummaryLanguage: d.Languages,
+			models.SummaryEditor:   d.Editors,
+			models.SummaryOS:       d.OperatingSystems,
+			models.SummaryMachine:  d.Machines,
+			models.SummaryBranch:   d.Branches,
+			models.SummaryLabel:    d.Labels,
+		}
+		for t, typeEntries := range entries {
+			items := summary.ItemsByType(t)
+			for _, e := range typeEntries {
+				item := &models.SummaryItem{
+					Type:  t,
+					Key:   e.Name,
+					Total: time.Duration(e.TotalSeconds), // workaround, see models.SummaryItem
+					Lines: e.LinesChanged,
+				}
+				*items = append(*items, item)
+			}
+		}
+	}
+
+	// adds up the same keys from multiple days
+	return summary.Combined(&models.Summary{})
+}
+
 func NewSummariesFrom(summaries []*models.Summary) *SummariesViewModel {
 	data := make([]*SummariesData, len(summaries))
 	minDate, maxDate := time.Now().Add(1*time.Second), time.Time{}
@@ -94,6 +132,7 @@ func newDataFrom(s *models.Summary) *SummariesData {
 		OperatingSystems: make([]*SummariesEntry, len(s.OperatingSystems)),
 		Projects:         make([]*SummariesEntry, len(s.Projects)),
 		Branches:         make([]*SummariesEntry, len(s.Branches)),
+		Labels:           make([]*SummariesEntry, len(s.Labels)),
 		GrandTotal: &SummariesGrandTotal{
 			Digital:      fmt.Sprintf("%d:%d", totalHrs, totalMins),
 			Hours:        totalHrs,
@@ -111,7 +150,7 @@ func newDataFrom(s *models.Summary) *SummariesData {
 	}
 
 	var wg sync.WaitGroup
-	wg.Add(6)
+	wg.Add(7)
 
 	go func(data *SummariesData) {
 		defer wg.Done()
@@ -155,6 +194,14 @@ func newDataFrom(s *models.Summary) *SummariesData {
 		}
 	}(data)
 
+	go func(data *SummariesData) {
+		defer wg.Done()
+		// projects can have multiple labels, so percentages refer to the total time instead of the sum of all labels
+		for i, e := range s.Labels {
+			data.Labels[i] = convertEntry(e, total)
+		}
+	}(data)
+
 	if s.Branches == nil {
 		data.Branches = nil
 	}
@@ -0,0 +1,87 @@
+package models
+
+import "strings"
+
+// Widgets, i.e. cards, shown on the dashboard
+const (
+	WidgetKpis         = "kpis"
+	WidgetProjects     = "projects"
+	WidgetLanguages    = "languages"
+	WidgetEditors      = "editors"
+	WidgetSystems      = "systems" // operating systems and machines
+	WidgetLabels       = "labels"
+	WidgetRepositories = "repositories"
+	WidgetBrowsing     = "browsing"
+	WidgetActivity     = "activity" // writing vs. reading
+	WidgetAchievements = "achievements"
+)
+
+// DashboardWidgets are all available widgets in their default order
+var DashboardWidgets = []string{WidgetKpis, WidgetProjects, WidgetLanguages, WidgetEditors, WidgetSystems, WidgetLabels, WidgetRepositories, WidgetBrowsing, WidgetActivity, WidgetAchievements}
+
+// DashboardChartWidgets are the widgets, which are laid out in a common grid
+var DashboardChartWidgets = []string{WidgetProjects, WidgetLanguages, WidgetEditors, WidgetSystems, WidgetLabels}
+
+// DashboardLayout defines which widgets are shown on a user's dashboard in which order and what time range it shows by default
+type DashboardLayout struct {
+	Widgets      []string `json:"widgets"`       // in order of appearance, all others are hidden
+	DefaultRange string   `json:"default_range"` // interval key, e.g. 'week', or empty for today
+}
+
+func NewDefaultDashboardLayout() *DashboardLayout {
+	return &DashboardLayout{Widgets: DashboardWidgets}
+}
+
+func (l *DashboardLayout) IsValid() bool {
+	seen := make(map[string]bool)
+	for _, w := range l.Widgets {
+		if seen[w] || !isDashboardWidget(w) {
+			return false
+		}
+		seen[w] = true
+	}
+	if l.DefaultRange == "" {
+		return true
+	}
+	for _, i := range AllIntervals {
+		if i.HasAlias(l.DefaultRange) {
+			return true
+		}
+	}
+	return false
+}
+
+// Position returns the 1-based position of the given widget or 0, if it is hidden
+func (l *DashboardLayout) Position(widget string) int {
+	for i, w := range l.Widgets {
+		if w == widget {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// ChartsPosition returns the position of the grid of chart widgets, which is the one of the first chart shown, or 0 if none is shown
+func (l *DashboardLayout) ChartsPosition() int {
+	for i, w := range l.Widgets {
+		for _, c := range DashboardChartWidgets {
+			if w == c {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+func (l *DashboardLayout) String() string {
+	return strings.Join(l.Widgets, ",")
+}
+
+func isDashboardWidget(widget string) bool {
+	for _, w := range DashboardWidgets {
+		if w == widget {
+			return true
+		}
+	}
+	return false
+}
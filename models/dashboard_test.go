@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDashboardLayout_IsValid(t *testing.T) {
+	assert.True(t, NewDefaultDashboardLayout().IsValid())
+	assert.True(t, (&DashboardLayout{Widgets: []string{WidgetLanguages, WidgetKpis}, DefaultRange: "week"}).IsValid())
+	assert.True(t, (&DashboardLayout{Widgets: []string{}}).IsValid())
+	assert.False(t, (&DashboardLayout{Widgets: []string{WidgetLanguages, "foo"}}).IsValid())
+	assert.False(t, (&DashboardLayout{Widgets: []string{WidgetLanguages, WidgetLanguages}}).IsValid())
+	assert.False(t, (&DashboardLayout{Widgets: []string{WidgetLanguages}, DefaultRange: "forever"}).IsValid())
+}
+
+func TestDashboardLayout_Position(t *testing.T) {
+	sut := &DashboardLayout{Widgets: []string{WidgetKpis, WidgetAchievements, WidgetEditors, WidgetProjects}}
+
+	assert.Equal(t, 1, sut.Position(WidgetKpis))
+	assert.Equal(t, 4, sut.Position(WidgetProjects))
+	assert.Equal(t, 0, sut.Position(WidgetLanguages))
+	assert.Equal(t, 3, sut.ChartsPosition())
+	assert.Equal(t, 0, (&DashboardLayout{Widgets: []string{WidgetKpis}}).ChartsPosition())
+}
+
+func TestUser_DashboardLayout(t *testing.T) {
+	sut := &User{}
+	assert.Equal(t, DashboardWidgets, sut.DashboardLayout().Widgets)
+	assert.Empty(t, sut.DashboardLayout().DefaultRange)
+
+	sut.SetDashboardLayout(&DashboardLayout{Widgets: []string{WidgetProjects, WidgetKpis}, DefaultRange: "week"})
+	assert.Equal(t, "projects,kpis", sut.DashboardWidgets)
+	assert.Equal(t, []string{WidgetProjects, WidgetKpis}, sut.DashboardLayout().Widgets)
+	assert.Equal(t, "week", sut.DashboardLayout().DefaultRange)
+}
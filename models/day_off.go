@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+const (
+	DayOffFormat   = "2006-01-02"
+	DayOffVacation = "vacation"
+	DayOffSick     = "sick"
+)
+
+// DayOff marks a calendar day (in the user's time zone), on which the user didn't intend to work, e.g. because of vacation or sickness.
+// Such days neither break streaks nor count towards averages.
+type DayOff struct {
+	ID     uint   `json:"-" gorm:"primary_key"`
+	User   *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID string `json:"-" gorm:"not null; uniqueIndex:idx_day_off_user_day"`
+	Day    string `json:"day" gorm:"not null; size:10; uniqueIndex:idx_day_off_user_day" example:"2022-10-24"`
+	Kind   string `json:"kind" gorm:"not null; size:16" enums:"vacation,sick"`
+}
+
+func (d *DayOff) IsValid() bool {
+	if _, err := time.Parse(DayOffFormat, d.Day); err != nil {
+		return false
+	}
+	return d.Kind == DayOffVacation || d.Kind == DayOffSick
+}
+
+// DaysOff are a user's days off, mapped by their date
+type DaysOff map[string]*DayOff
+
+func NewDaysOff(days []*DayOff) DaysOff {
+	daysOff := make(DaysOff, len(days))
+	for _, d := range days {
+		daysOff[d.Day] = d
+	}
+	return daysOff
+}
+
+// IsOff tells whether the day of the given time, as seen in its location, is a day off
+func (d DaysOff) IsOff(t time.Time) bool {
+	_, ok := d[t.Format(DayOffFormat)]
+	return ok
+}
+
+// CountWithin returns the number of days off between from (inclusive) and to (exclusive), both expected to be in the user's time zone
+func (d DaysOff) CountWithin(from, to time.Time) int {
+	var count int
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if d.IsOff(day) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDayOff_IsValid(t *testing.T) {
+	assert.True(t, (&DayOff{Day: "2022-10-24", Kind: DayOffVacation}).IsValid())
+	assert.True(t, (&DayOff{Day: "2022-10-24", Kind: DayOffSick}).IsValid())
+	assert.False(t, (&DayOff{Day: "2022-10-24", Kind: "party"}).IsValid())
+	assert.False(t, (&DayOff{Day: "24.10.2022", Kind: DayOffVacation}).IsValid())
+	assert.False(t, (&DayOff{Kind: DayOffVacation}).IsValid())
+}
+
+func TestDaysOff_CountWithin(t *testing.T) {
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	daysOff := NewDaysOff([]*DayOff{
+		{Day: "2022-10-23", Kind: DayOffSick},
+		{Day: "2022-10-24", Kind: DayOffVacation},
+		{Day: "2022-10-25", Kind: DayOffVacation},
+		{Day: "2022-10-31", Kind: DayOffVacation},
+	})
+
+	from := time.Date(2022, 10, 24, 0, 0, 0, 0, tz)
+	to := time.Date(2022, 10, 31, 0, 0, 0, 0, tz)
+
+	assert.True(t, daysOff.IsOff(from))
+	assert.True(t, daysOff.IsOff(from.Add(23*time.Hour)))
+	assert.False(t, daysOff.IsOff(from.Add(48*time.Hour)))
+	assert.Equal(t, 2, daysOff.CountWithin(from, to))
+	assert.Equal(t, 4, daysOff.CountWithin(from.AddDate(0, 0, -1), to.AddDate(0, 0, 1)))
+	assert.Equal(t, 0, daysOff.CountWithin(to, from))
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// DoctorReport summarizes the inconsistencies found (and possibly repaired) during a data integrity check
+type DoctorReport struct {
+	From                time.Time                `json:"from"`
+	To                  time.Time                `json:"to"`
+	OrphanedSummaries   int                      `json:"orphaned_summaries"`
+	OrphanedAliases     int                      `json:"orphaned_aliases"`
+	MismatchedSummaries []*DoctorSummaryMismatch `json:"mismatched_summaries"`
+	DuplicateSummaries  []*DoctorSummaryMismatch `json:"duplicate_summaries"`
+	SkippedUsers        []string                 `json:"skipped_users"` // users, whose summaries were being generated concurrently
+	Repaired            bool                     `json:"repaired"`
+}
+
+// DoctorSummaryMismatch is a day, whose persisted summaries disagree with the one recomputed from raw heartbeats,
+// either in total time or because there is more than one summary for that day
+type DoctorSummaryMismatch struct {
+	UserID     string        `json:"user_id"`
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	Count      int           `json:"count"` // number of persisted summaries for that day
+	Persisted  time.Duration `json:"persisted" swaggertype:"primitive,integer"`
+	Recomputed time.Duration `json:"recomputed" swaggertype:"primitive,integer"`
+}
+
+func (r *DoctorReport) IsHealthy() bool {
+	return r.OrphanedSummaries == 0 && r.OrphanedAliases == 0 && len(r.MismatchedSummaries) == 0 && len(r.DuplicateSummaries) == 0
+}
@@ -7,9 +7,19 @@ import (
 	"time"
 )
 
+const (
+	DurationStrategyDefault = ""      // heartbeats from all machines form a single timeline, parallel activity is counted once
+	DurationStrategyMerge   = "merge" // like default, but heartbeats for the same entity reported from another machine only moments later are dropped, so durations won't alternate between machines
+	DurationStrategySum     = "sum"   // durations are computed per machine and summed up, parallel activity is counted multiple times
+)
+
+// Duration is a span of continuous activity on the same project, language, etc., merged from consecutive heartbeats.
+// Durations of past days are materialized, i.e. persisted, so that they don't have to be recomputed from raw heartbeats on every request.
 type Duration struct {
-	UserID          string        `json:"user_id"`
-	Time            CustomTime    `json:"time" hash:"ignore"`
+	ID              uint64        `json:"-" gorm:"primary_key" hash:"ignore"`
+	User            *User         `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" hash:"ignore"`
+	UserID          string        `json:"user_id" gorm:"not null; index:idx_duration_user_time"`
+	Time            CustomTime    `json:"time" gorm:"type:timestamp; index:idx_duration_user_time" swaggertype:"primitive,number" hash:"ignore"`
 	Duration        time.Duration `json:"duration" hash:"ignore"`
 	Project         string        `json:"project"`
 	Language        string        `json:"language"`
@@ -17,8 +27,12 @@ type Duration struct {
 	OperatingSystem string        `json:"operating_system"`
 	Machine         string        `json:"machine"`
 	Branch          string        `json:"branch"`
+	Origin          string        `json:"origin"`
+	Domain          string        `json:"domain"`                       // only set for browsing activity
+	WriteDuration   time.Duration `json:"write_duration" hash:"ignore"` // share of the duration spent writing, i.e. preceding a write heartbeat
+	LinesChanged    int           `json:"lines_changed" hash:"ignore"`  // approximated by the differences in line counts between consecutive heartbeats per entity
 	NumHeartbeats   int           `json:"-" hash:"ignore"`
-	GroupHash       string        `json:"-" hash:"ignore"`
+	GroupHash       string        `json:"-" gorm:"-" hash:"ignore"`
 }
 
 func NewDurationFromHeartbeat(h *Heartbeat) *Duration {
@@ -32,6 +46,8 @@ func NewDurationFromHeartbeat(h *Heartbeat) *Duration {
 		OperatingSystem: h.OperatingSystem,
 		Machine:         h.Machine,
 		Branch:          h.Branch,
+		Origin:          h.OriginKind(),
+		Domain:          h.Domain(),
 		NumHeartbeats:   1,
 	}
 	return d.Hashed()
@@ -46,6 +62,10 @@ func (d *Duration) Hashed() *Duration {
 	return d
 }
 
+func (d *Duration) IsBrowsing() bool {
+	return d.Domain != ""
+}
+
 func (d *Duration) GetKey(t uint8) (key string) {
 	switch t {
 	case SummaryProject:
@@ -60,6 +80,12 @@ func (d *Duration) GetKey(t uint8) (key string) {
 		key = d.Machine
 	case SummaryBranch:
 		key = d.Branch
+	case SummaryTicket:
+		key = TicketFromBranch(d.Branch)
+	case SummaryOrigin:
+		key = d.Origin
+	case SummaryDomain:
+		key = d.Domain
 	}
 
 	if key == "" {
@@ -0,0 +1,11 @@
+package models
+
+// DurationDay marks a past utc day, for which a user's durations are materialized, i.e. persisted in the durations table.
+// Markers are removed within the same transaction as any change to the day's heartbeats, causing its durations to be recomputed on next access.
+type DurationDay struct {
+	ID       uint       `gorm:"primary_key"`
+	User     *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID   string     `gorm:"not null; uniqueIndex:idx_duration_day_user_day"`
+	Day      CustomTime `gorm:"not null; type:timestamp; uniqueIndex:idx_duration_day_user_day" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	Strategy string     // duration strategy the day's durations were computed with, see DurationStrategyDefault
+}
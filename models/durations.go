@@ -44,3 +44,16 @@ func (d *Durations) Last() *Duration {
 	}
 	return (*d)[d.Len()-1]
 }
+
+// SplitBrowsing separates browsing activity from any other activity
+func (d Durations) SplitBrowsing() (browsing Durations, other Durations) {
+	browsing, other = make(Durations, 0), make(Durations, 0, len(d))
+	for _, e := range d {
+		if e.IsBrowsing() {
+			browsing = append(browsing, e)
+		} else {
+			other = append(other, e)
+		}
+	}
+	return browsing, other
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Endpoints an embed token can be restricted to
+const (
+	EmbedScopeBadge  = "badge"  // shields.io badges
+	EmbedScopeStats  = "stats"  // wakatime-compatible stats, e.g. for readme stats cards
+	EmbedScopeCharts = "charts" // wakatime-compatible daily summaries, e.g. for activity charts
+)
+
+var embedScopes = []string{EmbedScopeBadge, EmbedScopeStats, EmbedScopeCharts}
+
+// EmbedToken grants read-only access to a user's own data via a few public endpoints, as if the user had shared all of it.
+// Other than the api key, it can be hard-coded into public html (e.g. a badge's url), as it's useless for anything else.
+// Optionally, it's only accepted from pages at the given hosts, as told by the request's Referer or Origin header.
+type EmbedToken struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	User      *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string     `json:"-" gorm:"not null; index:idx_embed_token_user"`
+	Token     string     `json:"token" gorm:"not null; uniqueIndex"`
+	Name      string     `json:"name" gorm:"size:64"`
+	Scopes    string     `json:"scopes"`    // comma-separated list of scopes, e.g. 'badge,stats'
+	Referrers string     `json:"referrers"` // comma-separated list of hosts, e.g. 'example.org,*.github.io', any if empty
+	CreatedAt CustomTime `json:"created_at" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func (t *EmbedToken) IsValid() bool {
+	if t.UserID == "" || t.Token == "" || len(t.Name) > 64 || len(t.ScopeList()) == 0 {
+		return false
+	}
+	for _, s := range t.ScopeList() {
+		if !isEmbedScope(s) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *EmbedToken) ScopeList() []string {
+	return splitList(t.Scopes)
+}
+
+func (t *EmbedToken) ReferrerList() []string {
+	return splitList(t.Referrers)
+}
+
+func (t *EmbedToken) HasScope(scope string) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsReferrer tells whether the token may be used from a page at the given url (Referer or Origin), where a leading '*.' matches any subdomain
+func (t *EmbedToken) AllowsReferrer(referrer string) bool {
+	allowed := t.ReferrerList()
+	if len(allowed) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if host == a || (strings.HasPrefix(a, "*.") && strings.HasSuffix(host, a[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmbedScope(scope string) bool {
+	for _, s := range embedScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	list := make([]string, 0)
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
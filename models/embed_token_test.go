@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbedToken_IsValid(t *testing.T) {
+	assert.True(t, (&EmbedToken{UserID: "john", Token: "t", Scopes: "badge, stats"}).IsValid())
+	assert.False(t, (&EmbedToken{UserID: "john", Token: "t"}).IsValid())
+	assert.False(t, (&EmbedToken{UserID: "john", Token: "t", Scopes: "badge,heartbeats"}).IsValid())
+}
+
+func TestEmbedToken_HasScope(t *testing.T) {
+	sut := &EmbedToken{Scopes: "badge,charts"}
+	assert.True(t, sut.HasScope(EmbedScopeBadge))
+	assert.True(t, sut.HasScope(EmbedScopeCharts))
+	assert.False(t, sut.HasScope(EmbedScopeStats))
+}
+
+func TestEmbedToken_AllowsReferrer(t *testing.T) {
+	assert.True(t, (&EmbedToken{}).AllowsReferrer(""))
+	assert.True(t, (&EmbedToken{}).AllowsReferrer("https://example.org/page"))
+
+	sut := &EmbedToken{Referrers: "example.org, *.github.io"}
+	assert.True(t, sut.AllowsReferrer("https://example.org/page"))
+	assert.True(t, sut.AllowsReferrer("https://Example.org:8080"))
+	assert.True(t, sut.AllowsReferrer("https://john.github.io/"))
+	assert.False(t, sut.AllowsReferrer("https://github.io/"))
+	assert.False(t, sut.AllowsReferrer("https://www.example.org/"))
+	assert.False(t, sut.AllowsReferrer("https://example.org.evil.com/"))
+	assert.False(t, sut.AllowsReferrer(""))
+}
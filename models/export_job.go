@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ExportJob tracks a user's latest data export, which runs in the background
+type ExportJob struct {
+	UserID     string     `json:"user_id"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Count      int        `json:"count"`                // number of heartbeats exported
+	Url        string     `json:"url,omitempty"`        // download link, once finished successfully
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // time at which the download link expires
+	Error      string     `json:"error,omitempty"`
+}
+
+func NewExportJob(userId string) *ExportJob {
+	return &ExportJob{
+		UserID:    userId,
+		StartedAt: time.Now(),
+	}
+}
+
+func (j *ExportJob) IsDone() bool {
+	return j.FinishedAt != nil
+}
+
+// IsAvailable returns whether the export finished successfully and its download link is still valid
+func (j *ExportJob) IsAvailable() bool {
+	return j.IsDone() && j.Url != "" && j.ExpiresAt != nil && time.Now().Before(*j.ExpiresAt)
+}
@@ -0,0 +1,37 @@
+package models
+
+import "regexp"
+
+var filterSetNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-.]{1,64}$`)
+
+// FilterSet is a named combination of filters, which can be recalled via the 'filter_set' query parameter instead of passing each filter individually
+type FilterSet struct {
+	ID       uint   `json:"id" gorm:"primary_key"`
+	User     *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID   string `json:"-" gorm:"not null; uniqueIndex:idx_filter_set_user_name"`
+	Name     string `json:"name" gorm:"not null; size:64; uniqueIndex:idx_filter_set_user_name"`
+	Project  string `json:"project,omitempty" gorm:"size:255"`
+	Label    string `json:"label,omitempty" gorm:"size:255"`
+	Language string `json:"language,omitempty" gorm:"size:255"`
+}
+
+func (f *FilterSet) IsValid() bool {
+	return filterSetNameRegex.MatchString(f.Name) && (f.Project != "" || f.Label != "" || f.Language != "")
+}
+
+// ApplyTo adds the set's filters to the given ones, except for such entities, that already are filtered by explicitly
+func (f *FilterSet) ApplyTo(filters *Filters) *Filters {
+	if filters == nil {
+		filters = &Filters{}
+	}
+	if f.Project != "" && !filters.Project.Exists() {
+		filters.With(SummaryProject, f.Project)
+	}
+	if f.Label != "" && !filters.Label.Exists() {
+		filters.With(SummaryLabel, f.Label)
+	}
+	if f.Language != "" && !filters.Language.Exists() {
+		filters.With(SummaryLanguage, f.Language)
+	}
+	return filters
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFilterSet_IsValid(t *testing.T) {
+	assert.True(t, (&FilterSet{Name: "work", Project: "wakapi"}).IsValid())
+	assert.True(t, (&FilterSet{Name: "go-at-work_2", Label: "work", Language: "Go"}).IsValid())
+	assert.False(t, (&FilterSet{Name: "work"}).IsValid())
+	assert.False(t, (&FilterSet{Name: "work stuff", Project: "wakapi"}).IsValid())
+	assert.False(t, (&FilterSet{Project: "wakapi"}).IsValid())
+}
+
+func TestFilterSet_ApplyTo(t *testing.T) {
+	sut := &FilterSet{Name: "work", Project: "wakapi", Label: "work", Language: "Go"}
+
+	filters := sut.ApplyTo(nil)
+	assert.Equal(t, OrFilter{"wakapi"}, filters.Project)
+	assert.Equal(t, OrFilter{"work"}, filters.Label)
+	assert.Equal(t, OrFilter{"Go"}, filters.Language)
+
+	// explicitly given filters take precedence
+	filters = sut.ApplyTo(NewFiltersWith(SummaryProject, "anchr").With(SummaryEditor, "vscode"))
+	assert.Equal(t, OrFilter{"anchr"}, filters.Project)
+	assert.Equal(t, OrFilter{"work"}, filters.Label)
+	assert.Equal(t, OrFilter{"Go"}, filters.Language)
+	assert.Equal(t, OrFilter{"vscode"}, filters.Editor)
+}
@@ -14,6 +14,7 @@ type Filters struct {
 	Machine  OrFilter
 	Label    OrFilter
 	Branch   OrFilter
+	Origin   OrFilter
 }
 
 type OrFilter []string
@@ -65,6 +66,8 @@ func (f *Filters) WithMultiple(entity uint8, keys []string) *Filters {
 		f.Label = append(f.Label, keys...)
 	case SummaryBranch:
 		f.Branch = append(f.Branch, keys...)
+	case SummaryOrigin:
+		f.Origin = append(f.Origin, keys...)
 	}
 	return f
 }
@@ -84,6 +87,8 @@ func (f *Filters) One() (bool, uint8, OrFilter) {
 		return true, SummaryLabel, f.Label
 	} else if f.Branch != nil && f.Branch.Exists() {
 		return true, SummaryBranch, f.Branch
+	} else if f.Origin != nil && f.Origin.Exists() {
+		return true, SummaryOrigin, f.Origin
 	}
 	return false, 0, OrFilter{}
 }
@@ -95,6 +100,26 @@ func (f *Filters) OneOrEmpty() FilterElement {
 	return FilterElement{}
 }
 
+// Entities returns the summary types, which any filter is set for
+func (f *Filters) Entities() []uint8 {
+	entities := make([]uint8, 0)
+	for t, of := range map[uint8]OrFilter{
+		SummaryProject:  f.Project,
+		SummaryOS:       f.OS,
+		SummaryLanguage: f.Language,
+		SummaryEditor:   f.Editor,
+		SummaryMachine:  f.Machine,
+		SummaryLabel:    f.Label,
+		SummaryBranch:   f.Branch,
+		SummaryOrigin:   f.Origin,
+	} {
+		if of.Exists() {
+			entities = append(entities, t)
+		}
+	}
+	return entities
+}
+
 func (f *Filters) IsEmpty() bool {
 	nonEmpty, _, _ := f.One()
 	return !nonEmpty
@@ -113,7 +138,8 @@ func (f *Filters) Match(h *Heartbeat) bool {
 		(f.OS == nil || f.OS.MatchAny(h.OperatingSystem)) &&
 		(f.Language == nil || f.Language.MatchAny(h.Language)) &&
 		(f.Editor == nil || f.Editor.MatchAny(h.Editor)) &&
-		(f.Machine == nil || f.Machine.MatchAny(h.Machine))
+		(f.Machine == nil || f.Machine.MatchAny(h.Machine)) &&
+		(f.Origin == nil || f.Origin.MatchAny(h.OriginKind()))
 }
 
 // WithAliases adds OR-conditions for every alias of a filter key as additional filter keys
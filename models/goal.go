@@ -0,0 +1,114 @@
+package models
+
+import "time"
+
+const (
+	GoalIntervalDay   = "day"
+	GoalIntervalWeek  = "week"
+	GoalIntervalMonth = "month"
+)
+
+var GoalIntervals = []string{GoalIntervalDay, GoalIntervalWeek, GoalIntervalMonth}
+
+// Goal is an amount of time to spend coding within every day, week or month, either in total or scoped to a single language or editor, e.g. "3 hours of Rust per week"
+type Goal struct {
+	ID       uint   `json:"id" gorm:"primary_key"`
+	User     *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID   string `json:"-" gorm:"not null; index:idx_goal_user"`
+	Title    string `json:"title" gorm:"size:255"`
+	Interval string `json:"interval" gorm:"not null; size:8"`
+	Minutes  int    `json:"minutes"`
+	Language string `json:"language,omitempty" gorm:"size:255"` // at most one of language or editor is set
+	Editor   string `json:"editor,omitempty" gorm:"size:255"`
+}
+
+func (g *Goal) IsValid() bool {
+	validInterval := false
+	for _, i := range GoalIntervals {
+		validInterval = validInterval || g.Interval == i
+	}
+	return validInterval && g.Minutes > 0 && !(g.Language != "" && g.Editor != "")
+}
+
+func (g *Goal) Target() time.Duration {
+	return time.Duration(g.Minutes) * time.Minute
+}
+
+// Filters returns the filters to apply to summaries when evaluating the goal or nil, if it is not scoped to a language or editor
+func (g *Goal) Filters() *Filters {
+	if g.Language != "" {
+		return NewFiltersWith(SummaryLanguage, g.Language)
+	}
+	if g.Editor != "" {
+		return NewFiltersWith(SummaryEditor, g.Editor)
+	}
+	return nil
+}
+
+// Name returns the goal's title or, if none was given, a generic description like "Rust per week"
+func (g *Goal) Name() string {
+	if g.Title != "" {
+		return g.Title
+	}
+	scope := "Coding"
+	if g.Language != "" {
+		scope = g.Language
+	} else if g.Editor != "" {
+		scope = g.Editor
+	}
+	return scope + " per " + g.Interval
+}
+
+// GoalProgress tells how much time was spent towards a goal within its current interval
+type GoalProgress struct {
+	Goal          *Goal         `json:"goal"`
+	From          time.Time     `json:"from"`
+	DaysOff       int           `json:"days_off"` // number of days off within the interval, by which the target is reduced proportionally
+	Target        time.Duration `json:"-"`
+	Actual        time.Duration `json:"-"`
+	TargetSeconds int64         `json:"target"`
+	ActualSeconds int64         `json:"actual"`
+	Percentage    float64       `json:"percentage"` // may exceed 100
+}
+
+// NewGoalProgress creates the progress towards a goal within the interval [from, to), whose target is only due on days that are not off
+func NewGoalProgress(goal *Goal, from, to time.Time, actual time.Duration, daysOff DaysOff) *GoalProgress {
+	var numDays int
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		numDays++
+	}
+
+	target := goal.Target()
+	numDaysOff := daysOff.CountWithin(from, to)
+	if numDays > 0 {
+		target = target * time.Duration(numDays-numDaysOff) / time.Duration(numDays)
+	}
+
+	progress := &GoalProgress{
+		Goal:          goal,
+		From:          from,
+		DaysOff:       numDaysOff,
+		Target:        target,
+		Actual:        actual,
+		TargetSeconds: int64(target.Seconds()),
+		ActualSeconds: int64(actual.Seconds()),
+	}
+	if progress.Target > 0 {
+		progress.Percentage = float64(actual) / float64(progress.Target) * 100
+	} else {
+		progress.Percentage = 100 // nothing to do on days off
+	}
+	return progress
+}
+
+func (p *GoalProgress) IsReached() bool {
+	return p.Actual >= p.Target
+}
+
+// BarPercentage returns the progress capped at 100 %, e.g. for displaying it as a progress bar
+func (p *GoalProgress) BarPercentage() float64 {
+	if p.Percentage > 100 {
+		return 100
+	}
+	return p.Percentage
+}
@@ -4,34 +4,50 @@ import (
 	"fmt"
 	"github.com/emvi/logbuch"
 	"github.com/mitchellh/hashstructure/v2"
+	"net/url"
 	"strings"
 	"time"
 )
 
+// Kinds of origins, i.e. ways by which time got into wakapi
+const (
+	OriginDirect  = "direct"  // sent by an editor plugin
+	OriginBrowser = "browser" // sent by a browser extension
+	OriginRelay   = "relay"   // relayed by another wakapi instance
+	OriginImport  = "import"  // imported from wakatime or a file
+	OriginManual  = "manual"  // added as a manual time entry
+)
+
+// CategoryBrowsing is the category of heartbeats sent by the wakatime browser extension, whose entities are domains or urls
+const CategoryBrowsing = "browsing"
+
 type Heartbeat struct {
-	ID              uint64     `gorm:"primary_key" hash:"ignore"`
-	User            *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" hash:"ignore"`
-	UserID          string     `json:"-" gorm:"not null; index:idx_time_user"`
-	Entity          string     `json:"entity" gorm:"not null; index:idx_entity"`
-	Type            string     `json:"type"`
-	Category        string     `json:"category"`
-	Project         string     `json:"project"`
-	Branch          string     `json:"branch"`
-	Language        string     `json:"language" gorm:"index:idx_language"`
-	IsWrite         bool       `json:"is_write"`
-	Editor          string     `json:"editor" hash:"ignore"`           // ignored because editor might be parsed differently by wakatime
-	OperatingSystem string     `json:"operating_system" hash:"ignore"` // ignored because os might be parsed differently by wakatime
-	Machine         string     `json:"machine" hash:"ignore"`          // ignored because wakatime api doesn't return machines currently
-	UserAgent       string     `json:"user_agent" hash:"ignore"`
-	Time            CustomTime `json:"time" gorm:"type:timestamp; index:idx_time,idx_time_user" swaggertype:"primitive,number"`
-	Hash            string     `json:"-" gorm:"type:varchar(17); uniqueIndex"`
-	Origin          string     `json:"-" hash:"ignore"`
-	OriginId        string     `json:"-" hash:"ignore"`
-	CreatedAt       CustomTime `json:"created_at" gorm:"type:timestamp" swaggertype:"primitive,number" hash:"ignore"` // https://gorm.io/docs/conventions.html#CreatedAt
+	ID              uint64            `gorm:"primary_key" hash:"ignore"`
+	User            *User             `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" hash:"ignore"`
+	UserID          string            `json:"-" gorm:"not null; index:idx_time_user"`
+	Entity          string            `json:"entity" gorm:"not null; index:idx_entity"`
+	Type            string            `json:"type"`
+	Category        string            `json:"category"`
+	Project         string            `json:"project"`
+	Branch          string            `json:"branch"`
+	Language        string            `json:"language" gorm:"index:idx_language"`
+	IsWrite         bool              `json:"is_write"`
+	Lines           int               `json:"lines" hash:"ignore"`            // total number of lines in the entity
+	CursorPos       int               `json:"cursorpos" hash:"ignore"`        // position of the cursor within the entity
+	Editor          string            `json:"editor" hash:"ignore"`           // ignored because editor might be parsed differently by wakatime
+	OperatingSystem string            `json:"operating_system" hash:"ignore"` // ignored because os might be parsed differently by wakatime
+	Machine         string            `json:"machine" hash:"ignore"`          // ignored because wakatime api doesn't return machines currently
+	UserAgent       string            `json:"user_agent" hash:"ignore"`
+	Metadata        HeartbeatMetadata `json:"metadata,omitempty" gorm:"type:text" swaggertype:"object" hash:"ignore"` // free-form context attached by custom agents, e.g. ci job ids
+	Time            CustomTime        `json:"time" gorm:"type:timestamp; index:idx_time,idx_time_user" swaggertype:"primitive,number"`
+	Hash            string            `json:"-" gorm:"type:varchar(17); uniqueIndex"`
+	Origin          string            `json:"-" hash:"ignore"`                                                               // either one of the origin kinds or the importer a heartbeat was imported by (e.g. 'wakatime')
+	OriginId        string            `json:"-" hash:"ignore"`                                                               // e.g. the heartbeat's id at wakatime or the id of the instance it was relayed by
+	CreatedAt       CustomTime        `json:"created_at" gorm:"type:timestamp" swaggertype:"primitive,number" hash:"ignore"` // https://gorm.io/docs/conventions.html#CreatedAt
 }
 
 func (h *Heartbeat) Valid() bool {
-	return h.User != nil && h.UserID != "" && h.User.ID == h.UserID && h.Time != CustomTime(time.Time{})
+	return h.User != nil && h.UserID != "" && h.User.ID == h.UserID && h.Time != CustomTime(time.Time{}) && h.Metadata.IsValid()
 }
 
 func (h *Heartbeat) Augment(languageMappings map[string]string) {
@@ -58,6 +74,12 @@ func (h *Heartbeat) GetKey(t uint8) (key string) {
 		key = h.Machine
 	case SummaryBranch:
 		key = h.Branch
+	case SummaryTicket:
+		key = TicketFromBranch(h.Branch)
+	case SummaryOrigin:
+		key = h.OriginKind()
+	case SummaryDomain:
+		key = h.Domain()
 	}
 
 	if key == "" {
@@ -67,6 +89,36 @@ func (h *Heartbeat) GetKey(t uint8) (key string) {
 	return key
 }
 
+// OriginKind returns how the heartbeat got into wakapi, i.e. one of OriginDirect, OriginBrowser, OriginRelay or OriginImport.
+// Heartbeats from before origins were tracked count as direct ones.
+func (h *Heartbeat) OriginKind() string {
+	switch h.Origin {
+	case "", OriginDirect:
+		return OriginDirect
+	case OriginBrowser, OriginRelay:
+		return h.Origin
+	default:
+		return OriginImport
+	}
+}
+
+func (h *Heartbeat) IsBrowsing() bool {
+	return h.Category == CategoryBrowsing
+}
+
+// Domain returns the web domain visited according to a browsing heartbeat or an empty string for any other heartbeat
+func (h *Heartbeat) Domain() string {
+	if !h.IsBrowsing() {
+		return ""
+	}
+	if h.Type == "url" {
+		if u, err := url.Parse(h.Entity); err == nil && u.Hostname() != "" {
+			return u.Hostname()
+		}
+	}
+	return h.Entity
+}
+
 func (h *Heartbeat) String() string {
 	return fmt.Sprintf(
 		"Heartbeat {user=%s, entity=%s, type=%s, category=%s, project=%s, branch=%s, language=%s, iswrite=%v, editor=%s, os=%s, machine=%s, time=%d}",
@@ -92,6 +144,7 @@ func (h *Heartbeat) String() string {
 // essentially double the space required for heartbeats, so we decided to go this way.
 
 func (h *Heartbeat) Hashed() *Heartbeat {
+	h.Hash = "" // a previously computed hash must not go into the new one, e.g. when re-hashing an edited heartbeat
 	hash, err := hashstructure.Hash(h, hashstructure.FormatV2, nil)
 	if err != nil {
 		logbuch.Error("CRITICAL ERROR: failed to hash struct - %v", err)
@@ -99,3 +152,13 @@ func (h *Heartbeat) Hashed() *Heartbeat {
 	h.Hash = fmt.Sprintf("%x", hash) // "uint64 values with high bit set are not supported"
 	return h
 }
+
+// HeartbeatCursor identifies a heartbeat's position within the sequence of all heartbeats ordered by time and id, to be used for keyset pagination
+type HeartbeatCursor struct {
+	Time CustomTime
+	ID   uint64
+}
+
+func NewHeartbeatCursor(h *Heartbeat) *HeartbeatCursor {
+	return &HeartbeatCursor{Time: h.Time, ID: h.ID}
+}
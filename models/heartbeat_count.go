@@ -0,0 +1,9 @@
+package models
+
+// HeartbeatCount holds the number of heartbeats per user, which is maintained incrementally on insertion to avoid
+// expensive count queries on the heartbeats table
+type HeartbeatCount struct {
+	User   *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID string `gorm:"primary_key"`
+	Total  int64  `gorm:"not null; default:0"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// HeartbeatMetadataMaxSize limits the size of a heartbeat's metadata in bytes, when serialized as json
+const HeartbeatMetadataMaxSize = 1024
+
+// HeartbeatMetadataFilterPrefix marks query parameters filtering heartbeats by their metadata, e.g. 'metadata.ci_job=1234'
+const HeartbeatMetadataFilterPrefix = "metadata."
+
+// HeartbeatMetadata is a free-form json object, which custom agents can attach to heartbeats to provide additional context, e.g. the id of a ci job.
+// It is stored as is and neither interpreted nor aggregated.
+type HeartbeatMetadata map[string]interface{}
+
+func (m HeartbeatMetadata) IsValid() bool {
+	if len(m) == 0 {
+		return true
+	}
+	data, err := json.Marshal(m)
+	return err == nil && len(data) <= HeartbeatMetadataMaxSize
+}
+
+// Get returns the value of the given top-level key as a string, where only strings, numbers and booleans are considered
+func (m HeartbeatMetadata) Get(key string) (string, bool) {
+	switch v := m[key].(type) {
+	case string:
+		return v, true
+	case float64, bool, json.Number:
+		return fmt.Sprintf("%v", v), true
+	}
+	return "", false
+}
+
+// Matches tells whether all of the given keys have the respective values
+func (m HeartbeatMetadata) Matches(filters map[string]string) bool {
+	for key, value := range filters {
+		if v, ok := m.Get(key); !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *HeartbeatMetadata) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New(fmt.Sprintf("unsupported type: %T", value))
+	}
+
+	if len(data) == 0 {
+		*m = nil
+		return nil
+	}
+	return json.Unmarshal(data, m)
+}
+
+func (m HeartbeatMetadata) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// ParseHeartbeatMetadataFilters extracts metadata filters from the given query parameters, e.g. {"ci_job": "1234"} from 'metadata.ci_job=1234'
+func ParseHeartbeatMetadataFilters(params map[string][]string) map[string]string {
+	filters := make(map[string]string)
+	for param, values := range params {
+		if key := strings.TrimPrefix(param, HeartbeatMetadataFilterPrefix); key != param && key != "" && len(values) > 0 {
+			filters[key] = values[0]
+		}
+	}
+	return filters
+}
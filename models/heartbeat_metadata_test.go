@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeatMetadata_IsValid(t *testing.T) {
+	assert.True(t, HeartbeatMetadata(nil).IsValid())
+	assert.True(t, HeartbeatMetadata{"ci_job": "1234"}.IsValid())
+	assert.False(t, HeartbeatMetadata{"ci_job": strings.Repeat("a", HeartbeatMetadataMaxSize)}.IsValid())
+}
+
+func TestHeartbeatMetadata_Matches(t *testing.T) {
+	sut := HeartbeatMetadata{"ci_job": "1234", "attempt": float64(2), "manual": true, "nested": map[string]interface{}{"foo": "bar"}}
+
+	assert.True(t, sut.Matches(map[string]string{}))
+	assert.True(t, sut.Matches(map[string]string{"ci_job": "1234"}))
+	assert.True(t, sut.Matches(map[string]string{"ci_job": "1234", "attempt": "2", "manual": "true"}))
+	assert.False(t, sut.Matches(map[string]string{"ci_job": "1235"}))
+	assert.False(t, sut.Matches(map[string]string{"nested": "map[foo:bar]"}))
+	assert.False(t, sut.Matches(map[string]string{"missing": ""}))
+	assert.False(t, HeartbeatMetadata(nil).Matches(map[string]string{"ci_job": "1234"}))
+}
+
+func TestHeartbeatMetadata_ScanValue(t *testing.T) {
+	sut := HeartbeatMetadata{"ci_job": "1234"}
+
+	value, err := sut.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, `{"ci_job":"1234"}`, value)
+
+	var scanned HeartbeatMetadata
+	assert.Nil(t, scanned.Scan([]byte(value.(string))))
+	assert.Equal(t, sut, scanned)
+
+	value, err = HeartbeatMetadata{}.Value()
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+
+	assert.Nil(t, scanned.Scan(nil))
+	assert.Nil(t, scanned)
+}
+
+func TestParseHeartbeatMetadataFilters(t *testing.T) {
+	filters := ParseHeartbeatMetadataFilters(map[string][]string{
+		"date":            {"2023-05-10"},
+		"metadata.ci_job": {"1234"},
+		"metadata.":       {"foo"},
+	})
+	assert.Equal(t, map[string]string{"ci_job": "1234"}, filters)
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// HeartbeatSelection matches a user's heartbeats by entity prefix, time range and project. Fields left empty match any heartbeat.
+type HeartbeatSelection struct {
+	EntityPrefix string    `json:"entity_prefix"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	Project      string    `json:"project"`
+}
+
+// HeartbeatReassignment moves all heartbeats matching a selection to another project and / or language, e.g. to clean up history after a repository got renamed
+type HeartbeatReassignment struct {
+	HeartbeatSelection
+	NewProject  string `json:"new_project"`
+	NewLanguage string `json:"new_language"`
+}
+
+// ReassignmentJob tracks a user's latest reassignment, which runs in the background
+type ReassignmentJob struct {
+	UserID     string     `json:"user_id"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Updated    int        `json:"updated"`           // number of heartbeats reassigned so far
+	Skipped    int        `json:"skipped"`           // number of heartbeats which could not be reassigned, e.g. because they would become duplicates of existing ones
+	UndoId     *uint      `json:"undo_id,omitempty"` // tombstone to undo the reassignment with, unless undo is disabled
+	Error      string     `json:"error,omitempty"`
+}
+
+func (s *HeartbeatSelection) IsEmpty() bool {
+	return s.EntityPrefix == "" && s.Project == ""
+}
+
+// Validate rejects reassignments without a target as well as such without an entity prefix or project to select by, as these would likely affect the entire history by accident
+func (r *HeartbeatReassignment) Validate() error {
+	if r.NewProject == "" && r.NewLanguage == "" {
+		return errors.New("either new project or new language required")
+	}
+	if r.IsEmpty() {
+		return errors.New("either entity prefix or project required")
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && !r.From.Before(r.To) {
+		return errors.New("invalid time range")
+	}
+	return nil
+}
+
+// Apply assigns the new project and language to the given heartbeat and returns whether anything has changed
+func (r *HeartbeatReassignment) Apply(h *Heartbeat) bool {
+	var changed bool
+	if r.NewProject != "" && h.Project != r.NewProject {
+		h.Project, changed = r.NewProject, true
+	}
+	if r.NewLanguage != "" && h.Language != r.NewLanguage {
+		h.Language, changed = r.NewLanguage, true
+	}
+	return changed
+}
+
+func NewReassignmentJob(userId string) *ReassignmentJob {
+	return &ReassignmentJob{
+		UserID:    userId,
+		StartedAt: time.Now(),
+	}
+}
+
+func (j *ReassignmentJob) IsDone() bool {
+	return j.FinishedAt != nil
+}
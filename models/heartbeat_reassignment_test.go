@@ -0,0 +1,31 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatReassignment_Validate(t *testing.T) {
+	now := time.Now()
+
+	assert.Nil(t, (&HeartbeatReassignment{HeartbeatSelection: HeartbeatSelection{Project: "old"}, NewProject: "new"}).Validate())
+	assert.Nil(t, (&HeartbeatReassignment{HeartbeatSelection: HeartbeatSelection{EntityPrefix: "/home/me/dev/old/", From: now.Add(-time.Hour), To: now}, NewLanguage: "Go"}).Validate())
+	assert.NotNil(t, (&HeartbeatReassignment{HeartbeatSelection: HeartbeatSelection{Project: "old"}}).Validate())
+	assert.NotNil(t, (&HeartbeatReassignment{HeartbeatSelection: HeartbeatSelection{From: now.Add(-time.Hour)}, NewProject: "new"}).Validate())
+	assert.NotNil(t, (&HeartbeatReassignment{HeartbeatSelection: HeartbeatSelection{Project: "old", From: now, To: now.Add(-time.Hour)}, NewProject: "new"}).Validate())
+}
+
+func TestHeartbeatReassignment_Apply(t *testing.T) {
+	sut := &HeartbeatReassignment{HeartbeatSelection: HeartbeatSelection{Project: "old"}, NewProject: "new"}
+
+	hb := &Heartbeat{Project: "old", Language: "Go"}
+	assert.True(t, sut.Apply(hb))
+	assert.Equal(t, "new", hb.Project)
+	assert.Equal(t, "Go", hb.Language)
+	assert.False(t, sut.Apply(hb))
+
+	sut.NewLanguage = "Python"
+	assert.True(t, sut.Apply(hb))
+	assert.Equal(t, "Python", hb.Language)
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -24,6 +26,20 @@ func TestHeartbeat_Valid_MissingUser(t *testing.T) {
 	assert.False(t, sut.Valid())
 }
 
+func TestHeartbeat_OriginKind(t *testing.T) {
+	assert.Equal(t, OriginDirect, (&Heartbeat{}).OriginKind())
+	assert.Equal(t, OriginDirect, (&Heartbeat{Origin: OriginDirect}).OriginKind())
+	assert.Equal(t, OriginBrowser, (&Heartbeat{Origin: OriginBrowser}).OriginKind())
+	assert.Equal(t, OriginRelay, (&Heartbeat{Origin: OriginRelay, OriginId: "some-instance"}).OriginKind())
+	assert.Equal(t, OriginImport, (&Heartbeat{Origin: "wakatime"}).OriginKind())
+}
+
+func TestHeartbeat_Domain(t *testing.T) {
+	assert.Equal(t, "github.com", (&Heartbeat{Entity: "github.com", Type: "domain", Category: CategoryBrowsing}).Domain())
+	assert.Equal(t, "github.com", (&Heartbeat{Entity: "https://github.com/muety/wakapi", Type: "url", Category: CategoryBrowsing}).Domain())
+	assert.Equal(t, "", (&Heartbeat{Entity: "~/dev/file.go", Type: "file", Category: "coding"}).Domain())
+}
+
 func TestHeartbeat_Augment(t *testing.T) {
 	testMappings := map[string]string{
 		"py":        "Python3",
@@ -64,3 +80,31 @@ func TestHeartbeat_GetKey(t *testing.T) {
 	assert.Equal(t, UnknownSummaryKey, sut.GetKey(SummaryEditor))
 	assert.Equal(t, UnknownSummaryKey, sut.GetKey(255))
 }
+
+func TestHeartbeat_Hashed(t *testing.T) {
+	t0 := CustomTime(time.Date(2022, 10, 14, 5, 0, 0, 0, time.UTC))
+	sut1 := (&Heartbeat{Entity: "main.go", Project: "wakapi", Time: t0}).Hashed()
+	sut2 := (&Heartbeat{Entity: "main.go", Project: "wakapi-legacy", Time: t0}).Hashed()
+	assert.NotEqual(t, sut1.Hash, sut2.Hash)
+
+	// re-hashing an edited heartbeat yields the same hash as for an identical new one
+	sut2.Project = "wakapi"
+	assert.Equal(t, sut1.Hash, sut2.Hashed().Hash)
+	assert.Equal(t, sut1.Hash, sut1.Hashed().Hash)
+}
+
+func TestHeartbeat_UnmarshalTime(t *testing.T) {
+	t0 := time.Date(2022, 10, 14, 5, 0, 0, 500000000, time.UTC)
+
+	// as sent by wakatime clients
+	var sut1 Heartbeat
+	assert.Nil(t, json.Unmarshal([]byte(`{"time": 1665723600.5}`), &sut1))
+	assert.True(t, t0.Equal(sut1.Time.T()))
+
+	// as exported by wakapi
+	data, err := json.Marshal(&Heartbeat{Entity: "main.go", Time: CustomTime(t0)})
+	assert.Nil(t, err)
+	var sut2 Heartbeat
+	assert.Nil(t, json.Unmarshal(data, &sut2))
+	assert.True(t, t0.Equal(sut2.Time.T()))
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HeartbeatTypes are the kinds of entities heartbeats may be sent for
+var HeartbeatTypes = []string{"file", "app", "domain", "url"}
+
+// HeartbeatCategories are the activities known to wakatime-cli, see https://github.com/wakatime/wakatime-cli/blob/develop/pkg/heartbeat/category.go
+var HeartbeatCategories = []string{
+	"ai coding", "browsing", "building", "code reviewing", "coding", "communicating", "debugging", "designing", "indexing", "learning",
+	"manual testing", "meeting", "planning", "researching", "running tests", "supporting", "translating", "writing docs", "writing tests",
+}
+
+// HeartbeatFieldError tells why a single field of a heartbeat sent by a client is invalid
+type HeartbeatFieldError struct {
+	Index   int    `json:"index"` // position of the heartbeat within the request
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// HeartbeatValidationError collects all invalid fields of all heartbeats of a request
+type HeartbeatValidationError []*HeartbeatFieldError
+
+func (e HeartbeatValidationError) Error() string {
+	if len(e) == 0 {
+		return "invalid heartbeat object"
+	}
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fmt.Sprintf("heartbeat %d: %s %s", fe.Index, fe.Field, fe.Message)
+	}
+	return "invalid heartbeat object (" + strings.Join(messages, ", ") + ")"
+}
+
+// Validate strictly checks the fields sent by the client, other than Valid, which checks the ones filled in by the server.
+// The given index is the heartbeat's position within the request, to be included in errors.
+func (h *Heartbeat) Validate(index int) []*HeartbeatFieldError {
+	errs := make([]*HeartbeatFieldError, 0)
+	fail := func(field, message string) {
+		errs = append(errs, &HeartbeatFieldError{Index: index, Field: field, Message: message})
+	}
+
+	if strings.TrimSpace(h.Entity) == "" {
+		fail("entity", "is required")
+	}
+	if h.Type == "" {
+		fail("type", "is required")
+	} else if !contains(HeartbeatTypes, h.Type) {
+		fail("type", "must be one of "+strings.Join(HeartbeatTypes, ", "))
+	}
+	if h.Category != "" && !contains(HeartbeatCategories, h.Category) {
+		fail("category", "must be one of "+strings.Join(HeartbeatCategories, ", "))
+	}
+	if h.Time.T().IsZero() {
+		fail("time", "is required")
+	} else if h.Time.T().Unix() <= 0 {
+		fail("time", "must be a positive unix timestamp")
+	}
+	if h.Lines < 0 {
+		fail("lines", "must not be negative")
+	}
+	if h.CursorPos < 0 {
+		fail("cursorpos", "must not be negative")
+	}
+	if !h.Metadata.IsValid() {
+		fail("metadata", fmt.Sprintf("must not exceed %d bytes", HeartbeatMetadataMaxSize))
+	}
+	return errs
+}
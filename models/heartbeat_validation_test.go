@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat_Validate(t *testing.T) {
+	valid := &Heartbeat{Entity: "/home/me/dev/wakapi/main.go", Type: "file", Category: "coding", Time: CustomTime(time.Now())}
+	assert.Empty(t, valid.Validate(0))
+
+	invalid := &Heartbeat{Entity: " ", Type: "folder", Category: "sleeping", Lines: -1}
+	errs := invalid.Validate(3)
+
+	fields := make([]string, len(errs))
+	for i, err := range errs {
+		assert.Equal(t, 3, err.Index)
+		fields[i] = err.Field
+	}
+	assert.Equal(t, []string{"entity", "type", "category", "time", "lines"}, fields)
+}
+
+func TestHeartbeatValidationError_Error(t *testing.T) {
+	err := HeartbeatValidationError{{Index: 1, Field: "type", Message: "is required"}}
+	assert.Equal(t, "invalid heartbeat object (heartbeat 1: type is required)", err.Error())
+}
@@ -0,0 +1,116 @@
+package models
+
+import "time"
+
+// ImportJob is the state of a user's latest heartbeat import from a certain source, which doubles as checkpoint to resume an interrupted or partially failed import from.
+// Importers deliver heartbeats day by day, though not necessarily in order, so the checkpoint is the first day, which was not imported successfully yet.
+// Imports not processed by day, i.e. of uploaded files, are processed row by row in order instead, so their checkpoint is the number of leading rows processed successfully.
+type ImportJob struct {
+	Source        string     `json:"source"` // e.g. 'wakatime', 'codestats' or 'file'
+	From          time.Time  `json:"from"`
+	To            time.Time  `json:"to"`
+	Checkpoint    time.Time  `json:"checkpoint"` // all days before were imported successfully
+	TotalDays     int        `json:"total_days"`
+	ProcessedDays int        `json:"processed_days"`  // including failed ones
+	Offset        int        `json:"offset"`          // leading rows of an uploaded file, which were processed successfully
+	Rows          int        `json:"rows"`            // heartbeats received from the source so far
+	Imported      int        `json:"imported"`        // including duplicates skipped on insert
+	Rejected      int        `json:"rejected"`        // invalid or rejected by heartbeat scripts
+	Errors        int        `json:"errors"`          // days or batches, which failed to be fetched or inserted
+	Error         string     `json:"error,omitempty"` // last error encountered
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	Eta           *time.Time `json:"eta"` // estimated time of completion, only known while running an import processed by day
+	days          []time.Time
+	completed     map[int64]bool
+}
+
+func NewImportJob(source string, from, to time.Time) *ImportJob {
+	return &ImportJob{
+		Source:     source,
+		From:       from,
+		To:         to,
+		Checkpoint: from,
+		StartedAt:  time.Now(),
+		completed:  map[int64]bool{},
+	}
+}
+
+// SetDays registers the days to be imported in chronological order, as announced by the importer
+func (j *ImportJob) SetDays(days []time.Time) {
+	j.days = days
+	j.TotalDays = len(days)
+	j.completed = map[int64]bool{}
+	j.advanceCheckpoint()
+}
+
+// ProcessDay records the outcome of importing a single day and moves the checkpoint forward, if possible
+func (j *ImportJob) ProcessDay(day time.Time, err error) {
+	j.ProcessedDays++
+	if err != nil {
+		j.Fail(err)
+	} else {
+		j.completed[day.Unix()] = true
+		j.advanceCheckpoint()
+	}
+	j.updateEta(time.Now())
+}
+
+// Fail records a (non-fatal) error, e.g. of a single day or batch
+func (j *ImportJob) Fail(err error) {
+	j.Errors++
+	j.Error = err.Error()
+}
+
+func (j *ImportJob) Finish(err error) {
+	if err != nil {
+		j.Fail(err)
+	}
+	now := time.Now()
+	j.FinishedAt = &now
+	j.Eta = nil
+}
+
+func (j *ImportJob) IsDone() bool {
+	return j.FinishedAt != nil
+}
+
+// IsResumable tells whether some of the import's days are still to be imported, e.g. because it was interrupted or single days failed,
+// or, for imports not processed by day, whether it failed after some rows were processed already
+func (j *ImportJob) IsResumable() bool {
+	if j.TotalDays == 0 {
+		return j.Offset > 0 && j.Error != ""
+	}
+	return j.Checkpoint.Before(j.To)
+}
+
+// Progress returns the share of processed days in percent
+func (j *ImportJob) Progress() int {
+	if j.TotalDays == 0 {
+		if j.IsDone() {
+			return 100
+		}
+		return 0
+	}
+	return j.ProcessedDays * 100 / j.TotalDays
+}
+
+func (j *ImportJob) advanceCheckpoint() {
+	for _, d := range j.days {
+		if !j.completed[d.Unix()] {
+			j.Checkpoint = d
+			return
+		}
+	}
+	j.Checkpoint = j.To
+}
+
+func (j *ImportJob) updateEta(now time.Time) {
+	if j.TotalDays == 0 || j.ProcessedDays == 0 || j.ProcessedDays >= j.TotalDays {
+		j.Eta = nil
+		return
+	}
+	elapsed := now.Sub(j.StartedAt)
+	eta := now.Add(elapsed * time.Duration(j.TotalDays-j.ProcessedDays) / time.Duration(j.ProcessedDays))
+	j.Eta = &eta
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestImportJob_ProcessDay(t *testing.T) {
+	from := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 4)
+	days := []time.Time{from, from.AddDate(0, 0, 1), from.AddDate(0, 0, 2), from.AddDate(0, 0, 3)}
+
+	sut := NewImportJob("wakatime", from, to)
+	sut.SetDays(days)
+
+	assert.Equal(t, 4, sut.TotalDays)
+	assert.Equal(t, from, sut.Checkpoint)
+	assert.True(t, sut.IsResumable())
+
+	// days may complete out of order
+	sut.ProcessDay(days[1], nil)
+	assert.Equal(t, from, sut.Checkpoint)
+	assert.NotNil(t, sut.Eta)
+
+	sut.ProcessDay(days[0], nil)
+	assert.Equal(t, days[2], sut.Checkpoint)
+
+	sut.ProcessDay(days[2], errors.New("got status 500 from wakatime api"))
+	sut.ProcessDay(days[3], nil)
+	assert.Equal(t, days[2], sut.Checkpoint)
+	assert.Equal(t, 1, sut.Errors)
+	assert.Equal(t, "got status 500 from wakatime api", sut.Error)
+	assert.Equal(t, 100, sut.Progress())
+	assert.Nil(t, sut.Eta)
+
+	sut.Finish(nil)
+	assert.True(t, sut.IsDone())
+	assert.True(t, sut.IsResumable())
+}
+
+func TestImportJob_ProcessDay_Complete(t *testing.T) {
+	from := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(36 * time.Hour)
+
+	sut := NewImportJob("codestats", from, to)
+	sut.SetDays([]time.Time{from, from.AddDate(0, 0, 1)})
+	sut.ProcessDay(from, nil)
+	sut.ProcessDay(from.AddDate(0, 0, 1), nil)
+	sut.Finish(nil)
+
+	assert.Equal(t, to, sut.Checkpoint)
+	assert.False(t, sut.IsResumable())
+	assert.Zero(t, sut.Errors)
+}
+
+func TestImportJob_Progress_Rows(t *testing.T) {
+	sut := NewImportJob("file", time.Time{}, time.Time{})
+	sut.Rows = 100
+
+	assert.Equal(t, 0, sut.Progress())
+	assert.False(t, sut.IsResumable())
+
+	sut.Finish(errors.New("unexpected end of input"))
+	assert.Equal(t, 100, sut.Progress())
+	assert.Equal(t, 1, sut.Errors)
+	assert.Nil(t, sut.Eta)
+	assert.False(t, sut.IsResumable())
+}
+
+func TestImportJob_IsResumable_Offset(t *testing.T) {
+	sut := NewImportJob("file", time.Time{}, time.Time{})
+	sut.Rows, sut.Offset = 100, 100
+	sut.Finish(nil)
+	assert.False(t, sut.IsResumable())
+
+	sut.Finish(errors.New("failed to insert heartbeats"))
+	assert.True(t, sut.IsResumable())
+}
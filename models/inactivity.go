@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// InactivityAlert tells a user, that no heartbeats were received for a while, either at all or from a single, previously active machine
+type InactivityAlert struct {
+	Machine      string // empty, if no heartbeats were received from any machine
+	LastActivity time.Time
+	Days         int
+}
+
+func (a *InactivityAlert) IsMachine() bool {
+	return a.Machine != ""
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// InstanceStatsLanguage is the time spent on a language across all users of an instance
+type InstanceStatsLanguage struct {
+	Name         string `json:"name"`
+	TotalSeconds int64  `json:"total"`
+}
+
+// InstanceStats are anonymous, aggregated numbers about an entire instance, which admins may choose to publish
+type InstanceStats struct {
+	TotalSeconds int64                    `json:"total"`
+	TotalUsers   int                      `json:"users"`
+	ActiveUsers  int                      `json:"active_users"` // users with any coding activity within the past 7 days
+	Languages    []*InstanceStatsLanguage `json:"languages"`    // most used first
+	UpdatedAt    time.Time                `json:"updated_at"`
+}
+
+// NewInstanceStats ranks the given per-language totals. Only languages used by at least minUsers users are included,
+// so that no individual user can be singled out, and of these at most maxLanguages.
+func NewInstanceStats(total time.Duration, totalUsers, activeUsers int, languageTotals map[string]time.Duration, languageUsers map[string]int, minUsers, maxLanguages int) *InstanceStats {
+	languages := make([]*InstanceStatsLanguage, 0, len(languageTotals))
+	for name, languageTotal := range languageTotals {
+		if name == UnknownSummaryKey || languageUsers[name] < minUsers {
+			continue
+		}
+		languages = append(languages, &InstanceStatsLanguage{Name: name, TotalSeconds: int64(languageTotal.Seconds())})
+	}
+
+	sort.Slice(languages, func(i, j int) bool {
+		if languages[i].TotalSeconds == languages[j].TotalSeconds {
+			return languages[i].Name < languages[j].Name
+		}
+		return languages[i].TotalSeconds > languages[j].TotalSeconds
+	})
+	if len(languages) > maxLanguages {
+		languages = languages[:maxLanguages]
+	}
+
+	return &InstanceStats{
+		TotalSeconds: int64(total.Seconds()),
+		TotalUsers:   totalUsers,
+		ActiveUsers:  activeUsers,
+		Languages:    languages,
+		UpdatedAt:    time.Now(),
+	}
+}
+
+// TotalHours returns the total tracked time across all users in full hours
+func (s *InstanceStats) TotalHours() int64 {
+	return s.TotalSeconds / 3600
+}
+
+// TotalHours returns the time spent on the given language in full hours
+func (l *InstanceStatsLanguage) TotalHours() int64 {
+	return l.TotalSeconds / 3600
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewInstanceStats(t *testing.T) {
+	languageTotals := map[string]time.Duration{
+		"Go":              10 * time.Hour,
+		"Python":          20 * time.Hour,
+		"Rust":            5 * time.Hour,
+		"Brainfuck":       50 * time.Hour,
+		UnknownSummaryKey: 100 * time.Hour,
+	}
+	languageUsers := map[string]int{"Go": 4, "Python": 3, "Rust": 3, "Brainfuck": 1, UnknownSummaryKey: 5}
+
+	sut := NewInstanceStats(185*time.Hour, 5, 2, languageTotals, languageUsers, 3, 2)
+
+	assert.Equal(t, int64(185), sut.TotalHours())
+	assert.Equal(t, 5, sut.TotalUsers)
+	assert.Equal(t, 2, sut.ActiveUsers)
+	assert.Len(t, sut.Languages, 2)
+	assert.Equal(t, "Python", sut.Languages[0].Name)
+	assert.Equal(t, int64(20), sut.Languages[0].TotalHours())
+	assert.Equal(t, "Go", sut.Languages[1].Name)
+}
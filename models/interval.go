@@ -6,10 +6,14 @@ var (
 	IntervalToday              = &IntervalKey{"today", "Today"}
 	IntervalYesterday          = &IntervalKey{"day", "yesterday", "Yesterday"}
 	IntervalThisWeek           = &IntervalKey{"week", "This Week"}
-	IntervalLastWeek           = &IntervalKey{"Last Week"}
+	IntervalLastWeek           = &IntervalKey{"last_week", "Last Week"}
 	IntervalThisMonth          = &IntervalKey{"month", "This Month"}
 	IntervalLastMonth          = &IntervalKey{"Last Month"}
-	IntervalThisYear           = &IntervalKey{"year"}
+	IntervalThisQuarter        = &IntervalKey{"quarter", "this_quarter", "This Quarter"}
+	IntervalLastQuarter        = &IntervalKey{"last_quarter", "Last Quarter"}
+	IntervalThisYear           = &IntervalKey{"year", "year_to_date", "This Year"}
+	IntervalThisFiscalYear     = &IntervalKey{"fiscal_year", "This Fiscal Year"}
+	IntervalLastFiscalYear     = &IntervalKey{"last_fiscal_year", "Last Fiscal Year"}
 	IntervalPast7Days          = &IntervalKey{"7_days", "last_7_days", "Last 7 Days"}
 	IntervalPast7DaysYesterday = &IntervalKey{"Last 7 Days from Yesterday"}
 	IntervalPast14Days         = &IntervalKey{"Last 14 Days"}
@@ -25,7 +29,11 @@ var AllIntervals = []*IntervalKey{
 	IntervalLastWeek,
 	IntervalThisMonth,
 	IntervalLastMonth,
+	IntervalThisQuarter,
+	IntervalLastQuarter,
 	IntervalThisYear,
+	IntervalThisFiscalYear,
+	IntervalLastFiscalYear,
 	IntervalPast7Days,
 	IntervalPast7DaysYesterday,
 	IntervalPast14Days,
@@ -0,0 +1,30 @@
+package models
+
+const (
+	JiraWorklogCreate    = "create"
+	JiraWorklogUpdate    = "update"
+	JiraWorklogUnchanged = "unchanged"
+)
+
+// JiraWorklog keeps track of the time pushed to a jira worklog for a ticket and day, so that later syncs update it instead of creating duplicates
+type JiraWorklog struct {
+	ID               uint        `json:"-" gorm:"primary_key"`
+	User             *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID           string      `json:"-" gorm:"not null; uniqueIndex:idx_jira_worklog_user_ticket_date"`
+	Ticket           string      `json:"ticket" gorm:"not null; size:64; uniqueIndex:idx_jira_worklog_user_ticket_date"`
+	Date             string      `json:"date" gorm:"not null; size:10; uniqueIndex:idx_jira_worklog_user_ticket_date"` // in the user's time zone, e.g. '2022-10-16'
+	TimeSpentSeconds int64       `json:"time_spent_seconds"`
+	WorklogID        string      `json:"worklog_id"` // id of the worklog in jira, once created
+	SyncedAt         *CustomTime `json:"synced_at" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	Error            string      `json:"error"`           // error of the most recent push, if any
+	Action           string      `json:"action" gorm:"-"` // what the most recent sync (or a dry run) did, one of JiraWorklogCreate, JiraWorklogUpdate or JiraWorklogUnchanged
+}
+
+func (w *JiraWorklog) Key() string {
+	return w.Ticket + "_" + w.Date
+}
+
+// TimeSpent returns the worklog's duration in jira notation, e.g. '1h 30m'
+func (w *JiraWorklog) TimeSpent() string {
+	return fmtJiraDuration(w.TimeSpentSeconds)
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+const (
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+const (
+	JobAggregation     = "aggregation"
+	JobRegeneration    = "regeneration"
+	JobCountTotalTime  = "count_total_time"
+	JobReport          = "report"
+	JobImportWakatime  = "import_wakatime"
+	JobImportCodeStats = "import_codestats"
+	JobCodeStatsSync   = "codestats_sync"
+	JobDataIntegrity   = "doctor"
+	JobExport          = "export"
+	JobDataCleanup     = "data_cleanup"
+	JobBackup          = "backup"
+	JobJiraSync        = "jira_sync"
+	JobCalendarSync    = "calendar_sync"
+	JobInactivity      = "inactivity_check"
+	JobReassignment    = "reassignment"
+	JobUndo            = "undo"
+	JobLeaderboard     = "leaderboard"
+	JobStoragePrune    = "storage_prune"
+	JobMigration       = "migration"
+	JobDemoReset       = "demo_reset"
+)
+
+// JobStatus describes the most recent run of a scheduled or ad-hoc background task, optionally bound to a single user
+type JobStatus struct {
+	Name        string     `json:"name"`
+	UserID      string     `json:"user_id,omitempty"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	Runs        int        `json:"runs"`
+	Failures    int        `json:"failures"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at"`
+}
+
+func (j *JobStatus) Key() string {
+	if j.UserID == "" {
+		return j.Name
+	}
+	return j.Name + "_" + j.UserID
+}
+
+func (j *JobStatus) IsRunning() bool {
+	return j.Status == JobStatusRunning
+}
+
+// Duration returns the run time of the latest run so far
+func (j *JobStatus) Duration() time.Duration {
+	if j.FinishedAt == nil {
+		return time.Since(j.StartedAt)
+	}
+	return j.FinishedAt.Sub(j.StartedAt)
+}
@@ -0,0 +1,32 @@
+package models
+
+import "strings"
+
+// LanguageMeta describes how a language is presented in charts, badges and the ui
+type LanguageMeta struct {
+	Key    string `json:"id"`     // lower-case name, as used as language key in summaries
+	Name   string `json:"name"`   // display name
+	Color  string `json:"color"`  // hex color, empty if unknown
+	Icon   string `json:"icon"`   // iconify icon id, e.g. 'simple-icons:go', empty if none
+	Custom bool   `json:"custom"` // whether added or overridden by an admin
+}
+
+func NewLanguageMeta(name string) *LanguageMeta {
+	return &LanguageMeta{Key: strings.ToLower(name), Name: name}
+}
+
+// Merge overrides the language's properties with all non-empty ones of the given meta data
+func (m *LanguageMeta) Merge(override *LanguageMeta) *LanguageMeta {
+	merged := *m
+	if override.Name != "" {
+		merged.Name = override.Name
+	}
+	if override.Color != "" {
+		merged.Color = override.Color
+	}
+	if override.Icon != "" {
+		merged.Icon = override.Icon
+	}
+	merged.Custom = true
+	return &merged
+}
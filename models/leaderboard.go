@@ -0,0 +1,112 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// LeaderboardItem is a user's precomputed position on the leaderboard. The leaderboard is regenerated as a whole by a scheduled job and only served from persisted items.
+type LeaderboardItem struct {
+	ID           uint                 `json:"-" gorm:"primary_key"`
+	User         *User                `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID       string               `json:"user" gorm:"not null; index:idx_leaderboard_user"`
+	Rank         int                  `json:"rank" gorm:"not null"`
+	TotalSeconds int64                `json:"total" gorm:"not null"`
+	Languages    LeaderboardLanguages `json:"languages" gorm:"type:text"` // most used first
+	CreatedAt    CustomTime           `json:"created_at" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// LeaderboardLanguages are the names of a user's top languages
+type LeaderboardLanguages []string
+
+// NewLeaderboard ranks the users of the given summaries by their total time, most first. Users with equal totals share the same rank.
+// Users without any coding time are left out. For every user, at most maxLanguages languages are included.
+func NewLeaderboard(summaries map[string]*Summary, maxLanguages int) []*LeaderboardItem {
+	now := CustomTime(time.Now())
+	items := make([]*LeaderboardItem, 0, len(summaries))
+	for userId, summary := range summaries {
+		total := int64(summary.TotalTime().Seconds())
+		if total <= 0 {
+			continue
+		}
+		items = append(items, &LeaderboardItem{
+			UserID:       userId,
+			TotalSeconds: total,
+			Languages:    topLanguages(summary, maxLanguages),
+			CreatedAt:    now,
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].TotalSeconds == items[j].TotalSeconds {
+			return items[i].UserID < items[j].UserID
+		}
+		return items[i].TotalSeconds > items[j].TotalSeconds
+	})
+	for i, item := range items {
+		item.Rank = i + 1
+		if i > 0 && item.TotalSeconds == items[i-1].TotalSeconds {
+			item.Rank = items[i-1].Rank
+		}
+	}
+	return items
+}
+
+// Total returns the user's coding time within the leaderboard's period
+func (i *LeaderboardItem) Total() time.Duration {
+	return time.Duration(i.TotalSeconds) * time.Second
+}
+
+func topLanguages(summary *Summary, max int) LeaderboardLanguages {
+	languages := make([]*SummaryItem, 0, len(summary.Languages))
+	for _, item := range summary.Languages {
+		if item.Key != UnknownSummaryKey && item.Total > 0 {
+			languages = append(languages, item)
+		}
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if languages[i].Total == languages[j].Total {
+			return languages[i].Key < languages[j].Key
+		}
+		return languages[i].Total > languages[j].Total
+	})
+
+	names := make(LeaderboardLanguages, 0, max)
+	for i := 0; i < len(languages) && i < max; i++ {
+		names = append(names, languages[i].Key)
+	}
+	return names
+}
+
+func (l *LeaderboardLanguages) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New(fmt.Sprintf("unsupported type: %T", value))
+	}
+
+	if len(data) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(data, l)
+}
+
+func (l LeaderboardLanguages) Value() (driver.Value, error) {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewLeaderboard(t *testing.T) {
+	summaries := map[string]*Summary{
+		"alice": {Languages: []*SummaryItem{
+			{Type: SummaryLanguage, Key: "Go", Total: 60},
+			{Type: SummaryLanguage, Key: "Python", Total: 20},
+			{Type: SummaryLanguage, Key: "Rust", Total: 30},
+		}},
+		"bob": {Languages: []*SummaryItem{
+			{Type: SummaryLanguage, Key: UnknownSummaryKey, Total: 80},
+			{Type: SummaryLanguage, Key: "Python", Total: 30},
+		}},
+		"carol": {Languages: []*SummaryItem{
+			{Type: SummaryLanguage, Key: "Java", Total: 50},
+		}},
+		"dave": {Languages: []*SummaryItem{}},
+	}
+
+	sut := NewLeaderboard(summaries, 2)
+
+	assert.Len(t, sut, 3) // users without coding time are left out
+	assert.Equal(t, "alice", sut[0].UserID)
+	assert.Equal(t, 1, sut[0].Rank)
+	assert.Equal(t, int64(110), sut[0].TotalSeconds)
+	assert.Equal(t, LeaderboardLanguages{"Go", "Rust"}, sut[0].Languages)
+	assert.Equal(t, "bob", sut[1].UserID)
+	assert.Equal(t, 1, sut[1].Rank) // equal totals share a rank
+	assert.Equal(t, LeaderboardLanguages{"Python"}, sut[1].Languages)
+	assert.Equal(t, "carol", sut[2].UserID)
+	assert.Equal(t, 3, sut[2].Rank)
+}
+
+func TestLeaderboardLanguages_RoundTrip(t *testing.T) {
+	value, err := LeaderboardLanguages{"Go", "C#"}.Value()
+	assert.Nil(t, err)
+
+	var restored LeaderboardLanguages
+	assert.Nil(t, restored.Scan(value))
+	assert.Equal(t, LeaderboardLanguages{"Go", "C#"}, restored)
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Maintenance describes whether the instance is in maintenance mode, during which only read requests are served, e.g. while backups or migrations are running
+type Maintenance struct {
+	Enabled bool      `json:"enabled"`
+	Message string    `json:"message,omitempty"` // optional, shown to users as part of the banner
+	Since   time.Time `json:"since,omitempty" swaggertype:"string" format:"date" example:"2006-01-02T15:04:05Z07:00"`
+}
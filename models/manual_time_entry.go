@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// ManualTimeEntry is a block of time for a project, which was added manually by the user instead of being tracked by heartbeats,
+// e.g. for meetings or other un-instrumented work. Manual entries are not persisted as part of summaries, but merged in at retrieval time.
+type ManualTimeEntry struct {
+	ID        uint          `json:"id" gorm:"primary_key"`
+	User      *User         `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID    string        `json:"-" gorm:"not null; index:idx_manual_time_entry_user_date"`
+	Project   string        `json:"project" gorm:"not null"`
+	Date      CustomTime    `json:"date" gorm:"not null; type:timestamp; index:idx_manual_time_entry_user_date" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	Duration  time.Duration `json:"duration" swaggertype:"primitive,integer"`
+	Note      string        `json:"note" gorm:"type:text"`
+	CreatedAt CustomTime    `json:"created_at" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+func (e *ManualTimeEntry) IsValid() bool {
+	return e.Project != "" && e.Duration > 0 && e.Duration <= 24*time.Hour && e.Date.Valid() && !e.Date.T().IsZero()
+}
+
+// MatchFilters returns whether the entry is to be included for the given filters. As manual entries only carry a project,
+// they are excluded as soon as a filter for any other entity (e.g. language or editor) is present.
+// Label filters are expected to have been resolved to their projects before (see Filters.WithProjectLabels).
+func (e *ManualTimeEntry) MatchFilters(f *Filters) bool {
+	if f == nil {
+		return true
+	}
+	if f.Label.Exists() && !f.Project.Exists() {
+		return false // none of the filtered labels is assigned to any project
+	}
+	return (f.Project == nil || f.Project.MatchAny(e.Project)) &&
+		f.OS == nil &&
+		f.Language == nil &&
+		f.Editor == nil &&
+		f.Machine == nil &&
+		f.Branch == nil &&
+		(f.Origin == nil || f.Origin.MatchAny(OriginManual))
+}
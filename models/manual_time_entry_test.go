@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestManualTimeEntry_MatchFilters(t *testing.T) {
+	sut := &ManualTimeEntry{Project: "wakapi"}
+
+	assert.True(t, sut.MatchFilters(nil))
+	assert.True(t, sut.MatchFilters(&Filters{}))
+	assert.True(t, sut.MatchFilters(NewFiltersWith(SummaryProject, "wakapi")))
+	assert.False(t, sut.MatchFilters(NewFiltersWith(SummaryProject, "anchr")))
+	assert.False(t, sut.MatchFilters(NewFiltersWith(SummaryLanguage, "Go")))
+
+	// label filters resolved to their projects
+	assert.True(t, sut.MatchFilters(NewFiltersWith(SummaryLabel, "work").With(SummaryProject, "wakapi")))
+	assert.False(t, sut.MatchFilters(NewFiltersWith(SummaryLabel, "work").With(SummaryProject, "anchr")))
+	assert.False(t, sut.MatchFilters(NewFiltersWith(SummaryLabel, "unassigned")))
+}
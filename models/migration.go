@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// HeartbeatPage is a page of a user's heartbeats, ordered by id, as served to other instances for migrating them
+type HeartbeatPage struct {
+	Data        []*Heartbeat `json:"data"`
+	NextAfterId uint64       `json:"next_after_id"` // id to request the next page after
+	HasMore     bool         `json:"has_more"`
+}
+
+// Migration is the state of a user's migration from another wakapi instance, which doubles as checkpoint to resume an interrupted migration from
+type Migration struct {
+	ApiUrl           string     `json:"api_url"`
+	AfterId          uint64     `json:"after_id"` // id of the last heartbeat (at the other instance) replicated so far
+	SettingsImported bool       `json:"settings_imported"`
+	Imported         int        `json:"imported"` // number of heartbeats replicated so far, including duplicates skipped on insert
+	StartedAt        time.Time  `json:"started_at"`
+	FinishedAt       *time.Time `json:"finished_at"`
+	Error            string     `json:"error,omitempty"`
+}
+
+func (m *Migration) IsDone() bool {
+	return m.FinishedAt != nil
+}
@@ -0,0 +1,92 @@
+package models
+
+const (
+	NotificationChannelEmail    = "email"
+	NotificationChannelWebhook  = "webhook"
+	NotificationChannelSlack    = "slack"
+	NotificationChannelTelegram = "telegram"
+)
+
+const (
+	NotificationEventReport          = "report"
+	NotificationEventBudgetAlert     = "budget_alert"
+	NotificationEventWakatimeFailure = "wakatime_failure"
+	NotificationEventImportFinished  = "import_finished"
+	NotificationEventInactivity      = "inactivity"
+)
+
+func NotificationChannels() []string {
+	return []string{NotificationChannelEmail, NotificationChannelWebhook, NotificationChannelSlack, NotificationChannelTelegram}
+}
+
+func NotificationEvents() []string {
+	return []string{NotificationEventReport, NotificationEventBudgetAlert, NotificationEventWakatimeFailure, NotificationEventImportFinished, NotificationEventInactivity}
+}
+
+// NotificationPreference tells whether a user wants to be notified about a certain kind of event via a certain channel.
+// Combinations without a preference fall back to DefaultNotificationEnabled.
+type NotificationPreference struct {
+	ID      uint   `json:"-" gorm:"primary_key"`
+	User    *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID  string `json:"-" gorm:"not null; uniqueIndex:idx_notification_preference_user_event_channel"`
+	Event   string `json:"event" gorm:"not null; size:32; uniqueIndex:idx_notification_preference_user_event_channel"`
+	Channel string `json:"channel" gorm:"not null; size:32; uniqueIndex:idx_notification_preference_user_event_channel"`
+	Enabled bool   `json:"enabled" gorm:"default:false; type:bool"`
+}
+
+func (p *NotificationPreference) IsValid() bool {
+	return contains(NotificationEvents(), p.Event) && contains(NotificationChannels(), p.Channel)
+}
+
+type NotificationPreferences []*NotificationPreference
+
+// IsEnabled tells whether the given event is to be notified about via the given channel, falling back to the default, if no preference was stored
+func (p NotificationPreferences) IsEnabled(event, channel string) bool {
+	for _, pref := range p {
+		if pref.Event == event && pref.Channel == channel {
+			return pref.Enabled
+		}
+	}
+	return DefaultNotificationEnabled(event, channel)
+}
+
+// AnyEnabled tells whether the given event is to be notified about via at least one channel
+func (p NotificationPreferences) AnyEnabled(event string) bool {
+	for _, c := range NotificationChannels() {
+		if p.IsEnabled(event, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultNotificationEnabled mirrors the behavior before notification preferences were introduced, i.e. everything except reports is sent via e-mail
+func DefaultNotificationEnabled(event, channel string) bool {
+	return channel == NotificationChannelEmail && event != NotificationEventReport
+}
+
+// Notification is a channel-agnostic message about an event, as sent via webhooks, slack or telegram
+type Notification struct {
+	Event string `json:"event"`
+	User  string `json:"user"`
+	Title string `json:"title"`
+	Text  string `json:"text"` // plain text
+	Link  string `json:"link,omitempty"`
+}
+
+func (n *Notification) String() string {
+	s := n.Title + "\n\n" + n.Text
+	if n.Link != "" {
+		s += "\n\n" + n.Link
+	}
+	return s
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNotificationPreferences_IsEnabled(t *testing.T) {
+	prefs := NotificationPreferences{
+		{Event: NotificationEventReport, Channel: NotificationChannelSlack, Enabled: true},
+		{Event: NotificationEventBudgetAlert, Channel: NotificationChannelEmail, Enabled: false},
+	}
+
+	assert.True(t, prefs.IsEnabled(NotificationEventReport, NotificationChannelSlack))
+	assert.False(t, prefs.IsEnabled(NotificationEventBudgetAlert, NotificationChannelEmail))
+
+	// defaults
+	assert.False(t, prefs.IsEnabled(NotificationEventReport, NotificationChannelEmail))
+	assert.True(t, prefs.IsEnabled(NotificationEventImportFinished, NotificationChannelEmail))
+	assert.False(t, prefs.IsEnabled(NotificationEventImportFinished, NotificationChannelWebhook))
+}
+
+func TestNotificationPreferences_AnyEnabled(t *testing.T) {
+	assert.False(t, NotificationPreferences{}.AnyEnabled(NotificationEventReport))
+	assert.True(t, NotificationPreferences{}.AnyEnabled(NotificationEventBudgetAlert))
+	assert.True(t, NotificationPreferences{
+		{Event: NotificationEventReport, Channel: NotificationChannelTelegram, Enabled: true},
+	}.AnyEnabled(NotificationEventReport))
+}
+
+func TestNotificationPreference_IsValid(t *testing.T) {
+	assert.True(t, (&NotificationPreference{Event: NotificationEventReport, Channel: NotificationChannelWebhook}).IsValid())
+	assert.False(t, (&NotificationPreference{Event: "foo", Channel: NotificationChannelWebhook}).IsValid())
+	assert.False(t, (&NotificationPreference{Event: NotificationEventReport, Channel: "pigeon"}).IsValid())
+}
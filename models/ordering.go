@@ -0,0 +1,48 @@
+package models
+
+const (
+	OrderByTime         = "time"
+	OrderByName         = "name"
+	OrderByLastActivity = "last_activity"
+	OrderByRank         = "rank"
+)
+
+// Ordering describes how to sort the results of list endpoints, as requested via their 'order_by' and 'order' parameters
+type Ordering struct {
+	By   string
+	Desc bool
+}
+
+func NewOrdering(by string, desc bool) *Ordering {
+	return &Ordering{By: by, Desc: desc}
+}
+
+func (o *Ordering) Direction() string {
+	if o.Desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// ProjectActivity holds the time of the latest heartbeat per project
+type ProjectActivity struct {
+	Project      string
+	LastActivity CustomTime
+}
+
+// MachineActivity holds the time of the latest heartbeat per machine
+type MachineActivity struct {
+	Machine      string
+	LastActivity CustomTime
+}
+
+// OriginActivity holds the latest heartbeat per machine and origin, e.g. to check which of a user's devices are actively reporting
+type OriginActivity struct {
+	Machine         string     `json:"machine"`
+	Origin          string     `json:"origin"` // origin kind, see OriginDirect, etc.
+	Editor          string     `json:"editor"`
+	OperatingSystem string     `json:"operating_system"`
+	UserAgent       string     `json:"user_agent"`
+	AgentVersion    string     `json:"agent_version,omitempty"` // version of wakatime-cli or the browser extension
+	LastActivity    CustomTime `json:"last_activity" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
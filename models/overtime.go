@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// DefaultWorkdays are monday to friday, as a bitmask of time.Weekday
+const DefaultWorkdays = 1<<time.Monday | 1<<time.Tuesday | 1<<time.Wednesday | 1<<time.Thursday | 1<<time.Friday
+
+// OvertimeDay compares the time worked on a day to the user's target for that day
+type OvertimeDay struct {
+	Date           time.Time     `json:"date" swaggertype:"string" format:"date" example:"2006-01-02"`
+	Target         time.Duration `json:"-"`
+	Actual         time.Duration `json:"-"`
+	TargetSeconds  int64         `json:"target"`
+	ActualSeconds  int64         `json:"actual"`
+	BalanceSeconds int64         `json:"balance"`           // positive for overtime, negative for undertime
+	DayOff         string        `json:"day_off,omitempty"` // kind of day off, see DayOffVacation and DayOffSick
+}
+
+func NewOvertimeDay(date time.Time, target, actual time.Duration) *OvertimeDay {
+	return &OvertimeDay{
+		Date:           date,
+		Target:         target,
+		Actual:         actual,
+		TargetSeconds:  int64(target.Seconds()),
+		ActualSeconds:  int64(actual.Seconds()),
+		BalanceSeconds: int64((actual - target).Seconds()),
+	}
+}
+
+// Overtime is the balance of time worked versus the user's workday targets within an interval
+type Overtime struct {
+	From           time.Time      `json:"from"`
+	To             time.Time      `json:"to"`
+	Target         time.Duration  `json:"-"`
+	Actual         time.Duration  `json:"-"`
+	TargetSeconds  int64          `json:"target"`
+	ActualSeconds  int64          `json:"actual"`
+	BalanceSeconds int64          `json:"balance"` // positive for overtime, negative for undertime
+	Days           []*OvertimeDay `json:"days"`
+}
+
+func NewOvertime(from, to time.Time, days []*OvertimeDay) *Overtime {
+	overtime := &Overtime{From: from, To: to, Days: days}
+	for _, d := range days {
+		overtime.Target += d.Target
+		overtime.Actual += d.Actual
+	}
+	overtime.TargetSeconds = int64(overtime.Target.Seconds())
+	overtime.ActualSeconds = int64(overtime.Actual.Seconds())
+	overtime.BalanceSeconds = int64(overtime.Balance().Seconds())
+	return overtime
+}
+
+func (o *Overtime) Balance() time.Duration {
+	return o.Actual - o.Target
+}
+
+func (o *Overtime) IsOvertime() bool {
+	return o.Balance() >= 0
+}
+
+// BalanceAbs returns the amount of over- or undertime, i.e. the balance without its sign
+func (o *Overtime) BalanceAbs() time.Duration {
+	if b := o.Balance(); b < 0 {
+		return -b
+	}
+	return o.Balance()
+}
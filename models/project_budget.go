@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// BudgetThresholds are the shares of a budget (in percent), at which users are notified, in ascending order
+var BudgetThresholds = []int{80, 100}
+
+// ProjectBudget is a monthly number of hours to spend on a project at most, e.g. as agreed upon with a client
+type ProjectBudget struct {
+	ID                uint   `json:"-" gorm:"primary_key"`
+	User              *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID            string `json:"-" gorm:"not null; uniqueIndex:idx_project_budget_user_project"`
+	ProjectKey        string `json:"project" gorm:"not null; size:255; uniqueIndex:idx_project_budget_user_project"`
+	Hours             int    `json:"hours"`
+	NotifiedThreshold int    `json:"-"`               // highest threshold the user was notified about within NotifiedMonth
+	NotifiedMonth     string `json:"-" gorm:"size:7"` // e.g. '2022-10'
+}
+
+func (b *ProjectBudget) IsValid() bool {
+	return b.ProjectKey != "" && b.Hours > 0
+}
+
+func (b *ProjectBudget) Total() time.Duration {
+	return time.Duration(b.Hours) * time.Hour
+}
+
+// BudgetStatus tells how much of a project's budget was consumed within the current month
+type BudgetStatus struct {
+	Project       string        `json:"project"`
+	Month         string        `json:"month"` // e.g. '2022-10'
+	Budget        time.Duration `json:"-"`
+	Used          time.Duration `json:"-"`
+	BudgetSeconds int64         `json:"budget"`
+	UsedSeconds   int64         `json:"used"`
+	Percentage    float64       `json:"percentage"` // share of the budget used, may exceed 100
+	Threshold     int           `json:"threshold"`  // highest of BudgetThresholds reached, 0 if none
+}
+
+func NewBudgetStatus(budget *ProjectBudget, month string, used time.Duration) *BudgetStatus {
+	status := &BudgetStatus{
+		Project:       budget.ProjectKey,
+		Month:         month,
+		Budget:        budget.Total(),
+		Used:          used,
+		BudgetSeconds: int64(budget.Total().Seconds()),
+		UsedSeconds:   int64(used.Seconds()),
+	}
+	if status.Budget > 0 {
+		status.Percentage = float64(used) / float64(status.Budget) * 100
+	}
+	for _, t := range BudgetThresholds {
+		if status.Percentage >= float64(t) {
+			status.Threshold = t
+		}
+	}
+	return status
+}
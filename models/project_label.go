@@ -1,5 +1,10 @@
 package models
 
+import "strings"
+
+// ProjectLabelSeparator nests labels into hierarchies, e.g. 'work/client-a' is a child of 'work'
+const ProjectLabelSeparator = "/"
+
 // ProjectLabelReverseResolver returns all projects for a given label
 type ProjectLabelReverseResolver func(l string) []string
 
@@ -12,5 +17,33 @@ type ProjectLabel struct {
 }
 
 func (l *ProjectLabel) IsValid() bool {
-	return l.ProjectKey != "" && l.Label != ""
+	return l.ProjectKey != "" && ValidateLabel(l.Label)
+}
+
+// ValidateLabel tells whether the given label is non-empty and, if nested, consists of non-empty parts only
+func ValidateLabel(label string) bool {
+	if label == "" {
+		return false
+	}
+	for _, part := range strings.Split(label, ProjectLabelSeparator) {
+		if strings.TrimSpace(part) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// LabelAncestors returns all parents of the given label, top-most first, e.g. 'work' and 'work/client-a' for 'work/client-a/frontend'
+func LabelAncestors(label string) []string {
+	parts := strings.Split(label, ProjectLabelSeparator)
+	ancestors := make([]string, 0, len(parts)-1)
+	for i := 1; i < len(parts); i++ {
+		ancestors = append(ancestors, strings.Join(parts[:i], ProjectLabelSeparator))
+	}
+	return ancestors
+}
+
+// LabelIncludes tells whether the given label equals the parent label or is nested below it
+func LabelIncludes(parent, label string) bool {
+	return label == parent || strings.HasPrefix(label, parent+ProjectLabelSeparator)
 }
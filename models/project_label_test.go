@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLabel(t *testing.T) {
+	assert.True(t, ValidateLabel("work"))
+	assert.True(t, ValidateLabel("work/client-a"))
+	assert.False(t, ValidateLabel(""))
+	assert.False(t, ValidateLabel("work/"))
+	assert.False(t, ValidateLabel("/work"))
+	assert.False(t, ValidateLabel("work//client-a"))
+}
+
+func TestLabelAncestors(t *testing.T) {
+	assert.Empty(t, LabelAncestors("work"))
+	assert.Equal(t, []string{"work"}, LabelAncestors("work/client-a"))
+	assert.Equal(t, []string{"work", "work/client-a"}, LabelAncestors("work/client-a/frontend"))
+}
+
+func TestLabelIncludes(t *testing.T) {
+	assert.True(t, LabelIncludes("work", "work"))
+	assert.True(t, LabelIncludes("work", "work/client-a"))
+	assert.True(t, LabelIncludes("work", "work/client-a/frontend"))
+	assert.False(t, LabelIncludes("work", "workshop"))
+	assert.False(t, LabelIncludes("work/client-a", "work"))
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+const (
+	RepoProviderGithub = "github"
+	RepoProviderGitlab = "gitlab"
+)
+
+// ProjectRepo links a project to the git repository hosting its code
+type ProjectRepo struct {
+	ID         uint   `json:"id" gorm:"primary_key"`
+	User       *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID     string `json:"-" gorm:"not null; uniqueIndex:idx_project_repo_user_project"`
+	ProjectKey string `json:"project" gorm:"not null; uniqueIndex:idx_project_repo_user_project; size:255"`
+	Url        string `json:"url" gorm:"not null; size:255"`
+}
+
+func (r *ProjectRepo) IsValid() bool {
+	if r.ProjectKey == "" {
+		return false
+	}
+	_, _, _, err := r.Parse()
+	return err == nil
+}
+
+// Parse splits the repository url into the hosting provider, its host name and the repository's path (e.g. "muety/wakapi").
+// Only GitHub and GitLab (including self-hosted instances with "gitlab" in their host name) are supported.
+func (r *ProjectRepo) Parse() (provider, host, path string, err error) {
+	u, err := url.Parse(strings.TrimSpace(r.Url))
+	if err != nil {
+		return "", "", "", err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return "", "", "", errors.New("repository url must be an http(s) url")
+	}
+
+	host = strings.ToLower(u.Host)
+	path = strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if strings.Count(path, "/") < 1 {
+		return "", "", "", errors.New("repository url must contain owner and name")
+	}
+
+	switch {
+	case host == "github.com" || host == "www.github.com":
+		if strings.Count(path, "/") != 1 {
+			return "", "", "", errors.New("github repository url must be of form https://github.com/<owner>/<name>")
+		}
+		return RepoProviderGithub, "github.com", path, nil
+	case strings.Contains(host, "gitlab"):
+		return RepoProviderGitlab, host, path, nil
+	}
+	return "", "", "", errors.New("only github and gitlab repositories are supported")
+}
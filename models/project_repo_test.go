@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestProjectRepo_Parse(t *testing.T) {
+	tests := []struct {
+		url      string
+		provider string
+		host     string
+		path     string
+	}{
+		{"https://github.com/muety/wakapi", RepoProviderGithub, "github.com", "muety/wakapi"},
+		{"https://www.github.com/muety/wakapi.git", RepoProviderGithub, "github.com", "muety/wakapi"},
+		{"https://github.com/muety/wakapi/", RepoProviderGithub, "github.com", "muety/wakapi"},
+		{"https://gitlab.com/group/subgroup/project", RepoProviderGitlab, "gitlab.com", "group/subgroup/project"},
+		{"https://gitlab.example.org/team/project.git", RepoProviderGitlab, "gitlab.example.org", "team/project"},
+		{"https://github.com/muety/wakapi/tree/master", "", "", ""},
+		{"https://github.com/muety", "", "", ""},
+		{"git@github.com:muety/wakapi.git", "", "", ""},
+		{"https://bitbucket.org/team/project", "", "", ""},
+		{"", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		repo := &ProjectRepo{ProjectKey: "wakapi", Url: tt.url}
+		provider, host, path, err := repo.Parse()
+		if tt.provider == "" {
+			assert.Error(t, err, tt.url)
+			assert.False(t, repo.IsValid(), tt.url)
+			continue
+		}
+		assert.Nil(t, err, tt.url)
+		assert.True(t, repo.IsValid(), tt.url)
+		assert.Equal(t, tt.provider, provider, tt.url)
+		assert.Equal(t, tt.host, host, tt.url)
+		assert.Equal(t, tt.path, path, tt.url)
+	}
+}
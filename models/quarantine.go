@@ -0,0 +1,21 @@
+package models
+
+// Reasons for heartbeats to be quarantined
+const (
+	QuarantineReasonRate       = "rate"       // implausibly many heartbeats within a single minute
+	QuarantineReasonTimestamps = "timestamps" // many heartbeats with the very same timestamp
+	QuarantineReasonMachines   = "machines"   // activity on implausibly many machines at the same time
+)
+
+// Quarantine flags a suspicious range of a user's heartbeats, e.g. sent by a misbehaving or malicious client, for admins to review.
+// Users with pending quarantines are excluded from the leaderboard, until admins either accept or purge the heartbeats.
+type Quarantine struct {
+	ID            uint       `json:"id" gorm:"primary_key"`
+	User          *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID        string     `json:"user_id" gorm:"not null; index:idx_quarantine_user"`
+	Reason        string     `json:"reason"`
+	StartTime     CustomTime `json:"start" gorm:"not null; type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	EndTime       CustomTime `json:"end" gorm:"not null; type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"` // exclusive
+	NumHeartbeats int        `json:"num_heartbeats"`                                                                                           // heartbeats seen within the range by the time it was flagged
+	CreatedAt     CustomTime `json:"created_at" gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
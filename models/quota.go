@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// QuotaStatus is the consumption of a quota within its current window, e.g. of a user's heartbeats per hour or a client's api requests per minute
+type QuotaStatus struct {
+	Limit int       `json:"limit"` // per window, 0 = unlimited
+	Used  int       `json:"used"`
+	Reset time.Time `json:"reset"` // start of the next window
+}
+
+func (s *QuotaStatus) IsUnlimited() bool {
+	return s.Limit <= 0
+}
+
+func (s *QuotaStatus) Remaining() int {
+	if s.IsUnlimited() || s.Used >= s.Limit {
+		return 0
+	}
+	return s.Limit - s.Used
+}
+
+// Allows tells whether another n heartbeats or requests fit into the quota
+func (s *QuotaStatus) Allows(n int) bool {
+	return s.IsUnlimited() || s.Used+n <= s.Limit
+}
+
+// QuotaViolation summarizes the requests of a user, which were rejected for exceeding their heartbeat quota
+type QuotaViolation struct {
+	UserID        string    `json:"user_id"`
+	Limit         int       `json:"limit"`
+	Hours         int       `json:"hours"`    // number of distinct hours, in which the quota was exceeded
+	Rejected      int       `json:"rejected"` // number of heartbeats rejected in total
+	LastViolation time.Time `json:"last_violation"`
+}
+
+// IsRepeated tells whether the user exceeded their quota in more than one hour
+func (v *QuotaViolation) IsRepeated() bool {
+	return v.Hours > 1
+}
+
+// StorageQuotaUsage is the number of heartbeats a user has stored compared to the number they may store at most
+type StorageQuotaUsage struct {
+	Limit  int64  `json:"limit"` // heartbeats, 0 = unlimited
+	Used   int64  `json:"used"`
+	Policy string `json:"policy"` // what happens once the quota is exceeded, either 'reject' or 'prune'
+}
+
+func (u *StorageQuotaUsage) IsUnlimited() bool {
+	return u.Limit <= 0
+}
+
+// Exceeds tells whether another n heartbeats would exceed the quota
+func (u *StorageQuotaUsage) Exceeds(n int64) bool {
+	return !u.IsUnlimited() && u.Used+n > u.Limit
+}
+
+// Excess returns the number of heartbeats stored beyond the quota
+func (u *StorageQuotaUsage) Excess() int64 {
+	if u.IsUnlimited() || u.Used <= u.Limit {
+		return 0
+	}
+	return u.Used - u.Limit
+}
+
+// Percentage returns the share of the quota used up, capped at 100
+func (u *StorageQuotaUsage) Percentage() int {
+	if u.IsUnlimited() {
+		return 0
+	}
+	if u.Used >= u.Limit {
+		return 100
+	}
+	return int(u.Used * 100 / u.Limit)
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// RegenerationJob tracks the progress of regenerating a user's persisted summaries for a given range of days
+type RegenerationJob struct {
+	UserID     string     `json:"user_id"`
+	From       time.Time  `json:"from"`
+	To         time.Time  `json:"to"`
+	Total      int        `json:"total"`     // number of days to process
+	Processed  int        `json:"processed"` // number of days processed so far, including failed ones
+	Failed     int        `json:"failed"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Error      string     `json:"error,omitempty"` // last error encountered
+}
+
+func NewRegenerationJob(userId string, from, to time.Time) *RegenerationJob {
+	total := 0
+	for t := from; t.Before(to); t = t.AddDate(0, 0, 1) {
+		total++
+	}
+	return &RegenerationJob{
+		UserID:    userId,
+		From:      from,
+		To:        to,
+		Total:     total,
+		StartedAt: time.Now(),
+	}
+}
+
+func (j *RegenerationJob) IsDone() bool {
+	return j.FinishedAt != nil
+}
+
+// Progress returns the share of processed days in percent
+func (j *RegenerationJob) Progress() int {
+	if j.Total == 0 {
+		return 100
+	}
+	return j.Processed * 100 / j.Total
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	RelayRuleProject   = "project"    // don't relay heartbeats of the given project
+	RelayRuleLabel     = "label"      // don't relay heartbeats of projects with the given label
+	RelayRuleLanguage  = "language"   // don't relay heartbeats in the given language
+	RelayRuleEditor    = "editor"     // don't relay heartbeats from the given editor
+	RelayRuleMachine   = "machine"    // don't relay heartbeats from the given machine
+	RelayRuleBranch    = "branch"     // don't relay heartbeats on the given branch
+	RelayRuleWorkHours = "work_hours" // only relay heartbeats within the given hours on the user's workdays, e.g. '09:00-17:00'
+)
+
+func RelayRuleTypes() []string {
+	return []string{RelayRuleProject, RelayRuleLabel, RelayRuleLanguage, RelayRuleEditor, RelayRuleMachine, RelayRuleBranch, RelayRuleWorkHours}
+}
+
+// RelayRule restricts which of a user's heartbeats are relayed to upstream services, e.g. to keep private projects to oneself.
+// Rules apply to all relay targets and a heartbeat is only relayed, if it passes every rule.
+type RelayRule struct {
+	ID     uint   `json:"id" gorm:"primary_key"`
+	User   *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID string `json:"-" gorm:"not null; index:idx_relay_rule_user"`
+	Type   string `json:"type" gorm:"not null; size:16"`
+	Value  string `json:"value" gorm:"not null; size:255"`
+}
+
+func (r *RelayRule) IsValid() bool {
+	if r.Type == RelayRuleWorkHours {
+		_, _, ok := r.WorkHours()
+		return ok
+	}
+	return contains(RelayRuleTypes(), r.Type) && r.Value != ""
+}
+
+// WorkHours returns the beginning and end of a work hours rule as offsets from midnight, whereby the end may lie before the beginning for night shifts
+func (r *RelayRule) WorkHours() (time.Duration, time.Duration, bool) {
+	parts := strings.Split(r.Value, "-")
+	if r.Type != RelayRuleWorkHours || len(parts) != 2 {
+		return 0, 0, false
+	}
+	from, err1 := time.Parse("15:04", strings.TrimSpace(parts[0]))
+	to, err2 := time.Parse("15:04", strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil || from.Equal(to) {
+		return 0, 0, false
+	}
+	return time.Duration(from.Hour())*time.Hour + time.Duration(from.Minute())*time.Minute,
+		time.Duration(to.Hour())*time.Hour + time.Duration(to.Minute())*time.Minute,
+		true
+}
+
+// Accepts tells whether the heartbeat passes the rule, given the labels of its project
+func (r *RelayRule) Accepts(heartbeat *Heartbeat, user *User, labels []string) bool {
+	switch r.Type {
+	case RelayRuleProject:
+		return heartbeat.Project != r.Value
+	case RelayRuleLabel:
+		for _, l := range labels {
+			if LabelIncludes(r.Value, l) {
+				return false
+			}
+		}
+		return true
+	case RelayRuleLanguage:
+		return heartbeat.Language != r.Value
+	case RelayRuleEditor:
+		return heartbeat.Editor != r.Value
+	case RelayRuleMachine:
+		return heartbeat.Machine != r.Value
+	case RelayRuleBranch:
+		return heartbeat.Branch != r.Value
+	case RelayRuleWorkHours:
+		from, to, ok := r.WorkHours()
+		if !ok {
+			return true
+		}
+		t := heartbeat.Time.T().In(user.TZ())
+		offset := t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()))
+		if from < to {
+			return user.IsWorkday(t.Weekday()) && offset >= from && offset < to
+		}
+		// night shift, the part after midnight belongs to the previous day
+		if offset >= from {
+			return user.IsWorkday(t.Weekday())
+		}
+		return offset < to && user.IsWorkday(t.AddDate(0, 0, -1).Weekday())
+	}
+	return true
+}
+
+type RelayRules []*RelayRule
+
+func (rs RelayRules) Accepts(heartbeat *Heartbeat, user *User, labels []string) bool {
+	for _, r := range rs {
+		if !r.Accepts(heartbeat, user, labels) {
+			return false
+		}
+	}
+	return true
+}
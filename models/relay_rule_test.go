@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelayRule_IsValid(t *testing.T) {
+	assert.True(t, (&RelayRule{Type: RelayRuleLabel, Value: "private"}).IsValid())
+	assert.True(t, (&RelayRule{Type: RelayRuleWorkHours, Value: "09:00-17:00"}).IsValid())
+	assert.True(t, (&RelayRule{Type: RelayRuleWorkHours, Value: "22:00 - 06:00"}).IsValid())
+	assert.False(t, (&RelayRule{Type: RelayRuleLabel}).IsValid())
+	assert.False(t, (&RelayRule{Type: "foo", Value: "bar"}).IsValid())
+	assert.False(t, (&RelayRule{Type: RelayRuleWorkHours, Value: "9-17"}).IsValid())
+	assert.False(t, (&RelayRule{Type: RelayRuleWorkHours, Value: "09:00-09:00"}).IsValid())
+}
+
+func TestRelayRules_Accepts(t *testing.T) {
+	user := &User{Location: "UTC", Workdays: DefaultWorkdays}
+	rules := RelayRules{
+		{Type: RelayRuleLabel, Value: "private"},
+		{Type: RelayRuleProject, Value: "secret"},
+	}
+
+	assert.True(t, rules.Accepts(&Heartbeat{Project: "wakapi"}, user, []string{"oss"}))
+	assert.False(t, rules.Accepts(&Heartbeat{Project: "dotfiles"}, user, []string{"oss", "private"}))
+	assert.False(t, rules.Accepts(&Heartbeat{Project: "secret"}, user, []string{}))
+	assert.True(t, RelayRules{}.Accepts(&Heartbeat{Project: "secret"}, user, []string{}))
+	assert.False(t, rules.Accepts(&Heartbeat{Project: "diary"}, user, []string{"private/notes"})) // nested below excluded label
+	assert.True(t, rules.Accepts(&Heartbeat{Project: "wakapi"}, user, []string{"privateer"}))
+}
+
+func TestRelayRule_Accepts_WorkHours(t *testing.T) {
+	user := &User{Location: "Europe/Berlin", Workdays: DefaultWorkdays}
+	tz, _ := time.LoadLocation("Europe/Berlin")
+	at := func(day, hour, min int) *Heartbeat {
+		return &Heartbeat{Time: CustomTime(time.Date(2022, 11, day, hour, min, 0, 0, tz).UTC())} // nov 7th is a monday
+	}
+
+	rule := &RelayRule{Type: RelayRuleWorkHours, Value: "09:00-17:00"}
+	assert.True(t, rule.Accepts(at(7, 9, 0), user, nil))
+	assert.True(t, rule.Accepts(at(7, 16, 59), user, nil))
+	assert.False(t, rule.Accepts(at(7, 17, 0), user, nil))
+	assert.False(t, rule.Accepts(at(7, 8, 30), user, nil))
+	assert.False(t, rule.Accepts(at(12, 10, 0), user, nil)) // saturday
+
+	nightShift := &RelayRule{Type: RelayRuleWorkHours, Value: "22:00-06:00"}
+	assert.True(t, nightShift.Accepts(at(7, 23, 0), user, nil))
+	assert.True(t, nightShift.Accepts(at(8, 5, 0), user, nil))
+	assert.False(t, nightShift.Accepts(at(7, 12, 0), user, nil))
+	assert.True(t, nightShift.Accepts(at(12, 2, 0), user, nil)) // friday's shift
+	assert.False(t, nightShift.Accepts(at(7, 2, 0), user, nil)) // sunday's shift
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// RelayTargetWakatime is the name of the implicit relay target, that is configured through the user's wakatime api key and url
+const RelayTargetWakatime = "wakatime"
+
+// RelayTarget is an additional upstream service, e.g. another Wakapi instance, that a user's heartbeats are relayed to with a separate api key
+type RelayTarget struct {
+	ID      uint        `json:"id" gorm:"primary_key"`
+	User    *User       `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID  string      `json:"-" gorm:"not null; index:idx_relay_target_user"`
+	Name    string      `json:"name" gorm:"not null; size:64"`
+	ApiUrl  string      `json:"api_url" gorm:"not null; size:255"` // base url of a wakatime-compatible api, e.g. https://wakapi.example.org/api/compat/wakatime/v1
+	ApiKey  string      `json:"-" gorm:"not null"`
+	Enabled bool        `json:"enabled" gorm:"default:true; type:bool"`
+	Stats   *RelayStats `json:"stats,omitempty" gorm:"-"`
+}
+
+// NewWakatimeRelayTarget wraps the user's legacy wakatime integration into a relay target
+func NewWakatimeRelayTarget(user *User, fallbackUrl string) *RelayTarget {
+	return &RelayTarget{
+		UserID:  user.ID,
+		Name:    RelayTargetWakatime,
+		ApiUrl:  user.WakaTimeURL(fallbackUrl),
+		ApiKey:  user.WakatimeApiKey,
+		Enabled: true,
+	}
+}
+
+func (t *RelayTarget) IsValid() bool {
+	return t.Name != "" &&
+		t.Name != RelayTargetWakatime &&
+		(strings.HasPrefix(t.ApiUrl, "http://") || strings.HasPrefix(t.ApiUrl, "https://")) &&
+		t.ApiKey != ""
+}
+
+// IsImplicit tells whether this is the user's wakatime integration rather than a stored target
+func (t *RelayTarget) IsImplicit() bool {
+	return t.ID == 0
+}
+
+// RelayStats are the delivery metrics of a single relay target since the server was last started
+type RelayStats struct {
+	Delivered           int        `json:"delivered"` // number of successfully relayed requests
+	Failed              int        `json:"failed"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastDelivery        *time.Time `json:"last_delivery,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
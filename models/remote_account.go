@@ -0,0 +1,21 @@
+package models
+
+import "strings"
+
+// RemoteAccount is an account at another Wakapi instance or at WakaTime, whose stats are pulled and combined with the user's local ones,
+// e.g. for people who are forced to track their work and personal coding on separate servers
+type RemoteAccount struct {
+	ID      uint   `json:"id" gorm:"primary_key"`
+	User    *User  `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID  string `json:"-" gorm:"not null; index:idx_remote_account_user"`
+	Name    string `json:"name" gorm:"not null; size:64"`
+	ApiUrl  string `json:"api_url" gorm:"not null; size:255"` // base url of a wakatime-compatible api, e.g. https://wakapi.example.org/api/compat/wakatime/v1
+	ApiKey  string `json:"-" gorm:"not null"`
+	Enabled bool   `json:"enabled" gorm:"default:true; type:bool"`
+}
+
+func (a *RemoteAccount) IsValid() bool {
+	return a.Name != "" &&
+		(strings.HasPrefix(a.ApiUrl, "http://") || strings.HasPrefix(a.ApiUrl, "https://")) &&
+		a.ApiKey != ""
+}
@@ -3,8 +3,10 @@ package models
 import "time"
 
 type Report struct {
-	From    time.Time
-	To      time.Time
-	User    *User
-	Summary *Summary
+	From     time.Time
+	To       time.Time
+	User     *User
+	Summary  *Summary
+	Overtime *Overtime // nil, unless the user has a workday target
+	PdfUrl   string
 }
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RouteStats sums up the requests to a single api route since server start, e.g. to tell whether a deprecated route can be removed
+type RouteStats struct {
+	Route       string           `json:"route"` // method and path template, e.g. 'POST /api/heartbeat'
+	Count       int64            `json:"count"`
+	Deprecated  bool             `json:"deprecated"`
+	LastRequest time.Time        `json:"last_request"`
+	Clients     map[string]int64 `json:"clients"` // number of requests by client, e.g. 'vscode' or 'curl'
+}
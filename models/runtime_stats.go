@@ -0,0 +1,54 @@
+package models
+
+import (
+	"runtime"
+	"time"
+)
+
+const runtimeStatsRecentGcPauses = 10
+
+// RuntimeStats is a snapshot of the server's runtime, to diagnose memory growth and load on large instances
+type RuntimeStats struct {
+	Goroutines        int       `json:"goroutines"`
+	HeapAllocBytes    uint64    `json:"heap_alloc_bytes"`
+	HeapSysBytes      uint64    `json:"heap_sys_bytes"`
+	HeapObjects       uint64    `json:"heap_objects"`
+	SysBytes          uint64    `json:"sys_bytes"`
+	NumGC             uint32    `json:"num_gc"`
+	GcPauseTotalMs    float64   `json:"gc_pause_total_ms"`
+	GcPausesRecentMs  []float64 `json:"gc_pauses_recent_ms"` // most recent first
+	LastGC            time.Time `json:"last_gc" swaggertype:"string" format:"date" example:"2006-01-02T15:04:05Z07:00"`
+	PendingHeartbeats int64     `json:"pending_heartbeats"` // heartbeats received, but not yet written to the database
+}
+
+func NewRuntimeStats(pendingHeartbeats int64) *RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := &RuntimeStats{
+		Goroutines:        runtime.NumGoroutine(),
+		HeapAllocBytes:    mem.HeapAlloc,
+		HeapSysBytes:      mem.HeapSys,
+		HeapObjects:       mem.HeapObjects,
+		SysBytes:          mem.Sys,
+		NumGC:             mem.NumGC,
+		GcPauseTotalMs:    durationMs(mem.PauseTotalNs),
+		GcPausesRecentMs:  make([]float64, 0, runtimeStatsRecentGcPauses),
+		PendingHeartbeats: pendingHeartbeats,
+	}
+
+	if mem.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(mem.LastGC))
+	}
+
+	// PauseNs is a circular buffer, whose most recent entry is at (NumGC+255)%256
+	for i := uint32(0); i < mem.NumGC && i < runtimeStatsRecentGcPauses; i++ {
+		stats.GcPausesRecentMs = append(stats.GcPausesRecentMs, durationMs(mem.PauseNs[(mem.NumGC-i+255)%256]))
+	}
+
+	return stats
+}
+
+func durationMs(ns uint64) float64 {
+	return float64(ns) / float64(time.Millisecond)
+}
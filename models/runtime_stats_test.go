@@ -0,0 +1,23 @@
+package models
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRuntimeStats(t *testing.T) {
+	runtime.GC()
+	runtime.GC()
+
+	sut := NewRuntimeStats(42)
+
+	assert.Equal(t, int64(42), sut.PendingHeartbeats)
+	assert.Positive(t, sut.Goroutines)
+	assert.Positive(t, sut.HeapAllocBytes)
+	assert.GreaterOrEqual(t, sut.NumGC, uint32(2))
+	assert.False(t, sut.LastGC.IsZero())
+	assert.NotEmpty(t, sut.GcPausesRecentMs)
+	assert.LessOrEqual(t, len(sut.GcPausesRecentMs), runtimeStatsRecentGcPauses)
+}
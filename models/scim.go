@@ -0,0 +1,171 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	ScimSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimSchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ScimSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// ScimUser is the representation of a user in the scim 2.0 protocol (rfc 7643), whereby the user name doubles as id
+type ScimUser struct {
+	Schemas    []string     `json:"schemas"`
+	Id         string       `json:"id,omitempty"`
+	ExternalId string       `json:"externalId,omitempty"`
+	UserName   string       `json:"userName"`
+	Active     *bool        `json:"active,omitempty"` // pointer to tell an omitted attribute from false
+	Emails     []*ScimEmail `json:"emails,omitempty"`
+	Password   string       `json:"password,omitempty"` // write-only
+	Meta       *ScimMeta    `json:"meta,omitempty"`
+}
+
+type ScimEmail struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+type ScimListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    []*ScimUser `json:"Resources"`
+}
+
+type ScimPatchRequest struct {
+	Schemas    []string              `json:"schemas"`
+	Operations []*ScimPatchOperation `json:"Operations"`
+}
+
+type ScimPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+type ScimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+func NewScimUser(user *User, baseUrl string) *ScimUser {
+	active := !user.Deactivated
+	scimUser := &ScimUser{
+		Schemas:    []string{ScimSchemaUser},
+		Id:         user.ID,
+		ExternalId: user.ExternalId,
+		UserName:   user.ID,
+		Active:     &active,
+		Meta: &ScimMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt.T().UTC().Format("2006-01-02T15:04:05Z"),
+			Location:     fmt.Sprintf("%s/Users/%s", baseUrl, user.ID),
+		},
+	}
+	if user.Email != "" {
+		scimUser.Emails = []*ScimEmail{{Value: user.Email, Primary: true}}
+	}
+	return scimUser
+}
+
+func NewScimError(status int, scimType, detail string) *ScimError {
+	return &ScimError{
+		Schemas:  []string{ScimSchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+// PrimaryEmail returns the primary e-mail address or the first one, if none is marked as primary
+func (u *ScimUser) PrimaryEmail() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// Apply updates the user by a patch operation, whereby only the active state, e-mail address and external id can be changed.
+// Identity providers differ in how they send patches (e.g. azure ad sends booleans as strings and capitalizes operations), which is accounted for.
+func (o *ScimPatchOperation) Apply(user *ScimUser) bool {
+	if op := strings.ToLower(o.Op); op != "replace" && op != "add" {
+		return false
+	}
+
+	// without path, value is a partial user
+	if o.Path == "" {
+		values, ok := o.Value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for path, value := range values {
+			if !(&ScimPatchOperation{Op: o.Op, Path: path, Value: value}).Apply(user) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch strings.ToLower(o.Path) {
+	case "active":
+		active, ok := parseScimBool(o.Value)
+		if !ok {
+			return false
+		}
+		user.Active = &active
+	case "externalid":
+		externalId, ok := o.Value.(string)
+		if !ok {
+			return false
+		}
+		user.ExternalId = externalId
+	case "emails", `emails[type eq "work"].value`, `emails[primary eq true].value`:
+		if email, ok := o.Value.(string); ok {
+			user.Emails = []*ScimEmail{{Value: email, Primary: true}}
+			return true
+		}
+		emails, ok := o.Value.([]interface{})
+		if !ok || len(emails) == 0 {
+			return false
+		}
+		email, ok := emails[0].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		value, _ := email["value"].(string)
+		user.Emails = []*ScimEmail{{Value: value, Primary: true}}
+	default:
+		return true // unsupported attributes, like names, are ignored
+	}
+	return true
+}
+
+func parseScimBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(strings.ToLower(v))
+		return b, err == nil
+	}
+	return false, false
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScimPatchOperation_Apply(t *testing.T) {
+	user := NewScimUser(&User{ID: "john", Email: "john@example.org"}, "")
+	assert.True(t, *user.Active)
+
+	// azure ad style
+	assert.True(t, (&ScimPatchOperation{Op: "Replace", Path: "active", Value: "False"}).Apply(user))
+	assert.False(t, *user.Active)
+
+	// okta style
+	assert.True(t, (&ScimPatchOperation{Op: "replace", Value: map[string]interface{}{"active": true, "externalId": "00u1"}}).Apply(user))
+	assert.True(t, *user.Active)
+	assert.Equal(t, "00u1", user.ExternalId)
+
+	assert.True(t, (&ScimPatchOperation{Op: "replace", Path: `emails[type eq "work"].value`, Value: "jd@example.org"}).Apply(user))
+	assert.Equal(t, "jd@example.org", user.PrimaryEmail())
+
+	assert.True(t, (&ScimPatchOperation{Op: "replace", Path: "name.givenName", Value: "John"}).Apply(user)) // ignored
+	assert.False(t, (&ScimPatchOperation{Op: "remove", Path: "active"}).Apply(user))
+	assert.False(t, (&ScimPatchOperation{Op: "replace", Path: "active", Value: "maybe"}).Apply(user))
+}
+
+func TestScimUser_PrimaryEmail(t *testing.T) {
+	assert.Equal(t, "", (&ScimUser{}).PrimaryEmail())
+	assert.Equal(t, "a@example.org", (&ScimUser{Emails: []*ScimEmail{{Value: "a@example.org"}, {Value: "b@example.org"}}}).PrimaryEmail())
+	assert.Equal(t, "b@example.org", (&ScimUser{Emails: []*ScimEmail{{Value: "a@example.org"}, {Value: "b@example.org", Primary: true}}}).PrimaryEmail())
+}
+
+func TestNewScimUser(t *testing.T) {
+	user := NewScimUser(&User{ID: "john", Deactivated: true, ExternalId: "00u1"}, "https://wakapi.example.org/api/scim/v2")
+	assert.Equal(t, "john", user.Id)
+	assert.Equal(t, "john", user.UserName)
+	assert.False(t, *user.Active)
+	assert.Empty(t, user.Emails)
+	assert.Equal(t, "https://wakapi.example.org/api/scim/v2/Users/john", user.Meta.Location)
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Session is a block of contiguous coding with no break longer than a given idle threshold, regardless of the projects worked on
+type Session struct {
+	Start         time.Time       `json:"start"`
+	End           time.Time       `json:"end"`
+	CodingSeconds float64         `json:"coding_seconds"` // time actually spent coding, i.e. excluding breaks
+	Projects      []string        `json:"projects"`       // sorted by time spent, descending
+	Breaks        []*SessionBreak `json:"breaks"`         // pauses too short to end the session
+}
+
+type SessionBreak struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// SessionList is the list of a user's sessions within a range, along with totals
+type SessionList struct {
+	From         time.Time  `json:"from"`
+	To           time.Time  `json:"to"`
+	IdleMinutes  int        `json:"idle_minutes"` // breaks longer than this end a session
+	Sessions     []*Session `json:"sessions"`
+	TotalSeconds float64    `json:"total_seconds"` // coding time of all sessions
+}
+
+func NewSessionList(from, to time.Time, idle time.Duration, sessions []*Session) *SessionList {
+	list := &SessionList{
+		From:        from,
+		To:          to,
+		IdleMinutes: int(idle / time.Minute),
+		Sessions:    sessions,
+	}
+	for _, s := range sessions {
+		list.TotalSeconds += s.CodingSeconds
+	}
+	return list
+}
+
+func (s *Session) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SettingsDocumentVersion is increased whenever the document's format changes incompatibly
+const SettingsDocumentVersion = 1
+
+const (
+	SettingsImportMerge   = "merge"   // keep existing settings and add missing ones
+	SettingsImportReplace = "replace" // remove all existing settings first
+)
+
+// SettingsDocument is a portable snapshot of a user's configuration, e.g. to keep it under version control or replicate it across instances.
+// It contains no ids and no user-specific references, so it can be imported for any user.
+type SettingsDocument struct {
+	Version          int                     `json:"version" yaml:"version"`
+	Aliases          []*SettingsAlias        `json:"aliases" yaml:"aliases"`
+	LanguageMappings map[string]string       `json:"language_mappings" yaml:"language_mappings"` // file extension -> language
+	Labels           []*SettingsProjectLabel `json:"labels" yaml:"labels"`
+	RelayRules       []*SettingsRelayRule    `json:"relay_rules" yaml:"relay_rules"`
+	Goals            []*SettingsGoal         `json:"goals" yaml:"goals"`
+}
+
+type SettingsAlias struct {
+	Type  uint8  `json:"type" yaml:"type"` // summary type, e.g. 0 for projects
+	Key   string `json:"key" yaml:"key"`
+	Value string `json:"value" yaml:"value"`
+}
+
+type SettingsProjectLabel struct {
+	Project string `json:"project" yaml:"project"`
+	Label   string `json:"label" yaml:"label"`
+}
+
+type SettingsRelayRule struct {
+	Type  string `json:"type" yaml:"type"`
+	Value string `json:"value" yaml:"value"`
+}
+
+type SettingsGoal struct {
+	Title    string `json:"title,omitempty" yaml:"title,omitempty"`
+	Interval string `json:"interval" yaml:"interval"`
+	Minutes  int    `json:"minutes" yaml:"minutes"`
+	Language string `json:"language,omitempty" yaml:"language,omitempty"`
+	Editor   string `json:"editor,omitempty" yaml:"editor,omitempty"`
+}
+
+// SettingsImportReport tells how many entries were created, skipped as already present or removed by an import
+type SettingsImportReport struct {
+	Created int `json:"created"`
+	Skipped int `json:"skipped"`
+	Deleted int `json:"deleted"`
+}
+
+func NewSettingsDocument(aliases []*Alias, mappings []*LanguageMapping, labels []*ProjectLabel, rules []*RelayRule, goals []*Goal) *SettingsDocument {
+	doc := &SettingsDocument{
+		Version:          SettingsDocumentVersion,
+		Aliases:          make([]*SettingsAlias, 0, len(aliases)),
+		LanguageMappings: make(map[string]string, len(mappings)),
+		Labels:           make([]*SettingsProjectLabel, 0, len(labels)),
+		RelayRules:       make([]*SettingsRelayRule, 0, len(rules)),
+		Goals:            make([]*SettingsGoal, 0, len(goals)),
+	}
+	for _, a := range aliases {
+		doc.Aliases = append(doc.Aliases, &SettingsAlias{Type: a.Type, Key: a.Key, Value: a.Value})
+	}
+	for _, m := range mappings {
+		doc.LanguageMappings[m.Extension] = m.Language
+	}
+	for _, l := range labels {
+		doc.Labels = append(doc.Labels, &SettingsProjectLabel{Project: l.ProjectKey, Label: l.Label})
+	}
+	for _, r := range rules {
+		doc.RelayRules = append(doc.RelayRules, &SettingsRelayRule{Type: r.Type, Value: r.Value})
+	}
+	for _, g := range goals {
+		doc.Goals = append(doc.Goals, &SettingsGoal{Title: g.Title, Interval: g.Interval, Minutes: g.Minutes, Language: g.Language, Editor: g.Editor})
+	}
+	return doc
+}
+
+// Validate checks the document as a whole before anything gets imported, so that an invalid entry does not leave a half-applied configuration behind
+func (d *SettingsDocument) Validate() error {
+	if d.Version < 1 || d.Version > SettingsDocumentVersion {
+		return errors.New(fmt.Sprintf("unsupported document version %d", d.Version))
+	}
+	for i, a := range d.ToAliases("") {
+		if !a.IsValid() {
+			return errors.New(fmt.Sprintf("invalid alias at position %d", i+1))
+		}
+	}
+	for _, m := range d.ToLanguageMappings("") {
+		if !m.IsValid() {
+			return errors.New(fmt.Sprintf("invalid language mapping for extension '%s'", m.Extension))
+		}
+	}
+	for i, l := range d.ToProjectLabels("") {
+		if !l.IsValid() {
+			return errors.New(fmt.Sprintf("invalid label at position %d", i+1))
+		}
+	}
+	for i, r := range d.ToRelayRules("") {
+		if !r.IsValid() {
+			return errors.New(fmt.Sprintf("invalid relay rule at position %d", i+1))
+		}
+	}
+	for i, g := range d.ToGoals("") {
+		if !g.IsValid() {
+			return errors.New(fmt.Sprintf("invalid goal at position %d", i+1))
+		}
+	}
+	return nil
+}
+
+func (d *SettingsDocument) ToAliases(userId string) []*Alias {
+	aliases := make([]*Alias, 0, len(d.Aliases))
+	for _, a := range d.Aliases {
+		aliases = append(aliases, &Alias{UserID: userId, Type: a.Type, Key: a.Key, Value: a.Value})
+	}
+	return aliases
+}
+
+func (d *SettingsDocument) ToLanguageMappings(userId string) []*LanguageMapping {
+	mappings := make([]*LanguageMapping, 0, len(d.LanguageMappings))
+	for ext, lang := range d.LanguageMappings {
+		mappings = append(mappings, &LanguageMapping{UserID: userId, Extension: ext, Language: lang})
+	}
+	return mappings
+}
+
+func (d *SettingsDocument) ToProjectLabels(userId string) []*ProjectLabel {
+	labels := make([]*ProjectLabel, 0, len(d.Labels))
+	for _, l := range d.Labels {
+		labels = append(labels, &ProjectLabel{UserID: userId, ProjectKey: l.Project, Label: l.Label})
+	}
+	return labels
+}
+
+func (d *SettingsDocument) ToRelayRules(userId string) []*RelayRule {
+	rules := make([]*RelayRule, 0, len(d.RelayRules))
+	for _, r := range d.RelayRules {
+		rules = append(rules, &RelayRule{UserID: userId, Type: r.Type, Value: r.Value})
+	}
+	return rules
+}
+
+func (d *SettingsDocument) ToGoals(userId string) []*Goal {
+	goals := make([]*Goal, 0, len(d.Goals))
+	for _, g := range d.Goals {
+		goals = append(goals, &Goal{UserID: userId, Title: g.Title, Interval: g.Interval, Minutes: g.Minutes, Language: g.Language, Editor: g.Editor})
+	}
+	return goals
+}
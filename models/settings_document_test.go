@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSettingsDocument(t *testing.T) {
+	doc := NewSettingsDocument(
+		[]*Alias{{ID: 1, UserID: "user1", Type: SummaryProject, Key: "wakapi", Value: "wakapi-mobile"}},
+		[]*LanguageMapping{{ID: 2, UserID: "user1", Extension: "tpl", Language: "HTML"}},
+		[]*ProjectLabel{{ID: 3, UserID: "user1", ProjectKey: "wakapi", Label: "oss"}},
+		[]*RelayRule{{ID: 4, UserID: "user1", Type: RelayRuleLabel, Value: "private"}},
+		[]*Goal{{ID: 5, UserID: "user1", Interval: GoalIntervalWeek, Minutes: 180, Language: "Rust"}},
+	)
+
+	assert.Equal(t, SettingsDocumentVersion, doc.Version)
+	assert.Nil(t, doc.Validate())
+
+	aliases := doc.ToAliases("user2")
+	assert.Len(t, aliases, 1)
+	assert.Equal(t, "user2", aliases[0].UserID)
+	assert.Zero(t, aliases[0].ID)
+	assert.Equal(t, "wakapi-mobile", aliases[0].Value)
+
+	assert.Equal(t, map[string]string{"tpl": "HTML"}, doc.LanguageMappings)
+	assert.Equal(t, "oss", doc.ToProjectLabels("user2")[0].Label)
+	assert.Equal(t, "private", doc.ToRelayRules("user2")[0].Value)
+	assert.Equal(t, "Rust", doc.ToGoals("user2")[0].Language)
+}
+
+func TestSettingsDocument_Validate(t *testing.T) {
+	assert.Nil(t, (&SettingsDocument{Version: 1}).Validate())
+	assert.NotNil(t, (&SettingsDocument{}).Validate())
+	assert.NotNil(t, (&SettingsDocument{Version: SettingsDocumentVersion + 1}).Validate())
+	assert.NotNil(t, (&SettingsDocument{Version: 1, Aliases: []*SettingsAlias{{Type: SummaryProject, Key: "wakapi"}}}).Validate())
+	assert.NotNil(t, (&SettingsDocument{Version: 1, LanguageMappings: map[string]string{"tpl": ""}}).Validate())
+	assert.NotNil(t, (&SettingsDocument{Version: 1, Labels: []*SettingsProjectLabel{{Project: "wakapi", Label: "oss/"}}}).Validate())
+	assert.NotNil(t, (&SettingsDocument{Version: 1, RelayRules: []*SettingsRelayRule{{Type: "foo", Value: "bar"}}}).Validate())
+	assert.NotNil(t, (&SettingsDocument{Version: 1, Goals: []*SettingsGoal{{Interval: GoalIntervalDay, Minutes: 0}}}).Validate())
+}
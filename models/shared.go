@@ -40,7 +40,13 @@ func (j *CustomTime) UnmarshalJSON(b []byte) error {
 	s := strings.Trim(string(b), "\"")
 	ts, err := strconv.ParseFloat(s, 64)
 	if err != nil {
-		return err
+		// timestamps are marshalled as rfc 3339 strings, e.g. in exports, which need to be read back when importing
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		*j = CustomTime(t)
+		return nil
 	}
 	t := time.Unix(0, int64(ts*1e9)) // ms to ns
 	*j = CustomTime(t)
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// StreakCounter keeps track of streaks, i.e. series of consecutive days with coding activity.
+// Days off in between don't interrupt a streak, but don't extend it either.
+type StreakCounter struct {
+	DaysOff   DaysOff
+	Current   int
+	Longest   int
+	lastDay   time.Time
+	lastStart time.Time
+	// start and end of the longest streak
+	LongestStart time.Time
+	LongestEnd   time.Time
+}
+
+func NewStreakCounter(daysOff DaysOff) *StreakCounter {
+	return &StreakCounter{DaysOff: daysOff}
+}
+
+// Add counts a day with activity, given as the start of the day in the user's time zone. Days have to be added in ascending order.
+func (s *StreakCounter) Add(day time.Time) {
+	if s.Current > 0 && s.continues(day) {
+		s.Current++
+	} else {
+		s.Current = 1
+		s.lastStart = day
+	}
+	s.lastDay = day
+
+	if s.Current > s.Longest {
+		s.Longest = s.Current
+		s.LongestStart, s.LongestEnd = s.lastStart, day
+	}
+}
+
+// continues tells whether every day between the last active one and the given one was a day off
+func (s *StreakCounter) continues(day time.Time) bool {
+	for d := s.lastDay.AddDate(0, 0, 1); d.Before(day); d = d.AddDate(0, 0, 1) {
+		if !s.DaysOff.IsOff(d) {
+			return false
+		}
+	}
+	return s.lastDay.Before(day)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStreakCounter_Add(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2022, 10, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	sut := NewStreakCounter(NewDaysOff([]*DayOff{
+		{Day: "2022-10-06", Kind: DayOffSick},
+		{Day: "2022-10-07", Kind: DayOffSick},
+	}))
+
+	sut.Add(day(1))
+	sut.Add(day(2))
+	sut.Add(day(4)) // gap
+	sut.Add(day(5))
+	sut.Add(day(8)) // days off in between
+	sut.Add(day(9))
+
+	assert.Equal(t, 4, sut.Current)
+	assert.Equal(t, 4, sut.Longest)
+	assert.Equal(t, day(4), sut.LongestStart)
+	assert.Equal(t, day(9), sut.LongestEnd)
+
+	sut.Add(day(11))
+
+	assert.Equal(t, 1, sut.Current)
+	assert.Equal(t, 4, sut.Longest)
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"math"
 	"sort"
 	"time"
 )
@@ -15,9 +16,13 @@ const (
 	SummaryMachine  uint8 = 4
 	SummaryLabel    uint8 = 5
 	SummaryBranch   uint8 = 6
+	SummaryTicket   uint8 = 7
+	SummaryOrigin   uint8 = 8
+	SummaryDomain   uint8 = 9
 )
 
 const UnknownSummaryKey = "unknown"
+const OtherSummaryKey = "Other"
 const DefaultProjectLabel = "default"
 
 type Summary struct {
@@ -31,8 +36,12 @@ type Summary struct {
 	Editors          SummaryItems `json:"editors" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	OperatingSystems SummaryItems `json:"operating_systems" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	Machines         SummaryItems `json:"machines" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Labels           SummaryItems `json:"labels" gorm:"-"`   // labels are not persisted, but calculated at runtime, i.e. when summary is retrieved
-	Branches         SummaryItems `json:"branches" gorm:"-"` // branches are not persisted, but calculated at runtime in case a project filter is applied
+	Tickets          SummaryItems `json:"tickets" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Origins          SummaryItems `json:"origins" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Domains          SummaryItems `json:"domains" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Labels           SummaryItems `json:"labels" gorm:"-"`          // labels are not persisted, but calculated at runtime, i.e. when summary is retrieved
+	Branches         SummaryItems `json:"branches" gorm:"-"`        // branches are not persisted, but calculated at runtime in case a project filter is applied
+	ManualProjects   SummaryItems `json:"manual_projects" gorm:"-"` // share of manually added time per project, already included in the other totals
 	NumHeartbeats    int          `json:"-" gorm:"default:0"`
 }
 
@@ -45,6 +54,8 @@ type SummaryItem struct {
 	Type      uint8         `json:"-" gorm:"index:idx_type"`
 	Key       string        `json:"key"`
 	Total     time.Duration `json:"total" swaggertype:"primitive,integer"`
+	Write     time.Duration `json:"write" swaggertype:"primitive,integer"` // share of total spent writing, also in seconds
+	Lines     int           `json:"lines"`                                 // approximate number of lines changed
 }
 
 type SummaryItemContainer struct {
@@ -61,15 +72,15 @@ type SummaryParams struct {
 }
 
 func SummaryTypes() []uint8 {
-	return []uint8{SummaryProject, SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine, SummaryLabel, SummaryBranch}
+	return []uint8{SummaryProject, SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine, SummaryLabel, SummaryBranch, SummaryTicket, SummaryOrigin}
 }
 
 func NativeSummaryTypes() []uint8 {
-	return []uint8{SummaryProject, SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine, SummaryBranch}
+	return []uint8{SummaryProject, SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine, SummaryBranch, SummaryTicket, SummaryOrigin}
 }
 
 func PersistedSummaryTypes() []uint8 {
-	return []uint8{SummaryProject, SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine}
+	return []uint8{SummaryProject, SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine, SummaryTicket, SummaryOrigin}
 }
 
 func (s *Summary) Sorted() *Summary {
@@ -80,6 +91,10 @@ func (s *Summary) Sorted() *Summary {
 	sort.Sort(sort.Reverse(s.Editors))
 	sort.Sort(sort.Reverse(s.Labels))
 	sort.Sort(sort.Reverse(s.Branches))
+	sort.Sort(sort.Reverse(s.Tickets))
+	sort.Sort(sort.Reverse(s.Origins))
+	sort.Sort(sort.Reverse(s.Domains))
+	sort.Sort(sort.Reverse(s.ManualProjects))
 	return s
 }
 
@@ -96,6 +111,9 @@ func (s *Summary) MappedItems() map[uint8]*SummaryItems {
 		SummaryMachine:  &s.Machines,
 		SummaryLabel:    &s.Labels,
 		SummaryBranch:   &s.Branches,
+		SummaryTicket:   &s.Tickets,
+		SummaryOrigin:   &s.Origins,
+		SummaryDomain:   &s.Domains,
 	}
 }
 
@@ -269,6 +287,8 @@ func (s *Summary) WithResolvedAliases(resolve AliasResolver) *Summary {
 			if key := resolve(item.Type, item.Key); key != item.Key {
 				if targetItem := findItem(key); targetItem != nil {
 					targetItem.Total += item.Total
+					targetItem.Write += item.Write
+					targetItem.Lines += item.Lines
 				} else {
 					target = append(target, &SummaryItem{
 						ID:        item.ID,
@@ -276,6 +296,8 @@ func (s *Summary) WithResolvedAliases(resolve AliasResolver) *Summary {
 						Type:      item.Type,
 						Key:       key,
 						Total:     item.Total,
+						Write:     item.Write,
+						Lines:     item.Lines,
 					})
 				}
 			}
@@ -292,10 +314,153 @@ func (s *Summary) WithResolvedAliases(resolve AliasResolver) *Summary {
 	s.Machines = processAliases(s.Machines)
 	s.Labels = processAliases(s.Labels)
 	s.Branches = processAliases(s.Branches)
+	s.Tickets = processAliases(s.Tickets)
+	s.Origins = processAliases(s.Origins)
 
 	return s
 }
 
+// WithManualEntries merges the given manual time entries into the summary. Their durations are added to their respective
+// project as well as the "manual" origin and counted as "unknown" for every other native type, since no such information is available for manual entries.
+// Additionally, manual time per project is kept track of separately, so it can be told apart from tracked time.
+// Types without any items are filled up with tracked time first, so that totals are consistent across all types afterwards.
+func (s *Summary) WithManualEntries(entries []*ManualTimeEntry, resolve AliasResolver) *Summary {
+	if len(entries) == 0 {
+		return s
+	}
+
+	unknownTypes := []uint8{SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine, SummaryTicket}
+	if presentType, err := s.findFirstPresentType(); err == nil {
+		for _, t := range append(unknownTypes, SummaryOrigin) {
+			if len(*s.ItemsByType(t)) == 0 {
+				s.FillBy(presentType, t)
+			}
+		}
+	}
+
+	addTo := func(items *SummaryItems, t uint8, key string, total time.Duration) {
+		for _, item := range *items {
+			if item.Key == key {
+				item.Total += total
+				return
+			}
+		}
+		*items = append(*items, &SummaryItem{Type: t, Key: key, Total: total})
+	}
+
+	for _, e := range entries {
+		total := e.Duration / time.Second // workaround
+		project := resolve(SummaryProject, e.Project)
+		addTo(&s.Projects, SummaryProject, project, total)
+		addTo(&s.ManualProjects, SummaryProject, project, total)
+		addTo(&s.Origins, SummaryOrigin, OriginManual, total)
+		for _, t := range unknownTypes {
+			addTo(s.ItemsByType(t), t, UnknownSummaryKey, total)
+		}
+	}
+
+	return s
+}
+
+// WithItemLimit keeps only the given number of items with the most time per type and rolls up all remaining ones into a single "Other" item,
+// to keep summaries of users with hundreds of projects small. Totals are preserved. A limit of zero or less keeps all items.
+func (s *Summary) WithItemLimit(limit int) *Summary {
+	if limit <= 0 {
+		return s
+	}
+
+	truncate := func(items SummaryItems) SummaryItems {
+		if len(items) <= limit {
+			return items
+		}
+		sort.Sort(sort.Reverse(items))
+
+		other := &SummaryItem{Type: items[0].Type, Key: OtherSummaryKey}
+		target := make(SummaryItems, 0, limit+1)
+		for i, item := range items {
+			if i < limit && item.Key != OtherSummaryKey {
+				target = append(target, item)
+				continue
+			}
+			other.Total += item.Total
+			other.Write += item.Write
+			other.Lines += item.Lines
+		}
+		return append(target, other)
+	}
+
+	for _, items := range s.MappedItems() {
+		*items = truncate(*items)
+	}
+	s.ManualProjects = truncate(s.ManualProjects)
+
+	return s
+}
+
+// Combined returns a new summary, in which the items of both summaries are added up per type and key, e.g. to include time tracked on another instance.
+// Neither of the two summaries is modified.
+func (s *Summary) Combined(other *Summary) *Summary {
+	combine := func(a, b SummaryItems) SummaryItems {
+		result := make(SummaryItems, 0, len(a)+len(b))
+		byKey := make(map[string]*SummaryItem)
+		for _, items := range []SummaryItems{a, b} {
+			for _, item := range items {
+				if existing, ok := byKey[item.Key]; ok {
+					existing.Total += item.Total
+					existing.Write += item.Write
+					existing.Lines += item.Lines
+					continue
+				}
+				itemCopy := &SummaryItem{Type: item.Type, Key: item.Key, Total: item.Total, Write: item.Write, Lines: item.Lines}
+				byKey[item.Key] = itemCopy
+				result = append(result, itemCopy)
+			}
+		}
+		return result
+	}
+
+	combined := &Summary{
+		UserID:        s.UserID,
+		FromTime:      s.FromTime,
+		ToTime:        s.ToTime,
+		NumHeartbeats: s.NumHeartbeats + other.NumHeartbeats,
+	}
+	for t, items := range combined.MappedItems() {
+		*items = combine(*s.ItemsByType(t), *other.ItemsByType(t))
+	}
+	combined.ManualProjects = combine(s.ManualProjects, other.ManualProjects)
+
+	return combined.Sorted()
+}
+
+// TotalBrowsingTime returns the time spent browsing, which, depending on the user's preferences, may or may not be included in the total time
+func (s *Summary) TotalBrowsingTime() time.Duration {
+	return s.TotalTimeBy(SummaryDomain)
+}
+
+// TotalWriteTime returns the time spent writing, as opposed to reading, i.e. such preceding write heartbeats
+func (s *Summary) TotalWriteTime() (timeSum time.Duration) {
+	for _, item := range s.Projects {
+		timeSum += item.WriteFixed()
+	}
+	return timeSum
+}
+
+// TotalLinesChanged returns the approximate number of lines changed, as derived from the line counts reported by heartbeats
+func (s *Summary) TotalLinesChanged() (sum int) {
+	for _, item := range s.Projects {
+		sum += item.Lines
+	}
+	return sum
+}
+
+func (s *Summary) TotalManualTime() (timeSum time.Duration) {
+	for _, item := range s.ManualProjects {
+		timeSum += item.TotalFixed()
+	}
+	return timeSum
+}
+
 func (s *Summary) findFirstPresentType() (uint8, error) {
 	for _, t := range s.Types() {
 		if s.TotalTimeBy(t) != 0 {
@@ -331,6 +496,26 @@ func (s *SummaryItem) TotalFixed() time.Duration {
 	return s.Total * time.Second
 }
 
+func (s *SummaryItem) WriteFixed() time.Duration {
+	// same workaround as for total time
+	return s.Write * time.Second
+}
+
+// WritePercentage returns the share of time spent writing (as opposed to reading) in percent
+func (s *SummaryItem) WritePercentage() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return math.Round(float64(s.Write)/float64(s.Total)*1e4) / 100
+}
+
+func (s *SummaryItem) ReadPercentage() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return math.Round((100-s.WritePercentage())*100) / 100
+}
+
 func (s SummaryItems) Len() int {
 	return len(s)
 }
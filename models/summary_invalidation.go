@@ -0,0 +1,11 @@
+package models
+
+// SummaryInvalidation marks a past day, for which a user's heartbeats have changed after (or while) its summary was generated,
+// so that the summary needs to be recomputed during the next aggregation run
+type SummaryInvalidation struct {
+	ID       uint       `gorm:"primary_key"`
+	User     *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID   string     `gorm:"not null; uniqueIndex:idx_summary_invalidation_user_day"`
+	Day      CustomTime `gorm:"not null; type:timestamp; uniqueIndex:idx_summary_invalidation_user_day" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	MarkedAt CustomTime `gorm:"not null; type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"` // time of the latest change, renewed when marked again
+}
@@ -27,6 +27,7 @@ func TestSummary_FillMissing(t *testing.T) {
 		sut.OperatingSystems,
 		sut.Languages,
 		sut.Editors,
+		sut.Tickets,
 	}
 	for _, l := range itemLists {
 		assert.Len(t, l, 1)
@@ -197,3 +198,84 @@ func TestSummaryItems_Sorted(t *testing.T) {
 	assert.Equal(t, testDuration1, sut.Projects[1].Total)
 	assert.Equal(t, testDuration2, sut.Projects[2].Total)
 }
+
+func TestSummary_WithManualEntries(t *testing.T) {
+	testDuration1, testDuration2 := 10*time.Minute, 30*time.Minute
+
+	sut := &Summary{
+		Projects: []*SummaryItem{
+			{
+				Type:  SummaryProject,
+				Key:   "wakapi",
+				Total: testDuration1 / time.Second,
+			},
+		},
+		Languages: []*SummaryItem{
+			{
+				Type:  SummaryLanguage,
+				Key:   "Go",
+				Total: testDuration1 / time.Second,
+			},
+		},
+	}
+
+	sut.WithManualEntries([]*ManualTimeEntry{
+		{Project: "meetings", Duration: testDuration2},
+	}, func(_ uint8, k string) string { return k })
+
+	for _, st := range []uint8{SummaryProject, SummaryLanguage, SummaryEditor, SummaryOS, SummaryMachine, SummaryTicket, SummaryOrigin} {
+		assert.Equal(t, testDuration1+testDuration2, sut.TotalTimeBy(st))
+	}
+	assert.Equal(t, testDuration2, sut.TotalTimeByKey(SummaryLanguage, UnknownSummaryKey))
+	assert.Equal(t, testDuration2, sut.TotalTimeByKey(SummaryOrigin, OriginManual))
+	assert.Equal(t, testDuration1+testDuration2, sut.TotalTimeByKey(SummaryMachine, UnknownSummaryKey))
+	assert.Equal(t, testDuration2, sut.TotalManualTime())
+}
+
+func TestSummary_WithItemLimit(t *testing.T) {
+	sut := &Summary{
+		Projects: []*SummaryItem{
+			{Type: SummaryProject, Key: "wakapi", Total: 30, Write: 10, Lines: 5},
+			{Type: SummaryProject, Key: "anchr", Total: 20},
+			{Type: SummaryProject, Key: "website", Total: 40},
+			{Type: SummaryProject, Key: "dotfiles", Total: 10, Write: 5, Lines: 2},
+		},
+		Languages: []*SummaryItem{
+			{Type: SummaryLanguage, Key: "Go", Total: 100},
+		},
+	}
+
+	sut.WithItemLimit(2)
+
+	assert.Len(t, sut.Projects, 3)
+	assert.Equal(t, "website", sut.Projects[0].Key)
+	assert.Equal(t, "wakapi", sut.Projects[1].Key)
+	assert.Equal(t, OtherSummaryKey, sut.Projects[2].Key)
+	assert.Equal(t, SummaryProject, sut.Projects[2].Type)
+	assert.Equal(t, time.Duration(30), sut.Projects[2].Total)
+	assert.Equal(t, time.Duration(5), sut.Projects[2].Write)
+	assert.Equal(t, 2, sut.Projects[2].Lines)
+	assert.Equal(t, 100*time.Second, sut.TotalTimeBy(SummaryProject))
+	assert.Len(t, sut.Languages, 1)
+}
+
+func TestSummary_Combined(t *testing.T) {
+	local := &Summary{
+		UserID:    "johndoe",
+		Projects:  []*SummaryItem{{Type: SummaryProject, Key: "wakapi", Total: 60}},
+		Languages: []*SummaryItem{{Type: SummaryLanguage, Key: "Go", Total: 60}},
+	}
+	remote := &Summary{
+		Projects:  []*SummaryItem{{Type: SummaryProject, Key: "work", Total: 120}, {Type: SummaryProject, Key: "wakapi", Total: 30}},
+		Languages: []*SummaryItem{{Type: SummaryLanguage, Key: "Java", Total: 150}},
+	}
+
+	sut := local.Combined(remote)
+
+	assert.Equal(t, "johndoe", sut.UserID)
+	assert.Len(t, sut.Projects, 2)
+	assert.Equal(t, "work", sut.Projects[0].Key)
+	assert.Equal(t, time.Duration(90), sut.Projects[1].Total)
+	assert.Equal(t, 210*time.Second, sut.TotalTimeBy(SummaryLanguage))
+	assert.Equal(t, time.Duration(60), local.Projects[0].Total) // unchanged
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// issue keys as used by jira and similar trackers, i.e. an upper case project key, followed by a number, e.g. 'PROJ-123'
+var ticketRegex = regexp.MustCompile(`(?:^|[^A-Za-z0-9])([A-Z][A-Z0-9_]+-[1-9][0-9]*)(?:$|[^0-9])`)
+
+// TicketFromBranch returns the first issue key contained in the given branch name (e.g. 'feature/PROJ-123-login') or an empty string, if none
+func TicketFromBranch(branch string) string {
+	if match := ticketRegex.FindStringSubmatch(branch); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// WorklogEntry is the time spent on a ticket on a single day
+type WorklogEntry struct {
+	Date             time.Time `json:"date" swaggertype:"string" format:"date" example:"2006-01-02"`
+	Ticket           string    `json:"ticket"`
+	TimeSpentSeconds int64     `json:"time_spent_seconds"`
+}
+
+// TimeSpent returns the entry's duration in jira notation, e.g. '1h 30m', rounded to full minutes
+func (e *WorklogEntry) TimeSpent() string {
+	return fmtJiraDuration(e.TimeSpentSeconds)
+}
+
+func fmtJiraDuration(seconds int64) string {
+	d := (time.Duration(seconds) * time.Second).Round(time.Minute)
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	if h > 0 && m > 0 {
+		return fmt.Sprintf("%dh %dm", h, m)
+	} else if h > 0 {
+		return fmt.Sprintf("%dh", h)
+	}
+	return fmt.Sprintf("%dm", m)
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTicketFromBranch(t *testing.T) {
+	tests := map[string]string{
+		"PROJ-123":                   "PROJ-123",
+		"feature/PROJ-123-login":     "PROJ-123",
+		"bugfix/AB2-7_fix-typo":      "AB2-7",
+		"PROJ-1/PROJ-2":              "PROJ-1",
+		"hotfix-PROJ-42":             "PROJ-42",
+		"feature/proj-123-login":     "",
+		"master":                     "",
+		"release-2022":               "",
+		"P-123":                      "",
+		"PROJ-0123":                  "",
+		"XPROJ-123":                  "XPROJ-123",
+		"v1.2.3-RC-1":                "RC-1",
+		"feature/utf8-PROJ-9-ümlaut": "PROJ-9",
+		"":                           "",
+	}
+
+	for in, out := range tests {
+		assert.Equal(t, out, TicketFromBranch(in), in)
+	}
+}
+
+func TestWorklogEntry_TimeSpent(t *testing.T) {
+	tests := map[int64]string{
+		0:    "0m",
+		29:   "0m",
+		30:   "1m",
+		1800: "30m",
+		3600: "1h",
+		5430: "1h 31m",
+	}
+
+	for in, out := range tests {
+		assert.Equal(t, out, (&WorklogEntry{TimeSpentSeconds: in}).TimeSpent())
+	}
+}
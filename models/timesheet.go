@@ -0,0 +1,61 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Timesheet is the time spent per project and day within a week
+type Timesheet struct {
+	From   time.Time       `json:"from"`
+	To     time.Time       `json:"to"`
+	Days   []time.Time     `json:"days" swaggertype:"array,string" example:"2006-01-02"`
+	Rows   []*TimesheetRow `json:"rows"`
+	Totals []float64       `json:"totals"` // hours per day, summed up over all projects
+	Total  float64         `json:"total"`  // hours
+}
+
+type TimesheetRow struct {
+	Project string    `json:"project"`
+	Hours   []float64 `json:"hours"` // one entry per day
+	Total   float64   `json:"total"`
+}
+
+// NewTimesheet creates a timesheet from the given durations per project and day, with each project's slice containing one entry per day
+func NewTimesheet(from, to time.Time, days []time.Time, durations map[string][]time.Duration) *Timesheet {
+	sheet := &Timesheet{
+		From:   from,
+		To:     to,
+		Days:   days,
+		Rows:   make([]*TimesheetRow, 0, len(durations)),
+		Totals: make([]float64, len(days)),
+	}
+
+	dayTotals := make([]time.Duration, len(days))
+	var total time.Duration
+
+	for project, projectDurations := range durations {
+		row := &TimesheetRow{Project: project, Hours: make([]float64, len(days))}
+		var rowTotal time.Duration
+		for i, d := range projectDurations {
+			row.Hours[i] = toHours(d)
+			rowTotal += d
+			dayTotals[i] += d
+		}
+		row.Total = toHours(rowTotal)
+		total += rowTotal
+		sheet.Rows = append(sheet.Rows, row)
+	}
+
+	for i, d := range dayTotals {
+		sheet.Totals[i] = toHours(d)
+	}
+	sheet.Total = toHours(total)
+
+	return sheet
+}
+
+// toHours converts the duration to hours, rounded to two decimal places
+func toHours(d time.Duration) float64 {
+	return math.Round(d.Hours()*100) / 100
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// TogglTimeEntry is a block of uninterrupted work on a project and branch, shaped like a Toggl Track time entry
+// (see https://developers.track.toggl.com/docs/api/time_entries), except for the project being referenced by name instead of id
+type TogglTimeEntry struct {
+	Description string    `json:"description"`
+	Project     string    `json:"project"`
+	Start       time.Time `json:"start"`
+	Stop        time.Time `json:"stop"`
+	Duration    int64     `json:"duration"` // seconds
+	CreatedWith string    `json:"created_with"`
+}
+
+// FmtDuration formats the entry's duration as hh:mm:ss, as expected by toggl's csv import
+func (e *TogglTimeEntry) FmtDuration() string {
+	return fmt.Sprintf("%02d:%02d:%02d", e.Duration/3600, e.Duration%3600/60, e.Duration%60)
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kinds of operations, which can be undone
+const (
+	TombstoneHeartbeatDeletion = "heartbeat_deletion"
+	TombstoneReassignment      = "reassignment"
+)
+
+// Tombstone records a destructive operation on a user's heartbeats, so that it can be undone until it expires.
+// The affected heartbeats' previous state is kept as separate items, as there might be many of them.
+type Tombstone struct {
+	ID            uint       `json:"id" gorm:"primary_key"`
+	User          *User      `json:"-" gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	UserID        string     `json:"-" gorm:"not null; index:idx_tombstone_user"`
+	Kind          string     `json:"kind" gorm:"not null; size:32"`
+	NumHeartbeats int        `json:"heartbeats"` // number of affected heartbeats
+	CreatedAt     CustomTime `json:"created_at" gorm:"type:timestamp" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	ExpiresAt     CustomTime `json:"expires_at" gorm:"not null; type:timestamp; index:idx_tombstone_expires" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+}
+
+// TombstoneItem is a copy of a single heartbeat as it was before the operation
+type TombstoneItem struct {
+	ID          uint64             `gorm:"primary_key"`
+	Tombstone   *Tombstone         `gorm:"not null; constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	TombstoneID uint               `gorm:"not null; index:idx_tombstone_item_tombstone"`
+	Heartbeat   TombstoneHeartbeat `gorm:"type:text"`
+}
+
+// TombstoneHeartbeat holds all of a heartbeat's persisted fields. The heartbeat itself is not serialized as is, because its json representation
+// hides some fields and its time does not survive a round trip.
+type TombstoneHeartbeat struct {
+	ID              uint64            `json:"id"`
+	UserID          string            `json:"user_id"`
+	Entity          string            `json:"entity"`
+	Type            string            `json:"type"`
+	Category        string            `json:"category"`
+	Project         string            `json:"project"`
+	Branch          string            `json:"branch"`
+	Language        string            `json:"language"`
+	IsWrite         bool              `json:"is_write"`
+	Lines           int               `json:"lines"`
+	CursorPos       int               `json:"cursorpos"`
+	Editor          string            `json:"editor"`
+	OperatingSystem string            `json:"operating_system"`
+	Machine         string            `json:"machine"`
+	UserAgent       string            `json:"user_agent"`
+	Metadata        HeartbeatMetadata `json:"metadata,omitempty"`
+	Time            time.Time         `json:"time"`
+	Hash            string            `json:"hash"`
+	Origin          string            `json:"origin,omitempty"`
+	OriginId        string            `json:"origin_id,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+}
+
+func NewTombstone(userId, kind string, window time.Duration) *Tombstone {
+	now := time.Now()
+	return &Tombstone{
+		UserID:    userId,
+		Kind:      kind,
+		CreatedAt: CustomTime(now),
+		ExpiresAt: CustomTime(now.Add(window)),
+	}
+}
+
+// NewTombstoneItem copies the given heartbeat, so it may be changed afterwards
+func NewTombstoneItem(tombstone *Tombstone, h *Heartbeat) *TombstoneItem {
+	return &TombstoneItem{
+		TombstoneID: tombstone.ID,
+		Heartbeat: TombstoneHeartbeat{
+			ID:              h.ID,
+			UserID:          h.UserID,
+			Entity:          h.Entity,
+			Type:            h.Type,
+			Category:        h.Category,
+			Project:         h.Project,
+			Branch:          h.Branch,
+			Language:        h.Language,
+			IsWrite:         h.IsWrite,
+			Lines:           h.Lines,
+			CursorPos:       h.CursorPos,
+			Editor:          h.Editor,
+			OperatingSystem: h.OperatingSystem,
+			Machine:         h.Machine,
+			UserAgent:       h.UserAgent,
+			Metadata:        h.Metadata,
+			Time:            h.Time.T(),
+			Hash:            h.Hash,
+			Origin:          h.Origin,
+			OriginId:        h.OriginId,
+			CreatedAt:       h.CreatedAt.T(),
+		},
+	}
+}
+
+func (t *Tombstone) IsExpired() bool {
+	return !t.ExpiresAt.T().After(time.Now())
+}
+
+// ToHeartbeat restores the heartbeat as it was before the operation
+func (i *TombstoneItem) ToHeartbeat() *Heartbeat {
+	h := i.Heartbeat
+	return &Heartbeat{
+		ID:              h.ID,
+		UserID:          h.UserID,
+		Entity:          h.Entity,
+		Type:            h.Type,
+		Category:        h.Category,
+		Project:         h.Project,
+		Branch:          h.Branch,
+		Language:        h.Language,
+		IsWrite:         h.IsWrite,
+		Lines:           h.Lines,
+		CursorPos:       h.CursorPos,
+		Editor:          h.Editor,
+		OperatingSystem: h.OperatingSystem,
+		Machine:         h.Machine,
+		UserAgent:       h.UserAgent,
+		Metadata:        h.Metadata,
+		Time:            CustomTime(h.Time),
+		Hash:            h.Hash,
+		Origin:          h.Origin,
+		OriginId:        h.OriginId,
+		CreatedAt:       CustomTime(h.CreatedAt),
+	}
+}
+
+func (h *TombstoneHeartbeat) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New(fmt.Sprintf("unsupported type: %T", value))
+	}
+	return json.Unmarshal(data, h)
+}
+
+func (h TombstoneHeartbeat) Value() (driver.Value, error) {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTombstoneItem_RoundTrip(t *testing.T) {
+	now := time.Now().Round(time.Millisecond)
+	tombstone := NewTombstone("johndoe@example.org", TombstoneHeartbeatDeletion, time.Hour)
+	tombstone.ID = 1
+
+	original := (&Heartbeat{
+		ID:       42,
+		UserID:   "johndoe@example.org",
+		Entity:   "/home/me/dev/wakapi/main.go",
+		Project:  "wakapi",
+		Language: "Go",
+		Metadata: HeartbeatMetadata{"ci_job": "1234"},
+		Time:     CustomTime(now),
+		Origin:   OriginImport,
+		OriginId: "abcd",
+	}).Hashed()
+
+	item := NewTombstoneItem(tombstone, original)
+	original.Project = "changed afterwards"
+
+	value, err := item.Heartbeat.Value()
+	assert.Nil(t, err)
+
+	var restored TombstoneItem
+	assert.Nil(t, restored.Heartbeat.Scan(value))
+
+	hb := restored.ToHeartbeat()
+	assert.Equal(t, uint64(42), hb.ID)
+	assert.Equal(t, "johndoe@example.org", hb.UserID)
+	assert.Equal(t, "wakapi", hb.Project)
+	assert.Equal(t, "Go", hb.Language)
+	assert.Equal(t, "1234", hb.Metadata["ci_job"])
+	assert.Equal(t, OriginImport, hb.Origin)
+	assert.Equal(t, "abcd", hb.OriginId)
+	assert.Equal(t, item.Heartbeat.Hash, hb.Hash)
+	assert.True(t, now.Equal(hb.Time.T()))
+}
+
+func TestTombstone_IsExpired(t *testing.T) {
+	assert.False(t, NewTombstone("johndoe@example.org", TombstoneReassignment, time.Hour).IsExpired())
+	assert.True(t, NewTombstone("johndoe@example.org", TombstoneReassignment, -time.Hour).IsExpired())
+}
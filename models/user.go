@@ -3,36 +3,78 @@ package models
 import (
 	"crypto/md5"
 	"fmt"
+	"github.com/muety/wakapi/i18n"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
 )
 
+// AvatarKeyPrefix is the storage folder of uploaded avatar images
+const AvatarKeyPrefix = "avatars/"
+
 func init() {
 	mailRegex = regexp.MustCompile(MailPattern)
 }
 
 type User struct {
-	ID               string     `json:"id" gorm:"primary_key"`
-	ApiKey           string     `json:"api_key" gorm:"unique"`
-	Email            string     `json:"email" gorm:"index:idx_user_email; size:255"`
-	Location         string     `json:"location"`
-	Password         string     `json:"-"`
-	CreatedAt        CustomTime `gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
-	LastLoggedInAt   CustomTime `gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
-	ShareDataMaxDays int        `json:"-" gorm:"default:0"`
-	ShareEditors     bool       `json:"-" gorm:"default:false; type:bool"`
-	ShareLanguages   bool       `json:"-" gorm:"default:false; type:bool"`
-	ShareProjects    bool       `json:"-" gorm:"default:false; type:bool"`
-	ShareOSs         bool       `json:"-" gorm:"default:false; type:bool; column:share_oss"`
-	ShareMachines    bool       `json:"-" gorm:"default:false; type:bool"`
-	ShareLabels      bool       `json:"-" gorm:"default:false; type:bool"`
-	IsAdmin          bool       `json:"-" gorm:"default:false; type:bool"`
-	HasData          bool       `json:"-" gorm:"default:false; type:bool"`
-	WakatimeApiKey   string     `json:"-"` // for relay middleware and imports
-	WakatimeApiUrl   string     `json:"-"` // for relay middleware and imports
-	ResetToken       string     `json:"-"`
-	ReportsWeekly    bool       `json:"-" gorm:"default:false; type:bool"`
+	ID             string     `json:"id" gorm:"primary_key"`
+	ApiKey         string     `json:"api_key" gorm:"unique"`
+	Email          string     `json:"email" gorm:"index:idx_user_email; size:255"`
+	Location       string     `json:"location"`
+	Password       string     `json:"-"`
+	CreatedAt      CustomTime `gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	LastLoggedInAt CustomTime `gorm:"type:timestamp; default:CURRENT_TIMESTAMP" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"`
+	// number of past days to publicly share the respective data for (0 = not at all, -1 = unlimited), e.g. via badges or the stats endpoint
+	ShareDataMaxDays   int    `json:"-" gorm:"default:0"` // total time, regardless of any dimension
+	ShareEditorsDays   int    `json:"-" gorm:"default:0"`
+	ShareLanguagesDays int    `json:"-" gorm:"default:0"`
+	ShareProjectsDays  int    `json:"-" gorm:"default:0"`
+	ShareOSsDays       int    `json:"-" gorm:"default:0; column:share_oss_days"`
+	ShareMachinesDays  int    `json:"-" gorm:"default:0"`
+	ShareLabelsDays    int    `json:"-" gorm:"default:0"`
+	IsAdmin            bool   `json:"-" gorm:"default:false; type:bool"`
+	HasData            bool   `json:"-" gorm:"default:false; type:bool"`
+	WakatimeApiKey     string `json:"-"` // for relay middleware and imports
+	WakatimeApiUrl     string `json:"-"` // for relay middleware and imports
+	ResetToken         string `json:"-"`
+	ReportsWeekly      bool   `json:"-" gorm:"default:false; type:bool"` // whether reports are to be scheduled, kept in sync with notification preferences
+	// heartbeat acceptance window, 0 means to fall back to the server-wide default
+	HeartbeatsMaxPastDays  int         `json:"-" gorm:"default:0"`
+	HeartbeatsMaxFutureMin int         `json:"-" gorm:"default:0"`
+	ImportScopeUntil       *CustomTime `json:"-" gorm:"type:timestamp"` // past-days limit is lifted until then to allow for intentional backfills
+	DurationStrategy       string      `json:"-"`                       // how to count parallel activity on multiple machines, see DurationStrategyDefault, DurationStrategyMerge and DurationStrategySum
+	HeartbeatScript        string      `json:"-" gorm:"type:text"`      // lua script to transform or reject incoming heartbeats, see HeartbeatScriptService
+	AvatarKey              string      `json:"-"`                       // storage key of an uploaded avatar image, if any
+	JiraUrl                string      `json:"-"`                       // jira cloud site to push worklogs to, e.g. https://example.atlassian.net
+	JiraEmail              string      `json:"-"`
+	JiraApiToken           string      `json:"-"`
+	GcalRefreshToken       string      `json:"-"`                                 // oauth refresh token for writing coding sessions to google calendar
+	GcalCalendarId         string      `json:"-"`                                 // calendar to write to, defaults to the user's primary calendar
+	GcalEnabled            bool        `json:"-" gorm:"default:false; type:bool"` // allows to pause the sync without disconnecting
+	GcalEventTitles        string      `json:"-"`                                 // level of detail in event titles, see CalendarTitlesGeneric and CalendarTitlesProjects
+	WorkdayTargetMin       int         `json:"-" gorm:"default:0"`                // minutes to work per workday, 0 means no target
+	Workdays               uint8       `json:"-" gorm:"default:62"`               // bitmask of time.Weekday, defaults to DefaultWorkdays
+	WorkTargetSince        string      `json:"-" gorm:"size:10"`                  // day to start the overtime balance at, e.g. '2022-10-24'
+	ExcludeBrowsing        bool        `json:"-" gorm:"default:false; type:bool"` // whether to leave out time tracked by the browser extension from totals and all types except domains
+	DashboardWidgets       string      `json:"-"`                                 // comma-separated, ordered list of widgets to show on the dashboard, all by default
+	DashboardRange         string      `json:"-" gorm:"size:32"`                  // interval to show on the dashboard by default, today if empty
+	Locale                 string      `json:"-" gorm:"size:8"`                   // preferred language of the ui and e-mails, see i18n, default locale if empty
+	NotificationWebhookUrl string      `json:"-"`                                 // generic webhook to post notifications to as json, see NotificationChannelWebhook
+	SlackWebhookUrl        string      `json:"-"`                                 // slack incoming webhook to post notifications to
+	TelegramChatId         string      `json:"-" gorm:"size:64"`                  // chat to send notifications to via the server's telegram bot
+	InactivityAlertDays    int         `json:"-" gorm:"default:0"`                // days without heartbeats (in total or from a single machine) to alert the user after, 0 means no alerts
+	Deactivated            bool        `json:"-" gorm:"default:false; type:bool"` // deactivated users can neither log in nor use the api, e.g. after being deprovisioned via scim
+	ExternalId             string      `json:"-" gorm:"size:255"`                 // id of the user at the identity provider, which provisioned it via scim
+	HeartbeatsQuota        int         `json:"-" gorm:"default:0"`                // heartbeats per hour, set by admins, 0 means to fall back to the server-wide default, -1 = unlimited
+	ClockSkewCorrection    bool        `json:"-" gorm:"default:false; type:bool"` // whether to shift timestamps of heartbeats from machines with misconfigured clocks, see ClockSkewService
+	AllowComparison        bool        `json:"-" gorm:"default:false; type:bool"` // whether other users may compare their summaries with this user's, see ComparisonService
+	PublicLeaderboard      bool        `json:"-" gorm:"default:false; type:bool"` // whether to appear on the leaderboard, see LeaderboardService
+	Quarantined            bool        `json:"-" gorm:"default:false; type:bool"` // whether the user has heartbeats pending review, see QuarantineService
+	StorageQuota           int64       `json:"-" gorm:"default:0"`                // number of heartbeats to store at most, set by admins, 0 means to fall back to the server-wide default, -1 = unlimited
+	StorageQuotaPolicy     string      `json:"-" gorm:"size:16"`                  // what to do once the storage quota is exceeded, see StorageQuotaService, server-wide default if empty
+	CodeStatsUsername      string      `json:"-"`                                 // public code::stats profile to import history from
+	CodeStatsApiToken      string      `json:"-"`                                 // machine token to export daily xp to code::stats with, optional
 }
 
 type Login struct {
@@ -65,9 +107,9 @@ type CredentialsReset struct {
 }
 
 type UserDataUpdate struct {
-	Email         string `schema:"email"`
-	Location      string `schema:"location"`
-	ReportsWeekly bool   `schema:"reports_weekly"`
+	Email    string `schema:"email"`
+	Location string `schema:"location"`
+	Locale   string `schema:"locale"`
 }
 
 type TimeByUser struct {
@@ -98,7 +140,91 @@ func (u *User) TZOffset() time.Duration {
 	return time.Duration(offset * int(time.Second))
 }
 
-func (u *User) AvatarURL(urlTemplate string) string {
+// AvatarURL returns the url of the user's uploaded avatar or, if none, of an avatar generated from the given url template.
+// With gravatar enabled, the latter is only used as a fallback for users, who have no gravatar for their e-mail address.
+func (u *User) AvatarURL(urlTemplate string, gravatar bool, publicUrl string) string {
+	if u.AvatarKey != "" {
+		return fmt.Sprintf("api/avatar/upload/%s.png", url.PathEscape(u.AvatarName()))
+	}
+
+	fallback := u.fillAvatarURLTemplate(urlTemplate)
+	if !gravatar || u.Email == "" {
+		return fallback
+	}
+
+	if !strings.HasPrefix(fallback, "http://") && !strings.HasPrefix(fallback, "https://") {
+		fallback = strings.TrimSuffix(publicUrl, "/") + "/" + strings.TrimPrefix(fallback, "/")
+	}
+	emailHash := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(u.Email))))
+	return fmt.Sprintf("https://www.gravatar.com/avatar/%x?s=128&d=%s", emailHash, url.QueryEscape(fallback))
+}
+
+func (u *User) HasJiraCredentials() bool {
+	return u.JiraUrl != "" && u.JiraEmail != "" && u.JiraApiToken != ""
+}
+
+func (u *User) HasCodeStats() bool {
+	return u.CodeStatsUsername != ""
+}
+
+// HasCodeStatsExport tells whether the user's daily xp are to be pushed to code::stats
+func (u *User) HasCodeStatsExport() bool {
+	return u.HasCodeStats() && u.CodeStatsApiToken != ""
+}
+
+func (u *User) HasGoogleCalendar() bool {
+	return u.GcalRefreshToken != ""
+}
+
+// GoogleCalendarId returns the id of the calendar to write coding sessions to
+func (u *User) GoogleCalendarId() string {
+	if u.GcalCalendarId == "" {
+		return "primary"
+	}
+	return u.GcalCalendarId
+}
+
+func (u *User) HasWorkdayTarget() bool {
+	return u.WorkdayTargetMin > 0
+}
+
+func (u *User) WorkdayTargetHours() float64 {
+	return float64(u.WorkdayTargetMin) / 60
+}
+
+func (u *User) IsWorkday(day time.Weekday) bool {
+	return u.Workdays&(1<<day) != 0
+}
+
+// WorkdayTarget returns the time the user intends to work on the given day, not considering days off
+func (u *User) WorkdayTarget(day time.Time) time.Duration {
+	if !u.IsWorkday(day.Weekday()) {
+		return 0
+	}
+	return time.Duration(u.WorkdayTargetMin) * time.Minute
+}
+
+// DashboardLayout returns the user's dashboard layout, which shows all widgets in their default order unless customized
+func (u *User) DashboardLayout() *DashboardLayout {
+	layout := NewDefaultDashboardLayout()
+	if u.DashboardWidgets != "" {
+		layout.Widgets = strings.Split(u.DashboardWidgets, ",")
+	}
+	layout.DefaultRange = u.DashboardRange
+	return layout
+}
+
+func (u *User) SetDashboardLayout(layout *DashboardLayout) {
+	u.DashboardWidgets = layout.String()
+	u.DashboardRange = layout.DefaultRange
+}
+
+// AvatarName returns the name of the user's uploaded avatar image, which is its storage key without folder and file extension
+func (u *User) AvatarName() string {
+	return strings.TrimSuffix(strings.TrimPrefix(u.AvatarKey, AvatarKeyPrefix), ".png")
+}
+
+func (u *User) fillAvatarURLTemplate(urlTemplate string) string {
 	urlTemplate = strings.ReplaceAll(urlTemplate, "{username}", u.ID)
 	urlTemplate = strings.ReplaceAll(urlTemplate, "{email}", u.Email)
 	if strings.Contains(urlTemplate, "{username_hash}") {
@@ -110,6 +236,59 @@ func (u *User) AvatarURL(urlTemplate string) string {
 	return urlTemplate
 }
 
+// SharesTotalWithin returns whether the user publicly shares their total coding time for the given time range
+func (u *User) SharesTotalWithin(from, to time.Time) bool {
+	return sharesWithin(u.ShareDataMaxDays, from, to)
+}
+
+// SharesWithin returns whether the user publicly shares data of the given summary type (e.g. SummaryProject) for the given time range
+func (u *User) SharesWithin(entityType uint8, from, to time.Time) bool {
+	var days int
+	switch entityType {
+	case SummaryProject, SummaryBranch:
+		days = u.ShareProjectsDays
+	case SummaryLanguage:
+		days = u.ShareLanguagesDays
+	case SummaryEditor:
+		days = u.ShareEditorsDays
+	case SummaryOS:
+		days = u.ShareOSsDays
+	case SummaryMachine:
+		days = u.ShareMachinesDays
+	case SummaryLabel:
+		days = u.ShareLabelsDays
+	}
+	return u.SharesTotalWithin(from, to) && sharesWithin(days, from, to)
+}
+
+// AcceptsHeartbeatAt returns whether a heartbeat with the given timestamp falls into the user's acceptance window.
+// Server-wide limits (0 = unlimited) act as an upper bound, which the user's own limits can only narrow down.
+// While the user's import scope, granted by an admin, is active, heartbeats are accepted regardless of their age.
+func (u *User) AcceptsHeartbeatAt(t, now time.Time, maxPastDays, maxFutureMin int) bool {
+	narrow := func(server, user int) int {
+		if server == 0 || (user > 0 && user < server) {
+			return user
+		}
+		return server
+	}
+
+	pastDays := narrow(maxPastDays, u.HeartbeatsMaxPastDays)
+	futureMin := narrow(maxFutureMin, u.HeartbeatsMaxFutureMin)
+
+	if futureMin > 0 && t.After(now.Add(time.Duration(futureMin)*time.Minute)) {
+		return false
+	}
+	if pastDays > 0 && !u.HasImportScope(now) && t.Before(now.AddDate(0, 0, -pastDays)) {
+		return false
+	}
+	return true
+}
+
+// HasImportScope returns whether an admin has currently lifted the user's heartbeat acceptance window for backfilling data
+func (u *User) HasImportScope(now time.Time) bool {
+	return u.ImportScopeUntil != nil && u.ImportScopeUntil.T().After(now)
+}
+
 // WakaTimeURL returns the user's effective WakaTime URL, i.e. a custom one (which could also point to another Wakapi instance) or fallback if not specified otherwise.
 func (u *User) WakaTimeURL(fallback string) string {
 	if u.WakatimeApiUrl != "" {
@@ -136,7 +315,7 @@ func (s *Signup) IsValid() bool {
 }
 
 func (r *UserDataUpdate) IsValid() bool {
-	return ValidateEmail(r.Email) && ValidateTimezone(r.Location)
+	return ValidateEmail(r.Email) && ValidateTimezone(r.Location) && (r.Locale == "" || i18n.IsSupported(r.Locale))
 }
 
 func ValidateUsername(username string) bool {
@@ -155,3 +334,10 @@ func ValidateTimezone(tz string) bool {
 	_, err := time.LoadLocation(tz)
 	return err == nil
 }
+
+func sharesWithin(days int, from, to time.Time) bool {
+	if days < 0 {
+		return true
+	}
+	return days > 0 && !from.Before(to.Add(-24*time.Hour*time.Duration(days)))
+}
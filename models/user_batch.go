@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	UserBatchStatusCreated = "created"
+	UserBatchStatusSkipped = "skipped" // user already exists
+	UserBatchStatusFailed  = "failed"
+)
+
+// UserBatchEntry is a single user to be created in bulk, e.g. for onboarding a class or team
+type UserBatchEntry struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	ApiKey   string `json:"api_key"` // optional, generated if empty
+}
+
+// UserBatchResult is the outcome of creating a single user
+type UserBatchResult struct {
+	Username string `json:"username"`
+	Status   string `json:"status"`
+	MailSent bool   `json:"mail_sent"`
+	Password string `json:"password,omitempty"` // only present, if credentials could not be mailed to the user
+	Error    string `json:"error,omitempty"`
+}
+
+type UserBatchReport struct {
+	Created int                `json:"created"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	Results []*UserBatchResult `json:"results"`
+}
+
+func (e *UserBatchEntry) Validate() error {
+	if !ValidateUsername(e.Username) {
+		return errors.New("invalid username")
+	}
+	if !ValidateEmail(e.Email) {
+		return errors.New("invalid e-mail address")
+	}
+	if e.ApiKey != "" {
+		if _, err := uuid.FromString(e.ApiKey); err != nil {
+			return errors.New("invalid api key, must be a uuid")
+		}
+	}
+	return nil
+}
+
+func (r *UserBatchReport) Add(result *UserBatchResult) {
+	switch result.Status {
+	case UserBatchStatusCreated:
+		r.Created++
+	case UserBatchStatusSkipped:
+		r.Skipped++
+	case UserBatchStatusFailed:
+		r.Failed++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// ParseUserBatchCsv reads rows of the form 'username,email[,api_key]', optionally preceded by a header row
+func ParseUserBatchCsv(r io.Reader) ([]*UserBatchEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*UserBatchEntry, 0, len(records))
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "username") {
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if len(record) < 2 || len(record) > 3 {
+			return nil, errors.New(fmt.Sprintf("line %d: expected 2 or 3 columns, got %d", i+1, len(record)))
+		}
+
+		entry := &UserBatchEntry{
+			Username: strings.TrimSpace(record[0]),
+			Email:    strings.TrimSpace(record[1]),
+		}
+		if len(record) == 3 {
+			entry.ApiKey = strings.TrimSpace(record[2])
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
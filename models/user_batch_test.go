@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserBatchCsv(t *testing.T) {
+	csv := `username,email,api_key
+alice, alice@example.org
+# comment
+bob,bob@example.org,1b2c8c4e-0f2a-4c7e-9d8e-5e3a6f1b2c3d
+`
+	entries, err := ParseUserBatchCsv(strings.NewReader(csv))
+
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, &UserBatchEntry{Username: "alice", Email: "alice@example.org"}, entries[0])
+	assert.Equal(t, "1b2c8c4e-0f2a-4c7e-9d8e-5e3a6f1b2c3d", entries[1].ApiKey)
+}
+
+func TestParseUserBatchCsv_InvalidColumns(t *testing.T) {
+	_, err := ParseUserBatchCsv(strings.NewReader("alice\n"))
+	assert.NotNil(t, err)
+
+	_, err = ParseUserBatchCsv(strings.NewReader("alice,alice@example.org,key,foo\n"))
+	assert.NotNil(t, err)
+}
+
+func TestUserBatchEntry_Validate(t *testing.T) {
+	assert.Nil(t, (&UserBatchEntry{Username: "alice"}).Validate())
+	assert.Nil(t, (&UserBatchEntry{Username: "alice", Email: "alice@example.org", ApiKey: "1b2c8c4e-0f2a-4c7e-9d8e-5e3a6f1b2c3d"}).Validate())
+	assert.NotNil(t, (&UserBatchEntry{Username: ""}).Validate())
+	assert.NotNil(t, (&UserBatchEntry{Username: "alice", Email: "foo"}).Validate())
+	assert.NotNil(t, (&UserBatchEntry{Username: "alice", ApiKey: "foo"}).Validate())
+}
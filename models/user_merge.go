@@ -0,0 +1,47 @@
+package models
+
+import "errors"
+
+// UserMerge selects two accounts of the same person to be merged, e.g. one created via sso and a legacy one, of which only the target survives
+type UserMerge struct {
+	SourceId   string `json:"source_id"`    // user to move all data from, deleted afterwards
+	TargetId   string `json:"target_id"`    // user to move all data to
+	KeepApiKey bool   `json:"keep_api_key"` // whether the target takes over the source's api key, so that clients configured with it keep working
+	DryRun     bool   `json:"-"`            // only report what would be done
+}
+
+// UserMergeReport tells what merging two users did or, in a dry run, would do
+type UserMergeReport struct {
+	SourceId                    string           `json:"source_id"`
+	TargetId                    string           `json:"target_id"`
+	DryRun                      bool             `json:"dry_run"`
+	Heartbeats                  int64            `json:"heartbeats"`                    // moved to the target
+	DuplicateHeartbeats         int64            `json:"duplicate_heartbeats"`          // present in both accounts, dropped
+	Summaries                   int64            `json:"summaries"`                     // moved to the target
+	OverlappingSummaries        int64            `json:"overlapping_summaries"`         // overlapping one of the target's, dropped in favor of recomputing the day from the merged heartbeats
+	Aliases                     int64            `json:"aliases"`                       // moved to the target
+	DuplicateAliases            int64            `json:"duplicate_aliases"`             // defined by the target already, dropped
+	LanguageMappings            int64            `json:"language_mappings"`             // moved to the target
+	ConflictingLanguageMappings int64            `json:"conflicting_language_mappings"` // extensions mapped by the target already, dropped
+	ProjectLabels               int64            `json:"project_labels"`                // moved to the target, except for duplicates
+	EmbedTokens                 int64            `json:"embed_tokens"`                  // moved to the target
+	ManualTimeEntries           int64            `json:"manual_time_entries"`           // moved to the target
+	Goals                       int64            `json:"goals"`                         // moved to the target
+	ProjectBudgets              int64            `json:"project_budgets"`               // moved to the target, except for projects it has a budget for already
+	DaysOff                     int64            `json:"days_off"`                      // moved to the target, except for days it has off already
+	FilterSets                  int64            `json:"filter_sets"`                   // moved to the target, except for names it uses already
+	ProjectRepos                int64            `json:"project_repos"`                 // moved to the target, except for projects it has linked already
+	Credentials                 []string         `json:"credentials"`                   // integrations, whose credentials the target took over, as it had none, e.g. 'wakatime'
+	ApiKeyAdopted               bool             `json:"api_key_adopted"`
+	Dropped                     map[string]int64 `json:"dropped"` // number of records per kind, which were not moved, but deleted along with the source, e.g. duplicates, relay targets or notification preferences
+}
+
+func (m *UserMerge) Validate() error {
+	if m.SourceId == "" || m.TargetId == "" {
+		return errors.New("source and target user required")
+	}
+	if m.SourceId == m.TargetId {
+		return errors.New("cannot merge a user with itself")
+	}
+	return nil
+}
@@ -18,3 +18,70 @@ func TestUser_TZ(t *testing.T) {
 	assert.InDelta(t, time.Duration(offset1*int(time.Second)), sut1.TZOffset(), float64(1*time.Second))
 	assert.InDelta(t, time.Duration(offset2*int(time.Second)), sut2.TZOffset(), float64(1*time.Second))
 }
+
+func TestUser_AcceptsHeartbeatAt(t *testing.T) {
+	now := time.Now()
+	importUntil := CustomTime(now.Add(1 * time.Hour))
+
+	sut1 := &User{}
+	sut2 := &User{HeartbeatsMaxPastDays: 3, HeartbeatsMaxFutureMin: 60}
+	sut3 := &User{ImportScopeUntil: &importUntil}
+
+	assert.True(t, sut1.AcceptsHeartbeatAt(now.AddDate(-1, 0, 0), now, 0, 0))
+	assert.True(t, sut1.AcceptsHeartbeatAt(now.AddDate(0, 0, -5), now, 7, 10))
+	assert.False(t, sut1.AcceptsHeartbeatAt(now.AddDate(0, 0, -8), now, 7, 10))
+	assert.False(t, sut1.AcceptsHeartbeatAt(now.Add(11*time.Minute), now, 7, 10))
+
+	// user limits can only narrow server limits
+	assert.False(t, sut2.AcceptsHeartbeatAt(now.AddDate(0, 0, -5), now, 7, 10))
+	assert.False(t, sut2.AcceptsHeartbeatAt(now.Add(11*time.Minute), now, 7, 10))
+	assert.True(t, sut2.AcceptsHeartbeatAt(now.Add(30*time.Minute), now, 0, 0))
+
+	// import scope lifts past limit only
+	assert.True(t, sut3.AcceptsHeartbeatAt(now.AddDate(0, 0, -8), now, 7, 10))
+	assert.False(t, sut3.AcceptsHeartbeatAt(now.Add(11*time.Minute), now, 7, 10))
+	assert.False(t, sut3.AcceptsHeartbeatAt(now.AddDate(0, 0, -8), now.Add(2*time.Hour), 7, 10))
+}
+
+func TestUser_AvatarURL(t *testing.T) {
+	sut1 := &User{ID: "john"}
+	sut2 := &User{ID: "john", Email: "John@example.org "}
+	sut3 := &User{ID: "john", Email: "john@example.org", AvatarKey: "avatars/6ba7b810-9dad-11d1-80b4-00c04fd430c8.png"}
+
+	assert.Equal(t, "api/avatar/527bd5b5d689e2c32ae974c6229ff785.svg", sut1.AvatarURL("api/avatar/{username_hash}.svg", false, ""))
+	assert.Equal(t, "api/avatar/527bd5b5d689e2c32ae974c6229ff785.svg", sut1.AvatarURL("api/avatar/{username_hash}.svg", true, "https://wakapi.dev"))
+	assert.Equal(t, "https://www.gravatar.com/avatar/08aff750c4586c34375a0ebd987c1a7e?s=128&d=https%3A%2F%2Fwakapi.dev%2Fapi%2Favatar%2F527bd5b5d689e2c32ae974c6229ff785.svg", sut2.AvatarURL("api/avatar/{username_hash}.svg", true, "https://wakapi.dev/"))
+	assert.Equal(t, "api/avatar/upload/6ba7b810-9dad-11d1-80b4-00c04fd430c8.png", sut3.AvatarURL("api/avatar/{username_hash}.svg", true, "https://wakapi.dev"))
+}
+
+func TestUser_SharesWithin(t *testing.T) {
+	to := time.Date(2022, 10, 16, 0, 0, 0, 0, time.UTC)
+	week, month := to.AddDate(0, 0, -7), to.AddDate(0, 0, -30)
+
+	sut := &User{
+		ShareDataMaxDays:   30,
+		ShareProjectsDays:  7,
+		ShareLanguagesDays: -1,
+		ShareEditorsDays:   0,
+	}
+
+	assert.True(t, sut.SharesTotalWithin(month, to))
+	assert.False(t, sut.SharesTotalWithin(month.Add(-time.Second), to))
+
+	assert.True(t, sut.SharesWithin(SummaryProject, week, to))
+	assert.True(t, sut.SharesWithin(SummaryBranch, week, to))
+	assert.False(t, sut.SharesWithin(SummaryProject, month, to))
+
+	assert.True(t, sut.SharesWithin(SummaryLanguage, month, to))
+	assert.False(t, sut.SharesWithin(SummaryLanguage, month.AddDate(0, 0, -1), to)) // capped by total
+
+	assert.False(t, sut.SharesWithin(SummaryEditor, week, to))
+	assert.False(t, sut.SharesWithin(SummaryMachine, week, to))
+
+	sut.ShareDataMaxDays = 0
+	assert.False(t, sut.SharesTotalWithin(week, to))
+	assert.False(t, sut.SharesWithin(SummaryLanguage, week, to))
+
+	sut.ShareDataMaxDays = -1
+	assert.True(t, sut.SharesWithin(SummaryLanguage, time.Time{}, to))
+}
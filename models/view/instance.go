@@ -0,0 +1,19 @@
+package view
+
+import "github.com/muety/wakapi/models"
+
+type InstanceViewModel struct {
+	Stats   *models.InstanceStats
+	Success string
+	Error   string
+}
+
+func (s *InstanceViewModel) WithSuccess(m string) *InstanceViewModel {
+	s.Success = m
+	return s
+}
+
+func (s *InstanceViewModel) WithError(m string) *InstanceViewModel {
+	s.Error = m
+	return s
+}
@@ -0,0 +1,19 @@
+package view
+
+import "github.com/muety/wakapi/models"
+
+type LeaderboardViewModel struct {
+	Items   []*models.LeaderboardItem
+	Success string
+	Error   string
+}
+
+func (s *LeaderboardViewModel) WithSuccess(m string) *LeaderboardViewModel {
+	s.Success = m
+	return s
+}
+
+func (s *LeaderboardViewModel) WithError(m string) *LeaderboardViewModel {
+	s.Error = m
+	return s
+}
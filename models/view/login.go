@@ -5,6 +5,7 @@ type LoginViewModel struct {
 	Error       string
 	TotalUsers  int
 	AllowSignup bool
+	DemoMode    bool
 }
 
 type SetPasswordViewModel struct {
@@ -0,0 +1,21 @@
+package view
+
+import "github.com/muety/wakapi/models"
+
+type ReportsViewModel struct {
+	User    *models.User
+	Reports []*models.ArchivedReport
+	ApiKey  string
+	Success string
+	Error   string
+}
+
+func (s *ReportsViewModel) WithSuccess(m string) *ReportsViewModel {
+	s.Success = m
+	return s
+}
+
+func (s *ReportsViewModel) WithError(m string) *ReportsViewModel {
+	s.Error = m
+	return s
+}
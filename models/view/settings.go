@@ -1,16 +1,42 @@
 package view
 
-import "github.com/muety/wakapi/models"
+import (
+	"github.com/muety/wakapi/models"
+	"time"
+)
 
 type SettingsViewModel struct {
-	User             *models.User
-	LanguageMappings []*models.LanguageMapping
-	Aliases          []*SettingsVMCombinedAlias
-	Labels           []*SettingsVMCombinedLabel
-	Projects         []string
-	ApiKey           string
-	Success          string
-	Error            string
+	User                     *models.User
+	LanguageMappings         []*models.LanguageMapping
+	ManualEntries            []*models.ManualTimeEntry
+	Aliases                  []*SettingsVMCombinedAlias
+	Labels                   []*SettingsVMCombinedLabel
+	Projects                 []string
+	ProjectRepos             []*models.ProjectRepo
+	Budgets                  []*models.BudgetStatus // consumption of project budgets within the current month
+	Goals                    []*models.GoalProgress
+	DaysOff                  []*SettingsVMDaysOff
+	ApiKey                   string
+	ImportScope              bool
+	RegenerationJob          *models.RegenerationJob
+	ExportJob                *models.ExportJob
+	JiraLog                  []*models.JiraWorklog
+	JiraPreview              []*models.JiraWorklog // result of a dry run, if requested
+	GoogleCalendar           bool                  // whether the google calendar integration is available on this server
+	GoogleCalendarMinSession time.Duration         // minimum length of sessions to be added to the calendar
+	Jobs                     []*models.JobStatus
+	ClockSkews               []*models.ClockSkew // machines with misconfigured clocks
+	QuotaViolations          []*models.QuotaViolation
+	DefaultQuota             int                       // server-wide heartbeats per hour, 0 = unlimited
+	StorageUsage             *models.StorageQuotaUsage // nil if unknown
+	DefaultStorageQuota      int64                     // server-wide heartbeats stored per user, 0 = unlimited
+	DefaultStoragePolicy     string
+	Notifications            models.NotificationPreferences
+	Telegram                 bool // whether telegram notifications are available on this server
+	RelayTargets             []*models.RelayTarget
+	RelayRules               models.RelayRules
+	Success                  string
+	Error                    string
 }
 
 type SettingsVMCombinedAlias struct {
@@ -19,6 +45,12 @@ type SettingsVMCombinedAlias struct {
 	Values []string
 }
 
+type SettingsVMDaysOff struct {
+	From string
+	To   string
+	Kind string
+}
+
 type SettingsVMCombinedLabel struct {
 	Key    string
 	Values []string
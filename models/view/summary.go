@@ -1,6 +1,9 @@
 package view
 
-import "github.com/muety/wakapi/models"
+import (
+	"github.com/muety/wakapi/models"
+	"time"
+)
 
 type SummaryViewModel struct {
 	*models.Summary
@@ -12,6 +15,19 @@ type SummaryViewModel struct {
 	Success        string
 	ApiKey         string
 	RawQuery       string
+	ProjectRepos   []*SummaryVMProjectRepo
+	Achievements   []*models.Achievement
+	FilterSets     []*models.FilterSet
+	FilterSet      string // name of the currently applied filter set, if any
+	Layout         *models.DashboardLayout
+}
+
+type SummaryVMProjectRepo struct {
+	Project    string
+	Url        string
+	Total      time.Duration
+	Commits    int
+	HasCommits bool // false if commits could not be counted, e.g. because of a private repository
 }
 
 func (s *SummaryViewModel) WithSuccess(m string) *SummaryViewModel {
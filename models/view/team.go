@@ -0,0 +1,23 @@
+package view
+
+import "github.com/muety/wakapi/models"
+
+type TeamViewModel struct {
+	User       *models.User
+	Comparison *models.Comparison
+	UserIds    string // comma-separated, as entered
+	Interval   string
+	ApiKey     string
+	Success    string
+	Error      string
+}
+
+func (s *TeamViewModel) WithSuccess(m string) *TeamViewModel {
+	s.Success = m
+	return s
+}
+
+func (s *TeamViewModel) WithError(m string) *TeamViewModel {
+	s.Error = m
+	return s
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// YearReview is an annual recap of a user's coding activity
+type YearReview struct {
+	Year               int               `json:"year"`
+	Total              time.Duration     `json:"-"`
+	TotalSeconds       int64             `json:"total"`
+	ActiveDays         int               `json:"active_days"`
+	TopProjects        []*YearReviewItem `json:"top_projects"`
+	TopLanguages       []*YearReviewItem `json:"top_languages"`
+	BusiestDay         time.Time         `json:"busiest_day" swaggertype:"string" format:"date" example:"2006-01-02"`
+	BusiestDayTotal    time.Duration     `json:"-"`
+	BusiestDaySeconds  int64             `json:"busiest_day_total"`
+	LongestStreak      int               `json:"longest_streak"` // in days, see StreakCounter
+	LongestStreakStart time.Time         `json:"longest_streak_start" swaggertype:"string" format:"date" example:"2006-01-02"`
+	LongestStreakEnd   time.Time         `json:"longest_streak_end" swaggertype:"string" format:"date" example:"2006-01-02"`
+	HoursOfDay         [24]time.Duration `json:"-"`
+	HoursOfDaySeconds  [24]int64         `json:"hours_of_day"` // time coded per hour of the day (in the user's time zone), summed up over the year
+	MostProductiveHour int               `json:"most_productive_hour"`
+}
+
+type YearReviewItem struct {
+	Key     string        `json:"key"`
+	Total   time.Duration `json:"-"`
+	Seconds int64         `json:"total"`
+}
+
+// NewYearReviewItems picks the first items of the given ones, which are expected to be sorted by their total time in descending order
+func NewYearReviewItems(items SummaryItems, limit int) []*YearReviewItem {
+	result := make([]*YearReviewItem, 0, limit)
+	for _, item := range items {
+		if len(result) == limit {
+			break
+		}
+		if item.Key == UnknownSummaryKey {
+			continue
+		}
+		result = append(result, &YearReviewItem{Key: item.Key, Total: item.TotalFixed(), Seconds: int64(item.TotalFixed().Seconds())})
+	}
+	return result
+}
+
+// Fill sets the json fields derived from durations
+func (r *YearReview) Fill() *YearReview {
+	r.TotalSeconds = int64(r.Total.Seconds())
+	r.BusiestDaySeconds = int64(r.BusiestDayTotal.Seconds())
+	for h, d := range r.HoursOfDay {
+		r.HoursOfDaySeconds[h] = int64(d.Seconds())
+		if d > r.HoursOfDay[r.MostProductiveHour] {
+			r.MostProductiveHour = h
+		}
+	}
+	return r
+}
@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AchievementRepository struct {
+	db *gorm.DB
+}
+
+func NewAchievementRepository(db *gorm.DB) *AchievementRepository {
+	return &AchievementRepository{db: db}
+}
+
+func (r *AchievementRepository) GetByUser(userId string) ([]*models.Achievement, error) {
+	var achievements []*models.Achievement
+	if err := r.db.
+		Where(&models.Achievement{UserID: userId}).
+		Order("earned_at asc").
+		Find(&achievements).Error; err != nil {
+		return nil, err
+	}
+	return achievements, nil
+}
+
+// InsertBatch inserts the given achievements, skipping those already earned by the user
+func (r *AchievementRepository) InsertBatch(achievements []*models.Achievement) error {
+	if len(achievements) == 0 {
+		return nil
+	}
+	return r.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&achievements).Error
+}
@@ -85,6 +85,17 @@ func (r *AliasRepository) GetByUserAndTypeAndValue(userId string, summaryType ui
 	return alias, nil
 }
 
+// GetOrphaned returns all aliases, whose user does not exist anymore
+func (r *AliasRepository) GetOrphaned() ([]*models.Alias, error) {
+	var aliases []*models.Alias
+	if err := r.db.
+		Where("user_id NOT IN (?)", r.db.Model(&models.User{}).Select("id")).
+		Find(&aliases).Error; err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
 func (r *AliasRepository) Insert(alias *models.Alias) (*models.Alias, error) {
 	if !alias.IsValid() {
 		return nil, errors.New("invalid alias")
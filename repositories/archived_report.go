@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type ArchivedReportRepository struct {
+	db *gorm.DB
+}
+
+func NewArchivedReportRepository(db *gorm.DB) *ArchivedReportRepository {
+	return &ArchivedReportRepository{db: db}
+}
+
+func (r *ArchivedReportRepository) GetById(id uint) (*models.ArchivedReport, error) {
+	report := &models.ArchivedReport{}
+	if err := r.db.Where(&models.ArchivedReport{ID: id}).First(report).Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (r *ArchivedReportRepository) GetByUser(userId string) ([]*models.ArchivedReport, error) {
+	var reports []*models.ArchivedReport
+	if err := r.db.
+		Where(&models.ArchivedReport{UserID: userId}).
+		Order("from_time desc").
+		Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (r *ArchivedReportRepository) Insert(report *models.ArchivedReport) (*models.ArchivedReport, error) {
+	if !report.IsValid() {
+		return nil, errors.New("invalid report")
+	}
+	if err := r.db.Create(report).Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/config"
+	"gorm.io/gorm"
+)
+
+var ErrBackupUnsupported = errors.New("database backups are only supported for sqlite")
+
+type BackupRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewBackupRepository(db *gorm.DB) *BackupRepository {
+	return &BackupRepository{config: config.Get(), db: db}
+}
+
+// DumpTo writes a consistent snapshot of the database to the given file, which must not exist yet
+func (r *BackupRepository) DumpTo(file string) error {
+	if !r.config.Db.IsSQLite() {
+		return ErrBackupUnsupported
+	}
+	return r.db.Exec("VACUUM INTO ?", file).Error
+}
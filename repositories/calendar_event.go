@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type CalendarEventRepository struct {
+	db *gorm.DB
+}
+
+func NewCalendarEventRepository(db *gorm.DB) *CalendarEventRepository {
+	return &CalendarEventRepository{db: db}
+}
+
+// GetByUserWithin returns the user's events starting within the given interval
+func (r *CalendarEventRepository) GetByUserWithin(userId string, from, to time.Time) ([]*models.CalendarEvent, error) {
+	var events []*models.CalendarEvent
+	if err := r.db.
+		Where(&models.CalendarEvent{UserID: userId}).
+		Where("start_time >= ?", from.Local()).
+		Where("start_time < ?", to.Local()).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *CalendarEventRepository) Upsert(event *models.CalendarEvent) (*models.CalendarEvent, error) {
+	if err := r.db.Save(event).Error; err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (r *CalendarEventRepository) DeleteByUser(userId string) error {
+	return r.db.
+		Where("user_id = ?", userId).
+		Delete(models.CalendarEvent{}).Error
+}
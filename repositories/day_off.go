@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DayOffRepository struct {
+	db *gorm.DB
+}
+
+func NewDayOffRepository(db *gorm.DB) *DayOffRepository {
+	return &DayOffRepository{db: db}
+}
+
+func (r *DayOffRepository) GetByUser(userId string) ([]*models.DayOff, error) {
+	var days []*models.DayOff
+	if err := r.db.
+		Where(&models.DayOff{UserID: userId}).
+		Order("day asc").
+		Find(&days).Error; err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// UpsertBatch inserts the given days or, if already marked, updates their kind
+func (r *DayOffRepository) UpsertBatch(days []*models.DayOff) error {
+	if len(days) == 0 {
+		return nil
+	}
+	for _, d := range days {
+		if !d.IsValid() {
+			return errors.New("invalid day off")
+		}
+	}
+	return r.db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "day"}},
+			DoUpdates: clause.AssignmentColumns([]string{"kind"}),
+		}).
+		Create(&days).Error
+}
+
+// DeleteByUserWithin deletes the user's days off between from and to (both inclusive, formatted as models.DayOffFormat)
+func (r *DayOffRepository) DeleteByUserWithin(userId, from, to string) error {
+	return r.db.
+		Where("user_id = ?", userId).
+		Where("day >= ?", from).
+		Where("day <= ?", to).
+		Delete(models.DayOff{}).Error
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type DurationRepository struct {
+	db *gorm.DB
+}
+
+func NewDurationRepository(db *gorm.DB) *DurationRepository {
+	return &DurationRepository{db: db}
+}
+
+// GetAllWithin returns the user's materialized durations starting within the given range, ordered by time
+func (r *DurationRepository) GetAllWithin(from, to time.Time, user *models.User) ([]*models.Duration, error) {
+	var durations []*models.Duration
+	if err := r.db.
+		Where(&models.Duration{UserID: user.ID}).
+		Where("time >= ?", from.Local()).
+		Where("time < ?", to.Local()).
+		Order("time asc").
+		Find(&durations).Error; err != nil {
+		return nil, err
+	}
+	return durations, nil
+}
+
+// GetDaysWithin returns the markers of all of the user's days within the given range, whose durations are currently materialized
+func (r *DurationRepository) GetDaysWithin(from, to time.Time, user *models.User) ([]*models.DurationDay, error) {
+	var days []*models.DurationDay
+	if err := r.db.
+		Where(&models.DurationDay{UserID: user.ID}).
+		Where("day >= ?", from.Local()).
+		Where("day < ?", to.Local()).
+		Find(&days).Error; err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// ReplaceDay swaps the user's materialized durations of the given utc day for the given ones and marks the day as materialized
+func (r *DurationRepository) ReplaceDay(user *models.User, day time.Time, strategy string, durations []*models.Duration) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where(&models.Duration{UserID: user.ID}).
+			Where("time >= ?", day.Local()).
+			Where("time < ?", day.Add(24*time.Hour).Local()).
+			Delete(models.Duration{}).Error; err != nil {
+			return err
+		}
+		if err := tx.
+			Where(&models.DurationDay{UserID: user.ID}).
+			Where("day = ?", day.Local()).
+			Delete(models.DurationDay{}).Error; err != nil {
+			return err
+		}
+		if len(durations) > 0 {
+			if err := tx.CreateInBatches(durations, 100).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&models.DurationDay{UserID: user.ID, Day: models.CustomTime(day), Strategy: strategy}).Error
+	})
+}
+
+// DeleteDaysByUser drops the markers of all of the user's days, e.g. because language mappings changed, which are applied to heartbeats at read time
+func (r *DurationRepository) DeleteDaysByUser(userId string) error {
+	return r.db.
+		Where(&models.DurationDay{UserID: userId}).
+		Delete(models.DurationDay{}).Error
+}
+
+// invalidateDurations drops the markers of all utc days of the given times, so that the user's durations of these days are recomputed on next access.
+// Just like invalidateDays, it is called from within the same transaction as the change to the heartbeats.
+func invalidateDurations(db *gorm.DB, userId string, times []models.CustomTime) error {
+	seen := make(map[time.Time]bool)
+	days := make([]time.Time, 0)
+	for _, ct := range times {
+		day := ct.T().UTC().Truncate(24 * time.Hour)
+		if seen[day] {
+			continue
+		}
+		seen[day] = true
+		days = append(days, day.Local())
+	}
+	if len(days) == 0 {
+		return nil
+	}
+	return db.
+		Where(&models.DurationDay{UserID: userId}).
+		Where("day IN ?", days).
+		Delete(models.DurationDay{}).Error
+}
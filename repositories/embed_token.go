@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type EmbedTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewEmbedTokenRepository(db *gorm.DB) *EmbedTokenRepository {
+	return &EmbedTokenRepository{db: db}
+}
+
+func (r *EmbedTokenRepository) GetByUser(userId string) ([]*models.EmbedToken, error) {
+	var tokens []*models.EmbedToken
+	if err := r.db.
+		Where(&models.EmbedToken{UserID: userId}).
+		Order("created_at asc").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *EmbedTokenRepository) GetByToken(token string) (*models.EmbedToken, error) {
+	embedToken := &models.EmbedToken{}
+	if err := r.db.
+		Where(&models.EmbedToken{Token: token}).
+		First(embedToken).Error; err != nil {
+		return nil, err
+	}
+	return embedToken, nil
+}
+
+func (r *EmbedTokenRepository) Insert(token *models.EmbedToken) (*models.EmbedToken, error) {
+	if !token.IsValid() {
+		return nil, errors.New("invalid embed token")
+	}
+	if err := r.db.Create(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *EmbedTokenRepository) DeleteByUserAndId(userId string, id uint) (int64, error) {
+	result := r.db.
+		Where("user_id = ?", userId).
+		Where("id = ?", id).
+		Delete(models.EmbedToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type FilterSetRepository struct {
+	db *gorm.DB
+}
+
+func NewFilterSetRepository(db *gorm.DB) *FilterSetRepository {
+	return &FilterSetRepository{db: db}
+}
+
+func (r *FilterSetRepository) GetByUser(userId string) ([]*models.FilterSet, error) {
+	var filterSets []*models.FilterSet
+	if err := r.db.
+		Where(&models.FilterSet{UserID: userId}).
+		Order("name asc").
+		Find(&filterSets).Error; err != nil {
+		return nil, err
+	}
+	return filterSets, nil
+}
+
+func (r *FilterSetRepository) GetByUserAndName(userId, name string) (*models.FilterSet, error) {
+	filterSet := &models.FilterSet{}
+	if err := r.db.
+		Where(&models.FilterSet{UserID: userId, Name: name}).
+		First(filterSet).Error; err != nil {
+		return nil, err
+	}
+	return filterSet, nil
+}
+
+func (r *FilterSetRepository) Insert(filterSet *models.FilterSet) (*models.FilterSet, error) {
+	if !filterSet.IsValid() {
+		return nil, errors.New("invalid filter set")
+	}
+	if err := r.db.Create(filterSet).Error; err != nil {
+		return nil, err
+	}
+	return filterSet, nil
+}
+
+func (r *FilterSetRepository) DeleteByUserAndName(userId, name string) error {
+	return r.db.
+		Where("user_id = ?", userId).
+		Where("name = ?", name).
+		Delete(models.FilterSet{}).Error
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type GoalRepository struct {
+	db *gorm.DB
+}
+
+func NewGoalRepository(db *gorm.DB) *GoalRepository {
+	return &GoalRepository{db: db}
+}
+
+func (r *GoalRepository) GetById(id uint) (*models.Goal, error) {
+	goal := &models.Goal{}
+	if err := r.db.Where(&models.Goal{ID: id}).First(goal).Error; err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+func (r *GoalRepository) GetByUser(userId string) ([]*models.Goal, error) {
+	var goals []*models.Goal
+	if err := r.db.
+		Where(&models.Goal{UserID: userId}).
+		Order("id asc").
+		Find(&goals).Error; err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+func (r *GoalRepository) Insert(goal *models.Goal) (*models.Goal, error) {
+	if !goal.IsValid() {
+		return nil, errors.New("invalid goal")
+	}
+	if err := r.db.Create(goal).Error; err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+func (r *GoalRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.Goal{}).Error
+}
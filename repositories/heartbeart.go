@@ -5,9 +5,14 @@ import (
 	"github.com/muety/wakapi/models"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"strings"
 	"time"
 )
 
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+var ErrDuplicateHeartbeat = errors.New("an identical heartbeat exists already")
+
 type HeartbeatRepository struct {
 	db *gorm.DB
 }
@@ -26,14 +31,109 @@ func (r *HeartbeatRepository) GetAll() ([]*models.Heartbeat, error) {
 }
 
 func (r *HeartbeatRepository) InsertBatch(heartbeats []*models.Heartbeat) error {
+	heartbeatsByUser := make(map[string][]*models.Heartbeat)
+	for _, h := range heartbeats {
+		heartbeatsByUser[h.UserID] = append(heartbeatsByUser[h.UserID], h)
+	}
+
+	// insert heartbeats, increment every user's counter by the number of actually inserted (i.e. non-duplicate) ones
+	// and invalidate summaries and durations of past days, all within a single transaction, as batches may contain heartbeats of multiple users
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for userId, userHeartbeats := range heartbeatsByUser {
+			result := tx.
+				Clauses(clause.OnConflict{
+					DoNothing: true,
+				}).
+				Create(&userHeartbeats)
+			if err := result.Error; err != nil {
+				return err
+			}
+			if err := r.incrementCount(tx, userId, result.RowsAffected); err != nil {
+				return err
+			}
+			if err := invalidateDays(tx, userId, heartbeatTimes(userHeartbeats)); err != nil {
+				return err
+			}
+			if err := invalidateDurations(tx, userId, heartbeatTimes(userHeartbeats)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *HeartbeatRepository) GetById(id uint64) (*models.Heartbeat, error) {
+	heartbeat := &models.Heartbeat{}
+	if err := r.db.Where(&models.Heartbeat{ID: id}).First(heartbeat).Error; err != nil {
+		return nil, err
+	}
+	return heartbeat, nil
+}
+
+// Update only persists the fields users may edit, i.e. project, language and branch, along with the resulting hash.
+// If the edit turns the heartbeat into a duplicate of another one, nothing is changed and ErrDuplicateHeartbeat is returned.
+func (r *HeartbeatRepository) Update(heartbeat *models.Heartbeat) (*models.Heartbeat, error) {
+	if err := r.db.Transaction(func(tx *gorm.DB) error {
+		var duplicates int64
+		if err := tx.
+			Model(&models.Heartbeat{}).
+			Where("hash = ? AND id != ?", heartbeat.Hash, heartbeat.ID).
+			Count(&duplicates).Error; err != nil {
+			return err
+		}
+		if duplicates > 0 {
+			return ErrDuplicateHeartbeat
+		}
+
+		if err := tx.
+			Model(heartbeat).
+			Select("project", "language", "branch", "hash").
+			Updates(heartbeat).Error; err != nil {
+			return err
+		}
+		if err := invalidateDays(tx, heartbeat.UserID, []models.CustomTime{heartbeat.Time}); err != nil {
+			return err
+		}
+		return invalidateDurations(tx, heartbeat.UserID, []models.CustomTime{heartbeat.Time})
+	}); err != nil {
+		return nil, err
+	}
+	return heartbeat, nil
+}
+
+// CountBySelection returns the number of the user's heartbeats matching the given selection
+func (r *HeartbeatRepository) CountBySelection(user *models.User, selection *models.HeartbeatSelection) (int64, error) {
+	var count int64
+	if err := r.selectionQuery(user, selection).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetPageBySelection returns at most limit of the user's heartbeats matching the given selection with an id greater than afterId, ordered by id.
+// Paginating by id keeps pages stable, even if heartbeats stop matching the selection after being changed in between.
+func (r *HeartbeatRepository) GetPageBySelection(user *models.User, selection *models.HeartbeatSelection, afterId uint64, limit int) ([]*models.Heartbeat, error) {
+	var heartbeats []*models.Heartbeat
+	if err := r.selectionQuery(user, selection).
+		Where("id > ?", afterId).
+		Order("id asc").
+		Limit(limit).
+		Find(&heartbeats).Error; err != nil {
+		return nil, err
+	}
+	return heartbeats, nil
+}
+
+func (r *HeartbeatRepository) GetFirstByUser(user *models.User) (*models.Heartbeat, error) {
+	var heartbeat models.Heartbeat
 	if err := r.db.
-		Clauses(clause.OnConflict{
-			DoNothing: true,
-		}).
-		Create(&heartbeats).Error; err != nil {
-		return err
+		Model(&models.Heartbeat{}).
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Order("time asc").
+		First(&heartbeat).Error; err != nil {
+		return nil, err
 	}
-	return nil
+	return &heartbeat, nil
 }
 
 func (r *HeartbeatRepository) GetLatestByUser(user *models.User) (*models.Heartbeat, error) {
@@ -63,6 +163,21 @@ func (r *HeartbeatRepository) GetLatestByOriginAndUser(origin string, user *mode
 	return &heartbeat, nil
 }
 
+// GetLatestByMachineOriginAndUser is like GetLatestByOriginAndUser, but additionally filters by machine, which may also be empty
+func (r *HeartbeatRepository) GetLatestByMachineOriginAndUser(machine, origin string, user *models.User) (*models.Heartbeat, error) {
+	var heartbeat models.Heartbeat
+	if err := r.db.
+		Model(&models.Heartbeat{}).
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Where("machine = ?", machine).
+		Where("origin = ?", origin).
+		Order("time desc").
+		First(&heartbeat).Error; err != nil {
+		return nil, err
+	}
+	return &heartbeat, nil
+}
+
 func (r *HeartbeatRepository) GetAllWithin(from, to time.Time, user *models.User) ([]*models.Heartbeat, error) {
 	// https://stackoverflow.com/a/20765152/3112139
 	var heartbeats []*models.Heartbeat
@@ -77,6 +192,55 @@ func (r *HeartbeatRepository) GetAllWithin(from, to time.Time, user *models.User
 	return heartbeats, nil
 }
 
+// GetAllWithinOrdered is like GetAllWithin, but sorts by time or by project name (and time) as requested
+func (r *HeartbeatRepository) GetAllWithinOrdered(from, to time.Time, user *models.User, ordering *models.Ordering) ([]*models.Heartbeat, error) {
+	query := r.db.
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Where("time >= ?", from.Local()).
+		Where("time < ?", to.Local())
+
+	switch ordering.By {
+	case models.OrderByTime:
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: "time"}, Desc: ordering.Desc})
+	case models.OrderByName:
+		query = query.
+			Order(clause.OrderByColumn{Column: clause.Column{Name: "project"}, Desc: ordering.Desc}).
+			Order("time asc")
+	default:
+		return nil, errors.New("unsupported ordering")
+	}
+
+	var heartbeats []*models.Heartbeat
+	if err := query.Find(&heartbeats).Error; err != nil {
+		return nil, err
+	}
+	return heartbeats, nil
+}
+
+// GetAllWithinPage returns at most limit heartbeats following the given cursor (or from the very beginning, if nil), ordered by time and id.
+// As opposed to offset-based pagination, this keyset approach makes use of the time index and does not slow down for later pages.
+func (r *HeartbeatRepository) GetAllWithinPage(from, to time.Time, user *models.User, cursor *models.HeartbeatCursor, limit int) ([]*models.Heartbeat, error) {
+	var heartbeats []*models.Heartbeat
+
+	query := r.db.
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Where("time >= ?", from.Local()).
+		Where("time < ?", to.Local())
+
+	if cursor != nil {
+		query = query.Where("(time > ? OR (time = ? AND id > ?))", cursor.Time.T().Local(), cursor.Time.T().Local(), cursor.ID)
+	}
+
+	if err := query.
+		Order("time asc").
+		Order("id asc").
+		Limit(limit).
+		Find(&heartbeats).Error; err != nil {
+		return nil, err
+	}
+	return heartbeats, nil
+}
+
 func (r *HeartbeatRepository) GetFirstByUsers() ([]*models.TimeByUser, error) {
 	var result []*models.TimeByUser
 	r.db.Model(&models.User{}).
@@ -100,22 +264,20 @@ func (r *HeartbeatRepository) GetLastByUsers() ([]*models.TimeByUser, error) {
 func (r *HeartbeatRepository) Count() (int64, error) {
 	var count int64
 	if err := r.db.
-		Model(&models.Heartbeat{}).
-		Count(&count).Error; err != nil {
+		Model(&models.HeartbeatCount{}).
+		Select("coalesce(sum(total), 0)").
+		Scan(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
 func (r *HeartbeatRepository) CountByUser(user *models.User) (int64, error) {
-	var count int64
-	if err := r.db.
-		Model(&models.Heartbeat{}).
-		Where(&models.Heartbeat{UserID: user.ID}).
-		Count(&count).Error; err != nil {
+	counts, err := r.CountByUsers([]*models.User{user})
+	if err != nil || len(counts) == 0 {
 		return 0, err
 	}
-	return count, nil
+	return counts[0].Count, nil
 }
 
 func (r *HeartbeatRepository) CountByUsers(users []*models.User) ([]*models.CountByUser, error) {
@@ -127,16 +289,29 @@ func (r *HeartbeatRepository) CountByUsers(users []*models.User) ([]*models.Coun
 	}
 
 	if err := r.db.
-		Model(&models.Heartbeat{}).
-		Select("user_id as user, count(id) as count").
+		Model(&models.HeartbeatCount{}).
+		Select("user_id as user, total as count").
 		Where("user_id in ?", userIds).
-		Group("user").
 		Find(&counts).Error; err != nil {
 		return counts, err
 	}
 	return counts, nil
 }
 
+// incrementCount adds n (which may be negative) to the user's counter, which is created on the user's first heartbeats.
+// Counters of pre-existing users are seeded once during migration.
+func (r *HeartbeatRepository) incrementCount(tx *gorm.DB, userId string, n int64) error {
+	if n == 0 {
+		return nil
+	}
+	return tx.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"total": gorm.Expr("heartbeat_counts.total + ?", n)}),
+		}).
+		Create(&models.HeartbeatCount{UserID: userId, Total: n}).Error
+}
+
 func (r HeartbeatRepository) GetEntitySetByUser(entityType uint8, user *models.User) ([]string, error) {
 	columns := []string{"project", "language", "editor", "operating_system", "machine"}
 	if int(entityType) >= len(columns) {
@@ -155,11 +330,204 @@ func (r HeartbeatRepository) GetEntitySetByUser(entityType uint8, user *models.U
 	return results, nil
 }
 
-func (r *HeartbeatRepository) DeleteBefore(t time.Time) error {
+// GetProjectActivityByUser returns all of the user's projects along with the time of their latest heartbeat, sorted by name or by that time as requested
+func (r *HeartbeatRepository) GetProjectActivityByUser(user *models.User, ordering *models.Ordering) ([]*models.ProjectActivity, error) {
+	var orderColumn string
+	switch ordering.By {
+	case models.OrderByName:
+		orderColumn = "project"
+	case models.OrderByLastActivity, models.OrderByTime:
+		orderColumn = "last_activity"
+	default:
+		return nil, errors.New("unsupported ordering")
+	}
+
+	var results []*models.ProjectActivity
 	if err := r.db.
-		Where("time <= ?", t.Local()).
-		Delete(models.Heartbeat{}).Error; err != nil {
-		return err
+		Model(&models.Heartbeat{}).
+		Select("project, max(time) as last_activity").
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Where("project != ''").
+		Group("project").
+		Order(clause.OrderByColumn{Column: clause.Column{Name: orderColumn}, Desc: ordering.Desc}).
+		Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *HeartbeatRepository) GetMachineActivityByUser(user *models.User) ([]*models.MachineActivity, error) {
+	var results []*models.MachineActivity
+	if err := r.db.
+		Model(&models.Heartbeat{}).
+		Select("machine, max(time) as last_activity").
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Where("machine != ''").
+		Group("machine").
+		Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetOriginActivityByUser returns the time of the latest heartbeat per machine and origin. Other fields are left empty.
+func (r *HeartbeatRepository) GetOriginActivityByUser(user *models.User) ([]*models.OriginActivity, error) {
+	var results []*models.OriginActivity
+	if err := r.db.
+		Model(&models.Heartbeat{}).
+		Select("machine, origin, max(time) as last_activity").
+		Where(&models.Heartbeat{UserID: user.ID}).
+		Group("machine, origin").
+		Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// DeleteByIds deletes the given heartbeats of the user, ignoring ids of other users' heartbeats
+func (r *HeartbeatRepository) DeleteByIds(user *models.User, ids []uint64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var times []models.CustomTime
+		if err := tx.
+			Model(&models.Heartbeat{}).
+			Where(&models.Heartbeat{UserID: user.ID}).
+			Where("id IN ?", ids).
+			Pluck("time", &times).Error; err != nil {
+			return err
+		}
+		if err := invalidateDays(tx, user.ID, times); err != nil {
+			return err
+		}
+		if err := invalidateDurations(tx, user.ID, times); err != nil {
+			return err
+		}
+
+		result := tx.
+			Where(&models.Heartbeat{UserID: user.ID}).
+			Where("id IN ?", ids).
+			Delete(models.Heartbeat{})
+		if err := result.Error; err != nil {
+			return err
+		}
+		return r.incrementCount(tx, user.ID, -result.RowsAffected)
+	})
+}
+
+func (r *HeartbeatRepository) DeleteBefore(t time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var counts []*models.CountByUser
+		if err := tx.
+			Model(&models.Heartbeat{}).
+			Select("user_id as user, count(id) as count").
+			Where("time <= ?", t.Local()).
+			Group("user_id").
+			Find(&counts).Error; err != nil {
+			return err
+		}
+
+		if err := tx.
+			Where("time <= ?", t.Local()).
+			Delete(models.Heartbeat{}).Error; err != nil {
+			return err
+		}
+
+		// materialized durations of the affected days are dropped along with the heartbeats they were computed from
+		if err := tx.
+			Where("time <= ?", t.Local()).
+			Delete(models.Duration{}).Error; err != nil {
+			return err
+		}
+		if err := tx.
+			Where("day <= ?", t.Local()).
+			Delete(models.DurationDay{}).Error; err != nil {
+			return err
+		}
+
+		for _, c := range counts {
+			if err := r.incrementCount(tx, c.User, -c.Count); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteOldestByUser deletes (at least) the user's n oldest heartbeats, including ones sharing the timestamp of the n-th, and returns the number of deleted heartbeats.
+// Unlike DeleteByIds, it does not invalidate the summaries of the affected days, so that they are retained.
+func (r *HeartbeatRepository) DeleteOldestByUser(user *models.User, n int64) (int64, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var times []models.CustomTime
+		if err := tx.
+			Model(&models.Heartbeat{}).
+			Where(&models.Heartbeat{UserID: user.ID}).
+			Order("time asc").
+			Offset(int(n-1)).
+			Limit(1).
+			Pluck("time", &times).Error; err != nil {
+			return err
+		}
+		if len(times) == 0 {
+			return nil
+		}
+		t := times[0].T()
+
+		result := tx.
+			Where(&models.Heartbeat{UserID: user.ID}).
+			Where("time <= ?", t.Local()).
+			Delete(models.Heartbeat{})
+		if err := result.Error; err != nil {
+			return err
+		}
+		deleted = result.RowsAffected
+
+		// materialized durations of the affected days are dropped along with the heartbeats they were computed from
+		if err := tx.
+			Where(&models.Duration{UserID: user.ID}).
+			Where("time <= ?", t.Local()).
+			Delete(models.Duration{}).Error; err != nil {
+			return err
+		}
+		if err := tx.
+			Where(&models.DurationDay{UserID: user.ID}).
+			Where("day <= ?", t.Local()).
+			Delete(models.DurationDay{}).Error; err != nil {
+			return err
+		}
+
+		return r.incrementCount(tx, user.ID, -deleted)
+	})
+	return deleted, err
+}
+
+func (r *HeartbeatRepository) selectionQuery(user *models.User, selection *models.HeartbeatSelection) *gorm.DB {
+	query := r.db.
+		Model(&models.Heartbeat{}).
+		Where(&models.Heartbeat{UserID: user.ID})
+	if selection.EntityPrefix != "" {
+		// '!' instead of backslash as escape character, because the latter needs escaping itself in mysql
+		query = query.Where("entity LIKE ? ESCAPE '!'", likeEscaper.Replace(selection.EntityPrefix)+"%")
+	}
+	if selection.Project != "" {
+		query = query.Where("project = ?", selection.Project)
+	}
+	if !selection.From.IsZero() {
+		query = query.Where("time >= ?", selection.From.Local())
+	}
+	if !selection.To.IsZero() {
+		query = query.Where("time < ?", selection.To.Local())
+	}
+	return query
+}
+
+func heartbeatTimes(heartbeats []*models.Heartbeat) []models.CustomTime {
+	times := make([]models.CustomTime, len(heartbeats))
+	for i, h := range heartbeats {
+		times[i] = h.Time
 	}
-	return nil
+	return times
 }
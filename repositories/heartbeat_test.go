@@ -0,0 +1,337 @@
+package repositories
+
+import (
+	"fmt"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testUserId = "muety"
+
+type HeartbeatRepositoryTestSuite struct {
+	suite.Suite
+	DB       *gorm.DB
+	TestUser *models.User
+}
+
+func (suite *HeartbeatRepositoryTestSuite) BeforeTest(suiteName, testName string) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	// every connection to an in-memory database gets its own, empty one
+	sqlDb, _ := db.DB()
+	sqlDb.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&models.User{}, &models.Heartbeat{}, &models.HeartbeatCount{}, &models.SummaryInvalidation{}, &models.Duration{}, &models.DurationDay{}); err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	suite.DB = db
+	suite.TestUser = &models.User{ID: testUserId}
+	suite.DB.Create(suite.TestUser)
+}
+
+func (suite *HeartbeatRepositoryTestSuite) AfterTest(suiteName, testName string) {
+	if sqlDb, err := suite.DB.DB(); err == nil {
+		sqlDb.Close()
+	}
+}
+
+func TestHeartbeatRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(HeartbeatRepositoryTestSuite))
+}
+
+func (suite *HeartbeatRepositoryTestSuite) TestHeartbeatRepository_GetAllWithinPage_EqualTimes() {
+	sut := NewHeartbeatRepository(suite.DB)
+
+	t0 := time.Date(2022, 10, 16, 12, 0, 0, 0, time.Local)
+	times := []time.Time{t0, t0.Add(1 * time.Minute), t0.Add(1 * time.Minute), t0.Add(1 * time.Minute), t0.Add(2 * time.Minute)}
+	for i, t := range times {
+		suite.DB.Create(&models.Heartbeat{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(t), Hash: fmt.Sprintf("%d", i)})
+	}
+
+	// pages of two, i.e. page boundary is in between heartbeats with equal timestamps
+	var cursor *models.HeartbeatCursor
+	var ids []uint64
+	for i := 0; i < len(times); i++ {
+		page, err := sut.GetAllWithinPage(t0, t0.Add(1*time.Hour), suite.TestUser, cursor, 2)
+		assert.Nil(suite.T(), err)
+		if len(page) == 0 {
+			break
+		}
+		for _, h := range page {
+			ids = append(ids, h.ID)
+		}
+		cursor = models.NewHeartbeatCursor(page[len(page)-1])
+	}
+
+	assert.Len(suite.T(), ids, len(times)) // no heartbeat is skipped or returned twice
+	for i := 1; i < len(ids); i++ {
+		assert.Greater(suite.T(), ids[i], ids[i-1])
+	}
+}
+
+func (suite *HeartbeatRepositoryTestSuite) TestHeartbeatRepository_Count() {
+	sut := NewHeartbeatRepository(suite.DB)
+
+	otherUser := &models.User{ID: "otheruser"}
+	suite.DB.Create(otherUser)
+
+	t0 := time.Date(2022, 10, 16, 12, 0, 0, 0, time.Local)
+	heartbeats := []*models.Heartbeat{
+		{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(t0), Hash: "1"},
+		{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(t0.Add(1 * time.Hour)), Hash: "2"},
+		{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(t0.Add(2 * time.Hour)), Hash: "3"},
+		{UserID: otherUser.ID, Entity: "main.go", Time: models.CustomTime(t0.Add(2 * time.Hour)), Hash: "4"},
+	}
+	assert.Nil(suite.T(), sut.InsertBatch(heartbeats))
+	assert.Nil(suite.T(), sut.InsertBatch(heartbeats[:1])) // duplicates are not counted
+
+	count, err := sut.Count()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(4), count)
+
+	userCount, err := sut.CountByUser(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), userCount)
+
+	// counters are decremented per user
+	assert.Nil(suite.T(), sut.DeleteBefore(t0.Add(1*time.Hour)))
+
+	counts, err := sut.CountByUsers([]*models.User{suite.TestUser, otherUser})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), counts, 2)
+	for _, c := range counts {
+		assert.Equal(suite.T(), int64(1), c.Count)
+	}
+
+	count, err = sut.Count()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), count)
+}
+
+func (suite *HeartbeatRepositoryTestSuite) TestHeartbeatRepository_Count_ConcurrentFirstInsert() {
+	sut := NewHeartbeatRepository(suite.DB)
+
+	t0 := time.Date(2022, 10, 16, 12, 0, 0, 0, time.Local)
+	n := 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- sut.InsertBatch([]*models.Heartbeat{
+				{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(t0.Add(time.Duration(i) * time.Minute)), Hash: fmt.Sprintf("%d", i)},
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Nil(suite.T(), err)
+	}
+
+	count, err := sut.CountByUser(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(n), count)
+}
+
+func (suite *HeartbeatRepositoryTestSuite) TestHeartbeatRepository_InvalidatesSummaries() {
+	sut := NewHeartbeatRepository(suite.DB)
+	invalidationRepository := NewSummaryInvalidationRepository(suite.DB)
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	heartbeats := []*models.Heartbeat{
+		{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(today.AddDate(0, 0, -3).Add(10 * time.Hour)), Hash: "1"},
+		{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(today.AddDate(0, 0, -3).Add(11 * time.Hour)), Hash: "2"},
+		{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(today.AddDate(0, 0, -1).Add(23 * time.Hour)), Hash: "3"},
+		{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(today.Add(1 * time.Minute)), Hash: "4"},
+	}
+	assert.Nil(suite.T(), sut.InsertBatch(heartbeats))
+
+	// today has no summary yet, so there is nothing to invalidate
+	invalidations, err := invalidationRepository.GetAll()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), invalidations, 2)
+	assert.True(suite.T(), invalidations[0].Day.T().Equal(today.AddDate(0, 0, -3)))
+	assert.True(suite.T(), invalidations[1].Day.T().Equal(today.AddDate(0, 0, -1)))
+
+	// deleting heartbeats invalidates their days as well
+	assert.Nil(suite.T(), invalidationRepository.DeleteBatchMarkedBefore([]uint{invalidations[0].ID, invalidations[1].ID}, now.Add(1*time.Minute)))
+	assert.Nil(suite.T(), sut.DeleteByIds(suite.TestUser, []uint64{heartbeats[2].ID}))
+
+	invalidations, err = invalidationRepository.GetAll()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), invalidations, 1)
+	assert.True(suite.T(), invalidations[0].Day.T().Equal(today.AddDate(0, 0, -1)))
+}
+
+func (suite *HeartbeatRepositoryTestSuite) TestHeartbeatRepository_InvalidatesDurations() {
+	sut := NewHeartbeatRepository(suite.DB)
+	durationRepository := NewDurationRepository(suite.DB)
+
+	day1 := time.Date(2022, 10, 14, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	assert.Nil(suite.T(), durationRepository.ReplaceDay(suite.TestUser, day1, models.DurationStrategyDefault, []*models.Duration{
+		{UserID: testUserId, Time: models.CustomTime(day1.Add(1 * time.Hour)), Duration: 10 * time.Minute},
+	}))
+	assert.Nil(suite.T(), durationRepository.ReplaceDay(suite.TestUser, day2, models.DurationStrategyDefault, []*models.Duration{}))
+
+	days, err := durationRepository.GetDaysWithin(day1, day2.Add(24*time.Hour), suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), days, 2)
+
+	durations, err := durationRepository.GetAllWithin(day1, day2.Add(24*time.Hour), suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), durations, 1)
+	assert.Equal(suite.T(), 10*time.Minute, durations[0].Duration)
+
+	// a late heartbeat causes its day to be recomputed
+	assert.Nil(suite.T(), sut.InsertBatch([]*models.Heartbeat{
+		{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(day2.Add(5 * time.Hour)), Hash: "1"},
+	}))
+
+	days, err = durationRepository.GetDaysWithin(day1, day2.Add(24*time.Hour), suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), days, 1)
+	assert.True(suite.T(), days[0].Day.T().Equal(day1))
+}
+
+func (suite *HeartbeatRepositoryTestSuite) TestHeartbeatRepository_Update() {
+	sut := NewHeartbeatRepository(suite.DB)
+	durationRepository := NewDurationRepository(suite.DB)
+	invalidationRepository := NewSummaryInvalidationRepository(suite.DB)
+
+	day := time.Date(2022, 10, 14, 0, 0, 0, 0, time.UTC)
+	heartbeat := (&models.Heartbeat{UserID: testUserId, Entity: "main.go", Project: "wakapi", Language: "Go", Time: models.CustomTime(day.Add(5 * time.Hour))}).Hashed()
+	assert.Nil(suite.T(), sut.InsertBatch([]*models.Heartbeat{heartbeat}))
+	assert.Nil(suite.T(), suite.DB.Where("1 = 1").Delete(&models.SummaryInvalidation{}).Error)
+	assert.Nil(suite.T(), durationRepository.ReplaceDay(suite.TestUser, day, models.DurationStrategyDefault, []*models.Duration{}))
+
+	oldHash := heartbeat.Hash
+	heartbeat.Project = "anchr"
+	result, err := sut.Update(heartbeat.Hashed())
+	assert.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), oldHash, result.Hash)
+
+	persisted, err := sut.GetById(heartbeat.ID)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "anchr", persisted.Project)
+	assert.Equal(suite.T(), result.Hash, persisted.Hash)
+
+	// both the day's summary and durations are recomputed
+	invalidations, err := invalidationRepository.GetAll()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), invalidations, 1)
+
+	days, err := durationRepository.GetDaysWithin(day, day.Add(24*time.Hour), suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), days)
+}
+
+func (suite *HeartbeatRepositoryTestSuite) TestHeartbeatRepository_Update_Duplicate() {
+	sut := NewHeartbeatRepository(suite.DB)
+	invalidationRepository := NewSummaryInvalidationRepository(suite.DB)
+
+	t0 := time.Date(2022, 10, 14, 5, 0, 0, 0, time.UTC)
+	heartbeats := []*models.Heartbeat{
+		(&models.Heartbeat{UserID: testUserId, Entity: "main.go", Project: "wakapi", Time: models.CustomTime(t0)}).Hashed(),
+		(&models.Heartbeat{UserID: testUserId, Entity: "main.go", Project: "wakapi-legacy", Time: models.CustomTime(t0)}).Hashed(),
+	}
+	assert.Nil(suite.T(), sut.InsertBatch(heartbeats))
+	assert.Nil(suite.T(), suite.DB.Where("1 = 1").Delete(&models.SummaryInvalidation{}).Error)
+
+	// renaming the project makes the second heartbeat identical to the first one
+	heartbeats[1].Project = "wakapi"
+	_, err := sut.Update(heartbeats[1].Hashed())
+	assert.Equal(suite.T(), ErrDuplicateHeartbeat, err)
+
+	persisted, err := sut.GetById(heartbeats[1].ID)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "wakapi-legacy", persisted.Project)
+
+	invalidations, err := invalidationRepository.GetAll()
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), invalidations)
+}
+
+func (suite *HeartbeatRepositoryTestSuite) TestHeartbeatRepository_DeleteOldestByUser() {
+	sut := NewHeartbeatRepository(suite.DB)
+	invalidationRepository := NewSummaryInvalidationRepository(suite.DB)
+
+	otherUser := &models.User{ID: "otheruser"}
+	suite.DB.Create(otherUser)
+
+	t0 := time.Date(2022, 10, 16, 12, 0, 0, 0, time.Local)
+	heartbeats := []*models.Heartbeat{
+		{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(t0), Hash: "1"},
+		{UserID: testUserId, Entity: "util.go", Time: models.CustomTime(t0.Add(1 * time.Hour)), Hash: "2"},
+		{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(t0.Add(1 * time.Hour)), Hash: "3"},
+		{UserID: testUserId, Entity: "main.go", Time: models.CustomTime(t0.Add(2 * time.Hour)), Hash: "4"},
+		{UserID: otherUser.ID, Entity: "main.go", Time: models.CustomTime(t0), Hash: "5"},
+	}
+	assert.Nil(suite.T(), sut.InsertBatch(heartbeats))
+	assert.Nil(suite.T(), suite.DB.Where("1 = 1").Delete(&models.SummaryInvalidation{}).Error)
+
+	// heartbeats sharing the timestamp of the last one to delete are deleted as well
+	deleted, err := sut.DeleteOldestByUser(suite.TestUser, 2)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), deleted)
+
+	count, err := sut.CountByUser(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+
+	count, err = sut.CountByUser(otherUser)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), count)
+
+	// summaries of pruned days are retained
+	invalidations, err := invalidationRepository.GetAll()
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), invalidations)
+}
+
+func (suite *HeartbeatRepositoryTestSuite) TestHeartbeatRepository_GetProjectActivityByUser() {
+	sut := NewHeartbeatRepository(suite.DB)
+
+	t0 := time.Date(2022, 10, 16, 12, 0, 0, 0, time.Local)
+	heartbeats := []*models.Heartbeat{
+		{UserID: testUserId, Project: "wakapi", Time: models.CustomTime(t0)},
+		{UserID: testUserId, Project: "anchr", Time: models.CustomTime(t0.Add(1 * time.Minute))},
+		{UserID: testUserId, Project: "wakapi", Time: models.CustomTime(t0.Add(2 * time.Minute))},
+		{UserID: testUserId, Project: "", Time: models.CustomTime(t0.Add(3 * time.Minute))},
+	}
+	for i, h := range heartbeats {
+		h.Hash = fmt.Sprintf("%d", i)
+		suite.DB.Create(h)
+	}
+
+	result, err := sut.GetProjectActivityByUser(suite.TestUser, models.NewOrdering(models.OrderByName, false))
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), "anchr", result[0].Project)
+	assert.Equal(suite.T(), "wakapi", result[1].Project)
+
+	result, err = sut.GetProjectActivityByUser(suite.TestUser, models.NewOrdering(models.OrderByLastActivity, true))
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), "wakapi", result[0].Project)
+	assert.Equal(suite.T(), t0.Add(2*time.Minute).Unix(), result[0].LastActivity.T().Unix())
+
+	_, err = sut.GetProjectActivityByUser(suite.TestUser, models.NewOrdering("foo", false))
+	assert.Error(suite.T(), err)
+}
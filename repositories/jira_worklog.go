@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type JiraWorklogRepository struct {
+	db *gorm.DB
+}
+
+func NewJiraWorklogRepository(db *gorm.DB) *JiraWorklogRepository {
+	return &JiraWorklogRepository{db: db}
+}
+
+// GetByUser returns the user's most recent worklogs, latest first
+func (r *JiraWorklogRepository) GetByUser(userId string, limit int) ([]*models.JiraWorklog, error) {
+	var worklogs []*models.JiraWorklog
+	if err := r.db.
+		Where(&models.JiraWorklog{UserID: userId}).
+		Order("date desc").
+		Order("ticket asc").
+		Limit(limit).
+		Find(&worklogs).Error; err != nil {
+		return nil, err
+	}
+	return worklogs, nil
+}
+
+// GetByUserWithin returns the user's worklogs between the given dates (formatted as '2006-01-02'), both inclusive
+func (r *JiraWorklogRepository) GetByUserWithin(userId string, from, to string) ([]*models.JiraWorklog, error) {
+	var worklogs []*models.JiraWorklog
+	if err := r.db.
+		Where(&models.JiraWorklog{UserID: userId}).
+		Where("date >= ?", from).
+		Where("date <= ?", to).
+		Find(&worklogs).Error; err != nil {
+		return nil, err
+	}
+	return worklogs, nil
+}
+
+func (r *JiraWorklogRepository) Upsert(worklog *models.JiraWorklog) (*models.JiraWorklog, error) {
+	if err := r.db.Save(worklog).Error; err != nil {
+		return nil, err
+	}
+	return worklog, nil
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type LeaderboardRepository struct {
+	db *gorm.DB
+}
+
+func NewLeaderboardRepository(db *gorm.DB) *LeaderboardRepository {
+	return &LeaderboardRepository{db: db}
+}
+
+func (r *LeaderboardRepository) GetAll() ([]*models.LeaderboardItem, error) {
+	var items []*models.LeaderboardItem
+	// ordering by rank is equivalent, but rank is a reserved word in some databases
+	if err := r.db.
+		Order("total_seconds desc").
+		Order("user_id asc").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ReplaceAll swaps the entire leaderboard for the given one within a single transaction, so that it is never served half-way generated
+func (r *LeaderboardRepository) ReplaceAll(items []*models.LeaderboardItem) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.LeaderboardItem{}).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(items, 100).Error
+	})
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+type ManualTimeEntryRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewManualTimeEntryRepository(db *gorm.DB) *ManualTimeEntryRepository {
+	return &ManualTimeEntryRepository{config: config.Get(), db: db}
+}
+
+func (r *ManualTimeEntryRepository) GetAll() ([]*models.ManualTimeEntry, error) {
+	var entries []*models.ManualTimeEntry
+	if err := r.db.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *ManualTimeEntryRepository) GetById(id uint) (*models.ManualTimeEntry, error) {
+	entry := &models.ManualTimeEntry{}
+	if err := r.db.Where(&models.ManualTimeEntry{ID: id}).First(entry).Error; err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+func (r *ManualTimeEntryRepository) GetByUser(userId string) ([]*models.ManualTimeEntry, error) {
+	var entries []*models.ManualTimeEntry
+	if userId == "" {
+		return entries, nil
+	}
+	if err := r.db.
+		Where(&models.ManualTimeEntry{UserID: userId}).
+		Order("date desc").
+		Find(&entries).Error; err != nil {
+		return entries, err
+	}
+	return entries, nil
+}
+
+func (r *ManualTimeEntryRepository) GetByUserWithin(userId string, from, to time.Time) ([]*models.ManualTimeEntry, error) {
+	var entries []*models.ManualTimeEntry
+	if userId == "" {
+		return entries, nil
+	}
+	if err := r.db.
+		Where(&models.ManualTimeEntry{UserID: userId}).
+		Where("date >= ?", from.Local()).
+		Where("date < ?", to.Local()).
+		Order("date asc").
+		Find(&entries).Error; err != nil {
+		return entries, err
+	}
+	return entries, nil
+}
+
+func (r *ManualTimeEntryRepository) Insert(entry *models.ManualTimeEntry) (*models.ManualTimeEntry, error) {
+	if !entry.IsValid() {
+		return nil, errors.New("invalid manual time entry")
+	}
+	result := r.db.Create(entry)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (r *ManualTimeEntryRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.ManualTimeEntry{}).Error
+}
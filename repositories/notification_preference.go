@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationPreferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationPreferenceRepository(db *gorm.DB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{db: db}
+}
+
+func (r *NotificationPreferenceRepository) GetByUser(userId string) ([]*models.NotificationPreference, error) {
+	var preferences []*models.NotificationPreference
+	if err := r.db.
+		Where(&models.NotificationPreference{UserID: userId}).
+		Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+	return preferences, nil
+}
+
+// Upsert creates the preference or, if the user already has one for the event and channel, updates whether it is enabled
+func (r *NotificationPreferenceRepository) Upsert(preference *models.NotificationPreference) (*models.NotificationPreference, error) {
+	if !preference.IsValid() {
+		return nil, errors.New("invalid notification preference")
+	}
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "event"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+	}).Create(preference).Error; err != nil {
+		return nil, err
+	}
+	return preference, nil
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectBudgetRepository struct {
+	db *gorm.DB
+}
+
+func NewProjectBudgetRepository(db *gorm.DB) *ProjectBudgetRepository {
+	return &ProjectBudgetRepository{db: db}
+}
+
+func (r *ProjectBudgetRepository) GetAll() ([]*models.ProjectBudget, error) {
+	var budgets []*models.ProjectBudget
+	if err := r.db.Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+func (r *ProjectBudgetRepository) GetByUser(userId string) ([]*models.ProjectBudget, error) {
+	var budgets []*models.ProjectBudget
+	if err := r.db.
+		Where(&models.ProjectBudget{UserID: userId}).
+		Order("project_key asc").
+		Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+// Upsert creates the budget or, if the user already has one for the project, updates its hours and resets notifications
+func (r *ProjectBudgetRepository) Upsert(budget *models.ProjectBudget) (*models.ProjectBudget, error) {
+	if !budget.IsValid() {
+		return nil, errors.New("invalid budget")
+	}
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "project_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hours", "notified_threshold"}),
+	}).Create(budget).Error; err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (r *ProjectBudgetRepository) UpdateNotified(budget *models.ProjectBudget) error {
+	return r.db.
+		Model(budget).
+		Select("notified_threshold", "notified_month").
+		Updates(budget).Error
+}
+
+func (r *ProjectBudgetRepository) DeleteByUserAndProject(userId, projectKey string) error {
+	return r.db.
+		Where("user_id = ?", userId).
+		Where("project_key = ?", projectKey).
+		Delete(models.ProjectBudget{}).Error
+}
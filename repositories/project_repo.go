@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectRepoRepository struct {
+	config *config.Config
+	db     *gorm.DB
+}
+
+func NewProjectRepoRepository(db *gorm.DB) *ProjectRepoRepository {
+	return &ProjectRepoRepository{config: config.Get(), db: db}
+}
+
+func (r *ProjectRepoRepository) GetByUser(userId string) ([]*models.ProjectRepo, error) {
+	if userId == "" {
+		return []*models.ProjectRepo{}, nil
+	}
+	var repos []*models.ProjectRepo
+	if err := r.db.
+		Where(&models.ProjectRepo{UserID: userId}).
+		Order("project_key asc").
+		Find(&repos).Error; err != nil {
+		return repos, err
+	}
+	return repos, nil
+}
+
+func (r *ProjectRepoRepository) Upsert(repo *models.ProjectRepo) (*models.ProjectRepo, error) {
+	if !repo.IsValid() {
+		return nil, errors.New("invalid repository")
+	}
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "project_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"url"}),
+	}).Create(repo)
+	if err := result.Error; err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+func (r *ProjectRepoRepository) DeleteByUserAndProject(userId, projectKey string) error {
+	return r.db.
+		Where("user_id = ?", userId).
+		Where("project_key = ?", projectKey).
+		Delete(models.ProjectRepo{}).Error
+}
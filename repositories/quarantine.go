@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type QuarantineRepository struct {
+	db *gorm.DB
+}
+
+func NewQuarantineRepository(db *gorm.DB) *QuarantineRepository {
+	return &QuarantineRepository{db: db}
+}
+
+func (r *QuarantineRepository) GetAll() ([]*models.Quarantine, error) {
+	var quarantines []*models.Quarantine
+	if err := r.db.Order("created_at asc").Find(&quarantines).Error; err != nil {
+		return nil, err
+	}
+	return quarantines, nil
+}
+
+func (r *QuarantineRepository) GetById(id uint) (*models.Quarantine, error) {
+	quarantine := &models.Quarantine{}
+	if err := r.db.Where(&models.Quarantine{ID: id}).First(quarantine).Error; err != nil {
+		return nil, err
+	}
+	return quarantine, nil
+}
+
+// GetLatestByUserAndReason returns the user's most recent quarantine for the given reason or nil, if there is none
+func (r *QuarantineRepository) GetLatestByUserAndReason(userId, reason string) (*models.Quarantine, error) {
+	quarantine := &models.Quarantine{}
+	if err := r.db.
+		Where(&models.Quarantine{UserID: userId, Reason: reason}).
+		Order("end_time desc").
+		First(quarantine).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return quarantine, nil
+}
+
+func (r *QuarantineRepository) CountByUser(userId string) (int64, error) {
+	var count int64
+	if err := r.db.
+		Model(&models.Quarantine{}).
+		Where(&models.Quarantine{UserID: userId}).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *QuarantineRepository) Upsert(quarantine *models.Quarantine) (*models.Quarantine, error) {
+	if err := r.db.Save(quarantine).Error; err != nil {
+		return nil, err
+	}
+	return quarantine, nil
+}
+
+func (r *QuarantineRepository) Delete(id uint) error {
+	return r.db.Where(&models.Quarantine{ID: id}).Delete(models.Quarantine{}).Error
+}
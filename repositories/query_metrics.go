@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	queryMetricsPackage  = "github.com/muety/wakapi/repositories."
+	queryMetricsStartKey = "wakapi:query_metrics_start"
+	queryMetricsUnknown  = "other" // queries not issued by any repository, e.g. migrations
+)
+
+// QueryStats sums up all queries issued by a single repository method
+type QueryStats struct {
+	Method   string // e.g. HeartbeatRepository.GetAllWithin
+	Count    int64
+	Rows     int64 // rows returned or affected
+	Duration time.Duration
+}
+
+// QueryMetrics is a gorm plugin, which measures every query and attributes it to the repository method it was issued by
+type QueryMetrics struct {
+	lock  *sync.Mutex
+	stats map[string]*QueryStats
+}
+
+func NewQueryMetrics() *QueryMetrics {
+	return &QueryMetrics{
+		lock:  &sync.Mutex{},
+		stats: make(map[string]*QueryStats),
+	}
+}
+
+func (m *QueryMetrics) Name() string {
+	return "wakapi:query_metrics"
+}
+
+func (m *QueryMetrics) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	errs := []error{
+		cb.Create().Before("gorm:create").Register(m.Name()+"_before_create", m.before),
+		cb.Create().After("gorm:create").Register(m.Name()+"_after_create", m.after),
+		cb.Query().Before("gorm:query").Register(m.Name()+"_before_query", m.before),
+		cb.Query().After("gorm:query").Register(m.Name()+"_after_query", m.after),
+		cb.Update().Before("gorm:update").Register(m.Name()+"_before_update", m.before),
+		cb.Update().After("gorm:update").Register(m.Name()+"_after_update", m.after),
+		cb.Delete().Before("gorm:delete").Register(m.Name()+"_before_delete", m.before),
+		cb.Delete().After("gorm:delete").Register(m.Name()+"_after_delete", m.after),
+		cb.Row().Before("gorm:row").Register(m.Name()+"_before_row", m.before),
+		cb.Row().After("gorm:row").Register(m.Name()+"_after_row", m.after),
+		cb.Raw().Before("gorm:raw").Register(m.Name()+"_before_raw", m.before),
+		cb.Raw().After("gorm:raw").Register(m.Name()+"_after_raw", m.after),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot returns a copy of the stats of all repository methods seen so far, sorted by method
+func (m *QueryMetrics) Snapshot() []*QueryStats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	stats := make([]*QueryStats, 0, len(m.stats))
+	for _, s := range m.stats {
+		statsCopy := *s
+		stats = append(stats, &statsCopy)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
+
+func (m *QueryMetrics) before(db *gorm.DB) {
+	db.InstanceSet(queryMetricsStartKey, time.Now())
+}
+
+func (m *QueryMetrics) after(db *gorm.DB) {
+	start, ok := db.InstanceGet(queryMetricsStartKey)
+	if !ok {
+		return
+	}
+	m.record(callingMethod(), db.Statement.RowsAffected, time.Since(start.(time.Time)))
+}
+
+func (m *QueryMetrics) record(method string, rows int64, duration time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	s, ok := m.stats[method]
+	if !ok {
+		s = &QueryStats{Method: method}
+		m.stats[method] = s
+	}
+	s.Count++
+	s.Rows += rows
+	s.Duration += duration
+}
+
+// callingMethod walks up the stack to find the innermost repository method, the current query was issued from
+func callingMethod() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if method := repositoryMethod(frame.Function); method != "" {
+			return method
+		}
+		if !more {
+			return queryMetricsUnknown
+		}
+	}
+}
+
+// repositoryMethod turns a qualified function name like "github.com/muety/wakapi/repositories.(*HeartbeatRepository).InsertBatch.func1"
+// into "HeartbeatRepository.InsertBatch" or returns an empty string, if it is not a repository's method
+func repositoryMethod(function string) string {
+	if !strings.HasPrefix(function, queryMetricsPackage) {
+		return ""
+	}
+	name := strings.TrimPrefix(function, queryMetricsPackage)
+	parts := strings.Split(name, ".")
+	if len(parts) < 2 || !strings.HasSuffix(parts[0], "Repository)") {
+		return ""
+	}
+	return strings.Trim(parts[0], "(*)") + "." + parts[1]
+}
@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+)
+
+func TestRepositoryMethod(t *testing.T) {
+	assert.Equal(t, "HeartbeatRepository.GetAllWithin", repositoryMethod("github.com/muety/wakapi/repositories.(*HeartbeatRepository).GetAllWithin"))
+	assert.Equal(t, "HeartbeatRepository.InsertBatch", repositoryMethod("github.com/muety/wakapi/repositories.(*HeartbeatRepository).InsertBatch.func1"))
+	assert.Empty(t, repositoryMethod("github.com/muety/wakapi/repositories.(*QueryMetrics).after"))
+	assert.Empty(t, repositoryMethod("github.com/muety/wakapi/repositories.callingMethod"))
+	assert.Empty(t, repositoryMethod("github.com/muety/wakapi/services.(*HeartbeatService).Count"))
+}
+
+func TestQueryMetrics(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	assert.Nil(t, err)
+	sqlDb, _ := db.DB()
+	sqlDb.SetMaxOpenConns(1)
+	defer sqlDb.Close()
+
+	assert.Nil(t, db.AutoMigrate(&models.User{}))
+
+	sut := NewQueryMetrics()
+	assert.Nil(t, db.Use(sut))
+
+	userRepository := NewUserRepository(db)
+	userRepository.InsertOrGet(&models.User{ID: "user1", ApiKey: "api-key-1"}) // api keys are unique
+	userRepository.InsertOrGet(&models.User{ID: "user2", ApiKey: "api-key-2"})
+	userRepository.GetAll()
+	userRepository.GetAll()
+	db.Exec("select 1")
+
+	stats := make(map[string]*QueryStats)
+	for _, s := range sut.Snapshot() {
+		stats[s.Method] = s
+	}
+
+	assert.Contains(t, stats, "UserRepository.InsertOrGet")
+	assert.Contains(t, stats, queryMetricsUnknown)
+	assert.Equal(t, int64(2), stats["UserRepository.GetAll"].Count)
+	assert.Equal(t, int64(4), stats["UserRepository.GetAll"].Rows)
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type RelayRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewRelayRuleRepository(db *gorm.DB) *RelayRuleRepository {
+	return &RelayRuleRepository{db: db}
+}
+
+func (r *RelayRuleRepository) GetById(id uint) (*models.RelayRule, error) {
+	rule := &models.RelayRule{}
+	if err := r.db.Where(&models.RelayRule{ID: id}).First(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *RelayRuleRepository) GetByUser(userId string) ([]*models.RelayRule, error) {
+	var rules []*models.RelayRule
+	if err := r.db.
+		Where(&models.RelayRule{UserID: userId}).
+		Order("id asc").
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *RelayRuleRepository) Insert(rule *models.RelayRule) (*models.RelayRule, error) {
+	if !rule.IsValid() {
+		return nil, errors.New("invalid relay rule")
+	}
+	if err := r.db.Create(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (r *RelayRuleRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.RelayRule{}).Error
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type RelayTargetRepository struct {
+	db *gorm.DB
+}
+
+func NewRelayTargetRepository(db *gorm.DB) *RelayTargetRepository {
+	return &RelayTargetRepository{db: db}
+}
+
+func (r *RelayTargetRepository) GetById(id uint) (*models.RelayTarget, error) {
+	target := &models.RelayTarget{}
+	if err := r.db.Where(&models.RelayTarget{ID: id}).First(target).Error; err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (r *RelayTargetRepository) GetByUser(userId string) ([]*models.RelayTarget, error) {
+	var targets []*models.RelayTarget
+	if err := r.db.
+		Where(&models.RelayTarget{UserID: userId}).
+		Order("id asc").
+		Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func (r *RelayTargetRepository) Insert(target *models.RelayTarget) (*models.RelayTarget, error) {
+	if !target.IsValid() {
+		return nil, errors.New("invalid relay target")
+	}
+	if err := r.db.Create(target).Error; err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (r *RelayTargetRepository) UpdateEnabled(target *models.RelayTarget, enabled bool) (*models.RelayTarget, error) {
+	if err := r.db.Model(target).Update("enabled", enabled).Error; err != nil {
+		return nil, err
+	}
+	target.Enabled = enabled
+	return target, nil
+}
+
+func (r *RelayTargetRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.RelayTarget{}).Error
+}
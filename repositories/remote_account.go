@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+type RemoteAccountRepository struct {
+	db *gorm.DB
+}
+
+func NewRemoteAccountRepository(db *gorm.DB) *RemoteAccountRepository {
+	return &RemoteAccountRepository{db: db}
+}
+
+func (r *RemoteAccountRepository) GetById(id uint) (*models.RemoteAccount, error) {
+	account := &models.RemoteAccount{}
+	if err := r.db.Where(&models.RemoteAccount{ID: id}).First(account).Error; err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (r *RemoteAccountRepository) GetByUser(userId string) ([]*models.RemoteAccount, error) {
+	var accounts []*models.RemoteAccount
+	if err := r.db.
+		Where(&models.RemoteAccount{UserID: userId}).
+		Order("id asc").
+		Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func (r *RemoteAccountRepository) Insert(account *models.RemoteAccount) (*models.RemoteAccount, error) {
+	if !account.IsValid() {
+		return nil, errors.New("invalid remote account")
+	}
+	if err := r.db.Create(account).Error; err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (r *RemoteAccountRepository) UpdateEnabled(account *models.RemoteAccount, enabled bool) (*models.RemoteAccount, error) {
+	if err := r.db.Model(account).Update("enabled", enabled).Error; err != nil {
+		return nil, err
+	}
+	account.Enabled = enabled
+	return account, nil
+}
+
+func (r *RemoteAccountRepository) Delete(id uint) error {
+	return r.db.
+		Where("id = ?", id).
+		Delete(models.RemoteAccount{}).Error
+}
@@ -14,21 +14,51 @@ type IAliasRepository interface {
 	GetByUserAndKey(string, string) ([]*models.Alias, error)
 	GetByUserAndKeyAndType(string, string, uint8) ([]*models.Alias, error)
 	GetByUserAndTypeAndValue(string, uint8, string) (*models.Alias, error)
+	GetOrphaned() ([]*models.Alias, error)
 }
 
 type IHeartbeatRepository interface {
 	InsertBatch([]*models.Heartbeat) error
 	GetAll() ([]*models.Heartbeat, error)
+	GetById(uint64) (*models.Heartbeat, error)
+	Update(*models.Heartbeat) (*models.Heartbeat, error)
+	CountBySelection(*models.User, *models.HeartbeatSelection) (int64, error)
+	GetPageBySelection(*models.User, *models.HeartbeatSelection, uint64, int) ([]*models.Heartbeat, error)
+	DeleteByIds(*models.User, []uint64) error
 	GetAllWithin(time.Time, time.Time, *models.User) ([]*models.Heartbeat, error)
+	GetAllWithinOrdered(time.Time, time.Time, *models.User, *models.Ordering) ([]*models.Heartbeat, error)
+	GetAllWithinPage(time.Time, time.Time, *models.User, *models.HeartbeatCursor, int) ([]*models.Heartbeat, error)
 	GetFirstByUsers() ([]*models.TimeByUser, error)
 	GetLastByUsers() ([]*models.TimeByUser, error)
+	GetFirstByUser(*models.User) (*models.Heartbeat, error)
 	GetLatestByUser(*models.User) (*models.Heartbeat, error)
 	GetLatestByOriginAndUser(string, *models.User) (*models.Heartbeat, error)
+	GetLatestByMachineOriginAndUser(string, string, *models.User) (*models.Heartbeat, error)
 	Count() (int64, error)
 	CountByUser(*models.User) (int64, error)
 	CountByUsers([]*models.User) ([]*models.CountByUser, error)
 	GetEntitySetByUser(uint8, *models.User) ([]string, error)
+	GetProjectActivityByUser(*models.User, *models.Ordering) ([]*models.ProjectActivity, error)
+	GetMachineActivityByUser(*models.User) ([]*models.MachineActivity, error)
+	GetOriginActivityByUser(*models.User) ([]*models.OriginActivity, error)
 	DeleteBefore(time.Time) error
+	DeleteOldestByUser(*models.User, int64) (int64, error)
+}
+
+type ICalendarEventRepository interface {
+	GetByUserWithin(string, time.Time, time.Time) ([]*models.CalendarEvent, error)
+	Upsert(*models.CalendarEvent) (*models.CalendarEvent, error)
+	DeleteByUser(string) error
+}
+
+type IJiraWorklogRepository interface {
+	GetByUser(string, int) ([]*models.JiraWorklog, error)
+	GetByUserWithin(string, string, string) ([]*models.JiraWorklog, error)
+	Upsert(*models.JiraWorklog) (*models.JiraWorklog, error)
+}
+
+type IBackupRepository interface {
+	DumpTo(string) error
 }
 
 type IDiagnosticsRepository interface {
@@ -58,12 +88,144 @@ type IProjectLabelRepository interface {
 	Delete(uint) error
 }
 
+type IAchievementRepository interface {
+	GetByUser(string) ([]*models.Achievement, error)
+	InsertBatch([]*models.Achievement) error
+}
+
+type IDayOffRepository interface {
+	GetByUser(string) ([]*models.DayOff, error)
+	UpsertBatch([]*models.DayOff) error
+	DeleteByUserWithin(string, string, string) error
+}
+
+type IProjectRepoRepository interface {
+	GetByUser(string) ([]*models.ProjectRepo, error)
+	Upsert(*models.ProjectRepo) (*models.ProjectRepo, error)
+	DeleteByUserAndProject(string, string) error
+}
+
+type IProjectBudgetRepository interface {
+	GetAll() ([]*models.ProjectBudget, error)
+	GetByUser(string) ([]*models.ProjectBudget, error)
+	Upsert(*models.ProjectBudget) (*models.ProjectBudget, error)
+	UpdateNotified(*models.ProjectBudget) error
+	DeleteByUserAndProject(string, string) error
+}
+
+type IRelayTargetRepository interface {
+	GetById(uint) (*models.RelayTarget, error)
+	GetByUser(string) ([]*models.RelayTarget, error)
+	Insert(*models.RelayTarget) (*models.RelayTarget, error)
+	UpdateEnabled(*models.RelayTarget, bool) (*models.RelayTarget, error)
+	Delete(uint) error
+}
+
+type IRemoteAccountRepository interface {
+	GetById(uint) (*models.RemoteAccount, error)
+	GetByUser(string) ([]*models.RemoteAccount, error)
+	Insert(*models.RemoteAccount) (*models.RemoteAccount, error)
+	UpdateEnabled(*models.RemoteAccount, bool) (*models.RemoteAccount, error)
+	Delete(uint) error
+}
+
+type IRelayRuleRepository interface {
+	GetById(uint) (*models.RelayRule, error)
+	GetByUser(string) ([]*models.RelayRule, error)
+	Insert(*models.RelayRule) (*models.RelayRule, error)
+	Delete(uint) error
+}
+
+type INotificationPreferenceRepository interface {
+	GetByUser(string) ([]*models.NotificationPreference, error)
+	Upsert(*models.NotificationPreference) (*models.NotificationPreference, error)
+}
+
+type IGoalRepository interface {
+	GetById(uint) (*models.Goal, error)
+	GetByUser(string) ([]*models.Goal, error)
+	Insert(*models.Goal) (*models.Goal, error)
+	Delete(uint) error
+}
+
+type IArchivedReportRepository interface {
+	GetById(uint) (*models.ArchivedReport, error)
+	GetByUser(string) ([]*models.ArchivedReport, error)
+	Insert(*models.ArchivedReport) (*models.ArchivedReport, error)
+}
+
+type ITombstoneRepository interface {
+	GetById(uint) (*models.Tombstone, error)
+	GetByUser(string) ([]*models.Tombstone, error)
+	GetItemsPage(uint, uint64, int) ([]*models.TombstoneItem, error)
+	Insert(*models.Tombstone) (*models.Tombstone, error)
+	InsertItems(*models.Tombstone, []*models.TombstoneItem) error
+	Delete(uint) error
+	DeleteExpired(time.Time) error
+}
+
+type ILeaderboardRepository interface {
+	GetAll() ([]*models.LeaderboardItem, error)
+	ReplaceAll([]*models.LeaderboardItem) error
+}
+
+type IQuarantineRepository interface {
+	GetAll() ([]*models.Quarantine, error)
+	GetById(uint) (*models.Quarantine, error)
+	GetLatestByUserAndReason(string, string) (*models.Quarantine, error)
+	CountByUser(string) (int64, error)
+	Upsert(*models.Quarantine) (*models.Quarantine, error)
+	Delete(uint) error
+}
+
+type IDurationRepository interface {
+	GetAllWithin(time.Time, time.Time, *models.User) ([]*models.Duration, error)
+	GetDaysWithin(time.Time, time.Time, *models.User) ([]*models.DurationDay, error)
+	ReplaceDay(*models.User, time.Time, string, []*models.Duration) error
+	DeleteDaysByUser(string) error
+}
+
+type IFilterSetRepository interface {
+	GetByUser(string) ([]*models.FilterSet, error)
+	GetByUserAndName(string, string) (*models.FilterSet, error)
+	Insert(*models.FilterSet) (*models.FilterSet, error)
+	DeleteByUserAndName(string, string) error
+}
+
+type IEmbedTokenRepository interface {
+	GetByUser(string) ([]*models.EmbedToken, error)
+	GetByToken(string) (*models.EmbedToken, error)
+	Insert(*models.EmbedToken) (*models.EmbedToken, error)
+	DeleteByUserAndId(string, uint) (int64, error)
+}
+
+type IManualTimeEntryRepository interface {
+	GetAll() ([]*models.ManualTimeEntry, error)
+	GetById(uint) (*models.ManualTimeEntry, error)
+	GetByUser(string) ([]*models.ManualTimeEntry, error)
+	GetByUserWithin(string, time.Time, time.Time) ([]*models.ManualTimeEntry, error)
+	Insert(*models.ManualTimeEntry) (*models.ManualTimeEntry, error)
+	Delete(uint) error
+}
+
 type ISummaryRepository interface {
 	Insert(*models.Summary) error
+	InsertBatch([]*models.Summary) error
 	GetAll() ([]*models.Summary, error)
 	GetByUserWithin(*models.User, time.Time, time.Time) ([]*models.Summary, error)
+	GetByUsersWithin([]*models.User, time.Time, time.Time) ([]*models.Summary, error)
 	GetLastByUser() ([]*models.TimeByUser, error)
+	GetOrphanedIds() ([]uint, error)
 	DeleteByUser(string) error
+	DeleteByUserWithin(string, time.Time, time.Time) error
+	ReplaceByUserWithin(string, time.Time, time.Time, []*models.Summary) error
+	DeleteByIds([]uint) error
+}
+
+type ISummaryInvalidationRepository interface {
+	GetAll() ([]*models.SummaryInvalidation, error)
+	InsertBatch([]*models.SummaryInvalidation) error
+	DeleteBatchMarkedBefore([]uint, time.Time) error
 }
 
 type IUserRepository interface {
@@ -81,4 +243,5 @@ type IUserRepository interface {
 	Update(*models.User) (*models.User, error)
 	UpdateField(*models.User, string, interface{}) (*models.User, error)
 	Delete(*models.User) error
+	Merge(*models.UserMerge) (*models.UserMergeReport, error)
 }
@@ -23,6 +23,9 @@ func (r *SummaryRepository) GetAll() ([]*models.Summary, error) {
 		Preload("Editors", "type = ?", models.SummaryEditor).
 		Preload("OperatingSystems", "type = ?", models.SummaryOS).
 		Preload("Machines", "type = ?", models.SummaryMachine).
+		Preload("Tickets", "type = ?", models.SummaryTicket).
+		Preload("Origins", "type = ?", models.SummaryOrigin).
+		Preload("Domains", "type = ?", models.SummaryDomain).
 		// branch summaries are currently not persisted, as only relevant in combination with project filter
 		Find(&summaries).Error; err != nil {
 		return nil, err
@@ -37,6 +40,16 @@ func (r *SummaryRepository) Insert(summary *models.Summary) error {
 	return nil
 }
 
+func (r *SummaryRepository) InsertBatch(summaries []*models.Summary) error {
+	if len(summaries) == 0 {
+		return nil
+	}
+	if err := r.db.Create(&summaries).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
 func (r *SummaryRepository) GetByUserWithin(user *models.User, from, to time.Time) ([]*models.Summary, error) {
 	var summaries []*models.Summary
 	if err := r.db.
@@ -49,6 +62,42 @@ func (r *SummaryRepository) GetByUserWithin(user *models.User, from, to time.Tim
 		Preload("Editors", "type = ?", models.SummaryEditor).
 		Preload("OperatingSystems", "type = ?", models.SummaryOS).
 		Preload("Machines", "type = ?", models.SummaryMachine).
+		Preload("Tickets", "type = ?", models.SummaryTicket).
+		Preload("Origins", "type = ?", models.SummaryOrigin).
+		Preload("Domains", "type = ?", models.SummaryDomain).
+		// branch summaries are currently not persisted, as only relevant in combination with project filter
+		Find(&summaries).Error; err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// GetByUsersWithin fetches the summaries of multiple users at once, ordered by user and time
+func (r *SummaryRepository) GetByUsersWithin(users []*models.User, from, to time.Time) ([]*models.Summary, error) {
+	var summaries []*models.Summary
+	if len(users) == 0 {
+		return summaries, nil
+	}
+
+	userIds := make([]string, len(users))
+	for i, u := range users {
+		userIds[i] = u.ID
+	}
+
+	if err := r.db.
+		Where("user_id IN ?", userIds).
+		Where("from_time >= ?", from.Local()).
+		Where("to_time <= ?", to.Local()).
+		Order("user_id asc").
+		Order("from_time asc").
+		Preload("Projects", "type = ?", models.SummaryProject).
+		Preload("Languages", "type = ?", models.SummaryLanguage).
+		Preload("Editors", "type = ?", models.SummaryEditor).
+		Preload("OperatingSystems", "type = ?", models.SummaryOS).
+		Preload("Machines", "type = ?", models.SummaryMachine).
+		Preload("Tickets", "type = ?", models.SummaryTicket).
+		Preload("Origins", "type = ?", models.SummaryOrigin).
+		Preload("Domains", "type = ?", models.SummaryDomain).
 		// branch summaries are currently not persisted, as only relevant in combination with project filter
 		Find(&summaries).Error; err != nil {
 		return nil, err
@@ -66,6 +115,18 @@ func (r *SummaryRepository) GetLastByUser() ([]*models.TimeByUser, error) {
 	return result, nil
 }
 
+// GetOrphanedIds returns the ids of all summaries, whose user does not exist anymore
+func (r *SummaryRepository) GetOrphanedIds() ([]uint, error) {
+	var ids []uint
+	if err := r.db.
+		Model(&models.Summary{}).
+		Where("user_id NOT IN (?)", r.db.Model(&models.User{}).Select("id")).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
 func (r *SummaryRepository) DeleteByUser(userId string) error {
 	if err := r.db.
 		Where("user_id = ?", userId).
@@ -74,3 +135,42 @@ func (r *SummaryRepository) DeleteByUser(userId string) error {
 	}
 	return nil
 }
+
+func (r *SummaryRepository) DeleteByUserWithin(userId string, from, to time.Time) error {
+	var ids []uint
+	if err := r.db.
+		Model(&models.Summary{}).
+		Where("user_id = ?", userId).
+		Where("from_time >= ?", from.Local()).
+		Where("to_time <= ?", to.Local()).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	return r.DeleteByIds(ids)
+}
+
+// ReplaceByUserWithin deletes the user's summaries within the given range and inserts the given ones instead in a single transaction
+func (r *SummaryRepository) ReplaceByUserWithin(userId string, from, to time.Time, summaries []*models.Summary) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := NewSummaryRepository(tx)
+		if err := txRepo.DeleteByUserWithin(userId, from, to); err != nil {
+			return err
+		}
+		return txRepo.InsertBatch(summaries)
+	})
+}
+
+func (r *SummaryRepository) DeleteByIds(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	// summary items are deleted explicitly, because foreign key constraints are not necessarily enforced (e.g. with sqlite)
+	if err := r.db.
+		Where("summary_id IN ?", ids).
+		Delete(models.SummaryItem{}).Error; err != nil {
+		return err
+	}
+	return r.db.
+		Where("id IN ?", ids).
+		Delete(models.Summary{}).Error
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type SummaryInvalidationRepository struct {
+	db *gorm.DB
+}
+
+func NewSummaryInvalidationRepository(db *gorm.DB) *SummaryInvalidationRepository {
+	return &SummaryInvalidationRepository{db: db}
+}
+
+func (r *SummaryInvalidationRepository) GetAll() ([]*models.SummaryInvalidation, error) {
+	var invalidations []*models.SummaryInvalidation
+	if err := r.db.Order("day asc").Find(&invalidations).Error; err != nil {
+		return nil, err
+	}
+	return invalidations, nil
+}
+
+func (r *SummaryInvalidationRepository) InsertBatch(invalidations []*models.SummaryInvalidation) error {
+	return insertInvalidations(r.db, invalidations)
+}
+
+// DeleteBatchMarkedBefore deletes the given markers, unless they were renewed at or after the given time
+func (r *SummaryInvalidationRepository) DeleteBatchMarkedBefore(ids []uint, t time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.
+		Where("id IN ?", ids).
+		Where("marked_at < ?", t.Local()).
+		Delete(models.SummaryInvalidation{}).Error
+}
+
+// invalidateDays marks all past, server-local days of the given times for the user's summaries to be recomputed.
+// It is called by other repositories from within the same transaction as the change to the heartbeats, so that no change can get lost.
+func invalidateDays(db *gorm.DB, userId string, times []models.CustomTime) error {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	seen := make(map[time.Time]bool)
+	invalidations := make([]*models.SummaryInvalidation, 0)
+	for _, ct := range times {
+		t := ct.T().In(time.Local)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		if !day.Before(today) || seen[day] {
+			continue
+		}
+		seen[day] = true
+		invalidations = append(invalidations, &models.SummaryInvalidation{UserID: userId, Day: models.CustomTime(day), MarkedAt: models.CustomTime(now)})
+	}
+	return insertInvalidations(db, invalidations)
+}
+
+func insertInvalidations(db *gorm.DB, invalidations []*models.SummaryInvalidation) error {
+	if len(invalidations) == 0 {
+		return nil
+	}
+	// days already marked as invalid only have their marker time renewed
+	return db.
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "day"}},
+			DoUpdates: clause.AssignmentColumns([]string{"marked_at"}),
+		}).
+		Create(&invalidations).Error
+}
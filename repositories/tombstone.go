@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"gorm.io/gorm"
+)
+
+// number of tombstone items to insert at once
+const tombstoneItemBatchSize = 1000
+
+type TombstoneRepository struct {
+	db *gorm.DB
+}
+
+func NewTombstoneRepository(db *gorm.DB) *TombstoneRepository {
+	return &TombstoneRepository{db: db}
+}
+
+func (r *TombstoneRepository) GetById(id uint) (*models.Tombstone, error) {
+	tombstone := &models.Tombstone{}
+	if err := r.db.Where(&models.Tombstone{ID: id}).First(tombstone).Error; err != nil {
+		return nil, err
+	}
+	return tombstone, nil
+}
+
+// GetByUser returns all of the user's tombstones, which did not expire yet, latest first
+func (r *TombstoneRepository) GetByUser(userId string) ([]*models.Tombstone, error) {
+	var tombstones []*models.Tombstone
+	if err := r.db.
+		Where(&models.Tombstone{UserID: userId}).
+		Where("expires_at > ?", time.Now().Local()).
+		Order("created_at desc").
+		Find(&tombstones).Error; err != nil {
+		return nil, err
+	}
+	return tombstones, nil
+}
+
+// GetItemsPage returns at most limit of the tombstone's items with an id greater than afterId, ordered by id
+func (r *TombstoneRepository) GetItemsPage(tombstoneId uint, afterId uint64, limit int) ([]*models.TombstoneItem, error) {
+	var items []*models.TombstoneItem
+	if err := r.db.
+		Where(&models.TombstoneItem{TombstoneID: tombstoneId}).
+		Where("id > ?", afterId).
+		Order("id asc").
+		Limit(limit).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *TombstoneRepository) Insert(tombstone *models.Tombstone) (*models.Tombstone, error) {
+	if err := r.db.Create(tombstone).Error; err != nil {
+		return nil, err
+	}
+	return tombstone, nil
+}
+
+// InsertItems adds the given items to their tombstone and increments its number of affected heartbeats accordingly
+func (r *TombstoneRepository) InsertItems(tombstone *models.Tombstone, items []*models.TombstoneItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(items, tombstoneItemBatchSize).Error; err != nil {
+			return err
+		}
+		return tx.
+			Model(tombstone).
+			UpdateColumn("num_heartbeats", gorm.Expr("num_heartbeats + ?", len(items))).Error
+	})
+}
+
+func (r *TombstoneRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&models.TombstoneItem{TombstoneID: id}).Delete(models.TombstoneItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Where(&models.Tombstone{ID: id}).Delete(models.Tombstone{}).Error
+	})
+}
+
+// DeleteExpired permanently drops all tombstones (and the heartbeat copies they hold) expired before the given time
+func (r *TombstoneRepository) DeleteExpired(t time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&models.Tombstone{}).Select("id").Where("expires_at <= ?", t.Local())
+		if err := tx.Where("tombstone_id IN (?)", expired).Delete(models.TombstoneItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("expires_at <= ?", t.Local()).Delete(models.Tombstone{}).Error
+	})
+}
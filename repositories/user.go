@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"errors"
+	"fmt"
 	"github.com/muety/wakapi/models"
 	"gorm.io/gorm"
 	"time"
@@ -140,23 +141,51 @@ func (r *UserRepository) InsertOrGet(user *models.User) (*models.User, bool, err
 
 func (r *UserRepository) Update(user *models.User) (*models.User, error) {
 	updateMap := map[string]interface{}{
-		"api_key":             user.ApiKey,
-		"password":            user.Password,
-		"email":               user.Email,
-		"last_logged_in_at":   user.LastLoggedInAt,
-		"share_data_max_days": user.ShareDataMaxDays,
-		"share_editors":       user.ShareEditors,
-		"share_languages":     user.ShareLanguages,
-		"share_oss":           user.ShareOSs,
-		"share_projects":      user.ShareProjects,
-		"share_machines":      user.ShareMachines,
-		"share_labels":        user.ShareLabels,
-		"wakatime_api_key":    user.WakatimeApiKey,
-		"wakatime_api_url":    user.WakatimeApiUrl,
-		"has_data":            user.HasData,
-		"reset_token":         user.ResetToken,
-		"location":            user.Location,
-		"reports_weekly":      user.ReportsWeekly,
+		"api_key":                   user.ApiKey,
+		"password":                  user.Password,
+		"email":                     user.Email,
+		"last_logged_in_at":         user.LastLoggedInAt,
+		"share_data_max_days":       user.ShareDataMaxDays,
+		"share_editors_days":        user.ShareEditorsDays,
+		"share_languages_days":      user.ShareLanguagesDays,
+		"share_oss_days":            user.ShareOSsDays,
+		"share_projects_days":       user.ShareProjectsDays,
+		"share_machines_days":       user.ShareMachinesDays,
+		"share_labels_days":         user.ShareLabelsDays,
+		"wakatime_api_key":          user.WakatimeApiKey,
+		"wakatime_api_url":          user.WakatimeApiUrl,
+		"has_data":                  user.HasData,
+		"reset_token":               user.ResetToken,
+		"location":                  user.Location,
+		"reports_weekly":            user.ReportsWeekly,
+		"heartbeats_max_past_days":  user.HeartbeatsMaxPastDays,
+		"heartbeats_max_future_min": user.HeartbeatsMaxFutureMin,
+		"import_scope_until":        user.ImportScopeUntil,
+		"duration_strategy":         user.DurationStrategy,
+		"heartbeat_script":          user.HeartbeatScript,
+		"avatar_key":                user.AvatarKey,
+		"jira_url":                  user.JiraUrl,
+		"jira_email":                user.JiraEmail,
+		"jira_api_token":            user.JiraApiToken,
+		"gcal_refresh_token":        user.GcalRefreshToken,
+		"gcal_calendar_id":          user.GcalCalendarId,
+		"gcal_enabled":              user.GcalEnabled,
+		"gcal_event_titles":         user.GcalEventTitles,
+		"workday_target_min":        user.WorkdayTargetMin,
+		"workdays":                  user.Workdays,
+		"work_target_since":         user.WorkTargetSince,
+		"exclude_browsing":          user.ExcludeBrowsing,
+		"dashboard_widgets":         user.DashboardWidgets,
+		"dashboard_range":           user.DashboardRange,
+		"locale":                    user.Locale,
+		"notification_webhook_url":  user.NotificationWebhookUrl,
+		"slack_webhook_url":         user.SlackWebhookUrl,
+		"telegram_chat_id":          user.TelegramChatId,
+		"inactivity_alert_days":     user.InactivityAlertDays,
+		"deactivated":               user.Deactivated,
+		"external_id":               user.ExternalId,
+		"heartbeats_quota":          user.HeartbeatsQuota,
+		"clock_skew_correction":     user.ClockSkewCorrection,
 	}
 
 	result := r.db.Model(user).Updates(updateMap)
@@ -183,3 +212,384 @@ func (r *UserRepository) UpdateField(user *models.User, key string, value interf
 func (r *UserRepository) Delete(user *models.User) error {
 	return r.db.Delete(user).Error
 }
+
+// number of heartbeats moved at once when merging users
+const mergePageSize = 1000
+
+var errMergeDryRun = errors.New("dry run")
+
+// records of a user, which are reported, if left with the source of a merge and deleted along with it
+var mergeReportedModels = []struct {
+	name  string
+	model interface{}
+}{
+	{"heartbeats", &models.Heartbeat{}},
+	{"aliases", &models.Alias{}},
+	{"language_mappings", &models.LanguageMapping{}},
+	{"project_labels", &models.ProjectLabel{}},
+	{"manual_time_entries", &models.ManualTimeEntry{}},
+	{"goals", &models.Goal{}},
+	{"project_budgets", &models.ProjectBudget{}},
+	{"days_off", &models.DayOff{}},
+	{"filter_sets", &models.FilterSet{}},
+	{"project_repos", &models.ProjectRepo{}},
+	{"relay_targets", &models.RelayTarget{}},
+	{"relay_rules", &models.RelayRule{}},
+	{"remote_accounts", &models.RemoteAccount{}},
+	{"notification_preferences", &models.NotificationPreference{}},
+	{"quarantined_heartbeats", &models.Quarantine{}},
+	{"archived_reports", &models.ArchivedReport{}},
+	{"jira_worklogs", &models.JiraWorklog{}},
+	{"calendar_events", &models.CalendarEvent{}},
+	{"achievements", &models.Achievement{}},
+	{"tombstones", &models.Tombstone{}},
+}
+
+// Merge moves the heartbeats, summaries, aliases, language mappings, project labels, embed tokens, manual time entries, goals, project budgets, days off, filter sets and
+// project repositories of one user to another one and then deletes it, all within a single transaction. Heartbeats present in both accounts are dropped, as are summaries
+// overlapping one of the target's, whose days are recomputed from the merged heartbeats instead, and settings the target has its own of. Integration credentials are taken over,
+// unless the target has its own, and the api key, if requested. Everything else, e.g. relay targets, is deleted along with the source and reported per kind of record.
+// A dry run is rolled back in the end, so that its report tells exactly what would be done.
+func (r *UserRepository) Merge(merge *models.UserMerge) (*models.UserMergeReport, error) {
+	report := &models.UserMergeReport{SourceId: merge.SourceId, TargetId: merge.TargetId, DryRun: merge.DryRun, Credentials: []string{}}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		source, target := &models.User{}, &models.User{}
+		if err := tx.Where(&models.User{ID: merge.SourceId}).First(source).Error; err != nil {
+			return err
+		}
+		if err := tx.Where(&models.User{ID: merge.TargetId}).First(target).Error; err != nil {
+			return err
+		}
+
+		if err := mergeHeartbeats(tx, source, target, report); err != nil {
+			return err
+		}
+		if err := mergeSummaries(tx, source, target, report); err != nil {
+			return err
+		}
+		if err := mergeSettings(tx, source, target, report); err != nil {
+			return err
+		}
+		if err := mergeRecords(tx, source, target, report); err != nil {
+			return err
+		}
+		if err := countDropped(tx, source, report); err != nil {
+			return err
+		}
+
+		// everything not moved, e.g. duplicates, goes along with the source, which has to be deleted before its api key can be reassigned
+		if err := tx.Delete(source).Error; err != nil {
+			return err
+		}
+		if err := mergeCredentials(tx, source, target, merge.KeepApiKey, report); err != nil {
+			return err
+		}
+
+		if merge.DryRun {
+			return errMergeDryRun
+		}
+		return nil
+	})
+	if err != nil && err != errMergeDryRun {
+		return nil, err
+	}
+	return report, nil
+}
+
+func mergeHeartbeats(tx *gorm.DB, source, target *models.User, report *models.UserMergeReport) error {
+	times := make([]models.CustomTime, 0)
+
+	var afterId uint64
+	for {
+		var heartbeats []*models.Heartbeat
+		if err := tx.
+			Where("user_id = ?", source.ID).
+			Where("id > ?", afterId).
+			Order("id asc").
+			Limit(mergePageSize).
+			Find(&heartbeats).Error; err != nil {
+			return err
+		}
+		if len(heartbeats) == 0 {
+			break
+		}
+		afterId = heartbeats[len(heartbeats)-1].ID
+
+		// the hash covers the user, so heartbeats present in both accounts collide once rehashed (Hashed disregards the source account's previous hash)
+		hashes := make([]string, len(heartbeats))
+		for i, h := range heartbeats {
+			h.User, h.UserID = target, target.ID
+			hashes[i] = h.Hashed().Hash
+		}
+
+		var existing []string
+		if err := tx.
+			Model(&models.Heartbeat{}).
+			Where("hash IN ?", hashes).
+			Pluck("hash", &existing).Error; err != nil {
+			return err
+		}
+		duplicates := make(map[string]bool, len(existing))
+		for _, hash := range existing {
+			duplicates[hash] = true
+		}
+
+		for _, h := range heartbeats {
+			if duplicates[h.Hash] {
+				report.DuplicateHeartbeats++
+				continue
+			}
+			if err := tx.
+				Model(&models.Heartbeat{}).
+				Where("id = ?", h.ID).
+				Updates(map[string]interface{}{"user_id": target.ID, "hash": h.Hash}).Error; err != nil {
+				return err
+			}
+			report.Heartbeats++
+			times = append(times, h.Time)
+		}
+	}
+
+	if report.Heartbeats == 0 {
+		return nil
+	}
+	if err := NewHeartbeatRepository(tx).incrementCount(tx, target.ID, report.Heartbeats); err != nil {
+		return err
+	}
+	if err := invalidateDays(tx, target.ID, times); err != nil {
+		return err
+	}
+	return invalidateDurations(tx, target.ID, times)
+}
+
+func mergeSummaries(tx *gorm.DB, source, target *models.User, report *models.UserMergeReport) error {
+	var summaries []*models.Summary
+	if err := tx.
+		Select("id", "from_time", "to_time").
+		Where("user_id = ?", source.ID).
+		Find(&summaries).Error; err != nil {
+		return err
+	}
+
+	moved, overlapping := make([]uint, 0, len(summaries)), make([]uint, 0)
+	for _, s := range summaries {
+		var count int64
+		if err := tx.
+			Model(&models.Summary{}).
+			Where("user_id = ?", target.ID).
+			Where("from_time < ? AND to_time > ?", s.ToTime, s.FromTime).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			// the day got invalidated along with the heartbeats moved
+			overlapping = append(overlapping, s.ID)
+		} else {
+			moved = append(moved, s.ID)
+		}
+	}
+
+	if err := NewSummaryRepository(tx).DeleteByIds(overlapping); err != nil {
+		return err
+	}
+	report.OverlappingSummaries = int64(len(overlapping))
+
+	n, err := moveRows(tx, &models.Summary{}, moved, target.ID)
+	report.Summaries = n
+	return err
+}
+
+func mergeSettings(tx *gorm.DB, source, target *models.User, report *models.UserMergeReport) error {
+	var sourceAliases, targetAliases []*models.Alias
+	if err := tx.Where("user_id = ?", source.ID).Find(&sourceAliases).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", target.ID).Find(&targetAliases).Error; err != nil {
+		return err
+	}
+	aliases := make(map[string]bool, len(targetAliases))
+	for _, a := range targetAliases {
+		aliases[fmt.Sprintf("%d_%s_%s", a.Type, a.Key, a.Value)] = true
+	}
+	aliasIds := make([]uint, 0, len(sourceAliases))
+	for _, a := range sourceAliases {
+		if aliases[fmt.Sprintf("%d_%s_%s", a.Type, a.Key, a.Value)] {
+			report.DuplicateAliases++
+			continue
+		}
+		aliasIds = append(aliasIds, a.ID)
+	}
+
+	var sourceMappings, targetMappings []*models.LanguageMapping
+	if err := tx.Where("user_id = ?", source.ID).Find(&sourceMappings).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", target.ID).Find(&targetMappings).Error; err != nil {
+		return err
+	}
+	extensions := make(map[string]bool, len(targetMappings))
+	for _, m := range targetMappings {
+		extensions[m.Extension] = true
+	}
+	mappingIds := make([]uint, 0, len(sourceMappings))
+	for _, m := range sourceMappings {
+		if extensions[m.Extension] {
+			report.ConflictingLanguageMappings++
+			continue
+		}
+		mappingIds = append(mappingIds, m.ID)
+	}
+
+	var sourceLabels, targetLabels []*models.ProjectLabel
+	if err := tx.Where("user_id = ?", source.ID).Find(&sourceLabels).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", target.ID).Find(&targetLabels).Error; err != nil {
+		return err
+	}
+	labels := make(map[string]bool, len(targetLabels))
+	for _, l := range targetLabels {
+		labels[l.ProjectKey+"_"+l.Label] = true
+	}
+	labelIds := make([]uint, 0, len(sourceLabels))
+	for _, l := range sourceLabels {
+		if !labels[l.ProjectKey+"_"+l.Label] {
+			labelIds = append(labelIds, l.ID)
+		}
+	}
+
+	var tokenIds []uint
+	if err := tx.Model(&models.EmbedToken{}).Where("user_id = ?", source.ID).Pluck("id", &tokenIds).Error; err != nil {
+		return err
+	}
+
+	var err error
+	if report.Aliases, err = moveRows(tx, &models.Alias{}, aliasIds, target.ID); err != nil {
+		return err
+	}
+	if report.LanguageMappings, err = moveRows(tx, &models.LanguageMapping{}, mappingIds, target.ID); err != nil {
+		return err
+	}
+	if report.ProjectLabels, err = moveRows(tx, &models.ProjectLabel{}, labelIds, target.ID); err != nil {
+		return err
+	}
+	report.EmbedTokens, err = moveRows(tx, &models.EmbedToken{}, tokenIds, target.ID)
+	return err
+}
+
+// mergeRecords moves the source's manual time entries and goals as well as its project budgets, days off, filter sets and project repositories, unless the target has its own one for the same project, day or name
+func mergeRecords(tx *gorm.DB, source, target *models.User, report *models.UserMergeReport) error {
+	var entryIds, goalIds []uint
+	if err := tx.Model(&models.ManualTimeEntry{}).Where("user_id = ?", source.ID).Pluck("id", &entryIds).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Goal{}).Where("user_id = ?", source.ID).Pluck("id", &goalIds).Error; err != nil {
+		return err
+	}
+
+	var err error
+	if report.ManualTimeEntries, err = moveRows(tx, &models.ManualTimeEntry{}, entryIds, target.ID); err != nil {
+		return err
+	}
+	if report.Goals, err = moveRows(tx, &models.Goal{}, goalIds, target.ID); err != nil {
+		return err
+	}
+	if report.ProjectBudgets, err = moveRowsUnlessPresent(tx, &models.ProjectBudget{}, "project_key", source, target); err != nil {
+		return err
+	}
+	if report.DaysOff, err = moveRowsUnlessPresent(tx, &models.DayOff{}, "day", source, target); err != nil {
+		return err
+	}
+	if report.FilterSets, err = moveRowsUnlessPresent(tx, &models.FilterSet{}, "name", source, target); err != nil {
+		return err
+	}
+	report.ProjectRepos, err = moveRowsUnlessPresent(tx, &models.ProjectRepo{}, "project_key", source, target)
+	return err
+}
+
+// countDropped reports the number of records per kind, which are left with the source and hence deleted along with it
+func countDropped(tx *gorm.DB, source *models.User, report *models.UserMergeReport) error {
+	report.Dropped = map[string]int64{}
+	for _, m := range mergeReportedModels {
+		if !tx.Migrator().HasTable(m.model) {
+			continue
+		}
+		var count int64
+		if err := tx.Model(m.model).Where("user_id = ?", source.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			report.Dropped[m.name] = count
+		}
+	}
+	return nil
+}
+
+// mergeCredentials has the target take over the source's integration credentials, where it has none of its own, and optionally its api key
+func mergeCredentials(tx *gorm.DB, source, target *models.User, keepApiKey bool, report *models.UserMergeReport) error {
+	updates := make(map[string]interface{})
+
+	if target.WakatimeApiKey == "" && source.WakatimeApiKey != "" {
+		updates["wakatime_api_key"], updates["wakatime_api_url"] = source.WakatimeApiKey, source.WakatimeApiUrl
+		report.Credentials = append(report.Credentials, "wakatime")
+	}
+	if target.CodeStatsUsername == "" && source.CodeStatsUsername != "" {
+		updates["code_stats_username"], updates["code_stats_api_token"] = source.CodeStatsUsername, source.CodeStatsApiToken
+		report.Credentials = append(report.Credentials, "codestats")
+	}
+	if target.JiraApiToken == "" && source.JiraApiToken != "" {
+		updates["jira_url"], updates["jira_email"], updates["jira_api_token"] = source.JiraUrl, source.JiraEmail, source.JiraApiToken
+		report.Credentials = append(report.Credentials, "jira")
+	}
+	if target.GcalRefreshToken == "" && source.GcalRefreshToken != "" {
+		updates["gcal_refresh_token"], updates["gcal_calendar_id"], updates["gcal_enabled"], updates["gcal_event_titles"] = source.GcalRefreshToken, source.GcalCalendarId, source.GcalEnabled, source.GcalEventTitles
+		report.Credentials = append(report.Credentials, "google_calendar")
+	}
+	if keepApiKey {
+		updates["api_key"] = source.ApiKey
+		report.ApiKeyAdopted = true
+	}
+	if source.HasData {
+		updates["has_data"] = true
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+	return tx.Model(target).Updates(updates).Error
+}
+
+// moveRows assigns the rows of the given model with the given ids to another user in chunks of mergePageSize and returns their number
+func moveRows(tx *gorm.DB, model interface{}, ids []uint, userId string) (int64, error) {
+	var moved int64
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > mergePageSize {
+			n = mergePageSize
+		}
+		result := tx.
+			Model(model).
+			Where("id IN ?", ids[:n]).
+			Update("user_id", userId)
+		if err := result.Error; err != nil {
+			return moved, err
+		}
+		moved += result.RowsAffected
+		ids = ids[n:]
+	}
+	return moved, nil
+}
+
+// moveRowsUnlessPresent assigns the source's rows of the given model to the target, except for those whose value of the given (per-user unique) column the target has a row with already
+func moveRowsUnlessPresent(tx *gorm.DB, model interface{}, column string, source, target *models.User) (int64, error) {
+	var ids []uint
+	if err := tx.
+		Model(model).
+		Where("user_id = ?", source.ID).
+		Where(fmt.Sprintf("%s NOT IN (?)", column), tx.Model(model).Select(column).Where("user_id = ?", target.ID)).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	return moveRows(tx, model, ids, target.ID)
+}
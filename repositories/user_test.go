@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type UserRepositoryTestSuite struct {
+	suite.Suite
+	DB         *gorm.DB
+	TestUser   *models.User
+	LegacyUser *models.User
+	Today      time.Time
+}
+
+func (suite *UserRepositoryTestSuite) BeforeTest(suiteName, testName string) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	// every connection to an in-memory database gets its own, empty one
+	sqlDb, _ := db.DB()
+	sqlDb.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(
+		&models.User{}, &models.Heartbeat{}, &models.HeartbeatCount{}, &models.SummaryInvalidation{}, &models.Duration{}, &models.DurationDay{},
+		&models.Summary{}, &models.SummaryItem{}, &models.Alias{}, &models.LanguageMapping{}, &models.ProjectLabel{}, &models.EmbedToken{},
+		&models.ManualTimeEntry{}, &models.Goal{}, &models.ProjectBudget{}, &models.DayOff{}, &models.FilterSet{}, &models.ProjectRepo{}, &models.RelayTarget{},
+	); err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	now := time.Now()
+	suite.DB = db
+	suite.Today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	suite.TestUser = &models.User{ID: testUserId, ApiKey: "f0ab5b33-3a2d-4a3c-9fc1-5c6ba0d6a8f0"}
+	suite.LegacyUser = &models.User{ID: "legacy", ApiKey: "6d5a7c3e-9a1b-4f7e-8c2d-3b4a5c6d7e8f", WakatimeApiKey: "waka_123", HasData: true}
+	suite.DB.Create(suite.TestUser)
+	suite.DB.Create(suite.LegacyUser)
+}
+
+func (suite *UserRepositoryTestSuite) AfterTest(suiteName, testName string) {
+	if sqlDb, err := suite.DB.DB(); err == nil {
+		sqlDb.Close()
+	}
+}
+
+func TestUserRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(UserRepositoryTestSuite))
+}
+
+func (suite *UserRepositoryTestSuite) TestUserRepository_Merge() {
+	sut := NewUserRepository(suite.DB)
+	suite.createMergeFixtures()
+
+	report, err := sut.Merge(&models.UserMerge{SourceId: suite.LegacyUser.ID, TargetId: testUserId, KeepApiKey: true})
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), report.DryRun)
+	assert.Equal(suite.T(), int64(1), report.Heartbeats)
+	assert.Equal(suite.T(), int64(1), report.DuplicateHeartbeats)
+	assert.Equal(suite.T(), int64(1), report.Summaries)
+	assert.Equal(suite.T(), int64(1), report.OverlappingSummaries)
+	assert.Equal(suite.T(), int64(1), report.Aliases)
+	assert.Equal(suite.T(), int64(1), report.DuplicateAliases)
+	assert.Equal(suite.T(), int64(1), report.LanguageMappings)
+	assert.Equal(suite.T(), int64(1), report.ConflictingLanguageMappings)
+	assert.Equal(suite.T(), int64(1), report.ManualTimeEntries)
+	assert.Equal(suite.T(), int64(1), report.Goals)
+	assert.Equal(suite.T(), int64(1), report.ProjectBudgets)
+	assert.Equal(suite.T(), []string{"wakatime"}, report.Credentials)
+	assert.True(suite.T(), report.ApiKeyAdopted)
+	assert.Equal(suite.T(), map[string]int64{
+		"heartbeats":        1,
+		"aliases":           1,
+		"language_mappings": 1,
+		"project_budgets":   1,
+		"relay_targets":     1,
+	}, report.Dropped)
+
+	_, err = sut.GetById(suite.LegacyUser.ID)
+	assert.Error(suite.T(), err)
+
+	user, err := sut.GetByApiKey(suite.LegacyUser.ApiKey)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), testUserId, user.ID)
+	assert.Equal(suite.T(), "waka_123", user.WakatimeApiKey)
+	assert.True(suite.T(), user.HasData)
+
+	var heartbeats []*models.Heartbeat
+	suite.DB.Where("user_id = ?", testUserId).Order("time asc").Find(&heartbeats)
+	assert.Len(suite.T(), heartbeats, 3)
+	assert.Equal(suite.T(), "legacy.go", heartbeats[2].Entity)
+	expected := &models.Heartbeat{User: suite.TestUser, UserID: testUserId, Entity: "legacy.go", Time: heartbeats[2].Time}
+	assert.Equal(suite.T(), expected.Hashed().Hash, heartbeats[2].Hash)
+
+	var heartbeatCount models.HeartbeatCount
+	suite.DB.Where("user_id = ?", testUserId).First(&heartbeatCount)
+	assert.Equal(suite.T(), int64(1), heartbeatCount.Total)
+
+	// the day of the moved heartbeat is recomputed
+	invalidations, err := NewSummaryInvalidationRepository(suite.DB).GetAll()
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), invalidations, 1)
+	assert.True(suite.T(), invalidations[0].Day.T().Equal(suite.Today.AddDate(0, 0, -2)))
+
+	var count int64
+	suite.DB.Model(&models.Summary{}).Where("user_id = ?", testUserId).Count(&count)
+	assert.Equal(suite.T(), int64(2), count)
+	suite.DB.Model(&models.Summary{}).Count(&count)
+	assert.Equal(suite.T(), int64(2), count)
+
+	var entries []*models.ManualTimeEntry
+	suite.DB.Where("user_id = ?", testUserId).Find(&entries)
+	assert.Len(suite.T(), entries, 1)
+	suite.DB.Model(&models.Goal{}).Where("user_id = ?", testUserId).Count(&count)
+	assert.Equal(suite.T(), int64(1), count)
+
+	// the target's own budget for the same project is kept
+	var budgets []*models.ProjectBudget
+	suite.DB.Where("user_id = ?", testUserId).Order("project_key asc").Find(&budgets)
+	assert.Len(suite.T(), budgets, 2)
+	assert.Equal(suite.T(), 20, budgets[1].Hours)
+}
+
+func (suite *UserRepositoryTestSuite) TestUserRepository_Merge_DryRun() {
+	sut := NewUserRepository(suite.DB)
+	suite.createMergeFixtures()
+
+	report, err := sut.Merge(&models.UserMerge{SourceId: suite.LegacyUser.ID, TargetId: testUserId, KeepApiKey: true, DryRun: true})
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), report.DryRun)
+	assert.Equal(suite.T(), int64(1), report.Heartbeats)
+	assert.Equal(suite.T(), int64(1), report.Summaries)
+	assert.Equal(suite.T(), int64(1), report.ManualTimeEntries)
+	assert.Equal(suite.T(), int64(1), report.Dropped["relay_targets"])
+
+	// nothing changed
+	_, err = sut.GetById(suite.LegacyUser.ID)
+	assert.Nil(suite.T(), err)
+
+	var count int64
+	suite.DB.Model(&models.Heartbeat{}).Where("user_id = ?", suite.LegacyUser.ID).Count(&count)
+	assert.Equal(suite.T(), int64(2), count)
+	suite.DB.Model(&models.SummaryInvalidation{}).Count(&count)
+	assert.Zero(suite.T(), count)
+}
+
+func (suite *UserRepositoryTestSuite) createMergeFixtures() {
+	day1, day2 := suite.Today.AddDate(0, 0, -3), suite.Today.AddDate(0, 0, -2)
+
+	for _, h := range []*models.Heartbeat{
+		{User: suite.TestUser, UserID: testUserId, Entity: "main.go", Time: models.CustomTime(day1.Add(10 * time.Hour))},
+		{User: suite.TestUser, UserID: testUserId, Entity: "shared.go", Time: models.CustomTime(day1.Add(11 * time.Hour))},
+		{User: suite.LegacyUser, UserID: suite.LegacyUser.ID, Entity: "shared.go", Time: models.CustomTime(day1.Add(11 * time.Hour))}, // synced to both accounts
+		{User: suite.LegacyUser, UserID: suite.LegacyUser.ID, Entity: "legacy.go", Time: models.CustomTime(day2.Add(10 * time.Hour))},
+	} {
+		suite.DB.Create(h.Hashed())
+	}
+
+	suite.DB.Create(&models.Summary{UserID: testUserId, FromTime: models.CustomTime(day1), ToTime: models.CustomTime(day1.AddDate(0, 0, 1))})
+	suite.DB.Create(&models.Summary{UserID: suite.LegacyUser.ID, FromTime: models.CustomTime(day1), ToTime: models.CustomTime(day1.AddDate(0, 0, 1))})
+	suite.DB.Create(&models.Summary{UserID: suite.LegacyUser.ID, FromTime: models.CustomTime(day2), ToTime: models.CustomTime(day2.AddDate(0, 0, 1))})
+
+	suite.DB.Create(&models.Alias{UserID: testUserId, Type: models.SummaryProject, Key: "wakapi", Value: "wakapi-legacy"})
+	suite.DB.Create(&models.Alias{UserID: suite.LegacyUser.ID, Type: models.SummaryProject, Key: "wakapi", Value: "wakapi-legacy"})
+	suite.DB.Create(&models.Alias{UserID: suite.LegacyUser.ID, Type: models.SummaryProject, Key: "wakapi", Value: "wakapi-old"})
+
+	suite.DB.Create(&models.LanguageMapping{UserID: testUserId, Extension: "tpl", Language: "Go Template"})
+	suite.DB.Create(&models.LanguageMapping{UserID: suite.LegacyUser.ID, Extension: "tpl", Language: "Smarty"})
+	suite.DB.Create(&models.LanguageMapping{UserID: suite.LegacyUser.ID, Extension: "templ", Language: "Templ"})
+
+	suite.DB.Create(&models.ManualTimeEntry{UserID: suite.LegacyUser.ID, Project: "wakapi", Date: models.CustomTime(day2), Duration: 1 * time.Hour})
+	suite.DB.Create(&models.Goal{UserID: suite.LegacyUser.ID, Title: "Daily coding", Interval: models.GoalIntervalDay, Minutes: 60})
+
+	suite.DB.Create(&models.ProjectBudget{UserID: testUserId, ProjectKey: "wakapi", Hours: 20})
+	suite.DB.Create(&models.ProjectBudget{UserID: suite.LegacyUser.ID, ProjectKey: "wakapi", Hours: 10})
+	suite.DB.Create(&models.ProjectBudget{UserID: suite.LegacyUser.ID, ProjectKey: "anchr", Hours: 5})
+
+	suite.DB.Create(&models.RelayTarget{UserID: suite.LegacyUser.ID, Name: "WakaTime", ApiUrl: "https://api.wakatime.com/api/v1", ApiKey: "waka_123"})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type AchievementApiHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	achievementSrvc services.IAchievementService
+}
+
+func NewAchievementApiHandler(userService services.IUserService, achievementService services.IAchievementService) *AchievementApiHandler {
+	return &AchievementApiHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		achievementSrvc: achievementService,
+	}
+}
+
+func (h *AchievementApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/achievements").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the achievements earned by the user
+// @ID get-achievements
+// @Tags achievements
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.AchievementViewModel
+// @Router /achievements [get]
+func (h *AchievementApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	achievements, err := h.achievementSrvc.GetByUser(user.ID)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to fetch achievements for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	vm := make([]*models.AchievementViewModel, len(achievements))
+	for i, a := range achievements {
+		vm[i] = models.NewAchievementViewModel(a)
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, vm)
+}
@@ -5,29 +5,34 @@ import (
 	"github.com/gorilla/mux"
 	lru "github.com/hashicorp/golang-lru"
 	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/services"
+	"io"
 	"net/http"
 )
 
 type AvatarHandler struct {
-	config *conf.Config
-	cache  *lru.Cache
+	config     *conf.Config
+	cache      *lru.Cache
+	avatarSrvc services.IAvatarService
 }
 
-func NewAvatarHandler() *AvatarHandler {
+func NewAvatarHandler(avatarService services.IAvatarService) *AvatarHandler {
 	cache, err := lru.New(1 * 1000 * 64) // assuming an avatar is 1 kb, allocate up to 64 mb of memory for avatars cache
 	if err != nil {
 		panic(err)
 	}
 
 	return &AvatarHandler{
-		config: conf.Get(),
-		cache:  cache,
+		config:     conf.Get(),
+		cache:      cache,
+		avatarSrvc: avatarService,
 	}
 }
 
 func (h *AvatarHandler) RegisterRoutes(router *mux.Router) {
 	r := router.PathPrefix("/avatar/{hash}.svg").Subrouter()
 	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+	router.Path("/avatar/upload/{name}.png").Methods(http.MethodGet).HandlerFunc(h.GetUploaded)
 }
 
 func (h *AvatarHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -43,3 +48,20 @@ func (h *AvatarHandler) Get(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(data.(string)))
 }
+
+// GetUploaded serves an avatar image, as uploaded by a user in the settings.
+// Images are addressed by their random name instead of the user name, so that responses don't reveal whether an account exists.
+func (h *AvatarHandler) GetUploaded(w http.ResponseWriter, r *http.Request) {
+	file, err := h.avatarSrvc.Open(mux.Vars(r)["name"])
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	// names change with every upload, so images can be cached for long
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "max-age=2592000")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type BudgetApiHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	budgetSrvc services.IProjectBudgetService
+}
+
+func NewBudgetApiHandler(userService services.IUserService, projectBudgetService services.IProjectBudgetService) *BudgetApiHandler {
+	return &BudgetApiHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		budgetSrvc: projectBudgetService,
+	}
+}
+
+func (h *BudgetApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/budgets").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.GetAll)
+	r.Path("/{project}").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the current month's consumption of all of the user's project budgets
+// @ID get-budgets
+// @Tags budgets
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.BudgetStatus
+// @Router /budgets [get]
+func (h *BudgetApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	statuses, err := h.budgetSrvc.GetStatuses(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compute budget statuses for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, statuses)
+}
+
+// @Summary Retrieve the current month's consumption of a project's budget
+// @ID get-budget
+// @Tags budgets
+// @Produce json
+// @Param project path string true "Project name"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.BudgetStatus
+// @Failure 404 {string} string "project has no budget"
+// @Router /budgets/{project} [get]
+func (h *BudgetApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	status, err := h.budgetSrvc.GetStatus(user, mux.Vars(r)["project"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compute budget status for user '%s' - %v", user.ID, err)
+		return
+	}
+	if status == nil {
+		utils.RespondError(w, r, http.StatusNotFound, "project has no budget")
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, status)
+}
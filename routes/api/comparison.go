@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type ComparisonApiHandler struct {
+	config         *conf.Config
+	userSrvc       services.IUserService
+	comparisonSrvc services.IComparisonService
+}
+
+func NewComparisonApiHandler(userService services.IUserService, comparisonService services.IComparisonService) *ComparisonApiHandler {
+	return &ComparisonApiHandler{
+		config:         conf.Get(),
+		userSrvc:       userService,
+		comparisonSrvc: comparisonService,
+	}
+}
+
+func (h *ComparisonApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/compare").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Compare the summaries of multiple users side by side
+// @Description Other users need to allow comparisons in their settings
+// @ID get-comparison
+// @Tags summary
+// @Produce json
+// @Param users query string true "Comma-separated list of user ids"
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, last_week, quarter, last_quarter, year_to_date, fiscal_year, last_fiscal_year, any)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Comparison
+// @Failure 400 {string} string "no users to compare"
+// @Failure 403 {string} string "user does not exist or does not allow comparisons"
+// @Router /compare [get]
+func (h *ComparisonApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	params, err := utils.ParseSummaryParams(r)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comparison, err := h.comparisonSrvc.Compare(strings.Split(r.URL.Query().Get("users"), ","), params.From, params.To, user)
+	if err == services.ErrNoComparisonUsers || err == services.ErrTooManyComparisonUsers {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, ok := err.(*services.ComparisonNotAllowedError); ok {
+		utils.RespondError(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compare summaries for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, comparison)
+}
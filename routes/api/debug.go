@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/emvi/logbuch"
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+// DebugApiHandler exposes go's profiling endpoints and runtime stats to admins, so that operators can diagnose memory growth and load on large instances
+type DebugApiHandler struct {
+	config        *conf.Config
+	userSrvc      services.IUserService
+	heartbeatSrvc services.IHeartbeatService
+}
+
+func NewDebugApiHandler(userService services.IUserService, heartbeatService services.IHeartbeatService) *DebugApiHandler {
+	return &DebugApiHandler{
+		config:        conf.Get(),
+		userSrvc:      userService,
+		heartbeatSrvc: heartbeatService,
+	}
+}
+
+func (h *DebugApiHandler) RegisterRoutes(router *mux.Router) {
+	if !h.config.Security.ExposeDebug {
+		return
+	}
+
+	logbuch.Info("exposing pprof and runtime stats to admins under /api/admin/debug")
+
+	r := router.PathPrefix("/admin/debug").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+		routeutils.AdminOnly,
+	)
+	r.Path("/runtime").Methods(http.MethodGet).HandlerFunc(h.GetRuntime)
+	r.Path("/pprof/").Methods(http.MethodGet).HandlerFunc(pprof.Index)
+	r.Path("/pprof/cmdline").Methods(http.MethodGet).HandlerFunc(pprof.Cmdline)
+	r.Path("/pprof/profile").Methods(http.MethodGet).HandlerFunc(pprof.Profile)
+	r.Path("/pprof/symbol").Methods(http.MethodGet, http.MethodPost).HandlerFunc(pprof.Symbol)
+	r.Path("/pprof/trace").Methods(http.MethodGet).HandlerFunc(pprof.Trace)
+	r.Path("/pprof/{profile}").Methods(http.MethodGet).HandlerFunc(h.GetProfile)
+}
+
+// @Summary Retrieve runtime stats of the server, e.g. goroutines, heap and gc pauses
+// @Description Only available to admin users and if enabled via security.expose_debug
+// @ID get-debug-runtime
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.RuntimeStats
+// @Router /admin/debug/runtime [get]
+func (h *DebugApiHandler) GetRuntime(w http.ResponseWriter, r *http.Request) {
+	utils.RespondJSON(w, r, http.StatusOK, models.NewRuntimeStats(h.heartbeatSrvc.CountPending()))
+}
+
+// GetProfile serves named profiles (heap, goroutine, allocs, ...), which pprof.Index would only resolve below /debug/pprof/
+func (h *DebugApiHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	pprof.Handler(mux.Vars(r)["profile"]).ServeHTTP(w, r)
+}
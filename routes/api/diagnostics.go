@@ -48,22 +48,19 @@ func (h *DiagnosticsApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 
 	user := middlewares.GetPrincipal(r)
 	if user == nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(conf.ErrUnauthorized))
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
 		return
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&diagnostics); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(conf.ErrBadRequest))
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
 		conf.Log().Request(r).Error("failed to parse diagnostics for user %s - %v", err)
 		return
 	}
 	diagnostics.UserID = user.ID
 
 	if _, err := h.diagnosticsSrvc.Create(&diagnostics); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(conf.ErrInternalServerError))
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
 		conf.Log().Request(r).Error("failed to insert diagnostics for user %s - %v", err)
 		return
 	}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+const defaultDoctorDays = 30
+
+type DoctorApiHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	doctorSrvc services.IDoctorService
+}
+
+func NewDoctorApiHandler(userService services.IUserService, doctorService services.IDoctorService) *DoctorApiHandler {
+	return &DoctorApiHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		doctorSrvc: doctorService,
+	}
+}
+
+func (h *DoctorApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/admin/doctor").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+}
+
+// @Summary Retrieve the report of the latest data integrity check
+// @Description Only available to admin users. The check's progress can be followed via the jobs endpoint.
+// @ID get-doctor
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.DoctorReport
+// @Router /admin/doctor [get]
+func (h *DoctorApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	report := h.doctorSrvc.GetLastReport()
+	if report == nil {
+		utils.RespondError(w, r, http.StatusNotFound, "no data integrity check was run, yet")
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, report)
+}
+
+// @Summary Check the database for inconsistencies in background and optionally repair them
+// @Description Only available to admin users. With repair, orphaned summaries and aliases are deleted and summaries disagreeing with their heartbeats are regenerated.
+// @ID post-doctor
+// @Tags admin
+// @Param days query int false "Number of past days to check summaries for (default: 30, at most 366)"
+// @Param repair query bool false "Whether to repair inconsistencies found"
+// @Security ApiKeyAuth
+// @Success 202
+// @Router /admin/doctor [post]
+func (h *DoctorApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	days := defaultDoctorDays
+	if daysParam := r.URL.Query().Get("days"); daysParam != "" {
+		d, err := strconv.Atoi(daysParam)
+		if err != nil || d < 0 || d > services.DoctorMaxDays {
+			utils.RespondError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid 'days' parameter, must be between 0 and %d", services.DoctorMaxDays))
+			return
+		}
+		days = d
+	}
+	repair := r.URL.Query().Get("repair") == "true"
+
+	if err := h.doctorSrvc.CheckAsync(days, repair); err != nil {
+		if err == services.ErrDoctorRunning {
+			utils.RespondError(w, r, http.StatusConflict, err.Error())
+			return
+		}
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to start data integrity check - %v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
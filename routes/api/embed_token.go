@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type EmbedTokenApiHandler struct {
+	config         *conf.Config
+	userSrvc       services.IUserService
+	embedTokenSrvc services.IEmbedTokenService
+}
+
+func NewEmbedTokenApiHandler(userService services.IUserService, embedTokenService services.IEmbedTokenService) *EmbedTokenApiHandler {
+	return &EmbedTokenApiHandler{
+		config:         conf.Get(),
+		userSrvc:       userService,
+		embedTokenSrvc: embedTokenService,
+	}
+}
+
+type embedTokenPayload struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`    // any of 'badge', 'stats' and 'charts'
+	Referrers []string `json:"referrers"` // hosts to accept the token from, e.g. 'example.org' or '*.github.io', any if empty
+}
+
+func (h *EmbedTokenApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/embed_tokens").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.GetAll)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+	r.Path("/{id:[0-9]+}").Methods(http.MethodDelete).HandlerFunc(h.Delete)
+}
+
+// @Summary Retrieve all of the user's embed tokens
+// @ID get-embed-tokens
+// @Tags embed tokens
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.EmbedToken
+// @Router /embed_tokens [get]
+func (h *EmbedTokenApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	tokens, err := h.embedTokenSrvc.GetByUser(user.ID)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to fetch embed tokens for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, tokens)
+}
+
+// @Summary Create a read-only token for embedding badges, stats or charts into public web pages
+// @Description The token is passed as 'embed_token' query parameter and only accepted by endpoints of the given scopes and, optionally, from pages at the given hosts (as told by the Referer or Origin header).
+// @ID post-embed-token
+// @Tags embed tokens
+// @Accept json
+// @Produce json
+// @Param embed_token body embedTokenPayload true "Scopes and referrers of the token"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.EmbedToken
+// @Failure 400 {object} models.ApiError "no or unknown scopes"
+// @Router /embed_tokens [post]
+func (h *EmbedTokenApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var payload embedTokenPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	token, err := h.embedTokenSrvc.Create(user, payload.Name, payload.Scopes, payload.Referrers)
+	if err == services.ErrInvalidEmbedToken {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to create embed token for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusCreated, token)
+}
+
+// @Summary Revoke an embed token
+// @ID delete-embed-token
+// @Tags embed tokens
+// @Param id path int true "Embed token ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /embed_tokens/{id} [delete]
+func (h *EmbedTokenApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	if err := h.embedTokenSrvc.Delete(user, uint(id)); err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "embed token not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type FilterSetApiHandler struct {
+	config        *conf.Config
+	userSrvc      services.IUserService
+	filterSetSrvc services.IFilterSetService
+}
+
+func NewFilterSetApiHandler(userService services.IUserService, filterSetService services.IFilterSetService) *FilterSetApiHandler {
+	return &FilterSetApiHandler{
+		config:        conf.Get(),
+		userSrvc:      userService,
+		filterSetSrvc: filterSetService,
+	}
+}
+
+type filterSetPayload struct {
+	Name     string `json:"name"` // letters, digits, '_', '-' and '.' only
+	Project  string `json:"project"`
+	Label    string `json:"label"`
+	Language string `json:"language"`
+}
+
+func (h *FilterSetApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/filter_sets").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.GetAll)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+	r.Path("/{name}").Methods(http.MethodDelete).HandlerFunc(h.Delete)
+}
+
+// @Summary Retrieve all of the user's saved filter sets
+// @ID get-filter-sets
+// @Tags filter sets
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.FilterSet
+// @Router /filter_sets [get]
+func (h *FilterSetApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	filterSets, err := h.filterSetSrvc.GetByUser(user.ID)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to fetch filter sets for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, filterSets)
+}
+
+// @Summary Save a named combination of project, label and language filters, to be applied via the 'filter_set' parameter
+// @ID post-filter-set
+// @Tags filter sets
+// @Accept json
+// @Produce json
+// @Param filter_set body filterSetPayload true "Filter set, at least one filter required"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.FilterSet
+// @Failure 409 {object} models.ApiError "filter set already exists"
+// @Router /filter_sets [post]
+func (h *FilterSetApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var payload filterSetPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	filterSet := &models.FilterSet{
+		UserID:   user.ID,
+		Name:     payload.Name,
+		Project:  payload.Project,
+		Label:    payload.Label,
+		Language: payload.Language,
+	}
+	if !filterSet.IsValid() {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid filter set")
+		return
+	}
+
+	if existing, _ := h.filterSetSrvc.GetByUserAndName(user.ID, filterSet.Name); existing != nil {
+		utils.RespondError(w, r, http.StatusConflict, "filter set already exists")
+		return
+	}
+
+	result, err := h.filterSetSrvc.Create(filterSet)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to create filter set for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Delete a saved filter set
+// @ID delete-filter-set
+// @Tags filter sets
+// @Param name path string true "Filter set name"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /filter_sets/{name} [delete]
+func (h *FilterSetApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	if err := h.filterSetSrvc.Delete(user.ID, mux.Vars(r)["name"]); err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "filter set not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
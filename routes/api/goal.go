@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type GoalApiHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+	goalSrvc services.IGoalService
+}
+
+func NewGoalApiHandler(userService services.IUserService, goalService services.IGoalService) *GoalApiHandler {
+	return &GoalApiHandler{
+		config:   conf.Get(),
+		userSrvc: userService,
+		goalSrvc: goalService,
+	}
+}
+
+type goalPayload struct {
+	Title    string `json:"title"`
+	Interval string `json:"interval"` // one of 'day', 'week' or 'month'
+	Minutes  int    `json:"minutes"`
+	Language string `json:"language"` // optional
+	Editor   string `json:"editor"`   // optional, mutually exclusive with language
+}
+
+func (h *GoalApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/goals").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.GetAll)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+	r.Path("/{id}").Methods(http.MethodGet).HandlerFunc(h.Get)
+	r.Path("/{id}").Methods(http.MethodDelete).HandlerFunc(h.Delete)
+}
+
+// @Summary Retrieve the progress of all of the user's goals within their current interval
+// @ID get-goals
+// @Tags goals
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.GoalProgress
+// @Router /goals [get]
+func (h *GoalApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	progresses, err := h.goalSrvc.GetProgresses(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compute goal progress for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, progresses)
+}
+
+// @Summary Retrieve the progress of a single goal within its current interval
+// @ID get-goal
+// @Tags goals
+// @Produce json
+// @Param id path int true "Goal ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.GoalProgress
+// @Failure 404 {object} models.ApiError "goal not found"
+// @Router /goals/{id} [get]
+func (h *GoalApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	progress, err := h.goalSrvc.GetProgress(user, uint(id))
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compute goal progress for user '%s' - %v", user.ID, err)
+		return
+	}
+	if progress == nil {
+		utils.RespondError(w, r, http.StatusNotFound, "goal not found")
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, progress)
+}
+
+// @Summary Create a goal, optionally scoped to a language or editor
+// @ID post-goal
+// @Tags goals
+// @Accept json
+// @Produce json
+// @Param goal body goalPayload true "Goal, target given in minutes per interval"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.Goal
+// @Router /goals [post]
+func (h *GoalApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var payload goalPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	goal := &models.Goal{
+		UserID:   user.ID,
+		Title:    payload.Title,
+		Interval: payload.Interval,
+		Minutes:  payload.Minutes,
+		Language: payload.Language,
+		Editor:   payload.Editor,
+	}
+	if !goal.IsValid() {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid goal")
+		return
+	}
+
+	result, err := h.goalSrvc.Create(goal)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to create goal for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Delete a goal
+// @ID delete-goal
+// @Tags goals
+// @Param id path int true "Goal ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /goals/{id} [delete]
+func (h *GoalApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.goalSrvc.Delete(user.ID, uint(id)); err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "goal not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,14 +1,22 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/emvi/logbuch"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
 	customMiddleware "github.com/muety/wakapi/middlewares/custom"
+	"github.com/muety/wakapi/repositories"
 	routeutils "github.com/muety/wakapi/routes/utils"
 	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/services/imports"
 	"github.com/muety/wakapi/utils"
 
 	"github.com/muety/wakapi/models"
@@ -19,45 +27,117 @@ type HeartbeatApiHandler struct {
 	userSrvc            services.IUserService
 	heartbeatSrvc       services.IHeartbeatService
 	languageMappingSrvc services.ILanguageMappingService
+	scriptSrvc          services.IHeartbeatScriptService
+	relaySrvc           services.IRelayTargetService
+	relayRuleSrvc       services.IRelayRuleService
+	quotaSrvc           services.IQuotaService
+	storageQuotaSrvc    services.IStorageQuotaService
+	clockSkewSrvc       services.IClockSkewService
+	importSrvc          services.IImportService
+	idempotency         *middlewares.IdempotencyMiddleware
 }
 
-func NewHeartbeatApiHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, languageMappingService services.ILanguageMappingService) *HeartbeatApiHandler {
+func NewHeartbeatApiHandler(userService services.IUserService, heartbeatService services.IHeartbeatService, languageMappingService services.ILanguageMappingService, heartbeatScriptService services.IHeartbeatScriptService, relayTargetService services.IRelayTargetService, relayRuleService services.IRelayRuleService, quotaService services.IQuotaService, storageQuotaService services.IStorageQuotaService, clockSkewService services.IClockSkewService, importService services.IImportService) *HeartbeatApiHandler {
 	return &HeartbeatApiHandler{
 		config:              conf.Get(),
 		userSrvc:            userService,
 		heartbeatSrvc:       heartbeatService,
 		languageMappingSrvc: languageMappingService,
+		scriptSrvc:          heartbeatScriptService,
+		relaySrvc:           relayTargetService,
+		relayRuleSrvc:       relayRuleService,
+		quotaSrvc:           quotaService,
+		storageQuotaSrvc:    storageQuotaService,
+		clockSkewSrvc:       clockSkewService,
+		importSrvc:          importService,
+		idempotency:         middlewares.NewIdempotencyMiddleware(conf.Get().App.GetIdempotencyWindow()),
 	}
 }
 
+// max. number of heartbeats per page of an export
+const heartbeatsExportPageSize = 1000
+
+var (
+	legacyHeartbeatRoutesSince  = time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	legacyHeartbeatRoutesSunset = time.Date(2027, 10, 1, 0, 0, 0, 0, time.UTC)
+)
+
+var errQuotaExceeded = errors.New("heartbeat quota exceeded")
+var errStorageQuotaExceeded = errors.New("storage quota exceeded")
+var errTooManyHeartbeats = errors.New("too many heartbeats")
+
 type heartbeatResponseVm struct {
 	Responses [][]interface{} `json:"responses"`
 }
 
+type heartbeatImportResponseVm struct {
+	Imported int `json:"imported"`
+	Rejected int `json:"rejected"`
+	Skipped  int `json:"skipped"` // leading rows processed by the previous import already, when resuming it
+}
+
+// fields left out are not changed
+type heartbeatUpdatePayload struct {
+	Project  *string `json:"project"`
+	Language *string `json:"language"`
+	Branch   *string `json:"branch"`
+}
+
 func (h *HeartbeatApiHandler) RegisterRoutes(router *mux.Router) {
 	r := router.PathPrefix("").Subrouter()
-	r.Use(
-		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
-		customMiddleware.NewWakatimeRelayMiddleware().Handler,
-	)
-	// see https://github.com/muety/wakapi/issues/203
-	r.Path("/heartbeat").Methods(http.MethodPost).HandlerFunc(h.Post)
-	r.Path("/heartbeats").Methods(http.MethodPost).HandlerFunc(h.Post)
+	// oversized bodies are rejected before anything, including the relay middleware, reads them into memory
+	r.Use(middlewares.NewBodyLimitMiddleware(h.config.App.GetHeartbeatsMaxBodySize()))
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+	// retries are answered before being relayed again
+	if h.config.App.GetIdempotencyWindow() > 0 {
+		r.Use(h.idempotency.Handler)
+	}
+	r.Use(customMiddleware.NewWakatimeRelayMiddleware(h.scriptSrvc, h.relaySrvc, h.relayRuleSrvc).Handler)
+	// see https://github.com/muety/wakapi/issues/203, only used by outdated clients, which don't strip the path from the api url
+	deprecated := middlewares.NewDeprecationMiddleware(legacyHeartbeatRoutesSince, legacyHeartbeatRoutesSunset, "/api/users/current/heartbeats.bulk")
+	r.Path("/heartbeat").Methods(http.MethodPost).Handler(deprecated(http.HandlerFunc(h.Post)))
+	r.Path("/heartbeats").Methods(http.MethodPost).Handler(deprecated(http.HandlerFunc(h.Post)))
 	r.Path("/users/{user}/heartbeats").Methods(http.MethodPost).HandlerFunc(h.Post)
 	r.Path("/users/{user}/heartbeats.bulk").Methods(http.MethodPost).HandlerFunc(h.Post)
 	r.Path("/v1/users/{user}/heartbeats").Methods(http.MethodPost).HandlerFunc(h.Post)
 	r.Path("/v1/users/{user}/heartbeats.bulk").Methods(http.MethodPost).HandlerFunc(h.Post)
 	r.Path("/compat/wakatime/v1/users/{user}/heartbeats").Methods(http.MethodPost).HandlerFunc(h.Post)
 	r.Path("/compat/wakatime/v1/users/{user}/heartbeats.bulk").Methods(http.MethodPost).HandlerFunc(h.Post)
+
+	// imports are not relayed to wakatime
+	ri := router.PathPrefix("/heartbeats/import").Subrouter()
+	ri.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+	ri.Path("").Methods(http.MethodPost).HandlerFunc(h.Import)
+
+	rx := router.PathPrefix("/heartbeats/export").Subrouter()
+	rx.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+	rx.Path("").Methods(http.MethodGet).HandlerFunc(h.GetExport)
+
+	rl := router.PathPrefix("/heartbeats/latest").Subrouter()
+	rl.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+	rl.Path("").Methods(http.MethodGet).HandlerFunc(h.GetLatest)
+
+	// edits are not relayed to wakatime either
+	re := router.PathPrefix("/heartbeats/{id:[0-9]+}").Subrouter()
+	re.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler)
+	re.Path("").Methods(http.MethodPatch).HandlerFunc(h.Patch)
+	re.Path("").Methods(http.MethodDelete).HandlerFunc(h.Delete)
 }
 
 // @Summary Push a new heartbeat
 // @ID post-heartbeat
 // @Tags heartbeat
 // @Accept json
+// @Accept x-ndjson
+// @Accept application/msgpack
 // @Param heartbeat body models.Heartbeat true "A single heartbeat"
+// @Param Idempotency-Key header string false "Unique key of this request, retries with the same key are answered with the original response"
 // @Security ApiKeyAuth
 // @Success 201
+// @Failure 403 "Storage quota exceeded"
+// @Failure 413 "Request body too large"
+// @Failure 422 "Too many heartbeats within a single request"
+// @Failure 429 "Hourly heartbeat quota exceeded, see X-RateLimit-* headers"
 // @Router /heartbeat [post]
 func (h *HeartbeatApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
@@ -65,56 +145,587 @@ func (h *HeartbeatApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 		return // response was already sent by util function
 	}
 
+	if routeutils.IsNdjson(r) {
+		h.postStream(w, r, user)
+		return
+	}
+
 	var heartbeats []*models.Heartbeat
 	heartbeats, err = routeutils.ParseHeartbeats(r)
+	if err == utils.ErrBodyTooLarge {
+		utils.RespondBodyTooLarge(w, r, h.config.App.GetHeartbeatsMaxBodySize())
+		return
+	}
 	if err != nil {
 		conf.Log().Request(r).Error(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(err.Error()))
+		respondInvalidHeartbeats(w, r, err)
+		return
+	}
+
+	if limit := h.maxHeartbeats(false); limit > 0 && len(heartbeats) > limit {
+		respondTooManyHeartbeats(w, r, limit)
+		return
+	}
+
+	if !h.consumeQuota(w, user, len(heartbeats)) {
+		h.respondQuotaExceeded(w, r, user)
 		return
 	}
 
+	if !h.allowsStorage(r, user, len(heartbeats)) {
+		h.respondStorageQuotaExceeded(w, r, user)
+		return
+	}
+
+	accepted, statuses, err := h.filterHeartbeats(r, user, heartbeats, false)
+	if err != nil {
+		respondInvalidHeartbeats(w, r, err)
+		return
+	}
+
+	if len(accepted) < len(heartbeats) {
+		logbuch.Warn("rejected %d heartbeats of user '%s' outside the acceptance window or by scripts", len(heartbeats)-len(accepted), user.ID)
+	}
+
+	if len(accepted) == 0 {
+		utils.RespondNegotiated(w, r, http.StatusCreated, constructResponse(statuses))
+		return
+	}
+
+	if err := h.heartbeatSrvc.InsertBatch(accepted); err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to batch-insert heartbeats - %v", err)
+		return
+	}
+
+	if err := h.setHasData(user); err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to update user - %v", err)
+		return
+	}
+
+	utils.RespondNegotiated(w, r, http.StatusCreated, constructResponse(statuses))
+}
+
+// postStream handles newline-delimited json bodies, which are parsed and stored batch by batch instead of as a whole.
+// As preceding batches are already stored, invalid heartbeats are rejected individually instead of failing the entire request.
+func (h *HeartbeatApiHandler) postStream(w http.ResponseWriter, r *http.Request, user *models.User) {
+	var insertErr error
+	var numAccepted int
+	statuses := make([]int, 0)
+	limit := h.maxHeartbeats(true)
+
+	err := routeutils.StreamHeartbeats(r.Body, h.config.App.ImportBatchSize, func(heartbeats []*models.Heartbeat) error {
+		if limit > 0 && len(statuses)+len(heartbeats) > limit {
+			return errTooManyHeartbeats
+		}
+		if !h.consumeQuota(w, user, len(heartbeats)) {
+			return errQuotaExceeded
+		}
+		if !h.allowsStorage(r, user, len(heartbeats)) {
+			return errStorageQuotaExceeded
+		}
+		accepted, batchStatuses, _ := h.filterHeartbeats(r, user, heartbeats, true)
+		statuses = append(statuses, batchStatuses...)
+		if len(accepted) == 0 {
+			return nil
+		}
+		if insertErr = h.heartbeatSrvc.InsertBatch(accepted); insertErr != nil {
+			return insertErr
+		}
+		numAccepted += len(accepted)
+		return nil
+	})
+
+	if insertErr != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to batch-insert heartbeats - %v", insertErr)
+		return
+	}
+	if err == errQuotaExceeded {
+		h.respondQuotaExceeded(w, r, user) // preceding batches were stored nevertheless
+		return
+	}
+	if err == errStorageQuotaExceeded {
+		h.respondStorageQuotaExceeded(w, r, user)
+		return
+	}
+	if err == errTooManyHeartbeats {
+		respondTooManyHeartbeats(w, r, limit)
+		return
+	}
+	if err == utils.ErrBodyTooLarge {
+		utils.RespondBodyTooLarge(w, r, h.config.App.GetHeartbeatsMaxBodySize())
+		return
+	}
+	if err != nil {
+		conf.Log().Request(r).Error(err.Error())
+		respondInvalidHeartbeats(w, r, err)
+		return
+	}
+
+	if numAccepted < len(statuses) {
+		logbuch.Warn("rejected %d streamed heartbeats of user '%s' as invalid, outside the acceptance window or by scripts", len(statuses)-numAccepted, user.ID)
+	}
+
+	if numAccepted > 0 {
+		if err := h.setHasData(user); err != nil {
+			utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+			conf.Log().Request(r).Error("failed to update user - %v", err)
+			return
+		}
+	}
+
+	utils.RespondJSON(w, r, http.StatusCreated, constructResponse(statuses))
+}
+
+// consumeQuota takes the given number of heartbeats from the user's hourly quota and tells whether they fit into it, while setting the respective rate limit headers
+func (h *HeartbeatApiHandler) consumeQuota(w http.ResponseWriter, user *models.User, n int) bool {
+	status, ok := h.quotaSrvc.Consume(user, n)
+	if status.IsUnlimited() {
+		return true
+	}
+
+	utils.SetRateLimitHeaders(w, status, !ok)
+	return ok
+}
+
+func (h *HeartbeatApiHandler) respondQuotaExceeded(w http.ResponseWriter, r *http.Request, user *models.User) {
+	utils.RespondError(w, r, http.StatusTooManyRequests, fmt.Sprintf("quota of %d heartbeats per hour exceeded", h.quotaSrvc.GetLimit(user)))
+}
+
+// allowsStorage tells whether another n heartbeats of the user may be stored without exceeding their storage quota.
+// Heartbeats are accepted, if the user's current usage can't be determined, as the quota is not meant to protect against sudden floods anyway.
+func (h *HeartbeatApiHandler) allowsStorage(r *http.Request, user *models.User, n int) bool {
+	ok, err := h.storageQuotaSrvc.Allows(user, n)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to check storage quota of user '%s' - %v", user.ID, err)
+		return true
+	}
+	return ok
+}
+
+func (h *HeartbeatApiHandler) respondStorageQuotaExceeded(w http.ResponseWriter, r *http.Request, user *models.User) {
+	utils.RespondError(w, r, http.StatusForbidden, fmt.Sprintf("storage quota of %d heartbeats exceeded, delete some of your data or ask your administrator to raise the quota", h.storageQuotaSrvc.GetLimit(user)))
+}
+
+// maxHeartbeats returns the maximum number of heartbeats accepted per request (0 = unlimited), where json arrays, which are held in memory as a whole, are additionally limited in size
+func (h *HeartbeatApiHandler) maxHeartbeats(streamed bool) int {
+	limit := h.config.App.HeartbeatsMaxPerReq
+	if batchSize := h.config.App.HeartbeatsMaxBatchSize; !streamed && batchSize > 0 && (limit == 0 || batchSize < limit) {
+		limit = batchSize
+	}
+	return limit
+}
+
+func respondTooManyHeartbeats(w http.ResponseWriter, r *http.Request, limit int) {
+	utils.RespondErrorDetails(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("request exceeds maximum of %d heartbeats, split it into smaller batches", limit), map[string]int{"max_heartbeats": limit})
+}
+
+// respondInvalidHeartbeats responds with details on every invalid field, if known
+func respondInvalidHeartbeats(w http.ResponseWriter, r *http.Request, err error) {
+	if validationErr, ok := err.(models.HeartbeatValidationError); ok {
+		utils.RespondErrorDetails(w, r, http.StatusBadRequest, "invalid heartbeat object", validationErr)
+		return
+	}
+	utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+}
+
+// filterHeartbeats enriches the given heartbeats by request metadata and returns the ones to be stored, along with a status for every heartbeat.
+// Invalid heartbeats fail the entire batch, unless lenient is set, in which case they are only rejected individually.
+// Unless permissive mode is enabled, heartbeats are validated strictly and the resulting error lists all invalid fields of the batch.
+func (h *HeartbeatApiHandler) filterHeartbeats(r *http.Request, user *models.User, heartbeats []*models.Heartbeat, lenient bool) ([]*models.Heartbeat, []int, error) {
 	userAgent := r.Header.Get("User-Agent")
 	opSys, editor, _ := utils.ParseUserAgent(userAgent)
 	machineName := r.Header.Get("X-Machine-Name")
+	origin, originId := h.getOrigin(r)
+
+	now := time.Now()
+	accepted := make([]*models.Heartbeat, 0, len(heartbeats))
+	statuses := make([]int, len(heartbeats))
+	invalid := make(models.HeartbeatValidationError, 0)
+	var hasInvalid bool
+
+	// compensate for misconfigured clocks before checking the acceptance window, relayed heartbeats were already corrected by the relaying instance
+	if origin != models.OriginRelay {
+		h.clockSkewSrvc.Record(user, machineName, heartbeats, now)
+		h.clockSkewSrvc.Correct(user, machineName, heartbeats)
+	}
 
-	for _, hb := range heartbeats {
+	for i, hb := range heartbeats {
 		hb.OperatingSystem = opSys
 		hb.Editor = editor
 		hb.Machine = machineName
 		hb.User = user
 		hb.UserID = user.ID
 		hb.UserAgent = userAgent
+		hb.Origin = origin
+		hb.OriginId = originId
 
-		if !hb.Valid() {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("invalid heartbeat object"))
-			return
+		valid := hb.Valid()
+		if !h.config.App.HeartbeatsPermissive {
+			if errs := hb.Validate(i); len(errs) > 0 {
+				invalid = append(invalid, errs...)
+				valid = false
+			}
+		}
+		if !valid {
+			hasInvalid = true
+			statuses[i] = http.StatusBadRequest
+			continue
+		}
+
+		// drop heartbeats outside the acceptance window, but report them as rejected, so clients do not retry them
+		if !user.AcceptsHeartbeatAt(hb.Time.T(), now, h.config.App.HeartbeatsMaxPastDays, h.config.App.HeartbeatsMaxFutureMin) {
+			statuses[i] = http.StatusBadRequest
+			continue
+		}
+
+		// let scripts transform or drop heartbeats, e.g. to strip sensitive paths
+		if ok, err := h.scriptSrvc.Apply(user, hb); err != nil || !ok || !hb.Valid() {
+			if err != nil {
+				logbuch.Warn("failed to run heartbeat script for user '%s' - %v", user.ID, err)
+			}
+			statuses[i] = http.StatusBadRequest
+			continue
 		}
 
 		hb.Hashed()
+		accepted = append(accepted, hb)
+		statuses[i] = http.StatusCreated
 	}
 
-	if err := h.heartbeatSrvc.InsertBatch(heartbeats); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(conf.ErrInternalServerError))
-		conf.Log().Request(r).Error("failed to batch-insert heartbeats - %v", err)
+	if hasInvalid && !lenient {
+		return nil, nil, invalid
+	}
+	return accepted, statuses, nil
+}
+
+// getOrigin tells whether heartbeats were sent by an editor plugin or a browser extension or were relayed by another wakapi instance, along with that instance's id
+func (h *HeartbeatApiHandler) getOrigin(r *http.Request) (string, string) {
+	if instanceId := r.Header.Get("X-Origin-Instance"); instanceId != "" && instanceId != h.config.InstanceId {
+		return models.OriginRelay, instanceId
+	}
+	if utils.IsBrowserUserAgent(r.Header.Get("User-Agent")) {
+		return models.OriginBrowser, ""
+	}
+	return models.OriginDirect, ""
+}
+
+func (h *HeartbeatApiHandler) setHasData(user *models.User) error {
+	if user.HasData {
+		return nil
+	}
+	user.HasData = true
+	_, err := h.userSrvc.Update(user)
+	return err
+}
+
+// @Summary Import heartbeats, e.g. from a previous data export
+// @Description Accepts a json array or newline-delimited json objects, which are parsed and stored incrementally. Other than heartbeats sent by clients, imported ones keep their editor, operating system and machine and are not subject to the acceptance window. The import's progress is reported at /api/imports/file. If the previous import failed halfway through, uploading the same file again with resume=true skips the rows it processed already.
+// @ID post-heartbeats-import
+// @Tags heartbeat
+// @Accept json
+// @Accept x-ndjson
+// @Produce json
+// @Param heartbeats body []models.Heartbeat true "Heartbeats to import"
+// @Param resume query bool false "Whether to skip the rows processed by the previous, failed import of the same file"
+// @Security ApiKeyAuth
+// @Success 201 {object} heartbeatImportResponseVm
+// @Failure 409 {string} string "an import from this source is already in progress"
+// @Router /heartbeats/import [post]
+func (h *HeartbeatApiHandler) Import(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
 		return
 	}
 
-	if !user.HasData {
-		user.HasData = true
-		if _, err := h.userSrvc.Update(user); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(conf.ErrInternalServerError))
+	var skip int
+	if r.URL.Query().Get("resume") == "true" {
+		if previous, err := h.importSrvc.Get(user, imports.OriginFile); err == nil && previous != nil && previous.IsDone() && previous.IsResumable() {
+			skip = previous.Offset
+		}
+	}
+
+	job, err := h.importSrvc.Begin(user, imports.OriginFile, time.Time{}, time.Time{})
+	if err == services.ErrImportInProgress {
+		utils.RespondError(w, r, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to begin import - %v", err)
+		return
+	}
+
+	var insertErr error
+	var result heartbeatImportResponseVm
+
+	err = routeutils.StreamHeartbeats(r.Body, h.config.App.ImportBatchSize, func(heartbeats []*models.Heartbeat) error {
+		// rows are processed in order, so the ones before the previous import's offset were stored already
+		skipped := len(heartbeats)
+		if skip < skipped {
+			skipped = skip
+		}
+		skip -= skipped
+		result.Skipped += skipped
+
+		batch := make([]*models.Heartbeat, 0, len(heartbeats)-skipped)
+		for _, hb := range heartbeats[skipped:] {
+			hb.ID = 0 // exports include primary keys
+			hb.User = user
+			hb.UserID = user.ID
+			hb.Origin = imports.OriginFile
+
+			if !hb.Valid() {
+				result.Rejected++
+				continue
+			}
+			// imported heartbeats are subject to the same scripts as ones sent by clients
+			if ok, err := h.scriptSrvc.Apply(user, hb); err != nil || !ok || !hb.Valid() {
+				result.Rejected++
+				continue
+			}
+			hb.Hashed()
+			batch = append(batch, hb)
+		}
+		if len(batch) > 0 {
+			if insertErr = h.heartbeatSrvc.InsertBatch(batch); insertErr != nil {
+				return insertErr
+			}
+			result.Imported += len(batch)
+		}
+
+		h.importSrvc.Update(user, job, func(job *models.ImportJob) {
+			job.Offset += len(heartbeats)
+			job.Rows += len(heartbeats) - skipped
+			job.Imported, job.Rejected = result.Imported, result.Rejected
+		})
+		return nil
+	})
+
+	if insertErr != nil {
+		h.importSrvc.Finish(user, job, insertErr)
+	} else {
+		h.importSrvc.Finish(user, job, err)
+	}
+
+	if insertErr != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to batch-insert imported heartbeats - %v", insertErr)
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if result.Imported > 0 {
+		if err := h.setHasData(user); err != nil {
+			utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
 			conf.Log().Request(r).Error("failed to update user - %v", err)
 			return
 		}
 	}
 
-	defer func() {}()
+	logbuch.Info("imported %d heartbeats for user '%s' (%d rejected)", result.Imported, user.ID, result.Rejected)
+	utils.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Retrieve the latest heartbeat's time, editor and agent version per machine and origin
+// @Description Intended to check which of the user's devices are actively reporting. Most recently active ones come first.
+// @ID get-heartbeats-latest
+// @Tags heartbeat
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.OriginActivity
+// @Router /heartbeats/latest [get]
+func (h *HeartbeatApiHandler) GetLatest(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	activities, err := h.heartbeatSrvc.GetOriginActivityByUser(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to fetch latest activity per origin for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, activities)
+}
+
+// @Summary Export all of the user's heartbeats page by page
+// @Description Heartbeats are ordered by the order they were stored in. Intended for migrating to another wakapi instance, which keeps requesting pages after the last one's next_after_id until has_more is false.
+// @ID get-heartbeats-export
+// @Tags heartbeat
+// @Produce json
+// @Param after_id query int false "Only return heartbeats stored after the one with this id"
+// @Param limit query int false "Max. number of heartbeats per page (at most 1000)"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.HeartbeatPage
+// @Router /heartbeats/export [get]
+func (h *HeartbeatApiHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var afterId uint64
+	if param := r.URL.Query().Get("after_id"); param != "" {
+		id, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			utils.RespondError(w, r, http.StatusBadRequest, "invalid after_id")
+			return
+		}
+		afterId = id
+	}
+
+	limit := heartbeatsExportPageSize
+	if param := r.URL.Query().Get("limit"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n <= 0 {
+			utils.RespondError(w, r, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		if n < limit {
+			limit = n
+		}
+	}
 
-	utils.RespondJSON(w, r, http.StatusCreated, constructSuccessResponse(len(heartbeats)))
+	heartbeats, err := h.heartbeatSrvc.GetPageByUser(user, afterId, limit)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to export heartbeats of user '%s' - %v", user.ID, err)
+		return
+	}
+
+	page := &models.HeartbeatPage{Data: heartbeats, NextAfterId: afterId, HasMore: len(heartbeats) == limit}
+	if len(heartbeats) > 0 {
+		page.NextAfterId = heartbeats[len(heartbeats)-1].ID
+	}
+	utils.RespondJSON(w, r, http.StatusOK, page)
+}
+
+// @Summary Change the project, language or branch of a single heartbeat, e.g. to fix a mis-attributed one
+// @Description The summary of the heartbeat's day is recomputed during the next aggregation run. Heartbeat ids are included in responses of the WakaTime-compatible heartbeats endpoint.
+// @ID patch-heartbeat
+// @Tags heartbeat
+// @Accept json
+// @Produce json
+// @Param id path int true "Heartbeat ID"
+// @Param heartbeat body heartbeatUpdatePayload true "Fields to change"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Heartbeat
+// @Failure 404 {object} models.ApiError "heartbeat not found"
+// @Failure 409 {object} models.ApiError "the edited heartbeat would duplicate an existing one"
+// @Router /heartbeats/{id} [patch]
+func (h *HeartbeatApiHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var payload heartbeatUpdatePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+	if payload.Project == nil && payload.Language == nil && payload.Branch == nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "nothing to update")
+		return
+	}
+	if payload.Project != nil && *payload.Project == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "project must not be empty")
+		return
+	}
+
+	heartbeat, err := h.heartbeatSrvc.GetById(id)
+	if err != nil || heartbeat.UserID != user.ID {
+		utils.RespondError(w, r, http.StatusNotFound, "heartbeat not found")
+		return
+	}
+
+	heartbeat.User = user
+	if payload.Project != nil {
+		heartbeat.Project = *payload.Project
+	}
+	if payload.Language != nil {
+		heartbeat.Language = *payload.Language
+	}
+	if payload.Branch != nil {
+		heartbeat.Branch = *payload.Branch
+	}
+
+	result, err := h.heartbeatSrvc.Update(heartbeat)
+	if err == repositories.ErrDuplicateHeartbeat {
+		utils.RespondError(w, r, http.StatusConflict, "an identical heartbeat exists already, delete this one instead")
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to update heartbeat %d of user '%s' - %v", id, user.ID, err)
+		return
+	}
+
+	logbuch.Info("user '%s' edited heartbeat %d", user.ID, id)
+	utils.RespondJSON(w, r, http.StatusOK, result)
+}
+
+// @Summary Delete a single heartbeat
+// @Description Unless disabled, the deletion can be undone for a while using the returned tombstone's id, see /undo. The summary of the heartbeat's day is recomputed during the next aggregation run.
+// @ID delete-heartbeat
+// @Tags heartbeat
+// @Produce json
+// @Param id path int true "Heartbeat ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Tombstone
+// @Success 204 "undo is disabled"
+// @Failure 404 {object} models.ApiError "heartbeat not found"
+// @Router /heartbeats/{id} [delete]
+func (h *HeartbeatApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	heartbeat, err := h.heartbeatSrvc.GetById(id)
+	if err != nil || heartbeat.UserID != user.ID {
+		utils.RespondError(w, r, http.StatusNotFound, "heartbeat not found")
+		return
+	}
+	heartbeat.User = user
+
+	tombstone, err := h.heartbeatSrvc.Delete(heartbeat)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to delete heartbeat %d of user '%s' - %v", id, user.ID, err)
+		return
+	}
+
+	logbuch.Info("user '%s' deleted heartbeat %d", user.ID, id)
+	if tombstone == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	utils.RespondJSON(w, r, http.StatusOK, tombstone)
 }
 
 // construct weird response format (see https://github.com/wakatime/wakatime/blob/2e636d389bf5da4e998e05d5285a96ce2c181e3d/wakatime/api.py#L288)
@@ -123,13 +734,13 @@ func (h *HeartbeatApiHandler) Post(w http.ResponseWriter, r *http.Request) {
 // this was probably a temporary bug at wakatime, responses actually looks like so: https://pastr.de/p/nyf6kj2e6843fbw4xkj4h4pj
 // TODO: adapt response format some time
 // however, wakatime-cli is still able to parse the response (see https://github.com/wakatime/wakatime-cli/blob/c2076c0e1abc1449baf5b7ac7db391b06041c719/pkg/api/heartbeat.go#L127), so no urgent need for action
-func constructSuccessResponse(n int) *heartbeatResponseVm {
-	responses := make([][]interface{}, n)
+func constructResponse(statuses []int) *heartbeatResponseVm {
+	responses := make([][]interface{}, len(statuses))
 
-	for i := 0; i < n; i++ {
+	for i, status := range statuses {
 		r := make([]interface{}, 2)
 		r[0] = nil
-		r[1] = http.StatusCreated
+		r[1] = status
 		responses[i] = r
 	}
 
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type HeartbeatScriptApiHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	scriptSrvc services.IHeartbeatScriptService
+}
+
+type heartbeatScriptVm struct {
+	Script string `json:"script"` // empty to disable
+}
+
+func NewHeartbeatScriptApiHandler(userService services.IUserService, heartbeatScriptService services.IHeartbeatScriptService) *HeartbeatScriptApiHandler {
+	return &HeartbeatScriptApiHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		scriptSrvc: heartbeatScriptService,
+	}
+}
+
+func (h *HeartbeatScriptApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/admin/heartbeat_scripts").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("/{user}").Methods(http.MethodGet).HandlerFunc(h.Get)
+	r.Path("/{user}").Methods(http.MethodPut).HandlerFunc(h.Put)
+}
+
+// @Summary Retrieve a user's heartbeat script
+// @Description Only available to admin users
+// @ID get-heartbeat-script
+// @Tags admin
+// @Produce json
+// @Param user path string true "User ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} heartbeatScriptVm
+// @Router /admin/heartbeat_scripts/{user} [get]
+func (h *HeartbeatScriptApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	user, err := middlewares.GetUserById(r, h.userSrvc, mux.Vars(r)["user"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "user not found")
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, &heartbeatScriptVm{Script: user.HeartbeatScript})
+}
+
+// @Summary Set a user's heartbeat script
+// @Description Only available to admin users, as scripts are trusted code, whose memory usage is not limited. Requires user heartbeat scripts to be enabled.
+// @ID put-heartbeat-script
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID"
+// @Param script body heartbeatScriptVm true "Lua script defining a function transform(hb)"
+// @Security ApiKeyAuth
+// @Success 200 {object} heartbeatScriptVm
+// @Router /admin/heartbeat_scripts/{user} [put]
+func (h *HeartbeatScriptApiHandler) Put(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+	if !h.config.App.UserHeartbeatScripts {
+		utils.RespondError(w, r, http.StatusForbidden, "heartbeat scripts are disabled on this server")
+		return
+	}
+
+	var payload heartbeatScriptVm
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+	script := strings.TrimSpace(payload.Script)
+	if script != "" {
+		if err := h.scriptSrvc.Validate(script); err != nil {
+			utils.RespondError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid script: %v", err))
+			return
+		}
+	}
+
+	user, err := middlewares.GetUserById(r, h.userSrvc, mux.Vars(r)["user"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "user not found")
+		return
+	}
+
+	user.HeartbeatScript = script
+	if _, err := h.userSrvc.Update(user); err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to update heartbeat script of user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, &heartbeatScriptVm{Script: user.HeartbeatScript})
+}
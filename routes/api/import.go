@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type ImportApiHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	importSrvc services.IImportService
+}
+
+type importJobVm struct {
+	*models.ImportJob
+	Progress  int  `json:"progress"` // in percent
+	Resumable bool `json:"resumable"`
+}
+
+func NewImportApiHandler(userService services.IUserService, importService services.IImportService) *ImportApiHandler {
+	return &ImportApiHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		importSrvc: importService,
+	}
+}
+
+func (h *ImportApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/imports").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.GetAll)
+	r.Path("/{source}").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the progress of the user's latest import from every source
+// @ID get-imports
+// @Tags import
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} importJobVm
+// @Router /imports [get]
+func (h *ImportApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	jobs, err := h.importSrvc.GetAll(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to get imports for user %s - %v", user.ID, err)
+		return
+	}
+
+	vms := make([]*importJobVm, len(jobs))
+	for i, job := range jobs {
+		vms[i] = newImportJobVm(job)
+	}
+	utils.RespondJSON(w, r, http.StatusOK, vms)
+}
+
+// @Summary Retrieve the progress of the user's latest import from a source
+// @Description Reports the rows processed, heartbeats imported and rejected, errors and, while running, the estimated time of completion. If some days could not be imported, the import is resumed from its checkpoint when started again.
+// @ID get-import
+// @Tags import
+// @Produce json
+// @Param source path string true "Import source, one of 'wakatime', 'codestats' or 'file'"
+// @Security ApiKeyAuth
+// @Success 200 {object} importJobVm
+// @Router /imports/{source} [get]
+func (h *ImportApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	job, err := h.importSrvc.Get(user, mux.Vars(r)["source"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to get import for user %s - %v", user.ID, err)
+		return
+	}
+	if job == nil {
+		utils.RespondError(w, r, http.StatusNotFound, "no import from this source, yet")
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, newImportJobVm(job))
+}
+
+func newImportJobVm(job *models.ImportJob) *importJobVm {
+	return &importJobVm{
+		ImportJob: job,
+		Progress:  job.Progress(),
+		Resumable: job.IsDone() && job.IsResumable(),
+	}
+}
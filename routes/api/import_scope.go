@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+// import scopes can be granted for at most a week at once
+const maxImportScopeHours = 7 * 24
+
+type ImportScopeApiHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+}
+
+type importScopeUpdateVm struct {
+	Hours int `json:"hours"` // for how long to accept heartbeats of any age, 0 to revoke
+}
+
+type importScopeVm struct {
+	UserID string             `json:"user_id"`
+	Until  *models.CustomTime `json:"until" swaggertype:"string" format:"date" example:"2006-01-02 15:04:05.000"` // null if not granted
+}
+
+func NewImportScopeApiHandler(userService services.IUserService) *ImportScopeApiHandler {
+	return &ImportScopeApiHandler{
+		config:   conf.Get(),
+		userSrvc: userService,
+	}
+}
+
+func (h *ImportScopeApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/admin/import_scopes").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+		routeutils.AdminOnly,
+	)
+	r.Path("/{user}").Methods(http.MethodGet).HandlerFunc(h.Get)
+	r.Path("/{user}").Methods(http.MethodPut).HandlerFunc(h.Put)
+}
+
+// @Summary Retrieve until when a user's heartbeat acceptance window is lifted for backfilling data
+// @Description Only available to admin users
+// @ID get-import-scope
+// @Tags admin
+// @Produce json
+// @Param user path string true "User ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} importScopeVm
+// @Router /admin/import_scopes/{user} [get]
+func (h *ImportScopeApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := middlewares.GetUserById(r, h.userSrvc, mux.Vars(r)["user"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "user not found")
+		return
+	}
+
+	h.respondImportScope(w, r, user)
+}
+
+// @Summary Temporarily lift a user's heartbeat acceptance window for backfilling data
+// @Description Only available to admin users. While granted, heartbeats sent with the user's api key are accepted regardless of their age, overriding app.heartbeats_max_past_days. Set hours to 0 to revoke.
+// @ID put-import-scope
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID"
+// @Param scope body importScopeUpdateVm true "Duration of the import scope"
+// @Security ApiKeyAuth
+// @Success 200 {object} importScopeVm
+// @Router /admin/import_scopes/{user} [put]
+func (h *ImportScopeApiHandler) Put(w http.ResponseWriter, r *http.Request) {
+	var payload importScopeUpdateVm
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Hours < 0 || payload.Hours > maxImportScopeHours {
+		utils.RespondError(w, r, http.StatusBadRequest, fmt.Sprintf("hours must be between 0 and %d", maxImportScopeHours))
+		return
+	}
+
+	user, err := middlewares.GetUserById(r, h.userSrvc, mux.Vars(r)["user"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "user not found")
+		return
+	}
+
+	user.ImportScopeUntil = nil
+	if payload.Hours > 0 {
+		until := models.CustomTime(time.Now().Add(time.Duration(payload.Hours) * time.Hour))
+		user.ImportScopeUntil = &until
+	}
+	if _, err := h.userSrvc.Update(user); err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to update import scope of user '%s' - %v", user.ID, err)
+		return
+	}
+
+	h.respondImportScope(w, r, user)
+}
+
+func (h *ImportScopeApiHandler) respondImportScope(w http.ResponseWriter, r *http.Request, user *models.User) {
+	vm := &importScopeVm{UserID: user.ID}
+	if user.HasImportScope(time.Now()) {
+		vm.Until = user.ImportScopeUntil
+	}
+	utils.RespondJSON(w, r, http.StatusOK, vm)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImportScopeApiHandler_Put(t *testing.T) {
+	user := &models.User{ID: "user1", ApiKey: "user-key"}
+	router, userService := setupImportScopeRouter(user)
+	userService.On("GetUserById", user.ID).Return(user, nil)
+	userService.On("Update", user).Return(user, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/admin/import_scopes/user1?api_key=admin-key", strings.NewReader(`{"hours": 24}`)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var result importScopeVm
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, user.ID, result.UserID)
+	assert.NotNil(t, result.Until)
+	assert.True(t, user.HasImportScope(time.Now().Add(23*time.Hour)))
+	assert.False(t, user.HasImportScope(time.Now().Add(25*time.Hour)))
+
+	// revoke
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/admin/import_scopes/user1?api_key=admin-key", strings.NewReader(`{"hours": 0}`)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, user.ImportScopeUntil)
+}
+
+func TestImportScopeApiHandler_Put_Invalid(t *testing.T) {
+	router, userService := setupImportScopeRouter(nil)
+
+	for _, body := range []string{`{"hours": -1}`, `{"hours": 169}`, `hours`} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/admin/import_scopes/user1?api_key=admin-key", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	userService.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestImportScopeApiHandler_Put_NonAdmin(t *testing.T) {
+	user := &models.User{ID: "user1", ApiKey: "user-key"}
+	router, userService := setupImportScopeRouter(user)
+
+	// users must not lift their own acceptance window
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/admin/import_scopes/user1?api_key=user-key", strings.NewReader(`{"hours": 24}`)))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Nil(t, user.ImportScopeUntil)
+	userService.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestImportScopeApiHandler_Get(t *testing.T) {
+	expired := models.CustomTime(time.Now().Add(-time.Hour))
+	user := &models.User{ID: "user1", ApiKey: "user-key", ImportScopeUntil: &expired}
+	router, userService := setupImportScopeRouter(user)
+	userService.On("GetUserById", user.ID).Return(user, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/import_scopes/user1?api_key=admin-key", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var result importScopeVm
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Nil(t, result.Until) // expired scopes are not granted anymore
+}
+
+func setupImportScopeRouter(user *models.User) (*mux.Router, *mocks.UserServiceMock) {
+	config.Set(&config.Config{})
+
+	userService := new(mocks.UserServiceMock)
+	userService.On("GetUserByKey", "admin-key").Return(&models.User{ID: "admin", ApiKey: "admin-key", IsAdmin: true}, nil)
+	if user != nil {
+		userService.On("GetUserByKey", user.ApiKey).Return(user, nil)
+	}
+
+	router := mux.NewRouter()
+	router.Use(middlewares.NewPrincipalMiddleware())
+	NewImportScopeApiHandler(userService).RegisterRoutes(router.PathPrefix("/api").Subrouter())
+	return router, userService
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type InstanceApiHandler struct {
+	config   *conf.Config
+	miscSrvc services.IMiscService
+}
+
+func NewInstanceApiHandler(miscService services.IMiscService) *InstanceApiHandler {
+	return &InstanceApiHandler{
+		config:   conf.Get(),
+		miscSrvc: miscService,
+	}
+}
+
+func (h *InstanceApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/instance").Subrouter()
+	r.Path("/stats").Methods(http.MethodGet).HandlerFunc(h.GetStats)
+}
+
+// @Summary Retrieve anonymous, aggregated stats of the entire instance
+// @Description Only available if enabled by the admin. Stats are updated hourly.
+// @ID get-instance-stats
+// @Tags misc
+// @Produce json
+// @Success 200 {object} models.InstanceStats
+// @Failure 404 {string} string "public instance stats are disabled"
+// @Router /instance/stats [get]
+func (h *InstanceApiHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.miscSrvc.GetInstanceStats()
+	if err == services.ErrInstanceStatsDisabled {
+		utils.RespondError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusServiceUnavailable, "instance stats are not available yet")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	utils.RespondJSON(w, r, http.StatusOK, stats)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type JobApiHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+	jobSrvc  services.IJobService
+}
+
+func NewJobApiHandler(userService services.IUserService, jobService services.IJobService) *JobApiHandler {
+	return &JobApiHandler{
+		config:   conf.Get(),
+		userSrvc: userService,
+		jobSrvc:  jobService,
+	}
+}
+
+func (h *JobApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/admin/jobs").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the status of all background jobs run since server start
+// @Description Only available to admin users
+// @ID get-jobs
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.JobStatus
+// @Router /admin/jobs [get]
+func (h *JobApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, h.jobSrvc.GetAll())
+}
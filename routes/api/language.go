@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type LanguageApiHandler struct {
+	config       *conf.Config
+	userSrvc     services.IUserService
+	languageSrvc services.ILanguageMetaService
+}
+
+type languageOverrideVm struct {
+	Name  string `json:"name"`  // display name, e.g. to fix the case, defaults to the language in the path
+	Color string `json:"color"` // hex color, built-in one if empty
+	Icon  string `json:"icon"`  // iconify icon id, built-in one if empty
+}
+
+func NewLanguageApiHandler(userService services.IUserService, languageMetaService services.ILanguageMetaService) *LanguageApiHandler {
+	return &LanguageApiHandler{
+		config:       conf.Get(),
+		userSrvc:     userService,
+		languageSrvc: languageMetaService,
+	}
+}
+
+func (h *LanguageApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("/languages").Methods(http.MethodGet).HandlerFunc(h.GetAll)
+	r.Path("/admin/languages/{language}").Methods(http.MethodPut).HandlerFunc(h.Put)
+	r.Path("/admin/languages/{language}").Methods(http.MethodDelete).HandlerFunc(h.Delete)
+}
+
+// @Summary Retrieve display names, colors and icons of all known languages
+// @ID get-languages
+// @Tags languages
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.LanguageMeta
+// @Router /languages [get]
+func (h *LanguageApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	if middlewares.GetPrincipal(r) == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+	utils.RespondJSON(w, r, http.StatusOK, h.languageSrvc.GetAll())
+}
+
+// @Summary Override the display name, color or icon of a language or add a custom one
+// @Description Only available to admin users. Empty properties keep their built-in values.
+// @ID put-language
+// @Tags languages
+// @Accept json
+// @Produce json
+// @Param language path string true "Language, e.g. 'Go'"
+// @Param language body languageOverrideVm true "Language meta data"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.LanguageMeta
+// @Router /admin/languages/{language} [put]
+func (h *LanguageApiHandler) Put(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	var payload languageOverrideVm
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	// display names may only differ from the language in case, as summaries are keyed by the lower-case name
+	language := mux.Vars(r)["language"]
+	if payload.Name == "" {
+		payload.Name = language
+	} else if !strings.EqualFold(payload.Name, language) {
+		utils.RespondError(w, r, http.StatusBadRequest, "name must only differ from the language in case")
+		return
+	}
+
+	meta, err := h.languageSrvc.PutOverride(&models.LanguageMeta{Name: payload.Name, Color: payload.Color, Icon: payload.Icon})
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, meta)
+}
+
+// @Summary Revert a language to its built-in display name, color and icon or remove a custom one
+// @Description Only available to admin users
+// @ID delete-language
+// @Tags languages
+// @Param language path string true "Language, e.g. 'Go'"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /admin/languages/{language} [delete]
+func (h *LanguageApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	if err := h.languageSrvc.DeleteOverride(mux.Vars(r)["language"]); err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to delete language override - %v", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
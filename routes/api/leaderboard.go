@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type LeaderboardApiHandler struct {
+	config          *conf.Config
+	leaderboardSrvc services.ILeaderboardService
+}
+
+func NewLeaderboardApiHandler(leaderboardService services.ILeaderboardService) *LeaderboardApiHandler {
+	return &LeaderboardApiHandler{
+		config:          conf.Get(),
+		leaderboardSrvc: leaderboardService,
+	}
+}
+
+func (h *LeaderboardApiHandler) RegisterRoutes(router *mux.Router) {
+	router.Path("/leaderboard").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the leaderboard
+// @Description Ranks all users, who opted in, by their coding time of the past 7 days. Only available if enabled by the admin. The leaderboard is regenerated on a schedule, not on every request.
+// @ID get-leaderboard
+// @Tags leaderboard
+// @Produce json
+// @Success 200 {array} models.LeaderboardItem
+// @Failure 404 {string} string "leaderboard is disabled"
+// @Router /leaderboard [get]
+func (h *LeaderboardApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	items, err := h.leaderboardSrvc.GetLeaderboard()
+	if err == services.ErrLeaderboardDisabled {
+		utils.RespondError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		conf.Log().Request(r).Error("failed to get leaderboard - %v", err)
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, items)
+}
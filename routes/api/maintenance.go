@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type MaintenanceApiHandler struct {
+	config          *conf.Config
+	userSrvc        services.IUserService
+	maintenanceSrvc services.IMaintenanceService
+}
+
+type maintenanceUpdateVm struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+}
+
+func NewMaintenanceApiHandler(userService services.IUserService, maintenanceService services.IMaintenanceService) *MaintenanceApiHandler {
+	return &MaintenanceApiHandler{
+		config:          conf.Get(),
+		userSrvc:        userService,
+		maintenanceSrvc: maintenanceService,
+	}
+}
+
+func (h *MaintenanceApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/admin/maintenance").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+	r.Path("").Methods(http.MethodPut).HandlerFunc(h.Put)
+}
+
+// @Summary Retrieve whether the instance is in maintenance mode
+// @Description Only available to admin users
+// @ID get-maintenance
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Maintenance
+// @Router /admin/maintenance [get]
+func (h *MaintenanceApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+	utils.RespondJSON(w, r, http.StatusOK, h.maintenanceSrvc.Get())
+}
+
+// @Summary Enable or disable maintenance mode
+// @Description Only available to admin users. While in maintenance mode, all write requests (e.g. heartbeats or settings) are rejected with status 503, while reads keep working.
+// @ID put-maintenance
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param maintenance body maintenanceUpdateVm true "Maintenance mode and an optional message to show to users"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Maintenance
+// @Router /admin/maintenance [put]
+func (h *MaintenanceApiHandler) Put(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	var payload maintenanceUpdateVm
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	var state *models.Maintenance
+	var err error
+	if payload.Enabled {
+		state, err = h.maintenanceSrvc.Enable(payload.Message)
+	} else {
+		state, err = h.maintenanceSrvc.Disable()
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to update maintenance mode - %v", err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, state)
+}
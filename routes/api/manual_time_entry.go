@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type ManualTimeEntryApiHandler struct {
+	config              *conf.Config
+	userSrvc            services.IUserService
+	manualTimeEntrySrvc services.IManualTimeEntryService
+}
+
+func NewManualTimeEntryApiHandler(userService services.IUserService, manualTimeEntryService services.IManualTimeEntryService) *ManualTimeEntryApiHandler {
+	return &ManualTimeEntryApiHandler{
+		config:              conf.Get(),
+		userSrvc:            userService,
+		manualTimeEntrySrvc: manualTimeEntryService,
+	}
+}
+
+type manualTimeEntryPayload struct {
+	Project  string `json:"project"`
+	Date     string `json:"date"`     // e.g. '2021-02-07' or '2021-02-07 09:30:00', in the user's time zone
+	Duration int64  `json:"duration"` // in seconds
+	Note     string `json:"note"`
+}
+
+func (h *ManualTimeEntryApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/manual").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+	r.Path("/{id}").Methods(http.MethodDelete).HandlerFunc(h.Delete)
+}
+
+// @Summary Retrieve manually added time entries
+// @ID get-manual-time-entries
+// @Tags manual
+// @Produce json
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.ManualTimeEntry
+// @Router /manual [get]
+func (h *ManualTimeEntryApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var entries []*models.ManualTimeEntry
+	var err error
+
+	fromParam, toParam := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if fromParam == "" && toParam == "" {
+		entries, err = h.manualTimeEntrySrvc.GetByUser(user.ID)
+	} else {
+		from, err1 := utils.ParseDateTimeTZ(fromParam, user.TZ())
+		to, err2 := utils.ParseDateTimeTZ(toParam, user.TZ())
+		if err1 != nil || err2 != nil {
+			utils.RespondError(w, r, http.StatusBadRequest, "missing or invalid 'from' or 'to' parameter")
+			return
+		}
+		entries, err = h.manualTimeEntrySrvc.GetByUserWithin(user.ID, from, to)
+	}
+
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to retrieve manual time entries for user %s - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, entries)
+}
+
+// @Summary Manually add a block of time to a project
+// @ID post-manual-time-entry
+// @Tags manual
+// @Accept json
+// @Produce json
+// @Param entry body manualTimeEntryPayload true "A single manual time entry, duration given in seconds"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.ManualTimeEntry
+// @Router /manual [post]
+func (h *ManualTimeEntryApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var payload manualTimeEntryPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	date, err := utils.ParseDateTimeTZ(payload.Date, user.TZ())
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid date")
+		return
+	}
+
+	entry := &models.ManualTimeEntry{
+		UserID:   user.ID,
+		Project:  payload.Project,
+		Date:     models.CustomTime(date),
+		Duration: time.Duration(payload.Duration) * time.Second,
+		Note:     payload.Note,
+	}
+	if !entry.IsValid() {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid manual time entry")
+		return
+	}
+
+	result, err := h.manualTimeEntrySrvc.Create(entry)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to insert manual time entry for user %s - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Delete a manually added time entry
+// @ID delete-manual-time-entry
+// @Tags manual
+// @Param id path int true "Entry ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /manual/{id} [delete]
+func (h *ManualTimeEntryApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	entry, err := h.manualTimeEntrySrvc.GetById(uint(id))
+	if err != nil || entry.UserID != user.ID {
+		utils.RespondError(w, r, http.StatusNotFound, "entry not found")
+		return
+	}
+
+	if err := h.manualTimeEntrySrvc.Delete(entry); err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to delete manual time entry %d for user %s - %v", entry.ID, user.ID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
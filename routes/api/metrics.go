@@ -9,11 +9,13 @@ import (
 	"github.com/muety/wakapi/models"
 	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
 	mm "github.com/muety/wakapi/models/metrics"
+	"github.com/muety/wakapi/repositories"
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
 	"net/http"
 	"runtime"
 	"sort"
+	"strconv"
 	"time"
 )
 
@@ -36,6 +38,12 @@ const (
 	DescAdminTotalUsers      = "Total number of registered users."
 	DescAdminActiveUsers     = "Number of active users."
 
+	DescAdminQueries       = "Total number of database queries by repository method."
+	DescAdminQueryDuration = "Total milliseconds spent on database queries by repository method."
+	DescAdminQueryRows     = "Total number of rows returned or affected by database queries by repository method."
+
+	DescAdminRouteRequests = "Total number of requests by api route and client."
+
 	DescMemAllocTotal = "Total number of bytes allocated for heap"
 	DescMemSysTotal   = "Total number of bytes obtained from the OS"
 	DescGoroutines    = "Total number of running goroutines"
@@ -47,14 +55,18 @@ type MetricsHandler struct {
 	summarySrvc   services.ISummaryService
 	heartbeatSrvc services.IHeartbeatService
 	keyValueSrvc  services.IKeyValueService
+	queryMetrics  *repositories.QueryMetrics
+	routeMetrics  *middlewares.RouteMetrics
 }
 
-func NewMetricsHandler(userService services.IUserService, summaryService services.ISummaryService, heartbeatService services.IHeartbeatService, keyValueService services.IKeyValueService) *MetricsHandler {
+func NewMetricsHandler(userService services.IUserService, summaryService services.ISummaryService, heartbeatService services.IHeartbeatService, keyValueService services.IKeyValueService, queryMetrics *repositories.QueryMetrics, routeMetrics *middlewares.RouteMetrics) *MetricsHandler {
 	return &MetricsHandler{
 		userSrvc:      userService,
 		summarySrvc:   summaryService,
 		heartbeatSrvc: heartbeatService,
 		keyValueSrvc:  keyValueService,
+		queryMetrics:  queryMetrics,
+		routeMetrics:  routeMetrics,
 		config:        conf.Get(),
 	}
 }
@@ -76,8 +88,7 @@ func (h *MetricsHandler) RegisterRoutes(router *mux.Router) {
 func (h *MetricsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	reqUser := middlewares.GetPrincipal(r)
 	if reqUser == nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(conf.ErrUnauthorized))
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
 		return
 	}
 
@@ -85,8 +96,7 @@ func (h *MetricsHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	if userMetrics, err := h.getUserMetrics(reqUser); err != nil {
 		conf.Log().Request(r).Error("%v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(conf.ErrInternalServerError))
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
 		return
 	} else {
 		for _, m := range *userMetrics {
@@ -97,8 +107,7 @@ func (h *MetricsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	if reqUser.IsAdmin {
 		if adminMetrics, err := h.getAdminMetrics(reqUser); err != nil {
 			conf.Log().Request(r).Error("%v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(conf.ErrInternalServerError))
+			utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
 			return
 		} else {
 			for _, m := range *adminMetrics {
@@ -309,5 +318,53 @@ func (h *MetricsHandler) getAdminMetrics(user *models.User) (*mm.Metrics, error)
 		})
 	}
 
+	// Database query metrics
+
+	if h.queryMetrics != nil {
+		for _, qs := range h.queryMetrics.Snapshot() {
+			labels := []mm.Label{{Key: "method", Value: qs.Method}}
+
+			metrics = append(metrics, &mm.CounterMetric{
+				Name:   MetricsPrefix + "_admin_queries_total",
+				Desc:   DescAdminQueries,
+				Value:  int(qs.Count),
+				Labels: labels,
+			})
+
+			metrics = append(metrics, &mm.CounterMetric{
+				Name:   MetricsPrefix + "_admin_query_duration_milliseconds_total",
+				Desc:   DescAdminQueryDuration,
+				Value:  int(qs.Duration.Milliseconds()),
+				Labels: labels,
+			})
+
+			metrics = append(metrics, &mm.CounterMetric{
+				Name:   MetricsPrefix + "_admin_query_rows_total",
+				Desc:   DescAdminQueryRows,
+				Value:  int(qs.Rows),
+				Labels: labels,
+			})
+		}
+	}
+
+	// Api route metrics
+
+	if h.routeMetrics != nil {
+		for _, rs := range h.routeMetrics.Snapshot() {
+			for client, count := range rs.Clients {
+				metrics = append(metrics, &mm.CounterMetric{
+					Name:  MetricsPrefix + "_admin_route_requests_total",
+					Desc:  DescAdminRouteRequests,
+					Value: int(count),
+					Labels: []mm.Label{
+						{Key: "route", Value: rs.Route},
+						{Key: "client", Value: client},
+						{Key: "deprecated", Value: strconv.FormatBool(rs.Deprecated)},
+					},
+				})
+			}
+		}
+	}
+
 	return &metrics, nil
 }
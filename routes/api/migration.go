@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type MigrationApiHandler struct {
+	config        *conf.Config
+	userSrvc      services.IUserService
+	migrationSrvc services.IMigrationService
+}
+
+type migrationPayload struct {
+	ApiUrl string `json:"api_url"` // e.g. 'https://wakapi.example.org/api'
+	ApiKey string `json:"api_key"` // the user's api key at the other instance
+}
+
+func NewMigrationApiHandler(userService services.IUserService, migrationService services.IMigrationService) *MigrationApiHandler {
+	return &MigrationApiHandler{
+		config:        conf.Get(),
+		userSrvc:      userService,
+		migrationSrvc: migrationService,
+	}
+}
+
+func (h *MigrationApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/migration").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+}
+
+// @Summary Retrieve the state of the user's latest migration from another wakapi instance
+// @ID get-migration
+// @Tags migration
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Migration
+// @Failure 404 {object} models.ApiError "user never migrated"
+// @Router /migration [get]
+func (h *MigrationApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	migration, err := h.migrationSrvc.Get(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to get migration state of user '%s' - %v", user.ID, err)
+		return
+	}
+	if migration == nil {
+		utils.RespondError(w, r, http.StatusNotFound, "no migration found")
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, migration)
+}
+
+// @Summary Migrate the user's settings and heartbeats from their account at another wakapi instance
+// @Description Runs in the background, see GET /migration for its progress. Starting it again for the same instance resumes an interrupted migration or replicates heartbeats stored since the last one.
+// @ID post-migration
+// @Tags migration
+// @Accept json
+// @Produce json
+// @Param migration body migrationPayload true "Other instance's api url and the user's api key at that instance"
+// @Security ApiKeyAuth
+// @Success 202 {object} models.Migration
+// @Failure 409 {object} models.ApiError "a migration is already in progress"
+// @Router /migration [post]
+func (h *MigrationApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var payload migrationPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if u, err := url.Parse(payload.ApiUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid api_url")
+		return
+	}
+	if payload.ApiKey == "" {
+		utils.RespondError(w, r, http.StatusBadRequest, "missing api_key")
+		return
+	}
+
+	migration, err := h.migrationSrvc.Start(user, payload.ApiUrl, payload.ApiKey)
+	if err == services.ErrMigrationInProgress {
+		utils.RespondError(w, r, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to start migration of user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusAccepted, migration)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type OvertimeApiHandler struct {
+	config       *conf.Config
+	userSrvc     services.IUserService
+	overtimeSrvc services.IOvertimeService
+}
+
+func NewOvertimeApiHandler(userService services.IUserService, overtimeService services.IOvertimeService) *OvertimeApiHandler {
+	return &OvertimeApiHandler{
+		config:       conf.Get(),
+		userSrvc:     userService,
+		overtimeSrvc: overtimeService,
+	}
+}
+
+func (h *OvertimeApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/overtime").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the balance of time worked versus the user's workday target, per day and in total
+// @Description Without any interval, the balance is computed since the target start date configured in the settings
+// @ID get-overtime
+// @Tags overtime
+// @Produce json
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, last_week, quarter, last_quarter, year_to_date, fiscal_year, last_fiscal_year, any)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Overtime
+// @Failure 400 {string} string "no workday target set"
+// @Router /overtime [get]
+func (h *OvertimeApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var overtime *models.Overtime
+	var err error
+
+	if q := r.URL.Query(); q.Get("interval") == "" && q.Get("start") == "" && q.Get("from") == "" {
+		overtime, err = h.overtimeSrvc.GetBalance(user)
+	} else {
+		params, paramsErr := utils.ParseSummaryParams(r)
+		if paramsErr != nil {
+			utils.RespondError(w, r, http.StatusBadRequest, paramsErr.Error())
+			return
+		}
+		overtime, err = h.overtimeSrvc.GetOvertime(user, params.From, params.To)
+	}
+
+	if err == services.ErrNoWorkdayTarget {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compute overtime for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, overtime)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type PreferencesApiHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+}
+
+func NewPreferencesApiHandler(userService services.IUserService) *PreferencesApiHandler {
+	return &PreferencesApiHandler{
+		config:   conf.Get(),
+		userSrvc: userService,
+	}
+}
+
+func (h *PreferencesApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/preferences").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("/dashboard").Methods(http.MethodGet).HandlerFunc(h.GetDashboard)
+	r.Path("/dashboard").Methods(http.MethodPut).HandlerFunc(h.PutDashboard)
+}
+
+// @Summary Retrieve the user's dashboard layout
+// @ID get-preferences-dashboard
+// @Tags preferences
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.DashboardLayout
+// @Router /preferences/dashboard [get]
+func (h *PreferencesApiHandler) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, user.DashboardLayout())
+}
+
+// @Summary Update the user's dashboard layout, i.e. which widgets to show in which order and the default time range
+// @Description An empty list of widgets restores the default layout. Available widgets are 'kpis', 'projects', 'languages', 'editors', 'systems', 'labels', 'repositories', 'browsing', 'activity' and 'achievements'.
+// @ID put-preferences-dashboard
+// @Tags preferences
+// @Accept json
+// @Produce json
+// @Param layout body models.DashboardLayout true "Dashboard layout"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.DashboardLayout
+// @Router /preferences/dashboard [put]
+func (h *PreferencesApiHandler) PutDashboard(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var layout models.DashboardLayout
+	if err := json.NewDecoder(r.Body).Decode(&layout); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+	if !layout.IsValid() {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid dashboard layout")
+		return
+	}
+
+	user.SetDashboardLayout(&layout)
+	if _, err := h.userSrvc.Update(user); err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to update dashboard layout for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, user.DashboardLayout())
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+	"gorm.io/gorm"
+)
+
+type QuarantineApiHandler struct {
+	config         *conf.Config
+	userSrvc       services.IUserService
+	quarantineSrvc services.IQuarantineService
+}
+
+type quarantinePurgeVm struct {
+	Deleted int `json:"deleted"` // number of deleted heartbeats
+}
+
+func NewQuarantineApiHandler(userService services.IUserService, quarantineService services.IQuarantineService) *QuarantineApiHandler {
+	return &QuarantineApiHandler{
+		config:         conf.Get(),
+		userSrvc:       userService,
+		quarantineSrvc: quarantineService,
+	}
+}
+
+func (h *QuarantineApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/admin/quarantine").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+	r.Path("/{id}/accept").Methods(http.MethodPost).HandlerFunc(h.Accept)
+	r.Path("/{id}/purge").Methods(http.MethodPost).HandlerFunc(h.Purge)
+}
+
+// @Summary Retrieve all ranges of heartbeats quarantined as suspicious, which are pending review
+// @Description Only available to admin users
+// @ID get-quarantine
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Quarantine
+// @Router /admin/quarantine [get]
+func (h *QuarantineApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	quarantines, err := h.quarantineSrvc.GetAll()
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to retrieve quarantines - %v", err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, quarantines)
+}
+
+// @Summary Accept quarantined heartbeats, keeping them
+// @Description Only available to admin users
+// @ID post-quarantine-accept
+// @Tags admin
+// @Produce json
+// @Param id path int true "Quarantine id"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Quarantine
+// @Router /admin/quarantine/{id}/accept [post]
+func (h *QuarantineApiHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	id, ok := h.parseId(w, r)
+	if !ok {
+		return
+	}
+
+	quarantine, err := h.quarantineSrvc.Accept(id)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, quarantine)
+}
+
+// @Summary Purge quarantined heartbeats, i.e. irrevocably delete all of the user's heartbeats within the quarantined range
+// @Description Only available to admin users
+// @ID post-quarantine-purge
+// @Tags admin
+// @Produce json
+// @Param id path int true "Quarantine id"
+// @Security ApiKeyAuth
+// @Success 200 {object} quarantinePurgeVm
+// @Router /admin/quarantine/{id}/purge [post]
+func (h *QuarantineApiHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	id, ok := h.parseId(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.quarantineSrvc.Purge(id)
+	if err != nil {
+		h.respondServiceError(w, r, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, &quarantinePurgeVm{Deleted: deleted})
+}
+
+func (h *QuarantineApiHandler) parseId(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid quarantine id")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *QuarantineApiHandler) respondServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondError(w, r, http.StatusNotFound, "quarantine not found")
+		return
+	}
+	utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+	conf.Log().Request(r).Error("failed to resolve quarantine - %v", err)
+}
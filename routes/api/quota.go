@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type QuotaApiHandler struct {
+	config           *conf.Config
+	userSrvc         services.IUserService
+	quotaSrvc        services.IQuotaService
+	storageQuotaSrvc services.IStorageQuotaService
+}
+
+type quotaUpdateVm struct {
+	HeartbeatsPerHour int `json:"heartbeats_per_hour"` // 0 = server-wide default, -1 = unlimited
+}
+
+type quotaVm struct {
+	UserID            string `json:"user_id"`
+	HeartbeatsPerHour int    `json:"heartbeats_per_hour"` // effective limit, 0 = unlimited
+}
+
+type storageQuotaUpdateVm struct {
+	Heartbeats int64  `json:"heartbeats"` // 0 = server-wide default, -1 = unlimited
+	Policy     string `json:"policy"`     // either 'reject' or 'prune', server-wide default if empty
+}
+
+type storageQuotaVm struct {
+	UserID string                    `json:"user_id"`
+	Usage  *models.StorageQuotaUsage `json:"usage"` // effective limit and policy
+}
+
+func NewQuotaApiHandler(userService services.IUserService, quotaService services.IQuotaService, storageQuotaService services.IStorageQuotaService) *QuotaApiHandler {
+	return &QuotaApiHandler{
+		config:           conf.Get(),
+		userSrvc:         userService,
+		quotaSrvc:        quotaService,
+		storageQuotaSrvc: storageQuotaService,
+	}
+}
+
+func (h *QuotaApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/admin/quotas").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("/violations").Methods(http.MethodGet).HandlerFunc(h.GetViolations)
+	r.Path("/{user}").Methods(http.MethodPut).HandlerFunc(h.Put)
+	r.Path("/{user}/storage").Methods(http.MethodGet).HandlerFunc(h.GetStorage)
+	r.Path("/{user}/storage").Methods(http.MethodPut).HandlerFunc(h.PutStorage)
+}
+
+// @Summary Retrieve all users, who exceeded their hourly heartbeat quota since server start
+// @Description Only available to admin users
+// @ID get-quota-violations
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.QuotaViolation
+// @Router /admin/quotas/violations [get]
+func (h *QuotaApiHandler) GetViolations(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+	utils.RespondJSON(w, r, http.StatusOK, h.quotaSrvc.GetViolations())
+}
+
+// @Summary Set the number of heartbeats a user may send per hour
+// @Description Only available to admin users. Set to 0 to fall back to the server-wide default or to -1 for unlimited.
+// @ID put-quota
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID"
+// @Param quota body quotaUpdateVm true "Heartbeats per hour"
+// @Security ApiKeyAuth
+// @Success 200 {object} quotaVm
+// @Router /admin/quotas/{user} [put]
+func (h *QuotaApiHandler) Put(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	var payload quotaUpdateVm
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.HeartbeatsPerHour < -1 {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	user, err := middlewares.GetUserById(r, h.userSrvc, mux.Vars(r)["user"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "user not found")
+		return
+	}
+
+	user.HeartbeatsQuota = payload.HeartbeatsPerHour
+	if _, err := h.userSrvc.Update(user); err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to update quota of user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, &quotaVm{UserID: user.ID, HeartbeatsPerHour: h.quotaSrvc.GetLimit(user)})
+}
+
+// @Summary Retrieve the number of heartbeats a user has stored compared to their storage quota
+// @Description Only available to admin users
+// @ID get-storage-quota
+// @Tags admin
+// @Produce json
+// @Param user path string true "User ID"
+// @Security ApiKeyAuth
+// @Success 200 {object} storageQuotaVm
+// @Router /admin/quotas/{user}/storage [get]
+func (h *QuotaApiHandler) GetStorage(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	user, err := middlewares.GetUserById(r, h.userSrvc, mux.Vars(r)["user"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "user not found")
+		return
+	}
+
+	h.respondStorageUsage(w, r, user)
+}
+
+// @Summary Set the number of heartbeats a user may store in total and what to do once they exceed it
+// @Description Only available to admin users. Set heartbeats to 0 to fall back to the server-wide default or to -1 for unlimited. Policy 'reject' rejects new heartbeats, 'prune' periodically deletes the oldest ones, while retaining summaries.
+// @ID put-storage-quota
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param user path string true "User ID"
+// @Param quota body storageQuotaUpdateVm true "Storage quota"
+// @Security ApiKeyAuth
+// @Success 200 {object} storageQuotaVm
+// @Router /admin/quotas/{user}/storage [put]
+func (h *QuotaApiHandler) PutStorage(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	var payload storageQuotaUpdateVm
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Heartbeats < -1 {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+	if payload.Policy != "" && payload.Policy != conf.StorageQuotaPolicyReject && payload.Policy != conf.StorageQuotaPolicyPrune {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid policy, must be either 'reject' or 'prune'")
+		return
+	}
+
+	user, err := middlewares.GetUserById(r, h.userSrvc, mux.Vars(r)["user"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "user not found")
+		return
+	}
+
+	user.StorageQuota = payload.Heartbeats
+	user.StorageQuotaPolicy = payload.Policy
+	if _, err := h.userSrvc.Update(user); err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to update storage quota of user '%s' - %v", user.ID, err)
+		return
+	}
+
+	h.respondStorageUsage(w, r, user)
+}
+
+func (h *QuotaApiHandler) respondStorageUsage(w http.ResponseWriter, r *http.Request, user *models.User) {
+	usage, err := h.storageQuotaSrvc.GetUsage(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to get storage usage of user '%s' - %v", user.ID, err)
+		return
+	}
+	utils.RespondJSON(w, r, http.StatusOK, &storageQuotaVm{UserID: user.ID, Usage: usage})
+}
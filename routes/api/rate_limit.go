@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type RateLimitApiHandler struct {
+	config    *conf.Config
+	userSrvc  services.IUserService
+	quotaSrvc services.IQuotaService
+}
+
+type rateLimitVm struct {
+	Requests   *models.QuotaStatus `json:"requests"`   // api requests per minute, null if not limited
+	Heartbeats *models.QuotaStatus `json:"heartbeats"` // heartbeats per hour, limit 0 if unlimited
+}
+
+func NewRateLimitApiHandler(userService services.IUserService, quotaService services.IQuotaService) *RateLimitApiHandler {
+	return &RateLimitApiHandler{
+		config:    conf.Get(),
+		userSrvc:  userService,
+		quotaSrvc: quotaService,
+	}
+}
+
+func (h *RateLimitApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/rate_limit").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the current client's api rate limit and the user's heartbeat quota, so that client tooling can back off in time
+// @Description The request itself counts towards the rate limit
+// @ID get-rate-limit
+// @Tags misc
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} rateLimitVm
+// @Router /rate_limit [get]
+func (h *RateLimitApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, &rateLimitVm{
+		Requests:   middlewares.GetRateLimitStatus(r),
+		Heartbeats: h.quotaSrvc.GetStatus(user),
+	})
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type ReassignmentApiHandler struct {
+	config           *conf.Config
+	userSrvc         services.IUserService
+	reassignmentSrvc services.IReassignmentService
+}
+
+func NewReassignmentApiHandler(userService services.IUserService, reassignmentService services.IReassignmentService) *ReassignmentApiHandler {
+	return &ReassignmentApiHandler{
+		config:           conf.Get(),
+		userSrvc:         userService,
+		reassignmentSrvc: reassignmentService,
+	}
+}
+
+type reassignmentPreviewVm struct {
+	Count int64 `json:"count"`
+}
+
+func (h *ReassignmentApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/heartbeats/reassign").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+}
+
+// @Summary Retrieve the status of the user's latest heartbeat reassignment
+// @ID get-reassignment
+// @Tags heartbeat
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ReassignmentJob
+// @Failure 404 {object} models.ApiError "no reassignment run since server start"
+// @Router /heartbeats/reassign [get]
+func (h *ReassignmentApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	job := h.reassignmentSrvc.GetJob(user.ID)
+	if job == nil {
+		utils.RespondError(w, r, http.StatusNotFound, "no reassignment found")
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, job)
+}
+
+// @Summary Move all heartbeats matching a filter to another project and / or language
+// @Description Heartbeats are selected by entity prefix, project and time range (RFC 3339, end exclusive), of which at least entity prefix or project is required. The reassignment runs in the background, its progress can be polled. With dry_run, only the number of matching heartbeats is returned and nothing is changed.
+// @ID post-reassignment
+// @Tags heartbeat
+// @Accept json
+// @Produce json
+// @Param dry_run query bool false "Only count matching heartbeats"
+// @Param reassignment body models.HeartbeatReassignment true "Selection and new project or language"
+// @Security ApiKeyAuth
+// @Success 200 {object} reassignmentPreviewVm "dry run"
+// @Success 202 {object} models.ReassignmentJob
+// @Failure 409 {object} models.ApiError "a reassignment is already in progress"
+// @Router /heartbeats/reassign [post]
+func (h *ReassignmentApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var reassignment models.HeartbeatReassignment
+	if err := json.NewDecoder(r.Body).Decode(&reassignment); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+	if err := reassignment.Validate(); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("dry_run") == "true" {
+		count, err := h.reassignmentSrvc.Count(user, &reassignment.HeartbeatSelection)
+		if err != nil {
+			utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+			conf.Log().Request(r).Error("failed to count heartbeats to reassign for user '%s' - %v", user.ID, err)
+			return
+		}
+		utils.RespondJSON(w, r, http.StatusOK, &reassignmentPreviewVm{Count: count})
+		return
+	}
+
+	job, err := h.reassignmentSrvc.Reassign(user, &reassignment)
+	if err == services.ErrReassignmentInProgress {
+		utils.RespondError(w, r, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to start reassignment for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusAccepted, job)
+}
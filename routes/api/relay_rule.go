@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type RelayRuleApiHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+	ruleSrvc services.IRelayRuleService
+}
+
+func NewRelayRuleApiHandler(userService services.IUserService, relayRuleService services.IRelayRuleService) *RelayRuleApiHandler {
+	return &RelayRuleApiHandler{
+		config:   conf.Get(),
+		userSrvc: userService,
+		ruleSrvc: relayRuleService,
+	}
+}
+
+type relayRulePayload struct {
+	Type  string `json:"type"`  // one of 'project', 'label', 'language', 'editor', 'machine', 'branch' or 'work_hours'
+	Value string `json:"value"` // key to exclude or, for 'work_hours', a range like '09:00-17:00'
+}
+
+func (h *RelayRuleApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/relay/rules").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.GetAll)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+	r.Path("/{id}").Methods(http.MethodDelete).HandlerFunc(h.Delete)
+}
+
+// @Summary Retrieve the rules restricting which of the user's heartbeats are relayed
+// @ID get-relay-rules
+// @Tags relay
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.RelayRule
+// @Router /relay/rules [get]
+func (h *RelayRuleApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	rules, err := h.ruleSrvc.GetByUser(user.ID)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to fetch relay rules for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, rules)
+}
+
+// @Summary Add a rule to exclude heartbeats from being relayed or to only relay them within work hours
+// @ID post-relay-rule
+// @Tags relay
+// @Accept json
+// @Produce json
+// @Param rule body relayRulePayload true "Relay rule"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.RelayRule
+// @Router /relay/rules [post]
+func (h *RelayRuleApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var payload relayRulePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	rule := &models.RelayRule{
+		UserID: user.ID,
+		Type:   payload.Type,
+		Value:  strings.TrimSpace(payload.Value),
+	}
+	if !rule.IsValid() {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid relay rule")
+		return
+	}
+
+	result, err := h.ruleSrvc.Create(rule)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to create relay rule for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Delete a relay rule
+// @ID delete-relay-rule
+// @Tags relay
+// @Param id path int true "Relay rule ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /relay/rules/{id} [delete]
+func (h *RelayRuleApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.ruleSrvc.Delete(user.ID, uint(id)); err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "relay rule not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
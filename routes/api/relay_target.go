@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type RelayTargetApiHandler struct {
+	config    *conf.Config
+	userSrvc  services.IUserService
+	relaySrvc services.IRelayTargetService
+}
+
+func NewRelayTargetApiHandler(userService services.IUserService, relayTargetService services.IRelayTargetService) *RelayTargetApiHandler {
+	return &RelayTargetApiHandler{
+		config:    conf.Get(),
+		userSrvc:  userService,
+		relaySrvc: relayTargetService,
+	}
+}
+
+type relayTargetPayload struct {
+	Name   string `json:"name"`
+	ApiUrl string `json:"api_url"` // base url of a wakatime-compatible api, e.g. https://wakapi.example.org/api/compat/wakatime/v1
+	ApiKey string `json:"api_key"`
+}
+
+type relayTargetStatePayload struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (h *RelayTargetApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/relay/targets").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.GetAll)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+	r.Path("/{id}").Methods(http.MethodPut).HandlerFunc(h.Put)
+	r.Path("/{id}").Methods(http.MethodDelete).HandlerFunc(h.Delete)
+}
+
+// @Summary Retrieve all targets the user's heartbeats are relayed to, including the wakatime integration, along with their delivery metrics since server start
+// @ID get-relay-targets
+// @Tags relay
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.RelayTarget
+// @Router /relay/targets [get]
+func (h *RelayTargetApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	targets, err := h.relaySrvc.GetWithStats(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to fetch relay targets for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, targets)
+}
+
+// @Summary Add another upstream service to relay the user's heartbeats to
+// @ID post-relay-target
+// @Tags relay
+// @Accept json
+// @Produce json
+// @Param target body relayTargetPayload true "Relay target"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.RelayTarget
+// @Router /relay/targets [post]
+func (h *RelayTargetApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var payload relayTargetPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	target := &models.RelayTarget{
+		UserID: user.ID,
+		Name:   payload.Name,
+		ApiUrl: payload.ApiUrl,
+		ApiKey: payload.ApiKey,
+	}
+	if !target.IsValid() {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid relay target")
+		return
+	}
+
+	result, err := h.relaySrvc.Create(target)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to create relay target for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Enable or disable a relay target, whereby enabling it resets its failure count
+// @ID put-relay-target
+// @Tags relay
+// @Accept json
+// @Produce json
+// @Param id path int true "Relay target ID"
+// @Param state body relayTargetStatePayload true "Target state"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.RelayTarget
+// @Router /relay/targets/{id} [put]
+func (h *RelayTargetApiHandler) Put(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var payload relayTargetStatePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	result, err := h.relaySrvc.SetEnabled(user, uint(id), payload.Enabled)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "relay target not found")
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, result)
+}
+
+// @Summary Delete a relay target
+// @ID delete-relay-target
+// @Tags relay
+// @Param id path int true "Relay target ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /relay/targets/{id} [delete]
+func (h *RelayTargetApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.relaySrvc.Delete(user, uint(id)); err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "relay target not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
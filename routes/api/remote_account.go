@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type RemoteAccountApiHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	remoteSrvc services.IRemoteAccountService
+}
+
+func NewRemoteAccountApiHandler(userService services.IUserService, remoteAccountService services.IRemoteAccountService) *RemoteAccountApiHandler {
+	return &RemoteAccountApiHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		remoteSrvc: remoteAccountService,
+	}
+}
+
+type remoteAccountPayload struct {
+	Name   string `json:"name"`
+	ApiUrl string `json:"api_url"` // base url of a wakatime-compatible api, e.g. https://wakapi.example.org/api/compat/wakatime/v1
+	ApiKey string `json:"api_key"`
+}
+
+type remoteAccountStatePayload struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (h *RemoteAccountApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/remote/accounts").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.GetAll)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+	r.Path("/{id}").Methods(http.MethodPut).HandlerFunc(h.Put)
+	r.Path("/{id}").Methods(http.MethodDelete).HandlerFunc(h.Delete)
+}
+
+// @Summary Retrieve all accounts at other Wakapi instances or at WakaTime, whose stats are combined with the user's local ones
+// @ID get-remote-accounts
+// @Tags remote
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.RemoteAccount
+// @Router /remote/accounts [get]
+func (h *RemoteAccountApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	accounts, err := h.remoteSrvc.GetByUser(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to fetch remote accounts for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, accounts)
+}
+
+// @Summary Add an account at another Wakapi instance or at WakaTime to pull stats from
+// @ID post-remote-account
+// @Tags remote
+// @Accept json
+// @Produce json
+// @Param account body remoteAccountPayload true "Remote account"
+// @Security ApiKeyAuth
+// @Success 201 {object} models.RemoteAccount
+// @Router /remote/accounts [post]
+func (h *RemoteAccountApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	var payload remoteAccountPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	account := &models.RemoteAccount{
+		UserID: user.ID,
+		Name:   payload.Name,
+		ApiUrl: payload.ApiUrl,
+		ApiKey: payload.ApiKey,
+	}
+	if !account.IsValid() {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid remote account")
+		return
+	}
+
+	result, err := h.remoteSrvc.Create(account)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to create remote account for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusCreated, result)
+}
+
+// @Summary Enable or disable a remote account
+// @ID put-remote-account
+// @Tags remote
+// @Accept json
+// @Produce json
+// @Param id path int true "Remote account ID"
+// @Param state body remoteAccountStatePayload true "Account state"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.RemoteAccount
+// @Router /remote/accounts/{id} [put]
+func (h *RemoteAccountApiHandler) Put(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var payload remoteAccountStatePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	result, err := h.remoteSrvc.SetEnabled(user, uint(id), payload.Enabled)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "remote account not found")
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, result)
+}
+
+// @Summary Delete a remote account
+// @ID delete-remote-account
+// @Tags remote
+// @Param id path int true "Remote account ID"
+// @Security ApiKeyAuth
+// @Success 204
+// @Router /remote/accounts/{id} [delete]
+func (h *RemoteAccountApiHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.remoteSrvc.Delete(user, uint(id)); err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "remote account not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
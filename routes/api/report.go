@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type ReportApiHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	reportSrvc services.IReportService
+}
+
+func NewReportApiHandler(userService services.IUserService, reportService services.IReportService) *ReportApiHandler {
+	return &ReportApiHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		reportSrvc: reportService,
+	}
+}
+
+func (h *ReportApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/reports").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.GetAll)
+	r.Path("/{id}").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve all of the user's past weekly and monthly reports, latest first
+// @ID get-reports
+// @Tags reports
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.ArchivedReport
+// @Router /reports [get]
+func (h *ReportApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	reports, err := h.reportSrvc.GetArchive(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to fetch report archive for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, reports)
+}
+
+// @Summary Retrieve a single past report
+// @ID get-report
+// @Tags reports
+// @Produce json
+// @Produce application/pdf
+// @Param id path int true "Report ID"
+// @Param format query string false "Response format" Enums(json, pdf)
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ArchivedReport
+// @Failure 404 {object} models.ApiError "report not found"
+// @Router /reports/{id} [get]
+func (h *ReportApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	report, err := h.reportSrvc.GetArchived(user, uint(id))
+	if err != nil || report == nil {
+		utils.RespondError(w, r, http.StatusNotFound, "report not found")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "pdf" {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"wakapi-report-%s.pdf\"", report.ToTime.T().Format("20060102")))
+		w.Write(h.reportSrvc.RenderPdf(report.ToReport(user)))
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, report)
+}
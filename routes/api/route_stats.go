@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type RouteStatsApiHandler struct {
+	config       *conf.Config
+	userSrvc     services.IUserService
+	routeMetrics *middlewares.RouteMetrics
+}
+
+func NewRouteStatsApiHandler(userService services.IUserService, routeMetrics *middlewares.RouteMetrics) *RouteStatsApiHandler {
+	return &RouteStatsApiHandler{
+		config:       conf.Get(),
+		userSrvc:     userService,
+		routeMetrics: routeMetrics,
+	}
+}
+
+func (h *RouteStatsApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/admin/routes").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the number of requests per api route and client since server start, e.g. to tell whether deprecated routes are still in use
+// @Description Only available to admin users
+// @ID get-route-stats
+// @Tags admin
+// @Produce json
+// @Param deprecated query bool false "Only include deprecated routes"
+// @Security ApiKeyAuth
+// @Success 200 {array} models.RouteStats
+// @Router /admin/routes [get]
+func (h *RouteStatsApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	stats := h.routeMetrics.Snapshot()
+	if r.URL.Query().Get("deprecated") == "true" {
+		deprecated := stats[:0]
+		for _, s := range stats {
+			if s.Deprecated {
+				deprecated = append(deprecated, s)
+			}
+		}
+		stats = deprecated
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, stats)
+}
@@ -0,0 +1,349 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	uuid "github.com/satori/go.uuid"
+)
+
+const scimContentType = "application/scim+json"
+
+var scimFilterRegex = regexp.MustCompile(`(?i)^\s*(userName|externalId|emails|emails\.value)\s+eq\s+"([^"]*)"\s*$`)
+
+// ScimApiHandler implements the users endpoints of scim 2.0 (rfc 7644), so that identity providers like okta or azure ad can provision and deprovision accounts.
+// Requests are authenticated with the server-wide scim token instead of a user's api key.
+type ScimApiHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+}
+
+func NewScimApiHandler(userService services.IUserService) *ScimApiHandler {
+	return &ScimApiHandler{
+		config:   conf.Get(),
+		userSrvc: userService,
+	}
+}
+
+func (h *ScimApiHandler) RegisterRoutes(router *mux.Router) {
+	if h.config.Security.ScimToken == "" {
+		return
+	}
+
+	r := router.PathPrefix("/scim/v2").Subrouter()
+	r.Use(h.authenticate)
+	r.Path("/ServiceProviderConfig").Methods(http.MethodGet).HandlerFunc(h.GetServiceProviderConfig)
+	r.Path("/Users").Methods(http.MethodGet).HandlerFunc(h.GetUsers)
+	r.Path("/Users").Methods(http.MethodPost).HandlerFunc(h.PostUser)
+	r.Path("/Users/{id}").Methods(http.MethodGet).HandlerFunc(h.GetUser)
+	r.Path("/Users/{id}").Methods(http.MethodPut).HandlerFunc(h.PutUser)
+	r.Path("/Users/{id}").Methods(http.MethodPatch).HandlerFunc(h.PatchUser)
+	r.Path("/Users/{id}").Methods(http.MethodDelete).HandlerFunc(h.DeleteUser)
+}
+
+func (h *ScimApiHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.config.Security.ScimToken)) != 1 {
+			h.respondError(w, http.StatusUnauthorized, "", "invalid scim token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// @Summary Retrieve the scim features supported by this server
+// @ID get-scim-service-provider-config
+// @Tags scim
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (h *ScimApiHandler) GetServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": 200},
+		"changePassword": map[string]bool{"supported": false}, // passwords are only taken over on creation
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{
+			{"type": "oauthbearertoken", "name": "OAuth Bearer Token", "description": "Authentication with the server's scim token", "primary": true},
+		},
+	})
+}
+
+// @Summary List users, optionally filtered by user name, external id or e-mail address (only 'eq' is supported)
+// @ID get-scim-users
+// @Tags scim
+// @Produce json
+// @Param filter query string false "e.g. userName eq \"john\""
+// @Param startIndex query int false "1-based index of the first result"
+// @Param count query int false "Maximum number of results"
+// @Success 200 {object} models.ScimListResponse
+// @Router /scim/v2/Users [get]
+func (h *ScimApiHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.userSrvc.GetAll()
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "", conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to fetch users for scim - %v", err)
+		return
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		match := scimFilterRegex.FindStringSubmatch(filter)
+		if match == nil {
+			h.respondError(w, http.StatusBadRequest, "invalidFilter", "only filters like 'userName eq \"value\"' are supported")
+			return
+		}
+		filtered := make([]*models.User, 0, 1)
+		for _, u := range users {
+			if scimAttribute(u, match[1]) == match[2] {
+				filtered = append(filtered, u)
+			}
+		}
+		users = filtered
+	}
+
+	startIndex, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 0 || count > 200 {
+		count = 200
+	}
+
+	resources := make([]*models.ScimUser, 0, count)
+	for i := startIndex - 1; i < len(users) && len(resources) < count; i++ {
+		resources = append(resources, models.NewScimUser(users[i], h.baseUrl()))
+	}
+
+	h.respond(w, http.StatusOK, &models.ScimListResponse{
+		Schemas:      []string{models.ScimSchemaListResponse},
+		TotalResults: len(users),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// @Summary Retrieve a single user
+// @ID get-scim-user
+// @Tags scim
+// @Produce json
+// @Param id path string true "User name"
+// @Success 200 {object} models.ScimUser
+// @Router /scim/v2/Users/{id} [get]
+func (h *ScimApiHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.userSrvc.GetUserById(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "", "user not found")
+		return
+	}
+	h.respond(w, http.StatusOK, models.NewScimUser(user, h.baseUrl()))
+}
+
+// @Summary Provision a new user, whose password is random, unless given
+// @ID post-scim-user
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param user body models.ScimUser true "User"
+// @Success 201 {object} models.ScimUser
+// @Router /scim/v2/Users [post]
+func (h *ScimApiHandler) PostUser(w http.ResponseWriter, r *http.Request) {
+	var payload models.ScimUser
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalidSyntax", conf.ErrBadRequest)
+		return
+	}
+
+	signup := &models.Signup{
+		Username: payload.UserName,
+		Email:    payload.PrimaryEmail(),
+		Password: payload.Password,
+		Location: "Local",
+	}
+	if signup.Password == "" {
+		signup.Password = uuid.NewV4().String()
+	}
+	signup.PasswordRepeat = signup.Password
+	if !signup.IsValid() {
+		h.respondError(w, http.StatusBadRequest, "invalidValue", "invalid user name, e-mail address or password")
+		return
+	}
+
+	user, created, err := h.userSrvc.CreateOrGet(signup, false)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, "", conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to provision user '%s' via scim - %v", signup.Username, err)
+		return
+	}
+	if !created {
+		h.respondError(w, http.StatusConflict, "uniqueness", "user already exists")
+		return
+	}
+
+	if _, err := h.apply(user, &payload); err != nil {
+		h.respondError(w, http.StatusInternalServerError, "", conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to update provisioned user '%s' via scim - %v", user.ID, err)
+		return
+	}
+
+	h.respond(w, http.StatusCreated, models.NewScimUser(user, h.baseUrl()))
+}
+
+// @Summary Replace a user's e-mail address, external id and active state, whereby deactivation revokes the user's api key
+// @ID put-scim-user
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param id path string true "User name"
+// @Param user body models.ScimUser true "User"
+// @Success 200 {object} models.ScimUser
+// @Router /scim/v2/Users/{id} [put]
+func (h *ScimApiHandler) PutUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.userSrvc.GetUserById(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "", "user not found")
+		return
+	}
+
+	var payload models.ScimUser
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalidSyntax", conf.ErrBadRequest)
+		return
+	}
+	if payload.UserName != "" && payload.UserName != user.ID {
+		h.respondError(w, http.StatusBadRequest, "mutability", "user name can not be changed")
+		return
+	}
+	if !models.ValidateEmail(payload.PrimaryEmail()) {
+		h.respondError(w, http.StatusBadRequest, "invalidValue", "invalid e-mail address")
+		return
+	}
+
+	// a put replaces the whole resource, so omitted attributes are reset
+	user.Email = payload.PrimaryEmail()
+	if _, err := h.apply(user, &payload); err != nil {
+		h.respondError(w, http.StatusInternalServerError, "", conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to update user '%s' via scim - %v", user.ID, err)
+		return
+	}
+
+	h.respond(w, http.StatusOK, models.NewScimUser(user, h.baseUrl()))
+}
+
+// @Summary Partially update a user, e.g. to deactivate it, whereby deactivation revokes the user's api key
+// @ID patch-scim-user
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param id path string true "User name"
+// @Param patch body models.ScimPatchRequest true "Patch operations"
+// @Success 200 {object} models.ScimUser
+// @Router /scim/v2/Users/{id} [patch]
+func (h *ScimApiHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.userSrvc.GetUserById(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "", "user not found")
+		return
+	}
+
+	var payload models.ScimPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalidSyntax", conf.ErrBadRequest)
+		return
+	}
+
+	patched := models.NewScimUser(user, h.baseUrl())
+	for _, op := range payload.Operations {
+		if !op.Apply(patched) {
+			h.respondError(w, http.StatusBadRequest, "invalidValue", fmt.Sprintf("unsupported operation '%s' on '%s'", op.Op, op.Path))
+			return
+		}
+	}
+	if !models.ValidateEmail(patched.PrimaryEmail()) {
+		h.respondError(w, http.StatusBadRequest, "invalidValue", "invalid e-mail address")
+		return
+	}
+
+	user.Email = patched.PrimaryEmail()
+	if _, err := h.apply(user, patched); err != nil {
+		h.respondError(w, http.StatusInternalServerError, "", conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to patch user '%s' via scim - %v", user.ID, err)
+		return
+	}
+
+	h.respond(w, http.StatusOK, models.NewScimUser(user, h.baseUrl()))
+}
+
+// @Summary Deprovision a user, deleting all of its data
+// @ID delete-scim-user
+// @Tags scim
+// @Param id path string true "User name"
+// @Success 204
+// @Router /scim/v2/Users/{id} [delete]
+func (h *ScimApiHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.userSrvc.GetUserById(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "", "user not found")
+		return
+	}
+
+	if err := h.userSrvc.Delete(user); err != nil {
+		h.respondError(w, http.StatusInternalServerError, "", conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to delete user '%s' via scim - %v", user.ID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apply persists the external id and active state of the given scim user, the e-mail address is expected to be set by the caller and passwords are only taken over on creation
+func (h *ScimApiHandler) apply(user *models.User, scimUser *models.ScimUser) (*models.User, error) {
+	user.ExternalId = scimUser.ExternalId
+	if scimUser.Active != nil && *scimUser.Active == user.Deactivated {
+		return h.userSrvc.SetDeactivated(user, !*scimUser.Active)
+	}
+	return h.userSrvc.Update(user)
+}
+
+func (h *ScimApiHandler) baseUrl() string {
+	return h.config.Server.GetPublicUrl() + "/api/scim/v2"
+}
+
+func (h *ScimApiHandler) respond(w http.ResponseWriter, status int, object interface{}) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(object)
+}
+
+func (h *ScimApiHandler) respondError(w http.ResponseWriter, status int, scimType, detail string) {
+	h.respond(w, status, models.NewScimError(status, scimType, detail))
+}
+
+func scimAttribute(user *models.User, attribute string) string {
+	switch strings.ToLower(attribute) {
+	case "username":
+		return user.ID
+	case "externalid":
+		return user.ExternalId
+	default:
+		return user.Email
+	}
+}
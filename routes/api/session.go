@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	sessionsMaxRange   = 31 * 24 * time.Hour
+	sessionsMaxIdleMin = 240
+)
+
+type SessionApiHandler struct {
+	config      *conf.Config
+	userSrvc    services.IUserService
+	sessionSrvc services.ISessionService
+}
+
+func NewSessionApiHandler(userService services.IUserService, sessionService services.ISessionService) *SessionApiHandler {
+	return &SessionApiHandler{
+		config:      conf.Get(),
+		userSrvc:    userService,
+		sessionSrvc: sessionService,
+	}
+}
+
+func (h *SessionApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/sessions").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve coding sessions, i.e. blocks of contiguous activity separated by idle time, along with their breaks and projects
+// @ID get-sessions
+// @Tags sessions
+// @Produce json
+// @Param from query string false "Start date (e.g. '2021-02-07'), defaults to the start of today"
+// @Param to query string false "End date (e.g. '2021-02-08'), defaults to the end of today"
+// @Param idle query int false "Minutes of inactivity, after which a session ends (default 15, max 240)"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.SessionList
+// @Router /sessions [get]
+func (h *SessionApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	from, to := utils.StartOfToday(user.TZ()), utils.EndOfToday(user.TZ())
+	fromParam, toParam := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if fromParam != "" || toParam != "" {
+		var err1, err2 error
+		from, err1 = utils.ParseDateTimeTZ(fromParam, user.TZ())
+		to, err2 = utils.ParseDateTimeTZ(toParam, user.TZ())
+		if err1 != nil || err2 != nil || !from.Before(to) {
+			utils.RespondError(w, r, http.StatusBadRequest, "missing or invalid 'from' or 'to' parameter")
+			return
+		}
+		if to.Sub(from) > sessionsMaxRange {
+			utils.RespondError(w, r, http.StatusBadRequest, "range must not exceed 31 days")
+			return
+		}
+	}
+
+	idle := services.SessionDefaultIdle
+	if idleParam := r.URL.Query().Get("idle"); idleParam != "" {
+		minutes, err := strconv.Atoi(idleParam)
+		if err != nil || minutes < 1 || minutes > sessionsMaxIdleMin {
+			utils.RespondError(w, r, http.StatusBadRequest, "invalid 'idle' parameter")
+			return
+		}
+		idle = time.Duration(minutes) * time.Minute
+	}
+
+	sessions, err := h.sessionSrvc.Get(from, to, user, idle)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compute sessions for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, models.NewSessionList(from, to, idle, sessions))
+}
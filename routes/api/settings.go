@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+	"gopkg.in/yaml.v2"
+)
+
+const maxSettingsDocumentSize = 1 << 20 // 1 MB
+
+type SettingsApiHandler struct {
+	config       *conf.Config
+	userSrvc     services.IUserService
+	settingsSrvc services.ISettingsService
+}
+
+func NewSettingsApiHandler(userService services.IUserService, settingsService services.ISettingsService) *SettingsApiHandler {
+	return &SettingsApiHandler{
+		config:       conf.Get(),
+		userSrvc:     userService,
+		settingsSrvc: settingsService,
+	}
+}
+
+func (h *SettingsApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/settings").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("/export").Methods(http.MethodGet).HandlerFunc(h.GetExport)
+	r.Path("/import").Methods(http.MethodPost).HandlerFunc(h.PostImport)
+}
+
+// @Summary Export the user's aliases, language mappings, labels, relay rules and goals as a single document
+// @ID get-settings-export
+// @Tags settings
+// @Produce json
+// @Produce application/x-yaml
+// @Param format query string false "Either 'json' (default) or 'yaml'"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.SettingsDocument
+// @Router /settings/export [get]
+func (h *SettingsApiHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	doc, err := h.settingsSrvc.Export(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to export settings for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	if !isYaml(r.URL.Query().Get("format"), r.Header.Get("Accept")) {
+		utils.RespondJSON(w, r, http.StatusOK, doc)
+		return
+	}
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to encode settings for user '%s' - %v", user.ID, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// @Summary Import a settings document as previously exported
+// @Description In 'merge' mode (default), entries already present are kept and only missing ones are added. In 'replace' mode, all existing aliases, language mappings, labels, relay rules and goals are removed first. The document is validated as a whole before anything is changed.
+// @ID post-settings-import
+// @Tags settings
+// @Accept json
+// @Accept application/x-yaml
+// @Produce json
+// @Param mode query string false "Either 'merge' (default) or 'replace'"
+// @Param document body models.SettingsDocument true "Settings document"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.SettingsImportReport
+// @Router /settings/import [post]
+func (h *SettingsApiHandler) PostImport(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = models.SettingsImportMerge
+	}
+	if mode != models.SettingsImportMerge && mode != models.SettingsImportReplace {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid mode")
+		return
+	}
+
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxSettingsDocumentSize))
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+
+	var doc models.SettingsDocument
+	if isYaml("", r.Header.Get("Content-Type")) {
+		err = yaml.Unmarshal(data, &doc)
+	} else {
+		err = json.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+	if err := doc.Validate(); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.settingsSrvc.Import(user, &doc, mode)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to import settings for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, report)
+}
+
+func isYaml(format, contentType string) bool {
+	return format == "yaml" || format == "yml" || strings.Contains(contentType, "yaml")
+}
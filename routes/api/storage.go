@@ -0,0 +1,59 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type StorageApiHandler struct {
+	config      *conf.Config
+	storageSrvc services.IStorageService
+}
+
+func NewStorageApiHandler(storageService services.IStorageService) *StorageApiHandler {
+	return &StorageApiHandler{
+		config:      conf.Get(),
+		storageSrvc: storageService,
+	}
+}
+
+func (h *StorageApiHandler) RegisterRoutes(router *mux.Router) {
+	// only used for local storage, files in object storage are downloaded from there directly
+	router.Path("/storage/download").Methods(http.MethodGet).HandlerFunc(h.Download)
+}
+
+// @Summary Download a previously stored file, e.g. a data export
+// @Description Links are generated by the server and expire after a configured time
+// @ID get-storage-download
+// @Tags storage
+// @Produce octet-stream
+// @Param token query string true "Signed download token"
+// @Success 200
+// @Router /storage/download [get]
+func (h *StorageApiHandler) Download(w http.ResponseWriter, r *http.Request) {
+	key, err := h.storageSrvc.ResolveDownloadToken(r.URL.Query().Get("token"))
+	if err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "invalid or expired download link")
+		return
+	}
+
+	file, err := h.storageSrvc.Open(key)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusNotFound, "file not found")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", path.Base(key)))
+	if _, err := io.Copy(w, file); err != nil {
+		conf.Log().Request(r).Error("failed to send stored file '%s' - %v", key, err)
+	}
+}
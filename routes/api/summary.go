@@ -3,25 +3,34 @@ package api
 import (
 	routeutils "github.com/muety/wakapi/routes/utils"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
 )
 
 type SummaryApiHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
+	config          *conf.Config
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	aggregationSrvc services.IAggregationService
+	filterSetSrvc   services.IFilterSetService
+	remoteSrvc      services.IRemoteAccountService
 }
 
-func NewSummaryApiHandler(userService services.IUserService, summaryService services.ISummaryService) *SummaryApiHandler {
+func NewSummaryApiHandler(userService services.IUserService, summaryService services.ISummaryService, aggregationService services.IAggregationService, filterSetService services.IFilterSetService, remoteAccountService services.IRemoteAccountService) *SummaryApiHandler {
 	return &SummaryApiHandler{
-		summarySrvc: summaryService,
-		userSrvc:    userService,
-		config:      conf.Get(),
+		summarySrvc:     summaryService,
+		userSrvc:        userService,
+		aggregationSrvc: aggregationService,
+		filterSetSrvc:   filterSetService,
+		remoteSrvc:      remoteAccountService,
+		config:          conf.Get(),
 	}
 }
 
@@ -31,13 +40,18 @@ func (h *SummaryApiHandler) RegisterRoutes(router *mux.Router) {
 		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
 	)
 	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+	r.Path("/delta").Methods(http.MethodGet).HandlerFunc(h.GetDelta)
+	r.Path("/regenerate").Methods(http.MethodGet).HandlerFunc(h.GetRegeneration)
+	r.Path("/regenerate").Methods(http.MethodPost).HandlerFunc(h.PostRegeneration)
 }
 
 // @Summary Retrieve a summary
 // @ID get-summary
 // @Tags summary
 // @Produce json
-// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, any)
+// @Produce application/msgpack
+// @Produce text/csv
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, last_week, quarter, last_quarter, year_to_date, fiscal_year, last_fiscal_year, any)
 // @Param from query string false "Start date (e.g. '2021-02-07')"
 // @Param to query string false "End date (e.g. '2021-02-08')"
 // @Param recompute query bool false "Whether to recompute the summary from raw heartbeat or use cache"
@@ -47,16 +61,180 @@ func (h *SummaryApiHandler) RegisterRoutes(router *mux.Router) {
 // @Param operating_system query string false "OS to filter by"
 // @Param machine query string false "Machine to filter by"
 // @Param label query string false "Project label to filter by"
+// @Param filter_set query string false "Name of a saved filter set to apply"
+// @Param fields query string false "Comma-separated list of sections to include (e.g. 'languages,projects'), all by default"
+// @Param combined query bool false "Whether to add up the summaries of the user's remote accounts at other instances"
+// @Param limit query int false "Maximum number of items per type, remaining ones are rolled up into 'Other' (0 for unlimited), server default if omitted"
 // @Security ApiKeyAuth
 // @Success 200 {object} models.Summary
 // @Router /summary [get]
 func (h *SummaryApiHandler) Get(w http.ResponseWriter, r *http.Request) {
-	summary, err, status := routeutils.LoadUserSummary(h.summarySrvc, r)
+	limit := h.config.App.SummaryMaxItems
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsedLimit, err := strconv.Atoi(limitParam)
+		if err != nil || parsedLimit < 0 {
+			utils.RespondError(w, r, http.StatusBadRequest, "invalid 'limit' parameter")
+			return
+		}
+		limit = parsedLimit
+	}
+
+	summary, err, status := routeutils.LoadUserSummary(h.summarySrvc, h.filterSetSrvc, r)
+	if err != nil {
+		utils.RespondError(w, r, status, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("combined") == "true" {
+		if summary, err = h.remoteSrvc.Combine(middlewares.GetPrincipal(r), summary); err != nil {
+			utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+			conf.Log().Request(r).Error("failed to combine summary with remote accounts - %v", err)
+			return
+		}
+	}
+
+	summary = summary.WithItemLimit(limit)
+
+	if utils.AcceptsCsv(r) {
+		routeutils.RespondSummariesCsv(w, r, []*models.Summary{summary})
+		return
+	}
+
+	utils.RespondNegotiated(w, r, http.StatusOK, utils.SelectFields(r, summary))
+}
+
+// @Summary Retrieve the time spent per project since a given point in time, e.g. for ci jobs to check whether any time was logged today
+// @Description Responds with status 412 instead of 200, if less than 'min_seconds' were logged, so that tools like 'curl --fail' can act upon it
+// @ID get-summary-delta
+// @Tags summary
+// @Produce json
+// @Param since query string false "Start time as unix timestamp, RFC3339 or date (e.g. '2021-02-07'), start of today by default"
+// @Param min_seconds query int false "Minimum number of seconds required to be logged in total"
+// @Param project query string false "Project to filter by"
+// @Param label query string false "Project label to filter by"
+// @Param filter_set query string false "Name of a saved filter set to apply"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ActivityDelta
+// @Failure 412 {object} models.ActivityDelta
+// @Router /summary/delta [get]
+func (h *SummaryApiHandler) GetDelta(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	until := time.Now()
+	since := utils.StartOfToday(user.TZ())
+	if sinceParam := r.URL.Query().Get("since"); sinceParam != "" {
+		if ts, err := strconv.ParseInt(sinceParam, 10, 64); err == nil {
+			since = time.Unix(ts, 0)
+		} else if since, err = utils.ParseDateTimeTZ(sinceParam, user.TZ()); err != nil {
+			utils.RespondError(w, r, http.StatusBadRequest, "invalid 'since' parameter")
+			return
+		}
+	}
+	if !since.Before(until) {
+		utils.RespondError(w, r, http.StatusBadRequest, "'since' must be in the past")
+		return
+	}
+
+	var minSeconds int64
+	if minParam := r.URL.Query().Get("min_seconds"); minParam != "" {
+		parsedMin, err := strconv.ParseInt(minParam, 10, 64)
+		if err != nil || parsedMin < 0 {
+			utils.RespondError(w, r, http.StatusBadRequest, "invalid 'min_seconds' parameter")
+			return
+		}
+		minSeconds = parsedMin
+	}
+
+	filters, err, status := routeutils.ApplyFilterSet(h.filterSetSrvc, user, r, utils.ParseSummaryFilters(r))
+	if err != nil {
+		utils.RespondError(w, r, status, err.Error())
+		return
+	}
+
+	summary, err := h.summarySrvc.Aliased(since, until, user, h.summarySrvc.Retrieve, filters, true)
 	if err != nil {
-		w.WriteHeader(status)
-		w.Write([]byte(err.Error()))
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to retrieve summary delta for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	delta := models.NewActivityDelta(since.In(user.TZ()), until.In(user.TZ()), summary, minSeconds)
+	if !delta.Passed {
+		utils.RespondJSON(w, r, http.StatusPreconditionFailed, delta)
+		return
+	}
+	utils.RespondJSON(w, r, http.StatusOK, delta)
+}
+
+// @Summary Retrieve the status of the latest summary regeneration job
+// @ID get-summary-regeneration
+// @Tags summary
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.RegenerationJob
+// @Router /summary/regenerate [get]
+func (h *SummaryApiHandler) GetRegeneration(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	job := h.aggregationSrvc.GetRegenerationJob(user.ID)
+	if job == nil {
+		utils.RespondError(w, r, http.StatusNotFound, "no regeneration job found")
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, job)
+}
+
+// @Summary Regenerate persisted summaries for a range of days in the background
+// @ID post-summary-regeneration
+// @Tags summary
+// @Produce json
+// @Param from query string true "Start date, inclusive (e.g. '2021-02-07')"
+// @Param to query string true "End date, exclusive (e.g. '2021-02-08')"
+// @Security ApiKeyAuth
+// @Success 202 {object} models.RegenerationJob
+// @Failure 400 {string} string "invalid date range"
+// @Failure 409 {string} string "regeneration already in progress"
+// @Router /summary/regenerate [post]
+func (h *SummaryApiHandler) PostRegeneration(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	from, err1 := utils.ParseDateTimeTZ(r.URL.Query().Get("from"), user.TZ())
+	to, err2 := utils.ParseDateTimeTZ(r.URL.Query().Get("to"), user.TZ())
+	if err1 != nil || err2 != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "missing or invalid 'from' or 'to' parameter")
+		return
+	}
+
+	if !from.Before(to) {
+		utils.RespondError(w, r, http.StatusBadRequest, "'from' must be before 'to'")
+		return
+	}
+
+	job, err := h.aggregationSrvc.Regenerate(user, from, to)
+	if err == services.ErrInvalidRegenerationRange {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	} else if err == services.ErrAggregationInProgress {
+		utils.RespondError(w, r, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to start summary regeneration for user '%s' - %v", user.ID, err)
 		return
 	}
 
-	utils.RespondJSON(w, r, http.StatusOK, summary)
+	utils.RespondJSON(w, r, http.StatusAccepted, job)
 }
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type TicketApiHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	ticketSrvc services.ITicketService
+}
+
+func NewTicketApiHandler(userService services.IUserService, ticketService services.ITicketService) *TicketApiHandler {
+	return &TicketApiHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		ticketSrvc: ticketService,
+	}
+}
+
+func (h *TicketApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/tickets").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("/worklog").Methods(http.MethodGet).HandlerFunc(h.GetWorklog)
+}
+
+// @Summary Retrieve the time spent per ticket (issue key detected in branch names, e.g. 'PROJ-123') and day
+// @ID get-ticket-worklog
+// @Tags tickets
+// @Produce json
+// @Produce text/csv
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, last_week, quarter, last_quarter, year_to_date, fiscal_year, last_fiscal_year, any)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param format query string false "Response format, csv can be pasted into worklogs" Enums(json, csv)
+// @Security ApiKeyAuth
+// @Success 200 {array} models.WorklogEntry
+// @Router /tickets/worklog [get]
+func (h *TicketApiHandler) GetWorklog(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	params, err := utils.ParseSummaryParams(r)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := h.ticketSrvc.GetWorklog(params.From, params.To, user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compute ticket worklog for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") != "csv" {
+		utils.RespondJSON(w, r, http.StatusOK, entries)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"worklog.csv\"")
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"date", "ticket", "time_spent", "time_spent_seconds"})
+	for _, e := range entries {
+		writer.Write([]string{e.Date.Format(conf.SimpleDateFormat), e.Ticket, e.TimeSpent(), strconv.FormatInt(e.TimeSpentSeconds, 10)})
+	}
+	writer.Flush()
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type TimesheetApiHandler struct {
+	config        *conf.Config
+	userSrvc      services.IUserService
+	timesheetSrvc services.ITimesheetService
+}
+
+func NewTimesheetApiHandler(userService services.IUserService, timesheetService services.ITimesheetService) *TimesheetApiHandler {
+	return &TimesheetApiHandler{
+		config:        conf.Get(),
+		userSrvc:      userService,
+		timesheetSrvc: timesheetService,
+	}
+}
+
+func (h *TimesheetApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/timesheet").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the hours spent per project on every day of a week
+// @ID get-timesheet
+// @Tags timesheet
+// @Produce json
+// @Produce text/csv
+// @Param week query string false "Any date within the requested week (e.g. '2021-02-08'), defaults to the current week"
+// @Param format query string false "Response format" Enums(json, csv)
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Timesheet
+// @Router /timesheet [get]
+func (h *TimesheetApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	date := time.Now().In(user.TZ())
+	if week := r.URL.Query().Get("week"); week != "" {
+		var err error
+		if date, err = time.ParseInLocation(conf.SimpleDateFormat, week, user.TZ()); err != nil {
+			utils.RespondError(w, r, http.StatusBadRequest, "invalid 'week' parameter")
+			return
+		}
+	}
+
+	sheet, err := h.timesheetSrvc.GetWeek(date, user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compute timesheet for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") != "csv" {
+		utils.RespondJSON(w, r, http.StatusOK, sheet)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"timesheet-%s.csv\"", sheet.From.Format(conf.SimpleDateFormat)))
+	w.WriteHeader(http.StatusOK)
+
+	header := []string{"Project"}
+	for _, d := range sheet.Days {
+		header = append(header, d.Format(conf.SimpleDateFormat))
+	}
+	header = append(header, "Total")
+
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+	for _, row := range sheet.Rows {
+		writer.Write(append(append([]string{row.Project}, fmtHours(row.Hours)...), fmtHours([]float64{row.Total})...))
+	}
+	writer.Write(append(append([]string{"Total"}, fmtHours(sheet.Totals)...), fmtHours([]float64{sheet.Total})...))
+	writer.Flush()
+}
+
+func fmtHours(hours []float64) []string {
+	formatted := make([]string, len(hours))
+	for i, h := range hours {
+		formatted[i] = strconv.FormatFloat(h, 'f', 2, 64)
+	}
+	return formatted
+}
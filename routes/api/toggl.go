@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type TogglApiHandler struct {
+	config    *conf.Config
+	userSrvc  services.IUserService
+	togglSrvc services.ITogglService
+}
+
+func NewTogglApiHandler(userService services.IUserService, togglService services.ITogglService) *TogglApiHandler {
+	return &TogglApiHandler{
+		config:    conf.Get(),
+		userSrvc:  userService,
+		togglSrvc: togglService,
+	}
+}
+
+func (h *TogglApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/export/toggl").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Export tracked time as Toggl Track time entries, one per uninterrupted block of work on a project and branch
+// @ID get-toggl-export
+// @Tags export
+// @Produce json
+// @Produce text/csv
+// @Param interval query string false "Interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, last_week, quarter, last_quarter, year_to_date, fiscal_year, last_fiscal_year, any)
+// @Param from query string false "Start date (e.g. '2021-02-07')"
+// @Param to query string false "End date (e.g. '2021-02-08')"
+// @Param format query string false "Response format, csv can be imported into toggl" Enums(json, csv)
+// @Security ApiKeyAuth
+// @Success 200 {array} models.TogglTimeEntry
+// @Router /export/toggl [get]
+func (h *TogglApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	params, err := utils.ParseSummaryParams(r)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := h.togglSrvc.GetTimeEntries(params.From, params.To, user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compute toggl time entries for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	tz := user.TZ()
+	for _, e := range entries {
+		e.Start, e.Stop = e.Start.In(tz), e.Stop.In(tz)
+	}
+
+	if r.URL.Query().Get("format") != "csv" {
+		utils.RespondJSON(w, r, http.StatusOK, entries)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"toggl.csv\"")
+	w.WriteHeader(http.StatusOK)
+
+	// see https://support.toggl.com/en/articles/2219285-importing-time-entries-from-a-csv-file
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"Email", "Project", "Description", "Start date", "Start time", "Duration"})
+	for _, e := range entries {
+		writer.Write([]string{user.Email, e.Project, e.Description, e.Start.Format(conf.SimpleDateFormat), e.Start.Format("15:04:05"), e.FmtDuration()})
+	}
+	writer.Flush()
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type UndoApiHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+	undoSrvc services.IUndoService
+}
+
+func NewUndoApiHandler(userService services.IUserService, undoService services.IUndoService) *UndoApiHandler {
+	return &UndoApiHandler{
+		config:   conf.Get(),
+		userSrvc: userService,
+		undoSrvc: undoService,
+	}
+}
+
+func (h *UndoApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/undo").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.GetAll)
+	r.Path("/{id}").Methods(http.MethodPost).HandlerFunc(h.Post)
+}
+
+// @Summary Retrieve all of the user's heartbeat deletions and reassignments, which can still be undone, latest first
+// @ID get-undo
+// @Tags heartbeat
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} models.Tombstone
+// @Router /undo [get]
+func (h *UndoApiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	tombstones, err := h.undoSrvc.GetByUser(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to fetch tombstones for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, tombstones)
+}
+
+// @Summary Undo a heartbeat deletion or reassignment
+// @Description Affected heartbeats are restored in the background, after which the operation disappears from the list of undoable ones.
+// @ID post-undo
+// @Tags heartbeat
+// @Produce json
+// @Param id path int true "Tombstone ID"
+// @Security ApiKeyAuth
+// @Success 202 {object} models.Tombstone
+// @Failure 404 {object} models.ApiError "operation not found or expired"
+// @Failure 409 {object} models.ApiError "operation is already being undone"
+// @Router /undo/{id} [post]
+func (h *UndoApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	tombstone, err := h.undoSrvc.Undo(user, uint(id))
+	if err == services.ErrUndoInProgress {
+		utils.RespondError(w, r, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil || tombstone == nil {
+		utils.RespondError(w, r, http.StatusNotFound, "operation not found")
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusAccepted, tombstone)
+}
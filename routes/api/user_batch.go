@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+const maxUserBatchSize = 1 << 20 // 1 MB
+
+type UserBatchApiHandler struct {
+	config        *conf.Config
+	userSrvc      services.IUserService
+	userBatchSrvc services.IUserBatchService
+}
+
+func NewUserBatchApiHandler(userService services.IUserService, userBatchService services.IUserBatchService) *UserBatchApiHandler {
+	return &UserBatchApiHandler{
+		config:        conf.Get(),
+		userSrvc:      userService,
+		userBatchSrvc: userBatchService,
+	}
+}
+
+func (h *UserBatchApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/admin/users/batch").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+}
+
+// @Summary Create multiple users at once and mail them their credentials
+// @Description Only available to admin users. Accepts either a csv file with rows of the form 'username,email[,api_key]' or a json array of users. Every user gets a random password. Existing users are skipped.
+// @ID post-user-batch
+// @Tags admin
+// @Accept text/csv
+// @Accept json
+// @Produce json
+// @Param mail query bool false "Whether to mail users their credentials (default: true). Passwords of users not mailed are included in the response."
+// @Param users body []models.UserBatchEntry true "Users to create"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.UserBatchReport
+// @Router /admin/users/batch [post]
+func (h *UserBatchApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxUserBatchSize)
+
+	var entries []*models.UserBatchEntry
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(body).Decode(&entries); err != nil {
+			utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+			return
+		}
+	} else {
+		parsed, err := models.ParseUserBatchCsv(body)
+		if err != nil {
+			utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		entries = parsed
+	}
+
+	if len(entries) == 0 {
+		utils.RespondError(w, r, http.StatusBadRequest, "no users given")
+		return
+	}
+
+	sendMail := r.URL.Query().Get("mail") != "false"
+	utils.RespondJSON(w, r, http.StatusOK, h.userBatchSrvc.Create(entries, sendMail))
+}
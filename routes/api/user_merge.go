@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type UserMergeApiHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+}
+
+func NewUserMergeApiHandler(userService services.IUserService) *UserMergeApiHandler {
+	return &UserMergeApiHandler{
+		config:   conf.Get(),
+		userSrvc: userService,
+	}
+}
+
+func (h *UserMergeApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/admin/users/merge").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodPost).HandlerFunc(h.Post)
+}
+
+// @Summary Merge two accounts of the same person, e.g. one created via sso and a legacy one
+// @Description Only available to admin users. Moves heartbeats, summaries, aliases, language mappings, project labels and embed tokens of the source user to the target user within a single transaction and deletes the source user afterwards. Duplicates are dropped, integration credentials are taken over where the target has none and the api key, if requested. With dry_run, nothing is changed, but the report tells what would be done.
+// @ID post-user-merge
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param dry_run query bool false "Only report what would be done"
+// @Param merge body models.UserMerge true "Users to merge"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.UserMergeReport
+// @Router /admin/users/merge [post]
+func (h *UserMergeApiHandler) Post(w http.ResponseWriter, r *http.Request) {
+	if !routeutils.CheckAdmin(w, r) {
+		return
+	}
+
+	var merge models.UserMerge
+	if err := json.NewDecoder(r.Body).Decode(&merge); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, conf.ErrBadRequest)
+		return
+	}
+	if err := merge.Validate(); err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	merge.DryRun = r.URL.Query().Get("dry_run") == "true"
+
+	for _, id := range []string{merge.SourceId, merge.TargetId} {
+		if _, err := h.userSrvc.GetUserById(id); err != nil {
+			utils.RespondError(w, r, http.StatusNotFound, "user not found")
+			return
+		}
+	}
+
+	report, err := h.userSrvc.Merge(&merge)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to merge user '%s' into '%s' - %v", merge.SourceId, merge.TargetId, err)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, report)
+}
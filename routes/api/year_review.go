@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type YearReviewApiHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	reviewSrvc services.IYearReviewService
+}
+
+func NewYearReviewApiHandler(userService services.IUserService, yearReviewService services.IYearReviewService) *YearReviewApiHandler {
+	return &YearReviewApiHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		reviewSrvc: yearReviewService,
+	}
+}
+
+func (h *YearReviewApiHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/review").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("/{year:[0-9]{4}}").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve an annual recap of the user's coding activity
+// @ID get-year-review
+// @Tags review
+// @Produce json
+// @Produce image/svg+xml
+// @Produce application/pdf
+// @Param year path int true "Year (e.g. 2022)"
+// @Param format query string false "Response format, svg and pdf for sharing" Enums(json, svg, pdf)
+// @Security ApiKeyAuth
+// @Success 200 {object} models.YearReview
+// @Router /review/{year} [get]
+func (h *YearReviewApiHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return
+	}
+
+	year, _ := strconv.Atoi(mux.Vars(r)["year"])
+	review, err := h.reviewSrvc.GetReview(user, year)
+	if err == services.ErrYearReviewInvalidYear {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compute year review for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"wakapi-%d.svg\"", year))
+		w.Write(h.reviewSrvc.RenderSvg(review))
+	case "pdf":
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"wakapi-%d.pdf\"", year))
+		w.Write(h.reviewSrvc.RenderPdf(review))
+	default:
+		utils.RespondJSON(w, r, http.StatusOK, review)
+	}
+}
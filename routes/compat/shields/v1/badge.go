@@ -8,6 +8,7 @@ import (
 
 	"github.com/gorilla/mux"
 	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
 	v1 "github.com/muety/wakapi/models/compat/shields/v1"
 	"github.com/muety/wakapi/services"
@@ -21,35 +22,42 @@ const (
 )
 
 type BadgeHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
-	cache       *cache.Cache
+	config         *conf.Config
+	userSrvc       services.IUserService
+	summarySrvc    services.ISummaryService
+	embedTokenSrvc services.IEmbedTokenService
+	cache          *cache.Cache
 }
 
-func NewBadgeHandler(summaryService services.ISummaryService, userService services.IUserService) *BadgeHandler {
+func NewBadgeHandler(summaryService services.ISummaryService, userService services.IUserService, embedTokenService services.IEmbedTokenService) *BadgeHandler {
 	return &BadgeHandler{
-		summarySrvc: summaryService,
-		userSrvc:    userService,
-		cache:       cache.New(time.Hour, time.Hour),
-		config:      conf.Get(),
+		summarySrvc:    summaryService,
+		userSrvc:       userService,
+		embedTokenSrvc: embedTokenService,
+		cache:          cache.New(time.Hour, time.Hour),
+		config:         conf.Get(),
 	}
 }
 
 func (h *BadgeHandler) RegisterRoutes(router *mux.Router) {
-	// no auth middleware here, handler itself resolves the user
+	// authentication is optional and only by embed token, as badges are public, handler itself resolves the user
+	// cookies and api keys are ignored, as badges are embedded into other web pages, where they'd grant access to unshared data
 	r := router.PathPrefix("/compat/shields/v1/{user}").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOnlyEmbedTokens(h.embedTokenSrvc, models.EmbedScopeBadge).WithOptionalFor([]string{"/"}).Handler,
+	)
 	r.Methods(http.MethodGet).HandlerFunc(h.Get)
 }
 
 // @Summary Get badge data
-// @Description Retrieve total time for a given entity (e.g. a project) within a given range (e.g. one week) in a format compatible with [Shields.io](https://shields.io/endpoint). Requires public data access to be allowed.
+// @Description Retrieve total time for a given entity (e.g. a project) within a given range (e.g. one week) in a format compatible with [Shields.io](https://shields.io/endpoint). Requires public data access to be allowed or an embed token with 'badge' scope.
 // @ID get-badge
 // @Tags badges
 // @Produce json
 // @Param user path string true "User ID to fetch data for"
-// @Param interval path string true "Interval to aggregate data for" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, any)
+// @Param interval path string true "Interval to aggregate data for" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, last_week, quarter, last_quarter, year_to_date, fiscal_year, last_fiscal_year, any)
 // @Param filter path string true "Filter to apply (e.g. 'project:wakapi' or 'language:Go')"
+// @Param embed_token query string false "Embed token, to access unshared data"
 // @Success 200 {object} v1.BadgeData
 // @Router /compat/shields/v1/{user}/{interval}/{filter} [get]
 func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -69,51 +77,50 @@ func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
 	}
 
 	requestedUserId := mux.Vars(r)["user"]
-	user, err := h.userSrvc.GetUserById(requestedUserId)
+	authorizedUser := middlewares.GetPrincipal(r)
+	if authorizedUser != nil && requestedUserId == "current" {
+		requestedUserId = authorizedUser.ID
+	}
+
+	user, err := middlewares.GetUserById(r, h.userSrvc, requestedUserId)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	isOwner := authorizedUser != nil && authorizedUser.ID == user.ID
 
 	_, rangeFrom, rangeTo := utils.ResolveIntervalTZ(interval, user.TZ())
-	minStart := rangeTo.Add(-24 * time.Hour * time.Duration(user.ShareDataMaxDays))
-	// negative value means no limit
-	if rangeFrom.Before(minStart) && user.ShareDataMaxDays >= 0 {
-		w.WriteHeader(http.StatusForbidden)
-		w.Write([]byte("requested time range too broad"))
+	if !isOwner && !user.SharesTotalWithin(rangeFrom, rangeTo) {
+		utils.RespondError(w, r, http.StatusForbidden, "requested time range too broad")
 		return
 	}
 
-	var permitEntity bool
-	var filters *models.Filters
+	var entityType uint8
 	switch filterEntity {
 	case "project":
-		permitEntity = user.ShareProjects
-		filters = models.NewFiltersWith(models.SummaryProject, filterKey)
+		entityType = models.SummaryProject
 	case "os":
-		permitEntity = user.ShareOSs
-		filters = models.NewFiltersWith(models.SummaryOS, filterKey)
+		entityType = models.SummaryOS
 	case "editor":
-		permitEntity = user.ShareEditors
-		filters = models.NewFiltersWith(models.SummaryEditor, filterKey)
+		entityType = models.SummaryEditor
 	case "language":
-		permitEntity = user.ShareLanguages
-		filters = models.NewFiltersWith(models.SummaryLanguage, filterKey)
+		entityType = models.SummaryLanguage
 	case "machine":
-		permitEntity = user.ShareMachines
-		filters = models.NewFiltersWith(models.SummaryMachine, filterKey)
+		entityType = models.SummaryMachine
 	case "label":
-		permitEntity = user.ShareLabels
-		filters = models.NewFiltersWith(models.SummaryLabel, filterKey)
+		entityType = models.SummaryLabel
 	// branches are intentionally omitted here, as only relevant in combination with a project filter
 	default:
-		permitEntity = true
-		filters = &models.Filters{}
+		entityType = models.NSummaryTypes
+	}
+
+	filters := &models.Filters{}
+	if entityType != models.NSummaryTypes {
+		filters = models.NewFiltersWith(entityType, filterKey)
 	}
 
-	if !permitEntity {
-		w.WriteHeader(http.StatusForbidden)
-		w.Write([]byte("user did not opt in to share entity-specific data"))
+	if entityType != models.NSummaryTypes && !isOwner && !user.SharesWithin(entityType, rangeFrom, rangeTo) {
+		utils.RespondError(w, r, http.StatusForbidden, "user did not opt in to share entity-specific data for the requested time range")
 		return
 	}
 
@@ -125,8 +132,7 @@ func (h *BadgeHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	summary, err, status := h.loadUserSummary(user, interval, filters)
 	if err != nil {
-		w.WriteHeader(status)
-		w.Write([]byte(err.Error()))
+		utils.RespondError(w, r, status, err.Error())
 		return
 	}
 
@@ -52,8 +52,7 @@ func (h *AllTimeHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	summary, err, status := h.loadUserSummary(user, utils.ParseSummaryFilters(r))
 	if err != nil {
-		w.WriteHeader(status)
-		w.Write([]byte(err.Error()))
+		utils.RespondError(w, r, status, err.Error())
 		return
 	}
 
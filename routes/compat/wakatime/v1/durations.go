@@ -0,0 +1,89 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	wakatime "github.com/muety/wakapi/models/compat/wakatime/v1"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type DurationsHandler struct {
+	userSrvc     services.IUserService
+	durationSrvc services.IDurationService
+}
+
+func NewDurationsHandler(userService services.IUserService, durationService services.IDurationService) *DurationsHandler {
+	return &DurationsHandler{
+		userSrvc:     userService,
+		durationSrvc: durationService,
+	}
+}
+
+func (h *DurationsHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/compat/wakatime/v1/users/{user}/durations").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the user's coding activity for the given day as a list of durations
+// @Description Mimics https://wakatime.com/developers#durations.
+// @ID get-wakatime-durations
+// @Tags wakatime
+// @Produce json
+// @Produce text/csv
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param date query string true "Date (e.g. '2021-02-07')"
+// @Param project query string false "Project to filter by"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.DurationsViewModel
+// @Failure 400 {string} string "bad date"
+// @Router /compat/wakatime/v1/users/{user}/durations [get]
+func (h *DurationsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	params := r.URL.Query()
+	date, err := time.Parse(conf.SimpleDateFormat, params.Get("date"))
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, "bad date")
+		return
+	}
+
+	timezone := user.TZ()
+	rangeFrom, rangeTo := utils.StartOfDay(date.In(timezone)), utils.EndOfDay(date.In(timezone))
+
+	var filters *models.Filters
+	if project := params.Get("project"); project != "" {
+		filters = models.NewFiltersWith(models.SummaryProject, project)
+	}
+
+	durations, err := h.durationSrvc.Get(rangeFrom, rangeTo, user, filters)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to retrieve durations - %v", err)
+		return
+	}
+
+	if utils.AcceptsCsv(r) {
+		routeutils.RespondDurationsCsv(w, r, durations)
+		return
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, &wakatime.DurationsViewModel{
+		Data:     wakatime.DurationsToCompat(durations),
+		Start:    rangeFrom.UTC().Format(time.RFC3339),
+		End:      rangeTo.UTC().Format(time.RFC3339),
+		Timezone: timezone.String(),
+	})
+}
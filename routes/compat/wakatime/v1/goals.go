@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type GoalsHandler struct {
+	config   *conf.Config
+	userSrvc services.IUserService
+	goalSrvc services.IGoalService
+}
+
+func NewGoalsHandler(userService services.IUserService, goalService services.IGoalService) *GoalsHandler {
+	return &GoalsHandler{
+		userSrvc: userService,
+		goalSrvc: goalService,
+		config:   conf.Get(),
+	}
+}
+
+func (h *GoalsHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/compat/wakatime/v1/users/{user}/goals").Subrouter()
+	r.Use(
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+	)
+	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the user's goals and their status within the current interval
+// @Description Mimics https://wakatime.com/developers#goals
+// @ID get-wakatime-goals
+// @Tags wakatime
+// @Produce json
+// @Param user path string true "User ID to fetch data for (or 'current')"
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Number of goals per page, all by default"
+// @Security ApiKeyAuth
+// @Success 200 {object} v1.GoalsViewModel
+// @Router /compat/wakatime/v1/users/{user}/goals [get]
+func (h *GoalsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := routeutils.CheckEffectiveUser(w, r, h.userSrvc, "current")
+	if err != nil {
+		return // response was already sent by util function
+	}
+
+	pageParams, err := routeutils.ParsePageParams(r, 0, 0)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	progresses, err := h.goalSrvc.GetProgresses(user)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		conf.Log().Request(r).Error("failed to compute goal progress for user '%s' - %v", user.ID, err)
+		return
+	}
+
+	from, to := pageParams.Bounds(len(progresses))
+	goals := make([]*v1.Goal, 0, to-from)
+	for _, p := range progresses[from:to] {
+		goals = append(goals, v1.NewGoal(p))
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, &v1.GoalsViewModel{Data: goals, Pagination: pageParams.Pagination(len(progresses))})
+}
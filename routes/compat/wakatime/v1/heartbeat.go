@@ -7,6 +7,7 @@ import (
 	"github.com/gorilla/mux"
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
 	wakatime "github.com/muety/wakapi/models/compat/wakatime/v1"
 	routeutils "github.com/muety/wakapi/routes/utils"
 	"github.com/muety/wakapi/services"
@@ -18,6 +19,7 @@ type HeartbeatsResult struct {
 	End      string                     `json:"end"`
 	Start    string                     `json:"start"`
 	Timezone string                     `json:"timezone"`
+	wakatime.Pagination
 }
 
 type HeartbeatHandler struct {
@@ -43,8 +45,16 @@ func (h *HeartbeatHandler) RegisterRoutes(router *mux.Router) {
 // @Summary Get heartbeats of user for specified date
 // @ID get-heartbeats
 // @Tags heartbeat
+// @Produce json
+// @Produce text/csv
 // @Param date query string true "Date"
 // @Param user path string true "Username (or current)"
+// @Param order_by query string false "Attribute to sort heartbeats by, where name refers to the project" Enums(time, name)
+// @Param order query string false "Sort direction" Enums(asc, desc)
+// @Param fields query string false "Comma-separated list of heartbeat attributes to include (e.g. 'time,project'), all by default"
+// @Param metadata.{key} query string false "Only include heartbeats, whose metadata has the given value for the given top-level key (e.g. 'metadata.ci_job=1234')"
+// @Param page query int false "Page number, starting at 1, ignored for csv"
+// @Param limit query int false "Number of heartbeats per page, all by default"
 // @Security ApiKeyAuth
 // @Success 200 {object} HeartbeatsResult
 // @Failure 400 {string} string "bad date"
@@ -59,27 +69,63 @@ func (h *HeartbeatHandler) Get(w http.ResponseWriter, r *http.Request) {
 	dateParam := params.Get("date")
 	date, err := time.Parse(conf.SimpleDateFormat, dateParam)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("bad date"))
+		utils.RespondError(w, r, http.StatusBadRequest, "bad date")
 		return
 	}
 
 	timezone := user.TZ()
 	rangeFrom, rangeTo := utils.StartOfDay(date.In(timezone)), utils.EndOfDay(date.In(timezone))
 
-	heartbeats, err := h.heartbeatSrvc.GetAllWithin(rangeFrom, rangeTo, user)
+	ordering, err := routeutils.ParseOrdering(r, models.OrderByTime, models.OrderByName)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(conf.ErrInternalServerError))
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pageParams, err := routeutils.ParsePageParams(r, 0, 0)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var heartbeats []*models.Heartbeat
+	if ordering != nil {
+		heartbeats, err = h.heartbeatSrvc.GetAllWithinOrdered(rangeFrom, rangeTo, user, ordering)
+	} else {
+		heartbeats, err = h.heartbeatSrvc.GetAllWithin(rangeFrom, rangeTo, user)
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
 		conf.Log().Request(r).Error("failed to retrieve heartbeats - %v", err)
 		return
 	}
 
+	if filters := models.ParseHeartbeatMetadataFilters(params); len(filters) > 0 {
+		heartbeats = filterHeartbeatsByMetadata(heartbeats, filters)
+	}
+
+	if utils.AcceptsCsv(r) {
+		routeutils.RespondHeartbeatsCsv(w, r, heartbeats)
+		return
+	}
+
+	from, to := pageParams.Bounds(len(heartbeats))
 	res := HeartbeatsResult{
-		Data:     wakatime.HeartbeatsToCompat(heartbeats),
-		Start:    rangeFrom.UTC().Format(time.RFC3339),
-		End:      rangeTo.UTC().Format(time.RFC3339),
-		Timezone: timezone.String(),
+		Data:       wakatime.HeartbeatsToCompat(heartbeats[from:to]),
+		Start:      rangeFrom.UTC().Format(time.RFC3339),
+		End:        rangeTo.UTC().Format(time.RFC3339),
+		Timezone:   timezone.String(),
+		Pagination: pageParams.Pagination(len(heartbeats)),
+	}
+	utils.RespondJSON(w, r, http.StatusOK, utils.SelectFields(r, res))
+}
+
+func filterHeartbeatsByMetadata(heartbeats []*models.Heartbeat, filters map[string]string) []*models.Heartbeat {
+	filtered := make([]*models.Heartbeat, 0, len(heartbeats))
+	for _, hb := range heartbeats {
+		if hb.Metadata.Matches(filters) {
+			filtered = append(filtered, hb)
+		}
 	}
-	utils.RespondJSON(w, r, http.StatusOK, res)
+	return filtered
 }
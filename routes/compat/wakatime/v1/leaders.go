@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	routeutils "github.com/muety/wakapi/routes/utils"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+const leadersPageSize = 100
+
+type LeadersHandler struct {
+	config          *conf.Config
+	leaderboardSrvc services.ILeaderboardService
+}
+
+func NewLeadersHandler(leaderboardService services.ILeaderboardService) *LeadersHandler {
+	return &LeadersHandler{
+		leaderboardSrvc: leaderboardService,
+		config:          conf.Get(),
+	}
+}
+
+func (h *LeadersHandler) RegisterRoutes(router *mux.Router) {
+	router.Path("/compat/wakatime/v1/leaders").Methods(http.MethodGet).HandlerFunc(h.Get)
+}
+
+// @Summary Retrieve the leaderboard
+// @Description Mimics https://wakatime.com/developers#leaders
+// @ID get-wakatime-leaders
+// @Tags wakatime
+// @Produce json
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Number of users per page, at most 100"
+// @Param order_by query string false "Attribute to sort by, either 'rank' (default) or 'name'"
+// @Param order query string false "Sort direction, either 'asc' (default) or 'desc'"
+// @Success 200 {object} v1.LeadersViewModel
+// @Failure 400 {string} string "invalid ordering or page parameters"
+// @Failure 404 {string} string "leaderboard is disabled"
+// @Router /compat/wakatime/v1/leaders [get]
+func (h *LeadersHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ordering, err := routeutils.ParseOrdering(r, models.OrderByRank, models.OrderByName)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pageParams, err := routeutils.ParsePageParams(r, leadersPageSize, leadersPageSize)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, err := h.leaderboardSrvc.GetLeaderboard()
+	if err == services.ErrLeaderboardDisabled {
+		utils.RespondError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		conf.Log().Request(r).Error("failed to get leaderboard - %v", err)
+		utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+		return
+	}
+
+	if ordering != nil {
+		items = sortLeaderboard(items, ordering)
+	}
+
+	from, to := pageParams.Bounds(len(items))
+	entries := make([]*v1.LeadersEntry, 0, to-from)
+	for _, item := range items[from:to] {
+		entries = append(entries, v1.NewLeadersEntry(item))
+	}
+
+	utils.RespondJSON(w, r, http.StatusOK, &v1.LeadersViewModel{Data: entries, Pagination: pageParams.Pagination(len(items))})
+}
+
+// sortLeaderboard returns a sorted copy of the given leaderboard, which is ordered by rank initially
+func sortLeaderboard(items []*models.LeaderboardItem, ordering *models.Ordering) []*models.LeaderboardItem {
+	sorted := make([]*models.LeaderboardItem, len(items))
+	copy(sorted, items)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if ordering.Desc {
+			a, b = b, a
+		}
+		if ordering.By == models.OrderByName {
+			return a.UserID < b.UserID
+		}
+		return a.Rank < b.Rank
+	})
+	return sorted
+}
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeadersHandler_Get_Ordering(t *testing.T) {
+	config.Set(&config.Config{})
+
+	leaderboardService := new(mocks.LeaderboardServiceMock)
+	leaderboardService.On("GetLeaderboard").Return([]*models.LeaderboardItem{
+		{UserID: "muety", Rank: 1, TotalSeconds: 7200},
+		{UserID: "anchan42", Rank: 2, TotalSeconds: 3600},
+		{UserID: "john", Rank: 2, TotalSeconds: 3600},
+	}, nil)
+
+	router := mux.NewRouter()
+	NewLeadersHandler(leaderboardService).RegisterRoutes(router)
+
+	get := func(query string) (int, []string) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/compat/wakatime/v1/leaders"+query, nil))
+		if w.Code != http.StatusOK {
+			return w.Code, nil
+		}
+
+		var result v1.LeadersViewModel
+		assert.Nil(t, json.NewDecoder(w.Body).Decode(&result))
+		users := make([]string, len(result.Data))
+		for i, e := range result.Data {
+			users[i] = e.User.ID
+		}
+		return w.Code, users
+	}
+
+	status, users := get("")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"muety", "anchan42", "john"}, users)
+
+	_, users = get("?order_by=name")
+	assert.Equal(t, []string{"anchan42", "john", "muety"}, users)
+
+	_, users = get("?order=desc")
+	assert.Equal(t, []string{"anchan42", "john", "muety"}, users) // equal ranks keep their order
+
+	_, users = get("?order_by=name&order=desc&limit=2")
+	assert.Equal(t, []string{"muety", "john"}, users)
+
+	status, _ = get("?order_by=total")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = get("?order=random")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
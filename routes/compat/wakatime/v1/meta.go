@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+// MetaHandler serves the editors and languages known to the instance, e.g. for clients to populate dropdowns or colorize charts
+type MetaHandler struct {
+	config       *conf.Config
+	languageSrvc services.ILanguageMetaService
+}
+
+func NewMetaHandler(languageMetaService services.ILanguageMetaService) *MetaHandler {
+	return &MetaHandler{
+		config:       conf.Get(),
+		languageSrvc: languageMetaService,
+	}
+}
+
+func (h *MetaHandler) RegisterRoutes(router *mux.Router) {
+	router.Path("/compat/wakatime/v1/editors").Methods(http.MethodGet).HandlerFunc(h.GetEditors)
+	router.Path("/compat/wakatime/v1/program_languages").Methods(http.MethodGet).HandlerFunc(h.GetProgramLanguages)
+}
+
+// @Summary Retrieve all known editors along with their colors
+// @Description Mimics https://wakatime.com/developers#editors
+// @ID get-wakatime-editors
+// @Tags wakatime
+// @Produce json
+// @Success 200 {object} v1.EditorsViewModel
+// @Router /compat/wakatime/v1/editors [get]
+func (h *MetaHandler) GetEditors(w http.ResponseWriter, r *http.Request) {
+	utils.RespondJSON(w, r, http.StatusOK, v1.NewEditorsViewModel(h.config.App.GetEditorColors()))
+}
+
+// @Summary Retrieve all known programming languages along with their colors
+// @Description Mimics https://wakatime.com/developers#program_languages
+// @ID get-wakatime-program-languages
+// @Tags wakatime
+// @Produce json
+// @Success 200 {object} v1.ProgramLanguagesViewModel
+// @Router /compat/wakatime/v1/program_languages [get]
+func (h *MetaHandler) GetProgramLanguages(w http.ResponseWriter, r *http.Request) {
+	utils.RespondJSON(w, r, http.StatusOK, v1.NewProgramLanguagesViewModel(h.languageSrvc.GetAll()))
+}
@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetaHandler_GetEditors(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.App.Colors = map[string]map[string]string{
+		"editors": {"VSCode": "#3c99dc", "goland": "#087cfa"},
+	}
+	config.Set(cfg)
+
+	router := mux.NewRouter()
+	NewMetaHandler(new(mocks.LanguageMetaServiceMock)).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/compat/wakatime/v1/editors", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result v1.EditorsViewModel
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, 2, result.Total)
+	assert.Len(t, result.Data, 2)
+	assert.Equal(t, "goland", result.Data[0].ID)
+	assert.Equal(t, "#087cfa", result.Data[0].Color)
+	assert.Equal(t, "vscode", result.Data[1].ID)
+	assert.Equal(t, "#3c99dc", result.Data[1].Color)
+}
+
+func TestMetaHandler_GetProgramLanguages(t *testing.T) {
+	config.Set(&config.Config{})
+
+	languageMetaService := new(mocks.LanguageMetaServiceMock)
+	languageMetaService.On("GetAll").Return([]*models.LanguageMeta{
+		{Key: "go", Name: "Go", Color: "#00add8", Icon: "simple-icons:go"},
+		{Key: "templ", Name: "Templ", Color: "#ffd700", Custom: true},
+		{Key: "unknownlang", Name: "UnknownLang"},
+	})
+
+	router := mux.NewRouter()
+	NewMetaHandler(languageMetaService).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/compat/wakatime/v1/program_languages", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result v1.ProgramLanguagesViewModel
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&result))
+	assert.Equal(t, 3, result.Total)
+	assert.Len(t, result.Data, 3)
+
+	assert.Equal(t, "go", result.Data[0].ID)
+	assert.Equal(t, "Go", result.Data[0].Name)
+	assert.Equal(t, "simple-icons:go", result.Data[0].Icon)
+	assert.True(t, result.Data[0].IsVerified)
+
+	// languages added by admins or without a linguist color are not verified
+	assert.False(t, result.Data[1].IsVerified)
+	assert.False(t, result.Data[2].IsVerified)
+
+	languageMetaService.AssertExpectations(t)
+}
+
+func TestMetaHandler_MethodNotAllowed(t *testing.T) {
+	config.Set(&config.Config{})
+
+	router := mux.NewRouter()
+	NewMetaHandler(new(mocks.LanguageMetaServiceMock)).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/compat/wakatime/v1/editors", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -15,16 +15,18 @@ import (
 )
 
 type ProjectsHandler struct {
-	config        *conf.Config
-	userSrvc      services.IUserService
-	heartbeatSrvc services.IHeartbeatService
+	config          *conf.Config
+	userSrvc        services.IUserService
+	heartbeatSrvc   services.IHeartbeatService
+	projectRepoSrvc services.IProjectRepoService
 }
 
-func NewProjectsHandler(userService services.IUserService, heartbeatsService services.IHeartbeatService) *ProjectsHandler {
+func NewProjectsHandler(userService services.IUserService, heartbeatsService services.IHeartbeatService, projectRepoService services.IProjectRepoService) *ProjectsHandler {
 	return &ProjectsHandler{
-		userSrvc:      userService,
-		heartbeatSrvc: heartbeatsService,
-		config:        conf.Get(),
+		userSrvc:        userService,
+		heartbeatSrvc:   heartbeatsService,
+		projectRepoSrvc: projectRepoService,
+		config:          conf.Get(),
 	}
 }
 
@@ -43,6 +45,10 @@ func (h *ProjectsHandler) RegisterRoutes(router *mux.Router) {
 // @Produce json
 // @Param user path string true "User ID to fetch data for (or 'current')"
 // @Param q query string true "Query to filter projects by"
+// @Param order_by query string false "Attribute to sort projects by" Enums(name, last_activity)
+// @Param order query string false "Sort direction" Enums(asc, desc)
+// @Param page query int false "Page number, starting at 1"
+// @Param limit query int false "Number of projects per page, all by default"
 // @Security ApiKeyAuth
 // @Success 200 {object} v1.ProjectsViewModel
 // @Router /compat/wakatime/v1/users/{user}/projects [get]
@@ -52,10 +58,33 @@ func (h *ProjectsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return // response was already sent by util function
 	}
 
-	results, err := h.heartbeatSrvc.GetEntitySetByUser(models.SummaryProject, user)
+	ordering, err := routeutils.ParseOrdering(r, models.OrderByName, models.OrderByLastActivity)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("something went wrong"))
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pageParams, err := routeutils.ParsePageParams(r, 0, 0)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var results []*models.ProjectActivity
+	if ordering != nil {
+		results, err = h.heartbeatSrvc.GetProjectActivityByUser(user, ordering)
+	} else {
+		results, err = h.getProjects(user)
+	}
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, "something went wrong")
+		conf.Log().Request(r).Error(err.Error())
+		return
+	}
+
+	repos, err := h.projectRepoSrvc.GetByUserMapped(user.ID)
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, "something went wrong")
 		conf.Log().Request(r).Error(err.Error())
 		return
 	}
@@ -64,11 +93,33 @@ func (h *ProjectsHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	projects := make([]*v1.Project, 0, len(results))
 	for _, p := range results {
-		if strings.HasPrefix(p, q) {
-			projects = append(projects, &v1.Project{ID: p, Name: p})
+		if strings.HasPrefix(p.Project, q) {
+			project := &v1.Project{ID: p.Project, Name: p.Project}
+			if p.LastActivity.Valid() {
+				t := p.LastActivity.T()
+				project.LastHeartbeatAt = &t
+			}
+			if repo, ok := repos[p.Project]; ok {
+				project.Repository = repo.Url
+			}
+			projects = append(projects, project)
 		}
 	}
 
-	vm := &v1.ProjectsViewModel{Data: projects}
+	from, to := pageParams.Bounds(len(projects))
+	vm := &v1.ProjectsViewModel{Data: projects[from:to], Pagination: pageParams.Pagination(len(projects))}
 	utils.RespondJSON(w, r, http.StatusOK, vm)
 }
+
+// getProjects returns the user's (cached) project names, without activity times, if no particular ordering was requested
+func (h *ProjectsHandler) getProjects(user *models.User) ([]*models.ProjectActivity, error) {
+	names, err := h.heartbeatSrvc.GetEntitySetByUser(models.SummaryProject, user)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*models.ProjectActivity, len(names))
+	for i, name := range names {
+		results[i] = &models.ProjectActivity{Project: name}
+	}
+	return results, nil
+}
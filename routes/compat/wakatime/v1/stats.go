@@ -2,35 +2,43 @@ package v1
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/gorilla/mux"
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
 	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	routeutils "github.com/muety/wakapi/routes/utils"
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
 )
 
 type StatsHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
+	config         *conf.Config
+	userSrvc       services.IUserService
+	summarySrvc    services.ISummaryService
+	dayOffSrvc     services.IDayOffService
+	filterSetSrvc  services.IFilterSetService
+	cacheSrvc      services.IStatsCacheService
+	embedTokenSrvc services.IEmbedTokenService
 }
 
-func NewStatsHandler(userService services.IUserService, summaryService services.ISummaryService) *StatsHandler {
+func NewStatsHandler(userService services.IUserService, summaryService services.ISummaryService, dayOffService services.IDayOffService, filterSetService services.IFilterSetService, statsCacheService services.IStatsCacheService, embedTokenService services.IEmbedTokenService) *StatsHandler {
 	return &StatsHandler{
-		userSrvc:    userService,
-		summarySrvc: summaryService,
-		config:      conf.Get(),
+		userSrvc:       userService,
+		summarySrvc:    summaryService,
+		dayOffSrvc:     dayOffService,
+		filterSetSrvc:  filterSetService,
+		cacheSrvc:      statsCacheService,
+		embedTokenSrvc: embedTokenService,
+		config:         conf.Get(),
 	}
 }
 
 func (h *StatsHandler) RegisterRoutes(router *mux.Router) {
 	r := router.PathPrefix("").Subrouter()
 	r.Use(
-		middlewares.NewAuthenticateMiddleware(h.userSrvc).WithOptionalFor([]string{"/"}).Handler,
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).WithEmbedTokens(h.embedTokenSrvc, models.EmbedScopeStats).WithOptionalFor([]string{"/"}).Handler,
 	)
 	r.Path("/v1/users/{user}/stats/{range}").Methods(http.MethodGet).HandlerFunc(h.Get)
 	r.Path("/compat/wakatime/v1/users/{user}/stats/{range}").Methods(http.MethodGet).HandlerFunc(h.Get)
@@ -48,13 +56,16 @@ func (h *StatsHandler) RegisterRoutes(router *mux.Router) {
 // @Tags wakatime
 // @Produce json
 // @Param user path string true "User ID to fetch data for (or 'current')"
-// @Param range path string false "Range interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, any)
+// @Param range path string false "Range interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, last_week, quarter, last_quarter, year_to_date, fiscal_year, last_fiscal_year, any)
 // @Param project query string false "Project to filter by"
 // @Param language query string false "Language to filter by"
 // @Param editor query string false "Editor to filter by"
 // @Param operating_system query string false "OS to filter by"
 // @Param machine query string false "Machine to filter by"
 // @Param label query string false "Project label to filter by"
+// @Param filter_set query string false "Name of a saved filter set to apply (only for the requesting user's own stats)"
+// @Param fields query string false "Comma-separated list of sections to include (e.g. 'languages,projects'), all by default"
+// @Param embed_token query string false "Embed token with 'stats' scope, as an alternative to the api key"
 // @Security ApiKeyAuth
 // @Success 200 {object} v1.StatsViewModel
 // @Router /compat/wakatime/v1/users/{user}/stats/{range} [get]
@@ -67,10 +78,9 @@ func (h *StatsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		vars["user"] = authorizedUser.ID
 	}
 
-	requestedUser, err := h.userSrvc.GetUserById(vars["user"])
+	requestedUser, err := middlewares.GetUserById(r, h.userSrvc, vars["user"])
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("user not found"))
+		utils.RespondError(w, r, http.StatusNotFound, "user not found")
 		return
 	}
 
@@ -81,60 +91,85 @@ func (h *StatsHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	err, rangeFrom, rangeTo := utils.ResolveIntervalRawTZ(rangeParam, requestedUser.TZ())
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("invalid range"))
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid range")
 		return
 	}
 
-	minStart := rangeTo.Add(-24 * time.Hour * time.Duration(requestedUser.ShareDataMaxDays))
-	if (authorizedUser == nil || requestedUser.ID != authorizedUser.ID) &&
-		rangeFrom.Before(minStart) && requestedUser.ShareDataMaxDays >= 0 {
-		w.WriteHeader(http.StatusForbidden)
-		w.Write([]byte("requested time range too broad"))
+	isOwner := authorizedUser != nil && requestedUser.ID == authorizedUser.ID
+	if !isOwner && !requestedUser.SharesTotalWithin(rangeFrom, rangeTo) {
+		utils.RespondError(w, r, http.StatusForbidden, "requested time range too broad")
 		return
 	}
 
-	summary, err, status := h.loadUserSummary(requestedUser, rangeFrom, rangeTo, utils.ParseSummaryFilters(r))
-	if err != nil {
-		w.WriteHeader(status)
-		w.Write([]byte(err.Error()))
+	filters := utils.ParseSummaryFilters(r)
+	if r.URL.Query().Get("filter_set") != "" {
+		if !isOwner {
+			utils.RespondError(w, r, http.StatusForbidden, "filter sets are private")
+			return
+		}
+		filterSetFilters, err, status := routeutils.ApplyFilterSet(h.filterSetSrvc, requestedUser, r, filters)
+		if err != nil {
+			utils.RespondError(w, r, status, err.Error())
+			return
+		}
+		filters = filterSetFilters
+	}
+	if !isOwner && !routeutils.SharesFilters(requestedUser, filters, rangeFrom, rangeTo) {
+		utils.RespondError(w, r, http.StatusForbidden, "filtering by unshared data")
 		return
 	}
 
-	stats := v1.NewStatsFrom(summary, &models.Filters{})
-
-	// post filter stats according to user's given sharing permissions
-	if !requestedUser.ShareEditors {
-		stats.Data.Editors = nil
-	}
-	if !requestedUser.ShareLanguages {
-		stats.Data.Languages = nil
-	}
-	if !requestedUser.ShareProjects {
-		stats.Data.Projects = nil
-	}
-	if !requestedUser.ShareOSs {
-		stats.Data.OperatingSystems = nil
-	}
-	if !requestedUser.ShareMachines {
-		stats.Data.Machines = nil
+	// cached summaries are shared among all requesters, sharing permissions are applied to the resulting stats below
+	summary, err := h.cacheSrvc.GetOrCompute(requestedUser, rangeParam, filters, func() (*models.Summary, error) {
+		return h.summarySrvc.Aliased(rangeFrom, rangeTo, requestedUser, h.summarySrvc.Retrieve, filters, false)
+	})
+	if err != nil {
+		utils.RespondError(w, r, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	utils.RespondJSON(w, r, http.StatusOK, stats)
-}
-
-func (h *StatsHandler) loadUserSummary(user *models.User, start, end time.Time, filters *models.Filters) (*models.Summary, error, int) {
-	overallParams := &models.SummaryParams{
-		From:      start,
-		To:        end,
-		User:      user,
-		Recompute: false,
+	// days off might be sick days, so they are neither revealed to nor accounted for in the daily average for anyone but the owner
+	var holidays int
+	if isOwner {
+		daysOff, err := h.dayOffSrvc.GetByUserMapped(requestedUser.ID)
+		if err != nil {
+			utils.RespondError(w, r, http.StatusInternalServerError, conf.ErrInternalServerError)
+			conf.Log().Request(r).Error("failed to fetch days off for user '%s' - %v", requestedUser.ID, err)
+			return
+		}
+		holidays = daysOff.CountWithin(summary.FromTime.T().In(requestedUser.TZ()), summary.ToTime.T().In(requestedUser.TZ()))
 	}
 
-	summary, err := h.summarySrvc.Aliased(overallParams.From, overallParams.To, user, h.summarySrvc.Retrieve, filters, false)
-	if err != nil {
-		return nil, err, http.StatusInternalServerError
+	stats := v1.NewStatsFrom(summary, &models.Filters{}, holidays)
+
+	// post filter stats according to user's given sharing permissions
+	if !isOwner {
+		if !requestedUser.SharesWithin(models.SummaryEditor, rangeFrom, rangeTo) {
+			stats.Data.Editors = nil
+		}
+		if !requestedUser.SharesWithin(models.SummaryLanguage, rangeFrom, rangeTo) {
+			stats.Data.Languages = nil
+		}
+		if !requestedUser.SharesWithin(models.SummaryProject, rangeFrom, rangeTo) {
+			// write time and lines changed are derived from projects
+			stats.Data.Projects = nil
+			stats.Data.WriteSeconds = 0
+			stats.Data.WritePercent = 0
+			stats.Data.LinesChanged = 0
+		}
+		if !requestedUser.SharesWithin(models.SummaryOS, rangeFrom, rangeTo) {
+			stats.Data.OperatingSystems = nil
+		}
+		if !requestedUser.SharesWithin(models.SummaryMachine, rangeFrom, rangeTo) {
+			stats.Data.Machines = nil
+		}
+		if !requestedUser.SharesWithin(models.SummaryBranch, rangeFrom, rangeTo) {
+			stats.Data.Branches = nil
+		}
+		if !requestedUser.SharesWithin(models.SummaryLabel, rangeFrom, rangeTo) {
+			stats.Data.Labels = nil
+		}
 	}
 
-	return summary, nil, http.StatusOK
+	utils.RespondJSON(w, r, http.StatusOK, utils.SelectFields(r, stats))
 }
@@ -0,0 +1,108 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStatsHandler_Get_Owner(t *testing.T) {
+	config.Set(&config.Config{})
+
+	user := &models.User{ID: "muety"}
+	userService, dayOffService, cacheService := setupStatsMocks(user)
+	dayOffService.On("GetByUserMapped", user.ID).Return(models.NewDaysOff([]*models.DayOff{
+		{UserID: user.ID, Day: time.Now().AddDate(0, 0, -2).Format(models.DayOffFormat), Kind: models.DayOffSick},
+	}), nil)
+
+	result := requestStats(t, NewStatsHandler(userService, nil, dayOffService, nil, cacheService, nil), user)
+
+	assert.Equal(t, 1, result.Data.Holidays)
+	assert.Equal(t, 6, result.Data.DaysMinusHolidays)
+	assert.Equal(t, 7*time.Hour.Seconds()/6, result.Data.DailyAverage)
+	assert.Equal(t, time.Hour.Seconds(), result.Data.WriteSeconds)
+	assert.Equal(t, 120, result.Data.LinesChanged)
+}
+
+func TestStatsHandler_Get_Public(t *testing.T) {
+	config.Set(&config.Config{})
+
+	user := &models.User{ID: "muety", ShareDataMaxDays: -1}
+	userService, dayOffService, cacheService := setupStatsMocks(user)
+
+	result := requestStats(t, NewStatsHandler(userService, nil, dayOffService, nil, cacheService, nil), nil)
+
+	// days off are private
+	assert.Equal(t, 0, result.Data.Holidays)
+	assert.Equal(t, 7, result.Data.DaysMinusHolidays)
+	assert.Equal(t, 7*time.Hour.Seconds()/7, result.Data.DailyAverage)
+	dayOffService.AssertNotCalled(t, "GetByUserMapped", mock.Anything)
+
+	// projects are not shared, neither is anything derived from them
+	assert.Nil(t, result.Data.Projects)
+	assert.Zero(t, result.Data.WriteSeconds)
+	assert.Zero(t, result.Data.WritePercent)
+	assert.Zero(t, result.Data.LinesChanged)
+}
+
+func TestStatsHandler_Get_PublicProjects(t *testing.T) {
+	config.Set(&config.Config{})
+
+	user := &models.User{ID: "muety", ShareDataMaxDays: -1, ShareProjectsDays: -1}
+	userService, dayOffService, cacheService := setupStatsMocks(user)
+
+	result := requestStats(t, NewStatsHandler(userService, nil, dayOffService, nil, cacheService, nil), nil)
+
+	assert.Len(t, result.Data.Projects, 1)
+	assert.Equal(t, time.Hour.Seconds(), result.Data.WriteSeconds)
+	assert.Equal(t, 120, result.Data.LinesChanged)
+}
+
+func setupStatsMocks(user *models.User) (*mocks.UserServiceMock, *mocks.DayOffServiceMock, *mocks.StatsCacheServiceMock) {
+	now := time.Now()
+	summary := &models.Summary{
+		UserID:   user.ID,
+		FromTime: models.CustomTime(now.AddDate(0, 0, -7)),
+		ToTime:   models.CustomTime(now),
+		Projects: []*models.SummaryItem{
+			{Type: models.SummaryProject, Key: "wakapi", Total: 7 * time.Hour / time.Second, Write: 1 * time.Hour / time.Second, Lines: 120},
+		},
+	}
+
+	userService := new(mocks.UserServiceMock)
+	userService.On("GetUserById", user.ID).Return(user, nil)
+	cacheService := new(mocks.StatsCacheServiceMock)
+	cacheService.On("GetOrCompute", user, "last_7_days", mock.Anything, mock.Anything).Return(summary, nil)
+
+	return userService, new(mocks.DayOffServiceMock), cacheService
+}
+
+func requestStats(t *testing.T, sut *StatsHandler, principal *models.User) *v1.StatsViewModel {
+	router := mux.NewRouter()
+	router.Use(middlewares.NewPrincipalMiddleware(), func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middlewares.SetPrincipal(r, principal)
+			next.ServeHTTP(w, r)
+		})
+	})
+	router.Path("/compat/wakatime/v1/users/{user}/stats/{range}").Methods(http.MethodGet).HandlerFunc(sut.Get)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/compat/wakatime/v1/users/muety/stats/last_7_days", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result v1.StatsViewModel
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&result))
+	return &result
+}
@@ -67,15 +67,13 @@ func (h *StatusBarHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	err, rangeFrom, rangeTo := utils.ResolveIntervalRawTZ(rangeParam, user.TZ())
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("invalid range"))
+		utils.RespondError(w, r, http.StatusBadRequest, "invalid range")
 		return
 	}
 
 	summary, status, err := h.loadUserSummary(user, rangeFrom, rangeTo)
 	if err != nil {
-		w.WriteHeader(status)
-		w.Write([]byte(err.Error()))
+		utils.RespondError(w, r, status, err.Error())
 		return
 	}
 	summariesView := v1.NewSummariesFrom([]*models.Summary{summary})
@@ -17,23 +17,25 @@ import (
 )
 
 type SummariesHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
+	config         *conf.Config
+	userSrvc       services.IUserService
+	summarySrvc    services.ISummaryService
+	embedTokenSrvc services.IEmbedTokenService
 }
 
-func NewSummariesHandler(userService services.IUserService, summaryService services.ISummaryService) *SummariesHandler {
+func NewSummariesHandler(userService services.IUserService, summaryService services.ISummaryService, embedTokenService services.IEmbedTokenService) *SummariesHandler {
 	return &SummariesHandler{
-		userSrvc:    userService,
-		summarySrvc: summaryService,
-		config:      conf.Get(),
+		userSrvc:       userService,
+		summarySrvc:    summaryService,
+		embedTokenSrvc: embedTokenService,
+		config:         conf.Get(),
 	}
 }
 
 func (h *SummariesHandler) RegisterRoutes(router *mux.Router) {
 	r := router.PathPrefix("/compat/wakatime/v1/users/{user}/summaries").Subrouter()
 	r.Use(
-		middlewares.NewAuthenticateMiddleware(h.userSrvc).Handler,
+		middlewares.NewAuthenticateMiddleware(h.userSrvc).WithEmbedTokens(h.embedTokenSrvc, models.EmbedScopeCharts).Handler,
 	)
 	r.Path("").Methods(http.MethodGet).HandlerFunc(h.Get)
 }
@@ -48,8 +50,10 @@ func (h *SummariesHandler) RegisterRoutes(router *mux.Router) {
 // @ID get-wakatime-summaries
 // @Tags wakatime
 // @Produce json
+// @Produce application/msgpack
+// @Produce text/csv
 // @Param user path string true "User ID to fetch data for (or 'current')"
-// @Param range query string false "Range interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, any)
+// @Param range query string false "Range interval identifier" Enums(today, yesterday, week, month, year, 7_days, last_7_days, 30_days, last_30_days, 12_months, last_12_months, last_week, quarter, last_quarter, year_to_date, fiscal_year, last_fiscal_year, any)
 // @Param start query string false "Start date (e.g. '2021-02-07')"
 // @Param end query string false "End date (e.g. '2021-02-08')"
 // @Param project query string false "Project to filter by"
@@ -58,6 +62,8 @@ func (h *SummariesHandler) RegisterRoutes(router *mux.Router) {
 // @Param operating_system query string false "OS to filter by"
 // @Param machine query string false "Machine to filter by"
 // @Param label query string false "Project label to filter by"
+// @Param fields query string false "Comma-separated list of sections to include (e.g. 'languages,projects'), all by default"
+// @Param embed_token query string false "Embed token with 'charts' scope, as an alternative to the api key"
 // @Security ApiKeyAuth
 // @Success 200 {object} v1.SummariesViewModel
 // @Router /compat/wakatime/v1/users/{user}/summaries [get]
@@ -69,13 +75,17 @@ func (h *SummariesHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	summaries, err, status := h.loadUserSummaries(r)
 	if err != nil {
-		w.WriteHeader(status)
-		w.Write([]byte(err.Error()))
+		utils.RespondError(w, r, status, err.Error())
+		return
+	}
+
+	if utils.AcceptsCsv(r) {
+		routeutils.RespondSummariesCsv(w, r, summaries)
 		return
 	}
 
 	vm := v1.NewSummariesFrom(summaries)
-	utils.RespondJSON(w, r, http.StatusOK, vm)
+	utils.RespondNegotiated(w, r, http.StatusOK, utils.SelectFields(r, vm))
 }
 
 func (h *SummariesHandler) loadUserSummaries(r *http.Request) ([]*models.Summary, error, int) {
@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models/view"
+	"github.com/muety/wakapi/services"
+)
+
+type InstanceHandler struct {
+	config   *conf.Config
+	miscSrvc services.IMiscService
+}
+
+func NewInstanceHandler(miscService services.IMiscService) *InstanceHandler {
+	return &InstanceHandler{
+		config:   conf.Get(),
+		miscSrvc: miscService,
+	}
+}
+
+func (h *InstanceHandler) RegisterRoutes(router *mux.Router) {
+	router.Path("/instance").Methods(http.MethodGet).HandlerFunc(h.GetIndex)
+}
+
+func (h *InstanceHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	stats, err := h.miscSrvc.GetInstanceStats()
+	if err == services.ErrInstanceStatsDisabled {
+		http.NotFound(w, r)
+		return
+	}
+
+	vm := h.buildViewModel(r)
+	if err != nil {
+		templates[conf.InstanceTemplate].Execute(w, vm.WithError("stats are not available yet, please check back later"))
+		return
+	}
+	vm.Stats = stats
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	templates[conf.InstanceTemplate].Execute(w, vm)
+}
+
+func (h *InstanceHandler) buildViewModel(r *http.Request) *view.InstanceViewModel {
+	return &view.InstanceViewModel{
+		Success: r.URL.Query().Get("success"),
+		Error:   r.URL.Query().Get("error"),
+	}
+}
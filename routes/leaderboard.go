@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models/view"
+	"github.com/muety/wakapi/services"
+)
+
+type LeaderboardHandler struct {
+	config          *conf.Config
+	leaderboardSrvc services.ILeaderboardService
+}
+
+func NewLeaderboardHandler(leaderboardService services.ILeaderboardService) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		config:          conf.Get(),
+		leaderboardSrvc: leaderboardService,
+	}
+}
+
+func (h *LeaderboardHandler) RegisterRoutes(router *mux.Router) {
+	router.Path("/leaderboard").Methods(http.MethodGet).HandlerFunc(h.GetIndex)
+}
+
+func (h *LeaderboardHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	items, err := h.leaderboardSrvc.GetLeaderboard()
+	if err == services.ErrLeaderboardDisabled {
+		http.NotFound(w, r)
+		return
+	}
+
+	vm := h.buildViewModel(r)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to get leaderboard - %v", err)
+		templates[conf.LeaderboardTemplate].Execute(w, vm.WithError("failed to load leaderboard"))
+		return
+	}
+	vm.Items = items
+
+	templates[conf.LeaderboardTemplate].Execute(w, vm)
+}
+
+func (h *LeaderboardHandler) buildViewModel(r *http.Request) *view.LeaderboardViewModel {
+	return &view.LeaderboardViewModel{
+		Success: r.URL.Query().Get("success"),
+		Error:   r.URL.Query().Get("error"),
+	}
+}
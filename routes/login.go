@@ -18,13 +18,15 @@ type LoginHandler struct {
 	config   *conf.Config
 	userSrvc services.IUserService
 	mailSrvc services.IMailService
+	demoSrvc services.IDemoService
 }
 
-func NewLoginHandler(userService services.IUserService, mailService services.IMailService) *LoginHandler {
+func NewLoginHandler(userService services.IUserService, mailService services.IMailService, demoService services.IDemoService) *LoginHandler {
 	return &LoginHandler{
 		config:   conf.Get(),
 		userSrvc: userService,
 		mailSrvc: mailService,
+		demoSrvc: demoService,
 	}
 }
 
@@ -32,6 +34,7 @@ func (h *LoginHandler) RegisterRoutes(router *mux.Router) {
 	router.Path("/login").Methods(http.MethodGet).HandlerFunc(h.GetIndex)
 	router.Path("/login").Methods(http.MethodPost).HandlerFunc(h.PostLogin)
 	router.Path("/logout").Methods(http.MethodPost).HandlerFunc(h.PostLogout)
+	router.Path("/demo").Methods(http.MethodGet).HandlerFunc(h.GetDemo)
 	router.Path("/signup").Methods(http.MethodGet).HandlerFunc(h.GetSignup)
 	router.Path("/signup").Methods(http.MethodPost).HandlerFunc(h.PostSignup)
 	router.Path("/set-password").Methods(http.MethodGet).HandlerFunc(h.GetSetPassword)
@@ -88,6 +91,12 @@ func (h *LoginHandler) PostLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.Deactivated {
+		w.WriteHeader(http.StatusForbidden)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r).WithError("account deactivated"))
+		return
+	}
+
 	encoded, err := h.config.Security.SecureCookie.Encode(models.AuthCookieKey, login.Username)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -111,6 +120,30 @@ func (h *LoginHandler) PostLogout(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, fmt.Sprintf("%s/", h.config.Server.BasePath), http.StatusFound)
 }
 
+// GetDemo logs the visitor in as the read-only demo user, if demo mode is enabled
+func (h *LoginHandler) GetDemo(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user, err := h.demoSrvc.GetUser()
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r).WithError("demo is not available"))
+		return
+	}
+
+	encoded, err := h.config.Security.SecureCookie.Encode(models.AuthCookieKey, user.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		templates[conf.LoginTemplate].Execute(w, h.buildViewModel(r).WithError("internal server error"))
+		return
+	}
+
+	http.SetCookie(w, h.config.CreateCookie(models.AuthCookieKey, encoded))
+	http.Redirect(w, r, fmt.Sprintf("%s/summary", h.config.Server.BasePath), http.StatusFound)
+}
+
 func (h *LoginHandler) GetSignup(w http.ResponseWriter, r *http.Request) {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -305,5 +338,6 @@ func (h *LoginHandler) buildViewModel(r *http.Request) *view.LoginViewModel {
 		Error:       r.URL.Query().Get("error"),
 		TotalUsers:  int(numUsers),
 		AllowSignup: h.config.IsDev() || h.config.Security.AllowSignup,
+		DemoMode:    h.demoSrvc.IsEnabled(),
 	}
 }
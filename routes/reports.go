@@ -0,0 +1,66 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models/view"
+	"github.com/muety/wakapi/services"
+)
+
+type ReportsHandler struct {
+	config     *conf.Config
+	userSrvc   services.IUserService
+	reportSrvc services.IReportService
+}
+
+func NewReportsHandler(userService services.IUserService, reportService services.IReportService) *ReportsHandler {
+	return &ReportsHandler{
+		config:     conf.Get(),
+		userSrvc:   userService,
+		reportSrvc: reportService,
+	}
+}
+
+func (h *ReportsHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/reports").Subrouter()
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithRedirectTarget(defaultErrorRedirectTarget()).Handler)
+	r.Methods(http.MethodGet).HandlerFunc(h.GetIndex)
+}
+
+func (h *ReportsHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		templates[conf.ReportsTemplate].Execute(w, h.buildViewModel(r).WithError("unauthorized"))
+		return
+	}
+
+	vm := h.buildViewModel(r)
+	vm.User = user
+	vm.ApiKey = user.ApiKey
+
+	reports, err := h.reportSrvc.GetArchive(user)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to fetch report archive for user '%s' - %v", user.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		templates[conf.ReportsTemplate].Execute(w, vm.WithError("failed to load reports"))
+		return
+	}
+	vm.Reports = reports
+
+	templates[conf.ReportsTemplate].Execute(w, vm)
+}
+
+func (h *ReportsHandler) buildViewModel(r *http.Request) *view.ReportsViewModel {
+	return &view.ReportsViewModel{
+		Success: r.URL.Query().Get("success"),
+		Error:   r.URL.Query().Get("error"),
+	}
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"fmt"
+	"github.com/muety/wakapi/i18n"
 	"github.com/muety/wakapi/views"
 	"html/template"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
 )
 
@@ -16,7 +18,10 @@ type action func(w http.ResponseWriter, r *http.Request) (int, string, string)
 
 var templates map[string]*template.Template
 
-func Init() {
+var maintenanceSrvc services.IMaintenanceService
+
+func Init(maintenanceService services.IMaintenanceService) {
+	maintenanceSrvc = maintenanceService
 	loadTemplates()
 }
 
@@ -38,6 +43,9 @@ func DefaultTemplateFuncs() template.FuncMap {
 		"localTZOffset":  utils.LocalTZOffset,
 		"entityTypes":    models.SummaryTypes,
 		"typeName":       typeName,
+		"t":              i18n.HTML,
+		"locales":        i18n.Locales,
+		"localeName":     i18n.Name,
 		"isDev": func() bool {
 			return config.Get().IsDev()
 		},
@@ -56,9 +64,65 @@ func DefaultTemplateFuncs() template.FuncMap {
 		"avatarUrlTemplate": func() string {
 			return config.Get().App.AvatarURLTemplate
 		},
+		"avatarUrl": func(user *models.User) string {
+			cfg := config.Get()
+			return user.AvatarURL(cfg.App.AvatarURLTemplate, cfg.App.AvatarGravatar, cfg.Server.GetPublicUrl())
+		},
+		"avatarGravatar": func() bool {
+			return config.Get().App.AvatarGravatar
+		},
+		"avatarUploads": func() bool {
+			return config.Get().App.AvatarUploads
+		},
 		"defaultWakatimeUrl": func() string {
 			return config.WakatimeApiUrl
 		},
+		"codeStatsXpPerMinute": func() int {
+			return models.CodeStatsXpPerMinute
+		},
+		"userHeartbeatScripts": func() bool {
+			return config.Get().App.UserHeartbeatScripts
+		},
+		"heartbeatsMaxPastDays": func() int {
+			return config.Get().App.HeartbeatsMaxPastDays
+		},
+		"heartbeatsMaxFutureMin": func() int {
+			return config.Get().App.HeartbeatsMaxFutureMin
+		},
+		"leaderboardEnabled": func() bool {
+			return config.Get().App.LeaderboardEnabled
+		},
+		"mailProvider": func() string {
+			if !config.Get().Mail.Enabled {
+				return ""
+			}
+			return config.Get().Mail.Provider
+		},
+		"maintenance": func() *models.Maintenance {
+			if maintenanceSrvc == nil {
+				return &models.Maintenance{}
+			}
+			return maintenanceSrvc.Get()
+		},
+		"logoUrl": func() string {
+			return config.Get().Theme.GetLogoUrl()
+		},
+		"mailLogoUrl": func() string {
+			return config.Get().Theme.GetMailLogoUrl(config.Get().Server.GetPublicUrl())
+		},
+		"primaryColor": func() template.CSS {
+			return template.CSS(config.Get().Theme.GetPrimaryColor())
+		},
+		"accentColor": func() template.CSS {
+			return template.CSS(config.Get().Theme.GetAccentColor())
+		},
+		"footerText": func() string {
+			return config.Get().Theme.FooterText
+		},
+		"themeCss":             themeCss,
+		"notificationEvents":   models.NotificationEvents,
+		"notificationChannels": models.NotificationChannels,
+		"relayRuleTypes":       models.RelayRuleTypes,
 	}
 }
 
@@ -84,9 +148,29 @@ func typeName(t uint8) string {
 	if t == models.SummaryBranch {
 		return "branch"
 	}
+	if t == models.SummaryTicket {
+		return "ticket"
+	}
+	if t == models.SummaryOrigin {
+		return "origin"
+	}
 	return "unknown"
 }
 
+// themeCss overrides the stylesheet's colors with those of the instance's theme, if any, as the stylesheet itself is precompiled.
+// Colors are validated to be hex codes at startup and thus safe to embed.
+func themeCss() template.CSS {
+	theme := config.Get().Theme
+	var css strings.Builder
+	if theme.PrimaryColor != "" {
+		css.WriteString(fmt.Sprintf(".text-green-700 { color: %s; } .border-green-700 { border-color: %s; } .bg-green-700 { background-color: %s; }", theme.PrimaryColor, theme.PrimaryColor, theme.PrimaryColor))
+	}
+	if theme.AccentColor != "" {
+		css.WriteString(fmt.Sprintf(" .bg-green-500 { background-color: %s; }", theme.AccentColor))
+	}
+	return template.CSS(css.String())
+}
+
 func loadTemplates() {
 	// Use local file system when in 'dev' environment, go embed file system otherwise
 	templateFs := config.ChooseFS("views", views.TemplateFiles)
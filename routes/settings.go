@@ -2,6 +2,7 @@ package routes
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/emvi/logbuch"
 	"github.com/gorilla/mux"
@@ -14,7 +15,9 @@ import (
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/services/imports"
 	"github.com/muety/wakapi/utils"
+	uuid "github.com/satori/go.uuid"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
@@ -22,6 +25,12 @@ import (
 )
 
 const criticalError = "a critical error has occurred, sorry"
+const googleCalendarStateKey = "wakapi_gcal_state"
+
+type googleCalendarState struct {
+	UserId string
+	Nonce  string
+}
 
 type SettingsHandler struct {
 	config              *conf.Config
@@ -32,8 +41,28 @@ type SettingsHandler struct {
 	aggregationSrvc     services.IAggregationService
 	languageMappingSrvc services.ILanguageMappingService
 	projectLabelSrvc    services.IProjectLabelService
+	manualTimeEntrySrvc services.IManualTimeEntryService
 	keyValueSrvc        services.IKeyValueService
 	mailSrvc            services.IMailService
+	jobSrvc             services.IJobService
+	scriptSrvc          services.IHeartbeatScriptService
+	exportSrvc          services.IExportService
+	avatarSrvc          services.IAvatarService
+	jiraSrvc            services.IJiraService
+	codeStatsSrvc       services.ICodeStatsService
+	projectRepoSrvc     services.IProjectRepoService
+	gcalSrvc            services.IGoogleCalendarService
+	budgetSrvc          services.IProjectBudgetService
+	goalSrvc            services.IGoalService
+	dayOffSrvc          services.IDayOffService
+	notificationSrvc    services.INotificationService
+	relaySrvc           services.IRelayTargetService
+	relayRuleSrvc       services.IRelayRuleService
+	quotaSrvc           services.IQuotaService
+	storageQuotaSrvc    services.IStorageQuotaService
+	clockSkewSrvc       services.IClockSkewService
+	maintenanceSrvc     services.IMaintenanceService
+	importSrvc          services.IImportService
 	httpClient          *http.Client
 }
 
@@ -47,8 +76,28 @@ func NewSettingsHandler(
 	aggregationService services.IAggregationService,
 	languageMappingService services.ILanguageMappingService,
 	projectLabelService services.IProjectLabelService,
+	manualTimeEntryService services.IManualTimeEntryService,
 	keyValueService services.IKeyValueService,
 	mailService services.IMailService,
+	jobService services.IJobService,
+	heartbeatScriptService services.IHeartbeatScriptService,
+	exportService services.IExportService,
+	avatarService services.IAvatarService,
+	jiraService services.IJiraService,
+	codeStatsService services.ICodeStatsService,
+	projectRepoService services.IProjectRepoService,
+	googleCalendarService services.IGoogleCalendarService,
+	projectBudgetService services.IProjectBudgetService,
+	goalService services.IGoalService,
+	dayOffService services.IDayOffService,
+	notificationService services.INotificationService,
+	relayTargetService services.IRelayTargetService,
+	relayRuleService services.IRelayRuleService,
+	quotaService services.IQuotaService,
+	storageQuotaService services.IStorageQuotaService,
+	clockSkewService services.IClockSkewService,
+	maintenanceService services.IMaintenanceService,
+	importService services.IImportService,
 ) *SettingsHandler {
 	return &SettingsHandler{
 		config:              conf.Get(),
@@ -57,19 +106,44 @@ func NewSettingsHandler(
 		aggregationSrvc:     aggregationService,
 		languageMappingSrvc: languageMappingService,
 		projectLabelSrvc:    projectLabelService,
+		manualTimeEntrySrvc: manualTimeEntryService,
 		userSrvc:            userService,
 		heartbeatSrvc:       heartbeatService,
 		keyValueSrvc:        keyValueService,
 		mailSrvc:            mailService,
-		httpClient:          &http.Client{Timeout: 10 * time.Second},
+		jobSrvc:             jobService,
+		scriptSrvc:          heartbeatScriptService,
+		exportSrvc:          exportService,
+		avatarSrvc:          avatarService,
+		jiraSrvc:            jiraService,
+		codeStatsSrvc:       codeStatsService,
+		projectRepoSrvc:     projectRepoService,
+		gcalSrvc:            googleCalendarService,
+		budgetSrvc:          projectBudgetService,
+		goalSrvc:            goalService,
+		dayOffSrvc:          dayOffService,
+		notificationSrvc:    notificationService,
+		relaySrvc:           relayTargetService,
+		relayRuleSrvc:       relayRuleService,
+		quotaSrvc:           quotaService,
+		storageQuotaSrvc:    storageQuotaService,
+		clockSkewSrvc:       clockSkewService,
+		maintenanceSrvc:     maintenanceService,
+		importSrvc:          importService,
+		httpClient:          conf.NewHttpClient(conf.ProxyScopeRelay, 10*time.Second),
 	}
 }
 
 func (h *SettingsHandler) RegisterRoutes(router *mux.Router) {
+	// not authenticated, because the strict session cookie is not sent along with the redirect from google, the state identifies the user instead
+	router.Path("/settings/google_calendar/callback").Methods(http.MethodGet).HandlerFunc(h.GetGoogleCalendarCallback)
+
 	r := router.PathPrefix("/settings").Subrouter()
 	r.Use(
 		middlewares.NewAuthenticateMiddleware(h.userSrvc).WithRedirectTarget(defaultErrorRedirectTarget()).Handler,
 	)
+	r.Path("/google_calendar/connect").Methods(http.MethodGet).HandlerFunc(h.GetConnectGoogleCalendar)
+	r.Path("/maintenance").Methods(http.MethodPost).HandlerFunc(h.PostMaintenance) // separate from other actions, as it must remain reachable in maintenance mode
 	r.Methods(http.MethodGet).HandlerFunc(h.GetIndex)
 	r.Methods(http.MethodPost).HandlerFunc(h.PostIndex)
 }
@@ -81,12 +155,133 @@ func (h *SettingsHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
 	templates[conf.SettingsTemplate].Execute(w, h.buildViewModel(r))
 }
 
+// GetConnectGoogleCalendar redirects to google's consent screen.
+// The state parameter identifies the user and is bound to the browser by a nonce cookie, so that the subsequent callback can't be forged or replayed from elsewhere.
+func (h *SettingsHandler) GetConnectGoogleCalendar(w http.ResponseWriter, r *http.Request) {
+	if !h.config.Integrations.GoogleCalendar.IsEnabled() {
+		h.redirectToIntegrations(w, r, "", "google calendar integration is not available")
+		return
+	}
+
+	user := middlewares.GetPrincipal(r)
+	nonce := uuid.NewV4().String()
+	state, err := h.config.Security.SecureCookie.Encode(googleCalendarStateKey, &googleCalendarState{UserId: user.ID, Nonce: nonce})
+	if err != nil {
+		conf.Log().Request(r).Error("failed to encode google calendar oauth state for user %s - %v", user.ID, err)
+		h.redirectToIntegrations(w, r, "", criticalError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     googleCalendarStateKey,
+		Value:    nonce,
+		Path:     fmt.Sprintf("%s/settings/google_calendar", h.config.Server.BasePath),
+		MaxAge:   600,
+		Secure:   !h.config.Security.InsecureCookies,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode, // sent along with the top-level redirect from google
+	})
+	http.Redirect(w, r, h.gcalSrvc.AuthCodeUrl(state), http.StatusFound)
+}
+
+func (h *SettingsHandler) GetGoogleCalendarCallback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var state googleCalendarState
+	nonceCookie, err := r.Cookie(googleCalendarStateKey)
+	if err != nil || h.config.Security.SecureCookie.Decode(googleCalendarStateKey, q.Get("state"), &state) != nil || state.Nonce != nonceCookie.Value {
+		h.redirectToIntegrations(w, r, "", "invalid request, please try again")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: googleCalendarStateKey, Path: fmt.Sprintf("%s/settings/google_calendar", h.config.Server.BasePath), MaxAge: -1})
+
+	if q.Get("error") != "" || q.Get("code") == "" {
+		h.redirectToIntegrations(w, r, "", "access to google calendar was not granted")
+		return
+	}
+
+	user, err := h.userSrvc.GetUserById(state.UserId)
+	if err != nil {
+		h.redirectToIntegrations(w, r, "", "invalid request, please try again")
+		return
+	}
+
+	if err := h.gcalSrvc.Connect(user, q.Get("code")); err != nil {
+		conf.Log().Request(r).Error("failed to connect google calendar for user %s - %v", user.ID, err)
+		h.redirectToIntegrations(w, r, "", "failed to connect google calendar")
+		return
+	}
+
+	h.redirectToIntegrations(w, r, "Google Calendar connected successfully, coding sessions will be added hourly", "")
+}
+
+// PostMaintenance enables or disables maintenance mode. Other than regular settings actions, it has a path of its own, so that it can be exempt from maintenance mode itself.
+func (h *SettingsHandler) PostMaintenance(w http.ResponseWriter, r *http.Request) {
+	user := middlewares.GetPrincipal(r)
+	if !user.IsAdmin {
+		h.redirectToAdmin(w, r, "", "only admins are allowed to toggle maintenance mode")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.redirectToAdmin(w, r, "", "missing form values")
+		return
+	}
+
+	if r.PostFormValue("enabled") == "true" {
+		if _, err := h.maintenanceSrvc.Enable(strings.TrimSpace(r.PostFormValue("message"))); err != nil {
+			conf.Log().Request(r).Error("failed to enable maintenance mode - %v", err)
+			h.redirectToAdmin(w, r, "", conf.ErrInternalServerError)
+			return
+		}
+		h.redirectToAdmin(w, r, "maintenance mode enabled, write requests are rejected until it is disabled again", "")
+		return
+	}
+
+	if _, err := h.maintenanceSrvc.Disable(); err != nil {
+		conf.Log().Request(r).Error("failed to disable maintenance mode - %v", err)
+		h.redirectToAdmin(w, r, "", conf.ErrInternalServerError)
+		return
+	}
+	h.redirectToAdmin(w, r, "maintenance mode disabled", "")
+}
+
+func (h *SettingsHandler) redirectToAdmin(w http.ResponseWriter, r *http.Request, successMsg, errorMsg string) {
+	query := url.Values{}
+	if successMsg != "" {
+		query.Set("success", successMsg)
+	}
+	if errorMsg != "" {
+		query.Set("error", errorMsg)
+	}
+	http.Redirect(w, r, fmt.Sprintf("%s/settings?%s#admin", h.config.Server.BasePath, query.Encode()), http.StatusFound)
+}
+
+func (h *SettingsHandler) redirectToIntegrations(w http.ResponseWriter, r *http.Request, successMsg, errorMsg string) {
+	query := url.Values{}
+	if successMsg != "" {
+		query.Set("success", successMsg)
+	}
+	if errorMsg != "" {
+		query.Set("error", errorMsg)
+	}
+	http.Redirect(w, r, fmt.Sprintf("%s/settings?%s#integrations", h.config.Server.BasePath, query.Encode()), http.StatusFound)
+}
+
 func (h *SettingsHandler) PostIndex(w http.ResponseWriter, r *http.Request) {
 	if h.config.IsDev() {
 		loadTemplates()
 	}
 
-	if err := r.ParseForm(); err != nil {
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.Body = http.MaxBytesReader(w, r.Body, services.AvatarMaxUploadSize+1024*1024)
+		err = r.ParseMultipartForm(services.AvatarMaxUploadSize)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		templates[conf.SettingsTemplate].Execute(w, h.buildViewModel(r).WithError("missing form values"))
 		return
@@ -129,6 +324,10 @@ func (h *SettingsHandler) dispatchAction(action string) action {
 		return h.actionChangePassword
 	case "update_user":
 		return h.actionUpdateUser
+	case "upload_avatar":
+		return h.actionUploadAvatar
+	case "delete_avatar":
+		return h.actionDeleteAvatar
 	case "reset_apikey":
 		return h.actionResetApiKey
 	case "delete_alias":
@@ -139,18 +338,88 @@ func (h *SettingsHandler) dispatchAction(action string) action {
 		return h.actionAddLabel
 	case "delete_label":
 		return h.actionDeleteLabel
+	case "update_repo":
+		return h.actionUpdateProjectRepo
+	case "update_budget":
+		return h.actionUpdateProjectBudget
+	case "add_goal":
+		return h.actionAddGoal
+	case "delete_goal":
+		return h.actionDeleteGoal
+	case "update_days_off":
+		return h.actionUpdateDaysOff
+	case "update_workday_target":
+		return h.actionUpdateWorkdayTarget
 	case "delete_mapping":
 		return h.actionDeleteLanguageMapping
 	case "add_mapping":
 		return h.actionAddLanguageMapping
+	case "add_manual_entry":
+		return h.actionAddManualTimeEntry
+	case "delete_manual_entry":
+		return h.actionDeleteManualTimeEntry
+	case "update_acceptance":
+		return h.actionUpdateHeartbeatAcceptance
+	case "update_heartbeat_script":
+		return h.actionUpdateHeartbeatScript
+	case "toggle_import_scope":
+		return h.actionToggleImportScope
+	case "update_duration_strategy":
+		return h.actionUpdateDurationStrategy
+	case "update_browsing":
+		return h.actionUpdateBrowsing
+	case "update_clock_skew_correction":
+		return h.actionUpdateClockSkewCorrection
 	case "update_sharing":
 		return h.actionUpdateSharing
+	case "update_comparison":
+		return h.actionUpdateComparison
+	case "update_leaderboard":
+		return h.actionUpdateLeaderboard
 	case "toggle_wakatime":
 		return h.actionSetWakatimeApiKey
+	case "add_relay_target":
+		return h.actionAddRelayTarget
+	case "toggle_relay_target":
+		return h.actionToggleRelayTarget
+	case "delete_relay_target":
+		return h.actionDeleteRelayTarget
+	case "add_relay_rule":
+		return h.actionAddRelayRule
+	case "delete_relay_rule":
+		return h.actionDeleteRelayRule
+	case "update_jira":
+		return h.actionUpdateJira
+	case "preview_jira":
+		return h.actionPreviewJira
+	case "update_google_calendar":
+		return h.actionUpdateGoogleCalendar
+	case "disconnect_google_calendar":
+		return h.actionDisconnectGoogleCalendar
+	case "sync_google_calendar":
+		return h.actionSyncGoogleCalendar
+	case "sync_jira":
+		return h.actionSyncJira
 	case "import_wakatime":
 		return h.actionImportWakatime
+	case "update_codestats":
+		return h.actionUpdateCodeStats
+	case "import_codestats":
+		return h.actionImportCodeStats
+	case "export_data":
+		return h.actionExportData
 	case "regenerate_summaries":
 		return h.actionRegenerateSummaries
+	case "regenerate_summaries_range":
+		return h.actionRegenerateSummariesRange
+	case "update_notifications":
+		return h.actionUpdateNotifications
+	case "send_test_mail":
+		return h.actionSendTestMail
+	case "set_heartbeats_quota":
+		return h.actionSetHeartbeatsQuota
+	case "set_storage_quota":
+		return h.actionSetStorageQuota
 	case "delete_account":
 		return h.actionDeleteUser
 	}
@@ -178,7 +447,7 @@ func (h *SettingsHandler) actionUpdateUser(w http.ResponseWriter, r *http.Reques
 
 	user.Email = payload.Email
 	user.Location = payload.Location
-	user.ReportsWeekly = payload.ReportsWeekly
+	user.Locale = payload.Locale
 
 	if _, err := h.userSrvc.Update(user); err != nil {
 		return http.StatusInternalServerError, "", conf.ErrInternalServerError
@@ -234,6 +503,47 @@ func (h *SettingsHandler) actionChangePassword(w http.ResponseWriter, r *http.Re
 	return http.StatusOK, "password was updated successfully", ""
 }
 
+func (h *SettingsHandler) actionUploadAvatar(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if !h.config.App.AvatarUploads {
+		return http.StatusForbidden, "", "avatar uploads are disabled on this server"
+	}
+
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		return http.StatusBadRequest, "", "missing image file"
+	}
+	defer file.Close()
+
+	user := middlewares.GetPrincipal(r)
+	if _, err := h.avatarSrvc.Upload(user, file); err != nil {
+		if err == services.ErrInvalidAvatar {
+			return http.StatusBadRequest, "", err.Error()
+		}
+		conf.Log().Request(r).Error("failed to upload avatar for user %s - %v", user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "avatar updated successfully", ""
+}
+
+func (h *SettingsHandler) actionDeleteAvatar(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if _, err := h.avatarSrvc.Remove(user); err != nil {
+		conf.Log().Request(r).Error("failed to delete avatar of user %s - %v", user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "avatar removed successfully", ""
+}
+
 func (h *SettingsHandler) actionResetApiKey(w http.ResponseWriter, r *http.Request) (int, string, string) {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -253,20 +563,11 @@ func (h *SettingsHandler) actionUpdateSharing(w http.ResponseWriter, r *http.Req
 		loadTemplates()
 	}
 
-	var err error
 	user := middlewares.GetPrincipal(r)
 
 	defer h.userSrvc.FlushCache()
 
-	user.ShareProjects, err = strconv.ParseBool(r.PostFormValue("share_projects"))
-	user.ShareLanguages, err = strconv.ParseBool(r.PostFormValue("share_languages"))
-	user.ShareEditors, err = strconv.ParseBool(r.PostFormValue("share_editors"))
-	user.ShareOSs, err = strconv.ParseBool(r.PostFormValue("share_oss"))
-	user.ShareMachines, err = strconv.ParseBool(r.PostFormValue("share_machines"))
-	user.ShareLabels, err = strconv.ParseBool(r.PostFormValue("share_labels"))
-	user.ShareDataMaxDays, err = strconv.Atoi(r.PostFormValue("max_days"))
-
-	if err != nil {
+	if err := parseSharingRanges(r.PostForm, user); err != nil {
 		return http.StatusBadRequest, "", "invalid input"
 	}
 
@@ -277,6 +578,34 @@ func (h *SettingsHandler) actionUpdateSharing(w http.ResponseWriter, r *http.Req
 	return http.StatusOK, "settings updated", ""
 }
 
+// parseSharingRanges reads the number of days to share per dimension (-1 = unlimited, 0 = not at all) from the form.
+// The user is only modified if all values are valid.
+func parseSharingRanges(form url.Values, user *models.User) error {
+	targets := map[string]*int{
+		"max_days":        &user.ShareDataMaxDays,
+		"share_projects":  &user.ShareProjectsDays,
+		"share_languages": &user.ShareLanguagesDays,
+		"share_editors":   &user.ShareEditorsDays,
+		"share_oss":       &user.ShareOSsDays,
+		"share_machines":  &user.ShareMachinesDays,
+		"share_labels":    &user.ShareLabelsDays,
+	}
+
+	values := make(map[string]int, len(targets))
+	for key := range targets {
+		days, err := strconv.Atoi(form.Get(key))
+		if err != nil || days < -1 {
+			return fmt.Errorf("invalid value for '%s'", key)
+		}
+		values[key] = days
+	}
+
+	for key, target := range targets {
+		*target = values[key]
+	}
+	return nil
+}
+
 func (h *SettingsHandler) actionDeleteAlias(w http.ResponseWriter, r *http.Request) (int, string, string) {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -374,6 +703,179 @@ func (h *SettingsHandler) actionDeleteLabel(w http.ResponseWriter, r *http.Reque
 	return http.StatusNotFound, "", "label not found"
 }
 
+func (h *SettingsHandler) actionUpdateProjectRepo(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	projectKey := r.PostFormValue("key")
+	repoUrl := strings.TrimSpace(r.PostFormValue("url"))
+
+	if projectKey == "" {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	if _, err := h.projectRepoSrvc.Set(user.ID, projectKey, repoUrl); err != nil {
+		return http.StatusBadRequest, "", fmt.Sprintf("invalid repository - %v", err)
+	}
+
+	if repoUrl == "" {
+		return http.StatusOK, "repository unlinked successfully", ""
+	}
+	return http.StatusOK, "repository linked successfully", ""
+}
+
+func (h *SettingsHandler) actionUpdateProjectBudget(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	projectKey := r.PostFormValue("key")
+	hours, err := strconv.Atoi(r.PostFormValue("hours"))
+
+	if projectKey == "" || err != nil || hours < 0 {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	if _, err := h.budgetSrvc.Set(user.ID, projectKey, hours); err != nil {
+		conf.Log().Request(r).Error("failed to update budget of project '%s' for user '%s' - %v", projectKey, user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	if hours == 0 {
+		return http.StatusOK, "budget removed successfully", ""
+	}
+	return http.StatusOK, "budget updated successfully", ""
+}
+
+func (h *SettingsHandler) actionAddGoal(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	hours, err := strconv.ParseFloat(r.PostFormValue("hours"), 64)
+	if err != nil {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	goal := &models.Goal{
+		UserID:   user.ID,
+		Title:    strings.TrimSpace(r.PostFormValue("title")),
+		Interval: r.PostFormValue("interval"),
+		Minutes:  int(hours * 60),
+	}
+	switch key := strings.TrimSpace(r.PostFormValue("key")); r.PostFormValue("scope") {
+	case "language":
+		goal.Language = key
+	case "editor":
+		goal.Editor = key
+	}
+
+	if !goal.IsValid() {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+	if _, err := h.goalSrvc.Create(goal); err != nil {
+		conf.Log().Request(r).Error("failed to create goal for user '%s' - %v", user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "goal added successfully", ""
+}
+
+func (h *SettingsHandler) actionDeleteGoal(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	id, err := strconv.Atoi(r.PostFormValue("id"))
+	if err != nil {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	if err := h.goalSrvc.Delete(user.ID, uint(id)); err != nil {
+		return http.StatusBadRequest, "", "failed to delete goal"
+	}
+
+	return http.StatusOK, "goal deleted successfully", ""
+}
+
+func (h *SettingsHandler) actionUpdateDaysOff(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	kind := r.PostFormValue("kind")
+	from, err := time.Parse(models.DayOffFormat, r.PostFormValue("from"))
+	if err != nil {
+		return http.StatusBadRequest, "", "invalid date"
+	}
+	to := from
+	if toValue := r.PostFormValue("to"); toValue != "" {
+		if to, err = time.Parse(models.DayOffFormat, toValue); err != nil {
+			return http.StatusBadRequest, "", "invalid date"
+		}
+	}
+	if kind != "" && kind != models.DayOffVacation && kind != models.DayOffSick {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	if err := h.dayOffSrvc.Set(user.ID, from, to, kind); err != nil {
+		return http.StatusBadRequest, "", fmt.Sprintf("failed to update days off - %v", err)
+	}
+
+	if kind == "" {
+		return http.StatusOK, "days off removed successfully", ""
+	}
+	return http.StatusOK, "days off marked successfully", ""
+}
+
+func (h *SettingsHandler) actionUpdateWorkdayTarget(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if err := r.ParseForm(); err != nil {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	hours, err := strconv.ParseFloat(r.PostFormValue("hours"), 64)
+	if err != nil || hours < 0 || hours > 24 {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	var workdays uint8
+	for _, v := range r.PostForm["workdays"] {
+		day, err := strconv.Atoi(v)
+		if err != nil || day < int(time.Sunday) || day > int(time.Saturday) {
+			return http.StatusBadRequest, "", "invalid input"
+		}
+		workdays |= 1 << day
+	}
+
+	since := r.PostFormValue("since")
+	if since != "" {
+		if _, err := time.Parse(models.DayOffFormat, since); err != nil {
+			return http.StatusBadRequest, "", "invalid date"
+		}
+	}
+
+	user.WorkdayTargetMin = int(hours * 60)
+	user.Workdays = workdays
+	user.WorkTargetSince = since
+
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "workday target updated successfully", ""
+}
+
 func (h *SettingsHandler) actionDeleteLanguageMapping(w http.ResponseWriter, r *http.Request) (int, string, string) {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -424,119 +926,681 @@ func (h *SettingsHandler) actionAddLanguageMapping(w http.ResponseWriter, r *htt
 	return http.StatusOK, "mapping added successfully", ""
 }
 
-func (h *SettingsHandler) actionSetWakatimeApiKey(w http.ResponseWriter, r *http.Request) (int, string, string) {
+func (h *SettingsHandler) actionAddManualTimeEntry(w http.ResponseWriter, r *http.Request) (int, string, string) {
 	if h.config.IsDev() {
 		loadTemplates()
 	}
-
 	user := middlewares.GetPrincipal(r)
-	apiKey := r.PostFormValue("api_key")
-	apiUrl := r.PostFormValue("api_url")
-	if apiUrl == conf.WakatimeApiUrl || apiKey == "" {
-		apiUrl = ""
+
+	date, err := utils.ParseDateTimeTZ(r.PostFormValue("date"), user.TZ())
+	if err != nil {
+		return http.StatusBadRequest, "", "invalid date"
+	}
+	minutes, err := strconv.Atoi(r.PostFormValue("minutes"))
+	if err != nil {
+		return http.StatusBadRequest, "", "invalid duration"
 	}
 
-	// Healthcheck, if a new API key is set, i.e. the feature is activated
-	if (user.WakatimeApiKey == "" && apiKey != "") && !h.validateWakatimeKey(apiKey, apiUrl) {
-		return http.StatusBadRequest, "", "failed to connect to WakaTime, API key invalid?"
+	entry := &models.ManualTimeEntry{
+		UserID:   user.ID,
+		Project:  strings.TrimSpace(r.PostFormValue("project")),
+		Date:     models.CustomTime(date),
+		Duration: time.Duration(minutes) * time.Minute,
+		Note:     r.PostFormValue("note"),
+	}
+	if !entry.IsValid() {
+		return http.StatusBadRequest, "", "invalid manual time entry"
 	}
 
-	if _, err := h.userSrvc.SetWakatimeApiCredentials(user, apiKey, apiUrl); err != nil {
-		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	if _, err := h.manualTimeEntrySrvc.Create(entry); err != nil {
+		return http.StatusInternalServerError, "", "could not add manual time entry"
 	}
 
-	return http.StatusOK, "Wakatime API Key updated successfully", ""
+	return http.StatusOK, "manual time entry added successfully", ""
 }
 
-func (h *SettingsHandler) actionImportWakatime(w http.ResponseWriter, r *http.Request) (int, string, string) {
+func (h *SettingsHandler) actionDeleteManualTimeEntry(w http.ResponseWriter, r *http.Request) (int, string, string) {
 	if h.config.IsDev() {
 		loadTemplates()
 	}
 
 	user := middlewares.GetPrincipal(r)
-	if user.WakatimeApiKey == "" {
-		return http.StatusForbidden, "", "not connected to wakatime"
+	id, err := strconv.Atoi(r.PostFormValue("entry_id"))
+	if err != nil {
+		return http.StatusBadRequest, "", "invalid manual time entry id"
 	}
 
-	kvKey := fmt.Sprintf("%s_%s", conf.KeyLastImportImport, user.ID)
+	entry, err := h.manualTimeEntrySrvc.GetById(uint(id))
+	if err != nil || entry == nil {
+		return http.StatusNotFound, "", "manual time entry not found"
+	} else if entry.UserID != user.ID {
+		return http.StatusForbidden, "", "not allowed to delete manual time entry"
+	}
 
-	if !h.config.IsDev() {
-		lastImportKv := h.keyValueSrvc.MustGetString(kvKey)
-		lastImport, _ := time.Parse(time.RFC822, lastImportKv.Value)
-		if time.Now().Sub(lastImport) < time.Duration(h.config.App.ImportBackoffMin)*time.Minute {
-			return http.StatusTooManyRequests,
-				"",
-				fmt.Sprintf("Too many data imports. You are only allowed to request an import every %d minutes.", h.config.App.ImportBackoffMin)
-		}
+	if err := h.manualTimeEntrySrvc.Delete(entry); err != nil {
+		return http.StatusInternalServerError, "", "could not delete manual time entry"
 	}
 
-	go func(user *models.User) {
-		start := time.Now()
-		importer := imports.NewWakatimeHeartbeatImporter(user.WakatimeApiKey)
+	return http.StatusOK, "manual time entry deleted successfully", ""
+}
 
-		countBefore, err := h.heartbeatSrvc.CountByUser(user)
-		if err != nil {
-			println(err)
-		}
+func (h *SettingsHandler) actionUpdateHeartbeatAcceptance(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
 
-		var stream <-chan *models.Heartbeat
-		if latest, err := h.heartbeatSrvc.GetLatestByOriginAndUser(imports.OriginWakatime, user); latest == nil || err != nil {
-			stream = importer.ImportAll(user)
-		} else {
-			// if an import has happened before, only import heartbeats newer than the latest of the last import
-			stream = importer.Import(user, latest.Time.T(), time.Now())
-		}
+	var err error
+	user := middlewares.GetPrincipal(r)
 
-		count := 0
-		batch := make([]*models.Heartbeat, 0)
+	user.HeartbeatsMaxPastDays, err = strconv.Atoi(r.PostFormValue("max_past_days"))
+	if err != nil || user.HeartbeatsMaxPastDays < 0 {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+	user.HeartbeatsMaxFutureMin, err = strconv.Atoi(r.PostFormValue("max_future_min"))
+	if err != nil || user.HeartbeatsMaxFutureMin < 0 {
+		return http.StatusBadRequest, "", "invalid input"
+	}
 
-		insert := func(batch []*models.Heartbeat) {
-			if err := h.heartbeatSrvc.InsertBatch(batch); err != nil {
-				logbuch.Warn("failed to insert imported heartbeat, already existing? - %v", err)
-			}
-		}
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
 
-		for hb := range stream {
-			count++
-			batch = append(batch, hb)
+	return http.StatusOK, "settings updated successfully", ""
+}
 
-			if len(batch) == h.config.App.ImportBatchSize {
-				insert(batch)
-				batch = make([]*models.Heartbeat, 0)
-			}
-		}
+func (h *SettingsHandler) actionUpdateHeartbeatScript(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	if !h.config.App.UserHeartbeatScripts {
+		return http.StatusForbidden, "", "heartbeat scripts are disabled on this server"
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.IsAdmin {
+		// scripts are trusted code, as their memory usage can't be limited
+		return http.StatusForbidden, "", "only admins may set heartbeat scripts"
+	}
 
-		if len(batch) > 0 {
-			insert(batch)
+	script := strings.TrimSpace(r.PostFormValue("heartbeat_script"))
+	if script != "" {
+		if err := h.scriptSrvc.Validate(script); err != nil {
+			return http.StatusBadRequest, "", fmt.Sprintf("invalid script: %v", err)
 		}
+	}
 
-		countAfter, _ := h.heartbeatSrvc.CountByUser(user)
-		logbuch.Info("downloaded %d heartbeats for user '%s' (%d actually imported)", count, user.ID, countAfter-countBefore)
+	user.HeartbeatScript = script
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
 
-		h.regenerateSummaries(user)
+	return http.StatusOK, "heartbeat script updated successfully", ""
+}
 
-		if !user.HasData {
-			user.HasData = true
-			if _, err := h.userSrvc.Update(user); err != nil {
-				conf.Log().Request(r).Error("failed to set 'has_data' flag for user %s - %v", user.ID, err)
-			}
+func (h *SettingsHandler) actionExportData(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if _, err := h.exportSrvc.ExportHeartbeats(user); err != nil {
+		if err == services.ErrExportInProgress {
+			return http.StatusConflict, "", "an export is already in progress, please check back later"
 		}
+		conf.Log().Request(r).Error("failed to start data export for user %s - %v", user.ID, err)
+		return http.StatusInternalServerError, "", "failed to export data"
+	}
 
-		if user.Email != "" {
-			if err := h.mailSrvc.SendImportNotification(user, time.Now().Sub(start), int(countAfter-countBefore)); err != nil {
-				conf.Log().Request(r).Error("failed to send import notification mail to %s - %v", user.ID, err)
-			} else {
-				logbuch.Info("sent import notification mail to %s", user.ID)
-			}
+	return http.StatusAccepted, "Export started. Depending on the amount of data, this may take a while. A download link will appear here once finished.", ""
+}
+
+func (h *SettingsHandler) actionToggleImportScope(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if user.HasImportScope(time.Now()) {
+		user.ImportScopeUntil = nil
+	} else if !user.IsAdmin {
+		// lifting the server-wide limit is up to admins, see ImportScopeApiHandler
+		return http.StatusForbidden, "", "backfilling has to be enabled by an admin"
+	} else {
+		until := models.CustomTime(time.Now().Add(24 * time.Hour))
+		user.ImportScopeUntil = &until
+	}
+
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	if user.ImportScopeUntil == nil {
+		return http.StatusOK, "backfilling disabled", ""
+	}
+	return http.StatusOK, "backfilling enabled for the next 24 hours", ""
+}
+
+func (h *SettingsHandler) actionUpdateDurationStrategy(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	strategy := r.PostFormValue("duration_strategy")
+	if strategy != models.DurationStrategyDefault && strategy != models.DurationStrategyMerge && strategy != models.DurationStrategySum {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+	if strategy == user.DurationStrategy {
+		return http.StatusOK, "settings updated successfully", ""
+	}
+
+	user.DurationStrategy = strategy
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	// previously computed summaries are based on the old strategy
+	go func(user *models.User) {
+		if err := h.regenerateSummaries(user); err != nil {
+			conf.Log().Request(r).Error("failed to regenerate summaries for user '%s' - %v", user.ID, err)
+		}
+	}(user)
+
+	return http.StatusAccepted, "settings updated successfully, summaries are being regenerated - this may take a up to a couple of minutes", ""
+}
+
+func (h *SettingsHandler) actionUpdateClockSkewCorrection(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	user.ClockSkewCorrection = r.PostFormValue("clock_skew_correction") == "true"
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "settings updated successfully", ""
+}
+
+func (h *SettingsHandler) actionUpdateComparison(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	user.AllowComparison = r.PostFormValue("allow_comparison") == "true"
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "settings updated successfully", ""
+}
+
+func (h *SettingsHandler) actionUpdateLeaderboard(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	user.PublicLeaderboard = r.PostFormValue("public_leaderboard") == "true"
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "settings updated successfully", ""
+}
+
+func (h *SettingsHandler) actionUpdateBrowsing(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	exclude := r.PostFormValue("exclude_browsing") == "true"
+	if exclude == user.ExcludeBrowsing {
+		return http.StatusOK, "settings updated successfully", ""
+	}
+
+	user.ExcludeBrowsing = exclude
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	// previously computed summaries include or exclude browsing time
+	go func(user *models.User) {
+		if err := h.regenerateSummaries(user); err != nil {
+			conf.Log().Request(r).Error("failed to regenerate summaries for user '%s' - %v", user.ID, err)
+		}
+	}(user)
+
+	return http.StatusAccepted, "settings updated successfully, summaries are being regenerated - this may take a up to a couple of minutes", ""
+}
+
+func (h *SettingsHandler) actionSetWakatimeApiKey(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	apiKey := r.PostFormValue("api_key")
+	apiUrl := r.PostFormValue("api_url")
+	if apiUrl == conf.WakatimeApiUrl || apiKey == "" {
+		apiUrl = ""
+	}
+
+	// Healthcheck, if a new API key is set, i.e. the feature is activated
+	if (user.WakatimeApiKey == "" && apiKey != "") && !h.validateWakatimeKey(apiKey, apiUrl) {
+		return http.StatusBadRequest, "", "failed to connect to WakaTime, API key invalid?"
+	}
+
+	if _, err := h.userSrvc.SetWakatimeApiCredentials(user, apiKey, apiUrl); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "Wakatime API Key updated successfully", ""
+}
+
+func (h *SettingsHandler) actionAddRelayTarget(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	target := &models.RelayTarget{
+		UserID: user.ID,
+		Name:   strings.TrimSpace(r.PostFormValue("name")),
+		ApiUrl: strings.TrimSuffix(strings.TrimSpace(r.PostFormValue("api_url")), "/"),
+		ApiKey: r.PostFormValue("api_key"),
+	}
+	if !target.IsValid() {
+		return http.StatusBadRequest, "", "invalid relay target, name must not be 'wakatime'"
+	}
+
+	if !h.validateWakatimeKey(target.ApiKey, target.ApiUrl) {
+		return http.StatusBadRequest, "", "failed to connect to relay target, API key invalid?"
+	}
+
+	if _, err := h.relaySrvc.Create(target); err != nil {
+		conf.Log().Request(r).Error("failed to create relay target for user '%s' - %v", user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "relay target added successfully", ""
+}
+
+func (h *SettingsHandler) actionToggleRelayTarget(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	id, err := strconv.Atoi(r.PostFormValue("id"))
+	if err != nil {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	if _, err := h.relaySrvc.SetEnabled(user, uint(id), r.PostFormValue("enabled") == "true"); err != nil {
+		return http.StatusBadRequest, "", "failed to update relay target"
+	}
+
+	return http.StatusOK, "relay target updated successfully", ""
+}
+
+func (h *SettingsHandler) actionDeleteRelayTarget(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	id, err := strconv.Atoi(r.PostFormValue("id"))
+	if err != nil {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	if err := h.relaySrvc.Delete(user, uint(id)); err != nil {
+		return http.StatusBadRequest, "", "failed to delete relay target"
+	}
+
+	return http.StatusOK, "relay target deleted successfully", ""
+}
+
+func (h *SettingsHandler) actionAddRelayRule(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	rule := &models.RelayRule{
+		UserID: user.ID,
+		Type:   r.PostFormValue("type"),
+		Value:  strings.TrimSpace(r.PostFormValue("value")),
+	}
+	if !rule.IsValid() {
+		return http.StatusBadRequest, "", "invalid relay rule, work hours must be given like 09:00-17:00"
+	}
+
+	if _, err := h.relayRuleSrvc.Create(rule); err != nil {
+		conf.Log().Request(r).Error("failed to create relay rule for user '%s' - %v", user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "relay rule added successfully", ""
+}
+
+func (h *SettingsHandler) actionDeleteRelayRule(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	id, err := strconv.Atoi(r.PostFormValue("id"))
+	if err != nil {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	if err := h.relayRuleSrvc.Delete(user.ID, uint(id)); err != nil {
+		return http.StatusBadRequest, "", "failed to delete relay rule"
+	}
+
+	return http.StatusOK, "relay rule deleted successfully", ""
+}
+
+func (h *SettingsHandler) actionUpdateJira(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	siteUrl := strings.TrimSuffix(strings.TrimSpace(r.PostFormValue("jira_url")), "/")
+	email := strings.TrimSpace(r.PostFormValue("jira_email"))
+	apiToken := r.PostFormValue("jira_api_token")
+
+	// disconnect
+	if siteUrl == "" || user.HasJiraCredentials() {
+		siteUrl, email, apiToken = "", "", ""
+	} else {
+		if u, err := url.Parse(siteUrl); err != nil || u.Scheme != "https" || u.Host == "" {
+			return http.StatusBadRequest, "", "invalid jira url, must start with https://"
+		}
+		if email == "" || apiToken == "" {
+			return http.StatusBadRequest, "", "e-mail address and api token are required"
+		}
+		if err := h.jiraSrvc.ValidateCredentials(siteUrl, email, apiToken); err != nil {
+			return http.StatusBadRequest, "", "failed to connect to jira, credentials invalid?"
+		}
+	}
+
+	user.JiraUrl, user.JiraEmail, user.JiraApiToken = siteUrl, email, apiToken
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	if !user.HasJiraCredentials() {
+		return http.StatusOK, "Jira disconnected successfully", ""
+	}
+	return http.StatusOK, "Jira connected successfully, worklogs will be pushed daily", ""
+}
+
+func (h *SettingsHandler) actionPreviewJira(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	preview, err := h.jiraSrvc.Sync(user, true)
+	if err != nil {
+		if err == services.ErrJiraNotConfigured {
+			return http.StatusBadRequest, "", err.Error()
+		}
+		conf.Log().Request(r).Error("failed to preview jira worklogs for user %s - %v", user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	vm := h.buildViewModel(r)
+	vm.JiraPreview = preview
+	templates[conf.SettingsTemplate].Execute(w, vm)
+	return -1, "", ""
+}
+
+func (h *SettingsHandler) actionSyncJira(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.HasJiraCredentials() {
+		return http.StatusBadRequest, "", services.ErrJiraNotConfigured.Error()
+	}
+
+	go func(user *models.User) {
+		if err := h.jiraSrvc.Run(user); err != nil {
+			conf.Log().Error("failed to sync jira worklogs for user %s - %v", user.ID, err)
+		}
+	}(user)
+
+	return http.StatusAccepted, "Worklogs are being pushed to Jira, check the sync log below in a few moments", ""
+}
+
+func (h *SettingsHandler) actionUpdateGoogleCalendar(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.HasGoogleCalendar() {
+		return http.StatusBadRequest, "", services.ErrGoogleCalendarNotConnected.Error()
+	}
+
+	titles := r.PostFormValue("gcal_event_titles")
+	if titles != models.CalendarTitlesGeneric && titles != models.CalendarTitlesProjects {
+		return http.StatusBadRequest, "", "invalid input"
+	}
+
+	user.GcalEnabled = r.PostFormValue("gcal_enabled") == "true"
+	user.GcalEventTitles = titles
+	user.GcalCalendarId = strings.TrimSpace(r.PostFormValue("gcal_calendar_id"))
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "Google Calendar settings updated successfully", ""
+}
+
+func (h *SettingsHandler) actionDisconnectGoogleCalendar(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if err := h.gcalSrvc.Disconnect(user); err != nil {
+		conf.Log().Request(r).Error("failed to disconnect google calendar for user %s - %v", user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "Google Calendar disconnected successfully, previously added events were kept", ""
+}
+
+func (h *SettingsHandler) actionSyncGoogleCalendar(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.HasGoogleCalendar() {
+		return http.StatusBadRequest, "", services.ErrGoogleCalendarNotConnected.Error()
+	}
+
+	go func(user *models.User) {
+		if err := h.gcalSrvc.Run(user); err != nil {
+			conf.Log().Error("failed to sync google calendar for user %s - %v", user.ID, err)
 		}
 	}(user)
 
+	return http.StatusAccepted, "Coding sessions are being added to your calendar", ""
+}
+
+func (h *SettingsHandler) actionImportWakatime(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if user.WakatimeApiKey == "" {
+		return http.StatusForbidden, "", "not connected to wakatime"
+	}
+
+	if status, errorMsg := h.checkImportBackoff(user); errorMsg != "" {
+		return status, "", errorMsg
+	}
+
+	// if an import has happened before, only import heartbeats newer than the latest of the last import
+	from := time.Time{}
+	if latest, err := h.heartbeatSrvc.GetLatestByOriginAndUser(imports.OriginWakatime, user); latest != nil && err == nil {
+		from = latest.Time.T()
+	}
+
+	importer := imports.NewWakatimeHeartbeatImporter(user.WakatimeApiKey)
+	go h.importHeartbeats(user, models.JobImportWakatime, "WakaTime", imports.OriginWakatime, importer, from, time.Now())
+
+	return http.StatusAccepted, "Import started. This will take several minutes. Please check back later.", ""
+}
+
+func (h *SettingsHandler) actionUpdateCodeStats(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	username := strings.TrimSpace(r.PostFormValue("codestats_username"))
+	apiToken := strings.TrimSpace(r.PostFormValue("codestats_api_token"))
+
+	// disconnect
+	if username == "" || user.HasCodeStats() {
+		username, apiToken = "", ""
+	}
+
+	user.CodeStatsUsername, user.CodeStatsApiToken = username, apiToken
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	if !user.HasCodeStats() {
+		return http.StatusOK, "Code::Stats disconnected successfully", ""
+	}
+	if user.HasCodeStatsExport() {
+		if err := h.codeStatsSrvc.StartExport(user); err != nil {
+			conf.Log().Request(r).Error("failed to start code::stats export for user %s - %v", user.ID, err)
+			return http.StatusInternalServerError, "", conf.ErrInternalServerError
+		}
+		return http.StatusOK, "Code::Stats connected successfully, your xp will be pushed daily from tomorrow on", ""
+	}
+	return http.StatusOK, "Code::Stats connected successfully", ""
+}
+
+func (h *SettingsHandler) actionImportCodeStats(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.HasCodeStats() {
+		return http.StatusForbidden, "", "not connected to code::stats"
+	}
+
+	if status, errorMsg := h.checkImportBackoff(user); errorMsg != "" {
+		return status, "", errorMsg
+	}
+
+	// only full days are imported, as code::stats' xp of a day are only known as a whole, and none of the days exported to code::stats
+	to := utils.StartOfDay(time.Now().In(user.TZ()))
+	if export, err := h.codeStatsSrvc.GetExport(user); err == nil && export != nil {
+		if since, err := time.ParseInLocation(conf.SimpleDateFormat, export.Since, user.TZ()); err == nil && since.Before(to) {
+			to = since
+		}
+	}
+
+	// days imported before are skipped
+	from := time.Time{}
+	if latest, err := h.heartbeatSrvc.GetLatestByOriginAndUser(imports.OriginCodeStats, user); latest != nil && err == nil {
+		from = utils.StartOfDay(latest.Time.T().In(user.TZ())).AddDate(0, 0, 1)
+	}
+
+	importer := imports.NewCodeStatsHeartbeatImporter(user.CodeStatsUsername)
+	go h.importHeartbeats(user, models.JobImportCodeStats, "Code::Stats", imports.OriginCodeStats, importer, from, to)
+
+	return http.StatusAccepted, "Import started. This will take a few minutes. Please check back later.", ""
+}
+
+// checkImportBackoff limits data imports to one every few minutes, regardless of the source
+func (h *SettingsHandler) checkImportBackoff(user *models.User) (int, string) {
+	kvKey := fmt.Sprintf("%s_%s", conf.KeyLastImportImport, user.ID)
+
+	if !h.config.IsDev() {
+		lastImportKv := h.keyValueSrvc.MustGetString(kvKey)
+		lastImport, _ := time.Parse(time.RFC822, lastImportKv.Value)
+		if time.Now().Sub(lastImport) < time.Duration(h.config.App.ImportBackoffMin)*time.Minute {
+			return http.StatusTooManyRequests,
+				fmt.Sprintf("Too many data imports. You are only allowed to request an import every %d minutes.", h.config.App.ImportBackoffMin)
+		}
+	}
+
 	h.keyValueSrvc.PutString(&models.KeyStringValue{
 		Key:   kvKey,
 		Value: time.Now().Format(time.RFC822),
 	})
+	return 0, ""
+}
 
-	return http.StatusAccepted, "Import started. This will take several minutes. Please check back later.", ""
+// importHeartbeats runs an import as a tracked job, which reports its progress and is resumed from its checkpoint, if interrupted before, then regenerates the user's summaries and notifies the user once done
+func (h *SettingsHandler) importHeartbeats(user *models.User, jobKind, source, origin string, importer imports.HeartbeatImporter, from, to time.Time) {
+	var importErr error
+	done := h.jobSrvc.Start(jobKind, user.ID)
+	defer func() { done(importErr) }()
+
+	start := time.Now()
+
+	countBefore, err := h.heartbeatSrvc.CountByUser(user)
+	if err != nil {
+		conf.Log().Error("failed to count heartbeats for user %s - %v", user.ID, err)
+	}
+
+	job, err := h.importSrvc.Run(user, origin, importer, from, to)
+	if err != nil {
+		conf.Log().Error("failed to import %s data for user %s - %v", source, user.ID, err)
+		importErr = err
+		return
+	} else if job.Errors > 0 {
+		importErr = errors.New(fmt.Sprintf("%d days failed to be imported, last error: %s", job.Errors, job.Error))
+	}
+
+	countAfter, _ := h.heartbeatSrvc.CountByUser(user)
+	logbuch.Info("downloaded %d heartbeats for user '%s' (%d actually imported, %d rejected)", job.Rows, user.ID, countAfter-countBefore, job.Rejected)
+
+	h.regenerateSummaries(user)
+
+	if !user.HasData {
+		user.HasData = true
+		if _, err := h.userSrvc.Update(user); err != nil {
+			conf.Log().Error("failed to set 'has_data' flag for user %s - %v", user.ID, err)
+		}
+	}
+
+	if h.notificationSrvc.IsEnabled(user, models.NotificationEventImportFinished, models.NotificationChannelEmail) {
+		if err := h.mailSrvc.SendImportNotification(user, time.Now().Sub(start), int(countAfter-countBefore)); err != nil {
+			conf.Log().Error("failed to send import notification mail to %s - %v", user.ID, err)
+		} else {
+			logbuch.Info("sent import notification mail to %s", user.ID)
+		}
+	}
+
+	h.notificationSrvc.Notify(user, &models.Notification{
+		Event: models.NotificationEventImportFinished,
+		Title: "Data import finished",
+		Text:  fmt.Sprintf("The import of your %s data has finished after %.0f seconds (%d new heartbeats imported).", source, time.Now().Sub(start).Seconds(), countAfter-countBefore),
+		Link:  h.config.Server.GetPublicUrl() + "/summary",
+	})
 }
 
 func (h *SettingsHandler) actionRegenerateSummaries(w http.ResponseWriter, r *http.Request) (int, string, string) {
@@ -553,6 +1617,173 @@ func (h *SettingsHandler) actionRegenerateSummaries(w http.ResponseWriter, r *ht
 	return http.StatusAccepted, "summaries are being regenerated - this may take a up to a couple of minutes, please come back later", ""
 }
 
+func (h *SettingsHandler) actionRegenerateSummariesRange(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+
+	from, err1 := utils.ParseDateTimeTZ(r.PostFormValue("from"), user.TZ())
+	to, err2 := utils.ParseDateTimeTZ(r.PostFormValue("to"), user.TZ())
+	if err1 != nil || err2 != nil || to.Before(from) {
+		return http.StatusBadRequest, "", "invalid date range"
+	}
+
+	// end date is inclusive in the ui
+	if _, err := h.aggregationSrvc.Regenerate(user, from, to.AddDate(0, 0, 1)); err == services.ErrInvalidRegenerationRange {
+		return http.StatusBadRequest, "", err.Error()
+	} else if err == services.ErrAggregationInProgress {
+		return http.StatusConflict, "", "summaries are already being regenerated, please wait for it to finish"
+	} else if err != nil {
+		return http.StatusInternalServerError, "", fmt.Sprintf("failed to regenerate summaries - %v", err)
+	}
+
+	return http.StatusAccepted, "summaries are being regenerated in the background, reload this page to see the progress", ""
+}
+
+func (h *SettingsHandler) actionUpdateNotifications(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if err := r.ParseForm(); err != nil {
+		return http.StatusBadRequest, "", "missing parameters"
+	}
+
+	user.NotificationWebhookUrl = strings.TrimSpace(r.PostFormValue("notification_webhook_url"))
+	user.SlackWebhookUrl = strings.TrimSpace(r.PostFormValue("slack_webhook_url"))
+	if _, ok := r.PostForm["telegram_chat_id"]; ok { // only shown if telegram is available
+		user.TelegramChatId = strings.TrimSpace(r.PostFormValue("telegram_chat_id"))
+	}
+	for _, u := range []string{user.NotificationWebhookUrl, user.SlackWebhookUrl} {
+		if u != "" && !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+			return http.StatusBadRequest, "", "invalid webhook url"
+		}
+	}
+
+	inactivityDays, err := strconv.Atoi(r.PostFormValue("inactivity_alert_days"))
+	if err != nil || inactivityDays < 0 {
+		return http.StatusBadRequest, "", "invalid number of days"
+	}
+	user.InactivityAlertDays = inactivityDays
+
+	// checkboxes named like '<event>:<channel>', unchecked ones are not sent at all
+	prefs := make([]*models.NotificationPreference, 0)
+	for _, event := range models.NotificationEvents() {
+		for _, channel := range models.NotificationChannels() {
+			prefs = append(prefs, &models.NotificationPreference{
+				Event:   event,
+				Channel: channel,
+				Enabled: r.PostFormValue(event+":"+channel) == "true",
+			})
+		}
+	}
+
+	updatedPrefs, err := h.notificationSrvc.UpdatePreferences(user, prefs)
+	if err != nil {
+		conf.Log().Request(r).Error("failed to update notification preferences for user '%s' - %v", user.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	// reports are only scheduled for users, who want to receive them via any channel
+	user.ReportsWeekly = updatedPrefs.AnyEnabled(models.NotificationEventReport)
+	if _, err := h.userSrvc.Update(user); err != nil {
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, "notification preferences updated successfully", ""
+}
+
+func (h *SettingsHandler) actionSendTestMail(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.IsAdmin {
+		return http.StatusForbidden, "", "only admins are allowed to send test mails"
+	}
+	if !h.config.Mail.Enabled {
+		return http.StatusBadRequest, "", "mail sending is disabled on this instance"
+	}
+	if user.Email == "" {
+		return http.StatusBadRequest, "", "you need to set an e-mail address in your account settings first"
+	}
+
+	if err := h.mailSrvc.SendTestMail(user); err != nil {
+		conf.Log().Request(r).Error("failed to send test mail via %s - %v", h.config.Mail.Provider, err)
+		return http.StatusInternalServerError, "", fmt.Sprintf("failed to send test mail via %s - %v", h.config.Mail.Provider, err)
+	}
+
+	return http.StatusOK, fmt.Sprintf("test mail sent to %s", user.Email), ""
+}
+
+func (h *SettingsHandler) actionSetHeartbeatsQuota(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.IsAdmin {
+		return http.StatusForbidden, "", "only admins are allowed to set quotas"
+	}
+
+	quota, err := strconv.Atoi(r.PostFormValue("quota"))
+	if err != nil || quota < -1 {
+		return http.StatusBadRequest, "", "invalid quota, must be a number of heartbeats per hour, 0 for the default or -1 for unlimited"
+	}
+
+	targetUser, err := h.userSrvc.GetUserById(strings.TrimSpace(r.PostFormValue("user")))
+	if err != nil {
+		return http.StatusNotFound, "", "user not found"
+	}
+
+	targetUser.HeartbeatsQuota = quota
+	if _, err := h.userSrvc.Update(targetUser); err != nil {
+		conf.Log().Request(r).Error("failed to update quota of user '%s' - %v", targetUser.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, fmt.Sprintf("quota of user '%s' updated successfully", targetUser.ID), ""
+}
+
+func (h *SettingsHandler) actionSetStorageQuota(w http.ResponseWriter, r *http.Request) (int, string, string) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if !user.IsAdmin {
+		return http.StatusForbidden, "", "only admins are allowed to set quotas"
+	}
+
+	quota, err := strconv.ParseInt(r.PostFormValue("quota"), 10, 64)
+	if err != nil || quota < -1 {
+		return http.StatusBadRequest, "", "invalid quota, must be a number of heartbeats, 0 for the default or -1 for unlimited"
+	}
+
+	policy := r.PostFormValue("policy")
+	if policy != "" && policy != conf.StorageQuotaPolicyReject && policy != conf.StorageQuotaPolicyPrune {
+		return http.StatusBadRequest, "", "invalid policy"
+	}
+
+	targetUser, err := h.userSrvc.GetUserById(strings.TrimSpace(r.PostFormValue("user")))
+	if err != nil {
+		return http.StatusNotFound, "", "user not found"
+	}
+
+	targetUser.StorageQuota = quota
+	targetUser.StorageQuotaPolicy = policy
+	if _, err := h.userSrvc.Update(targetUser); err != nil {
+		conf.Log().Request(r).Error("failed to update storage quota of user '%s' - %v", targetUser.ID, err)
+		return http.StatusInternalServerError, "", conf.ErrInternalServerError
+	}
+
+	return http.StatusOK, fmt.Sprintf("storage quota of user '%s' updated successfully", targetUser.ID), ""
+}
+
 func (h *SettingsHandler) actionDeleteUser(w http.ResponseWriter, r *http.Request) (int, string, string) {
 	if h.config.IsDev() {
 		loadTemplates()
@@ -626,6 +1857,9 @@ func (h *SettingsHandler) buildViewModel(r *http.Request) *view.SettingsViewMode
 	// mappings
 	mappings, _ := h.languageMappingSrvc.GetByUser(user.ID)
 
+	// manual time entries
+	manualEntries, _ := h.manualTimeEntrySrvc.GetByUser(user.ID)
+
 	// aliases
 	aliases, err := h.aliasSrvc.GetByUser(user.ID)
 	if err != nil {
@@ -684,14 +1918,117 @@ func (h *SettingsHandler) buildViewModel(r *http.Request) *view.SettingsViewMode
 		return &view.SettingsViewModel{Error: criticalError}
 	}
 
+	// repositories
+	projectRepos, err := h.projectRepoSrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching project repositories - %v", err)
+		return &view.SettingsViewModel{Error: criticalError}
+	}
+
+	// budgets
+	budgets, err := h.budgetSrvc.GetStatuses(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching project budgets - %v", err)
+		return &view.SettingsViewModel{Error: criticalError}
+	}
+
+	// goals
+	goals, err := h.goalSrvc.GetProgresses(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching goals - %v", err)
+		return &view.SettingsViewModel{Error: criticalError}
+	}
+
+	// days off, consecutive ones of the same kind combined
+	daysOff, err := h.dayOffSrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching days off - %v", err)
+		return &view.SettingsViewModel{Error: criticalError}
+	}
+
+	combinedDaysOff := make([]*view.SettingsVMDaysOff, 0)
+	for _, d := range daysOff {
+		if n := len(combinedDaysOff); n > 0 {
+			last := combinedDaysOff[n-1]
+			lastDay, _ := time.Parse(models.DayOffFormat, last.To)
+			if last.Kind == d.Kind && lastDay.AddDate(0, 0, 1).Format(models.DayOffFormat) == d.Day {
+				last.To = d.Day
+				continue
+			}
+		}
+		combinedDaysOff = append(combinedDaysOff, &view.SettingsVMDaysOff{From: d.Day, To: d.Day, Kind: d.Kind})
+	}
+
+	// jira sync log
+	var jiraLog []*models.JiraWorklog
+	if user.HasJiraCredentials() {
+		jiraLog, err = h.jiraSrvc.GetLog(user)
+		if err != nil {
+			conf.Log().Request(r).Error("error while fetching jira sync log - %v", err)
+		}
+	}
+
+	notifications, err := h.notificationSrvc.GetPreferences(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching notification preferences - %v", err)
+		return &view.SettingsViewModel{Error: criticalError}
+	}
+
+	relayTargets, err := h.relaySrvc.GetWithStats(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching relay targets - %v", err)
+		return &view.SettingsViewModel{Error: criticalError}
+	}
+
+	relayRules, err := h.relayRuleSrvc.GetByUser(user.ID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching relay rules - %v", err)
+		return &view.SettingsViewModel{Error: criticalError}
+	}
+
+	// background jobs and quota violations, only visible to admins
+	var jobs []*models.JobStatus
+	var quotaViolations []*models.QuotaViolation
+	if user.IsAdmin {
+		jobs = h.jobSrvc.GetAll()
+		quotaViolations = h.quotaSrvc.GetViolations()
+	}
+
+	storageUsage, err := h.storageQuotaSrvc.GetUsage(user)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching storage usage - %v", err)
+	}
+
 	return &view.SettingsViewModel{
-		User:             user,
-		LanguageMappings: mappings,
-		Aliases:          combinedAliases,
-		Labels:           combinedLabels,
-		Projects:         projects,
-		ApiKey:           user.ApiKey,
-		Success:          r.URL.Query().Get("success"),
-		Error:            r.URL.Query().Get("error"),
+		User:                     user,
+		LanguageMappings:         mappings,
+		ManualEntries:            manualEntries,
+		Aliases:                  combinedAliases,
+		Labels:                   combinedLabels,
+		Projects:                 projects,
+		ProjectRepos:             projectRepos,
+		Budgets:                  budgets,
+		Goals:                    goals,
+		DaysOff:                  combinedDaysOff,
+		ApiKey:                   user.ApiKey,
+		ImportScope:              user.HasImportScope(time.Now()),
+		RegenerationJob:          h.aggregationSrvc.GetRegenerationJob(user.ID),
+		ExportJob:                h.exportSrvc.GetExportJob(user.ID),
+		JiraLog:                  jiraLog,
+		GoogleCalendar:           h.config.Integrations.GoogleCalendar.IsEnabled(),
+		GoogleCalendarMinSession: h.config.Integrations.GoogleCalendar.GetMinSession(),
+		Jobs:                     jobs,
+		ClockSkews:               h.clockSkewSrvc.GetByUser(user),
+		QuotaViolations:          quotaViolations,
+		DefaultQuota:             h.config.App.HeartbeatsQuotaPerHour,
+		StorageUsage:             storageUsage,
+		DefaultStorageQuota:      h.config.App.StorageQuotaHeartbeats,
+		DefaultStoragePolicy:     h.config.App.GetStorageQuotaPolicy(),
+		Notifications:            notifications,
+		Telegram:                 h.config.App.TelegramBotToken != "",
+		RelayTargets:             relayTargets,
+		RelayRules:               relayRules,
+		Success:                  r.URL.Query().Get("success"),
+		Error:                    r.URL.Query().Get("error"),
 	}
 }
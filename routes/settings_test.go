@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSharingRanges(t *testing.T) {
+	form := url.Values{
+		"max_days":        []string{"30"},
+		"share_projects":  []string{"7"},
+		"share_languages": []string{"-1"},
+		"share_editors":   []string{"0"},
+		"share_oss":       []string{"0"},
+		"share_machines":  []string{"0"},
+		"share_labels":    []string{"14"},
+	}
+
+	user := &models.User{}
+	assert.Nil(t, parseSharingRanges(form, user))
+	assert.Equal(t, 30, user.ShareDataMaxDays)
+	assert.Equal(t, 7, user.ShareProjectsDays)
+	assert.Equal(t, -1, user.ShareLanguagesDays)
+	assert.Equal(t, 0, user.ShareEditorsDays)
+	assert.Equal(t, 14, user.ShareLabelsDays)
+}
+
+func TestParseSharingRanges_Invalid(t *testing.T) {
+	for _, invalid := range []string{"", "abc", "-2"} {
+		form := url.Values{
+			"max_days":        []string{"30"},
+			"share_projects":  []string{invalid},
+			"share_languages": []string{"-1"},
+			"share_editors":   []string{"0"},
+			"share_oss":       []string{"0"},
+			"share_machines":  []string{"0"},
+			"share_labels":    []string{"0"},
+		}
+
+		user := &models.User{ShareDataMaxDays: 1, ShareLanguagesDays: 2}
+		assert.NotNil(t, parseSharingRanges(form, user))
+		assert.Equal(t, 1, user.ShareDataMaxDays) // left untouched
+		assert.Equal(t, 2, user.ShareLanguagesDays)
+	}
+}
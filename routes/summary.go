@@ -4,6 +4,7 @@ import (
 	"github.com/gorilla/mux"
 	conf "github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/models/view"
 	su "github.com/muety/wakapi/routes/utils"
 	"github.com/muety/wakapi/services"
@@ -12,16 +13,24 @@ import (
 )
 
 type SummaryHandler struct {
-	config      *conf.Config
-	userSrvc    services.IUserService
-	summarySrvc services.ISummaryService
+	config          *conf.Config
+	userSrvc        services.IUserService
+	summarySrvc     services.ISummaryService
+	projectRepoSrvc services.IProjectRepoService
+	achievementSrvc services.IAchievementService
+	filterSetSrvc   services.IFilterSetService
+	languageSrvc    services.ILanguageMetaService
 }
 
-func NewSummaryHandler(summaryService services.ISummaryService, userService services.IUserService) *SummaryHandler {
+func NewSummaryHandler(summaryService services.ISummaryService, userService services.IUserService, projectRepoService services.IProjectRepoService, achievementService services.IAchievementService, filterSetService services.IFilterSetService, languageMetaService services.ILanguageMetaService) *SummaryHandler {
 	return &SummaryHandler{
-		summarySrvc: summaryService,
-		userSrvc:    userService,
-		config:      conf.Get(),
+		summarySrvc:     summaryService,
+		userSrvc:        userService,
+		projectRepoSrvc: projectRepoService,
+		achievementSrvc: achievementService,
+		filterSetSrvc:   filterSetService,
+		languageSrvc:    languageMetaService,
+		config:          conf.Get(),
 	}
 }
 
@@ -44,11 +53,14 @@ func (h *SummaryHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 	if q.Get("interval") == "" && q.Get("from") == "" {
 		q.Set("interval", "today")
+		if user := middlewares.GetPrincipal(r); user != nil && user.DashboardRange != "" {
+			q.Set("interval", user.DashboardRange)
+		}
 		r.URL.RawQuery = q.Encode()
 	}
 
 	summaryParams, _ := utils.ParseSummaryParams(r)
-	summary, err, status := su.LoadUserSummary(h.summarySrvc, r)
+	summary, err, status := su.LoadUserSummary(h.summarySrvc, h.filterSetSrvc, r)
 	if err != nil {
 		w.WriteHeader(status)
 		templates[conf.SummaryTemplate].Execute(w, h.buildViewModel(r).WithError(err.Error()))
@@ -66,14 +78,58 @@ func (h *SummaryHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
 		Summary:        summary,
 		SummaryParams:  summaryParams,
 		User:           user,
-		LanguageColors: utils.FilterColors(h.config.App.GetLanguageColors(), summary.Languages),
+		LanguageColors: utils.FilterColors(h.languageSrvc.GetColors(), summary.Languages),
 		ApiKey:         user.ApiKey,
 		RawQuery:       rawQuery,
+		ProjectRepos:   h.buildProjectRepos(r, summary),
+		FilterSet:      q.Get("filter_set"),
+		Layout:         user.DashboardLayout(),
+	}
+
+	if filterSets, err := h.filterSetSrvc.GetByUser(user.ID); err == nil {
+		vm.FilterSets = filterSets
+	} else {
+		conf.Log().Request(r).Error("error while fetching filter sets - %v", err)
+	}
+
+	if achievements, err := h.achievementSrvc.GetByUser(user.ID); err == nil {
+		vm.Achievements = achievements
+	} else {
+		conf.Log().Request(r).Error("error while fetching achievements - %v", err)
 	}
 
 	templates[conf.SummaryTemplate].Execute(w, vm)
 }
 
+// buildProjectRepos lists the summary's projects, that are linked to a repository, along with the number of commits made within the summary's interval
+func (h *SummaryHandler) buildProjectRepos(r *http.Request, summary *models.Summary) []*view.SummaryVMProjectRepo {
+	repos, err := h.projectRepoSrvc.GetByUserMapped(summary.UserID)
+	if err != nil {
+		conf.Log().Request(r).Error("error while fetching project repositories - %v", err)
+		return nil
+	}
+	if len(repos) == 0 {
+		return nil
+	}
+
+	result := make([]*view.SummaryVMProjectRepo, 0)
+	for _, p := range summary.Projects {
+		repo, ok := repos[p.Key]
+		if !ok {
+			continue
+		}
+
+		item := &view.SummaryVMProjectRepo{Project: p.Key, Url: repo.Url, Total: p.TotalFixed()}
+		if count, err := h.projectRepoSrvc.CountCommits(repo, summary.FromTime.T(), summary.ToTime.T()); err == nil {
+			item.Commits, item.HasCommits = count, true
+		} else {
+			conf.Log().Request(r).Warn("failed to count commits of repository '%s' - %v", repo.Url, err)
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
 func (h *SummaryHandler) buildViewModel(r *http.Request) *view.SummaryViewModel {
 	return &view.SummaryViewModel{
 		Success: r.URL.Query().Get("success"),
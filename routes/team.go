@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/models/view"
+	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
+)
+
+type TeamHandler struct {
+	config         *conf.Config
+	userSrvc       services.IUserService
+	comparisonSrvc services.IComparisonService
+}
+
+func NewTeamHandler(userService services.IUserService, comparisonService services.IComparisonService) *TeamHandler {
+	return &TeamHandler{
+		config:         conf.Get(),
+		userSrvc:       userService,
+		comparisonSrvc: comparisonService,
+	}
+}
+
+func (h *TeamHandler) RegisterRoutes(router *mux.Router) {
+	r := router.PathPrefix("/team").Subrouter()
+	r.Use(middlewares.NewAuthenticateMiddleware(h.userSrvc).WithRedirectTarget(defaultErrorRedirectTarget()).Handler)
+	r.Methods(http.MethodGet).HandlerFunc(h.GetIndex)
+}
+
+func (h *TeamHandler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	if h.config.IsDev() {
+		loadTemplates()
+	}
+
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		templates[conf.TeamTemplate].Execute(w, h.buildViewModel(r).WithError("unauthorized"))
+		return
+	}
+
+	vm := h.buildViewModel(r)
+	vm.User = user
+	vm.ApiKey = user.ApiKey
+
+	if vm.UserIds == "" {
+		templates[conf.TeamTemplate].Execute(w, vm)
+		return
+	}
+
+	err, from, to := utils.ResolveIntervalRawTZ(vm.Interval, user.TZ())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		templates[conf.TeamTemplate].Execute(w, vm.WithError("invalid interval"))
+		return
+	}
+
+	userIds := strings.Split(strings.ReplaceAll(vm.UserIds, " ", ""), ",")
+	comparison, err := h.comparisonSrvc.Compare(userIds, from, to, user)
+	if err != nil {
+		if err == services.ErrNoComparisonUsers || err == services.ErrTooManyComparisonUsers {
+			w.WriteHeader(http.StatusBadRequest)
+		} else if _, ok := err.(*services.ComparisonNotAllowedError); ok {
+			w.WriteHeader(http.StatusForbidden)
+		} else {
+			conf.Log().Request(r).Error("failed to compare summaries for user '%s' - %v", user.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			templates[conf.TeamTemplate].Execute(w, vm.WithError("failed to load comparison"))
+			return
+		}
+		templates[conf.TeamTemplate].Execute(w, vm.WithError(err.Error()))
+		return
+	}
+	vm.Comparison = comparison
+
+	templates[conf.TeamTemplate].Execute(w, vm)
+}
+
+func (h *TeamHandler) buildViewModel(r *http.Request) *view.TeamViewModel {
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = (*models.IntervalPast7Days)[1]
+	}
+	return &view.TeamViewModel{
+		UserIds:  r.URL.Query().Get("users"),
+		Interval: interval,
+		Success:  r.URL.Query().Get("success"),
+		Error:    r.URL.Query().Get("error"),
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"net/http"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/utils"
+)
+
+// CheckAdmin tells whether the request was authenticated by an admin and otherwise writes an HTTP error (401 without any user, 403 for non-admins)
+func CheckAdmin(w http.ResponseWriter, r *http.Request) bool {
+	user := middlewares.GetPrincipal(r)
+	if user == nil {
+		utils.RespondError(w, r, http.StatusUnauthorized, conf.ErrUnauthorized)
+		return false
+	}
+	if !user.IsAdmin {
+		utils.RespondError(w, r, http.StatusForbidden, conf.ErrForbidden)
+		return false
+	}
+	return true
+}
+
+// AdminOnly is a middleware, which only passes on requests authenticated by an admin, see CheckAdmin
+func AdminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if CheckAdmin(w, r) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
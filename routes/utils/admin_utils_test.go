@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/middlewares"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminOnly(t *testing.T) {
+	serve := func(principal *models.User) int {
+		handler := middlewares.NewPrincipalMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal != nil {
+				middlewares.SetPrincipal(r, principal)
+			}
+			AdminOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})).ServeHTTP(w, r)
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/admin/jobs", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(nil))
+	assert.Equal(t, http.StatusForbidden, serve(&models.User{ID: "user1"}))
+	assert.Equal(t, http.StatusNoContent, serve(&models.User{ID: "admin", IsAdmin: true}))
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+// Csv representations of summaries, durations and heartbeats, served instead of json, if requested via 'Accept: text/csv'.
+// Every record is a flat row, so that the data can be opened in spreadsheets or processed with shell tools right away.
+
+// RespondSummariesCsv writes one record per summary and item, e.g. one per project of every day
+func RespondSummariesCsv(w http.ResponseWriter, r *http.Request, summaries []*models.Summary) {
+	res := utils.NewCsvResponse(w, r, http.StatusOK, []string{"from", "to", "type", "key", "total_seconds", "write_seconds", "lines"})
+	defer res.Close()
+
+	for _, s := range summaries {
+		from, to := s.FromTime.T().Format(time.RFC3339), s.ToTime.T().Format(time.RFC3339)
+		for _, section := range []struct {
+			name  string
+			items models.SummaryItems
+		}{
+			{"project", s.Projects},
+			{"language", s.Languages},
+			{"editor", s.Editors},
+			{"operating_system", s.OperatingSystems},
+			{"machine", s.Machines},
+			{"label", s.Labels},
+			{"branch", s.Branches},
+			{"ticket", s.Tickets},
+			{"origin", s.Origins},
+			{"domain", s.Domains},
+		} {
+			for _, item := range section.items {
+				res.Write([]string{from, to, section.name, item.Key, fmtSeconds(item.TotalFixed()), fmtSeconds(item.WriteFixed()), strconv.Itoa(item.Lines)})
+			}
+		}
+	}
+}
+
+func RespondDurationsCsv(w http.ResponseWriter, r *http.Request, durations models.Durations) {
+	res := utils.NewCsvResponse(w, r, http.StatusOK, []string{"time", "duration_seconds", "project", "language", "editor", "operating_system", "machine", "branch", "write_seconds", "lines_changed"})
+	defer res.Close()
+
+	for _, d := range durations {
+		res.Write([]string{
+			d.Time.T().Format(time.RFC3339),
+			fmtSeconds(d.Duration),
+			d.Project,
+			d.Language,
+			d.Editor,
+			d.OperatingSystem,
+			d.Machine,
+			d.Branch,
+			fmtSeconds(d.WriteDuration),
+			strconv.Itoa(d.LinesChanged),
+		})
+	}
+}
+
+func RespondHeartbeatsCsv(w http.ResponseWriter, r *http.Request, heartbeats []*models.Heartbeat) {
+	res := utils.NewCsvResponse(w, r, http.StatusOK, []string{"time", "entity", "type", "category", "project", "branch", "language", "is_write", "lines", "editor", "operating_system", "machine"})
+	defer res.Close()
+
+	for _, h := range heartbeats {
+		res.Write([]string{
+			h.Time.T().Format(time.RFC3339Nano),
+			h.Entity,
+			h.Type,
+			h.Category,
+			h.Project,
+			h.Branch,
+			h.Language,
+			strconv.FormatBool(h.IsWrite),
+			strconv.Itoa(h.Lines),
+			h.Editor,
+			h.OperatingSystem,
+			h.Machine,
+		})
+	}
+}
+
+func fmtSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 0, 64)
+}
@@ -1,18 +1,55 @@
 package utils
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 )
 
+// transcodeMsgpack replaces a messagepack-encoded request body by its json equivalent, so that it can be read (and relayed) like any other
+func transcodeMsgpack(r *http.Request) error {
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	data, err := utils.MsgpackToJson(body)
+	if err != nil {
+		return err
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewBuffer(data))
+	r.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// IsNdjson tells whether the request body consists of newline-delimited json objects (https://github.com/ndjson/ndjson-spec)
+func IsNdjson(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/x-ndjson" || mediaType == "application/ndjson" || mediaType == "application/jsonl"
+}
+
 func ParseHeartbeats(r *http.Request) ([]*models.Heartbeat, error) {
+	if utils.IsMsgpack(r) {
+		if err := transcodeMsgpack(r); err != nil {
+			return []*models.Heartbeat{}, err
+		}
+	}
+
 	heartbeats, err := tryParseBulk(r)
 	if err == nil {
 		return heartbeats, err
 	}
+	if _, ok := err.(models.HeartbeatValidationError); ok || err == utils.ErrBodyTooLarge {
+		return []*models.Heartbeat{}, err
+	}
 
 	heartbeats, err = tryParseSingle(r)
 	if err == nil {
@@ -23,31 +60,119 @@ func ParseHeartbeats(r *http.Request) ([]*models.Heartbeat, error) {
 }
 
 func tryParseBulk(r *http.Request) ([]*models.Heartbeat, error) {
-	var heartbeats []*models.Heartbeat
+	var rawHeartbeats []json.RawMessage
 
-	body, _ := ioutil.ReadAll(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
 	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
 	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 
 	dec := json.NewDecoder(ioutil.NopCloser(bytes.NewBuffer(body)))
-	if err := dec.Decode(&heartbeats); err != nil {
+	if err := dec.Decode(&rawHeartbeats); err != nil {
 		return nil, err
 	}
 
+	heartbeats := make([]*models.Heartbeat, len(rawHeartbeats))
+	for i, raw := range rawHeartbeats {
+		hb, err := decodeHeartbeat(raw, i)
+		if err != nil {
+			return nil, err
+		}
+		heartbeats[i] = hb
+	}
 	return heartbeats, nil
 }
 
 func tryParseSingle(r *http.Request) ([]*models.Heartbeat, error) {
-	var heartbeat models.Heartbeat
-
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 	r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 
-	dec := json.NewDecoder(ioutil.NopCloser(bytes.NewBuffer(body)))
-	if err := dec.Decode(&heartbeat); err != nil {
+	heartbeat, err := decodeHeartbeat(body, 0)
+	if err != nil {
 		return nil, err
 	}
+	return []*models.Heartbeat{heartbeat}, nil
+}
 
-	return []*models.Heartbeat{&heartbeat}, nil
+// decodeHeartbeat decodes a single heartbeat, where a value of the wrong type results in a validation error pointing to the respective field
+func decodeHeartbeat(data []byte, index int) (*models.Heartbeat, error) {
+	var heartbeat models.Heartbeat
+	if err := json.Unmarshal(data, &heartbeat); err != nil {
+		return nil, asValidationError(err, index)
+	}
+	return &heartbeat, nil
+}
+
+func asValidationError(err error, index int) error {
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok && typeErr.Field != "" {
+		return models.HeartbeatValidationError{{Index: index, Field: typeErr.Field, Message: "must be of type " + typeErr.Type.String()}}
+	}
+	return err
+}
+
+// StreamHeartbeats incrementally decodes heartbeats from either a json array or newline-delimited json objects
+// and passes them on in batches of the given size, so that arbitrarily large bodies never have to be held in memory as a whole.
+func StreamHeartbeats(r io.Reader, batchSize int, f func([]*models.Heartbeat) error) error {
+	reader := bufio.NewReader(r)
+	dec := json.NewDecoder(reader)
+
+	// json arrays, e.g. as produced by the data export, are streamed element by element
+	isArray, err := startsWith(reader, '[')
+	if err != nil {
+		return err
+	}
+	if isArray {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+
+	var index int
+	batch := make([]*models.Heartbeat, 0, batchSize)
+	for ; ; index++ {
+		if isArray && !dec.More() {
+			if _, err := dec.Token(); err != nil {
+				return err
+			}
+			break
+		}
+
+		var hb models.Heartbeat
+		if err := dec.Decode(&hb); err == io.EOF {
+			break
+		} else if err != nil {
+			return asValidationError(err, index)
+		}
+
+		batch = append(batch, &hb)
+		if len(batch) >= batchSize {
+			if err := f(batch); err != nil {
+				return err
+			}
+			batch = make([]*models.Heartbeat, 0, batchSize)
+		}
+	}
+
+	if len(batch) > 0 {
+		return f(batch)
+	}
+	return nil
+}
+
+// startsWith tells whether the first non-whitespace character of the reader is the given one, without consuming it
+func startsWith(reader *bufio.Reader, c byte) (bool, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b == c, reader.UnreadByte()
+		}
+	}
 }
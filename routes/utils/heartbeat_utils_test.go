@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamHeartbeats(t *testing.T) {
+	type test struct {
+		body    string
+		batches []int
+		err     bool
+	}
+
+	tests := []test{
+		{body: "{\"entity\":\"a\"}\n{\"entity\":\"b\"}\n\n{\"entity\":\"c\"}\n", batches: []int{2, 1}}, // ndjson, with blank line
+		{body: " [{\"entity\":\"a\"}, {\"entity\":\"b\"}]", batches: []int{2}},                         // array
+		{body: "[]", batches: []int{}},
+		{body: "", batches: []int{}},
+		{body: "{\"entity\":\"a\"}\n{\"entity\":", batches: []int{}, err: true}, // truncated
+	}
+
+	for _, tt := range tests {
+		batches := make([]int, 0)
+		err := StreamHeartbeats(strings.NewReader(tt.body), 2, func(heartbeats []*models.Heartbeat) error {
+			batches = append(batches, len(heartbeats))
+			return nil
+		})
+		assert.Equal(t, tt.err, err != nil, tt.body)
+		assert.Equal(t, tt.batches, batches, tt.body)
+	}
+}
+
+func TestParseHeartbeats_ValidationError(t *testing.T) {
+	body := "[{\"entity\":\"a\",\"type\":\"file\",\"time\":1}, {\"entity\":\"b\",\"type\":\"file\",\"lines\":\"many\",\"time\":1}]"
+	r, _ := http.NewRequest(http.MethodPost, "/api/heartbeats", strings.NewReader(body))
+
+	_, err := ParseHeartbeats(r)
+	assert.IsType(t, models.HeartbeatValidationError{}, err)
+
+	validationErr := err.(models.HeartbeatValidationError)
+	assert.Len(t, validationErr, 1)
+	assert.Equal(t, 1, validationErr[0].Index)
+	assert.Equal(t, "lines", validationErr[0].Field)
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+// ParseOrdering reads the 'order_by' and 'order' (either 'asc' or 'desc') query parameters of list endpoints and validates them against the supported attributes.
+// Returns nil, if no ordering was requested.
+func ParseOrdering(r *http.Request, supported ...string) (*models.Ordering, error) {
+	orderBy := strings.ToLower(r.URL.Query().Get("order_by"))
+	order := strings.ToLower(r.URL.Query().Get("order"))
+	if orderBy == "" && order == "" {
+		return nil, nil
+	}
+
+	if orderBy == "" {
+		orderBy = supported[0]
+	}
+	if utils.FindString(orderBy, supported, "") == "" {
+		return nil, fmt.Errorf("invalid order_by, must be one of %s", strings.Join(supported, ", "))
+	}
+	if order != "" && order != "asc" && order != "desc" {
+		return nil, errors.New("invalid order, must be either asc or desc")
+	}
+
+	return models.NewOrdering(orderBy, order == "desc"), nil
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	v1 "github.com/muety/wakapi/models/compat/wakatime/v1"
+)
+
+// PageParams are the 1-based page and the page size of list endpoints, where a limit of 0 means all items on a single page
+type PageParams struct {
+	Page  int
+	Limit int
+}
+
+// ParsePageParams reads the 'page' and 'limit' query parameters of list endpoints, falling back to the given default page size.
+// Page sizes larger than maxLimit (0 = unlimited) are capped.
+func ParsePageParams(r *http.Request, defaultLimit, maxLimit int) (*PageParams, error) {
+	params := &PageParams{Page: 1, Limit: defaultLimit}
+
+	if pageParam := r.URL.Query().Get("page"); pageParam != "" {
+		page, err := strconv.Atoi(pageParam)
+		if err != nil || page < 1 {
+			return nil, errors.New("invalid page, must be a positive integer")
+		}
+		params.Page = page
+	}
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			return nil, errors.New("invalid limit, must be a positive integer")
+		}
+		params.Limit = limit
+	}
+
+	if maxLimit > 0 && (params.Limit == 0 || params.Limit > maxLimit) {
+		params.Limit = maxLimit
+	}
+	return params, nil
+}
+
+// Bounds returns the slice bounds of the requested page within a list of total items, which are empty for pages beyond the last one
+func (p *PageParams) Bounds(total int) (int, int) {
+	if p.Limit == 0 {
+		if p.Page > 1 {
+			return total, total
+		}
+		return 0, total
+	}
+	from, to := (p.Page-1)*p.Limit, p.Page*p.Limit
+	if from > total {
+		from = total
+	}
+	if to > total {
+		to = total
+	}
+	return from, to
+}
+
+// Pagination returns the pagination envelope of the requested page within a list of total items. There is always at least one (possibly empty) page.
+func (p *PageParams) Pagination(total int) v1.Pagination {
+	totalPages := 1
+	if p.Limit > 0 && total > p.Limit {
+		totalPages = (total + p.Limit - 1) / p.Limit
+	}
+
+	pagination := v1.Pagination{Total: total, TotalPages: totalPages, Page: p.Page}
+	if p.Page < totalPages {
+		next := p.Page + 1
+		pagination.NextPage = &next
+	}
+	return pagination
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePageParams(t *testing.T) {
+	type test struct {
+		query    string
+		maxLimit int
+		page     int
+		limit    int
+		failed   bool
+	}
+
+	tests := []test{
+		{query: "", page: 1, limit: 0},
+		{query: "page=3&limit=20", page: 3, limit: 20},
+		{query: "limit=500", maxLimit: 100, page: 1, limit: 100}, // capped
+		{query: "page=0", failed: true},
+		{query: "page=abc", failed: true},
+		{query: "limit=-1", failed: true},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest(http.MethodGet, "/api/compat/wakatime/v1/users/current/projects?"+tt.query, nil)
+		params, err := ParsePageParams(r, 0, tt.maxLimit)
+		assert.Equal(t, tt.failed, err != nil, tt.query)
+		if err == nil {
+			assert.Equal(t, tt.page, params.Page, tt.query)
+			assert.Equal(t, tt.limit, params.Limit, tt.query)
+		}
+	}
+}
+
+func TestPageParams_Pagination(t *testing.T) {
+	params := &PageParams{Page: 2, Limit: 10}
+	from, to := params.Bounds(25)
+	pagination := params.Pagination(25)
+	assert.Equal(t, 10, from)
+	assert.Equal(t, 20, to)
+	assert.Equal(t, 25, pagination.Total)
+	assert.Equal(t, 3, pagination.TotalPages)
+	assert.Equal(t, 2, pagination.Page)
+	assert.Equal(t, 3, *pagination.NextPage)
+
+	params.Page = 3
+	from, to = params.Bounds(25)
+	assert.Equal(t, 20, from)
+	assert.Equal(t, 25, to)
+	assert.Nil(t, params.Pagination(25).NextPage)
+
+	// beyond the last page
+	params.Page = 4
+	from, to = params.Bounds(25)
+	assert.Equal(t, 25, from)
+	assert.Equal(t, 25, to)
+
+	// everything on a single page
+	params = &PageParams{Page: 1}
+	from, to = params.Bounds(25)
+	pagination = params.Pagination(25)
+	assert.Equal(t, 0, from)
+	assert.Equal(t, 25, to)
+	assert.Equal(t, 1, pagination.TotalPages)
+	assert.Nil(t, pagination.NextPage)
+
+	assert.Equal(t, 1, (&PageParams{Page: 1, Limit: 10}).Pagination(0).TotalPages)
+}
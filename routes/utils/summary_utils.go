@@ -1,20 +1,28 @@
 package utils
 
 import (
+	"errors"
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/services"
 	"github.com/muety/wakapi/utils"
 	"net/http"
+	"time"
 )
 
-func LoadUserSummary(ss services.ISummaryService, r *http.Request) (*models.Summary, error, int) {
+func LoadUserSummary(ss services.ISummaryService, fss services.IFilterSetService, r *http.Request) (*models.Summary, error, int) {
 	user := middlewares.GetPrincipal(r)
 	summaryParams, err := utils.ParseSummaryParams(r)
 	if err != nil {
 		return nil, err, http.StatusBadRequest
 	}
 
+	filters, err, status := ApplyFilterSet(fss, user, r, summaryParams.Filters)
+	if err != nil {
+		return nil, err, status
+	}
+	summaryParams.Filters = filters
+
 	var retrieveSummary services.SummaryRetriever = ss.Retrieve
 	if summaryParams.Recompute {
 		retrieveSummary = ss.Summarize
@@ -30,3 +38,28 @@ func LoadUserSummary(ss services.ISummaryService, r *http.Request) (*models.Summ
 
 	return summary, nil, http.StatusOK
 }
+
+// ApplyFilterSet adds the filters saved by the user under the name given as 'filter_set' parameter, if any, to the given ones
+func ApplyFilterSet(fss services.IFilterSetService, user *models.User, r *http.Request, filters *models.Filters) (*models.Filters, error, int) {
+	name := r.URL.Query().Get("filter_set")
+	if name == "" {
+		return filters, nil, http.StatusOK
+	}
+
+	filterSet, err := fss.GetByUserAndName(user.ID, name)
+	if err != nil {
+		return nil, errors.New("filter set not found"), http.StatusNotFound
+	}
+	return filterSet.ApplyTo(filters), nil, http.StatusOK
+}
+
+// SharesFilters returns whether the owner publicly shares every dimension the given filters refer to,
+// so that unshared data can't be probed for by filtering
+func SharesFilters(owner *models.User, filters *models.Filters, from, to time.Time) bool {
+	for _, t := range filters.Entities() {
+		if !owner.SharesWithin(t, from, to) {
+			return false
+		}
+	}
+	return true
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/muety/wakapi/middlewares"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/services"
+	"github.com/muety/wakapi/utils"
 	"net/http"
 )
 
@@ -27,18 +28,16 @@ func CheckEffectiveUser(w http.ResponseWriter, r *http.Request, userService serv
 		}
 	}
 
-	requestedUser, err := userService.GetUserById(vars["user"])
+	requestedUser, err := middlewares.GetUserById(r, userService, vars["user"])
 	if err != nil {
 		err := errors.New("user not found")
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte(err.Error()))
+		utils.RespondError(w, r, http.StatusNotFound, err.Error())
 		return nil, err
 	}
 
 	if authorizedUser == nil || authorizedUser.ID != requestedUser.ID {
 		err := errors.New(conf.ErrUnauthorized)
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(err.Error()))
+		utils.RespondError(w, r, http.StatusUnauthorized, err.Error())
 		return nil, err
 	}
 
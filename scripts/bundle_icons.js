@@ -30,6 +30,7 @@ let icons = [
     'noto:stop-button',
     'noto:lock',
     'twemoji:gear',
+    'twemoji:trophy',
     'eva:corner-right-down-fill',
     'bi:heart-fill',
     'fxemoji:running',
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+type AchievementService struct {
+	config            *config.Config
+	eventBus          *hub.Hub
+	repository        repositories.IAchievementRepository
+	summaryRepository repositories.ISummaryRepository
+	dayOffService     IDayOffService
+}
+
+func NewAchievementService(achievementRepository repositories.IAchievementRepository, summaryRepository repositories.ISummaryRepository, dayOffService IDayOffService) *AchievementService {
+	srv := &AchievementService{
+		config:            config.Get(),
+		eventBus:          config.EventBus(),
+		repository:        achievementRepository,
+		summaryRepository: summaryRepository,
+		dayOffService:     dayOffService,
+	}
+
+	sub := srv.eventBus.Subscribe(0, config.EventSummaryCreate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			user := m.Fields[config.FieldPayload].(*models.User)
+			if _, err := srv.Evaluate(user); err != nil {
+				config.Log().Error("failed to evaluate achievements for user '%s' - %v", user.ID, err)
+			}
+		}
+	}(&sub)
+
+	return srv
+}
+
+func (srv *AchievementService) GetByUser(userId string) ([]*models.Achievement, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+// Evaluate replays the user's persisted summaries day by day and awards all achievements not earned so far, dated to the day they were reached at.
+// Returns the newly earned achievements.
+func (srv *AchievementService) Evaluate(user *models.User) ([]*models.Achievement, error) {
+	earned, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]*models.AchievementDefinition, 0, len(models.Achievements))
+	for _, d := range models.Achievements {
+		if !containsAchievement(earned, d.Key) {
+			pending = append(pending, d)
+		}
+	}
+	if len(pending) == 0 {
+		return []*models.Achievement{}, nil
+	}
+
+	daysOff, err := srv.dayOffService.GetByUserMapped(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := srv.summaryRepository.GetByUserWithin(user, time.Time{}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.AchievementStats{Languages: map[string]bool{}, Streak: models.NewStreakCounter(daysOff)}
+	newlyEarned := make([]*models.Achievement, 0)
+
+	var lastDay time.Time
+	for _, s := range summaries {
+		if len(pending) == 0 {
+			break
+		}
+
+		total := s.TotalTime()
+		if total <= 0 {
+			continue
+		}
+
+		stats.Total += total
+		for _, l := range s.Languages {
+			if l.Key != models.UnknownSummaryKey {
+				stats.Languages[l.Key] = true
+			}
+		}
+		// summaries might not be exactly one day long, e.g. if generated at a different time zone
+		if day := s.FromTime.T().In(user.TZ()); day.Format(models.DayOffFormat) != lastDay.Format(models.DayOffFormat) {
+			lastDay = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+			stats.Streak.Add(lastDay)
+		}
+
+		stillPending := pending[:0]
+		for _, d := range pending {
+			if d.IsEarned(stats) {
+				newlyEarned = append(newlyEarned, &models.Achievement{UserID: user.ID, Key: d.Key, EarnedAt: s.ToTime})
+			} else {
+				stillPending = append(stillPending, d)
+			}
+		}
+		pending = stillPending
+	}
+
+	if err := srv.repository.InsertBatch(newlyEarned); err != nil {
+		return nil, err
+	}
+	for _, a := range newlyEarned {
+		logbuch.Info("user '%s' earned achievement '%s'", user.ID, a.Key)
+	}
+
+	return newlyEarned, nil
+}
+
+func containsAchievement(achievements []*models.Achievement, key string) bool {
+	for _, a := range achievements {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type AchievementServiceTestSuite struct {
+	suite.Suite
+	TestUser              *models.User
+	TestStartTime         time.Time
+	AchievementRepository *mocks.AchievementRepositoryMock
+	SummaryRepository     *mocks.SummaryRepositoryMock
+	DayOffService         *mocks.DayOffServiceMock
+}
+
+func (suite *AchievementServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: "johndoe", Location: "UTC"}
+	suite.TestStartTime = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (suite *AchievementServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.AchievementRepository = new(mocks.AchievementRepositoryMock)
+	suite.SummaryRepository = new(mocks.SummaryRepositoryMock)
+	suite.DayOffService = new(mocks.DayOffServiceMock)
+	suite.DayOffService.On("GetByUserMapped", suite.TestUser.ID).Return(models.NewDaysOff([]*models.DayOff{}), nil)
+
+	// summaries generated by other tests are published on the shared event bus, achievements of their users are not evaluated here
+	otherUser := mock.MatchedBy(func(userId string) bool { return userId != suite.TestUser.ID })
+	suite.AchievementRepository.On("GetByUser", otherUser).Return([]*models.Achievement(nil), errors.New("unrelated user")).Maybe()
+}
+
+func TestAchievementServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(AchievementServiceTestSuite))
+}
+
+func (suite *AchievementServiceTestSuite) TestAchievementService_Evaluate() {
+	// 3 hours of go on 35 days in a row, plus 9 other languages on the 10th day
+	summaries := make([]*models.Summary, 35)
+	for i := range summaries {
+		from := suite.TestStartTime.AddDate(0, 0, i)
+		summaries[i] = &models.Summary{
+			FromTime:  models.CustomTime(from),
+			ToTime:    models.CustomTime(from.AddDate(0, 0, 1)),
+			Languages: []*models.SummaryItem{{Type: models.SummaryLanguage, Key: "Go", Total: 3 * time.Hour / time.Second}},
+		}
+		if i == 9 {
+			for j := 0; j < 9; j++ {
+				summaries[i].Languages = append(summaries[i].Languages, &models.SummaryItem{Type: models.SummaryLanguage, Key: fmt.Sprintf("lang%d", j), Total: 1})
+			}
+		}
+	}
+
+	suite.AchievementRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.Achievement{}, nil)
+	suite.AchievementRepository.On("InsertBatch", mock.Anything).Return(nil)
+	suite.SummaryRepository.On("GetByUserWithin", suite.TestUser, mock.Anything, mock.Anything).Return(summaries, nil)
+
+	sut := NewAchievementService(suite.AchievementRepository, suite.SummaryRepository, suite.DayOffService)
+
+	result, err := sut.Evaluate(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 3)
+	assert.Equal(suite.T(), models.AchievementLanguages10, result[0].Key)
+	assert.Equal(suite.T(), suite.TestStartTime.AddDate(0, 0, 10), result[0].EarnedAt.T())
+	assert.Equal(suite.T(), models.AchievementStreak30, result[1].Key)
+	assert.Equal(suite.T(), suite.TestStartTime.AddDate(0, 0, 30), result[1].EarnedAt.T())
+	assert.Equal(suite.T(), models.AchievementHours100, result[2].Key)
+	assert.Equal(suite.T(), suite.TestStartTime.AddDate(0, 0, 34), result[2].EarnedAt.T())
+	suite.AchievementRepository.AssertCalled(suite.T(), "InsertBatch", result)
+}
+
+func (suite *AchievementServiceTestSuite) TestAchievementService_Evaluate_AllEarned() {
+	earned := make([]*models.Achievement, len(models.Achievements))
+	for i, d := range models.Achievements {
+		earned[i] = &models.Achievement{UserID: suite.TestUser.ID, Key: d.Key}
+	}
+	suite.AchievementRepository.On("GetByUser", suite.TestUser.ID).Return(earned, nil)
+
+	sut := NewAchievementService(suite.AchievementRepository, suite.SummaryRepository, suite.DayOffService)
+
+	result, err := sut.Evaluate(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), result)
+	suite.SummaryRepository.AssertNotCalled(suite.T(), "GetByUserWithin", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -2,9 +2,13 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/wakapi/config"
-	"runtime"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
 	"sync"
 	"time"
 
@@ -14,43 +18,94 @@ import (
 
 const (
 	aggregateIntervalDays int = 1
+	aggregateBatchSize    int = 50            // number of summaries to insert at once
+	aggregateCatchUpGrace     = 1 * time.Hour // time to wait for a scheduled run before considering it missed
 )
 
 var aggregationLock = sync.Mutex{}
 
+var (
+	ErrAggregationInProgress    = errors.New("aggregation already in progress for at least one of the requested users")
+	ErrInvalidRegenerationRange = errors.New("invalid date range, must contain at least one completed day")
+)
+
 type AggregationService struct {
-	config           *config.Config
-	userService      IUserService
-	summaryService   ISummaryService
-	heartbeatService IHeartbeatService
-	inProgress       map[string]bool
+	config                        *config.Config
+	eventBus                      *hub.Hub
+	dirtyCache                    *cache.Cache
+	summaryInvalidationRepository repositories.ISummaryInvalidationRepository
+	userService                   IUserService
+	summaryService                ISummaryService
+	heartbeatService              IHeartbeatService
+	jobService                    IJobService
+	keyValueService               IKeyValueService
+	inProgress                    map[string]bool
+	fullRunInProgress             bool
+	regenerationJobs              map[string]*models.RegenerationJob
+	regenerationLock              sync.RWMutex
 }
 
-func NewAggregationService(userService IUserService, summaryService ISummaryService, heartbeatService IHeartbeatService) *AggregationService {
-	return &AggregationService{
-		config:           config.Get(),
-		userService:      userService,
-		summaryService:   summaryService,
-		heartbeatService: heartbeatService,
-		inProgress:       map[string]bool{},
+func NewAggregationService(summaryInvalidationRepository repositories.ISummaryInvalidationRepository, userService IUserService, summaryService ISummaryService, heartbeatService IHeartbeatService, jobService IJobService, keyValueService IKeyValueService) *AggregationService {
+	srv := &AggregationService{
+		config:                        config.Get(),
+		eventBus:                      config.EventBus(),
+		dirtyCache:                    cache.New(24*time.Hour, 24*time.Hour),
+		summaryInvalidationRepository: summaryInvalidationRepository,
+		userService:                   userService,
+		summaryService:                summaryService,
+		heartbeatService:              heartbeatService,
+		jobService:                    jobService,
+		keyValueService:               keyValueService,
+		inProgress:                    map[string]bool{},
+		regenerationJobs:              map[string]*models.RegenerationJob{},
 	}
+
+	// inserted, edited and deleted heartbeats invalidate their days' summaries within the same transaction already (see HeartbeatRepository),
+	// but bulk updates and deletions are only signaled via events
+	sub1 := srv.eventBus.Subscribe(0, config.EventHeartbeatUpdate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			heartbeat := m.Fields[config.FieldPayload].(*models.Heartbeat)
+			srv.markDirty(heartbeat.UserID, heartbeat.Time.T(), heartbeat.Time.T())
+		}
+	}(&sub1)
+
+	sub2 := srv.eventBus.Subscribe(0, config.EventHeartbeatDelete)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			interval := m.Fields[config.FieldPayload].(*models.Interval)
+			srv.markDirty(m.Fields[config.FieldUserId].(string), interval.Start, interval.End)
+		}
+	}(&sub2)
+
+	return srv
 }
 
 type AggregationJob struct {
-	UserID string
-	From   time.Time
-	To     time.Time
+	UserID    string
+	From      time.Time
+	To        time.Time
+	Recompute bool // whether a summary for this day might exist already and has to be replaced
+}
+
+type userAggregationJobs struct {
+	User *models.User
+	Jobs []*AggregationJob
 }
 
 // Schedule a job to (re-)generate summaries every day shortly after midnight
 func (srv *AggregationService) Schedule() {
-	// Run once initially
+	// Run once initially, which backfills all days skipped while the server was down
+	if missed := srv.missedRuns(time.Now()); missed > 0 {
+		logbuch.Warn("missed %d scheduled aggregation runs since %v, backfilling skipped days", missed, srv.getLatestRun())
+	}
 	if err := srv.Run(nil); err != nil {
 		logbuch.Fatal("failed to run AggregationJob: %v", err)
 	}
 
 	s := gocron.NewScheduler(time.Local)
 	s.Every(1).Day().At(srv.config.App.AggregationTime).Do(srv.Run, map[string]bool{})
+	s.Every(1).Hour().Do(srv.catchUp)
 	s.StartBlocking()
 }
 
@@ -60,100 +115,433 @@ func (srv *AggregationService) Run(userIds map[string]bool) error {
 	}
 	defer srv.unlockUsers(userIds)
 
-	jobs := make(chan *AggregationJob)
-	summaries := make(chan *models.Summary)
+	if len(userIds) == 0 {
+		aggregationLock.Lock()
+		srv.fullRunInProgress = true
+		aggregationLock.Unlock()
+		defer func() {
+			aggregationLock.Lock()
+			srv.fullRunInProgress = false
+			aggregationLock.Unlock()
+		}()
+	}
+
+	var userId string
+	if len(userIds) == 1 {
+		for uid := range userIds {
+			userId = uid
+		}
+	}
+
+	return srv.jobService.Track(models.JobAggregation, userId, func() error {
+		return srv.run(userIds)
+	})
+}
+
+// Regenerate asynchronously re-computes and replaces a user's persisted summaries for every day from (inclusive) to (exclusive).
+// As summaries are generated per server-local day, the range is extended to cover all server-local days it overlaps with.
+// Days before the user's first heartbeat (or their registration, if there are none) are skipped. Today is never included, as
+// summaries are only persisted for completed days. Progress can be polled using GetRegenerationJob.
+func (srv *AggregationService) Regenerate(user *models.User, from, to time.Time) (*models.RegenerationJob, error) {
+	if to.Before(from) {
+		return nil, ErrInvalidRegenerationRange
+	}
+
+	earliest := user.CreatedAt.T()
+	if first, err := srv.heartbeatService.GetFirstByUser(user); err == nil && first != nil {
+		earliest = first.Time.T()
+	}
+	if from.Before(earliest) {
+		from = earliest
+	}
 
-	for i := 0; i < runtime.NumCPU(); i++ {
-		go srv.summaryWorker(jobs, summaries)
+	from, to = utils.StartOfDay(from.In(time.Local)), utils.CeilDate(to.In(time.Local))
+	if today := utils.StartOfToday(time.Local); to.After(today) {
+		to = today
+	}
+	if !from.Before(to) {
+		return nil, ErrInvalidRegenerationRange
 	}
 
-	for i := 0; i < int(srv.config.Db.MaxConn); i++ {
-		go srv.persistWorker(summaries)
+	userIds := map[string]bool{user.ID: true}
+	if err := srv.lockUsers(userIds); err != nil {
+		return nil, err
 	}
 
-	// don't leak open channels
-	go func(c1 chan *AggregationJob, c2 chan *models.Summary) {
-		defer close(c1)
-		defer close(c2)
-		time.Sleep(1 * time.Hour)
-	}(jobs, summaries)
+	job := models.NewRegenerationJob(user.ID, from, to)
+	srv.updateRegenerationJob(job, nil)
 
-	return srv.trigger(jobs, userIds)
+	go func() {
+		defer srv.unlockUsers(userIds)
+		srv.regenerate(user, job)
+	}()
+
+	return srv.GetRegenerationJob(user.ID), nil
 }
 
-func (srv *AggregationService) summaryWorker(jobs <-chan *AggregationJob, summaries chan<- *models.Summary) {
-	for job := range jobs {
-		if summary, err := srv.summaryService.Summarize(job.From, job.To, &models.User{ID: job.UserID}, nil); err != nil {
-			config.Log().Error("failed to generate summary (%v, %v, %s) - %v", job.From, job.To, job.UserID, err)
-		} else {
-			logbuch.Info("successfully generated summary (%v, %v, %s)", job.From, job.To, job.UserID)
-			summaries <- summary
-		}
+// GetRegenerationJob returns a snapshot of the user's latest regeneration job or nil, if none was run since server start
+func (srv *AggregationService) GetRegenerationJob(userId string) *models.RegenerationJob {
+	srv.regenerationLock.RLock()
+	defer srv.regenerationLock.RUnlock()
+	if job, ok := srv.regenerationJobs[userId]; ok {
+		jobCopy := *job
+		return &jobCopy
 	}
+	return nil
 }
 
-func (srv *AggregationService) persistWorker(summaries <-chan *models.Summary) {
-	for summary := range summaries {
-		if err := srv.summaryService.Insert(summary); err != nil {
-			config.Log().Error("failed to save summary (%v, %v, %s) - %v", summary.UserID, summary.FromTime, summary.ToTime, err)
+func (srv *AggregationService) regenerate(user *models.User, job *models.RegenerationJob) {
+	logbuch.Info("regenerating summaries for user '%s' from %v to %v", user.ID, job.From, job.To)
+	done := srv.jobService.Start(models.JobRegeneration, user.ID)
+
+	for from := job.From; from.Before(job.To); from = from.AddDate(0, 0, 1) {
+		to := from.AddDate(0, 0, 1)
+		err := srv.RegenerateDay(user, from, to)
+		if err != nil {
+			config.Log().Error("failed to regenerate summary (%v, %v, %s) - %v", from, to, user.ID, err)
 		}
+		srv.updateRegenerationJob(job, func(j *models.RegenerationJob) {
+			j.Processed++
+			if err != nil {
+				j.Failed++
+				j.Error = err.Error()
+			}
+		})
+	}
+
+	srv.updateRegenerationJob(job, func(j *models.RegenerationJob) {
+		now := time.Now()
+		j.FinishedAt = &now
+	})
+	logbuch.Info("finished regenerating summaries for user '%s' (%d days, %d failed)", user.ID, job.Total, job.Failed)
+
+	if job.Failed > 0 {
+		done(fmt.Errorf("failed to regenerate %d of %d summaries - %s", job.Failed, job.Total, job.Error))
+	} else {
+		done(nil)
 	}
 }
 
-func (srv *AggregationService) trigger(jobs chan<- *AggregationJob, userIds map[string]bool) error {
+// WithUserLock runs the given function while holding the user's aggregation lock, so that it won't interfere with summaries being generated concurrently.
+// Fails with ErrAggregationInProgress, if the lock is currently held by someone else.
+func (srv *AggregationService) WithUserLock(userId string, f func() error) error {
+	userIds := map[string]bool{userId: true}
+	if err := srv.lockUsers(userIds); err != nil {
+		return err
+	}
+	defer srv.unlockUsers(userIds)
+	return f()
+}
+
+// RegenerateDay replaces all of the user's persisted summaries within the given day by a single one recomputed from raw heartbeats.
+// Callers are expected to hold the user's aggregation lock (see WithUserLock).
+func (srv *AggregationService) RegenerateDay(user *models.User, from, to time.Time) error {
+	summary, err := srv.summaryService.Summarize(from, to, user, nil)
+	if err != nil {
+		return err
+	}
+	return srv.summaryService.ReplaceWithin(user.ID, from, to, summary)
+}
+
+func (srv *AggregationService) updateRegenerationJob(job *models.RegenerationJob, update func(*models.RegenerationJob)) {
+	srv.regenerationLock.Lock()
+	defer srv.regenerationLock.Unlock()
+	if update != nil {
+		update(job)
+	}
+	srv.regenerationJobs[job.UserID] = job
+}
+
+// run generates all missing daily summaries for the given users (or all users, if empty) and blocks until done.
+// Users are processed concurrently by a bounded pool of workers, each of which handles all of a single user's days.
+func (srv *AggregationService) run(userIds map[string]bool) error {
 	logbuch.Info("generating summaries")
+	start := time.Now()
+
+	// days marked again from now on will have to be recomputed once more, so their markers are kept (see below)
+	srv.dirtyCache.Flush()
 
-	var users []*models.User
-	if allUsers, err := srv.userService.GetAll(); err != nil {
+	users, err := srv.getUsers(userIds)
+	if err != nil {
 		config.Log().Error(err.Error())
 		return err
-	} else if userIds != nil && len(userIds) > 0 {
-		users = make([]*models.User, 0)
-		for _, u := range allUsers {
-			if yes, ok := userIds[u.ID]; yes && ok {
-				users = append(users, u)
+	}
+
+	jobsByUser, invalidationIds, err := srv.collectJobs(users)
+	if err != nil {
+		config.Log().Error(err.Error())
+		return err
+	}
+
+	userJobs := make(chan *userAggregationJobs)
+	var wg sync.WaitGroup
+
+	numWorkers := srv.config.GetAggregationWorkers()
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			srv.summaryWorker(userJobs)
+		}()
+	}
+
+	var numJobs int
+	for _, u := range users {
+		if jobs, ok := jobsByUser[u.ID]; ok && len(jobs) > 0 {
+			userJobs <- &userAggregationJobs{User: u, Jobs: jobs}
+			numJobs += len(jobs)
+		}
+	}
+	close(userJobs)
+	wg.Wait()
+
+	// markers renewed during this run are kept (timestamps are compared at second precision to be safe with all databases)
+	if err := srv.summaryInvalidationRepository.DeleteBatchMarkedBefore(invalidationIds, start.Truncate(time.Second)); err != nil {
+		config.Log().Error("failed to clear summary invalidations - %v", err)
+	}
+
+	logbuch.Info("finished generating %d summaries for %d users using %d workers in %v", numJobs, len(jobsByUser), numWorkers, time.Since(start))
+
+	if len(userIds) == 0 {
+		srv.setLatestRun(start)
+	}
+	return nil
+}
+
+// catchUp runs the aggregation, if a scheduled run was missed while the server was up, e.g. because its host was suspended or the run failed
+func (srv *AggregationService) catchUp() {
+	aggregationLock.Lock()
+	running := srv.fullRunInProgress
+	aggregationLock.Unlock()
+
+	now := time.Now()
+	if running || srv.missedRuns(now) == 0 || now.Sub(srv.latestScheduledRun(now)) < aggregateCatchUpGrace {
+		return
+	}
+
+	logbuch.Warn("missed scheduled aggregation run at %v, catching up", srv.latestScheduledRun(now))
+	if err := srv.Run(map[string]bool{}); err != nil {
+		config.Log().Error("failed to catch up on aggregation - %v", err)
+	}
+}
+
+// missedRuns returns the number of scheduled daily runs since the latest completed one, which didn't happen, e.g. because the server was down.
+// Zero, if aggregation never completed before.
+func (srv *AggregationService) missedRuns(now time.Time) int {
+	latest := srv.getLatestRun()
+	if latest == nil {
+		return 0
+	}
+
+	var missed int
+	for t := srv.latestScheduledRun(now); t.After(*latest); t = t.AddDate(0, 0, -1) {
+		missed++
+	}
+	return missed
+}
+
+// latestScheduledRun returns when the daily aggregation was due most recently, as of the given time
+func (srv *AggregationService) latestScheduledRun(now time.Time) time.Time {
+	at, err := time.Parse("15:04", srv.config.App.AggregationTime)
+	if err != nil {
+		at = time.Time{}
+	}
+
+	now = now.In(time.Local)
+	t := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, time.Local)
+	if t.After(now) {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+func (srv *AggregationService) getLatestRun() *time.Time {
+	t, err := time.Parse(time.RFC3339, srv.keyValueService.MustGetString(config.KeyLatestAggregation).Value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func (srv *AggregationService) setLatestRun(t time.Time) {
+	if err := srv.keyValueService.PutString(&models.KeyStringValue{
+		Key:   config.KeyLatestAggregation,
+		Value: t.Format(time.RFC3339),
+	}); err != nil {
+		config.Log().Error("failed to save time of latest aggregation - %v", err)
+	}
+}
+
+func (srv *AggregationService) summaryWorker(userJobs <-chan *userAggregationJobs) {
+	for uj := range userJobs {
+		batch := make([]*models.Summary, 0, aggregateBatchSize)
+		var generated int
+
+		for _, job := range uj.Jobs {
+			// days failing to be aggregated are marked to be retried during the next run, instead of leaving a hole
+			summary, err := srv.summaryService.Summarize(job.From, job.To, uj.User, nil)
+			if err != nil {
+				config.Log().Error("failed to generate summary (%v, %v, %s) - %v", job.From, job.To, job.UserID, err)
+				srv.markDirty(job.UserID, job.From, job.To)
+				continue
+			}
+
+			// outdated summaries are only replaced once the new one was computed successfully
+			if job.Recompute {
+				if err := srv.summaryService.ReplaceWithin(job.UserID, job.From, job.To, summary); err != nil {
+					config.Log().Error("failed to replace outdated summary (%v, %v, %s) - %v", job.From, job.To, job.UserID, err)
+					srv.markDirty(job.UserID, job.From, job.To)
+				} else {
+					generated++
+				}
+				continue
+			}
+
+			batch = append(batch, summary)
+			if len(batch) == aggregateBatchSize {
+				generated += srv.persist(batch)
+				batch = make([]*models.Summary, 0, aggregateBatchSize)
 			}
 		}
-	} else {
-		users = allUsers
+
+		if len(batch) > 0 {
+			generated += srv.persist(batch)
+		}
+
+		if generated > 0 {
+			srv.eventBus.Publish(hub.Message{
+				Name:   config.EventSummaryCreate,
+				Fields: map[string]interface{}{config.FieldPayload: uj.User, config.FieldUserId: uj.User.ID},
+			})
+		}
 	}
+}
 
+// persist saves the given summaries and returns how many of them were saved
+func (srv *AggregationService) persist(summaries []*models.Summary) int {
+	if err := srv.summaryService.InsertBatch(summaries); err != nil {
+		config.Log().Error("failed to save %d summaries for user '%s' - %v", len(summaries), summaries[0].UserID, err)
+		srv.markDirty(summaries[0].UserID, summaries[0].FromTime.T(), summaries[len(summaries)-1].ToTime.T())
+		return 0
+	}
+	logbuch.Info("successfully generated %d summaries (%v, %v, %s)", len(summaries), summaries[0].FromTime.T(), summaries[len(summaries)-1].ToTime.T(), summaries[0].UserID)
+	return len(summaries)
+}
+
+func (srv *AggregationService) getUsers(userIds map[string]bool) ([]*models.User, error) {
+	allUsers, err := srv.userService.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(userIds) == 0 {
+		return allUsers, nil
+	}
+
+	users := make([]*models.User, 0, len(userIds))
+	for _, u := range allUsers {
+		if yes, ok := userIds[u.ID]; yes && ok {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+// collectJobs determines the days to generate summaries for per user, using batch queries for all users at once.
+// Besides the ids of all jobs, it returns the ids of all summary invalidations covered by these jobs.
+func (srv *AggregationService) collectJobs(users []*models.User) (map[string][]*AggregationJob, []uint, error) {
 	// Get a map from user ids to the time of their latest summary or nil if none exists yet
 	lastUserSummaryTimes, err := srv.summaryService.GetLatestByUser()
 	if err != nil {
-		config.Log().Error(err.Error())
-		return err
+		return nil, nil, err
 	}
 
 	// Get a map from user ids to the time of their earliest heartbeats or nil if none exists yet
 	firstUserHeartbeatTimes, err := srv.heartbeatService.GetFirstByUsers()
 	if err != nil {
-		config.Log().Error(err.Error())
-		return err
+		return nil, nil, err
 	}
 
-	// Build actual lookup table from it
+	// Get all days, which received heartbeats after their summary had been generated
+	invalidations, err := srv.summaryInvalidationRepository.GetAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Build actual lookup tables from it
+	lastUserSummaryLookup := make(map[string]models.CustomTime)
+	for _, e := range lastUserSummaryTimes {
+		lastUserSummaryLookup[e.User] = e.Time
+	}
 	firstUserHeartbeatLookup := make(map[string]models.CustomTime)
 	for _, e := range firstUserHeartbeatTimes {
 		firstUserHeartbeatLookup[e.User] = e.Time
 	}
+	invalidationLookup := make(map[string][]*models.SummaryInvalidation)
+	for _, d := range invalidations {
+		invalidationLookup[d.UserID] = append(invalidationLookup[d.UserID], d)
+	}
 
 	// Generate summary aggregation jobs
-	for _, e := range lastUserSummaryTimes {
-		if e.Time.Valid() {
+	jobs := make(map[string][]*AggregationJob)
+	invalidationIds := make([]uint, 0)
+	for _, u := range users {
+		if t := lastUserSummaryLookup[u.ID]; t.Valid() {
 			// Case 1: User has aggregated summaries already
+			// -> Spawn jobs to recompute days, which changed since their aggregation
 			// -> Spawn jobs to create summaries from their latest aggregation to now
-			generateUserJobs(e.User, e.Time.T(), jobs)
-		} else if t := firstUserHeartbeatLookup[e.User]; t.Valid() {
+			for _, d := range invalidationLookup[u.ID] {
+				// days after the latest aggregation are covered by the regular jobs anyway
+				if day := d.Day.T(); day.Before(t.T()) {
+					jobs[u.ID] = append(jobs[u.ID], &AggregationJob{UserID: u.ID, From: day, To: day.AddDate(0, 0, 1), Recompute: true})
+				}
+			}
+			jobs[u.ID] = append(jobs[u.ID], generateUserJobs(u.ID, t.T())...)
+		} else if t := firstUserHeartbeatLookup[u.ID]; t.Valid() {
 			// Case 2: User has no aggregated summaries, yet, but has heartbeats
 			// -> Spawn jobs to create summaries from their first heartbeat to now
-			generateUserJobs(e.User, t.T(), jobs)
+			jobs[u.ID] = generateUserJobs(u.ID, t.T())
 		}
 		// Case 3: User doesn't have heartbeats at all
 		// -> Nothing to do
+
+		for _, d := range invalidationLookup[u.ID] {
+			invalidationIds = append(invalidationIds, d.ID)
+		}
 	}
 
-	return nil
+	return jobs, invalidationIds, nil
+}
+
+// markDirty remembers all past days overlapping the given time range to have their summaries recomputed, e.g. if heartbeats of these days were updated or deleted.
+// Days are server-local, as are the summaries generated during aggregation (see generateUserJobs).
+func (srv *AggregationService) markDirty(userId string, from, to time.Time) {
+	today := utils.StartOfToday(time.Local)
+	if to.After(today) {
+		to = today
+	}
+
+	now := time.Now()
+	days := make([]*models.SummaryInvalidation, 0)
+	cacheKeys := make([]string, 0)
+	for day := utils.StartOfDay(from.In(time.Local)); day.Before(to); day = day.AddDate(0, 0, 1) {
+		cacheKey := fmt.Sprintf("%s--%s", userId, day.Format(config.SimpleDateFormat))
+		if _, found := srv.dirtyCache.Get(cacheKey); found {
+			continue
+		}
+		days = append(days, &models.SummaryInvalidation{UserID: userId, Day: models.CustomTime(day), MarkedAt: models.CustomTime(now)})
+		cacheKeys = append(cacheKeys, cacheKey)
+	}
+	if len(days) == 0 {
+		return
+	}
+
+	if err := srv.summaryInvalidationRepository.InsertBatch(days); err != nil {
+		config.Log().Error("failed to mark %d days as dirty for user '%s' - %v", len(days), userId, err)
+		return
+	}
+	for _, k := range cacheKeys {
+		srv.dirtyCache.SetDefault(k, true)
+	}
 }
 
 func (srv *AggregationService) lockUsers(userIds map[string]bool) error {
@@ -161,7 +549,7 @@ func (srv *AggregationService) lockUsers(userIds map[string]bool) error {
 	defer aggregationLock.Unlock()
 	for uid := range userIds {
 		if _, ok := srv.inProgress[uid]; ok {
-			return errors.New("aggregation already in progress for at least of the request users")
+			return ErrAggregationInProgress
 		}
 	}
 	for uid := range userIds {
@@ -178,8 +566,9 @@ func (srv *AggregationService) unlockUsers(userIds map[string]bool) {
 	}
 }
 
-func generateUserJobs(userId string, from time.Time, jobs chan<- *AggregationJob) {
+func generateUserJobs(userId string, from time.Time) []*AggregationJob {
 	var to time.Time
+	jobs := make([]*AggregationJob, 0)
 
 	// Go to next day of either user's first heartbeat or latest aggregation
 	from = from.Add(-1 * time.Second)
@@ -201,9 +590,10 @@ func generateUserJobs(userId string, from time.Time, jobs chan<- *AggregationJob
 			0, 0, 0, 0,
 			from.Location(),
 		)
-		jobs <- &AggregationJob{userId, from, to}
+		jobs = append(jobs, &AggregationJob{UserID: userId, From: from, To: to})
 		from = to
 	}
+	return jobs
 }
 
 func getStartOfToday() time.Time {
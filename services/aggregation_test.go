@@ -0,0 +1,300 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+type AggregationServiceTestSuite struct {
+	suite.Suite
+	TestUser                      *models.User
+	SummaryInvalidationRepository *mocks.SummaryInvalidationRepositoryMock
+	UserService                   *mocks.UserServiceMock
+	SummaryService                *mocks.SummaryServiceMock
+	HeartbeatService              *mocks.HeartbeatServiceMock
+	KeyValueService               *mocks.KeyValueServiceMock
+}
+
+func (suite *AggregationServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: TestUserId, Location: "Pacific/Kiritimati"} // utc+14, i.e. user and server days never align
+}
+
+func (suite *AggregationServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.SummaryInvalidationRepository = new(mocks.SummaryInvalidationRepositoryMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+	suite.KeyValueService.On("PutString", mock.Anything).Return(nil)
+	// heartbeats updated by other tests are published on the shared event bus and mark their days dirty
+	suite.SummaryInvalidationRepository.On("InsertBatch", mock.Anything).Return(nil).Maybe()
+}
+
+func TestAggregationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(AggregationServiceTestSuite))
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_Regenerate() {
+	sut := NewAggregationService(suite.SummaryInvalidationRepository, suite.UserService, suite.SummaryService, suite.HeartbeatService, NewJobService(), suite.KeyValueService)
+
+	isLocalMidnight := mock.MatchedBy(func(t time.Time) bool {
+		return t.Equal(utils.StartOfDay(t.In(time.Local)))
+	})
+	suite.SummaryService.On("Summarize", isLocalMidnight, isLocalMidnight, suite.TestUser, mock.Anything).Return(&models.Summary{UserID: TestUserId}, nil)
+	suite.SummaryService.On("ReplaceWithin", TestUserId, isLocalMidnight, isLocalMidnight, mock.Anything).Return(nil)
+
+	// two days in the user's time zone
+	from := utils.StartOfToday(suite.TestUser.TZ()).AddDate(0, 0, -5)
+	to := from.AddDate(0, 0, 2)
+	suite.HeartbeatService.On("GetFirstByUser", suite.TestUser).Return(&models.Heartbeat{Time: models.CustomTime(from.AddDate(0, 0, -30))}, nil)
+
+	job, err := sut.Regenerate(suite.TestUser, from, to)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), job.From.Equal(utils.StartOfDay(from.In(time.Local))))
+	assert.True(suite.T(), job.To.Equal(utils.CeilDate(to.In(time.Local))))
+	assert.Eventually(suite.T(), func() bool { return sut.GetRegenerationJob(TestUserId).FinishedAt != nil }, time.Second, 10*time.Millisecond)
+
+	finished := sut.GetRegenerationJob(TestUserId)
+	assert.Equal(suite.T(), finished.Total, finished.Processed)
+	assert.Zero(suite.T(), finished.Failed)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Summarize", finished.Total)
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_Regenerate_InvalidRange() {
+	sut := NewAggregationService(suite.SummaryInvalidationRepository, suite.UserService, suite.SummaryService, suite.HeartbeatService, NewJobService(), suite.KeyValueService)
+
+	today := utils.StartOfToday(time.Local)
+	suite.HeartbeatService.On("GetFirstByUser", suite.TestUser).Return(&models.Heartbeat{Time: models.CustomTime(today.AddDate(0, 0, -30))}, nil)
+
+	_, err := sut.Regenerate(suite.TestUser, today, today.AddDate(0, 0, 1)) // today is never regenerated
+	assert.Equal(suite.T(), ErrInvalidRegenerationRange, err)
+
+	_, err = sut.Regenerate(suite.TestUser, today.AddDate(0, 0, -2), today.AddDate(0, 0, -4))
+	assert.Equal(suite.T(), ErrInvalidRegenerationRange, err)
+
+	_, err = sut.Regenerate(suite.TestUser, today.AddDate(0, 0, -40), today.AddDate(0, 0, -35)) // before the first heartbeat
+	assert.Equal(suite.T(), ErrInvalidRegenerationRange, err)
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_Regenerate_FromFirstHeartbeat() {
+	sut := NewAggregationService(suite.SummaryInvalidationRepository, suite.UserService, suite.SummaryService, suite.HeartbeatService, NewJobService(), suite.KeyValueService)
+
+	today := utils.StartOfToday(time.Local)
+	first := today.AddDate(0, 0, -3).Add(2 * time.Hour)
+	suite.HeartbeatService.On("GetFirstByUser", suite.TestUser).Return(&models.Heartbeat{Time: models.CustomTime(first)}, nil)
+	suite.SummaryService.On("Summarize", mock.Anything, mock.Anything, suite.TestUser, mock.Anything).Return(&models.Summary{UserID: TestUserId}, nil)
+	suite.SummaryService.On("ReplaceWithin", TestUserId, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	job, err := sut.Regenerate(suite.TestUser, today.AddDate(-5, 0, 0), today)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), job.From.Equal(today.AddDate(0, 0, -3)))
+	assert.Equal(suite.T(), 3, job.Total)
+	assert.Eventually(suite.T(), func() bool { return sut.GetRegenerationJob(TestUserId).FinishedAt != nil }, time.Second, 10*time.Millisecond)
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_Regenerate_FromRegistration() {
+	sut := NewAggregationService(suite.SummaryInvalidationRepository, suite.UserService, suite.SummaryService, suite.HeartbeatService, NewJobService(), suite.KeyValueService)
+
+	today := utils.StartOfToday(time.Local)
+	user := &models.User{ID: TestUserId, CreatedAt: models.CustomTime(today.AddDate(0, 0, -2))}
+	suite.HeartbeatService.On("GetFirstByUser", user).Return((*models.Heartbeat)(nil), gorm.ErrRecordNotFound)
+	suite.SummaryService.On("Summarize", mock.Anything, mock.Anything, user, mock.Anything).Return(&models.Summary{UserID: TestUserId}, nil)
+	suite.SummaryService.On("ReplaceWithin", TestUserId, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	job, err := sut.Regenerate(user, today.AddDate(0, -1, 0), today)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), job.From.Equal(today.AddDate(0, 0, -2)))
+	assert.Eventually(suite.T(), func() bool { return sut.GetRegenerationJob(TestUserId).FinishedAt != nil }, time.Second, 10*time.Millisecond)
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_Regenerate_InProgress() {
+	sut := NewAggregationService(suite.SummaryInvalidationRepository, suite.UserService, suite.SummaryService, suite.HeartbeatService, NewJobService(), suite.KeyValueService)
+
+	today := utils.StartOfToday(time.Local)
+	suite.HeartbeatService.On("GetFirstByUser", suite.TestUser).Return(&models.Heartbeat{Time: models.CustomTime(today.AddDate(0, 0, -30))}, nil)
+
+	err := sut.WithUserLock(TestUserId, func() error {
+		_, err := sut.Regenerate(suite.TestUser, today.AddDate(0, 0, -2), today)
+		return err
+	})
+	assert.Equal(suite.T(), ErrAggregationInProgress, err)
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_Run() {
+	sut := NewAggregationService(suite.SummaryInvalidationRepository, suite.UserService, suite.SummaryService, suite.HeartbeatService, NewJobService(), suite.KeyValueService)
+
+	today := utils.StartOfToday(time.Local)
+	user1, user2, user3 := &models.User{ID: "user1"}, &models.User{ID: "user2"}, &models.User{ID: "user3"}
+
+	suite.UserService.On("GetAll").Return([]*models.User{user1, user2, user3}, nil)
+	suite.SummaryService.On("GetLatestByUser").Return([]*models.TimeByUser{
+		{User: user1.ID, Time: models.CustomTime(today.AddDate(0, 0, -3).Add(1 * time.Hour))},
+	}, nil)
+	suite.HeartbeatService.On("GetFirstByUsers").Return([]*models.TimeByUser{
+		{User: user1.ID, Time: models.CustomTime(today.AddDate(0, 0, -10))},
+		{User: user2.ID, Time: models.CustomTime(today.AddDate(0, 0, -2).Add(1 * time.Hour))},
+	}, nil)
+	suite.SummaryInvalidationRepository.On("GetAll").Return([]*models.SummaryInvalidation{
+		{ID: 1, UserID: user1.ID, Day: models.CustomTime(today.AddDate(0, 0, -5))},
+	}, nil)
+	suite.SummaryInvalidationRepository.On("DeleteBatchMarkedBefore", []uint{1}, mock.Anything).Return(nil)
+
+	suite.SummaryService.On("Summarize", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&models.Summary{}, nil)
+	suite.SummaryService.On("ReplaceWithin", user1.ID, today.AddDate(0, 0, -5), today.AddDate(0, 0, -4), mock.Anything).Return(nil)
+	suite.SummaryService.On("InsertBatch", mock.Anything).Return(nil)
+
+	err := sut.Run(nil)
+
+	assert.Nil(suite.T(), err)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Summarize", 4) // user 1: dirty day + two days since latest summary, user 2: one day, user 3: none
+	suite.SummaryService.AssertNotCalled(suite.T(), "Summarize", mock.Anything, mock.Anything, user3, mock.Anything)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "ReplaceWithin", 1)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "InsertBatch", 2) // one batch per user
+	suite.SummaryService.AssertNotCalled(suite.T(), "DeleteByUserWithin", mock.Anything, mock.Anything, mock.Anything)
+	suite.SummaryInvalidationRepository.AssertExpectations(suite.T())
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_Run_RecomputeFailed() {
+	sut := NewAggregationService(suite.SummaryInvalidationRepository, suite.UserService, suite.SummaryService, suite.HeartbeatService, NewJobService(), suite.KeyValueService)
+
+	today := utils.StartOfToday(time.Local)
+
+	suite.UserService.On("GetAll").Return([]*models.User{suite.TestUser}, nil)
+	suite.SummaryService.On("GetLatestByUser").Return([]*models.TimeByUser{
+		{User: TestUserId, Time: models.CustomTime(today.Add(-1 * time.Hour))}, // no new days to aggregate
+	}, nil)
+	suite.HeartbeatService.On("GetFirstByUsers").Return([]*models.TimeByUser{}, nil)
+	suite.SummaryInvalidationRepository.On("GetAll").Return([]*models.SummaryInvalidation{
+		{ID: 1, UserID: TestUserId, Day: models.CustomTime(today.AddDate(0, 0, -2))},
+	}, nil)
+	suite.SummaryInvalidationRepository.On("DeleteBatchMarkedBefore", mock.Anything, mock.Anything).Return(nil)
+	suite.SummaryInvalidationRepository.On("InsertBatch", mock.Anything).Return(nil)
+	suite.SummaryService.On("Summarize", mock.Anything, mock.Anything, suite.TestUser, mock.Anything).Return(&models.Summary{}, assert.AnError)
+
+	err := sut.Run(nil)
+
+	// the outdated summary is kept, if the new one couldn't be computed
+	assert.Nil(suite.T(), err)
+	suite.SummaryService.AssertNumberOfCalls(suite.T(), "Summarize", 1)
+	suite.SummaryService.AssertNotCalled(suite.T(), "ReplaceWithin", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.SummaryService.AssertNotCalled(suite.T(), "DeleteByUserWithin", mock.Anything, mock.Anything, mock.Anything)
+
+	// and the day is marked again to be retried during the next run
+	suite.SummaryInvalidationRepository.AssertNumberOfCalls(suite.T(), "InsertBatch", 1)
+	for _, c := range suite.SummaryInvalidationRepository.Calls {
+		if c.Method == "InsertBatch" {
+			days := c.Arguments.Get(0).([]*models.SummaryInvalidation)
+			assert.Len(suite.T(), days, 1)
+			assert.True(suite.T(), days[0].Day.T().Equal(today.AddDate(0, 0, -2)))
+		}
+	}
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_MissedRuns() {
+	cfg := &config.Config{}
+	cfg.App.AggregationTime = "02:15"
+	config.Set(cfg)
+	defer config.Set(&config.Config{})
+
+	latestRun := &models.KeyStringValue{Key: config.KeyLatestAggregation}
+	keyValueService := new(mocks.KeyValueServiceMock)
+	keyValueService.On("MustGetString", config.KeyLatestAggregation).Return(latestRun)
+
+	sut := NewAggregationService(suite.SummaryInvalidationRepository, suite.UserService, suite.SummaryService, suite.HeartbeatService, NewJobService(), keyValueService)
+
+	now := time.Date(2023, 3, 13, 10, 0, 0, 0, time.Local) // monday
+	assert.Equal(suite.T(), time.Date(2023, 3, 13, 2, 15, 0, 0, time.Local), sut.latestScheduledRun(now))
+	assert.Equal(suite.T(), time.Date(2023, 3, 12, 2, 15, 0, 0, time.Local), sut.latestScheduledRun(now.Add(-8*time.Hour)))
+
+	// never run before
+	assert.Zero(suite.T(), sut.missedRuns(now))
+
+	// last run early on friday, server was down over the weekend
+	latestRun.Value = time.Date(2023, 3, 10, 2, 15, 5, 0, time.Local).Format(time.RFC3339)
+	assert.Equal(suite.T(), 3, sut.missedRuns(now))
+
+	// last run this morning
+	latestRun.Value = time.Date(2023, 3, 13, 2, 15, 5, 0, time.Local).Format(time.RFC3339)
+	assert.Zero(suite.T(), sut.missedRuns(now))
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_Run_KeepsRenewedMarkers() {
+	sut := NewAggregationService(suite.SummaryInvalidationRepository, suite.UserService, suite.SummaryService, suite.HeartbeatService, NewJobService(), suite.KeyValueService)
+
+	today := utils.StartOfToday(time.Local)
+	day := today.AddDate(0, 0, -2)
+
+	suite.UserService.On("GetAll").Return([]*models.User{suite.TestUser}, nil)
+	suite.SummaryService.On("GetLatestByUser").Return([]*models.TimeByUser{
+		{User: TestUserId, Time: models.CustomTime(today.Add(-1 * time.Hour))},
+	}, nil)
+	suite.HeartbeatService.On("GetFirstByUsers").Return([]*models.TimeByUser{}, nil)
+	suite.SummaryInvalidationRepository.On("GetAll").Return([]*models.SummaryInvalidation{
+		{ID: 1, UserID: TestUserId, Day: models.CustomTime(day)},
+	}, nil)
+	suite.SummaryInvalidationRepository.On("InsertBatch", mock.Anything).Return(nil)
+	suite.SummaryInvalidationRepository.On("DeleteBatchMarkedBefore", []uint{1}, mock.Anything).Return(nil)
+	suite.SummaryService.On("ReplaceWithin", TestUserId, day, day.AddDate(0, 0, 1), mock.Anything).Return(nil)
+
+	// day was marked before the run already, which is cached
+	sut.markDirty(TestUserId, day.Add(1*time.Hour), day.Add(1*time.Hour))
+	suite.SummaryInvalidationRepository.AssertNumberOfCalls(suite.T(), "InsertBatch", 1)
+
+	// another late heartbeat for the same day arrives while it's being recomputed
+	suite.SummaryService.On("Summarize", mock.Anything, mock.Anything, suite.TestUser, mock.Anything).Run(func(args mock.Arguments) {
+		sut.markDirty(TestUserId, day.Add(2*time.Hour), day.Add(2*time.Hour))
+	}).Return(&models.Summary{}, nil)
+
+	start := time.Now()
+	err := sut.Run(nil)
+
+	assert.Nil(suite.T(), err)
+	suite.SummaryInvalidationRepository.AssertNumberOfCalls(suite.T(), "InsertBatch", 2) // marker was renewed despite the cache
+	deleteCalls := 0
+	for _, c := range suite.SummaryInvalidationRepository.Calls {
+		if c.Method == "DeleteBatchMarkedBefore" {
+			deleteCalls++
+			assert.False(suite.T(), c.Arguments.Get(1).(time.Time).After(start)) // marker renewed during the run is kept
+		}
+	}
+	assert.Equal(suite.T(), 1, deleteCalls)
+}
+
+func (suite *AggregationServiceTestSuite) TestAggregationService_MarkDirty() {
+	sut := NewAggregationService(suite.SummaryInvalidationRepository, suite.UserService, suite.SummaryService, suite.HeartbeatService, NewJobService(), suite.KeyValueService)
+
+	today := utils.StartOfToday(time.Local)
+
+	suite.SummaryInvalidationRepository.On("InsertBatch", mock.Anything).Return(nil)
+
+	// late heartbeat shortly before server-local midnight, which is a different day in the user's time zone
+	sut.markDirty(TestUserId, today.Add(-1*time.Minute), today.Add(-1*time.Minute))
+	// heartbeat from today
+	sut.markDirty(TestUserId, today.Add(1*time.Minute), today.Add(1*time.Minute))
+	// heartbeats deleted from the past three days
+	sut.markDirty(TestUserId, today.AddDate(0, 0, -3).Add(1*time.Hour), today.Add(1*time.Hour))
+
+	calls := suite.SummaryInvalidationRepository.Calls
+	assert.Len(suite.T(), calls, 2) // nothing to mark for today
+
+	days1 := calls[0].Arguments.Get(0).([]*models.SummaryInvalidation)
+	assert.Len(suite.T(), days1, 1)
+	assert.True(suite.T(), days1[0].Day.T().Equal(today.AddDate(0, 0, -1)))
+
+	days3 := calls[1].Arguments.Get(0).([]*models.SummaryInvalidation)
+	assert.Len(suite.T(), days3, 2) // yesterday is cached already
+	assert.True(suite.T(), days3[0].Day.T().Equal(today.AddDate(0, 0, -3)))
+	assert.True(suite.T(), days3[1].Day.T().Equal(today.AddDate(0, 0, -2)))
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/repositories"
@@ -12,12 +13,14 @@ import (
 
 type AliasService struct {
 	config     *config.Config
+	eventBus   *hub.Hub
 	repository repositories.IAliasRepository
 }
 
 func NewAliasService(aliasRepo repositories.IAliasRepository) *AliasService {
 	return &AliasService{
 		config:     config.Get(),
+		eventBus:   config.EventBus(),
 		repository: aliasRepo,
 	}
 }
@@ -96,6 +99,7 @@ func (srv *AliasService) Create(alias *models.Alias) (*models.Alias, error) {
 	// reload entire cache (async, though)
 	go srv.MayInitializeUser(alias.UserID)
 
+	srv.notifyUpdate(alias, false)
 	return result, nil
 }
 
@@ -112,6 +116,9 @@ func (srv *AliasService) Delete(alias *models.Alias) error {
 	// reload entire cache (async, though)
 	go srv.MayInitializeUser(alias.UserID)
 
+	if err == nil {
+		srv.notifyUpdate(alias, true)
+	}
 	return err
 }
 
@@ -139,9 +146,25 @@ func (srv *AliasService) DeleteMulti(aliases []*models.Alias) error {
 		go srv.MayInitializeUser(k)
 	}
 
+	if err == nil {
+		for _, a := range aliases {
+			srv.notifyUpdate(a, true)
+		}
+	}
 	return err
 }
 
+func (srv *AliasService) notifyUpdate(alias *models.Alias, isDelete bool) {
+	name := config.EventAliasCreate
+	if isDelete {
+		name = config.EventAliasDelete
+	}
+	srv.eventBus.Publish(hub.Message{
+		Name:   name,
+		Fields: map[string]interface{}{config.FieldPayload: alias, config.FieldUserId: alias.UserID},
+	})
+}
+
 func (srv *AliasService) updateCache(reason *models.Alias, removal bool) {
 	if !removal {
 		if aliases, ok := userAliases.Load(reason.UserID); ok {
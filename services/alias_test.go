@@ -1,12 +1,14 @@
 package services
 
 import (
+	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/mocks"
 	"github.com/muety/wakapi/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"testing"
+	"time"
 )
 
 type AliasServiceTestSuite struct {
@@ -52,3 +54,33 @@ func (suite *AliasServiceTestSuite) TestAliasService_GetAliasOrDefault() {
 	assert.Equal(suite.T(), "anchr", result3)
 	assert.Nil(suite.T(), err3)
 }
+
+func (suite *AliasServiceTestSuite) TestAliasService_Delete_PublishesOnSuccessOnly() {
+	sut := NewAliasService(suite.AliasRepository)
+
+	suite.AliasRepository.On("Delete", uint(1)).Return(nil)
+	suite.AliasRepository.On("Delete", uint(2)).Return(assert.AnError)
+
+	sub := config.EventBus().Subscribe(1, config.EventAliasDelete)
+	defer config.EventBus().Unsubscribe(sub)
+
+	err1 := sut.Delete(&models.Alias{ID: 1, UserID: suite.TestUserId, Type: models.SummaryProject, Key: "wakapi", Value: "wakapi-mobile"})
+	assert.Nil(suite.T(), err1)
+
+	select {
+	case m := <-sub.Receiver:
+		assert.Equal(suite.T(), suite.TestUserId, m.Fields[config.FieldUserId])
+		assert.Equal(suite.T(), uint(1), m.Fields[config.FieldPayload].(*models.Alias).ID)
+	case <-time.After(time.Second):
+		suite.Fail("no event published after deletion")
+	}
+
+	err2 := sut.Delete(&models.Alias{ID: 2, UserID: suite.TestUserId, Type: models.SummaryProject, Key: "wakapi", Value: "wakapi-mobile"})
+	assert.Error(suite.T(), err2)
+
+	select {
+	case <-sub.Receiver:
+		suite.Fail("event published despite failed deletion")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
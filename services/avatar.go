@@ -0,0 +1,128 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	AvatarMaxUploadSize = 2 * 1024 * 1024
+	avatarMaxDimension  = 4096 // refuse to decode larger images, which might exhaust memory
+	avatarSize          = 256
+)
+
+var ErrInvalidAvatar = errors.New("invalid image, must be a png, jpeg or gif file of at most 2 mb and 4096 x 4096 pixels")
+
+var avatarNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-][a-zA-Z0-9_.-]*$`)
+
+type AvatarService struct {
+	config         *config.Config
+	eventBus       *hub.Hub
+	userService    IUserService
+	storageService IStorageService
+}
+
+func NewAvatarService(userService IUserService, storageService IStorageService) *AvatarService {
+	srv := &AvatarService{
+		config:         config.Get(),
+		eventBus:       config.EventBus(),
+		userService:    userService,
+		storageService: storageService,
+	}
+
+	sub := srv.eventBus.Subscribe(0, config.EventUserDelete)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			user := m.Fields[config.FieldPayload].(*models.User)
+			if user.AvatarKey == "" {
+				continue
+			}
+			if err := srv.storageService.Delete(user.AvatarKey); err != nil {
+				config.Log().Error("failed to delete avatar of deleted user '%s' - %v", user.ID, err)
+			}
+		}
+	}(&sub)
+
+	return srv
+}
+
+// Upload crops and scales down the given image, stores it as the user's avatar and replaces a previously uploaded one
+func (srv *AvatarService) Upload(user *models.User, data io.Reader) (*models.User, error) {
+	raw, err := ioutil.ReadAll(io.LimitReader(data, AvatarMaxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > AvatarMaxUploadSize {
+		return nil, ErrInvalidAvatar
+	}
+
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(raw)); err != nil || cfg.Width > avatarMaxDimension || cfg.Height > avatarMaxDimension {
+		return nil, ErrInvalidAvatar
+	}
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrInvalidAvatar
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, utils.Resize(utils.CropSquare(img), avatarSize, avatarSize)); err != nil {
+		return nil, err
+	}
+
+	// keys are random, so that avatar urls neither reveal user names nor keep showing a cached previous avatar
+	key := fmt.Sprintf("%s%s.png", models.AvatarKeyPrefix, uuid.NewV4().String())
+	if err := srv.storageService.Put(key, &buf, int64(buf.Len()), "image/png"); err != nil {
+		return nil, err
+	}
+
+	previousKey := user.AvatarKey
+	user.AvatarKey = key
+	if _, err := srv.userService.Update(user); err != nil {
+		return nil, err
+	}
+
+	if previousKey != "" {
+		if err := srv.storageService.Delete(previousKey); err != nil {
+			logbuch.Warn("failed to delete previous avatar of user '%s' - %v", user.ID, err)
+		}
+	}
+
+	return user, nil
+}
+
+// Remove deletes the user's uploaded avatar, so that the configured default is shown again
+func (srv *AvatarService) Remove(user *models.User) (*models.User, error) {
+	if user.AvatarKey == "" {
+		return user, nil
+	}
+
+	key := user.AvatarKey
+	user.AvatarKey = ""
+	if _, err := srv.userService.Update(user); err != nil {
+		return nil, err
+	}
+	return user, srv.storageService.Delete(key)
+}
+
+// Open returns the uploaded avatar image with the given name, as contained in the user's avatar url
+func (srv *AvatarService) Open(name string) (io.ReadCloser, error) {
+	if !avatarNameRegex.MatchString(name) {
+		return nil, errors.New("invalid avatar name")
+	}
+	return srv.storageService.Open(models.AvatarKeyPrefix + name + ".png")
+}
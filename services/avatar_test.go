@@ -0,0 +1,130 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type AvatarServiceTestSuite struct {
+	suite.Suite
+	UserService    *mocks.UserServiceMock
+	StorageService *mocks.StorageServiceMock
+}
+
+func (suite *AvatarServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+}
+
+func (suite *AvatarServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.StorageService = new(mocks.StorageServiceMock)
+}
+
+func TestAvatarServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(AvatarServiceTestSuite))
+}
+
+func (suite *AvatarServiceTestSuite) TestAvatarService_Upload() {
+	sut := NewAvatarService(suite.UserService, suite.StorageService)
+	user := &models.User{ID: "johndoe", AvatarKey: "avatars/previous.png"}
+
+	var stored []byte
+	suite.StorageService.On("Put", mock.Anything, mock.Anything, mock.Anything, "image/png").Run(func(args mock.Arguments) {
+		stored, _ = ioutil.ReadAll(args.Get(1).(io.Reader))
+	}).Return(nil)
+	suite.StorageService.On("Delete", "avatars/previous.png").Return(nil)
+	suite.UserService.On("Update", user).Return(user, nil)
+
+	result, err := sut.Upload(user, bytes.NewReader(encodeTestPng(600, 400)))
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), strings.HasPrefix(result.AvatarKey, models.AvatarKeyPrefix))
+	assert.NotContains(suite.T(), result.AvatarKey, user.ID)
+	assert.NotEqual(suite.T(), "avatars/previous.png", result.AvatarKey)
+	suite.StorageService.AssertCalled(suite.T(), "Put", result.AvatarKey, mock.Anything, mock.Anything, "image/png")
+	suite.StorageService.AssertCalled(suite.T(), "Delete", "avatars/previous.png")
+
+	img, err := png.Decode(bytes.NewReader(stored))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), avatarSize, img.Bounds().Dx())
+	assert.Equal(suite.T(), avatarSize, img.Bounds().Dy())
+}
+
+func (suite *AvatarServiceTestSuite) TestAvatarService_Upload_Oversized() {
+	sut := NewAvatarService(suite.UserService, suite.StorageService)
+	user := &models.User{ID: "johndoe"}
+
+	// valid png header, followed by more than the allowed number of bytes
+	data := append(encodeTestPng(10, 10), make([]byte, AvatarMaxUploadSize)...)
+	result, err := sut.Upload(user, bytes.NewReader(data))
+
+	assert.Nil(suite.T(), result)
+	assert.Equal(suite.T(), ErrInvalidAvatar, err)
+	suite.StorageService.AssertNotCalled(suite.T(), "Put", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.UserService.AssertNotCalled(suite.T(), "Update", mock.Anything)
+}
+
+func (suite *AvatarServiceTestSuite) TestAvatarService_Upload_TooLargeDimensions() {
+	sut := NewAvatarService(suite.UserService, suite.StorageService)
+	user := &models.User{ID: "johndoe"}
+
+	result, err := sut.Upload(user, bytes.NewReader(encodeTestPng(avatarMaxDimension+1, 1)))
+
+	assert.Nil(suite.T(), result)
+	assert.Equal(suite.T(), ErrInvalidAvatar, err)
+	suite.StorageService.AssertNotCalled(suite.T(), "Put", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AvatarServiceTestSuite) TestAvatarService_Upload_NoImage() {
+	sut := NewAvatarService(suite.UserService, suite.StorageService)
+	user := &models.User{ID: "johndoe"}
+
+	for _, data := range [][]byte{
+		[]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"),
+		[]byte("#!/bin/sh\necho hello"),
+		encodeTestPng(10, 10)[:32], // truncated
+		{},
+	} {
+		result, err := sut.Upload(user, bytes.NewReader(data))
+		assert.Nil(suite.T(), result)
+		assert.Equal(suite.T(), ErrInvalidAvatar, err)
+	}
+	suite.StorageService.AssertNotCalled(suite.T(), "Put", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *AvatarServiceTestSuite) TestAvatarService_Open() {
+	sut := NewAvatarService(suite.UserService, suite.StorageService)
+	suite.StorageService.On("Open", "avatars/6ba7b810-9dad-11d1-80b4-00c04fd430c8.png").Return(ioutil.NopCloser(strings.NewReader("")), nil)
+
+	_, err := sut.Open("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	assert.Nil(suite.T(), err)
+
+	for _, name := range []string{"", "../users/johndoe", "..", ".hidden", "a/b"} {
+		_, err := sut.Open(name)
+		assert.NotNil(suite.T(), err, name)
+	}
+	suite.StorageService.AssertNumberOfCalls(suite.T(), "Open", 1)
+}
+
+func encodeTestPng(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width && x < 100; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-co-op/gocron"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+const backupPrefix = "backups/"
+
+type BackupService struct {
+	config         *config.Config
+	repository     repositories.IBackupRepository
+	storageService IStorageService
+	jobService     IJobService
+}
+
+func NewBackupService(backupRepo repositories.IBackupRepository, storageService IStorageService, jobService IJobService) *BackupService {
+	return &BackupService{
+		config:         config.Get(),
+		repository:     backupRepo,
+		storageService: storageService,
+		jobService:     jobService,
+	}
+}
+
+// Schedule creates a daily database backup in the configured storage and deletes backups older than the retention period
+func (srv *BackupService) Schedule() {
+	if !srv.config.Storage.Backups {
+		return
+	}
+
+	logbuch.Info("scheduling daily database backups")
+
+	s := gocron.NewScheduler(time.Local)
+	s.Every(1).Day().At("03:00").Do(srv.Run)
+	s.StartBlocking()
+}
+
+func (srv *BackupService) Run() error {
+	return srv.jobService.Track(models.JobBackup, "", srv.run)
+}
+
+func (srv *BackupService) run() error {
+	dir, err := ioutil.TempDir("", "wakapi-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	// the dump must go to a file that does not exist yet
+	file := filepath.Join(dir, "wakapi.db")
+	if err := srv.repository.DumpTo(file); err != nil {
+		return err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%swakapi-%s.db", backupPrefix, time.Now().Format("20060102-150405"))
+	if err := srv.storageService.Put(key, f, info.Size(), "application/vnd.sqlite3"); err != nil {
+		return err
+	}
+	logbuch.Info("created database backup '%s' (%d bytes)", key, info.Size())
+
+	n, err := srv.storageService.DeleteBefore(backupPrefix, time.Now().Add(-srv.config.Storage.GetBackupRetention()))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		logbuch.Info("deleted %d outdated database backups", n)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	// skews below this are not worth correcting, as they hardly affect durations (cf. heartbeat timeout)
+	clockSkewThreshold = 2 * time.Minute
+	// number of requests a machine must have sent, before its skew is considered systematic
+	clockSkewMinSamples = 10
+	clockSkewMaxSamples = 50
+)
+
+// ClockSkewService detects machines with misconfigured clocks and optionally corrects the timestamps of heartbeats sent from them.
+// A request's freshest heartbeat can never be older than the request itself, except for the machine's clock being behind, nor dated in the future, except for the clock being ahead.
+// Therefore, the smallest delay between a heartbeat's timestamp and its arrival across a machine's recent requests is taken as the skew of its clock, as network latency and offline queues only ever increase the delay.
+type ClockSkewService struct {
+	lock    sync.Mutex
+	samples *cache.Cache // user id -> machine -> delays of recent requests
+}
+
+type clockSkewSamples struct {
+	delays    []time.Duration
+	updatedAt time.Time
+}
+
+func NewClockSkewService() *ClockSkewService {
+	return &ClockSkewService{
+		samples: cache.New(7*24*time.Hour, 1*time.Hour),
+	}
+}
+
+// Record remembers the delay of the freshest of the given heartbeats, which were received at the given time from the given machine
+func (srv *ClockSkewService) Record(user *models.User, machine string, heartbeats []*models.Heartbeat, receivedAt time.Time) {
+	var latest time.Time
+	for _, hb := range heartbeats {
+		if t := hb.Time.T(); t.After(latest) {
+			latest = t
+		}
+	}
+	if latest.IsZero() {
+		return
+	}
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	machines := srv.getMachines(user)
+	entry, ok := machines[machine]
+	if !ok {
+		entry = &clockSkewSamples{}
+		machines[machine] = entry
+	}
+
+	entry.delays = append(entry.delays, receivedAt.Sub(latest))
+	if len(entry.delays) > clockSkewMaxSamples {
+		entry.delays = entry.delays[len(entry.delays)-clockSkewMaxSamples:]
+	}
+	entry.updatedAt = receivedAt
+
+	srv.samples.SetDefault(user.ID, machines)
+}
+
+// Get returns the skew of the given machine's clock or nil, if there is none or not enough data to tell
+func (srv *ClockSkewService) Get(user *models.User, machine string) *models.ClockSkew {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if entry, ok := srv.getMachines(user)[machine]; ok {
+		return estimateClockSkew(machine, entry)
+	}
+	return nil
+}
+
+// GetByUser returns the skews of all of the user's machines, whose clocks deviate from the server's one
+func (srv *ClockSkewService) GetByUser(user *models.User) []*models.ClockSkew {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	skews := make([]*models.ClockSkew, 0)
+	for machine, entry := range srv.getMachines(user) {
+		if skew := estimateClockSkew(machine, entry); skew != nil {
+			skews = append(skews, skew)
+		}
+	}
+	sort.Slice(skews, func(i, j int) bool {
+		return skews[i].Machine < skews[j].Machine
+	})
+	return skews
+}
+
+// Correct shifts the timestamps of the given heartbeats by the skew of the machine they were sent from, if the user opted in to correction
+func (srv *ClockSkewService) Correct(user *models.User, machine string, heartbeats []*models.Heartbeat) {
+	if !user.ClockSkewCorrection {
+		return
+	}
+
+	skew := srv.Get(user, machine)
+	if skew == nil {
+		return
+	}
+
+	for _, hb := range heartbeats {
+		hb.Time = models.CustomTime(hb.Time.T().Add(-skew.Offset))
+	}
+}
+
+func (srv *ClockSkewService) getMachines(user *models.User) map[string]*clockSkewSamples {
+	if machines, ok := srv.samples.Get(user.ID); ok {
+		return machines.(map[string]*clockSkewSamples)
+	}
+	return map[string]*clockSkewSamples{}
+}
+
+func estimateClockSkew(machine string, entry *clockSkewSamples) *models.ClockSkew {
+	if len(entry.delays) < clockSkewMinSamples {
+		return nil
+	}
+
+	minDelay := entry.delays[0]
+	for _, d := range entry.delays[1:] {
+		if d < minDelay {
+			minDelay = d
+		}
+	}
+
+	offset := -minDelay.Round(time.Second)
+	if offset > -clockSkewThreshold && offset < clockSkewThreshold {
+		return nil
+	}
+
+	return &models.ClockSkew{
+		Machine:   machine,
+		Offset:    offset,
+		Samples:   len(entry.delays),
+		UpdatedAt: entry.updatedAt,
+	}
+}
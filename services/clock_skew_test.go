@@ -0,0 +1,79 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClockSkewServiceTestSuite struct {
+	suite.Suite
+	TestUser *models.User
+	Now      time.Time
+}
+
+func (suite *ClockSkewServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: "user1"}
+	suite.Now = time.Date(2022, 11, 10, 10, 0, 0, 0, time.UTC)
+}
+
+func TestClockSkewServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ClockSkewServiceTestSuite))
+}
+
+func (suite *ClockSkewServiceTestSuite) TestClockSkewService_Get_Ahead() {
+	sut := NewClockSkewService()
+
+	for i := 0; i < clockSkewMinSamples; i++ {
+		received := suite.Now.Add(time.Duration(i) * time.Minute)
+		latency := time.Duration(i%3) * time.Second
+		sut.Record(suite.TestUser, "laptop", []*models.Heartbeat{
+			{Time: models.CustomTime(received.Add(10*time.Minute - latency - 5*time.Minute))}, // queued
+			{Time: models.CustomTime(received.Add(10*time.Minute - latency))},
+		}, received)
+
+		if i < clockSkewMinSamples-1 {
+			assert.Nil(suite.T(), sut.Get(suite.TestUser, "laptop"))
+		}
+	}
+
+	result := sut.Get(suite.TestUser, "laptop")
+	assert.NotNil(suite.T(), result)
+	assert.Equal(suite.T(), 10*time.Minute, result.Offset)
+	assert.True(suite.T(), result.IsAhead())
+	assert.Nil(suite.T(), sut.Get(suite.TestUser, "desktop"))
+}
+
+func (suite *ClockSkewServiceTestSuite) TestClockSkewService_Get_BelowThreshold() {
+	sut := NewClockSkewService()
+
+	for i := 0; i < clockSkewMinSamples; i++ {
+		received := suite.Now.Add(time.Duration(i) * time.Minute)
+		sut.Record(suite.TestUser, "laptop", []*models.Heartbeat{{Time: models.CustomTime(received.Add(-30 * time.Second))}}, received)
+	}
+
+	assert.Nil(suite.T(), sut.Get(suite.TestUser, "laptop"))
+	assert.Empty(suite.T(), sut.GetByUser(suite.TestUser))
+}
+
+func (suite *ClockSkewServiceTestSuite) TestClockSkewService_Correct() {
+	sut := NewClockSkewService()
+	user := &models.User{ID: "user2", ClockSkewCorrection: true}
+
+	for i := 0; i < clockSkewMinSamples; i++ {
+		received := suite.Now.Add(time.Duration(i) * time.Minute)
+		sut.Record(user, "desktop", []*models.Heartbeat{{Time: models.CustomTime(received.Add(-1 * time.Hour))}}, received)
+	}
+
+	heartbeats := []*models.Heartbeat{{Time: models.CustomTime(suite.Now.Add(-1 * time.Hour))}}
+	sut.Correct(suite.TestUser, "desktop", heartbeats) // no correction for user without opt-in
+	assert.Equal(suite.T(), suite.Now.Add(-1*time.Hour), heartbeats[0].Time.T())
+
+	sut.Correct(user, "desktop", heartbeats)
+	assert.Equal(suite.T(), suite.Now, heartbeats[0].Time.T())
+	assert.Len(suite.T(), sut.GetByUser(user), 1)
+	assert.False(suite.T(), sut.GetByUser(user)[0].IsAhead())
+}
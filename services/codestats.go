@@ -0,0 +1,200 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-co-op/gocron"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	codeStatsSyncTime = "04:30" // after summaries were generated
+	codeStatsMaxDays  = 6       // code::stats rejects pulses older than a week
+)
+
+var ErrCodeStatsNotConfigured = errors.New("code::stats api token is not set")
+
+// CodeStatsService pushes every user's daily xp, derived from their coding time per language, to code::stats as one pulse per day.
+// As pulses can't be changed once sent, a day is only pushed once it's over and only days since the export was enabled are pushed,
+// while earlier ones are left to be imported from code::stats (see imports.CodeStatsHeartbeatImporter).
+type CodeStatsService struct {
+	config          *config.Config
+	userService     IUserService
+	summaryService  ISummaryService
+	keyValueService IKeyValueService
+	jobService      IJobService
+	httpClient      *http.Client
+	baseUrl         string
+}
+
+func NewCodeStatsService(userService IUserService, summaryService ISummaryService, keyValueService IKeyValueService, jobService IJobService) *CodeStatsService {
+	return &CodeStatsService{
+		config:          config.Get(),
+		userService:     userService,
+		summaryService:  summaryService,
+		keyValueService: keyValueService,
+		jobService:      jobService,
+		httpClient:      config.NewHttpClient(config.ProxyScopeIntegrations, 10*time.Second),
+		baseUrl:         config.CodeStatsUrl,
+	}
+}
+
+// Schedule daily pushes the xp of the past days to code::stats for every user, who has set a code::stats api token
+func (srv *CodeStatsService) Schedule() {
+	logbuch.Info("scheduling code::stats xp export")
+
+	s := gocron.NewScheduler(time.Local)
+	s.Every(1).Day().At(codeStatsSyncTime).Do(srv.syncAll)
+	s.StartBlocking()
+}
+
+func (srv *CodeStatsService) syncAll() {
+	users, err := srv.userService.GetAll()
+	if err != nil {
+		config.Log().Error("failed to fetch users for code::stats export - %v", err)
+		return
+	}
+
+	for _, u := range users {
+		if !u.HasCodeStatsExport() {
+			continue
+		}
+		if err := srv.Run(u); err != nil {
+			config.Log().Error("failed to export xp of user '%s' to code::stats - %v", u.ID, err)
+		}
+	}
+}
+
+// Run pushes the user's xp to code::stats as a tracked background job
+func (srv *CodeStatsService) Run(user *models.User) error {
+	return srv.jobService.Track(models.JobCodeStatsSync, user.ID, func() error {
+		_, err := srv.Export(user)
+		return err
+	})
+}
+
+// GetExport returns the user's export progress or nil, if the export was never enabled
+func (srv *CodeStatsService) GetExport(user *models.User) (*models.CodeStatsExport, error) {
+	kv := srv.keyValueService.MustGetString(codeStatsExportKey(user))
+	if kv.Value == "" {
+		return nil, nil
+	}
+	var export models.CodeStatsExport
+	if err := json.Unmarshal([]byte(kv.Value), &export); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// StartExport (re-)starts the user's export as of today, which is pushed tomorrow first
+func (srv *CodeStatsService) StartExport(user *models.User) error {
+	today := time.Now().In(user.TZ()).Format(config.SimpleDateFormat)
+	return srv.saveExport(user, &models.CodeStatsExport{Since: today})
+}
+
+// Export pushes a pulse for every day (excluding today) since the last one pushed and returns the number of pulses sent
+func (srv *CodeStatsService) Export(user *models.User) (int, error) {
+	if !user.HasCodeStatsExport() {
+		return 0, ErrCodeStatsNotConfigured
+	}
+
+	export, err := srv.GetExport(user)
+	if err != nil {
+		return 0, err
+	}
+	if export == nil {
+		return 0, srv.StartExport(user)
+	}
+
+	today := utils.StartOfDay(time.Now().In(user.TZ()))
+	from, err := time.ParseInLocation(config.SimpleDateFormat, export.Since, user.TZ())
+	if err != nil {
+		return 0, err
+	}
+	if last, err := time.ParseInLocation(config.SimpleDateFormat, export.LastDate, user.TZ()); err == nil && !last.Before(from) {
+		from = last.AddDate(0, 0, 1)
+	}
+	if min := today.AddDate(0, 0, -codeStatsMaxDays); from.Before(min) {
+		from = min
+	}
+
+	var pushed int
+	for day := from; day.Before(today); day = day.AddDate(0, 0, 1) {
+		summary, err := srv.summaryService.Aliased(day, day.AddDate(0, 0, 1), user, srv.summaryService.Retrieve, nil, false)
+		if err != nil {
+			return pushed, err
+		}
+
+		pulse := &models.CodeStatsPulse{CodedAt: day.AddDate(0, 0, 1).Add(-time.Minute), Xps: []*models.CodeStatsXp{}}
+		for _, item := range summary.Languages {
+			if xp := models.DurationToXp(item.TotalFixed()); xp > 0 {
+				pulse.Xps = append(pulse.Xps, &models.CodeStatsXp{Language: models.LanguageToCodeStats(item.Key), Xp: xp})
+			}
+		}
+		if len(pulse.Xps) > 0 {
+			if err := srv.push(user, pulse); err != nil {
+				return pushed, err
+			}
+			pushed++
+		}
+
+		export.LastDate = day.Format(config.SimpleDateFormat)
+		if err := srv.saveExport(user, export); err != nil {
+			return pushed, err
+		}
+	}
+
+	if pushed > 0 {
+		logbuch.Info("pushed %d pulses of user '%s' to code::stats", pushed, user.ID)
+	}
+	return pushed, nil
+}
+
+func (srv *CodeStatsService) push(user *models.User, pulse *models.CodeStatsPulse) error {
+	payload, err := json.Marshal(pulse)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, srv.baseUrl+config.CodeStatsApiPulsesUrl, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Token", user.CodeStatsApiToken)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := srv.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("code::stats responded with status %d - %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (srv *CodeStatsService) saveExport(user *models.User, export *models.CodeStatsExport) error {
+	data, err := json.Marshal(export)
+	if err != nil {
+		return err
+	}
+	return srv.keyValueService.PutString(&models.KeyStringValue{Key: codeStatsExportKey(user), Value: string(data)})
+}
+
+func codeStatsExportKey(user *models.User) string {
+	return fmt.Sprintf("%s_%s", config.KeyCodeStatsExport, user.ID)
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type CodeStatsServiceTestSuite struct {
+	suite.Suite
+	TestUser        *models.User
+	Today           time.Time
+	Server          *httptest.Server
+	Pulses          []*models.CodeStatsPulse
+	State           *models.KeyStringValue
+	UserService     *mocks.UserServiceMock
+	SummaryService  *mocks.SummaryServiceMock
+	KeyValueService *mocks.KeyValueServiceMock
+}
+
+func (suite *CodeStatsServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+
+	suite.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != config.CodeStatsApiPulsesUrl || r.Header.Get("X-API-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var pulse models.CodeStatsPulse
+		json.NewDecoder(r.Body).Decode(&pulse)
+		suite.Pulses = append(suite.Pulses, &pulse)
+		w.WriteHeader(http.StatusCreated)
+	}))
+}
+
+func (suite *CodeStatsServiceTestSuite) TearDownSuite() {
+	suite.Server.Close()
+}
+
+func (suite *CodeStatsServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUser = &models.User{ID: TestUserId, CodeStatsUsername: "john", CodeStatsApiToken: "secret"}
+	suite.Today = utils.StartOfDay(time.Now().In(suite.TestUser.TZ()))
+	suite.Pulses = nil
+	suite.State = &models.KeyStringValue{Key: "codestats_export_" + TestUserId}
+
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+	suite.KeyValueService.On("MustGetString", "codestats_export_"+TestUserId).Return(suite.State)
+	suite.KeyValueService.On("PutString", mock.Anything).Run(func(args mock.Arguments) {
+		suite.State.Value = args.Get(0).(*models.KeyStringValue).Value
+	}).Return(nil)
+}
+
+func TestCodeStatsServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(CodeStatsServiceTestSuite))
+}
+
+func (suite *CodeStatsServiceTestSuite) TestCodeStatsService_Export() {
+	yesterday, dayBefore := suite.Today.AddDate(0, 0, -1), suite.Today.AddDate(0, 0, -2)
+	suite.SummaryService.On("Aliased", dayBefore, yesterday, suite.TestUser, mock.Anything, (*models.Filters)(nil), false).Return(&models.Summary{}, nil)
+	suite.SummaryService.On("Aliased", yesterday, suite.Today, suite.TestUser, mock.Anything, (*models.Filters)(nil), false).Return(&models.Summary{
+		Languages: []*models.SummaryItem{
+			{Type: models.SummaryLanguage, Key: "Go", Total: 3600},
+			{Type: models.SummaryLanguage, Key: "Text", Total: 90},
+			{Type: models.SummaryLanguage, Key: "Markdown", Total: 0},
+		},
+	}, nil)
+
+	sut := NewCodeStatsService(suite.UserService, suite.SummaryService, suite.KeyValueService, nil)
+	sut.baseUrl = suite.Server.URL
+	sut.saveExport(suite.TestUser, &models.CodeStatsExport{Since: dayBefore.Format(config.SimpleDateFormat)})
+
+	pushed, err := sut.Export(suite.TestUser)
+
+	// days without coding time are skipped, but considered exported
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, pushed)
+	assert.Len(suite.T(), suite.Pulses, 1)
+	assert.Len(suite.T(), suite.Pulses[0].Xps, 2)
+	assert.Equal(suite.T(), &models.CodeStatsXp{Language: "Go", Xp: 3600}, suite.Pulses[0].Xps[0])
+	assert.Equal(suite.T(), &models.CodeStatsXp{Language: "Plain text", Xp: 90}, suite.Pulses[0].Xps[1])
+	assert.True(suite.T(), suite.Pulses[0].CodedAt.After(yesterday) && suite.Pulses[0].CodedAt.Before(suite.Today))
+
+	export, _ := sut.GetExport(suite.TestUser)
+	assert.Equal(suite.T(), yesterday.Format(config.SimpleDateFormat), export.LastDate)
+
+	// every day is only pushed once
+	pushed, err = sut.Export(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Zero(suite.T(), pushed)
+	assert.Len(suite.T(), suite.Pulses, 1)
+}
+
+func (suite *CodeStatsServiceTestSuite) TestCodeStatsService_Export_Start() {
+	sut := NewCodeStatsService(suite.UserService, suite.SummaryService, suite.KeyValueService, nil)
+	sut.baseUrl = suite.Server.URL
+
+	// the export starts with today, once it's over
+	pushed, err := sut.Export(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Zero(suite.T(), pushed)
+	suite.SummaryService.AssertNotCalled(suite.T(), "Aliased", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	export, _ := sut.GetExport(suite.TestUser)
+	assert.Equal(suite.T(), suite.Today.Format(config.SimpleDateFormat), export.Since)
+	assert.Empty(suite.T(), export.LastDate)
+
+	suite.TestUser.CodeStatsApiToken = ""
+	_, err = sut.Export(suite.TestUser)
+	assert.Equal(suite.T(), ErrCodeStatsNotConfigured, err)
+}
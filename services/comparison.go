@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+var (
+	ErrNoComparisonUsers      = errors.New("no users to compare")
+	ErrTooManyComparisonUsers = errors.New(fmt.Sprintf("at most %d users can be compared at once", models.MaxComparisonUsers))
+)
+
+// ComparisonNotAllowedError is returned for users, who do not exist or have not opted in to be compared with others.
+// Both cases are indistinguishable on purpose, so that user names can't be probed for.
+type ComparisonNotAllowedError struct {
+	UserID string
+}
+
+func (e *ComparisonNotAllowedError) Error() string {
+	return fmt.Sprintf("user '%s' does not exist or does not allow comparisons", e.UserID)
+}
+
+// ComparisonService puts summaries of multiple users side by side. Users need to opt in to be compared with others in their settings,
+// except for the requesting user itself.
+type ComparisonService struct {
+	config         *config.Config
+	userService    IUserService
+	summaryService ISummaryService
+}
+
+func NewComparisonService(userService IUserService, summaryService ISummaryService) *ComparisonService {
+	return &ComparisonService{
+		config:         config.Get(),
+		userService:    userService,
+		summaryService: summaryService,
+	}
+}
+
+// Compare retrieves the summaries of all given users for the given range on behalf of the requesting user
+func (srv *ComparisonService) Compare(userIds []string, from, to time.Time, requestingUser *models.User) (*models.Comparison, error) {
+	userIds = uniqueUserIds(userIds)
+	if len(userIds) == 0 {
+		return nil, ErrNoComparisonUsers
+	}
+	if len(userIds) > models.MaxComparisonUsers {
+		return nil, ErrTooManyComparisonUsers
+	}
+
+	users := make([]*models.User, len(userIds))
+	for i, id := range userIds {
+		if id == requestingUser.ID {
+			users[i] = requestingUser
+			continue
+		}
+		user, err := srv.userService.GetUserById(id)
+		if err != nil || !user.AllowComparison || user.Deactivated {
+			return nil, &ComparisonNotAllowedError{UserID: id}
+		}
+		users[i] = user
+	}
+
+	summaries := make([]*models.Summary, len(users))
+	for i, user := range users {
+		summary, err := srv.summaryService.Aliased(from, to, user, srv.summaryService.Retrieve, nil, false)
+		if err != nil {
+			return nil, err
+		}
+		summaries[i] = summary
+	}
+
+	return models.NewComparison(from, to, users, summaries), nil
+}
+
+func uniqueUserIds(userIds []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(userIds))
+	for _, id := range userIds {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
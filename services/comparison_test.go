@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ComparisonServiceTestSuite struct {
+	suite.Suite
+	TestUsers      []*models.User
+	UserService    *mocks.UserServiceMock
+	SummaryService *mocks.SummaryServiceMock
+}
+
+func (suite *ComparisonServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+}
+
+func (suite *ComparisonServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUsers = []*models.User{
+		{ID: "mentor"},
+		{ID: "student1", AllowComparison: true},
+		{ID: "student2", AllowComparison: false},
+	}
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+
+	for _, u := range suite.TestUsers {
+		suite.UserService.On("GetUserById", u.ID).Return(u, nil)
+		suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, u, mock.Anything, mock.Anything, false).Return(&models.Summary{
+			Languages: []*models.SummaryItem{{Type: models.SummaryLanguage, Key: "Go", Total: time.Hour / time.Second}},
+		}, nil)
+	}
+	suite.UserService.On("GetUserById", "nobody").Return((*models.User)(nil), errors.New("not found"))
+}
+
+func TestComparisonServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ComparisonServiceTestSuite))
+}
+
+func (suite *ComparisonServiceTestSuite) TestComparisonService_Compare() {
+	sut := NewComparisonService(suite.UserService, suite.SummaryService)
+
+	result, err := sut.Compare([]string{"mentor", "student1", "student1"}, time.Now().Add(-time.Hour), time.Now(), suite.TestUsers[0])
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result.Users, 2)
+	assert.Equal(suite.T(), "student1", result.Users[1].UserID)
+	assert.Equal(suite.T(), int64(3600), result.Users[1].TotalSeconds)
+	assert.Equal(suite.T(), []string{"Go"}, result.CommonLanguages)
+}
+
+func (suite *ComparisonServiceTestSuite) TestComparisonService_Compare_NotAllowed() {
+	sut := NewComparisonService(suite.UserService, suite.SummaryService)
+
+	for _, id := range []string{"student2", "nobody"} {
+		result, err := sut.Compare([]string{"student1", id}, time.Now().Add(-time.Hour), time.Now(), suite.TestUsers[0])
+		assert.Nil(suite.T(), result)
+		assert.IsType(suite.T(), &ComparisonNotAllowedError{}, err)
+	}
+
+	_, err := sut.Compare([]string{}, time.Now().Add(-time.Hour), time.Now(), suite.TestUsers[0])
+	assert.Equal(suite.T(), ErrNoComparisonUsers, err)
+}
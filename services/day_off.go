@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+// maximum number of days to be marked at once
+const maxDaysOffRange = 366
+
+type DayOffService struct {
+	config     *config.Config
+	cache      *cache.Cache
+	repository repositories.IDayOffRepository
+}
+
+func NewDayOffService(dayOffRepository repositories.IDayOffRepository) *DayOffService {
+	return &DayOffService{
+		config:     config.Get(),
+		repository: dayOffRepository,
+		cache:      cache.New(24*time.Hour, 24*time.Hour),
+	}
+}
+
+func (srv *DayOffService) GetByUser(userId string) ([]*models.DayOff, error) {
+	if days, found := srv.cache.Get(userId); found {
+		return days.([]*models.DayOff), nil
+	}
+
+	days, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(userId, days, cache.DefaultExpiration)
+	return days, nil
+}
+
+func (srv *DayOffService) GetByUserMapped(userId string) (models.DaysOff, error) {
+	days, err := srv.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewDaysOff(days), nil
+}
+
+// Set marks every day from the first to the last one (both inclusive) as day off of the given kind or, if kind is empty, unmarks them
+func (srv *DayOffService) Set(userId string, from, to time.Time, kind string) error {
+	defer srv.cache.Delete(userId)
+
+	from, to = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC), time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	if to.Before(from) {
+		return errors.New("invalid range")
+	}
+	if to.Sub(from) >= maxDaysOffRange*24*time.Hour {
+		return errors.New("range too long")
+	}
+
+	if kind == "" {
+		return srv.repository.DeleteByUserWithin(userId, from.Format(models.DayOffFormat), to.Format(models.DayOffFormat))
+	}
+
+	days := make([]*models.DayOff, 0)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, &models.DayOff{UserID: userId, Day: day.Format(models.DayOffFormat), Kind: kind})
+	}
+	return srv.repository.UpsertBatch(days)
+}
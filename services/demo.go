@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-co-op/gocron"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/fixtures"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	DemoUserId = "demo"
+	demoDays   = 30 // days of generated history
+)
+
+// DemoService maintains a sandbox user with generated data, so that admins can showcase the dashboard publicly without exposing anybody's real data.
+// The user is deleted and recreated periodically, which discards whatever visitors changed in the meantime. To never delete a real user of the same name,
+// the demo user's id is persisted as key-value pair and an existing user is only ever reset, if it was created as demo user before.
+type DemoService struct {
+	config             *config.Config
+	userService        IUserService
+	heartbeatService   IHeartbeatService
+	aggregationService IAggregationService
+	keyValueService    IKeyValueService
+	jobService         IJobService
+	lock               sync.RWMutex
+	userId             string
+}
+
+func NewDemoService(userService IUserService, heartbeatService IHeartbeatService, aggregationService IAggregationService, keyValueService IKeyValueService, jobService IJobService) *DemoService {
+	return &DemoService{
+		config:             config.Get(),
+		userService:        userService,
+		heartbeatService:   heartbeatService,
+		aggregationService: aggregationService,
+		keyValueService:    keyValueService,
+		jobService:         jobService,
+		userId:             keyValueService.MustGetString(config.KeyDemoUser).Value,
+	}
+}
+
+func (srv *DemoService) Schedule() {
+	if !srv.IsEnabled() {
+		return
+	}
+
+	logbuch.Info("scheduling demo user reset every %d hours", srv.config.App.DemoResetHours)
+
+	s := gocron.NewScheduler(time.Local)
+	s.Every(srv.config.App.DemoResetHours).Hours().Do(srv.resetJob)
+	s.StartBlocking()
+}
+
+func (srv *DemoService) IsEnabled() bool {
+	return srv.config.App.DemoMode
+}
+
+// IsDemoUser tells whether the given user is the demo user, which visitors may look at, but must not modify
+func (srv *DemoService) IsDemoUser(user *models.User) bool {
+	if !srv.IsEnabled() || user == nil {
+		return false
+	}
+	srv.lock.RLock()
+	defer srv.lock.RUnlock()
+	return srv.userId != "" && user.ID == srv.userId
+}
+
+// GetUser returns the demo user or an error, if demo mode is disabled or the user was not created yet
+func (srv *DemoService) GetUser() (*models.User, error) {
+	if !srv.IsEnabled() {
+		return nil, errors.New("demo mode is disabled")
+	}
+	srv.lock.RLock()
+	userId := srv.userId
+	srv.lock.RUnlock()
+	if userId == "" {
+		return nil, errors.New("demo user does not exist yet")
+	}
+	return srv.userService.GetUserById(userId)
+}
+
+// Reset (re-)creates the demo user with heartbeats of the past days and regenerates its summaries in the background
+func (srv *DemoService) Reset() (*models.User, error) {
+	if existing, err := srv.userService.GetUserById(DemoUserId); err == nil {
+		if srv.keyValueService.MustGetString(config.KeyDemoUser).Value != existing.ID {
+			return nil, errors.New(fmt.Sprintf("user '%s' already exists, but was not created as demo user", existing.ID))
+		}
+		if err := srv.userService.Delete(existing); err != nil {
+			return nil, err
+		}
+	}
+
+	user, _, err := srv.userService.CreateOrGet(&models.Signup{Username: DemoUserId, Password: uuid.NewV4().String()}, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := srv.keyValueService.PutString(&models.KeyStringValue{Key: config.KeyDemoUser, Value: user.ID}); err != nil {
+		return nil, err
+	}
+
+	srv.lock.Lock()
+	srv.userId = user.ID
+	srv.lock.Unlock()
+
+	to := time.Now().In(user.TZ())
+	from := utils.StartOfDay(to.AddDate(0, 0, -demoDays))
+	heartbeats := fixtures.NewHeartbeatGenerator(to.Unix()).Generate(user, from, to)
+	if err := srv.heartbeatService.InsertBatch(heartbeats); err != nil {
+		return nil, err
+	}
+
+	// today's summary is computed on the fly anyway
+	if _, err := srv.aggregationService.Regenerate(user, from, utils.StartOfDay(to)); err != nil {
+		return nil, err
+	}
+
+	logbuch.Info("reset demo user '%s' with %d generated heartbeats", user.ID, len(heartbeats))
+	return user, nil
+}
+
+func (srv *DemoService) resetJob() {
+	srv.jobService.Track(models.JobDemoReset, "", func() error {
+		_, err := srv.Reset()
+		return err
+	})
+}
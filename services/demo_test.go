@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type DemoServiceTestSuite struct {
+	suite.Suite
+	DemoUser           *models.User
+	UserService        *mocks.UserServiceMock
+	HeartbeatService   *mocks.HeartbeatServiceMock
+	AggregationService *mocks.AggregationServiceMock
+	KeyValueService    *mocks.KeyValueServiceMock
+}
+
+func (suite *DemoServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.App.DemoMode = true
+	cfg.App.DemoResetHours = 24
+	config.Set(cfg)
+}
+
+func (suite *DemoServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.DemoUser = &models.User{ID: DemoUserId}
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.AggregationService = new(mocks.AggregationServiceMock)
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+}
+
+func TestDemoServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(DemoServiceTestSuite))
+}
+
+func (suite *DemoServiceTestSuite) TestDemoService_Reset() {
+	suite.KeyValueService.On("MustGetString", config.KeyDemoUser).Return(&models.KeyStringValue{Key: config.KeyDemoUser, Value: DemoUserId})
+	suite.KeyValueService.On("PutString", mock.Anything).Return(nil)
+	suite.UserService.On("GetUserById", DemoUserId).Return(suite.DemoUser, nil)
+	suite.UserService.On("Delete", suite.DemoUser).Return(nil)
+	suite.UserService.On("CreateOrGet", mock.Anything, false).Return(suite.DemoUser, true, nil)
+	suite.HeartbeatService.On("InsertBatch", mock.Anything).Return(nil)
+	suite.AggregationService.On("Regenerate", suite.DemoUser, mock.Anything, mock.Anything).Return(&models.RegenerationJob{}, nil)
+
+	sut := NewDemoService(suite.UserService, suite.HeartbeatService, suite.AggregationService, suite.KeyValueService, nil)
+
+	user, err := sut.Reset()
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.DemoUser, user)
+	assert.True(suite.T(), sut.IsDemoUser(user))
+	assert.False(suite.T(), sut.IsDemoUser(&models.User{ID: "alice"}))
+	suite.UserService.AssertCalled(suite.T(), "Delete", suite.DemoUser)
+	suite.AggregationService.AssertNumberOfCalls(suite.T(), "Regenerate", 1)
+
+	heartbeats := suite.HeartbeatService.Calls[0].Arguments.Get(0).([]*models.Heartbeat)
+	assert.NotEmpty(suite.T(), heartbeats)
+	for _, h := range heartbeats {
+		assert.Equal(suite.T(), DemoUserId, h.UserID)
+	}
+}
+
+func (suite *DemoServiceTestSuite) TestDemoService_Reset_ExistingRealUser() {
+	suite.KeyValueService.On("MustGetString", config.KeyDemoUser).Return(&models.KeyStringValue{Key: config.KeyDemoUser})
+	suite.UserService.On("GetUserById", DemoUserId).Return(suite.DemoUser, nil)
+
+	sut := NewDemoService(suite.UserService, suite.HeartbeatService, suite.AggregationService, suite.KeyValueService, nil)
+
+	_, err := sut.Reset()
+
+	assert.Error(suite.T(), err)
+	assert.False(suite.T(), sut.IsDemoUser(suite.DemoUser))
+	suite.UserService.AssertNotCalled(suite.T(), "Delete", mock.Anything)
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "InsertBatch", mock.Anything)
+}
+
+func (suite *DemoServiceTestSuite) TestDemoService_Reset_NewUser() {
+	suite.KeyValueService.On("MustGetString", config.KeyDemoUser).Return(&models.KeyStringValue{Key: config.KeyDemoUser})
+	suite.KeyValueService.On("PutString", &models.KeyStringValue{Key: config.KeyDemoUser, Value: DemoUserId}).Return(nil)
+	suite.UserService.On("GetUserById", DemoUserId).Return((*models.User)(nil), errors.New("record not found"))
+	suite.UserService.On("CreateOrGet", mock.Anything, false).Return(suite.DemoUser, true, nil)
+	suite.HeartbeatService.On("InsertBatch", mock.Anything).Return(nil)
+	suite.AggregationService.On("Regenerate", suite.DemoUser, mock.Anything, mock.Anything).Return(&models.RegenerationJob{}, nil)
+
+	sut := NewDemoService(suite.UserService, suite.HeartbeatService, suite.AggregationService, suite.KeyValueService, nil)
+
+	_, err := sut.Reset()
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), sut.IsDemoUser(suite.DemoUser))
+	suite.UserService.AssertNotCalled(suite.T(), "Delete", mock.Anything)
+	suite.KeyValueService.AssertCalled(suite.T(), "PutString", &models.KeyStringValue{Key: config.KeyDemoUser, Value: DemoUserId})
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"sync"
+	"time"
+)
+
+const (
+	// summaries are considered to be consistent, as long as their total time deviates from the recomputed one by less than this
+	doctorMismatchTolerance = 1 * time.Minute
+	// summaries are recomputed for every single day checked, so the range is limited to keep runs reasonably short
+	DoctorMaxDays = 366
+)
+
+var ErrDoctorRunning = errors.New("data integrity check already running")
+
+type DoctorService struct {
+	config             *config.Config
+	userService        IUserService
+	summaryService     ISummaryService
+	aggregationService IAggregationService
+	summaryRepository  repositories.ISummaryRepository
+	aliasRepository    repositories.IAliasRepository
+	jobService         IJobService
+	lock               sync.Mutex
+	running            bool
+	lastReport         *models.DoctorReport
+}
+
+func NewDoctorService(userService IUserService, summaryService ISummaryService, aggregationService IAggregationService, summaryRepository repositories.ISummaryRepository, aliasRepository repositories.IAliasRepository, jobService IJobService) *DoctorService {
+	return &DoctorService{
+		config:             config.Get(),
+		userService:        userService,
+		summaryService:     summaryService,
+		aggregationService: aggregationService,
+		summaryRepository:  summaryRepository,
+		aliasRepository:    aliasRepository,
+		jobService:         jobService,
+	}
+}
+
+// Check scans the database for inconsistencies, i.e. orphaned summaries and aliases as well as persisted summaries within the past given number of days,
+// which disagree with the data recomputed from raw heartbeats or are duplicates of each other. If repair is set, orphans are deleted and affected summaries are regenerated.
+// Blocks until done, see CheckAsync for running the check in background.
+func (srv *DoctorService) Check(days int, repair bool) (*models.DoctorReport, error) {
+	if !srv.tryStart() {
+		return nil, ErrDoctorRunning
+	}
+	defer srv.stop()
+	return srv.track(days, repair)
+}
+
+// CheckAsync runs Check in background. Its progress is tracked as a job and its result can be retrieved using GetLastReport.
+func (srv *DoctorService) CheckAsync(days int, repair bool) error {
+	if !srv.tryStart() {
+		return ErrDoctorRunning
+	}
+
+	go func() {
+		defer srv.stop()
+		if _, err := srv.track(days, repair); err != nil {
+			config.Log().Error("data integrity check failed - %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// GetLastReport returns the report of the latest successful check or nil, if none was run since server start
+func (srv *DoctorService) GetLastReport() *models.DoctorReport {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	return srv.lastReport
+}
+
+func (srv *DoctorService) track(days int, repair bool) (report *models.DoctorReport, err error) {
+	err = srv.jobService.Track(models.JobDataIntegrity, "", func() error {
+		report, err = srv.check(days, repair)
+		return err
+	})
+	if err == nil {
+		srv.lock.Lock()
+		srv.lastReport = report
+		srv.lock.Unlock()
+	}
+	return report, err
+}
+
+func (srv *DoctorService) tryStart() bool {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	if srv.running {
+		return false
+	}
+	srv.running = true
+	return true
+}
+
+func (srv *DoctorService) stop() {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	srv.running = false
+}
+
+func (srv *DoctorService) check(days int, repair bool) (*models.DoctorReport, error) {
+	if days > DoctorMaxDays {
+		days = DoctorMaxDays
+	} else if days < 0 {
+		days = 0
+	}
+
+	to := utils.StartOfToday(time.Local)
+	report := &models.DoctorReport{
+		From:                to.AddDate(0, 0, -days),
+		To:                  to,
+		MismatchedSummaries: []*models.DoctorSummaryMismatch{},
+		DuplicateSummaries:  []*models.DoctorSummaryMismatch{},
+		SkippedUsers:        []string{},
+		Repaired:            repair,
+	}
+
+	// orphaned summaries
+	orphanedSummaryIds, err := srv.summaryRepository.GetOrphanedIds()
+	if err != nil {
+		return nil, err
+	}
+	report.OrphanedSummaries = len(orphanedSummaryIds)
+	if repair && len(orphanedSummaryIds) > 0 {
+		if err := srv.summaryRepository.DeleteByIds(orphanedSummaryIds); err != nil {
+			return nil, err
+		}
+		logbuch.Info("deleted %d orphaned summaries", len(orphanedSummaryIds))
+	}
+
+	// orphaned aliases
+	orphanedAliases, err := srv.aliasRepository.GetOrphaned()
+	if err != nil {
+		return nil, err
+	}
+	report.OrphanedAliases = len(orphanedAliases)
+	if repair && len(orphanedAliases) > 0 {
+		ids := make([]uint, len(orphanedAliases))
+		for i, a := range orphanedAliases {
+			ids[i] = a.ID
+		}
+		if err := srv.aliasRepository.DeleteBatch(ids); err != nil {
+			return nil, err
+		}
+		logbuch.Info("deleted %d orphaned aliases", len(orphanedAliases))
+	}
+
+	// summaries disagreeing with heartbeats
+	users, err := srv.userService.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		var mismatches, duplicates []*models.DoctorSummaryMismatch
+
+		// hold the user's aggregation lock, so summaries won't be generated concurrently while being checked and regenerated
+		err := srv.aggregationService.WithUserLock(u.ID, func() (err error) {
+			mismatches, duplicates, err = srv.checkUserSummaries(u, report.From, report.To, repair)
+			return err
+		})
+		if err == ErrAggregationInProgress {
+			logbuch.Warn("skipping summaries of user '%s', because they are being generated concurrently", u.ID)
+			report.SkippedUsers = append(report.SkippedUsers, u.ID)
+			continue
+		}
+
+		report.MismatchedSummaries = append(report.MismatchedSummaries, mismatches...)
+		report.DuplicateSummaries = append(report.DuplicateSummaries, duplicates...)
+		if err != nil {
+			config.Log().Error("failed to check summaries for user '%s' - %v", u.ID, err)
+		}
+	}
+
+	return report, nil
+}
+
+func (srv *DoctorService) checkUserSummaries(user *models.User, from, to time.Time, repair bool) (mismatches, duplicates []*models.DoctorSummaryMismatch, err error) {
+	mismatches, duplicates = make([]*models.DoctorSummaryMismatch, 0), make([]*models.DoctorSummaryMismatch, 0)
+
+	summaries, err := srv.summaryRepository.GetByUserWithin(user, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// summaries are generated per server-local day, while their from and to times are those of the first and last heartbeat
+	days := make([]time.Time, 0)
+	summariesByDay := make(map[time.Time][]*models.Summary)
+	for _, s := range summaries {
+		day := utils.StartOfDay(s.FromTime.T().In(time.Local))
+		if _, ok := summariesByDay[day]; !ok {
+			days = append(days, day)
+		}
+		summariesByDay[day] = append(summariesByDay[day], s)
+	}
+
+	for _, dayFrom := range days {
+		dayTo := dayFrom.AddDate(0, 0, 1)
+
+		recomputed, err := srv.summaryService.Summarize(dayFrom, dayTo, user, nil)
+		if err != nil {
+			return mismatches, duplicates, err
+		}
+
+		var persisted time.Duration
+		for _, s := range summariesByDay[dayFrom] {
+			persisted += s.TotalTime()
+		}
+
+		result := &models.DoctorSummaryMismatch{
+			UserID:     user.ID,
+			From:       dayFrom,
+			To:         dayTo,
+			Count:      len(summariesByDay[dayFrom]),
+			Persisted:  persisted,
+			Recomputed: recomputed.TotalTime(),
+		}
+
+		diff := result.Persisted - result.Recomputed
+		if diff < 0 {
+			diff = -diff
+		}
+
+		if result.Count > 1 {
+			duplicates = append(duplicates, result)
+		} else if diff >= doctorMismatchTolerance {
+			mismatches = append(mismatches, result)
+		} else {
+			continue
+		}
+
+		if repair {
+			if err := srv.aggregationService.RegenerateDay(user, dayFrom, dayTo); err != nil {
+				return mismatches, duplicates, err
+			}
+			logbuch.Info("regenerated summary (%v, %v, %s)", dayFrom, dayTo, user.ID)
+		}
+	}
+
+	return mismatches, duplicates, nil
+}
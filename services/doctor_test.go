@@ -0,0 +1,126 @@
+package services
+
+import (
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type DoctorServiceTestSuite struct {
+	suite.Suite
+	TestUser1          *models.User
+	TestUser2          *models.User
+	UserService        *mocks.UserServiceMock
+	SummaryService     *mocks.SummaryServiceMock
+	AggregationService *mocks.AggregationServiceMock
+	SummaryRepository  *mocks.SummaryRepositoryMock
+	AliasRepository    *mocks.AliasRepositoryMock
+}
+
+func (suite *DoctorServiceTestSuite) SetupSuite() {
+	suite.TestUser1 = &models.User{ID: TestUserId}
+	suite.TestUser2 = &models.User{ID: "janedoe"}
+}
+
+func (suite *DoctorServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.AggregationService = new(mocks.AggregationServiceMock)
+	suite.SummaryRepository = new(mocks.SummaryRepositoryMock)
+	suite.AliasRepository = new(mocks.AliasRepositoryMock)
+
+	suite.SummaryRepository.On("GetOrphanedIds").Return([]uint{}, nil)
+	suite.AliasRepository.On("GetOrphaned").Return([]*models.Alias{}, nil)
+	suite.UserService.On("GetAll").Return([]*models.User{suite.TestUser1, suite.TestUser2}, nil)
+	suite.AggregationService.On("WithUserLock", suite.TestUser1.ID, mock.Anything).Return(nil)
+	suite.AggregationService.On("WithUserLock", suite.TestUser2.ID, mock.Anything).Return(ErrAggregationInProgress)
+}
+
+func TestDoctorServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(DoctorServiceTestSuite))
+}
+
+func (suite *DoctorServiceTestSuite) TestDoctorService_Check() {
+	sut := NewDoctorService(suite.UserService, suite.SummaryService, suite.AggregationService, suite.SummaryRepository, suite.AliasRepository, NewJobService())
+
+	today := utils.StartOfToday(time.Local)
+	day1, day2, day3 := today.AddDate(0, 0, -3), today.AddDate(0, 0, -2), today.AddDate(0, 0, -1)
+
+	suite.SummaryRepository.On("GetByUserWithin", suite.TestUser1, today.AddDate(0, 0, -30), today).Return([]*models.Summary{
+		newDoctorTestSummary(day1, 10*time.Minute),
+		newDoctorTestSummary(day1, 10*time.Minute), // duplicate
+		newDoctorTestSummary(day2, 60*time.Minute), // mismatch
+		newDoctorTestSummary(day3, 10*time.Minute), // consistent
+	}, nil)
+	for _, day := range []time.Time{day1, day2, day3} {
+		suite.SummaryService.On("Summarize", day, day.AddDate(0, 0, 1), suite.TestUser1, mock.Anything).Return(newDoctorTestSummary(day, 10*time.Minute), nil)
+	}
+	suite.AggregationService.On("RegenerateDay", suite.TestUser1, mock.Anything, mock.Anything).Return(nil)
+
+	/* TEST 1 – report only */
+	report, err := sut.Check(30, false)
+
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), report.IsHealthy())
+	assert.Len(suite.T(), report.DuplicateSummaries, 1)
+	assert.Equal(suite.T(), day1, report.DuplicateSummaries[0].From)
+	assert.Equal(suite.T(), 2, report.DuplicateSummaries[0].Count)
+	assert.Len(suite.T(), report.MismatchedSummaries, 1)
+	assert.Equal(suite.T(), day2, report.MismatchedSummaries[0].From)
+	assert.Equal(suite.T(), 60*time.Minute, report.MismatchedSummaries[0].Persisted)
+	assert.Equal(suite.T(), 10*time.Minute, report.MismatchedSummaries[0].Recomputed)
+	assert.Equal(suite.T(), []string{suite.TestUser2.ID}, report.SkippedUsers)
+	assert.Equal(suite.T(), report, sut.GetLastReport())
+	suite.AggregationService.AssertNotCalled(suite.T(), "RegenerateDay", mock.Anything, mock.Anything, mock.Anything)
+
+	/* TEST 2 – repair */
+	report, err = sut.Check(30, true)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), report.Repaired)
+	suite.AggregationService.AssertCalled(suite.T(), "RegenerateDay", suite.TestUser1, day1, day1.AddDate(0, 0, 1))
+	suite.AggregationService.AssertCalled(suite.T(), "RegenerateDay", suite.TestUser1, day2, day2.AddDate(0, 0, 1))
+	suite.AggregationService.AssertNotCalled(suite.T(), "RegenerateDay", suite.TestUser1, day3, day3.AddDate(0, 0, 1))
+}
+
+func (suite *DoctorServiceTestSuite) TestDoctorService_Check_MaxDays() {
+	sut := NewDoctorService(suite.UserService, suite.SummaryService, suite.AggregationService, suite.SummaryRepository, suite.AliasRepository, NewJobService())
+
+	today := utils.StartOfToday(time.Local)
+	suite.SummaryRepository.On("GetByUserWithin", suite.TestUser1, mock.Anything, mock.Anything).Return([]*models.Summary{}, nil)
+
+	report, err := sut.Check(DoctorMaxDays+100, false)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), today.AddDate(0, 0, -DoctorMaxDays), report.From)
+}
+
+func (suite *DoctorServiceTestSuite) TestDoctorService_CheckAsync_AlreadyRunning() {
+	sut := NewDoctorService(suite.UserService, suite.SummaryService, suite.AggregationService, suite.SummaryRepository, suite.AliasRepository, NewJobService())
+
+	release := make(chan time.Time)
+	suite.SummaryRepository.On("GetByUserWithin", suite.TestUser1, mock.Anything, mock.Anything).WaitUntil(release).Return([]*models.Summary{}, nil)
+
+	assert.Nil(suite.T(), sut.CheckAsync(30, false))
+	assert.Equal(suite.T(), ErrDoctorRunning, sut.CheckAsync(30, false))
+	_, err := sut.Check(30, false)
+	assert.Equal(suite.T(), ErrDoctorRunning, err)
+
+	close(release)
+	assert.Eventually(suite.T(), func() bool { return sut.GetLastReport() != nil }, time.Second, 10*time.Millisecond)
+	assert.Nil(suite.T(), sut.CheckAsync(30, false))
+}
+
+func newDoctorTestSummary(day time.Time, total time.Duration) *models.Summary {
+	return &models.Summary{
+		UserID:   TestUserId,
+		FromTime: models.CustomTime(day.Add(1 * time.Hour)),
+		ToTime:   models.CustomTime(day.Add(2 * time.Hour)),
+		Projects: []*models.SummaryItem{{Type: models.SummaryProject, Key: TestProject1, Total: total / time.Second}},
+	}
+}
@@ -1,12 +1,16 @@
 package services
 
 import (
+	"fmt"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"time"
 )
 
-const HeartbeatDiffThreshold = 2 * time.Minute
+const (
+	HeartbeatDiffThreshold     = 2 * time.Minute
+	HeartbeatParallelThreshold = 5 * time.Second
+)
 
 type DurationService struct {
 	config           *config.Config
@@ -22,55 +26,120 @@ func NewDurationService(heartbeatService IHeartbeatService) *DurationService {
 }
 
 func (srv *DurationService) Get(from, to time.Time, user *models.User, filters *models.Filters) (models.Durations, error) {
-	heartbeats, err := srv.heartbeatService.GetAllWithin(from, to, user)
+	// compute durations independently for every machine and add them up, i.e. parallel activity is counted multiple times
+	sumMachines := user.DurationStrategy == models.DurationStrategySum
+	dropParallel := user.DurationStrategy == models.DurationStrategyMerge
+
+	aggregators := make(map[string]*durationAggregator)
+	parallel := newParallelFilter()
+
+	// heartbeats are processed page-wise, so that large ranges won't have to be loaded into memory at once
+	err := srv.heartbeatService.StreamAllWithin(from, to, user, func(heartbeats []*models.Heartbeat) error {
+		for _, h := range heartbeats {
+			// filter first, otherwise a heartbeat might be dropped in favor of a parallel one, which is filtered out later on
+			if filters != nil && !filters.Match(h) {
+				continue
+			}
+
+			var key string
+			if sumMachines {
+				key = h.Machine
+			} else if dropParallel && !parallel.keep(h) {
+				continue
+			}
+
+			if _, ok := aggregators[key]; !ok {
+				aggregators[key] = newDurationAggregator()
+			}
+			aggregators[key].add(h)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Aggregation
-	var count int
-	var latest *models.Duration
+	durations := make(models.Durations, 0)
+	for _, a := range aggregators {
+		durations = append(durations, a.durations()...)
+	}
+	return durations.Sorted(), nil
+}
 
-	mapping := make(map[string][]*models.Duration)
+// durationAggregator incrementally merges heartbeats into durations. Expects heartbeats to be added in order of time.
+type durationAggregator struct {
+	latest  *models.Duration
+	mapping map[string][]*models.Duration
+	lines   map[string]int // latest known line count per entity
+	count   int
+}
 
-	for _, h := range heartbeats {
-		if filters != nil && !filters.Match(h) {
-			continue
-		}
+func newDurationAggregator() *durationAggregator {
+	return &durationAggregator{
+		mapping: make(map[string][]*models.Duration),
+		lines:   make(map[string]int),
+	}
+}
 
-		d1 := models.NewDurationFromHeartbeat(h)
+func (a *durationAggregator) add(h *models.Heartbeat) {
+	d1 := models.NewDurationFromHeartbeat(h)
+	linesChanged := a.linesChanged(h)
 
-		if list, ok := mapping[d1.GroupHash]; !ok || len(list) < 1 {
-			mapping[d1.GroupHash] = []*models.Duration{d1}
-		}
+	if list, ok := a.mapping[d1.GroupHash]; !ok || len(list) < 1 {
+		a.mapping[d1.GroupHash] = []*models.Duration{d1}
+	}
 
-		if latest == nil {
-			latest = d1
-			continue
-		}
+	if a.latest == nil {
+		a.latest = d1
+		a.latest.LinesChanged += linesChanged
+		return
+	}
 
-		dur := d1.Time.T().Sub(latest.Time.T().Add(latest.Duration))
-		if dur > HeartbeatDiffThreshold {
-			dur = HeartbeatDiffThreshold
-		}
-		latest.Duration += dur
+	dur := d1.Time.T().Sub(a.latest.Time.T().Add(a.latest.Duration))
+	if dur > HeartbeatDiffThreshold {
+		dur = HeartbeatDiffThreshold
+	}
+	a.latest.Duration += dur
+	if h.IsWrite {
+		// time preceding a write heartbeat is considered spent writing
+		a.latest.WriteDuration += dur
+	}
 
-		if dur >= HeartbeatDiffThreshold || latest.GroupHash != d1.GroupHash {
-			list := mapping[d1.GroupHash]
-			if d0 := list[len(list)-1]; d0 != d1 {
-				mapping[d1.GroupHash] = append(mapping[d1.GroupHash], d1)
-			}
-			latest = d1
-		} else {
-			latest.NumHeartbeats++
+	if dur >= HeartbeatDiffThreshold || a.latest.GroupHash != d1.GroupHash {
+		list := a.mapping[d1.GroupHash]
+		if d0 := list[len(list)-1]; d0 != d1 {
+			a.mapping[d1.GroupHash] = append(a.mapping[d1.GroupHash], d1)
 		}
+		a.latest = d1
+	} else {
+		a.latest.NumHeartbeats++
+	}
 
-		count++
+	a.latest.LinesChanged += linesChanged
+	a.count++
+}
+
+// linesChanged approximates the number of lines changed by the difference in line count to the entity's previous heartbeat
+func (a *durationAggregator) linesChanged(h *models.Heartbeat) int {
+	if h.Lines <= 0 {
+		return 0
+	}
+	prev, ok := a.lines[h.Entity]
+	a.lines[h.Entity] = h.Lines
+	if !ok {
+		return 0
+	}
+	diff := h.Lines - prev
+	if diff < 0 {
+		diff = -diff
 	}
+	return diff
+}
 
-	durations := make(models.Durations, 0, count)
+func (a *durationAggregator) durations() models.Durations {
+	durations := make(models.Durations, 0, a.count)
 
-	for _, list := range mapping {
+	for _, list := range a.mapping {
 		for _, d := range list {
 			if d.Duration == 0 {
 				d.Duration = HeartbeatDiffThreshold
@@ -79,5 +148,25 @@ func (srv *DurationService) Get(from, to time.Time, user *models.User, filters *
 		}
 	}
 
-	return durations.Sorted(), nil
+	return durations
+}
+
+// parallelFilter drops heartbeats for an entity, which was already reported from a different machine only moments before
+// (e.g. by both an editor and a browser extension), so they won't fragment durations into alternating machines or editors.
+// Expects heartbeats to be passed in order of time.
+type parallelFilter struct {
+	latestByEntity map[string]*models.Heartbeat
+}
+
+func newParallelFilter() *parallelFilter {
+	return &parallelFilter{latestByEntity: make(map[string]*models.Heartbeat)}
+}
+
+func (f *parallelFilter) keep(h *models.Heartbeat) bool {
+	key := fmt.Sprintf("%s__%s", h.Project, h.Entity)
+	if h0, ok := f.latestByEntity[key]; ok && h0.Machine != h.Machine && h.Time.T().Sub(h0.Time.T()) < HeartbeatParallelThreshold {
+		return false
+	}
+	f.latestByEntity[key] = h
+	return true
 }
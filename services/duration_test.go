@@ -4,6 +4,7 @@ import (
 	"github.com/muety/wakapi/mocks"
 	"github.com/muety/wakapi/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"math/rand"
 	"testing"
@@ -127,7 +128,7 @@ func (suite *DurationServiceTestSuite) TestDurationService_Get() {
 
 	/* TEST 1 */
 	from, to = suite.TestStartTime.Add(-1*time.Hour), suite.TestStartTime.Add(-1*time.Minute)
-	suite.HeartbeatService.On("GetAllWithin", from, to, suite.TestUser).Return(filterHeartbeats(from, to, suite.TestHeartbeats), nil)
+	suite.HeartbeatService.On("StreamAllWithin", from, to, suite.TestUser, mock.Anything).Return(filterHeartbeats(from, to, suite.TestHeartbeats), nil)
 
 	durations, err = sut.Get(from, to, suite.TestUser, nil)
 
@@ -136,7 +137,7 @@ func (suite *DurationServiceTestSuite) TestDurationService_Get() {
 
 	/* TEST 2 */
 	from, to = suite.TestStartTime.Add(-1*time.Hour), suite.TestStartTime.Add(1*time.Second)
-	suite.HeartbeatService.On("GetAllWithin", from, to, suite.TestUser).Return(filterHeartbeats(from, to, suite.TestHeartbeats), nil)
+	suite.HeartbeatService.On("StreamAllWithin", from, to, suite.TestUser, mock.Anything).Return(filterHeartbeats(from, to, suite.TestHeartbeats), nil)
 
 	durations, err = sut.Get(from, to, suite.TestUser, nil)
 
@@ -147,7 +148,7 @@ func (suite *DurationServiceTestSuite) TestDurationService_Get() {
 
 	/* TEST 3 */
 	from, to = suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
-	suite.HeartbeatService.On("GetAllWithin", from, to, suite.TestUser).Return(filterHeartbeats(from, to, suite.TestHeartbeats), nil)
+	suite.HeartbeatService.On("StreamAllWithin", from, to, suite.TestUser, mock.Anything).Return(filterHeartbeats(from, to, suite.TestHeartbeats), nil)
 
 	durations, err = sut.Get(from, to, suite.TestUser, nil)
 
@@ -175,7 +176,7 @@ func (suite *DurationServiceTestSuite) TestDurationService_Get_Filtered() {
 	)
 
 	from, to = suite.TestStartTime.Add(-1*time.Hour), suite.TestStartTime.Add(1*time.Hour)
-	suite.HeartbeatService.On("GetAllWithin", from, to, suite.TestUser).Return(filterHeartbeats(from, to, suite.TestHeartbeats), nil)
+	suite.HeartbeatService.On("StreamAllWithin", from, to, suite.TestUser, mock.Anything).Return(filterHeartbeats(from, to, suite.TestHeartbeats), nil)
 
 	durations, err = sut.Get(from, to, suite.TestUser, models.NewFiltersWith(models.SummaryEditor, TestEditorGoland))
 	assert.Nil(suite.T(), err)
@@ -185,6 +186,89 @@ func (suite *DurationServiceTestSuite) TestDurationService_Get_Filtered() {
 	}
 }
 
+func (suite *DurationServiceTestSuite) TestDurationService_Get_ParallelMachines() {
+	sut := NewDurationService(suite.HeartbeatService)
+
+	var (
+		from      time.Time
+		to        time.Time
+		durations models.Durations
+		err       error
+	)
+
+	from, to = suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
+	heartbeats := []*models.Heartbeat{
+		{
+			ID:       rand.Uint64(),
+			UserID:   TestUserId,
+			Project:  TestProject1,
+			Entity:   "main.go",
+			Language: TestLanguageGo,
+			Machine:  TestMachine1,
+			Time:     models.CustomTime(suite.TestStartTime), // 0:00
+		},
+		{
+			ID:       rand.Uint64(),
+			UserID:   TestUserId,
+			Project:  TestProject1,
+			Entity:   "main.go",
+			Language: TestLanguageGo,
+			Machine:  TestMachine2,
+			Time:     models.CustomTime(suite.TestStartTime.Add(1 * time.Second)), // 0:01, same entity from other machine
+		},
+		{
+			ID:       rand.Uint64(),
+			UserID:   TestUserId,
+			Project:  TestProject1,
+			Entity:   "main.go",
+			Language: TestLanguageGo,
+			Machine:  TestMachine1,
+			Time:     models.CustomTime(suite.TestStartTime.Add(60 * time.Second)), // 1:00
+		},
+		{
+			ID:       rand.Uint64(),
+			UserID:   TestUserId,
+			Project:  TestProject1,
+			Entity:   "main.go",
+			Language: TestLanguageGo,
+			Machine:  TestMachine2,
+			Time:     models.CustomTime(suite.TestStartTime.Add(61 * time.Second)), // 1:01, same entity from other machine
+		},
+	}
+	suite.HeartbeatService.On("StreamAllWithin", from, to, mock.Anything, mock.Anything).Return(heartbeats, nil)
+
+	/* TEST 1 – default, single timeline alternating between machines */
+	durations, err = sut.Get(from, to, &models.User{ID: TestUserId}, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), durations, 4)
+
+	/* TEST 2 – merge */
+	durations, err = sut.Get(from, to, &models.User{ID: TestUserId, DurationStrategy: models.DurationStrategyMerge}, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), durations, 1)
+	assert.Equal(suite.T(), TestMachine1, durations[0].Machine)
+	assert.Equal(suite.T(), 60*time.Second, durations[0].Duration)
+	assert.Equal(suite.T(), 2, durations[0].NumHeartbeats)
+
+	/* TEST 3 – merge, filtered by machine, whose heartbeats would otherwise be dropped as parallel ones */
+	durations, err = sut.Get(from, to, &models.User{ID: TestUserId, DurationStrategy: models.DurationStrategyMerge}, models.NewFiltersWith(models.SummaryMachine, TestMachine2))
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), durations, 1)
+	assert.Equal(suite.T(), TestMachine2, durations[0].Machine)
+	assert.Equal(suite.T(), 60*time.Second, durations[0].Duration)
+
+	/* TEST 4 – sum */
+	durations, err = sut.Get(from, to, &models.User{ID: TestUserId, DurationStrategy: models.DurationStrategySum}, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), durations, 2)
+	assert.Equal(suite.T(), 60*time.Second, durations[0].Duration)
+	assert.Equal(suite.T(), 60*time.Second, durations[1].Duration)
+}
+
 func filterHeartbeats(from, to time.Time, heartbeats []*models.Heartbeat) []*models.Heartbeat {
 	filtered := make([]*models.Heartbeat, 0, len(heartbeats))
 	for _, h := range heartbeats {
@@ -194,3 +278,58 @@ func filterHeartbeats(from, to time.Time, heartbeats []*models.Heartbeat) []*mod
 	}
 	return filtered
 }
+
+func (suite *DurationServiceTestSuite) TestDurationService_Get_WriteAndLines() {
+	sut := NewDurationService(suite.HeartbeatService)
+
+	from, to := suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
+	heartbeats := []*models.Heartbeat{
+		{
+			ID:       rand.Uint64(),
+			UserID:   TestUserId,
+			Project:  TestProject1,
+			Entity:   "main.go",
+			Language: TestLanguageGo,
+			Lines:    100,
+			Time:     models.CustomTime(suite.TestStartTime), // 0:00
+		},
+		{
+			ID:       rand.Uint64(),
+			UserID:   TestUserId,
+			Project:  TestProject1,
+			Entity:   "main.go",
+			Language: TestLanguageGo,
+			Lines:    100,
+			Time:     models.CustomTime(suite.TestStartTime.Add(30 * time.Second)), // 0:30, reading
+		},
+		{
+			ID:       rand.Uint64(),
+			UserID:   TestUserId,
+			Project:  TestProject1,
+			Entity:   "main.go",
+			Language: TestLanguageGo,
+			IsWrite:  true,
+			Lines:    110,
+			Time:     models.CustomTime(suite.TestStartTime.Add(90 * time.Second)), // 1:30, writing
+		},
+		{
+			ID:       rand.Uint64(),
+			UserID:   TestUserId,
+			Project:  TestProject1,
+			Entity:   "main.go",
+			Language: TestLanguageGo,
+			IsWrite:  true,
+			Lines:    105,
+			Time:     models.CustomTime(suite.TestStartTime.Add(100 * time.Second)), // 1:40, writing
+		},
+	}
+	suite.HeartbeatService.On("StreamAllWithin", from, to, mock.Anything, mock.Anything).Return(heartbeats, nil)
+
+	durations, err := sut.Get(from, to, &models.User{ID: TestUserId}, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), durations, 1)
+	assert.Equal(suite.T(), 100*time.Second, durations[0].Duration)
+	assert.Equal(suite.T(), 70*time.Second, durations[0].WriteDuration)
+	assert.Equal(suite.T(), 15, durations[0].LinesChanged)
+}
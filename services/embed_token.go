@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	ErrInvalidEmbedToken  = errors.New("invalid embed token, at least one valid scope required")
+	ErrEmbedTokenScope    = errors.New("embed token not valid for this endpoint")
+	ErrEmbedTokenReferrer = errors.New("embed token not valid for this referrer")
+)
+
+type EmbedTokenService struct {
+	config     *config.Config
+	eventBus   *hub.Hub
+	repository repositories.IEmbedTokenRepository
+	cache      *cache.Cache // token -> embed token, as badges and stats may be requested on every single page view
+}
+
+func NewEmbedTokenService(embedTokenRepository repositories.IEmbedTokenRepository) *EmbedTokenService {
+	srv := &EmbedTokenService{
+		config:     config.Get(),
+		eventBus:   config.EventBus(),
+		repository: embedTokenRepository,
+		cache:      cache.New(1*time.Hour, 2*time.Hour),
+	}
+
+	// tokens of deleted users are either gone or, if the user was merged into another one, belong to that one now
+	sub := srv.eventBus.Subscribe(0, config.EventUserDelete)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.evictUser(m.Fields[config.FieldPayload].(*models.User).ID)
+		}
+	}(&sub)
+
+	return srv
+}
+
+func (srv *EmbedTokenService) GetByUser(userId string) ([]*models.EmbedToken, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+// Create issues a new token for the given scopes and (optionally) referrer hosts
+func (srv *EmbedTokenService) Create(user *models.User, name string, scopes, referrers []string) (*models.EmbedToken, error) {
+	token := &models.EmbedToken{
+		UserID:    user.ID,
+		Token:     uuid.NewV4().String(),
+		Name:      name,
+		Scopes:    strings.Join(scopes, ","),
+		Referrers: strings.Join(referrers, ","),
+		CreatedAt: models.CustomTime(time.Now()),
+	}
+	if !token.IsValid() {
+		return nil, ErrInvalidEmbedToken
+	}
+	return srv.repository.Insert(token)
+}
+
+func (srv *EmbedTokenService) Delete(user *models.User, id uint) error {
+	tokens, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	n, err := srv.repository.DeleteByUserAndId(user.ID, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("embed token not found")
+	}
+	for _, t := range tokens {
+		if t.ID == id {
+			srv.cache.Delete(t.Token)
+		}
+	}
+	return nil
+}
+
+// Authorize checks the token against the requested endpoint's scope and the page it's requested from (Referer or Origin) and returns it, if valid
+func (srv *EmbedTokenService) Authorize(token, scope, referrer string) (*models.EmbedToken, error) {
+	var embedToken *models.EmbedToken
+	if t, ok := srv.cache.Get(token); ok {
+		embedToken = t.(*models.EmbedToken)
+	} else {
+		t, err := srv.repository.GetByToken(token)
+		if err != nil {
+			return nil, err
+		}
+		srv.cache.SetDefault(token, t)
+		embedToken = t
+	}
+
+	if !embedToken.HasScope(scope) {
+		return nil, ErrEmbedTokenScope
+	}
+	if !embedToken.AllowsReferrer(referrer) {
+		return nil, ErrEmbedTokenReferrer
+	}
+	return embedToken, nil
+}
+
+func (srv *EmbedTokenService) evictUser(userId string) {
+	for key, item := range srv.cache.Items() {
+		if item.Object.(*models.EmbedToken).UserID == userId {
+			srv.cache.Delete(key)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type EmbedTokenServiceTestSuite struct {
+	suite.Suite
+	EmbedTokenRepository *mocks.EmbedTokenRepositoryMock
+}
+
+func (suite *EmbedTokenServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+}
+
+func (suite *EmbedTokenServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.EmbedTokenRepository = new(mocks.EmbedTokenRepositoryMock)
+}
+
+func TestEmbedTokenServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(EmbedTokenServiceTestSuite))
+}
+
+func (suite *EmbedTokenServiceTestSuite) TestEmbedTokenService_Authorize() {
+	token := &models.EmbedToken{UserID: TestUserId, Token: "token1", Scopes: models.EmbedScopeBadge}
+	suite.EmbedTokenRepository.On("GetByToken", "token1").Return(token, nil).Once()
+
+	sut := NewEmbedTokenService(suite.EmbedTokenRepository)
+
+	result, err := sut.Authorize("token1", models.EmbedScopeBadge, "")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), TestUserId, result.UserID)
+
+	// served from cache
+	_, err = sut.Authorize("token1", models.EmbedScopeStats, "")
+	assert.Equal(suite.T(), ErrEmbedTokenScope, err)
+	suite.EmbedTokenRepository.AssertNumberOfCalls(suite.T(), "GetByToken", 1)
+}
+
+func (suite *EmbedTokenServiceTestSuite) TestEmbedTokenService_EvictsDeletedUser() {
+	sut := NewEmbedTokenService(suite.EmbedTokenRepository)
+	sut.cache.SetDefault("token1", &models.EmbedToken{UserID: TestUserId, Token: "token1"})
+	sut.cache.SetDefault("token2", &models.EmbedToken{UserID: "otheruser", Token: "token2"})
+
+	// e.g. when merged into another user, whose tokens they are now
+	config.EventBus().Publish(hub.Message{
+		Name:   config.EventUserDelete,
+		Fields: map[string]interface{}{config.FieldPayload: &models.User{ID: TestUserId}},
+	})
+
+	assert.Eventually(suite.T(), func() bool {
+		_, found := sut.cache.Get("token1")
+		return !found
+	}, time.Second, 10*time.Millisecond)
+	_, found := sut.cache.Get("token2")
+	assert.True(suite.T(), found)
+}
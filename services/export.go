@@ -0,0 +1,165 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-co-op/gocron"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+var ErrExportInProgress = errors.New("an export is already in progress")
+
+type ExportService struct {
+	config           *config.Config
+	heartbeatService IHeartbeatService
+	storageService   IStorageService
+	jobService       IJobService
+	exportJobs       map[string]*models.ExportJob
+	exportLock       sync.RWMutex
+}
+
+func NewExportService(heartbeatService IHeartbeatService, storageService IStorageService, jobService IJobService) *ExportService {
+	return &ExportService{
+		config:           config.Get(),
+		heartbeatService: heartbeatService,
+		storageService:   storageService,
+		jobService:       jobService,
+		exportJobs:       map[string]*models.ExportJob{},
+	}
+}
+
+// Schedule periodically deletes generated files (exports and report pdfs), whose download links have expired
+func (srv *ExportService) Schedule() {
+	s := gocron.NewScheduler(time.Local)
+	s.Every(1).Hour().Do(srv.cleanup)
+	s.StartBlocking()
+}
+
+// ExportHeartbeats asynchronously dumps all of the user's heartbeats as json to the configured storage.
+// Progress and, eventually, the download link can be polled using GetExportJob.
+func (srv *ExportService) ExportHeartbeats(user *models.User) (*models.ExportJob, error) {
+	srv.exportLock.Lock()
+	if job, ok := srv.exportJobs[user.ID]; ok && !job.IsDone() {
+		srv.exportLock.Unlock()
+		return nil, ErrExportInProgress
+	}
+	job := models.NewExportJob(user.ID)
+	srv.exportJobs[user.ID] = job
+	srv.exportLock.Unlock()
+
+	go func() {
+		var count int
+		var url string
+		err := srv.jobService.Track(models.JobExport, user.ID, func() (err error) {
+			count, url, err = srv.exportHeartbeats(user)
+			return err
+		})
+		if err != nil {
+			config.Log().Error("failed to export data for user '%s' - %v", user.ID, err)
+		}
+
+		srv.exportLock.Lock()
+		defer srv.exportLock.Unlock()
+		now := time.Now()
+		expiresAt := now.Add(srv.config.Storage.GetLinkExpiry())
+		job.FinishedAt, job.Count, job.Url, job.ExpiresAt = &now, count, url, &expiresAt
+		if err != nil {
+			job.Error = err.Error()
+		}
+	}()
+
+	return srv.GetExportJob(user.ID), nil
+}
+
+// GetExportJob returns a snapshot of the user's latest export or nil, if none was run since server start
+func (srv *ExportService) GetExportJob(userId string) *models.ExportJob {
+	srv.exportLock.RLock()
+	defer srv.exportLock.RUnlock()
+	if job, ok := srv.exportJobs[userId]; ok {
+		jobCopy := *job
+		return &jobCopy
+	}
+	return nil
+}
+
+func (srv *ExportService) exportHeartbeats(user *models.User) (int, string, error) {
+	// heartbeats are buffered in a temporary file first, because object storage requires to know the size in advance
+	tmp, err := ioutil.TempFile("", "wakapi-export-*.json")
+	if err != nil {
+		return 0, "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := tmp.WriteString("["); err != nil {
+		return 0, "", err
+	}
+
+	var count int
+	encoder := json.NewEncoder(tmp)
+	if err := srv.heartbeatService.StreamAllWithin(time.Time{}, time.Now(), user, func(heartbeats []*models.Heartbeat) error {
+		for _, hb := range heartbeats {
+			if count > 0 {
+				if _, err := tmp.WriteString(","); err != nil {
+					return err
+				}
+			}
+			if err := encoder.Encode(hb); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	}); err != nil {
+		return 0, "", err
+	}
+
+	if _, err := tmp.WriteString("]"); err != nil {
+		return 0, "", err
+	}
+
+	size, err := tmp.Seek(0, 1)
+	if err != nil {
+		return 0, "", err
+	}
+	if _, err := tmp.Seek(0, 0); err != nil {
+		return 0, "", err
+	}
+
+	key := fmt.Sprintf("exports/%s/heartbeats-%s.json", user.ID, time.Now().Format("20060102-150405"))
+	if err := srv.storageService.Put(key, tmp, size, "application/json"); err != nil {
+		return 0, "", err
+	}
+
+	logbuch.Info("exported %d heartbeats of user '%s' to '%s'", count, user.ID, key)
+	url, err := srv.storageService.GetDownloadUrl(key, srv.config.Storage.GetLinkExpiry())
+	return count, url, err
+}
+
+func (srv *ExportService) cleanup() error {
+	return srv.jobService.Track(models.JobDataCleanup, "", func() error {
+		// generated files are only kept for as long as their download links are valid
+		retentions := map[string]time.Duration{
+			"exports/": srv.config.Storage.GetLinkExpiry(),
+			"reports/": reportPdfLinkExpiry,
+		}
+		for prefix, retention := range retentions {
+			n, err := srv.storageService.DeleteBefore(prefix, time.Now().Add(-retention))
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				logbuch.Info("deleted %d expired files from '%s'", n, prefix)
+			}
+		}
+		return nil
+	})
+}
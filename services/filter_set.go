@@ -0,0 +1,38 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+type FilterSetService struct {
+	config     *config.Config
+	repository repositories.IFilterSetRepository
+}
+
+func NewFilterSetService(filterSetRepository repositories.IFilterSetRepository) *FilterSetService {
+	return &FilterSetService{
+		config:     config.Get(),
+		repository: filterSetRepository,
+	}
+}
+
+func (srv *FilterSetService) GetByUser(userId string) ([]*models.FilterSet, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+func (srv *FilterSetService) GetByUserAndName(userId, name string) (*models.FilterSet, error) {
+	return srv.repository.GetByUserAndName(userId, name)
+}
+
+func (srv *FilterSetService) Create(filterSet *models.FilterSet) (*models.FilterSet, error) {
+	return srv.repository.Insert(filterSet)
+}
+
+func (srv *FilterSetService) Delete(userId, name string) error {
+	if _, err := srv.repository.GetByUserAndName(userId, name); err != nil {
+		return err
+	}
+	return srv.repository.DeleteByUserAndName(userId, name)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+)
+
+type GoalService struct {
+	config         *config.Config
+	repository     repositories.IGoalRepository
+	summaryService ISummaryService
+	dayOffService  IDayOffService
+}
+
+func NewGoalService(goalRepository repositories.IGoalRepository, summaryService ISummaryService, dayOffService IDayOffService) *GoalService {
+	return &GoalService{
+		config:         config.Get(),
+		repository:     goalRepository,
+		summaryService: summaryService,
+		dayOffService:  dayOffService,
+	}
+}
+
+func (srv *GoalService) GetByUser(userId string) ([]*models.Goal, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+func (srv *GoalService) Create(goal *models.Goal) (*models.Goal, error) {
+	return srv.repository.Insert(goal)
+}
+
+func (srv *GoalService) Delete(userId string, id uint) error {
+	goal, err := srv.repository.GetById(id)
+	if err != nil {
+		return err
+	}
+	if goal.UserID != userId {
+		return errors.New("goal does not belong to user")
+	}
+	return srv.repository.Delete(id)
+}
+
+// GetProgresses evaluates all of the user's goals against their current day, week or month
+func (srv *GoalService) GetProgresses(user *models.User) ([]*models.GoalProgress, error) {
+	goals, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	daysOff, err := srv.dayOffService.GetByUserMapped(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(user.TZ())
+	progresses := make([]*models.GoalProgress, len(goals))
+	for i, g := range goals {
+		if progresses[i], err = srv.getProgress(user, g, now, daysOff); err != nil {
+			return nil, err
+		}
+	}
+	return progresses, nil
+}
+
+// GetProgress evaluates a single goal or returns nil, if the user has no goal with the given id
+func (srv *GoalService) GetProgress(user *models.User, id uint) (*models.GoalProgress, error) {
+	goals, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range goals {
+		if g.ID == id {
+			daysOff, err := srv.dayOffService.GetByUserMapped(user.ID)
+			if err != nil {
+				return nil, err
+			}
+			return srv.getProgress(user, g, time.Now().In(user.TZ()), daysOff)
+		}
+	}
+	return nil, nil
+}
+
+// getProgress evaluates the goal within its current interval, whose target is reduced proportionally by the days off within it
+func (srv *GoalService) getProgress(user *models.User, goal *models.Goal, now time.Time, daysOff models.DaysOff) (*models.GoalProgress, error) {
+	from, to := goalIntervalStart(goal.Interval, now), goalIntervalEnd(goal.Interval, now)
+	summary, err := srv.summaryService.Aliased(from, now, user, srv.summaryService.Retrieve, goal.Filters(), false)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewGoalProgress(goal, from, to, summary.TotalTime(), daysOff), nil
+}
+
+func goalIntervalStart(interval string, now time.Time) time.Time {
+	switch interval {
+	case models.GoalIntervalWeek:
+		return utils.StartOfWeek(now)
+	case models.GoalIntervalMonth:
+		return utils.StartOfMonth(now)
+	default:
+		return utils.StartOfDay(now)
+	}
+}
+
+func goalIntervalEnd(interval string, now time.Time) time.Time {
+	from := goalIntervalStart(interval, now)
+	switch interval {
+	case models.GoalIntervalWeek:
+		return from.AddDate(0, 0, 7)
+	case models.GoalIntervalMonth:
+		return from.AddDate(0, 1, 0)
+	default:
+		return from.AddDate(0, 0, 1)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type GoalServiceTestSuite struct {
+	suite.Suite
+	TestUser       *models.User
+	GoalRepository *mocks.GoalRepositoryMock
+	SummaryService *mocks.SummaryServiceMock
+	DayOffService  *mocks.DayOffServiceMock
+}
+
+func (suite *GoalServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: "user1"}
+}
+
+func (suite *GoalServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.GoalRepository = new(mocks.GoalRepositoryMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.DayOffService = new(mocks.DayOffServiceMock)
+}
+
+func TestGoalServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(GoalServiceTestSuite))
+}
+
+func (suite *GoalServiceTestSuite) TestGoalService_GetProgresses() {
+	sut := NewGoalService(suite.GoalRepository, suite.SummaryService, suite.DayOffService)
+
+	suite.GoalRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.Goal{
+		{ID: 1, UserID: suite.TestUser.ID, Interval: models.GoalIntervalWeek, Minutes: 180, Language: "Rust"},
+		{ID: 2, UserID: suite.TestUser.ID, Interval: models.GoalIntervalDay, Minutes: 60},
+	}, nil)
+	suite.DayOffService.On("GetByUserMapped", suite.TestUser.ID).Return(models.DaysOff{}, nil)
+
+	rustFilters := models.NewFiltersWith(models.SummaryLanguage, "Rust")
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, rustFilters, false).Return(&models.Summary{
+		Languages: []*models.SummaryItem{
+			{Type: models.SummaryLanguage, Key: "Rust", Total: 90 * time.Minute / time.Second},
+		},
+	}, nil)
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, (*models.Filters)(nil), false).Return(&models.Summary{
+		Languages: []*models.SummaryItem{
+			{Type: models.SummaryLanguage, Key: "Rust", Total: 45 * time.Minute / time.Second},
+			{Type: models.SummaryLanguage, Key: "Go", Total: 45 * time.Minute / time.Second},
+		},
+	}, nil)
+
+	result, err := sut.GetProgresses(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), 90*time.Minute, result[0].Actual)
+	assert.Equal(suite.T(), 50.0, result[0].Percentage)
+	assert.False(suite.T(), result[0].IsReached())
+	assert.Equal(suite.T(), time.Monday, result[0].From.Weekday())
+	assert.Equal(suite.T(), 90*time.Minute, result[1].Actual)
+	assert.Equal(suite.T(), 150.0, result[1].Percentage)
+	assert.Equal(suite.T(), 100.0, result[1].BarPercentage())
+	assert.True(suite.T(), result[1].IsReached())
+}
+
+func (suite *GoalServiceTestSuite) TestGoalService_GetProgresses_DaysOff() {
+	sut := NewGoalService(suite.GoalRepository, suite.SummaryService, suite.DayOffService)
+
+	suite.GoalRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.Goal{
+		{ID: 1, UserID: suite.TestUser.ID, Interval: models.GoalIntervalWeek, Minutes: 7 * 60},
+		{ID: 2, UserID: suite.TestUser.ID, Interval: models.GoalIntervalDay, Minutes: 60},
+	}, nil)
+	today := time.Now().In(suite.TestUser.TZ()).Format(models.DayOffFormat)
+	suite.DayOffService.On("GetByUserMapped", suite.TestUser.ID).Return(models.NewDaysOff([]*models.DayOff{
+		{UserID: suite.TestUser.ID, Day: today, Kind: models.DayOffVacation},
+	}), nil)
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, (*models.Filters)(nil), false).Return(&models.Summary{}, nil)
+
+	result, err := sut.GetProgresses(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	// one out of seven days is off
+	assert.Equal(suite.T(), 1, result[0].DaysOff)
+	assert.Equal(suite.T(), 6*time.Hour, result[0].Target)
+	assert.False(suite.T(), result[0].IsReached())
+	// nothing is due on a day off
+	assert.Equal(suite.T(), 1, result[1].DaysOff)
+	assert.Equal(suite.T(), time.Duration(0), result[1].Target)
+	assert.Equal(suite.T(), 100.0, result[1].Percentage)
+	assert.True(suite.T(), result[1].IsReached())
+}
+
+func (suite *GoalServiceTestSuite) TestGoalService_Delete_ForeignGoal() {
+	sut := NewGoalService(suite.GoalRepository, suite.SummaryService, suite.DayOffService)
+
+	suite.GoalRepository.On("GetById", uint(1)).Return(&models.Goal{ID: 1, UserID: "user2"}, nil)
+
+	err := sut.Delete(suite.TestUser.ID, 1)
+
+	assert.Error(suite.T(), err)
+	suite.GoalRepository.AssertNotCalled(suite.T(), "Delete", mock.Anything)
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-co-op/gocron"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	googleAuthUrl       = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenUrl      = "https://oauth2.googleapis.com/token"
+	googleRevokeUrl     = "https://oauth2.googleapis.com/revoke"
+	googleCalendarUrl   = "https://www.googleapis.com/calendar/v3"
+	googleCalendarScope = "https://www.googleapis.com/auth/calendar.events"
+)
+
+const (
+	calendarSyncDays    = 2                // past days to write sessions for, so that heartbeats sent later on (e.g. offline) are still considered
+	calendarSessionGap  = 10 * time.Minute // breaks up to this long don't end a session
+	calendarDescription = "Tracked with Wakapi"
+)
+
+var ErrGoogleCalendarNotConnected = errors.New("google calendar is not connected")
+
+type googleApiError struct {
+	Status int
+	Body   string
+}
+
+func (e *googleApiError) Error() string {
+	return fmt.Sprintf("google responded with status %d - %s", e.Status, e.Body)
+}
+
+type GoogleCalendarService struct {
+	config         *config.Config
+	repository     repositories.ICalendarEventRepository
+	userService    IUserService
+	sessionService ISessionService
+	jobService     IJobService
+	tokenCache     *cache.Cache
+	httpClient     *http.Client
+}
+
+func NewGoogleCalendarService(calendarEventRepo repositories.ICalendarEventRepository, userService IUserService, sessionService ISessionService, jobService IJobService) *GoogleCalendarService {
+	return &GoogleCalendarService{
+		config:         config.Get(),
+		repository:     calendarEventRepo,
+		userService:    userService,
+		sessionService: sessionService,
+		jobService:     jobService,
+		tokenCache:     cache.New(time.Hour, time.Hour),
+		httpClient:     config.NewHttpClient(config.ProxyScopeIntegrations, 10*time.Second),
+	}
+}
+
+// Schedule hourly writes recent coding sessions to the calendars of all users, who connected google calendar and have the sync enabled
+func (srv *GoogleCalendarService) Schedule() {
+	if !srv.config.Integrations.GoogleCalendar.IsEnabled() {
+		return
+	}
+
+	logbuch.Info("scheduling google calendar sync")
+
+	s := gocron.NewScheduler(time.Local)
+	s.Every(1).Hour().Do(srv.syncAll)
+	s.StartBlocking()
+}
+
+func (srv *GoogleCalendarService) syncAll() {
+	users, err := srv.userService.GetAll()
+	if err != nil {
+		config.Log().Error("failed to fetch users for google calendar sync - %v", err)
+		return
+	}
+
+	for _, u := range users {
+		if !u.HasGoogleCalendar() || !u.GcalEnabled {
+			continue
+		}
+		if err := srv.Run(u); err != nil {
+			config.Log().Error("failed to sync google calendar of user '%s' - %v", u.ID, err)
+		}
+	}
+}
+
+// AuthCodeUrl returns the url to redirect users to for granting access to their calendar
+func (srv *GoogleCalendarService) AuthCodeUrl(state string) string {
+	query := url.Values{}
+	query.Set("client_id", srv.config.Integrations.GoogleCalendar.ClientId)
+	query.Set("redirect_uri", srv.redirectUri())
+	query.Set("response_type", "code")
+	query.Set("scope", googleCalendarScope)
+	query.Set("access_type", "offline") // to get a refresh token
+	query.Set("prompt", "consent")      // otherwise, the refresh token is only issued on the very first consent
+	query.Set("state", state)
+	return googleAuthUrl + "?" + query.Encode()
+}
+
+// Connect exchanges the authorization code obtained from google's consent screen for tokens and enables the sync
+func (srv *GoogleCalendarService) Connect(user *models.User, code string) error {
+	token, err := srv.requestToken(url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {srv.redirectUri()},
+	})
+	if err != nil {
+		return err
+	}
+	if token.RefreshToken == "" {
+		return errors.New("google did not issue a refresh token")
+	}
+
+	// events written to a previously connected account can't be updated anymore
+	if err := srv.repository.DeleteByUser(user.ID); err != nil {
+		return err
+	}
+
+	user.GcalRefreshToken = token.RefreshToken
+	user.GcalEnabled = true
+	if _, err := srv.userService.Update(user); err != nil {
+		return err
+	}
+
+	srv.tokenCache.Set(user.ID, token.AccessToken, time.Duration(token.ExpiresIn)*time.Second-time.Minute)
+	return nil
+}
+
+// Disconnect revokes the access granted to wakapi and forgets about previously written events, which remain in the calendar, though
+func (srv *GoogleCalendarService) Disconnect(user *models.User) error {
+	if user.GcalRefreshToken != "" {
+		// best effort, users can still revoke access from their google account
+		if res, err := srv.httpClient.PostForm(googleRevokeUrl, url.Values{"token": {user.GcalRefreshToken}}); err != nil {
+			logbuch.Warn("failed to revoke google token of user '%s' - %v", user.ID, err)
+		} else {
+			res.Body.Close()
+		}
+	}
+
+	if err := srv.repository.DeleteByUser(user.ID); err != nil {
+		return err
+	}
+
+	srv.tokenCache.Delete(user.ID)
+	user.GcalRefreshToken, user.GcalEnabled = "", false
+	_, err := srv.userService.Update(user)
+	return err
+}
+
+// Run writes the user's recent coding sessions to their calendar as a tracked background job
+func (srv *GoogleCalendarService) Run(user *models.User) error {
+	return srv.jobService.Track(models.JobCalendarSync, user.ID, func() error {
+		_, err := srv.Sync(user)
+		return err
+	})
+}
+
+// Sync writes the user's finished coding sessions of the past days, which exceed the configured minimum length, to their calendar.
+// Sessions already written before are updated, if they changed (e.g. because of heartbeats sent later on), and left alone otherwise.
+// Returns the events created or updated.
+func (srv *GoogleCalendarService) Sync(user *models.User) ([]*models.CalendarEvent, error) {
+	if !user.HasGoogleCalendar() {
+		return nil, ErrGoogleCalendarNotConnected
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -calendarSyncDays)
+
+	// look further back, so that sessions around the start of the interval won't be cut off and written twice with different start times
+	sessions, err := srv.sessionService.Get(from.AddDate(0, 0, -1), now, user, calendarSessionGap)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := srv.repository.GetByUserWithin(user.ID, from, now)
+	if err != nil {
+		return nil, err
+	}
+	events := make(map[int64]*models.CalendarEvent, len(existing))
+	for _, e := range existing {
+		events[e.StartTime.T().Unix()] = e
+	}
+
+	results := make([]*models.CalendarEvent, 0)
+	minSession := srv.config.Integrations.GoogleCalendar.GetMinSession()
+
+	for _, s := range sessions {
+		if s.Start.Before(from) || s.End.Add(calendarSessionGap).After(now) || s.Duration() < minSession {
+			continue // out of range, possibly still ongoing or too short
+		}
+
+		event, ok := events[s.Start.Unix()]
+		if !ok {
+			event = &models.CalendarEvent{UserID: user.ID, StartTime: models.CustomTime(s.Start)}
+		} else if event.EndTime.T().Unix() == s.End.Unix() { // compare at the precision of the database
+			continue
+		}
+
+		token, err := srv.accessToken(user)
+		if err != nil {
+			return results, err
+		}
+
+		eventId, err := srv.pushEvent(token, user, event.EventID, s)
+		var apiErr *googleApiError
+		if err != nil && event.EventID != "" && errors.As(err, &apiErr) && (apiErr.Status == http.StatusNotFound || apiErr.Status == http.StatusGone) {
+			continue // event was deleted by the user, don't bring it back
+		}
+		if err != nil {
+			return results, err
+		}
+
+		event.EventID, event.EndTime, event.SyncedAt = eventId, models.CustomTime(s.End), models.CustomTime(now)
+		if _, err := srv.repository.Upsert(event); err != nil {
+			return results, err
+		}
+		results = append(results, event)
+	}
+
+	return results, nil
+}
+
+// pushEvent creates or, if an event id is given, updates the session's event in the user's calendar and returns its id
+func (srv *GoogleCalendarService) pushEvent(token string, user *models.User, eventId string, session *models.Session) (string, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"summary":      session.Title(user.GcalEventTitles),
+		"description":  calendarDescription,
+		"start":        map[string]string{"dateTime": session.Start.In(user.TZ()).Format(time.RFC3339)},
+		"end":          map[string]string{"dateTime": session.End.In(user.TZ()).Format(time.RFC3339)},
+		"transparency": "opaque", // shows as busy
+	})
+	if err != nil {
+		return "", err
+	}
+
+	method, path := http.MethodPost, fmt.Sprintf("%s/calendars/%s/events", googleCalendarUrl, url.PathEscape(user.GoogleCalendarId()))
+	if eventId != "" {
+		method, path = http.MethodPut, path+"/"+url.PathEscape(eventId)
+	}
+
+	req, err := http.NewRequest(method, path, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := srv.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+// accessToken returns a cached access token for the user's calendar or obtains a new one using their refresh token
+func (srv *GoogleCalendarService) accessToken(user *models.User) (string, error) {
+	if token, found := srv.tokenCache.Get(user.ID); found {
+		return token.(string), nil
+	}
+
+	token, err := srv.requestToken(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {user.GcalRefreshToken},
+	})
+	var apiErr *googleApiError
+	if err != nil && errors.As(err, &apiErr) && strings.Contains(apiErr.Body, "invalid_grant") {
+		// access was revoked from the google account, stop trying
+		logbuch.Warn("google calendar access of user '%s' was revoked, disconnecting", user.ID)
+		user.GcalRefreshToken, user.GcalEnabled = "", false
+		if _, err := srv.userService.Update(user); err != nil {
+			return "", err
+		}
+		return "", ErrGoogleCalendarNotConnected
+	}
+	if err != nil {
+		return "", err
+	}
+
+	srv.tokenCache.Set(user.ID, token.AccessToken, time.Duration(token.ExpiresIn)*time.Second-time.Minute)
+	return token.AccessToken, nil
+}
+
+type googleToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+func (srv *GoogleCalendarService) requestToken(params url.Values) (*googleToken, error) {
+	params.Set("client_id", srv.config.Integrations.GoogleCalendar.ClientId)
+	params.Set("client_secret", srv.config.Integrations.GoogleCalendar.ClientSecret)
+
+	req, err := http.NewRequest(http.MethodPost, googleTokenUrl, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := srv.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var token googleToken
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (srv *GoogleCalendarService) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Accept", "application/json")
+	res, err := srv.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		res.Body.Close()
+		return nil, &googleApiError{Status: res.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	return res, nil
+}
+
+func (srv *GoogleCalendarService) redirectUri() string {
+	return srv.config.Server.GetPublicUrl() + "/settings/google_calendar/callback"
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type GoogleCalendarServiceTestSuite struct {
+	suite.Suite
+	TestUser                *models.User
+	Now                     time.Time
+	Server                  *httptest.Server
+	Requests                []*http.Request
+	RequestBodies           []map[string]interface{}
+	RevokeGrant             bool
+	CalendarEventRepository *mocks.CalendarEventRepositoryMock
+	UserService             *mocks.UserServiceMock
+	DurationService         *mocks.DurationServiceMock
+	AliasService            *mocks.AliasServiceMock
+}
+
+func (suite *GoogleCalendarServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+
+	suite.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if r.Header.Get("Content-Type") == "application/json" {
+			json.NewDecoder(r.Body).Decode(&body)
+		} else {
+			r.ParseForm()
+		}
+		suite.Requests = append(suite.Requests, r)
+		suite.RequestBodies = append(suite.RequestBodies, body)
+
+		switch r.Method + " " + r.Header.Get("X-Original-Host") + r.URL.Path {
+		case "POST oauth2.googleapis.com/token":
+			if suite.RevokeGrant {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "invalid_grant", "error_description": "Token has been expired or revoked."}`))
+				return
+			}
+			w.Write([]byte(`{"access_token": "access-token", "expires_in": 3599}`))
+		case "POST www.googleapis.com/calendar/v3/calendars/primary/events":
+			w.Write([]byte(`{"id": "ev1"}`))
+		case "PUT www.googleapis.com/calendar/v3/calendars/primary/events/ev2":
+			w.Write([]byte(`{"id": "ev2"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func (suite *GoogleCalendarServiceTestSuite) TearDownSuite() {
+	suite.Server.Close()
+}
+
+func (suite *GoogleCalendarServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUser = &models.User{ID: "johndoe", GcalRefreshToken: "refresh-token", GcalEnabled: true, GcalEventTitles: models.CalendarTitlesProjects}
+	suite.Now = time.Now().Truncate(time.Second)
+	suite.Requests = nil
+	suite.RequestBodies = nil
+	suite.RevokeGrant = false
+
+	suite.CalendarEventRepository = new(mocks.CalendarEventRepositoryMock)
+	suite.CalendarEventRepository.On("Upsert", mock.Anything).Return(&models.CalendarEvent{}, nil)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.UserService.On("Update", suite.TestUser).Return(suite.TestUser, nil)
+	suite.DurationService = new(mocks.DurationServiceMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	for _, p := range []string{"wakapi", "mailwhale"} {
+		suite.AliasService.On("GetAliasOrDefault", suite.TestUser.ID, models.SummaryProject, p).Return(p, nil)
+	}
+}
+
+func TestGoogleCalendarServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(GoogleCalendarServiceTestSuite))
+}
+
+func (suite *GoogleCalendarServiceTestSuite) TestGoogleCalendarService_Sync() {
+	t1, t2, t3 := suite.Now.Add(-30*time.Hour), suite.Now.Add(-20*time.Hour), suite.Now.Add(-10*time.Hour)
+	suite.DurationService.On("Get", mock.Anything, mock.Anything, suite.TestUser, mock.Anything).Return(models.Durations{
+		{Time: models.CustomTime(t1), Duration: 45 * time.Minute, Project: "wakapi"},                                 // new
+		{Time: models.CustomTime(t2), Duration: 40 * time.Minute, Project: "wakapi"},                                 // grown
+		{Time: models.CustomTime(t3), Duration: 35 * time.Minute, Project: "wakapi"},                                 // unchanged
+		{Time: models.CustomTime(suite.Now.Add(-5 * time.Hour)), Duration: 10 * time.Minute, Project: "wakapi"},      // too short
+		{Time: models.CustomTime(suite.Now.Add(-45 * time.Minute)), Duration: 40 * time.Minute, Project: "wakapi"},   // ongoing
+		{Time: models.CustomTime(suite.Now.Add(-4 * 24 * time.Hour)), Duration: 40 * time.Minute, Project: "wakapi"}, // too old
+	}.Sorted(), nil)
+	suite.CalendarEventRepository.On("GetByUserWithin", suite.TestUser.ID, mock.Anything, mock.Anything).Return([]*models.CalendarEvent{
+		{ID: 2, UserID: suite.TestUser.ID, StartTime: models.CustomTime(t2), EndTime: models.CustomTime(t2.Add(30 * time.Minute)), EventID: "ev2"},
+		{ID: 3, UserID: suite.TestUser.ID, StartTime: models.CustomTime(t3), EndTime: models.CustomTime(t3.Add(35 * time.Minute)), EventID: "ev3"},
+	}, nil)
+
+	sut := suite.newService()
+
+	result, err := sut.Sync(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.Len(suite.T(), suite.Requests, 3) // token, create, update
+	suite.CalendarEventRepository.AssertNumberOfCalls(suite.T(), "Upsert", 2)
+
+	assert.Equal(suite.T(), "refresh-token", suite.Requests[0].PostForm.Get("refresh_token"))
+
+	assert.Equal(suite.T(), "ev1", result[0].EventID)
+	assert.Equal(suite.T(), t1, result[0].StartTime.T())
+	assert.Equal(suite.T(), t1.Add(45*time.Minute), result[0].EndTime.T())
+	assert.Equal(suite.T(), "Bearer access-token", suite.Requests[1].Header.Get("Authorization"))
+	assert.Equal(suite.T(), "Coding: wakapi", suite.RequestBodies[1]["summary"])
+	assert.Equal(suite.T(), t1.Format(time.RFC3339), suite.RequestBodies[1]["start"].(map[string]interface{})["dateTime"])
+
+	assert.Equal(suite.T(), "ev2", result[1].EventID)
+	assert.Equal(suite.T(), uint(2), result[1].ID)
+	assert.Equal(suite.T(), t2.Add(40*time.Minute), result[1].EndTime.T())
+	assert.Equal(suite.T(), http.MethodPut, suite.Requests[2].Method)
+}
+
+func (suite *GoogleCalendarServiceTestSuite) TestGoogleCalendarService_Sync_Revoked() {
+	suite.RevokeGrant = true
+	suite.DurationService.On("Get", mock.Anything, mock.Anything, suite.TestUser, mock.Anything).Return(models.Durations{
+		{Time: models.CustomTime(suite.Now.Add(-10 * time.Hour)), Duration: 45 * time.Minute, Project: "wakapi"},
+	}, nil)
+	suite.CalendarEventRepository.On("GetByUserWithin", suite.TestUser.ID, mock.Anything, mock.Anything).Return([]*models.CalendarEvent{}, nil)
+
+	sut := suite.newService()
+
+	_, err := sut.Sync(suite.TestUser)
+
+	assert.Equal(suite.T(), ErrGoogleCalendarNotConnected, err)
+	assert.Empty(suite.T(), suite.TestUser.GcalRefreshToken)
+	assert.False(suite.T(), suite.TestUser.GcalEnabled)
+	suite.UserService.AssertCalled(suite.T(), "Update", suite.TestUser)
+	suite.CalendarEventRepository.AssertNotCalled(suite.T(), "Upsert", mock.Anything)
+}
+
+func (suite *GoogleCalendarServiceTestSuite) TestGoogleCalendarService_Sync_NotConnected() {
+	sut := suite.newService()
+
+	_, err := sut.Sync(&models.User{ID: "johndoe"})
+	assert.Equal(suite.T(), ErrGoogleCalendarNotConnected, err)
+}
+
+func (suite *GoogleCalendarServiceTestSuite) newService() *GoogleCalendarService {
+	target, _ := url.Parse(suite.Server.URL)
+	sut := NewGoogleCalendarService(suite.CalendarEventRepository, suite.UserService, NewSessionService(suite.DurationService, suite.AliasService), NewJobService())
+	sut.httpClient = &http.Client{Transport: &redirectTransport{target: target}}
+	return sut
+}
@@ -7,29 +7,49 @@ import (
 	"github.com/muety/wakapi/repositories"
 	"github.com/muety/wakapi/utils"
 	"github.com/patrickmn/go-cache"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/muety/wakapi/models"
 )
 
+// number of heartbeats to fetch at once when streaming
+const heartbeatPageSize = 10000
+
+// maximum number of heartbeats from concurrent requests to be written within a single transaction with sqlite
+const maxCoalescedHeartbeats = 1000
+
+// heartbeatWrite is a batch of heartbeats waiting to be written by the single writer, along with a channel to report back the result
+type heartbeatWrite struct {
+	heartbeats []*models.Heartbeat
+	result     chan error
+}
+
 type HeartbeatService struct {
+	pending             int64 // heartbeats received, but not yet written to the database, first field to be 64-bit aligned for atomic access
 	config              *config.Config
 	cache               *cache.Cache
 	eventBus            *hub.Hub
 	repository          repositories.IHeartbeatRepository
 	languageMappingSrvc ILanguageMappingService
+	jobService          IJobService
+	undoService         IUndoService
 	entityCacheLock     *sync.RWMutex
+	writes              chan *heartbeatWrite // only used with sqlite, see runWriter
 }
 
-func NewHeartbeatService(heartbeatRepo repositories.IHeartbeatRepository, languageMappingService ILanguageMappingService) *HeartbeatService {
+func NewHeartbeatService(heartbeatRepo repositories.IHeartbeatRepository, languageMappingService ILanguageMappingService, jobService IJobService, undoService IUndoService) *HeartbeatService {
 	srv := &HeartbeatService{
 		config:              config.Get(),
 		cache:               cache.New(24*time.Hour, 24*time.Hour),
 		eventBus:            config.EventBus(),
 		repository:          heartbeatRepo,
 		languageMappingSrvc: languageMappingService,
+		jobService:          jobService,
+		undoService:         undoService,
 		entityCacheLock:     &sync.RWMutex{},
 	}
 
@@ -45,12 +65,39 @@ func NewHeartbeatService(heartbeatRepo repositories.IHeartbeatRepository, langua
 		}
 	}(&sub1)
 
+	// counts are re-fetched from the (decremented) counters after deletion
+	sub2 := srv.eventBus.Subscribe(0, config.EventHeartbeatDelete)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.cache.Delete(srv.countByUserCacheKey(m.Fields[config.FieldUserId].(string)))
+			srv.cache.Delete(srv.countTotalCacheKey())
+		}
+	}(&sub2)
+
+	// edited heartbeats might carry new projects or languages (they are usually loaded from the database, so only their user id is set)
+	sub3 := srv.eventBus.Subscribe(0, config.EventHeartbeatUpdate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.updateEntityUserCacheByHeartbeat(m.Fields[config.FieldPayload].(*models.Heartbeat))
+		}
+	}(&sub3)
+
+	// sqlite only allows for a single writer at a time, so concurrent requests would otherwise compete for the database lock
+	if srv.config.Db.IsSQLite() {
+		srv.writes = make(chan *heartbeatWrite, 256)
+		go srv.runWriter()
+	}
+
 	return srv
 }
 
 func (srv *HeartbeatService) Insert(heartbeat *models.Heartbeat) error {
 	go srv.updateEntityUserCacheByHeartbeat(heartbeat)
-	return srv.repository.InsertBatch([]*models.Heartbeat{heartbeat})
+
+	atomic.AddInt64(&srv.pending, 1)
+	defer atomic.AddInt64(&srv.pending, -1)
+
+	return srv.write([]*models.Heartbeat{heartbeat})
 }
 
 func (srv *HeartbeatService) InsertBatch(heartbeats []*models.Heartbeat) error {
@@ -66,13 +113,60 @@ func (srv *HeartbeatService) InsertBatch(heartbeats []*models.Heartbeat) error {
 		go srv.updateEntityUserCacheByHeartbeat(hb)
 	}
 
-	err := srv.repository.InsertBatch(filteredHeartbeats)
+	atomic.AddInt64(&srv.pending, int64(len(filteredHeartbeats)))
+	err := srv.write(filteredHeartbeats)
+	atomic.AddInt64(&srv.pending, -int64(len(filteredHeartbeats)))
 	if err == nil {
 		go srv.notifyBatch(filteredHeartbeats)
 	}
 	return err
 }
 
+// write inserts the given heartbeats, either directly or, with sqlite, by handing them to the single writer and waiting for it to finish
+func (srv *HeartbeatService) write(heartbeats []*models.Heartbeat) error {
+	if srv.writes == nil {
+		return srv.repository.InsertBatch(heartbeats)
+	}
+	w := &heartbeatWrite{heartbeats: heartbeats, result: make(chan error, 1)}
+	srv.writes <- w
+	return <-w.result
+}
+
+// runWriter serializes all heartbeat inserts and coalesces the ones queued up in the meantime into a single transaction,
+// which is much cheaper with sqlite than many small ones. If such combined write fails, every batch is retried on its own,
+// so that an error is only reported for the request it was caused by.
+func (srv *HeartbeatService) runWriter() {
+	for w := range srv.writes {
+		batch := []*heartbeatWrite{w}
+		heartbeats := append([]*models.Heartbeat{}, w.heartbeats...)
+
+	coalesce:
+		for len(heartbeats) < maxCoalescedHeartbeats {
+			select {
+			case next := <-srv.writes:
+				batch = append(batch, next)
+				heartbeats = append(heartbeats, next.heartbeats...)
+			default:
+				break coalesce
+			}
+		}
+
+		err := srv.repository.InsertBatch(heartbeats)
+		for _, w := range batch {
+			if err != nil && len(batch) > 1 {
+				w.result <- srv.repository.InsertBatch(w.heartbeats)
+				continue
+			}
+			w.result <- err
+		}
+	}
+}
+
+// CountPending returns the number of heartbeats waiting to be written to the database, e.g. because it is busy
+func (srv *HeartbeatService) CountPending() int64 {
+	return atomic.LoadInt64(&srv.pending)
+}
+
 func (srv *HeartbeatService) Count() (int64, error) {
 	result, ok := srv.cache.Get(srv.countTotalCacheKey())
 	if ok {
@@ -134,6 +228,52 @@ func (srv *HeartbeatService) GetAllWithin(from, to time.Time, user *models.User)
 	return srv.augmented(heartbeats, user.ID)
 }
 
+func (srv *HeartbeatService) GetAllWithinOrdered(from, to time.Time, user *models.User, ordering *models.Ordering) ([]*models.Heartbeat, error) {
+	heartbeats, err := srv.repository.GetAllWithinOrdered(from, to, user, ordering)
+	if err != nil {
+		return nil, err
+	}
+	return srv.augmented(heartbeats, user.ID)
+}
+
+// StreamAllWithin passes all heartbeats within the given range to the callback, page by page and ordered by time, instead of loading all of them into memory at once
+func (srv *HeartbeatService) StreamAllWithin(from, to time.Time, user *models.User, f func([]*models.Heartbeat) error) error {
+	var cursor *models.HeartbeatCursor
+
+	for {
+		heartbeats, err := srv.repository.GetAllWithinPage(from, to, user, cursor, heartbeatPageSize)
+		if err != nil {
+			return err
+		}
+		if len(heartbeats) == 0 {
+			return nil
+		}
+
+		cursor = models.NewHeartbeatCursor(heartbeats[len(heartbeats)-1])
+
+		heartbeats, err = srv.augmented(heartbeats, user.ID)
+		if err != nil {
+			return err
+		}
+		if err := f(heartbeats); err != nil {
+			return err
+		}
+
+		if len(heartbeats) < heartbeatPageSize {
+			return nil
+		}
+	}
+}
+
+// GetPageByUser returns at most limit of the user's heartbeats with an id greater than afterId, ordered by id, so that all of them can be paged through reliably, even while new ones arrive
+func (srv *HeartbeatService) GetPageByUser(user *models.User, afterId uint64, limit int) ([]*models.Heartbeat, error) {
+	return srv.repository.GetPageBySelection(user, &models.HeartbeatSelection{}, afterId, limit)
+}
+
+func (srv *HeartbeatService) GetFirstByUser(user *models.User) (*models.Heartbeat, error) {
+	return srv.repository.GetFirstByUser(user)
+}
+
 func (srv *HeartbeatService) GetLatestByUser(user *models.User) (*models.Heartbeat, error) {
 	return srv.repository.GetLatestByUser(user)
 }
@@ -147,7 +287,7 @@ func (srv *HeartbeatService) GetFirstByUsers() ([]*models.TimeByUser, error) {
 }
 
 func (srv *HeartbeatService) GetEntitySetByUser(entityType uint8, user *models.User) ([]string, error) {
-	cacheKey := srv.getEntityUserCacheKey(entityType, user)
+	cacheKey := srv.getEntityUserCacheKey(entityType, user.ID)
 	if results, found := srv.cache.Get(cacheKey); found {
 		srv.entityCacheLock.RLock()
 		defer srv.entityCacheLock.RUnlock()
@@ -170,8 +310,119 @@ func (srv *HeartbeatService) GetEntitySetByUser(entityType uint8, user *models.U
 	return filtered, nil
 }
 
+func (srv *HeartbeatService) GetProjectActivityByUser(user *models.User, ordering *models.Ordering) ([]*models.ProjectActivity, error) {
+	return srv.repository.GetProjectActivityByUser(user, ordering)
+}
+
+func (srv *HeartbeatService) GetMachineActivityByUser(user *models.User) ([]*models.MachineActivity, error) {
+	return srv.repository.GetMachineActivityByUser(user)
+}
+
+// GetOriginActivityByUser returns the latest heartbeat's time, editor, operating system and agent version per machine and origin, most recently active first
+func (srv *HeartbeatService) GetOriginActivityByUser(user *models.User) ([]*models.OriginActivity, error) {
+	activities, err := srv.repository.GetOriginActivityByUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range activities {
+		latest, err := srv.repository.GetLatestByMachineOriginAndUser(a.Machine, a.Origin, user)
+		if err != nil {
+			return nil, err
+		}
+		a.Origin = latest.OriginKind()
+		a.Editor = latest.Editor
+		a.OperatingSystem = latest.OperatingSystem
+		a.UserAgent = latest.UserAgent
+		a.AgentVersion = utils.ParseAgentVersion(latest.UserAgent)
+	}
+
+	sort.Slice(activities, func(i, j int) bool {
+		return activities[i].LastActivity.T().After(activities[j].LastActivity.T())
+	})
+	return activities, nil
+}
+
+func (srv *HeartbeatService) GetById(id uint64) (*models.Heartbeat, error) {
+	return srv.repository.GetById(id)
+}
+
+// Update persists changes to a heartbeat's project, language or branch, e.g. to fix a mis-attributed one.
+// The summary and durations of the heartbeat's day are invalidated along with the change and an event is published for caches to be cleared.
+// Edits, which would make the heartbeat a duplicate of another one, fail with repositories.ErrDuplicateHeartbeat.
+func (srv *HeartbeatService) Update(heartbeat *models.Heartbeat) (*models.Heartbeat, error) {
+	result, err := srv.repository.Update(heartbeat.Hashed())
+	if err != nil {
+		return nil, err
+	}
+
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventHeartbeatUpdate,
+		Fields: map[string]interface{}{config.FieldPayload: result, config.FieldUserId: result.UserID},
+	})
+	return result, nil
+}
+
+// Delete removes a single heartbeat of its user. Unless undo is disabled, a copy of it is kept, so that the deletion can be undone using the returned tombstone.
+func (srv *HeartbeatService) Delete(heartbeat *models.Heartbeat) (*models.Tombstone, error) {
+	tombstone, err := srv.undoService.Begin(heartbeat.User, models.TombstoneHeartbeatDeletion)
+	if err != nil {
+		return nil, err
+	}
+	if err := srv.undoService.Record(tombstone, []*models.Heartbeat{heartbeat}); err != nil {
+		srv.undoService.Discard(tombstone)
+		return nil, err
+	}
+	if err := srv.repository.DeleteByIds(heartbeat.User, []uint64{heartbeat.ID}); err != nil {
+		srv.undoService.Discard(tombstone)
+		return nil, err
+	}
+
+	srv.eventBus.Publish(hub.Message{
+		Name: config.EventHeartbeatDelete,
+		Fields: map[string]interface{}{
+			config.FieldPayload: &models.Interval{Start: heartbeat.Time.T(), End: heartbeat.Time.T()},
+			config.FieldUserId:  heartbeat.UserID,
+		},
+	})
+	return tombstone, nil
+}
+
+// DeleteBefore deletes all users' heartbeats older than the given time, e.g. to enforce a data retention period.
+// For every affected user, an event with the time range of deleted heartbeats is published, so that summaries can be updated accordingly.
 func (srv *HeartbeatService) DeleteBefore(t time.Time) error {
-	return srv.repository.DeleteBefore(t)
+	return srv.jobService.Track(models.JobDataCleanup, "", func() error {
+		firstUserHeartbeatTimes, err := srv.repository.GetFirstByUsers()
+		if err != nil {
+			return err
+		}
+		if err := srv.repository.DeleteBefore(t); err != nil {
+			return err
+		}
+		for _, e := range firstUserHeartbeatTimes {
+			if e.Time.Valid() && !e.Time.T().After(t) {
+				srv.eventBus.Publish(hub.Message{
+					Name: config.EventHeartbeatDelete,
+					Fields: map[string]interface{}{
+						config.FieldPayload: &models.Interval{Start: e.Time.T(), End: t},
+						config.FieldUserId:  e.User,
+					},
+				})
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteOldestByUser deletes the user's n oldest heartbeats (see HeartbeatRepository) to free up storage, while retaining summaries.
+// Hence, unlike Delete or DeleteBefore, it does not publish any event, which would cause the affected days' summaries to be regenerated.
+func (srv *HeartbeatService) DeleteOldestByUser(user *models.User, n int64) (int64, error) {
+	deleted, err := srv.repository.DeleteOldestByUser(user, n)
+	if deleted > 0 {
+		srv.cache.Delete(srv.countByUserCacheKey(user.ID))
+		srv.cache.Delete(srv.countTotalCacheKey())
+	}
+	return deleted, err
 }
 
 func (srv *HeartbeatService) augmented(heartbeats []*models.Heartbeat, userId string) ([]*models.Heartbeat, error) {
@@ -187,12 +438,12 @@ func (srv *HeartbeatService) augmented(heartbeats []*models.Heartbeat, userId st
 	return heartbeats, nil
 }
 
-func (srv *HeartbeatService) getEntityUserCacheKey(entityType uint8, user *models.User) string {
-	return fmt.Sprintf("entity_set_%d_%s", entityType, user.ID)
+func (srv *HeartbeatService) getEntityUserCacheKey(entityType uint8, userId string) string {
+	return fmt.Sprintf("entity_set_%d_%s", entityType, userId)
 }
 
-func (srv *HeartbeatService) updateEntityUserCache(entityType uint8, entityKey string, user *models.User) {
-	cacheKey := srv.getEntityUserCacheKey(entityType, user)
+func (srv *HeartbeatService) updateEntityUserCache(entityType uint8, entityKey string, userId string) {
+	cacheKey := srv.getEntityUserCacheKey(entityType, userId)
 	if entities, found := srv.cache.Get(cacheKey); found {
 		entitySet := entities.(map[string]bool)
 
@@ -209,12 +460,12 @@ func (srv *HeartbeatService) updateEntityUserCache(entityType uint8, entityKey s
 }
 
 func (srv *HeartbeatService) updateEntityUserCacheByHeartbeat(hb *models.Heartbeat) {
-	go srv.updateEntityUserCache(models.SummaryProject, hb.Project, hb.User)
-	go srv.updateEntityUserCache(models.SummaryLanguage, hb.Language, hb.User)
-	go srv.updateEntityUserCache(models.SummaryEditor, hb.Editor, hb.User)
-	go srv.updateEntityUserCache(models.SummaryOS, hb.OperatingSystem, hb.User)
-	go srv.updateEntityUserCache(models.SummaryMachine, hb.Machine, hb.User)
-	go srv.updateEntityUserCache(models.SummaryBranch, hb.Branch, hb.User)
+	go srv.updateEntityUserCache(models.SummaryProject, hb.Project, hb.UserID)
+	go srv.updateEntityUserCache(models.SummaryLanguage, hb.Language, hb.UserID)
+	go srv.updateEntityUserCache(models.SummaryEditor, hb.Editor, hb.UserID)
+	go srv.updateEntityUserCache(models.SummaryOS, hb.OperatingSystem, hb.UserID)
+	go srv.updateEntityUserCache(models.SummaryMachine, hb.Machine, hb.UserID)
+	go srv.updateEntityUserCache(models.SummaryBranch, hb.Branch, hb.UserID)
 }
 
 func (srv *HeartbeatService) notifyBatch(heartbeats []*models.Heartbeat) {
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/patrickmn/go-cache"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+const (
+	heartbeatScriptFunction = "transform"
+	heartbeatScriptMaxSize  = 16 * 1024
+
+	// limits of the interpreter's stacks, which do not limit its memory usage as a whole, e.g. of strings and tables created by scripts
+	heartbeatScriptCallStackSize   = 64
+	heartbeatScriptRegistrySize    = 1024
+	heartbeatScriptRegistryMaxSize = 64 * 1024
+)
+
+// functions available to scripts per library, everything else (e.g. print, load, dofile, require, collectgarbage, setmetatable) is removed
+var heartbeatScriptAllowedFunctions = map[string][]string{
+	lua.BaseLibName:   {"assert", "error", "ipairs", "next", "pairs", "pcall", "rawequal", "rawget", "rawset", "select", "tonumber", "tostring", "type", "unpack", "xpcall"},
+	lua.StringLibName: {"byte", "char", "find", "format", "gmatch", "gsub", "len", "lower", "match", "rep", "reverse", "sub", "upper"},
+	lua.TabLibName:    {"concat", "insert", "remove", "sort"},
+	lua.MathLibName:   {"abs", "ceil", "floor", "fmod", "huge", "max", "min", "pi", "sqrt"},
+}
+
+type compiledHeartbeatScript struct {
+	source string
+	proto  *lua.FunctionProto
+	states *sync.Pool // of *heartbeatScriptState with the script already loaded
+}
+
+// heartbeatScriptState is an interpreter, which had the script's top-level chunk executed, so that it's ready to be called for heartbeats
+type heartbeatScriptState struct {
+	L  *lua.LState
+	fn *lua.LFunction
+}
+
+// HeartbeatScriptService runs lua scripts, which may transform or reject heartbeats at ingestion time.
+// A script is expected to define a global function 'transform(hb)', which receives a heartbeat as a table
+// and returns the (possibly modified) table to accept the heartbeat or nil / false to reject it.
+// Scripts run with only a whitelisted subset of the base, string, table and math libraries and are aborted after a configured timeout.
+// Interpreters are pooled per script, so state is never shared between different scripts (i.e. users).
+// As the interpreter's memory usage can't be limited, e.g. of strings grown by concatenation within the timeout, scripts are trusted code
+// and can only be set by admins.
+type HeartbeatScriptService struct {
+	config       *config.Config
+	cache        *cache.Cache
+	globalScript *compiledHeartbeatScript
+}
+
+func NewHeartbeatScriptService() *HeartbeatScriptService {
+	srv := &HeartbeatScriptService{
+		config: config.Get(),
+		cache:  cache.New(1*time.Hour, 1*time.Hour),
+	}
+
+	if path := srv.config.App.HeartbeatScript; path != "" {
+		source, err := ioutil.ReadFile(path)
+		if err != nil {
+			logbuch.Fatal("failed to read heartbeat script '%s' - %v", path, err)
+		}
+		script, err := compileHeartbeatScript(string(source))
+		if err != nil {
+			logbuch.Fatal("failed to compile heartbeat script '%s' - %v", path, err)
+		}
+		srv.globalScript = script
+	}
+
+	return srv
+}
+
+// Validate checks whether the given source compiles and defines the expected function
+func (srv *HeartbeatScriptService) Validate(source string) error {
+	if len(source) > heartbeatScriptMaxSize {
+		return fmt.Errorf("script must not be larger than %d bytes", heartbeatScriptMaxSize)
+	}
+	script, err := compileHeartbeatScript(source)
+	if err != nil {
+		return err
+	}
+
+	state, err := srv.newState(script)
+	if err != nil {
+		return err
+	}
+	state.L.Close()
+	return nil
+}
+
+// Apply runs the server-wide script and, if enabled, the user's own script against the given heartbeat, modifying it in place.
+// Returns false if the heartbeat was rejected by either of them.
+func (srv *HeartbeatScriptService) Apply(user *models.User, heartbeat *models.Heartbeat) (bool, error) {
+	if srv.globalScript != nil {
+		if ok, err := srv.run(srv.globalScript, heartbeat); err != nil || !ok {
+			return ok, err
+		}
+	}
+
+	if !srv.config.App.UserHeartbeatScripts || strings.TrimSpace(user.HeartbeatScript) == "" {
+		return true, nil
+	}
+
+	script, err := srv.getUserScript(user)
+	if err != nil {
+		return false, err
+	}
+	return srv.run(script, heartbeat)
+}
+
+func (srv *HeartbeatScriptService) getUserScript(user *models.User) (*compiledHeartbeatScript, error) {
+	if cached, found := srv.cache.Get(user.ID); found {
+		if script := cached.(*compiledHeartbeatScript); script.source == user.HeartbeatScript {
+			return script, nil
+		}
+	}
+
+	script, err := compileHeartbeatScript(user.HeartbeatScript)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.SetDefault(user.ID, script)
+	return script, nil
+}
+
+func (srv *HeartbeatScriptService) run(script *compiledHeartbeatScript, heartbeat *models.Heartbeat) (bool, error) {
+	state, ok := script.states.Get().(*heartbeatScriptState)
+	if !ok {
+		var err error
+		if state, err = srv.newState(script); err != nil {
+			return false, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), srv.config.App.GetHeartbeatScriptTimeout())
+	defer cancel()
+
+	L := state.L
+	L.SetContext(ctx)
+	err := L.CallByParam(lua.P{Fn: state.fn, NRet: 1, Protect: true}, heartbeatToTable(L, heartbeat))
+	L.RemoveContext()
+
+	if err != nil {
+		// interpreters, which were aborted (e.g. due to timeout), are not reused
+		L.Close()
+		return false, err
+	}
+
+	// the interpreter is only returned to the pool once the result was read, otherwise a concurrent run might overwrite it meanwhile
+	defer script.states.Put(state)
+
+	ret := L.Get(-1)
+	L.SetTop(0)
+
+	switch v := ret.(type) {
+	case *lua.LTable:
+		tableToHeartbeat(v, heartbeat)
+		return true, nil
+	case lua.LBool:
+		return bool(v), nil
+	case *lua.LNilType:
+		return false, nil
+	default:
+		return false, errors.New("script returned an unexpected value")
+	}
+}
+
+// newState creates a sandboxed interpreter and executes the script's top-level chunk within the configured timeout
+func (srv *HeartbeatScriptService) newState(script *compiledHeartbeatScript) (*heartbeatScriptState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), srv.config.App.GetHeartbeatScriptTimeout())
+	defer cancel()
+
+	L := newHeartbeatScriptState()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	if err := L.CallByParam(lua.P{Fn: L.NewFunctionFromProto(script.proto), NRet: 0, Protect: true}); err != nil {
+		L.Close()
+		return nil, err
+	}
+	fn, ok := L.GetGlobal(heartbeatScriptFunction).(*lua.LFunction)
+	if !ok {
+		L.Close()
+		return nil, fmt.Errorf("script must define a function '%s(hb)'", heartbeatScriptFunction)
+	}
+	return &heartbeatScriptState{L: L, fn: fn}, nil
+}
+
+func compileHeartbeatScript(source string) (*compiledHeartbeatScript, error) {
+	chunk, err := parse.Parse(strings.NewReader(source), "<heartbeat script>")
+	if err != nil {
+		return nil, err
+	}
+	proto, err := lua.Compile(chunk, "<heartbeat script>")
+	if err != nil {
+		return nil, err
+	}
+	return &compiledHeartbeatScript{source: source, proto: proto, states: &sync.Pool{}}, nil
+}
+
+func newHeartbeatScriptState() *lua.LState {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:        true,
+		CallStackSize:       heartbeatScriptCallStackSize,
+		RegistrySize:        heartbeatScriptRegistrySize,
+		RegistryMaxSize:     heartbeatScriptRegistryMaxSize,
+		IncludeGoStackTrace: false,
+	})
+
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	// replace globals by only the whitelisted functions
+	globals := L.NewTable()
+	for lib, names := range heartbeatScriptAllowedFunctions {
+		source, target := L.G.Global, globals
+		if lib != lua.BaseLibName {
+			source, target = L.GetGlobal(lib).(*lua.LTable), L.NewTable()
+			globals.RawSetString(lib, target)
+		}
+		for _, name := range names {
+			target.RawSetString(name, source.RawGetString(name))
+		}
+	}
+	// methods on strings (e.g. ("x"):upper()) must resolve to the restricted library as well
+	if mt, ok := L.GetMetatable(lua.LString("")).(*lua.LTable); ok {
+		mt.RawSetString("__index", globals.RawGetString(lua.StringLibName))
+	}
+
+	var names []lua.LValue
+	L.G.Global.ForEach(func(k, _ lua.LValue) { names = append(names, k) })
+	for _, k := range names {
+		L.G.Global.RawSet(k, lua.LNil)
+	}
+	globals.ForEach(func(k, v lua.LValue) { L.G.Global.RawSet(k, v) })
+
+	return L
+}
+
+func heartbeatToTable(L *lua.LState, heartbeat *models.Heartbeat) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString("entity", lua.LString(heartbeat.Entity))
+	t.RawSetString("type", lua.LString(heartbeat.Type))
+	t.RawSetString("category", lua.LString(heartbeat.Category))
+	t.RawSetString("project", lua.LString(heartbeat.Project))
+	t.RawSetString("branch", lua.LString(heartbeat.Branch))
+	t.RawSetString("language", lua.LString(heartbeat.Language))
+	t.RawSetString("is_write", lua.LBool(heartbeat.IsWrite))
+	t.RawSetString("editor", lua.LString(heartbeat.Editor))
+	t.RawSetString("operating_system", lua.LString(heartbeat.OperatingSystem))
+	t.RawSetString("machine", lua.LString(heartbeat.Machine))
+	t.RawSetString("user_agent", lua.LString(heartbeat.UserAgent))
+	t.RawSetString("time", lua.LNumber(float64(heartbeat.Time.T().UnixNano())/1e9)) // read-only
+	return t
+}
+
+func tableToHeartbeat(t *lua.LTable, heartbeat *models.Heartbeat) {
+	str := func(key string, target *string) {
+		if v, ok := t.RawGetString(key).(lua.LString); ok {
+			*target = string(v)
+		}
+	}
+
+	str("entity", &heartbeat.Entity)
+	str("type", &heartbeat.Type)
+	str("category", &heartbeat.Category)
+	str("project", &heartbeat.Project)
+	str("branch", &heartbeat.Branch)
+	str("language", &heartbeat.Language)
+	str("editor", &heartbeat.Editor)
+	str("operating_system", &heartbeat.OperatingSystem)
+	str("machine", &heartbeat.Machine)
+
+	if v, ok := t.RawGetString("is_write").(lua.LBool); ok {
+		heartbeat.IsWrite = bool(v)
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"fmt"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"sync"
+	"testing"
+	"time"
+)
+
+type HeartbeatScriptServiceTestSuite struct {
+	suite.Suite
+	TestUser      *models.User
+	TestHeartbeat *models.Heartbeat
+}
+
+func (suite *HeartbeatScriptServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.App.UserHeartbeatScripts = true
+	cfg.App.HeartbeatScriptTimeout = 50
+	config.Set(cfg)
+}
+
+func (suite *HeartbeatScriptServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUser = &models.User{ID: TestUserId}
+	suite.TestHeartbeat = &models.Heartbeat{
+		UserID:   TestUserId,
+		Entity:   "/home/muety/secret/main.go",
+		Project:  "WakAPI",
+		Language: "Go",
+		Time:     models.CustomTime(time.Now()),
+	}
+}
+
+func TestHeartbeatScriptServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(HeartbeatScriptServiceTestSuite))
+}
+
+func (suite *HeartbeatScriptServiceTestSuite) TestHeartbeatScriptService_Apply_Transform() {
+	sut := NewHeartbeatScriptService()
+
+	suite.TestUser.HeartbeatScript = `
+function transform(hb)
+	hb.project = string.lower(hb.project)
+	hb.entity = ("x"):rep(3)
+	return hb
+end`
+
+	// run repeatedly to make sure pooled interpreters behave the same
+	for i := 0; i < 3; i++ {
+		hb := *suite.TestHeartbeat
+		ok, err := sut.Apply(suite.TestUser, &hb)
+
+		assert.Nil(suite.T(), err)
+		assert.True(suite.T(), ok)
+		assert.Equal(suite.T(), "wakapi", hb.Project)
+		assert.Equal(suite.T(), "xxx", hb.Entity)
+		assert.Equal(suite.T(), "Go", hb.Language)
+	}
+}
+
+func (suite *HeartbeatScriptServiceTestSuite) TestHeartbeatScriptService_Apply_Concurrent() {
+	sut := NewHeartbeatScriptService()
+
+	suite.TestUser.HeartbeatScript = `
+function transform(hb)
+	hb.project = string.lower(hb.project)
+	return hb
+end`
+
+	// every run must read back its own result, even though interpreters are shared among runs
+	var wg sync.WaitGroup
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hb := *suite.TestHeartbeat
+			hb.Project = fmt.Sprintf("Project-%d", i)
+			if ok, err := sut.Apply(suite.TestUser, &hb); err == nil && ok {
+				results[i] = hb.Project
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, project := range results {
+		assert.Equal(suite.T(), fmt.Sprintf("project-%d", i), project)
+	}
+}
+
+func (suite *HeartbeatScriptServiceTestSuite) TestHeartbeatScriptService_Apply_Reject() {
+	sut := NewHeartbeatScriptService()
+
+	suite.TestUser.HeartbeatScript = `
+function transform(hb)
+	if string.find(hb.entity, "/secret/", 1, true) then
+		return nil
+	end
+	return hb
+end`
+
+	ok, err := sut.Apply(suite.TestUser, suite.TestHeartbeat)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), ok)
+
+	suite.TestHeartbeat.Entity = "/home/muety/public/main.go"
+	ok, err = sut.Apply(suite.TestUser, suite.TestHeartbeat)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), ok)
+}
+
+func (suite *HeartbeatScriptServiceTestSuite) TestHeartbeatScriptService_Apply_ForbiddenGlobals() {
+	sut := NewHeartbeatScriptService()
+
+	for _, call := range []string{`require("os")`, `dofile("/etc/passwd")`, `os.exit(1)`, `io.open("/etc/passwd")`, `load("return 1")`, `print("hello")`, `collectgarbage()`} {
+		suite.TestUser.HeartbeatScript = "function transform(hb)\n" + call + "\nreturn hb\nend"
+		ok, err := sut.Apply(suite.TestUser, suite.TestHeartbeat)
+		assert.Error(suite.T(), err, call)
+		assert.False(suite.T(), ok, call)
+	}
+}
+
+func (suite *HeartbeatScriptServiceTestSuite) TestHeartbeatScriptService_Apply_Growth() {
+	sut := NewHeartbeatScriptService()
+
+	// memory usage isn't limited, but scripts growing strings without end are aborted by the timeout
+	for _, code := range []string{
+		`local s = "x" while true do s = s .. "x" end`,
+		`local s = "x" while true do s = string.gsub(s, "x", "xx") end`,
+	} {
+		suite.TestUser.HeartbeatScript = "function transform(hb)\n" + code + "\nreturn hb\nend"
+
+		start := time.Now()
+		ok, err := sut.Apply(suite.TestUser, suite.TestHeartbeat)
+
+		assert.Error(suite.T(), err, code)
+		assert.False(suite.T(), ok, code)
+		assert.Less(suite.T(), time.Since(start), 1*time.Second, code)
+	}
+}
+
+func (suite *HeartbeatScriptServiceTestSuite) TestHeartbeatScriptService_Apply_Timeout() {
+	sut := NewHeartbeatScriptService()
+
+	suite.TestUser.HeartbeatScript = `
+function transform(hb)
+	while true do end
+end`
+
+	start := time.Now()
+	ok, err := sut.Apply(suite.TestUser, suite.TestHeartbeat)
+
+	assert.Error(suite.T(), err)
+	assert.False(suite.T(), ok)
+	assert.Less(suite.T(), time.Since(start), 1*time.Second)
+}
+
+func (suite *HeartbeatScriptServiceTestSuite) TestHeartbeatScriptService_Apply_Error() {
+	sut := NewHeartbeatScriptService()
+
+	suite.TestUser.HeartbeatScript = `
+function transform(hb)
+	error("failed")
+end`
+
+	ok, err := sut.Apply(suite.TestUser, suite.TestHeartbeat)
+	assert.Error(suite.T(), err)
+	assert.False(suite.T(), ok)
+
+	suite.TestUser.HeartbeatScript = `function transform(hb) return 42 end`
+	ok, err = sut.Apply(suite.TestUser, suite.TestHeartbeat)
+	assert.Error(suite.T(), err)
+	assert.False(suite.T(), ok)
+}
+
+func (suite *HeartbeatScriptServiceTestSuite) TestHeartbeatScriptService_Validate() {
+	sut := NewHeartbeatScriptService()
+
+	assert.Nil(suite.T(), sut.Validate(`function transform(hb) return hb end`))
+	assert.Error(suite.T(), sut.Validate(`function transform(hb) return hb`))   // syntax error
+	assert.Error(suite.T(), sut.Validate(`function convert(hb) return hb end`)) // missing function
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+	"time"
+)
+
+type HeartbeatServiceTestSuite struct {
+	suite.Suite
+	DB                  *gorm.DB
+	HeartbeatRepository *repositories.HeartbeatRepository
+	TestUser            *models.User
+}
+
+func (suite *HeartbeatServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+}
+
+func (suite *HeartbeatServiceTestSuite) BeforeTest(suiteName, testName string) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	// every connection to an in-memory database gets its own, empty one
+	sqlDb, _ := db.DB()
+	sqlDb.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&models.User{}, &models.Heartbeat{}, &models.HeartbeatCount{}, &models.SummaryInvalidation{}, &models.Duration{}, &models.DurationDay{}); err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	suite.DB = db
+	suite.HeartbeatRepository = repositories.NewHeartbeatRepository(db)
+	suite.TestUser = &models.User{ID: TestUserId}
+	suite.DB.Create(suite.TestUser)
+}
+
+func (suite *HeartbeatServiceTestSuite) AfterTest(suiteName, testName string) {
+	if sqlDb, err := suite.DB.DB(); err == nil {
+		sqlDb.Close()
+	}
+}
+
+func TestHeartbeatServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(HeartbeatServiceTestSuite))
+}
+
+func (suite *HeartbeatServiceTestSuite) TestHeartbeatService_Update_Duplicate() {
+	sut := NewHeartbeatService(suite.HeartbeatRepository, nil, NewJobService(), nil)
+
+	t0 := time.Date(2022, 10, 14, 5, 0, 0, 0, time.UTC)
+	assert.Nil(suite.T(), suite.HeartbeatRepository.InsertBatch([]*models.Heartbeat{
+		(&models.Heartbeat{UserID: TestUserId, Entity: "main.go", Project: "wakapi", Time: models.CustomTime(t0)}).Hashed(),
+		(&models.Heartbeat{UserID: TestUserId, Entity: "main.go", Project: "wakapi-legacy", Time: models.CustomTime(t0)}).Hashed(),
+	}))
+
+	heartbeats, err := suite.HeartbeatRepository.GetAllWithin(t0, t0.Add(time.Second), suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), heartbeats, 2)
+
+	// heartbeat as loaded from the database, i.e. carrying its previous hash
+	var legacy *models.Heartbeat
+	for _, h := range heartbeats {
+		if h.Project == "wakapi-legacy" {
+			legacy = h
+		}
+	}
+	assert.NotEmpty(suite.T(), legacy.Hash)
+
+	// renaming the project makes it identical to the other heartbeat
+	legacy.Project = "wakapi"
+	_, err = sut.Update(legacy)
+	assert.Equal(suite.T(), repositories.ErrDuplicateHeartbeat, err)
+
+	persisted, err := suite.HeartbeatRepository.GetById(legacy.ID)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "wakapi-legacy", persisted.Project)
+}
+
+func (suite *HeartbeatServiceTestSuite) TestHeartbeatService_Update() {
+	sut := NewHeartbeatService(suite.HeartbeatRepository, nil, NewJobService(), nil)
+
+	t0 := time.Date(2022, 10, 14, 5, 0, 0, 0, time.UTC)
+	heartbeat := (&models.Heartbeat{UserID: TestUserId, Entity: "main.go", Project: "wakapi-legacy", Time: models.CustomTime(t0)}).Hashed()
+	assert.Nil(suite.T(), suite.HeartbeatRepository.InsertBatch([]*models.Heartbeat{heartbeat}))
+
+	heartbeat.Project = "wakapi"
+	result, err := sut.Update(heartbeat)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), (&models.Heartbeat{UserID: TestUserId, Entity: "main.go", Project: "wakapi", Time: models.CustomTime(t0)}).Hashed().Hash, result.Hash)
+}
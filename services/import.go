@@ -0,0 +1,222 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+)
+
+var ErrImportInProgress = errors.New("an import from this source is already in progress")
+
+// the state of running imports is persisted at most this often, except for when they finish
+const importSaveInterval = 5 * time.Second
+
+// sources, whose imports are tracked
+var importSources = []string{imports.OriginWakatime, imports.OriginCodeStats, imports.OriginFile}
+
+// ImportService runs heartbeat imports from other services and keeps track of their progress. The state of every user's latest import per source
+// is persisted as key-value pair, so that an import, which was interrupted or failed to fetch single days, is resumed from its checkpoint, when started again.
+type ImportService struct {
+	config                 *config.Config
+	heartbeatService       IHeartbeatService
+	heartbeatScriptService IHeartbeatScriptService
+	keyValueService        IKeyValueService
+	lock                   sync.RWMutex
+	running                map[string]*models.ImportJob
+	savedAt                map[string]time.Time
+}
+
+func NewImportService(heartbeatService IHeartbeatService, heartbeatScriptService IHeartbeatScriptService, keyValueService IKeyValueService) *ImportService {
+	return &ImportService{
+		config:                 config.Get(),
+		heartbeatService:       heartbeatService,
+		heartbeatScriptService: heartbeatScriptService,
+		keyValueService:        keyValueService,
+		running:                map[string]*models.ImportJob{},
+		savedAt:                map[string]time.Time{},
+	}
+}
+
+// Get returns a snapshot of the user's latest import from the given source or nil, if the user never imported from there
+func (srv *ImportService) Get(user *models.User, source string) (*models.ImportJob, error) {
+	key := importKey(user, source)
+
+	srv.lock.RLock()
+	if job, ok := srv.running[key]; ok {
+		jobCopy := *job
+		srv.lock.RUnlock()
+		return &jobCopy, nil
+	}
+	srv.lock.RUnlock()
+
+	kv := srv.keyValueService.MustGetString(key)
+	if kv.Value == "" {
+		return nil, nil
+	}
+	var job models.ImportJob
+	if err := json.Unmarshal([]byte(kv.Value), &job); err != nil {
+		return nil, err
+	}
+	if !job.IsDone() {
+		// not running, but never finished either, e.g. due to a restart
+		job.Finish(errors.New("import was interrupted"))
+	}
+	return &job, nil
+}
+
+// GetAll returns the user's latest import from every source imported from before
+func (srv *ImportService) GetAll(user *models.User) ([]*models.ImportJob, error) {
+	jobs := make([]*models.ImportJob, 0, len(importSources))
+	for _, source := range importSources {
+		job, err := srv.Get(user, source)
+		if err != nil {
+			return nil, err
+		}
+		if job != nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// Run synchronously imports the user's heartbeats within the given range by means of the given importer. If the previous import
+// from the same source is resumable, i.e. it was interrupted or single days failed, its checkpoint is imported from instead, unless the range starts earlier anyway.
+func (srv *ImportService) Run(user *models.User, source string, importer imports.HeartbeatImporter, from, to time.Time) (*models.ImportJob, error) {
+	if previous, err := srv.Get(user, source); err == nil && previous != nil && previous.IsResumable() && previous.Checkpoint.Before(from) {
+		logbuch.Info("resuming %s import of user '%s' from %s", source, user.ID, previous.Checkpoint.Format(config.SimpleDateFormat))
+		from = previous.Checkpoint
+	}
+
+	job, err := srv.Begin(user, source, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	days, stream, err := importer.Import(user, from, to)
+	if err != nil {
+		srv.Finish(user, job, err)
+		return job, err
+	}
+	srv.Update(user, job, func(job *models.ImportJob) {
+		job.SetDays(days)
+	})
+
+	for day := range stream {
+		imported, rejected, err := srv.insert(user, day.Heartbeats)
+		if day.Err != nil {
+			err = day.Err
+		}
+		srv.Update(user, job, func(job *models.ImportJob) {
+			job.Rows += len(day.Heartbeats)
+			job.Imported += imported
+			job.Rejected += rejected
+			job.ProcessDay(day.Day, err)
+		})
+	}
+
+	srv.Finish(user, job, nil)
+	return job, nil
+}
+
+// Begin registers a new import, whose progress is to be reported via Update and Finish
+func (srv *ImportService) Begin(user *models.User, source string, from, to time.Time) (*models.ImportJob, error) {
+	key := importKey(user, source)
+
+	srv.lock.Lock()
+	if _, ok := srv.running[key]; ok {
+		srv.lock.Unlock()
+		return nil, ErrImportInProgress
+	}
+	job := models.NewImportJob(source, from, to)
+	srv.running[key] = job
+	srv.lock.Unlock()
+
+	srv.save(user, job)
+	return job, nil
+}
+
+// Update applies the given changes to a running import and persists its state every now and then
+func (srv *ImportService) Update(user *models.User, job *models.ImportJob, f func(*models.ImportJob)) {
+	key := importKey(user, job.Source)
+
+	srv.lock.Lock()
+	f(job)
+	due := time.Since(srv.savedAt[key]) >= importSaveInterval
+	srv.lock.Unlock()
+
+	if due {
+		srv.save(user, job)
+	}
+}
+
+// Finish marks a running import as done, optionally with an error, which prevented it from completing
+func (srv *ImportService) Finish(user *models.User, job *models.ImportJob, err error) {
+	key := importKey(user, job.Source)
+
+	srv.lock.Lock()
+	job.Finish(err)
+	srv.lock.Unlock()
+
+	srv.save(user, job)
+
+	srv.lock.Lock()
+	delete(srv.running, key)
+	delete(srv.savedAt, key)
+	srv.lock.Unlock()
+
+	logbuch.Info("finished %s import of user '%s' with %d heartbeats imported, %d rejected and %d errors", job.Source, user.ID, job.Imported, job.Rejected, job.Errors)
+}
+
+// insert stores an imported day's heartbeats in batches, after applying the user's heartbeat scripts, and returns the number of imported and rejected ones
+func (srv *ImportService) insert(user *models.User, heartbeats []*models.Heartbeat) (imported, rejected int, err error) {
+	batch := make([]*models.Heartbeat, 0, len(heartbeats))
+	for _, hb := range heartbeats {
+		// imported heartbeats are subject to the same scripts as ones sent by clients
+		if ok, err := srv.heartbeatScriptService.Apply(user, hb); err != nil || !ok || !hb.Valid() {
+			rejected++
+			continue
+		}
+		batch = append(batch, hb.Hashed())
+	}
+
+	// duplicates, e.g. of a day imported before an interruption already, are skipped on insert
+	for len(batch) > 0 {
+		n := len(batch)
+		if batchSize := srv.config.App.ImportBatchSize; batchSize > 0 && n > batchSize {
+			n = batchSize
+		}
+		if err := srv.heartbeatService.InsertBatch(batch[:n]); err != nil {
+			return imported, rejected, err
+		}
+		imported += n
+		batch = batch[n:]
+	}
+	return imported, rejected, nil
+}
+
+func (srv *ImportService) save(user *models.User, job *models.ImportJob) {
+	key := importKey(user, job.Source)
+
+	srv.lock.Lock()
+	data, err := json.Marshal(job)
+	srv.savedAt[key] = time.Now()
+	srv.lock.Unlock()
+
+	if err == nil {
+		err = srv.keyValueService.PutString(&models.KeyStringValue{Key: key, Value: string(data)})
+	}
+	if err != nil {
+		config.Log().Error("failed to save state of %s import of user '%s' - %v", job.Source, user.ID, err)
+	}
+}
+
+func importKey(user *models.User, source string) string {
+	return fmt.Sprintf("%s_%s_%s", config.KeyImport, source, user.ID)
+}
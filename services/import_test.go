@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeImporter streams two heartbeats for every day in range, except for the failing ones
+type fakeImporter struct {
+	failing map[int64]bool
+	from    time.Time // range requested last
+}
+
+func (f *fakeImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) ([]time.Time, <-chan *imports.ImportedDay, error) {
+	f.from = minFrom
+
+	days := make([]time.Time, 0)
+	for d := minFrom; d.Before(maxTo); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+
+	out := make(chan *imports.ImportedDay, len(days))
+	for _, d := range days {
+		if f.failing[d.Unix()] {
+			out <- &imports.ImportedDay{Day: d, Err: errors.New("got status 500 from wakatime api")}
+			continue
+		}
+		out <- &imports.ImportedDay{Day: d, Heartbeats: []*models.Heartbeat{
+			{User: user, UserID: user.ID, Entity: "main.go", Time: models.CustomTime(d.Add(1 * time.Hour))},
+			{User: user, UserID: user.ID, Entity: "main.go", Time: models.CustomTime(d.Add(2 * time.Hour))},
+		}}
+	}
+	close(out)
+
+	return days, out, nil
+}
+
+func (f *fakeImporter) ImportAll(user *models.User) ([]time.Time, <-chan *imports.ImportedDay, error) {
+	return f.Import(user, time.Time{}, time.Now())
+}
+
+type ImportServiceTestSuite struct {
+	suite.Suite
+	TestUser         *models.User
+	From             time.Time
+	To               time.Time
+	HeartbeatService *mocks.HeartbeatServiceMock
+	KeyValueService  *mocks.KeyValueServiceMock
+}
+
+func (suite *ImportServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.App.ImportBatchSize = 3
+	config.Set(cfg)
+
+	suite.TestUser = &models.User{ID: "user1"}
+	suite.From = time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	suite.To = suite.From.AddDate(0, 0, 3)
+}
+
+func (suite *ImportServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+}
+
+func TestImportServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ImportServiceTestSuite))
+}
+
+func (suite *ImportServiceTestSuite) TestImportService_Run() {
+	key := importKey(suite.TestUser, imports.OriginWakatime)
+	var saved string
+
+	suite.KeyValueService.On("MustGetString", key).Return(&models.KeyStringValue{Key: key})
+	suite.KeyValueService.On("PutString", mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(0).(*models.KeyStringValue).Value
+	}).Return(nil)
+	suite.HeartbeatService.On("InsertBatch", mock.Anything).Return(nil)
+
+	importer := &fakeImporter{failing: map[int64]bool{suite.From.AddDate(0, 0, 1).Unix(): true}}
+	sut := NewImportService(suite.HeartbeatService, NewHeartbeatScriptService(), suite.KeyValueService)
+
+	job, err := sut.Run(suite.TestUser, imports.OriginWakatime, importer, suite.From, suite.To)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), job.IsDone())
+	assert.Equal(suite.T(), 3, job.TotalDays)
+	assert.Equal(suite.T(), 3, job.ProcessedDays)
+	assert.Equal(suite.T(), 4, job.Rows)
+	assert.Equal(suite.T(), 4, job.Imported)
+	assert.Equal(suite.T(), 1, job.Errors)
+	assert.Equal(suite.T(), suite.From.AddDate(0, 0, 1), job.Checkpoint)
+	assert.Equal(suite.T(), 100, job.Progress())
+
+	var persisted models.ImportJob
+	assert.Nil(suite.T(), json.Unmarshal([]byte(saved), &persisted))
+	assert.True(suite.T(), persisted.IsDone())
+	assert.Equal(suite.T(), suite.From.AddDate(0, 0, 1).Unix(), persisted.Checkpoint.Unix())
+}
+
+func (suite *ImportServiceTestSuite) TestImportService_Run_Resume() {
+	key := importKey(suite.TestUser, imports.OriginWakatime)
+
+	previous := models.NewImportJob(imports.OriginWakatime, suite.From, suite.To)
+	previous.SetDays([]time.Time{suite.From, suite.From.AddDate(0, 0, 1), suite.From.AddDate(0, 0, 2)})
+	previous.ProcessDay(suite.From, nil)
+	previous.Finish(nil)
+	data, _ := json.Marshal(previous)
+
+	suite.KeyValueService.On("MustGetString", key).Return(&models.KeyStringValue{Key: key, Value: string(data)})
+	suite.KeyValueService.On("PutString", mock.Anything).Return(nil)
+	suite.HeartbeatService.On("InsertBatch", mock.Anything).Return(nil)
+
+	importer := &fakeImporter{}
+	sut := NewImportService(suite.HeartbeatService, NewHeartbeatScriptService(), suite.KeyValueService)
+
+	// the latest heartbeat imported before is from the last day, but the second one is still missing
+	job, err := sut.Run(suite.TestUser, imports.OriginWakatime, importer, suite.From.AddDate(0, 0, 2), suite.To)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.From.AddDate(0, 0, 1).Unix(), importer.from.Unix())
+	assert.Equal(suite.T(), 2, job.TotalDays)
+	assert.Zero(suite.T(), job.Errors)
+	assert.False(suite.T(), job.IsResumable())
+}
+
+func (suite *ImportServiceTestSuite) TestImportService_Begin_InProgress() {
+	key := importKey(suite.TestUser, imports.OriginFile)
+
+	suite.KeyValueService.On("MustGetString", key).Return(&models.KeyStringValue{Key: key})
+	suite.KeyValueService.On("PutString", mock.Anything).Return(nil)
+
+	sut := NewImportService(suite.HeartbeatService, NewHeartbeatScriptService(), suite.KeyValueService)
+
+	job, err := sut.Begin(suite.TestUser, imports.OriginFile, time.Time{}, time.Time{})
+	assert.Nil(suite.T(), err)
+
+	_, err = sut.Begin(suite.TestUser, imports.OriginFile, time.Time{}, time.Time{})
+	assert.Equal(suite.T(), ErrImportInProgress, err)
+
+	sut.Update(suite.TestUser, job, func(job *models.ImportJob) {
+		job.Rows += 10
+	})
+	running, err := sut.Get(suite.TestUser, imports.OriginFile)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 10, running.Rows)
+	assert.False(suite.T(), running.IsDone())
+
+	sut.Finish(suite.TestUser, job, nil)
+	_, err = sut.Begin(suite.TestUser, imports.OriginFile, time.Time{}, time.Time{})
+	assert.Nil(suite.T(), err)
+}
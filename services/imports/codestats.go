@@ -0,0 +1,163 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const OriginCodeStats = "codestats"
+
+// code::stats only knows xp per day and language, which are replayed as one heartbeat per minute of (approximated) coding time, starting at this time of the day
+const codeStatsDayStart = 9 * time.Hour
+
+// code::stats was launched in 2016, there's no older data
+var codeStatsEpoch = time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// CodeStatsHeartbeatImporter imports a user's history from their public code::stats profile. As code::stats doesn't track time,
+// but experience points, coding time is derived from the xp per language and day (see models.CodeStatsXpPerMinute). Projects, editors and machines are unknown.
+type CodeStatsHeartbeatImporter struct {
+	Username string
+	BaseUrl  string
+}
+
+func NewCodeStatsHeartbeatImporter(username string) *CodeStatsHeartbeatImporter {
+	return &CodeStatsHeartbeatImporter{
+		Username: username,
+		BaseUrl:  config.CodeStatsUrl,
+	}
+}
+
+func (c *CodeStatsHeartbeatImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) ([]time.Time, <-chan *ImportedDay, error) {
+	if minFrom.Before(codeStatsEpoch) {
+		minFrom = codeStatsEpoch
+	}
+
+	xps, err := c.fetchDayXps(minFrom)
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("failed to fetch xp - %v", err))
+	}
+
+	sort.Slice(xps, func(i, j int) bool {
+		if xps[i].Date == xps[j].Date {
+			return xps[i].Language < xps[j].Language
+		}
+		return xps[i].Date < xps[j].Date
+	})
+
+	days := make([]time.Time, 0)
+	xpsByDay := make(map[int64][]*models.CodeStatsDayXp)
+	for _, xp := range xps {
+		d, err := time.ParseInLocation(config.SimpleDateFormat, xp.Date, user.TZ())
+		if err != nil || d.Before(utils.StartOfDay(minFrom.In(user.TZ()))) || !d.Before(maxTo) {
+			continue
+		}
+		if len(days) == 0 || !d.Equal(days[len(days)-1]) {
+			days = append(days, d)
+		}
+		xpsByDay[d.Unix()] = append(xpsByDay[d.Unix()], xp)
+	}
+
+	out := make(chan *ImportedDay)
+
+	go func() {
+		defer close(out)
+		for _, day := range days {
+			out <- &ImportedDay{Day: day, Heartbeats: mapCodeStatsDay(day, xpsByDay[day.Unix()], user)}
+		}
+	}()
+
+	return days, out, nil
+}
+
+func (c *CodeStatsHeartbeatImporter) ImportAll(user *models.User) ([]time.Time, <-chan *ImportedDay, error) {
+	return c.Import(user, codeStatsEpoch, time.Now())
+}
+
+// https://codestats.net/profile-graph
+func (c *CodeStatsHeartbeatImporter) fetchDayXps(since time.Time) ([]*models.CodeStatsDayXp, error) {
+	httpClient := config.NewHttpClient(config.ProxyScopeImports, 30*time.Second)
+
+	query := fmt.Sprintf(`{ profile(username: %q) { dayLanguageXps: dayLanguageXps(since: %q) { date language xp } } }`, c.Username, since.Format(config.SimpleDateFormat))
+	payload, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.BaseUrl+config.CodeStatsGraphUrl, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return nil, errors.New(fmt.Sprintf("got status %d from code::stats api", res.StatusCode))
+	}
+
+	var data struct {
+		Data struct {
+			Profile *struct {
+				DayLanguageXps []*models.CodeStatsDayXp `json:"dayLanguageXps"`
+			} `json:"profile"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	if data.Data.Profile == nil {
+		return nil, errors.New(fmt.Sprintf("code::stats profile '%s' not found or private", c.Username))
+	}
+
+	return data.Data.Profile.DayLanguageXps, nil
+}
+
+// mapCodeStatsDay replays a day's xp per language as heartbeats, one per minute of coding time
+func mapCodeStatsDay(day time.Time, xps []*models.CodeStatsDayXp, user *models.User) []*models.Heartbeat {
+	heartbeats := make([]*models.Heartbeat, 0)
+	t := day.Add(codeStatsDayStart)
+	for _, xp := range xps {
+		minutes := int(models.XpToDuration(xp.Xp) / time.Minute)
+		for i := 0; i < minutes && t.Before(day.AddDate(0, 0, 1)); i++ {
+			heartbeats = append(heartbeats, mapCodeStatsHeartbeat(xp, t, user))
+			t = t.Add(time.Minute)
+		}
+	}
+	// languages are replayed back to back, so only the day's last one needs to be closed
+	if len(heartbeats) > 0 {
+		heartbeats = append(heartbeats, closingHeartbeat(heartbeats[len(heartbeats)-1], user))
+	}
+	return heartbeats
+}
+
+func mapCodeStatsHeartbeat(xp *models.CodeStatsDayXp, t time.Time, user *models.User) *models.Heartbeat {
+	return (&models.Heartbeat{
+		User:     user,
+		UserID:   user.ID,
+		Type:     "file",
+		Category: "coding",
+		Language: models.LanguageFromCodeStats(xp.Language),
+		Time:     models.CustomTime(t),
+		Origin:   OriginCodeStats,
+		OriginId: fmt.Sprintf("%s/%s", xp.Date, xp.Language),
+	}).Hashed()
+}
+
+func closingHeartbeat(last *models.Heartbeat, user *models.User) *models.Heartbeat {
+	h := *last
+	h.Time = models.CustomTime(last.Time.T().Add(time.Minute))
+	h.User = user
+	return h.Hashed()
+}
@@ -5,7 +5,19 @@ import (
 	"time"
 )
 
+// OriginFile marks heartbeats imported from an uploaded file, e.g. a previous data export
+const OriginFile = "file"
+
+// ImportedDay holds all heartbeats of a single day, as streamed by importers, or the error, which occurred while fetching them
+type ImportedDay struct {
+	Day        time.Time
+	Heartbeats []*models.Heartbeat
+	Err        error
+}
+
+// HeartbeatImporter fetches a user's heartbeats from another service. Import and ImportAll announce the days to be imported upfront, in chronological order,
+// and then stream them day by day, though not necessarily in the same order, so that imports can report their progress and be resumed from the first day not imported.
 type HeartbeatImporter interface {
-	Import(*models.User, time.Time, time.Time) <-chan *models.Heartbeat
-	ImportAll(*models.User) <-chan *models.Heartbeat
+	Import(*models.User, time.Time, time.Time) ([]time.Time, <-chan *ImportedDay, error)
+	ImportAll(*models.User) ([]time.Time, <-chan *ImportedDay, error)
 }
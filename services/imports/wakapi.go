@@ -0,0 +1,83 @@
+package imports
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+const OriginWakapi = "wakapi"
+
+// WakapiImporter fetches a user's settings and heartbeats from another wakapi instance, e.g. to migrate between self-hosted servers.
+// Other than wakatime's api, wakapi's serves heartbeats page by page in the order they were stored, so that a migration can be resumed after the last page.
+type WakapiImporter struct {
+	ApiUrl string // base url of the other instance's api, e.g. 'https://wakapi.example.org/api'
+	ApiKey string
+}
+
+func NewWakapiImporter(apiUrl, apiKey string) *WakapiImporter {
+	return &WakapiImporter{
+		ApiUrl: strings.TrimSuffix(apiUrl, "/"),
+		ApiKey: apiKey,
+	}
+}
+
+// FetchSettings fetches the user's aliases, language mappings, labels, relay rules and goals
+func (w *WakapiImporter) FetchSettings() (*models.SettingsDocument, error) {
+	var doc models.SettingsDocument
+	if err := w.get("/settings/export", &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// FetchPage fetches the next page of heartbeats after the given (remote) id and maps them to the given user
+func (w *WakapiImporter) FetchPage(user *models.User, afterId uint64) (*models.HeartbeatPage, error) {
+	var page models.HeartbeatPage
+	if err := w.get(fmt.Sprintf("/heartbeats/export?after_id=%d", afterId), &page); err != nil {
+		return nil, err
+	}
+	for i, h := range page.Data {
+		page.Data[i] = mapWakapiHeartbeat(h, user)
+	}
+	return &page, nil
+}
+
+func (w *WakapiImporter) get(path string, v interface{}) error {
+	httpClient := config.NewHttpClient(config.ProxyScopeImports, 30*time.Second)
+
+	req, err := http.NewRequest(http.MethodGet, w.ApiUrl+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(w.ApiKey))))
+	req.Header.Set("Accept", "application/json")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return errors.New(fmt.Sprintf("got status %d from wakapi api at %s", res.StatusCode, w.ApiUrl))
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func mapWakapiHeartbeat(h *models.Heartbeat, user *models.User) *models.Heartbeat {
+	h.OriginId = strconv.FormatUint(h.ID, 10)
+	h.ID = 0
+	h.User = user
+	h.UserID = user.ID
+	h.Origin = OriginWakapi
+	return h.Hashed()
+}
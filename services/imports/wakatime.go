@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/emvi/logbuch"
@@ -14,7 +15,6 @@ import (
 	"github.com/muety/wakapi/models"
 	wakatime "github.com/muety/wakapi/models/compat/wakatime/v1"
 	"github.com/muety/wakapi/utils"
-	"go.uber.org/atomic"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -37,40 +37,38 @@ func NewWakatimeHeartbeatImporter(apiKey string) *WakatimeHeartbeatImporter {
 	}
 }
 
-func (w *WakatimeHeartbeatImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) <-chan *models.Heartbeat {
-	out := make(chan *models.Heartbeat)
+func (w *WakatimeHeartbeatImporter) Import(user *models.User, minFrom time.Time, maxTo time.Time) ([]time.Time, <-chan *ImportedDay, error) {
+	baseUrl := user.WakaTimeURL(config.WakatimeApiUrl)
 
-	go func(user *models.User, out chan *models.Heartbeat) {
-		baseUrl := user.WakaTimeURL(config.WakatimeApiUrl)
+	startDate, endDate, err := w.fetchRange(baseUrl)
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("failed to fetch date range - %v", err))
+	}
 
-		startDate, endDate, err := w.fetchRange(baseUrl)
-		if err != nil {
-			config.Log().Error("failed to fetch date range while importing wakatime heartbeats for user '%s' - %v", user.ID, err)
-			return
-		}
+	if startDate.Before(minFrom) {
+		startDate = minFrom
+	}
+	if endDate.After(maxTo) {
+		endDate = maxTo
+	}
 
-		if startDate.Before(minFrom) {
-			startDate = minFrom
-		}
-		if endDate.After(maxTo) {
-			endDate = maxTo
-		}
+	userAgents, err := w.fetchUserAgents(baseUrl)
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("failed to fetch user agents - %v", err))
+	}
 
-		userAgents, err := w.fetchUserAgents(baseUrl)
-		if err != nil {
-			config.Log().Error("failed to fetch user agents while importing wakatime heartbeats for user '%s' - %v", user.ID, err)
-			return
-		}
+	machinesNames, err := w.fetchMachineNames(baseUrl)
+	if err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("failed to fetch machine names - %v", err))
+	}
 
-		machinesNames, err := w.fetchMachineNames(baseUrl)
-		if err != nil {
-			config.Log().Error("failed to fetch machine names while importing wakatime heartbeats for user '%s' - %v", user.ID, err)
-			return
-		}
+	days := generateDays(startDate, endDate)
+	out := make(chan *ImportedDay)
 
-		days := generateDays(startDate, endDate)
+	go func() {
+		defer close(out)
 
-		c := atomic.NewUint32(uint32(len(days)))
+		var wg sync.WaitGroup
 		ctx := context.TODO()
 		sem := semaphore.NewWeighted(maxWorkers)
 
@@ -80,38 +78,40 @@ func (w *WakatimeHeartbeatImporter) Import(user *models.User, minFrom time.Time,
 				break
 			}
 
+			wg.Add(1)
 			go func(day time.Time) {
+				defer wg.Done()
 				defer sem.Release(1)
 				defer time.Sleep(throttleDelay)
 
 				d := day.Format(config.SimpleDateFormat)
-				heartbeats, err := w.fetchHeartbeats(d, baseUrl)
+				entries, err := w.fetchHeartbeats(d, baseUrl)
 				if err != nil {
-					config.Log().Error("failed to fetch heartbeats for day '%s' and user '%s' - &v", d, user.ID, err)
-				}
-
-				for _, h := range heartbeats {
-					out <- mapHeartbeat(h, userAgents, machinesNames, user)
+					config.Log().Error("failed to fetch heartbeats for day '%s' and user '%s' - %v", d, user.ID, err)
 				}
 
-				if c.Dec() == 0 {
-					close(out)
+				result := &ImportedDay{Day: day, Heartbeats: make([]*models.Heartbeat, 0, len(entries)), Err: err}
+				for _, h := range entries {
+					result.Heartbeats = append(result.Heartbeats, mapHeartbeat(h, userAgents, machinesNames, user))
 				}
+				out <- result
 			}(d)
 		}
-	}(user, out)
 
-	return out
+		wg.Wait()
+	}()
+
+	return days, out, nil
 }
 
-func (w *WakatimeHeartbeatImporter) ImportAll(user *models.User) <-chan *models.Heartbeat {
+func (w *WakatimeHeartbeatImporter) ImportAll(user *models.User) ([]time.Time, <-chan *ImportedDay, error) {
 	return w.Import(user, time.Time{}, time.Now())
 }
 
 // https://wakatime.com/api/v1/users/current/heartbeats?date=2021-02-05
 // https://pastr.de/p/b5p4od5s8w0pfntmwoi117jy
 func (w *WakatimeHeartbeatImporter) fetchHeartbeats(day string, baseUrl string) ([]*wakatime.HeartbeatEntry, error) {
-	httpClient := &http.Client{Timeout: 10 * time.Second}
+	httpClient := config.NewHttpClient(config.ProxyScopeImports, 10*time.Second)
 
 	req, err := http.NewRequest(http.MethodGet, baseUrl+config.WakatimeApiHeartbeatsUrl, nil)
 	if err != nil {
@@ -140,7 +140,7 @@ func (w *WakatimeHeartbeatImporter) fetchHeartbeats(day string, baseUrl string)
 // https://wakatime.com/api/v1/users/current/all_time_since_today
 // https://pastr.de/p/w8xb4biv575pu32pox7jj2gr
 func (w *WakatimeHeartbeatImporter) fetchRange(baseUrl string) (time.Time, time.Time, error) {
-	httpClient := &http.Client{Timeout: 10 * time.Second}
+	httpClient := config.NewHttpClient(config.ProxyScopeImports, 10*time.Second)
 
 	notime := time.Time{}
 
@@ -175,7 +175,7 @@ func (w *WakatimeHeartbeatImporter) fetchRange(baseUrl string) (time.Time, time.
 // https://wakatime.com/api/v1/users/current/user_agents
 // https://pastr.de/p/05k5do8q108k94lic4lfl3pc
 func (w *WakatimeHeartbeatImporter) fetchUserAgents(baseUrl string) (map[string]*wakatime.UserAgentEntry, error) {
-	httpClient := &http.Client{Timeout: 10 * time.Second}
+	httpClient := config.NewHttpClient(config.ProxyScopeImports, 10*time.Second)
 
 	userAgents := make(map[string]*wakatime.UserAgentEntry)
 
@@ -211,7 +211,7 @@ func (w *WakatimeHeartbeatImporter) fetchUserAgents(baseUrl string) (map[string]
 // https://wakatime.com/api/v1/users/current/machine_names
 // https://pastr.de/p/v58cv0xrupp3zvyyv8o6973j
 func (w *WakatimeHeartbeatImporter) fetchMachineNames(baseUrl string) (map[string]*wakatime.MachineEntry, error) {
-	httpClient := &http.Client{Timeout: 10 * time.Second}
+	httpClient := config.NewHttpClient(config.ProxyScopeImports, 10*time.Second)
 
 	machines := make(map[string]*wakatime.MachineEntry)
 
@@ -281,6 +281,8 @@ func mapHeartbeat(
 		Branch:          entry.Branch,
 		Language:        entry.Language,
 		IsWrite:         entry.IsWrite,
+		Lines:           entry.Lines,
+		CursorPos:       entry.CursorPos,
 		Editor:          ua.Editor,
 		OperatingSystem: ua.Os,
 		Machine:         ma.Value,
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-co-op/gocron"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+const inactivityCheckTime = "10:00"
+
+// InactivityService alerts users, who haven't sent any heartbeats for a configurable number of days, either at all or from a single machine, to catch broken plugin installations early.
+// Checks run once a day and only alert about inactivity, that started exactly that many days ago, so every inactive period is alerted about only once.
+type InactivityService struct {
+	config              *config.Config
+	userService         IUserService
+	heartbeatService    IHeartbeatService
+	mailService         IMailService
+	notificationService INotificationService
+	jobService          IJobService
+}
+
+func NewInactivityService(userService IUserService, heartbeatService IHeartbeatService, mailService IMailService, notificationService INotificationService, jobService IJobService) *InactivityService {
+	return &InactivityService{
+		config:              config.Get(),
+		userService:         userService,
+		heartbeatService:    heartbeatService,
+		mailService:         mailService,
+		notificationService: notificationService,
+		jobService:          jobService,
+	}
+}
+
+func (srv *InactivityService) Schedule() {
+	logbuch.Info("scheduling inactivity checks")
+
+	s := gocron.NewScheduler(time.Local)
+	s.Every(1).Day().At(inactivityCheckTime).Do(srv.checkAll)
+	s.StartBlocking()
+}
+
+func (srv *InactivityService) checkAll() {
+	srv.jobService.Track(models.JobInactivity, "", func() error {
+		users, err := srv.userService.GetAll()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, u := range users {
+			if u.InactivityAlertDays <= 0 {
+				continue
+			}
+			if err := srv.check(u, now); err != nil {
+				config.Log().Error("failed to check inactivity of user '%s' - %v", u.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetAlerts returns the inactivity periods, that reached the user's threshold within the day before the given time
+func (srv *InactivityService) GetAlerts(user *models.User, now time.Time) ([]*models.InactivityAlert, error) {
+	if user.InactivityAlertDays <= 0 {
+		return []*models.InactivityAlert{}, nil
+	}
+
+	threshold := now.Add(-time.Duration(user.InactivityAlertDays) * 24 * time.Hour)
+	since := threshold.Add(-24 * time.Hour)
+	reached := func(t time.Time) bool {
+		return t.After(since) && !t.After(threshold)
+	}
+
+	latest, err := srv.heartbeatService.GetLatestByUser(user)
+	if err != nil || latest == nil {
+		return []*models.InactivityAlert{}, nil // no heartbeats at all, nothing to be broken
+	}
+
+	// no alerts about single machines, while the user is inactive altogether
+	if !latest.Time.T().After(threshold) {
+		if reached(latest.Time.T()) {
+			return []*models.InactivityAlert{{LastActivity: latest.Time.T(), Days: user.InactivityAlertDays}}, nil
+		}
+		return []*models.InactivityAlert{}, nil
+	}
+
+	machines, err := srv.heartbeatService.GetMachineActivityByUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*models.InactivityAlert, 0)
+	for _, m := range machines {
+		if reached(m.LastActivity.T()) {
+			alerts = append(alerts, &models.InactivityAlert{Machine: m.Machine, LastActivity: m.LastActivity.T(), Days: user.InactivityAlertDays})
+		}
+	}
+	return alerts, nil
+}
+
+func (srv *InactivityService) check(user *models.User, now time.Time) error {
+	alerts, err := srv.GetAlerts(user, now)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range alerts {
+		if srv.notificationService.IsEnabled(user, models.NotificationEventInactivity, models.NotificationChannelEmail) {
+			if err := srv.mailService.SendInactivityAlert(user, a); err != nil {
+				return err
+			}
+			logbuch.Info("sent inactivity alert (machine: '%s') to user '%s'", a.Machine, user.ID)
+		}
+
+		srv.notificationService.Notify(user, newInactivityNotification(a, srv.config.Server.GetPublicUrl()))
+	}
+
+	return nil
+}
+
+func newInactivityNotification(alert *models.InactivityAlert, publicUrl string) *models.Notification {
+	text := fmt.Sprintf("Wakapi has not received any heartbeats from you since %s. If you haven't been coding, you can ignore this message, otherwise please check your editor plugins.", utils.FormatDateHuman(alert.LastActivity))
+	if alert.IsMachine() {
+		text = fmt.Sprintf("Wakapi has not received any heartbeats from your machine %s since %s, while other machines keep reporting. Please check the editor plugins on it.", alert.Machine, utils.FormatDateHuman(alert.LastActivity))
+	}
+
+	return &models.Notification{
+		Event: models.NotificationEventInactivity,
+		Title: fmt.Sprintf("No coding activity for %d days", alert.Days),
+		Text:  text,
+		Link:  publicUrl + "/settings#account",
+	}
+}
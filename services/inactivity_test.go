@@ -0,0 +1,100 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type InactivityServiceTestSuite struct {
+	suite.Suite
+	TestUser            *models.User
+	Now                 time.Time
+	UserService         *mocks.UserServiceMock
+	HeartbeatService    *mocks.HeartbeatServiceMock
+	MailService         *mocks.MailServiceMock
+	NotificationService *mocks.NotificationServiceMock
+}
+
+func (suite *InactivityServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: "user1", Email: "john@example.org", InactivityAlertDays: 3}
+	suite.Now = time.Date(2022, 11, 10, 10, 0, 0, 0, time.UTC)
+}
+
+func (suite *InactivityServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.MailService = new(mocks.MailServiceMock)
+	suite.NotificationService = new(mocks.NotificationServiceMock)
+}
+
+func TestInactivityServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(InactivityServiceTestSuite))
+}
+
+func (suite *InactivityServiceTestSuite) TestInactivityService_GetAlerts_Inactive() {
+	sut := NewInactivityService(suite.UserService, suite.HeartbeatService, suite.MailService, suite.NotificationService, nil)
+
+	last := suite.Now.Add(-3*24*time.Hour - 2*time.Hour) // threshold crossed within the last day
+	suite.HeartbeatService.On("GetLatestByUser", suite.TestUser).Return(&models.Heartbeat{Time: models.CustomTime(last)}, nil)
+
+	result, err := sut.GetAlerts(suite.TestUser, suite.Now)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 1)
+	assert.False(suite.T(), result[0].IsMachine())
+	assert.Equal(suite.T(), 3, result[0].Days)
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "GetMachineActivityByUser", mock.Anything)
+}
+
+func (suite *InactivityServiceTestSuite) TestInactivityService_GetAlerts_AlreadyAlerted() {
+	sut := NewInactivityService(suite.UserService, suite.HeartbeatService, suite.MailService, suite.NotificationService, nil)
+
+	last := suite.Now.Add(-5 * 24 * time.Hour) // alerted about two days ago
+	suite.HeartbeatService.On("GetLatestByUser", suite.TestUser).Return(&models.Heartbeat{Time: models.CustomTime(last)}, nil)
+
+	result, err := sut.GetAlerts(suite.TestUser, suite.Now)
+
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), result)
+}
+
+func (suite *InactivityServiceTestSuite) TestInactivityService_GetAlerts_Machine() {
+	sut := NewInactivityService(suite.UserService, suite.HeartbeatService, suite.MailService, suite.NotificationService, nil)
+
+	suite.HeartbeatService.On("GetLatestByUser", suite.TestUser).Return(&models.Heartbeat{Time: models.CustomTime(suite.Now.Add(-1 * time.Hour))}, nil)
+	suite.HeartbeatService.On("GetMachineActivityByUser", suite.TestUser).Return([]*models.MachineActivity{
+		{Machine: "laptop", LastActivity: models.CustomTime(suite.Now.Add(-1 * time.Hour))},
+		{Machine: "desktop", LastActivity: models.CustomTime(suite.Now.Add(-3*24*time.Hour - time.Minute))},
+		{Machine: "old-pc", LastActivity: models.CustomTime(suite.Now.Add(-90 * 24 * time.Hour))},
+	}, nil)
+
+	result, err := sut.GetAlerts(suite.TestUser, suite.Now)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 1)
+	assert.Equal(suite.T(), "desktop", result[0].Machine)
+}
+
+func (suite *InactivityServiceTestSuite) TestInactivityService_Check() {
+	sut := NewInactivityService(suite.UserService, suite.HeartbeatService, suite.MailService, suite.NotificationService, nil)
+
+	last := suite.Now.Add(-3*24*time.Hour - 2*time.Hour)
+	suite.HeartbeatService.On("GetLatestByUser", suite.TestUser).Return(&models.Heartbeat{Time: models.CustomTime(last)}, nil)
+	suite.NotificationService.On("IsEnabled", suite.TestUser, models.NotificationEventInactivity, models.NotificationChannelEmail).Return(true)
+	suite.NotificationService.On("Notify", suite.TestUser, mock.Anything).Return(nil)
+	suite.MailService.On("SendInactivityAlert", suite.TestUser, mock.Anything).Return(nil)
+
+	err := sut.check(suite.TestUser, suite.Now)
+
+	assert.Nil(suite.T(), err)
+	suite.MailService.AssertNumberOfCalls(suite.T(), "SendInactivityAlert", 1)
+	suite.NotificationService.AssertNumberOfCalls(suite.T(), "Notify", 1)
+}
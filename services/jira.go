@@ -0,0 +1,255 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-co-op/gocron"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+)
+
+const (
+	jiraSyncTime     = "04:00" // after summaries were generated
+	jiraSyncDays     = 7       // past days to push, so that time tracked later on (e.g. offline) is still considered
+	jiraMinTimeSpent = 60      // jira rejects worklogs of less than a minute
+	jiraLogLimit     = 100
+	jiraDateFormat   = "2006-01-02T15:04:05.000-0700"
+	jiraComment      = "Tracked with Wakapi"
+)
+
+var ErrJiraNotConfigured = errors.New("jira credentials are not set")
+
+type JiraService struct {
+	config        *config.Config
+	repository    repositories.IJiraWorklogRepository
+	userService   IUserService
+	ticketService ITicketService
+	jobService    IJobService
+	httpClient    *http.Client
+}
+
+func NewJiraService(jiraWorklogRepo repositories.IJiraWorklogRepository, userService IUserService, ticketService ITicketService, jobService IJobService) *JiraService {
+	return &JiraService{
+		config:        config.Get(),
+		repository:    jiraWorklogRepo,
+		userService:   userService,
+		ticketService: ticketService,
+		jobService:    jobService,
+		httpClient:    config.NewHttpClient(config.ProxyScopeIntegrations, 10*time.Second),
+	}
+}
+
+// Schedule daily pushes the time per ticket of the past days to jira for every user, who has set jira credentials
+func (srv *JiraService) Schedule() {
+	logbuch.Info("scheduling jira worklog sync")
+
+	s := gocron.NewScheduler(time.Local)
+	s.Every(1).Day().At(jiraSyncTime).Do(srv.syncAll)
+	s.StartBlocking()
+}
+
+func (srv *JiraService) syncAll() {
+	users, err := srv.userService.GetAll()
+	if err != nil {
+		config.Log().Error("failed to fetch users for jira sync - %v", err)
+		return
+	}
+
+	for _, u := range users {
+		if !u.HasJiraCredentials() {
+			continue
+		}
+		if err := srv.Run(u); err != nil {
+			config.Log().Error("failed to sync jira worklogs of user '%s' - %v", u.ID, err)
+		}
+	}
+}
+
+// Run pushes the user's time per ticket to jira worklogs as a tracked background job
+func (srv *JiraService) Run(user *models.User) error {
+	return srv.jobService.Track(models.JobJiraSync, user.ID, func() error {
+		_, err := srv.Sync(user, false)
+		return err
+	})
+}
+
+// Sync pushes the time per ticket and day of the past days (excluding today) to jira, creating a worklog per ticket and day or updating a previously created one.
+// In dry-run mode, nothing is pushed, but the returned worklogs tell what would be done.
+// Failed pushes are logged to the worklog and retried on the next sync.
+func (srv *JiraService) Sync(user *models.User, dryRun bool) ([]*models.JiraWorklog, error) {
+	if !user.HasJiraCredentials() {
+		return nil, ErrJiraNotConfigured
+	}
+
+	to := utils.StartOfDay(time.Now().In(user.TZ()))
+	from := to.AddDate(0, 0, -jiraSyncDays)
+
+	entries, err := srv.ticketService.GetWorklog(from, to, user)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := srv.repository.GetByUserWithin(user.ID, from.Format(config.SimpleDateFormat), to.AddDate(0, 0, -1).Format(config.SimpleDateFormat))
+	if err != nil {
+		return nil, err
+	}
+	worklogs := make(map[string]*models.JiraWorklog, len(existing))
+	for _, w := range existing {
+		worklogs[w.Key()] = w
+	}
+
+	results := make([]*models.JiraWorklog, 0, len(entries))
+	var pushErr error
+
+	for _, e := range entries {
+		// jira only accepts full minutes
+		timeSpent := (e.TimeSpentSeconds + 30) / 60 * 60
+		if timeSpent < jiraMinTimeSpent {
+			continue
+		}
+
+		worklog := &models.JiraWorklog{UserID: user.ID, Ticket: e.Ticket, Date: e.Date.Format(config.SimpleDateFormat)}
+		if w, ok := worklogs[worklog.Key()]; ok {
+			worklog = w
+		}
+
+		if worklog.WorklogID == "" {
+			worklog.Action = models.JiraWorklogCreate
+		} else if worklog.TimeSpentSeconds != timeSpent {
+			worklog.Action = models.JiraWorklogUpdate
+		} else {
+			worklog.Action = models.JiraWorklogUnchanged
+		}
+
+		if dryRun || worklog.Action == models.JiraWorklogUnchanged {
+			worklog.TimeSpentSeconds = timeSpent
+			results = append(results, worklog)
+			continue
+		}
+
+		worklogId, err := srv.push(user, worklog, timeSpent)
+		now := models.CustomTime(time.Now())
+		worklog.SyncedAt = &now
+		if err != nil {
+			logbuch.Warn("failed to push jira worklog for ticket '%s' of user '%s' - %v", worklog.Ticket, user.ID, err)
+			worklog.Error = err.Error()
+			if pushErr == nil {
+				pushErr = err
+			}
+		} else {
+			worklog.WorklogID, worklog.TimeSpentSeconds, worklog.Error = worklogId, timeSpent, ""
+		}
+
+		if _, err := srv.repository.Upsert(worklog); err != nil {
+			return results, err
+		}
+		results = append(results, worklog)
+	}
+
+	return results, pushErr
+}
+
+// GetLog returns the user's most recently synced worklogs
+func (srv *JiraService) GetLog(user *models.User) ([]*models.JiraWorklog, error) {
+	return srv.repository.GetByUser(user.ID, jiraLogLimit)
+}
+
+// ValidateCredentials checks whether the given credentials are accepted by the given jira site
+func (srv *JiraService) ValidateCredentials(siteUrl, email, apiToken string) error {
+	req, err := srv.newRequest(http.MethodGet, siteUrl, "/rest/api/3/myself", email, apiToken, nil)
+	if err != nil {
+		return err
+	}
+	res, err := srv.do(req)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// push creates or updates the worklog in jira and returns its id
+func (srv *JiraService) push(user *models.User, worklog *models.JiraWorklog, timeSpent int64) (string, error) {
+	day, err := time.ParseInLocation(config.SimpleDateFormat, worklog.Date, user.TZ())
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"started":          day.Add(12 * time.Hour).Format(jiraDateFormat),
+		"timeSpentSeconds": timeSpent,
+		"comment": map[string]interface{}{ // atlassian document format
+			"type":    "doc",
+			"version": 1,
+			"content": []interface{}{
+				map[string]interface{}{
+					"type":    "paragraph",
+					"content": []interface{}{map[string]interface{}{"type": "text", "text": jiraComment}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	method, path := http.MethodPost, fmt.Sprintf("/rest/api/3/issue/%s/worklog", url.PathEscape(worklog.Ticket))
+	if worklog.WorklogID != "" {
+		method, path = http.MethodPut, path+"/"+url.PathEscape(worklog.WorklogID)
+	}
+
+	req, err := srv.newRequest(method, user.JiraUrl, path, user.JiraEmail, user.JiraApiToken, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	res, err := srv.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+func (srv *JiraService) newRequest(method, siteUrl, path, email, apiToken string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(siteUrl, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(email, apiToken)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+func (srv *JiraService) do(req *http.Request) (*http.Response, error) {
+	res, err := srv.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		res.Body.Close()
+		return nil, fmt.Errorf("jira responded with status %d - %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return res, nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type JiraServiceTestSuite struct {
+	suite.Suite
+	TestUser              *models.User
+	TestDay               time.Time
+	Server                *httptest.Server
+	Requests              []*http.Request
+	RequestBodies         []map[string]interface{}
+	JiraWorklogRepository *mocks.JiraWorklogRepositoryMock
+	TicketService         *mocks.TicketServiceMock
+	UserService           *mocks.UserServiceMock
+}
+
+func (suite *JiraServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+
+	suite.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		suite.Requests = append(suite.Requests, r)
+		suite.RequestBodies = append(suite.RequestBodies, body)
+
+		if email, token, ok := r.BasicAuth(); !ok || email != "john@example.org" || token != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /rest/api/3/issue/PROJ-1/worklog":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "100"}`))
+		case "PUT /rest/api/3/issue/PROJ-2/worklog/200":
+			w.Write([]byte(`{"id": "200"}`))
+		case "GET /rest/api/3/myself":
+			w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errorMessages": ["Issue does not exist or you do not have permission to see it."]}`))
+		}
+	}))
+}
+
+func (suite *JiraServiceTestSuite) TearDownSuite() {
+	suite.Server.Close()
+}
+
+func (suite *JiraServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUser = &models.User{ID: "johndoe", JiraUrl: suite.Server.URL + "/", JiraEmail: "john@example.org", JiraApiToken: "secret"}
+	suite.TestDay = utils.StartOfDay(time.Now()).AddDate(0, 0, -1)
+	suite.Requests = nil
+	suite.RequestBodies = nil
+
+	date := suite.TestDay.Format(config.SimpleDateFormat)
+	suite.JiraWorklogRepository = new(mocks.JiraWorklogRepositoryMock)
+	suite.JiraWorklogRepository.On("GetByUserWithin", suite.TestUser.ID, mock.Anything, mock.Anything).Return([]*models.JiraWorklog{
+		{ID: 1, UserID: suite.TestUser.ID, Ticket: "PROJ-2", Date: date, TimeSpentSeconds: 600, WorklogID: "200"},
+		{ID: 2, UserID: suite.TestUser.ID, Ticket: "PROJ-4", Date: date, TimeSpentSeconds: 1800, WorklogID: "300"},
+	}, nil)
+	suite.JiraWorklogRepository.On("Upsert", mock.Anything).Return(&models.JiraWorklog{}, nil)
+
+	suite.TicketService = new(mocks.TicketServiceMock)
+	suite.TicketService.On("GetWorklog", mock.Anything, mock.Anything, suite.TestUser).Return([]*models.WorklogEntry{
+		{Date: suite.TestDay, Ticket: "PROJ-1", TimeSpentSeconds: 1790}, // create
+		{Date: suite.TestDay, Ticket: "PROJ-2", TimeSpentSeconds: 900},  // update
+		{Date: suite.TestDay, Ticket: "PROJ-3", TimeSpentSeconds: 300},  // create, but fails
+		{Date: suite.TestDay, Ticket: "PROJ-4", TimeSpentSeconds: 1810}, // unchanged after rounding
+		{Date: suite.TestDay, Ticket: "PROJ-5", TimeSpentSeconds: 20},   // too short
+	}, nil)
+
+	suite.UserService = new(mocks.UserServiceMock)
+}
+
+func TestJiraServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(JiraServiceTestSuite))
+}
+
+func (suite *JiraServiceTestSuite) TestJiraService_Sync_DryRun() {
+	sut := NewJiraService(suite.JiraWorklogRepository, suite.UserService, suite.TicketService, NewJobService())
+
+	result, err := sut.Sync(suite.TestUser, true)
+
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), suite.Requests)
+	suite.JiraWorklogRepository.AssertNotCalled(suite.T(), "Upsert", mock.Anything)
+
+	assert.Len(suite.T(), result, 4)
+	assert.Equal(suite.T(), []string{models.JiraWorklogCreate, models.JiraWorklogUpdate, models.JiraWorklogCreate, models.JiraWorklogUnchanged}, actions(result))
+	assert.Equal(suite.T(), int64(1800), result[0].TimeSpentSeconds)
+	assert.Equal(suite.T(), int64(900), result[1].TimeSpentSeconds)
+}
+
+func (suite *JiraServiceTestSuite) TestJiraService_Sync() {
+	sut := NewJiraService(suite.JiraWorklogRepository, suite.UserService, suite.TicketService, NewJobService())
+
+	result, err := sut.Sync(suite.TestUser, false)
+
+	assert.NotNil(suite.T(), err) // PROJ-3 failed
+	assert.Len(suite.T(), result, 4)
+	assert.Len(suite.T(), suite.Requests, 3)
+	suite.JiraWorklogRepository.AssertNumberOfCalls(suite.T(), "Upsert", 3)
+
+	assert.Equal(suite.T(), "100", result[0].WorklogID)
+	assert.Equal(suite.T(), int64(1800), result[0].TimeSpentSeconds)
+	assert.Empty(suite.T(), result[0].Error)
+	assert.NotNil(suite.T(), result[0].SyncedAt)
+	assert.Equal(suite.T(), float64(1800), suite.RequestBodies[0]["timeSpentSeconds"])
+	assert.Equal(suite.T(), suite.TestDay.Add(12*time.Hour).Format(jiraDateFormat), suite.RequestBodies[0]["started"])
+
+	assert.Equal(suite.T(), "200", result[1].WorklogID)
+	assert.Equal(suite.T(), int64(900), result[1].TimeSpentSeconds)
+	assert.Equal(suite.T(), http.MethodPut, suite.Requests[1].Method)
+
+	assert.Empty(suite.T(), result[2].WorklogID)
+	assert.Contains(suite.T(), result[2].Error, "404")
+
+	assert.Equal(suite.T(), models.JiraWorklogUnchanged, result[3].Action)
+	assert.Nil(suite.T(), result[3].SyncedAt)
+}
+
+func (suite *JiraServiceTestSuite) TestJiraService_Sync_NotConfigured() {
+	sut := NewJiraService(suite.JiraWorklogRepository, suite.UserService, suite.TicketService, NewJobService())
+
+	_, err := sut.Sync(&models.User{ID: "johndoe"}, true)
+	assert.Equal(suite.T(), ErrJiraNotConfigured, err)
+}
+
+func (suite *JiraServiceTestSuite) TestJiraService_ValidateCredentials() {
+	sut := NewJiraService(suite.JiraWorklogRepository, suite.UserService, suite.TicketService, NewJobService())
+
+	assert.Nil(suite.T(), sut.ValidateCredentials(suite.Server.URL, "john@example.org", "secret"))
+	assert.NotNil(suite.T(), sut.ValidateCredentials(suite.Server.URL, "john@example.org", "wrong"))
+}
+
+func actions(worklogs []*models.JiraWorklog) []string {
+	result := make([]string, len(worklogs))
+	for i, w := range worklogs {
+		result[i] = w.Action
+	}
+	return result
+}
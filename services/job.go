@@ -0,0 +1,103 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// finished jobs are forgotten after this time, so that one-off jobs (e.g. per-user exports) won't pile up
+const jobRetention = 7 * 24 * time.Hour
+
+// JobService is an in-memory registry keeping track of the latest run of every background task
+type JobService struct {
+	config *config.Config
+	lock   sync.RWMutex
+	jobs   map[string]*models.JobStatus
+}
+
+func NewJobService() *JobService {
+	return &JobService{
+		config: config.Get(),
+		jobs:   map[string]*models.JobStatus{},
+	}
+}
+
+// Start marks the given job as running and returns a callback to be invoked with the job's outcome once it has finished
+func (srv *JobService) Start(name, userId string) func(error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	srv.prune()
+
+	job := &models.JobStatus{Name: name, UserID: userId}
+	if existing, ok := srv.jobs[job.Key()]; ok {
+		job = existing
+	} else {
+		srv.jobs[job.Key()] = job
+	}
+
+	job.Status = models.JobStatusRunning
+	job.StartedAt = time.Now()
+	job.FinishedAt = nil
+	job.Runs++
+
+	return func(err error) {
+		srv.finish(job, err)
+	}
+}
+
+// Track runs the given function synchronously and records its outcome
+func (srv *JobService) Track(name, userId string, f func() error) error {
+	done := srv.Start(name, userId)
+	err := f()
+	done(err)
+	return err
+}
+
+// GetAll returns snapshots of all known jobs, most recently started first
+func (srv *JobService) GetAll() []*models.JobStatus {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	srv.prune()
+
+	jobs := make([]*models.JobStatus, 0, len(srv.jobs))
+	for _, j := range srv.jobs {
+		jobCopy := *j
+		jobs = append(jobs, &jobCopy)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].StartedAt.After(jobs[j].StartedAt)
+	})
+	return jobs
+}
+
+func (srv *JobService) finish(job *models.JobStatus, err error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = models.JobStatusSucceeded
+
+	if err != nil {
+		job.Status = models.JobStatusFailed
+		job.Failures++
+		job.LastError = err.Error()
+		job.LastErrorAt = &now
+	}
+}
+
+// prune removes jobs, which finished longer ago than the retention time. Expects the lock to be held.
+func (srv *JobService) prune() {
+	deadline := time.Now().Add(-jobRetention)
+	for key, j := range srv.jobs {
+		if !j.IsRunning() && j.FinishedAt != nil && j.FinishedAt.Before(deadline) {
+			delete(srv.jobs, key)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJobService_Track_Concurrent(t *testing.T) {
+	sut := NewJobService()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sut.Track(models.JobAggregation, "", func() error { return nil })
+		}()
+	}
+	wg.Wait()
+
+	jobs := sut.GetAll()
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, 50, jobs[0].Runs)
+	assert.Equal(t, models.JobStatusSucceeded, jobs[0].Status)
+	assert.NotNil(t, jobs[0].FinishedAt)
+}
+
+func TestJobService_Track_Error(t *testing.T) {
+	sut := NewJobService()
+
+	err := sut.Track(models.JobExport, TestUserId, func() error { return assert.AnError })
+	assert.Equal(t, assert.AnError, err)
+
+	sut.Track(models.JobExport, "janedoe", func() error { return nil })
+
+	jobs := sut.GetAll()
+	assert.Len(t, jobs, 2)
+	for _, j := range jobs {
+		if j.UserID == TestUserId {
+			assert.Equal(t, models.JobStatusFailed, j.Status)
+			assert.Equal(t, 1, j.Failures)
+			assert.Equal(t, assert.AnError.Error(), j.LastError)
+			assert.NotNil(t, j.LastErrorAt)
+		} else {
+			assert.Equal(t, models.JobStatusSucceeded, j.Status)
+			assert.Zero(t, j.Failures)
+		}
+	}
+
+	// failure is still recorded after a subsequent successful run
+	sut.Track(models.JobExport, TestUserId, func() error { return nil })
+	for _, j := range sut.GetAll() {
+		if j.UserID == TestUserId {
+			assert.Equal(t, models.JobStatusSucceeded, j.Status)
+			assert.Equal(t, 1, j.Failures)
+			assert.Equal(t, 2, j.Runs)
+		}
+	}
+}
+
+func TestJobService_Prune(t *testing.T) {
+	sut := NewJobService()
+
+	sut.Track(models.JobExport, TestUserId, func() error { return nil })
+	done := sut.Start(models.JobAggregation, "")
+
+	// pretend all jobs to have finished long ago
+	for _, j := range sut.jobs {
+		finishedAt := time.Now().Add(-jobRetention - time.Minute)
+		j.FinishedAt = &finishedAt
+	}
+
+	jobs := sut.GetAll()
+	assert.Len(t, jobs, 1) // running jobs are never pruned
+	assert.Equal(t, models.JobAggregation, jobs[0].Name)
+
+	done(nil)
+}
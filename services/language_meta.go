@@ -0,0 +1,217 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// icons of popular languages, see https://icon-sets.iconify.design/simple-icons/
+var languageIcons = map[string]string{
+	"bash":       "simple-icons:gnubash",
+	"c":          "simple-icons:c",
+	"c++":        "simple-icons:cplusplus",
+	"clojure":    "simple-icons:clojure",
+	"css":        "simple-icons:css3",
+	"dart":       "simple-icons:dart",
+	"elixir":     "simple-icons:elixir",
+	"go":         "simple-icons:go",
+	"haskell":    "simple-icons:haskell",
+	"html":       "simple-icons:html5",
+	"javascript": "simple-icons:javascript",
+	"julia":      "simple-icons:julia",
+	"kotlin":     "simple-icons:kotlin",
+	"lua":        "simple-icons:lua",
+	"markdown":   "simple-icons:markdown",
+	"php":        "simple-icons:php",
+	"python":     "simple-icons:python",
+	"r":          "simple-icons:r",
+	"ruby":       "simple-icons:ruby",
+	"rust":       "simple-icons:rust",
+	"scala":      "simple-icons:scala",
+	"shell":      "simple-icons:gnubash",
+	"svelte":     "simple-icons:svelte",
+	"swift":      "simple-icons:swift",
+	"typescript": "simple-icons:typescript",
+	"vue":        "simple-icons:vuedotjs",
+	"zig":        "simple-icons:zig",
+}
+
+// LanguageMetaService provides display names, colors and icons of all known languages, i.e. those of github-linguist and the server's custom languages.
+// Admins can override them or add further languages. Overrides are persisted as key-value pair and held in memory, as they are needed for every chart.
+type LanguageMetaService struct {
+	config          *config.Config
+	keyValueService IKeyValueService
+	lock            sync.RWMutex
+	overrides       map[string]*models.LanguageMeta
+}
+
+func NewLanguageMetaService(keyValueService IKeyValueService) *LanguageMetaService {
+	srv := &LanguageMetaService{
+		config:          config.Get(),
+		keyValueService: keyValueService,
+		overrides:       map[string]*models.LanguageMeta{},
+	}
+
+	if kv := keyValueService.MustGetString(config.KeyLanguageOverrides); kv.Value != "" {
+		var overrides []*models.LanguageMeta
+		if err := json.Unmarshal([]byte(kv.Value), &overrides); err != nil {
+			config.Log().Error("failed to parse language overrides - %v", err)
+		}
+		for _, o := range overrides {
+			srv.overrides[o.Key] = o
+		}
+	}
+
+	return srv
+}
+
+// GetAll returns all known languages, ordered by id
+func (srv *LanguageMetaService) GetAll() []*models.LanguageMeta {
+	srv.lock.RLock()
+	defer srv.lock.RUnlock()
+
+	all := srv.builtin()
+	for key, o := range srv.overrides {
+		base, ok := all[key]
+		if !ok {
+			base = models.NewLanguageMeta(o.Name)
+		}
+		all[key] = base.Merge(o)
+	}
+
+	languages := make([]*models.LanguageMeta, 0, len(all))
+	for _, l := range all {
+		languages = append(languages, l)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		return languages[i].Key < languages[j].Key
+	})
+	return languages
+}
+
+// Get returns the given language's meta data, which for unknown languages only consists of their name
+func (srv *LanguageMetaService) Get(language string) *models.LanguageMeta {
+	srv.lock.RLock()
+	defer srv.lock.RUnlock()
+
+	key := strings.ToLower(language)
+	meta, ok := srv.builtin()[key]
+	if !ok {
+		meta = models.NewLanguageMeta(language)
+	}
+	if o, ok := srv.overrides[key]; ok {
+		meta = meta.Merge(o)
+	}
+	return meta
+}
+
+// GetColors returns the colors of all languages, which have one, by lower-case name
+func (srv *LanguageMetaService) GetColors() map[string]string {
+	colors := map[string]string{}
+	for _, l := range srv.GetAll() {
+		if l.Color != "" {
+			colors[l.Key] = l.Color
+		}
+	}
+	return colors
+}
+
+func (srv *LanguageMetaService) GetOverrides() []*models.LanguageMeta {
+	srv.lock.RLock()
+	defer srv.lock.RUnlock()
+
+	overrides := make([]*models.LanguageMeta, 0, len(srv.overrides))
+	for _, o := range srv.overrides {
+		copied := *o
+		overrides = append(overrides, &copied)
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].Key < overrides[j].Key
+	})
+	return overrides
+}
+
+// PutOverride overrides the non-empty properties of the given language, or adds it, if unknown, and returns the resulting meta data
+func (srv *LanguageMetaService) PutOverride(override *models.LanguageMeta) (*models.LanguageMeta, error) {
+	if strings.TrimSpace(override.Name) == "" {
+		return nil, errors.New("language name must not be empty")
+	}
+	if override.Color != "" && !config.IsHexColor(override.Color) {
+		return nil, errors.New(fmt.Sprintf("invalid color '%s', must be a hex color like '#00ADD8'", override.Color))
+	}
+
+	o := &models.LanguageMeta{Key: strings.ToLower(override.Name), Name: override.Name, Color: override.Color, Icon: override.Icon, Custom: true}
+
+	srv.lock.Lock()
+	previous, existed := srv.overrides[o.Key]
+	srv.overrides[o.Key] = o
+	if err := srv.persist(); err != nil {
+		if existed {
+			srv.overrides[o.Key] = previous
+		} else {
+			delete(srv.overrides, o.Key)
+		}
+		srv.lock.Unlock()
+		return nil, err
+	}
+	srv.lock.Unlock()
+
+	return srv.Get(o.Key), nil
+}
+
+// DeleteOverride reverts the given language to its built-in meta data or removes it, if it was added by an admin
+func (srv *LanguageMetaService) DeleteOverride(language string) error {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	key := strings.ToLower(language)
+	previous, ok := srv.overrides[key]
+	if !ok {
+		return nil
+	}
+	delete(srv.overrides, key)
+	if err := srv.persist(); err != nil {
+		srv.overrides[key] = previous
+		return err
+	}
+	return nil
+}
+
+// builtin returns the meta data of all languages known from github-linguist and the server's custom languages by lower-case name
+func (srv *LanguageMetaService) builtin() map[string]*models.LanguageMeta {
+	languages := map[string]*models.LanguageMeta{}
+	for name, color := range srv.config.App.GetLanguageColorsByName() {
+		meta := models.NewLanguageMeta(name)
+		meta.Color = color
+		languages[meta.Key] = meta
+	}
+	for _, name := range srv.config.App.GetCustomLanguages() {
+		if _, ok := languages[strings.ToLower(name)]; !ok {
+			meta := models.NewLanguageMeta(name)
+			languages[meta.Key] = meta
+		}
+	}
+	for key, meta := range languages {
+		meta.Icon = languageIcons[key]
+	}
+	return languages
+}
+
+func (srv *LanguageMetaService) persist() error {
+	overrides := make([]*models.LanguageMeta, 0, len(srv.overrides))
+	for _, o := range srv.overrides {
+		overrides = append(overrides, o)
+	}
+	data, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+	return srv.keyValueService.PutString(&models.KeyStringValue{Key: config.KeyLanguageOverrides, Value: string(data)})
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type LanguageMetaServiceTestSuite struct {
+	suite.Suite
+	KeyValueService *mocks.KeyValueServiceMock
+}
+
+func (suite *LanguageMetaServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.App.Colors = map[string]map[string]string{
+		"languages": {"Go": "#00ADD8", "JavaScript": "#f1e05a"},
+	}
+	cfg.App.CustomLanguages = map[string]string{"mylang": "MyLang"}
+	config.Set(cfg)
+}
+
+func (suite *LanguageMetaServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+	suite.KeyValueService.On("PutString", mock.Anything).Return(nil)
+}
+
+func TestLanguageMetaServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(LanguageMetaServiceTestSuite))
+}
+
+func (suite *LanguageMetaServiceTestSuite) TestLanguageMetaService_GetAll() {
+	suite.KeyValueService.On("MustGetString", config.KeyLanguageOverrides).Return(&models.KeyStringValue{Key: config.KeyLanguageOverrides})
+
+	sut := NewLanguageMetaService(suite.KeyValueService)
+
+	all := sut.GetAll()
+	assert.Len(suite.T(), all, 3)
+	assert.Equal(suite.T(), &models.LanguageMeta{Key: "go", Name: "Go", Color: "#00ADD8", Icon: "simple-icons:go"}, all[0])
+	assert.Equal(suite.T(), "JavaScript", all[1].Name)
+	assert.Equal(suite.T(), &models.LanguageMeta{Key: "mylang", Name: "MyLang"}, all[2])
+
+	assert.Equal(suite.T(), "#00ADD8", sut.Get("GO").Color)
+	assert.Equal(suite.T(), &models.LanguageMeta{Key: "cobol", Name: "COBOL"}, sut.Get("COBOL"))
+	assert.Equal(suite.T(), map[string]string{"go": "#00ADD8", "javascript": "#f1e05a"}, sut.GetColors())
+}
+
+func (suite *LanguageMetaServiceTestSuite) TestLanguageMetaService_PutOverride() {
+	suite.KeyValueService.On("MustGetString", config.KeyLanguageOverrides).Return(&models.KeyStringValue{Key: config.KeyLanguageOverrides})
+
+	sut := NewLanguageMetaService(suite.KeyValueService)
+
+	_, err := sut.PutOverride(&models.LanguageMeta{Name: "Go", Color: "blue"})
+	assert.Error(suite.T(), err)
+	suite.KeyValueService.AssertNotCalled(suite.T(), "PutString", mock.Anything)
+
+	result, err := sut.PutOverride(&models.LanguageMeta{Name: "go", Color: "#0000ff"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), &models.LanguageMeta{Key: "go", Name: "go", Color: "#0000ff", Icon: "simple-icons:go", Custom: true}, result)
+
+	result, err = sut.PutOverride(&models.LanguageMeta{Name: "MyLang", Color: "#ff0000", Icon: "mdi:code-braces"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "#ff0000", result.Color)
+
+	_, err = sut.PutOverride(&models.LanguageMeta{Name: "COBOL", Color: "#123456"})
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), sut.GetAll(), 4)
+	assert.Len(suite.T(), sut.GetOverrides(), 3)
+	assert.Equal(suite.T(), "#123456", sut.GetColors()["cobol"])
+
+	assert.Nil(suite.T(), sut.DeleteOverride("Go"))
+	assert.Nil(suite.T(), sut.DeleteOverride("cobol"))
+	assert.Equal(suite.T(), &models.LanguageMeta{Key: "go", Name: "Go", Color: "#00ADD8", Icon: "simple-icons:go"}, sut.Get("go"))
+	assert.Len(suite.T(), sut.GetAll(), 3)
+	suite.KeyValueService.AssertNumberOfCalls(suite.T(), "PutString", 5)
+}
+
+func (suite *LanguageMetaServiceTestSuite) TestLanguageMetaService_LoadOverrides() {
+	suite.KeyValueService.On("MustGetString", config.KeyLanguageOverrides).Return(&models.KeyStringValue{
+		Key:   config.KeyLanguageOverrides,
+		Value: `[{"id": "javascript", "name": "JavaScript", "color": "#000000", "custom": true}]`,
+	})
+
+	sut := NewLanguageMetaService(suite.KeyValueService)
+
+	meta := sut.Get("javascript")
+	assert.Equal(suite.T(), "#000000", meta.Color)
+	assert.Equal(suite.T(), "simple-icons:javascript", meta.Icon)
+	assert.True(suite.T(), meta.Custom)
+}
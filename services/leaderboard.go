@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-co-op/gocron"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	leaderboardDays         = 7 // leaderboard covers the past week
+	leaderboardMaxLanguages = 3
+	leaderboardCacheKey     = "leaderboard"
+)
+
+var ErrLeaderboardDisabled = errors.New("leaderboard is disabled")
+
+// LeaderboardService ranks all users, who opted in to appear on the leaderboard, by their coding time of the past week.
+// The leaderboard is generated by a scheduled job only and persisted, so that serving it never involves computing any summaries.
+type LeaderboardService struct {
+	config         *config.Config
+	eventBus       *hub.Hub
+	cache          *cache.Cache
+	repository     repositories.ILeaderboardRepository
+	userService    IUserService
+	summaryService ISummaryService
+	jobService     IJobService
+}
+
+func NewLeaderboardService(leaderboardRepository repositories.ILeaderboardRepository, userService IUserService, summaryService ISummaryService, jobService IJobService) *LeaderboardService {
+	srv := &LeaderboardService{
+		config:         config.Get(),
+		eventBus:       config.EventBus(),
+		cache:          cache.New(1*time.Hour, 1*time.Hour),
+		repository:     leaderboardRepository,
+		userService:    userService,
+		summaryService: summaryService,
+		jobService:     jobService,
+	}
+
+	// users opting out or being quarantined disappear from the leaderboard right away instead of only after its next generation
+	sub := srv.eventBus.Subscribe(0, config.EventUserUpdate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			if user := m.Fields[config.FieldPayload].(*models.User); !user.PublicLeaderboard || user.Quarantined {
+				srv.cache.Delete(leaderboardCacheKey)
+			}
+		}
+	}(&sub)
+
+	return srv
+}
+
+// Schedule generates the leaderboard according to the configured cron expression and once initially, if none was generated before
+func (srv *LeaderboardService) Schedule() {
+	if !srv.config.App.LeaderboardEnabled {
+		return
+	}
+
+	logbuch.Info("scheduling leaderboard generation")
+
+	if items, err := srv.repository.GetAll(); err == nil && len(items) == 0 {
+		if err := srv.Generate(); err != nil {
+			config.Log().Error("failed to generate initial leaderboard - %v", err)
+		}
+	}
+
+	s := gocron.NewScheduler(time.Local)
+	if _, err := s.Cron(srv.config.App.LeaderboardSchedule).Do(srv.Generate); err != nil {
+		config.Log().Error("failed to schedule leaderboard generation - %v", err)
+		return
+	}
+	s.StartBlocking()
+}
+
+// Generate recomputes the leaderboard from all participating users' summaries and replaces the persisted one
+func (srv *LeaderboardService) Generate() error {
+	return srv.jobService.Track(models.JobLeaderboard, "", srv.generate)
+}
+
+// GetLeaderboard returns the latest generated leaderboard, best ranked first, leaving out users, who opted out since
+func (srv *LeaderboardService) GetLeaderboard() ([]*models.LeaderboardItem, error) {
+	if !srv.config.App.LeaderboardEnabled {
+		return nil, ErrLeaderboardDisabled
+	}
+	if cached, ok := srv.cache.Get(leaderboardCacheKey); ok {
+		return cached.([]*models.LeaderboardItem), nil
+	}
+
+	items, err := srv.repository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := srv.userService.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	participating := make(map[string]bool)
+	for _, u := range users {
+		participating[u.ID] = u.PublicLeaderboard && !u.Deactivated && !u.Quarantined
+	}
+
+	result := make([]*models.LeaderboardItem, 0, len(items))
+	for _, item := range items {
+		if participating[item.UserID] {
+			result = append(result, item)
+		}
+	}
+
+	srv.cache.SetDefault(leaderboardCacheKey, result)
+	return result, nil
+}
+
+func (srv *LeaderboardService) generate() error {
+	logbuch.Info("generating leaderboard")
+	start := time.Now()
+
+	users, err := srv.userService.GetAll()
+	if err != nil {
+		return err
+	}
+	participants := make([]*models.User, 0)
+	for _, u := range users {
+		if u.PublicLeaderboard && !u.Deactivated && !u.Quarantined {
+			participants = append(participants, u)
+		}
+	}
+
+	summaries := make(map[string]*models.Summary)
+	if len(participants) > 0 {
+		if summaries, err = srv.summaryService.RetrieveBatch(start.AddDate(0, 0, -leaderboardDays), start, participants); err != nil {
+			return err
+		}
+	}
+
+	items := models.NewLeaderboard(summaries, leaderboardMaxLanguages)
+	if err := srv.repository.ReplaceAll(items); err != nil {
+		return err
+	}
+	srv.cache.Flush()
+
+	logbuch.Info("finished generating leaderboard of %d users in %v", len(items), time.Since(start))
+	return nil
+}
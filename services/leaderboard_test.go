@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type LeaderboardServiceTestSuite struct {
+	suite.Suite
+	TestUsers             []*models.User
+	LeaderboardRepository *mocks.LeaderboardRepositoryMock
+	UserService           *mocks.UserServiceMock
+	SummaryService        *mocks.SummaryServiceMock
+}
+
+func (suite *LeaderboardServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.App.LeaderboardEnabled = true
+	config.Set(cfg)
+}
+
+func (suite *LeaderboardServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUsers = []*models.User{
+		{ID: "alice", PublicLeaderboard: true},
+		{ID: "bob", PublicLeaderboard: true},
+		{ID: "carol", PublicLeaderboard: false},
+		{ID: "dave", PublicLeaderboard: true, Deactivated: true},
+	}
+	suite.LeaderboardRepository = new(mocks.LeaderboardRepositoryMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+
+	suite.UserService.On("GetAll").Return(suite.TestUsers, nil)
+}
+
+func TestLeaderboardServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(LeaderboardServiceTestSuite))
+}
+
+func (suite *LeaderboardServiceTestSuite) TestLeaderboardService_Generate() {
+	sut := NewLeaderboardService(suite.LeaderboardRepository, suite.UserService, suite.SummaryService, NewJobService())
+
+	suite.SummaryService.On("RetrieveBatch", mock.Anything, mock.Anything, mock.Anything).Return(map[string]*models.Summary{
+		"alice": {Languages: []*models.SummaryItem{{Type: models.SummaryLanguage, Key: "Go", Total: time.Hour / time.Second}}},
+		"bob":   {Languages: []*models.SummaryItem{{Type: models.SummaryLanguage, Key: "Rust", Total: 2 * time.Hour / time.Second}}},
+	}, nil)
+	suite.LeaderboardRepository.On("ReplaceAll", mock.Anything).Return(nil)
+
+	assert.Nil(suite.T(), sut.Generate())
+
+	participants := suite.SummaryService.Calls[0].Arguments.Get(2).([]*models.User)
+	assert.Len(suite.T(), participants, 2) // neither users, who did not opt in, nor deactivated ones
+
+	items := suite.LeaderboardRepository.Calls[0].Arguments.Get(0).([]*models.LeaderboardItem)
+	assert.Len(suite.T(), items, 2)
+	assert.Equal(suite.T(), "bob", items[0].UserID)
+	assert.Equal(suite.T(), 1, items[0].Rank)
+	assert.Equal(suite.T(), int64(7200), items[0].TotalSeconds)
+	assert.Equal(suite.T(), models.LeaderboardLanguages{"Rust"}, items[0].Languages)
+	assert.Equal(suite.T(), "alice", items[1].UserID)
+	assert.Equal(suite.T(), 2, items[1].Rank)
+}
+
+func (suite *LeaderboardServiceTestSuite) TestLeaderboardService_GetLeaderboard() {
+	sut := NewLeaderboardService(suite.LeaderboardRepository, suite.UserService, suite.SummaryService, NewJobService())
+
+	suite.LeaderboardRepository.On("GetAll").Return([]*models.LeaderboardItem{
+		{UserID: "bob", Rank: 1},
+		{UserID: "carol", Rank: 2}, // opted out since the leaderboard was generated
+		{UserID: "alice", Rank: 3},
+	}, nil)
+
+	result, err := sut.GetLeaderboard()
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), "bob", result[0].UserID)
+	assert.Equal(suite.T(), "alice", result[1].UserID)
+	suite.SummaryService.AssertNotCalled(suite.T(), "RetrieveBatch", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *LeaderboardServiceTestSuite) TestLeaderboardService_GetLeaderboard_Disabled() {
+	config.Get().App.LeaderboardEnabled = false
+	defer func() { config.Get().App.LeaderboardEnabled = true }()
+
+	sut := NewLeaderboardService(suite.LeaderboardRepository, suite.UserService, suite.SummaryService, NewJobService())
+
+	_, err := sut.GetLeaderboard()
+	assert.Equal(suite.T(), ErrLeaderboardDisabled, err)
+}
@@ -3,6 +3,7 @@ package mail
 import (
 	"bytes"
 	"fmt"
+	"github.com/muety/wakapi/i18n"
 	"github.com/muety/wakapi/models"
 	"github.com/muety/wakapi/routes"
 	"github.com/muety/wakapi/services"
@@ -18,10 +19,18 @@ const (
 	tplNameImportNotification          = "import_finished"
 	tplNameWakatimeFailureNotification = "wakatime_connection_failure"
 	tplNameReport                      = "report"
-	subjectPasswordReset               = "Wakapi - Password Reset"
-	subjectImportNotification          = "Wakapi - Data Import Finished"
-	subjectWakatimeFailureNotification = "Wakapi - WakaTime Connection Failure"
-	subjectReport                      = "Wakapi - Report from %s"
+	tplNameBudgetAlert                 = "budget_alert"
+	tplNameTestMail                    = "test_mail"
+	tplNameInactivityAlert             = "inactivity_alert"
+	tplNameCredentials                 = "credentials"
+	subjectPasswordReset               = "mail.password_reset.subject" // message keys, see i18n
+	subjectImportNotification          = "mail.import.subject"
+	subjectWakatimeFailureNotification = "mail.wakatime_failure.subject"
+	subjectReport                      = "mail.report.subject"
+	subjectBudgetAlert                 = "mail.budget_alert.subject"
+	subjectTestMail                    = "mail.test.subject"
+	subjectInactivityAlert             = "mail.inactivity.subject"
+	subjectCredentials                 = "mail.credentials.subject"
 )
 
 type SendingService interface {
@@ -37,16 +46,7 @@ type MailService struct {
 func NewMailService() services.IMailService {
 	config := conf.Get()
 
-	var sendingService SendingService
-	sendingService = &NoopSendingService{}
-
-	if config.Mail.Enabled {
-		if config.Mail.Provider == conf.MailProviderMailWhale {
-			sendingService = NewMailWhaleSendingService(config.Mail.MailWhale)
-		} else if config.Mail.Provider == conf.MailProviderSmtp {
-			sendingService = NewSMTPSendingService(config.Mail.Smtp)
-		}
-	}
+	sendingService := newSendingService(config)
 
 	// Use local file system when in 'dev' environment, go embed file system otherwise
 	templateFs := conf.ChooseFS("views/mail", mail.TemplateFiles)
@@ -59,14 +59,14 @@ func NewMailService() services.IMailService {
 }
 
 func (m *MailService) SendPasswordReset(recipient *models.User, resetLink string) error {
-	tpl, err := m.getPasswordResetTemplate(PasswordResetTplData{ResetLink: resetLink})
+	tpl, err := m.getPasswordResetTemplate(PasswordResetTplData{Locale: recipient.Locale, ResetLink: resetLink})
 	if err != nil {
 		return err
 	}
 	mail := &models.Mail{
 		From:    models.MailAddress(m.config.Mail.Sender),
 		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
-		Subject: subjectPasswordReset,
+		Subject: i18n.T(recipient.Locale, subjectPasswordReset),
 	}
 	mail.WithHTML(tpl.String())
 	return m.sendingService.Send(mail)
@@ -74,7 +74,8 @@ func (m *MailService) SendPasswordReset(recipient *models.User, resetLink string
 
 func (m *MailService) SendWakatimeFailureNotification(recipient *models.User, numFailures int) error {
 	tpl, err := m.getWakatimeFailureNotificationTemplate(WakatimeFailureNotificationNotificationTplData{
-		PublicUrl:   m.config.Server.PublicUrl,
+		Locale:      recipient.Locale,
+		PublicUrl:   m.config.Server.GetPublicUrl(),
 		NumFailures: numFailures,
 	})
 	if err != nil {
@@ -83,7 +84,7 @@ func (m *MailService) SendWakatimeFailureNotification(recipient *models.User, nu
 	mail := &models.Mail{
 		From:    models.MailAddress(m.config.Mail.Sender),
 		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
-		Subject: subjectWakatimeFailureNotification,
+		Subject: i18n.T(recipient.Locale, subjectWakatimeFailureNotification),
 	}
 	mail.WithHTML(tpl.String())
 	return m.sendingService.Send(mail)
@@ -91,8 +92,9 @@ func (m *MailService) SendWakatimeFailureNotification(recipient *models.User, nu
 
 func (m *MailService) SendImportNotification(recipient *models.User, duration time.Duration, numHeartbeats int) error {
 	tpl, err := m.getImportNotificationTemplate(ImportNotificationTplData{
-		PublicUrl:     m.config.Server.PublicUrl,
-		Duration:      fmt.Sprintf("%.0f seconds", duration.Seconds()),
+		Locale:        recipient.Locale,
+		PublicUrl:     m.config.Server.GetPublicUrl(),
+		Duration:      fmt.Sprintf("%.0f", duration.Seconds()),
 		NumHeartbeats: numHeartbeats,
 	})
 	if err != nil {
@@ -101,21 +103,91 @@ func (m *MailService) SendImportNotification(recipient *models.User, duration ti
 	mail := &models.Mail{
 		From:    models.MailAddress(m.config.Mail.Sender),
 		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
-		Subject: subjectImportNotification,
+		Subject: i18n.T(recipient.Locale, subjectImportNotification),
 	}
 	mail.WithHTML(tpl.String())
 	return m.sendingService.Send(mail)
 }
 
 func (m *MailService) SendReport(recipient *models.User, report *models.Report) error {
-	tpl, err := m.getReportTemplate(ReportTplData{report})
+	tpl, err := m.getReportTemplate(ReportTplData{Locale: recipient.Locale, Report: report})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: i18n.T(recipient.Locale, subjectReport, utils.FormatDateHuman(time.Now().In(recipient.TZ()))),
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
+func (m *MailService) SendBudgetAlert(recipient *models.User, status *models.BudgetStatus) error {
+	tpl, err := m.getBudgetAlertTemplate(BudgetAlertTplData{
+		Locale:    recipient.Locale,
+		PublicUrl: m.config.Server.GetPublicUrl(),
+		Status:    status,
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: i18n.T(recipient.Locale, subjectBudgetAlert, status.Threshold, status.Project),
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
+func (m *MailService) SendInactivityAlert(recipient *models.User, alert *models.InactivityAlert) error {
+	tpl, err := m.getInactivityAlertTemplate(InactivityAlertTplData{
+		Locale:    recipient.Locale,
+		PublicUrl: m.config.Server.GetPublicUrl(),
+		Alert:     alert,
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: i18n.T(recipient.Locale, subjectInactivityAlert, alert.Days),
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
+func (m *MailService) SendCredentials(recipient *models.User, password string) error {
+	tpl, err := m.getCredentialsTemplate(CredentialsTplData{
+		Locale:    recipient.Locale,
+		PublicUrl: m.config.Server.GetPublicUrl(),
+		Username:  recipient.ID,
+		Password:  password,
+		ApiKey:    recipient.ApiKey,
+	})
+	if err != nil {
+		return err
+	}
+	mail := &models.Mail{
+		From:    models.MailAddress(m.config.Mail.Sender),
+		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
+		Subject: i18n.T(recipient.Locale, subjectCredentials),
+	}
+	mail.WithHTML(tpl.String())
+	return m.sendingService.Send(mail)
+}
+
+func (m *MailService) SendTestMail(recipient *models.User) error {
+	tpl, err := m.getTestMailTemplate(TestMailTplData{Locale: recipient.Locale, Provider: m.config.Mail.Provider})
 	if err != nil {
 		return err
 	}
 	mail := &models.Mail{
 		From:    models.MailAddress(m.config.Mail.Sender),
 		To:      models.MailAddresses([]models.MailAddress{models.MailAddress(recipient.Email)}),
-		Subject: fmt.Sprintf(subjectReport, utils.FormatDateHuman(time.Now().In(recipient.TZ()))),
+		Subject: i18n.T(recipient.Locale, subjectTestMail),
 	}
 	mail.WithHTML(tpl.String())
 	return m.sendingService.Send(mail)
@@ -153,6 +225,59 @@ func (m *MailService) getReportTemplate(data ReportTplData) (*bytes.Buffer, erro
 	return &rendered, nil
 }
 
+func (m *MailService) getBudgetAlertTemplate(data BudgetAlertTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameBudgetAlert)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getInactivityAlertTemplate(data InactivityAlertTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameInactivityAlert)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getCredentialsTemplate(data CredentialsTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameCredentials)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+func (m *MailService) getTestMailTemplate(data TestMailTplData) (*bytes.Buffer, error) {
+	var rendered bytes.Buffer
+	if err := m.templates[m.fmtName(tplNameTestMail)].Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
 func (m *MailService) fmtName(name string) string {
 	return fmt.Sprintf("%s.tpl.html", name)
 }
+
+func newSendingService(config *conf.Config) SendingService {
+	if !config.Mail.Enabled {
+		return &NoopSendingService{}
+	}
+
+	switch config.Mail.Provider {
+	case conf.MailProviderMailWhale:
+		return NewMailWhaleSendingService(config.Mail.MailWhale)
+	case conf.MailProviderSmtp:
+		return NewSMTPSendingService(config.Mail.Smtp)
+	case conf.MailProviderSendGrid:
+		return NewSendGridSendingService(config.Mail.SendGrid)
+	case conf.MailProviderMailgun:
+		return NewMailgunSendingService(config.Mail.Mailgun)
+	case conf.MailProviderSES:
+		return NewSESSendingService(config.Mail.SES)
+	default:
+		return &NoopSendingService{}
+	}
+}
@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type MailgunSendingService struct {
+	config     conf.MailgunMailConfig
+	httpClient *http.Client
+}
+
+func NewMailgunSendingService(config conf.MailgunMailConfig) *MailgunSendingService {
+	return &MailgunSendingService{
+		config:     config,
+		httpClient: conf.NewHttpClient(conf.ProxyScopeMail, 10*time.Second),
+	}
+}
+
+func (s *MailgunSendingService) Send(mail *models.Mail) error {
+	if len(mail.To) == 0 {
+		return errors.New("not sending mail as recipient mail address seems to be invalid")
+	}
+
+	form := url.Values{}
+	form.Set("from", mail.From.String())
+	form.Set("subject", mail.Subject)
+	for _, addr := range mail.To.Strings() {
+		form.Add("to", addr)
+	}
+	if mail.Type == models.HtmlType {
+		form.Set("html", mail.Body)
+	} else {
+		form.Set("text", mail.Body)
+	}
+
+	endpoint := fmt.Sprintf("%s/v3/%s/messages", strings.TrimSuffix(s.config.Url, "/"), s.config.Domain)
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth("api", s.config.ApiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return errors.New(fmt.Sprintf("got status %d from mailgun", res.StatusCode))
+	}
+
+	return nil
+}
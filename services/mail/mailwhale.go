@@ -27,10 +27,8 @@ type MailWhaleSendRequest struct {
 
 func NewMailWhaleSendingService(config conf.MailwhaleMailConfig) *MailWhaleSendingService {
 	return &MailWhaleSendingService{
-		config: config,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		config:     config,
+		httpClient: conf.NewHttpClient(conf.ProxyScopeMail, 10*time.Second),
 	}
 }
 
@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"net/http"
+	"time"
+)
+
+const sendGridUrl = "https://api.sendgrid.com/v3/mail/send"
+
+type SendGridSendingService struct {
+	config     conf.SendGridMailConfig
+	httpClient *http.Client
+}
+
+type SendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type SendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type SendGridPersonalization struct {
+	To []SendGridAddress `json:"to"`
+}
+
+type SendGridSendRequest struct {
+	Personalizations []SendGridPersonalization `json:"personalizations"`
+	From             SendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []SendGridContent         `json:"content"`
+}
+
+func NewSendGridSendingService(config conf.SendGridMailConfig) *SendGridSendingService {
+	return &SendGridSendingService{
+		config:     config,
+		httpClient: conf.NewHttpClient(conf.ProxyScopeMail, 10*time.Second),
+	}
+}
+
+func (s *SendGridSendingService) Send(mail *models.Mail) error {
+	if len(mail.To) == 0 {
+		return errors.New("not sending mail as recipient mail address seems to be invalid")
+	}
+
+	recipients := make([]SendGridAddress, len(mail.To))
+	for i, addr := range mail.To.RawStrings() {
+		recipients[i] = SendGridAddress{Email: addr}
+	}
+
+	contentType := "text/plain"
+	if mail.Type == models.HtmlType {
+		contentType = "text/html"
+	}
+
+	sendRequest := &SendGridSendRequest{
+		Personalizations: []SendGridPersonalization{{To: recipients}},
+		From:             SendGridAddress{Email: mail.From.Raw()},
+		Subject:          mail.Subject,
+		Content:          []SendGridContent{{Type: contentType, Value: mail.Body}},
+	}
+	payload, _ := json.Marshal(sendRequest)
+
+	req, err := http.NewRequest(http.MethodPost, sendGridUrl, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.config.ApiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return errors.New(fmt.Sprintf("got status %d from sendgrid", res.StatusCode))
+	}
+
+	return nil
+}
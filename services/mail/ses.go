@@ -0,0 +1,146 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	sesAlgorithm       = "AWS4-HMAC-SHA256"
+	sesService         = "ses"
+	sesDateFormat      = "20060102T150405Z"
+	sesShortDateFormat = "20060102"
+)
+
+// SESSendingService sends mails via amazon ses' v2 api, talking to it directly instead of pulling in the aws sdk.
+// Requests are authenticated using aws signature version 4.
+type SESSendingService struct {
+	config     conf.SESMailConfig
+	httpClient *http.Client
+}
+
+type SESContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type SESSendRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject SESContent            `json:"Subject"`
+			Body    map[string]SESContent `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+func NewSESSendingService(config conf.SESMailConfig) *SESSendingService {
+	return &SESSendingService{
+		config:     config,
+		httpClient: conf.NewHttpClient(conf.ProxyScopeMail, 10*time.Second),
+	}
+}
+
+func (s *SESSendingService) Send(mail *models.Mail) error {
+	if len(mail.To) == 0 {
+		return errors.New("not sending mail as recipient mail address seems to be invalid")
+	}
+
+	bodyType := "Text"
+	if mail.Type == models.HtmlType {
+		bodyType = "Html"
+	}
+
+	sendRequest := &SESSendRequest{FromEmailAddress: mail.From.String()}
+	sendRequest.Destination.ToAddresses = mail.To.Strings()
+	sendRequest.Content.Simple.Subject = SESContent{Data: mail.Subject, Charset: "UTF-8"}
+	sendRequest.Content.Simple.Body = map[string]SESContent{bodyType: {Data: mail.Body, Charset: "UTF-8"}}
+	payload, _ := json.Marshal(sendRequest)
+
+	endpoint := fmt.Sprintf("https://email.%s.amazonaws.com/v2/email/outbound-emails", s.config.Region)
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	s.signRequest(req, payload, time.Now().UTC())
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return errors.New(fmt.Sprintf("got status %d from ses", res.StatusCode))
+	}
+
+	return nil
+}
+
+func (s *SESSendingService) signRequest(req *http.Request, payload []byte, now time.Time) {
+	payloadHash := sha256.Sum256(payload)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", now.Format(sesDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	signedHeaders := []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(req.Header.Get(h)) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"",
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sesAlgorithm, s.config.AccessKeyId, s.scope(now), strings.Join(signedHeaders, ";"), s.sign(now, canonicalRequest),
+	))
+}
+
+func (s *SESSendingService) sign(now time.Time, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		sesAlgorithm,
+		now.Format(sesDateFormat),
+		s.scope(now),
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+s.config.SecretAccessKey), now.Format(sesShortDateFormat))
+	key = hmacSha256(key, s.config.Region)
+	key = hmacSha256(key, sesService)
+	key = hmacSha256(key, "aws4_request")
+	return hex.EncodeToString(hmacSha256(key, stringToSign))
+}
+
+func (s *SESSendingService) scope(now time.Time) string {
+	return fmt.Sprintf("%s/%s/%s/aws4_request", now.Format(sesShortDateFormat), s.config.Region, sesService)
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -3,20 +3,49 @@ package mail
 import "github.com/muety/wakapi/models"
 
 type PasswordResetTplData struct {
+	Locale    string
 	ResetLink string
 }
 
 type ImportNotificationTplData struct {
+	Locale        string
 	PublicUrl     string
 	Duration      string
 	NumHeartbeats int
 }
 
 type WakatimeFailureNotificationNotificationTplData struct {
+	Locale      string
 	PublicUrl   string
 	NumFailures int
 }
 
+type BudgetAlertTplData struct {
+	Locale    string
+	PublicUrl string
+	Status    *models.BudgetStatus
+}
+
+type InactivityAlertTplData struct {
+	Locale    string
+	PublicUrl string
+	Alert     *models.InactivityAlert
+}
+
+type CredentialsTplData struct {
+	Locale    string
+	PublicUrl string
+	Username  string
+	Password  string
+	ApiKey    string
+}
+
 type ReportTplData struct {
+	Locale string
 	Report *models.Report
 }
+
+type TestMailTplData struct {
+	Locale   string
+	Provider string
+}
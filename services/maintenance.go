@@ -0,0 +1,84 @@
+package services
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// MaintenanceService keeps track of whether the instance is in maintenance (read-only) mode.
+// The state is persisted as key-value pair, so that it survives restarts, e.g. in the middle of a migration, and held in memory, as it is checked on every request.
+type MaintenanceService struct {
+	config          *config.Config
+	keyValueService IKeyValueService
+	lock            sync.RWMutex
+	state           models.Maintenance
+}
+
+func NewMaintenanceService(keyValueService IKeyValueService) *MaintenanceService {
+	srv := &MaintenanceService{
+		config:          config.Get(),
+		keyValueService: keyValueService,
+	}
+
+	if kv := keyValueService.MustGetString(config.KeyMaintenance); kv.Value != "" {
+		if err := json.Unmarshal([]byte(kv.Value), &srv.state); err != nil {
+			config.Log().Error("failed to parse maintenance state - %v", err)
+		}
+	}
+
+	return srv
+}
+
+func (srv *MaintenanceService) Get() *models.Maintenance {
+	srv.lock.RLock()
+	defer srv.lock.RUnlock()
+	state := srv.state
+	return &state
+}
+
+func (srv *MaintenanceService) IsEnabled() bool {
+	srv.lock.RLock()
+	defer srv.lock.RUnlock()
+	return srv.state.Enabled
+}
+
+// Enable puts the instance into maintenance mode or updates the message, if it already is
+func (srv *MaintenanceService) Enable(message string) (*models.Maintenance, error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	state := models.Maintenance{Enabled: true, Message: message, Since: srv.state.Since}
+	if !srv.state.Enabled {
+		state.Since = time.Now()
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if err := srv.keyValueService.PutString(&models.KeyStringValue{Key: config.KeyMaintenance, Value: string(data)}); err != nil {
+		return nil, err
+	}
+
+	srv.state = state
+	logbuch.Info("maintenance mode enabled")
+	return &state, nil
+}
+
+func (srv *MaintenanceService) Disable() (*models.Maintenance, error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if err := srv.keyValueService.DeleteString(config.KeyMaintenance); err != nil {
+		return nil, err
+	}
+
+	srv.state = models.Maintenance{}
+	logbuch.Info("maintenance mode disabled")
+	return &models.Maintenance{}, nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MaintenanceServiceTestSuite struct {
+	suite.Suite
+	KeyValueService *mocks.KeyValueServiceMock
+}
+
+func (suite *MaintenanceServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+}
+
+func (suite *MaintenanceServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+}
+
+func TestMaintenanceServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(MaintenanceServiceTestSuite))
+}
+
+func (suite *MaintenanceServiceTestSuite) TestMaintenanceService_Restore() {
+	suite.KeyValueService.On("MustGetString", config.KeyMaintenance).Return(&models.KeyStringValue{Key: config.KeyMaintenance, Value: `{"enabled":true,"message":"backup running"}`})
+
+	sut := NewMaintenanceService(suite.KeyValueService)
+
+	assert.True(suite.T(), sut.IsEnabled())
+	assert.Equal(suite.T(), "backup running", sut.Get().Message)
+}
+
+func (suite *MaintenanceServiceTestSuite) TestMaintenanceService_EnableDisable() {
+	suite.KeyValueService.On("MustGetString", config.KeyMaintenance).Return(&models.KeyStringValue{Key: config.KeyMaintenance})
+	suite.KeyValueService.On("PutString", mock.Anything).Return(nil)
+	suite.KeyValueService.On("DeleteString", config.KeyMaintenance).Return(nil)
+
+	sut := NewMaintenanceService(suite.KeyValueService)
+	assert.False(suite.T(), sut.IsEnabled())
+
+	state, err := sut.Enable("migrating database")
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), state.Enabled)
+	assert.False(suite.T(), state.Since.IsZero())
+	assert.True(suite.T(), sut.IsEnabled())
+
+	// updating the message keeps the original start time
+	updated, err := sut.Enable("almost done")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "almost done", updated.Message)
+	assert.Equal(suite.T(), state.Since, updated.Since)
+
+	_, err = sut.Disable()
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), sut.IsEnabled())
+	suite.KeyValueService.AssertNumberOfCalls(suite.T(), "PutString", 2)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+	"time"
+)
+
+type ManualTimeEntryService struct {
+	config     *config.Config
+	cache      *cache.Cache
+	eventBus   *hub.Hub
+	repository repositories.IManualTimeEntryRepository
+}
+
+func NewManualTimeEntryService(manualTimeEntryRepository repositories.IManualTimeEntryRepository) *ManualTimeEntryService {
+	return &ManualTimeEntryService{
+		config:     config.Get(),
+		eventBus:   config.EventBus(),
+		repository: manualTimeEntryRepository,
+		cache:      cache.New(24*time.Hour, 24*time.Hour),
+	}
+}
+
+func (srv *ManualTimeEntryService) GetById(id uint) (*models.ManualTimeEntry, error) {
+	return srv.repository.GetById(id)
+}
+
+func (srv *ManualTimeEntryService) GetByUser(userId string) ([]*models.ManualTimeEntry, error) {
+	if entries, found := srv.cache.Get(userId); found {
+		return entries.([]*models.ManualTimeEntry), nil
+	}
+
+	entries, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(userId, entries, cache.DefaultExpiration)
+	return entries, nil
+}
+
+// GetByUserWithin returns all entries booked for a day, which overlaps with the given interval. As manual entries are day-granular,
+// an entry is included as a whole, even if the interval only starts in the middle of the entry's day.
+func (srv *ManualTimeEntryService) GetByUserWithin(userId string, from, to time.Time) ([]*models.ManualTimeEntry, error) {
+	return srv.repository.GetByUserWithin(userId, utils.StartOfDay(from), to)
+}
+
+func (srv *ManualTimeEntryService) Create(entry *models.ManualTimeEntry) (*models.ManualTimeEntry, error) {
+	result, err := srv.repository.Insert(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	srv.cache.Delete(result.UserID)
+	srv.notifyUpdate(entry, false)
+	return result, nil
+}
+
+func (srv *ManualTimeEntryService) Delete(entry *models.ManualTimeEntry) error {
+	if entry.UserID == "" {
+		return errors.New("no user id specified")
+	}
+	if err := srv.repository.Delete(entry.ID); err != nil {
+		return err
+	}
+	srv.cache.Delete(entry.UserID)
+	srv.notifyUpdate(entry, true)
+	return nil
+}
+
+func (srv *ManualTimeEntryService) notifyUpdate(entry *models.ManualTimeEntry, isDelete bool) {
+	name := config.EventManualTimeEntryCreate
+	if isDelete {
+		name = config.EventManualTimeEntryDelete
+	}
+	srv.eventBus.Publish(hub.Message{
+		Name:   name,
+		Fields: map[string]interface{}{config.FieldPayload: entry, config.FieldUserId: entry.UserID},
+	})
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type ManualTimeEntryServiceTestSuite struct {
+	suite.Suite
+	ManualTimeEntryRepository *mocks.ManualTimeEntryRepositoryMock
+}
+
+func (suite *ManualTimeEntryServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.ManualTimeEntryRepository = new(mocks.ManualTimeEntryRepositoryMock)
+}
+
+func TestManualTimeEntryServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ManualTimeEntryServiceTestSuite))
+}
+
+func (suite *ManualTimeEntryServiceTestSuite) TestManualTimeEntryService_GetByUserWithin_DayGranular() {
+	sut := NewManualTimeEntryService(suite.ManualTimeEntryRepository)
+
+	from := time.Date(2022, 10, 16, 14, 30, 0, 0, time.UTC)
+	to := time.Date(2022, 10, 17, 14, 30, 0, 0, time.UTC)
+	startOfDay := time.Date(2022, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	entries := []*models.ManualTimeEntry{{UserID: TestUserId, Project: TestProject1, Date: models.CustomTime(startOfDay), Duration: time.Hour}}
+	suite.ManualTimeEntryRepository.On("GetByUserWithin", TestUserId, startOfDay, to).Return(entries, nil)
+
+	result, err := sut.GetByUserWithin(TestUserId, from, to)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 1) // entry booked for the day, the interval starts in, is included as a whole
+	suite.ManualTimeEntryRepository.AssertExpectations(suite.T())
+}
+
+func (suite *ManualTimeEntryServiceTestSuite) TestManualTimeEntryService_Delete_Failed() {
+	sut := NewManualTimeEntryService(suite.ManualTimeEntryRepository)
+	entry := &models.ManualTimeEntry{ID: 1, UserID: TestUserId}
+
+	sub := config.EventBus().Subscribe(1, config.EventManualTimeEntryDelete)
+	defer config.EventBus().Unsubscribe(sub)
+
+	suite.ManualTimeEntryRepository.On("Delete", uint(1)).Return(assert.AnError)
+
+	assert.Error(suite.T(), sut.Delete(entry))
+
+	select {
+	case <-sub.Receiver:
+		suite.T().Error("expected no delete event to be published for failed deletion")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func (suite *ManualTimeEntryServiceTestSuite) TestManualTimeEntryService_Delete() {
+	sut := NewManualTimeEntryService(suite.ManualTimeEntryRepository)
+	entry := &models.ManualTimeEntry{ID: 1, UserID: TestUserId}
+
+	sub := config.EventBus().Subscribe(1, config.EventManualTimeEntryDelete)
+	defer config.EventBus().Unsubscribe(sub)
+
+	suite.ManualTimeEntryRepository.On("Delete", uint(1)).Return(nil)
+
+	assert.Nil(suite.T(), sut.Delete(entry))
+
+	select {
+	case m := <-sub.Receiver:
+		assert.Equal(suite.T(), TestUserId, m.Fields[config.FieldUserId])
+	case <-time.After(time.Second):
+		suite.T().Error("expected delete event to be published")
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+// MaterializedDurationService serves durations of past (utc) days from the durations table instead of recomputing them from raw heartbeats on every request.
+// A day is materialized upon first access by the wrapped duration service. Whenever its heartbeats change later on, the heartbeat repository drops
+// the day's marker within the same transaction, so that it is recomputed on next access. Durations of the current day, which keeps changing
+// all the time, as well as filtered durations are always computed from heartbeats.
+type MaterializedDurationService struct {
+	config             *config.Config
+	eventBus           *hub.Hub
+	durationService    IDurationService
+	durationRepository repositories.IDurationRepository
+	locks              *sync.Map // per-user locks, so that concurrent requests won't materialize the same days twice
+}
+
+func NewMaterializedDurationService(durationService IDurationService, durationRepository repositories.IDurationRepository) *MaterializedDurationService {
+	srv := &MaterializedDurationService{
+		config:             config.Get(),
+		eventBus:           config.EventBus(),
+		durationService:    durationService,
+		durationRepository: durationRepository,
+		locks:              &sync.Map{},
+	}
+
+	// language mappings are applied to heartbeats at read time, so changing them affects all of the user's durations
+	sub1 := srv.eventBus.Subscribe(0, config.TopicLanguageMapping)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			userId := m.Fields[config.FieldUserId].(string)
+			if err := srv.durationRepository.DeleteDaysByUser(userId); err != nil {
+				config.Log().Error("failed to invalidate materialized durations of user '%s' - %v", userId, err)
+			}
+		}
+	}(&sub1)
+
+	return srv
+}
+
+func (srv *MaterializedDurationService) Get(from, to time.Time, user *models.User, filters *models.Filters) (models.Durations, error) {
+	// heartbeats are filtered before being merged into durations, so filtered durations can't be derived from materialized ones
+	if filters != nil && !filters.IsEmpty() {
+		return srv.durationService.Get(from, to, user, filters)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	durations := make(models.Durations, 0)
+
+	if from.Before(today) {
+		materializedFrom, materializedTo := from.UTC().Truncate(24*time.Hour), to
+		if materializedTo.After(today) {
+			materializedTo = today
+		}
+
+		if err := srv.materialize(materializedFrom, materializedTo, user); err != nil {
+			return nil, err
+		}
+
+		stored, err := srv.durationRepository.GetAllWithin(materializedFrom, materializedTo, user)
+		if err != nil {
+			return nil, err
+		}
+		durations = append(durations, clipDurations(stored, from, materializedTo)...)
+	}
+
+	if to.After(today) {
+		liveFrom := from
+		if liveFrom.Before(today) {
+			liveFrom = today
+		}
+		live, err := srv.durationService.Get(liveFrom, to, user, nil)
+		if err != nil {
+			return nil, err
+		}
+		durations = append(durations, live...)
+	}
+
+	return durations.Sorted(), nil
+}
+
+// materialize computes and persists durations for all of the user's days within the given range, which are not materialized yet.
+// Contiguous missing days are computed at once and split up afterwards, to not query heartbeats day by day.
+func (srv *MaterializedDurationService) materialize(from, to time.Time, user *models.User) error {
+	lock, _ := srv.locks.LoadOrStore(user.ID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	days, err := srv.durationRepository.GetDaysWithin(from, to, user)
+	if err != nil {
+		return err
+	}
+
+	materialized := make(map[int64]bool)
+	for _, d := range days {
+		// durations computed with a different strategy than the user's current one are outdated
+		if d.Strategy == user.DurationStrategy {
+			materialized[d.Day.T().Unix()] = true
+		}
+	}
+
+	missing := make([]time.Time, 0)
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		if !materialized[day.Unix()] {
+			missing = append(missing, day)
+		}
+	}
+
+	for len(missing) > 0 {
+		n := 1
+		for n < len(missing) && missing[n].Equal(missing[n-1].Add(24*time.Hour)) {
+			n++
+		}
+		if err := srv.materializeDays(missing[:n], user); err != nil {
+			return err
+		}
+		missing = missing[n:]
+	}
+
+	return nil
+}
+
+// materializeDays computes and persists durations for the given consecutive days
+func (srv *MaterializedDurationService) materializeDays(days []time.Time, user *models.User) error {
+	from, to := days[0], days[len(days)-1].Add(24*time.Hour)
+
+	durations, err := srv.durationService.Get(from, to, user, nil)
+	if err != nil {
+		return err
+	}
+
+	byDay := make(map[int64][]*models.Duration)
+	for _, d := range splitDurationsByDay(clipDurations(durations, from, to)) {
+		day := d.Time.T().UTC().Truncate(24 * time.Hour)
+		byDay[day.Unix()] = append(byDay[day.Unix()], d)
+	}
+
+	for _, day := range days {
+		if err := srv.durationRepository.ReplaceDay(user, day, user.DurationStrategy, byDay[day.Unix()]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clipDurations cuts off the parts of the given durations, which lie outside the given range, and drops the ones not overlapping it at all
+func clipDurations(durations []*models.Duration, from, to time.Time) models.Durations {
+	clipped := make(models.Durations, 0, len(durations))
+	for _, d := range durations {
+		start, end := d.Time.T(), d.Time.T().Add(d.Duration)
+		if !start.Before(to) || !end.After(from) {
+			continue
+		}
+		if start.Before(from) || end.After(to) {
+			dCopy := *d
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			dCopy.Time = models.CustomTime(start)
+			dCopy.Duration = end.Sub(start)
+			if dCopy.WriteDuration > dCopy.Duration {
+				dCopy.WriteDuration = dCopy.Duration
+			}
+			d = &dCopy
+		}
+		clipped = append(clipped, d)
+	}
+	return clipped
+}
+
+// splitDurationsByDay splits durations spanning past utc midnight into one per day. Lines changed and heartbeats are attributed to the first one.
+func splitDurationsByDay(durations []*models.Duration) models.Durations {
+	split := make(models.Durations, 0, len(durations))
+	for _, d := range durations {
+		for {
+			start := d.Time.T()
+			midnight := start.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+			if !start.Add(d.Duration).After(midnight) {
+				split = append(split, d)
+				break
+			}
+
+			head, tail := *d, *d
+			head.Duration = midnight.Sub(start)
+			tail.Time = models.CustomTime(midnight)
+			tail.Duration = d.Duration - head.Duration
+			tail.LinesChanged, tail.NumHeartbeats = 0, 0
+			if head.WriteDuration > head.Duration {
+				head.WriteDuration = head.Duration
+			}
+			tail.WriteDuration = d.WriteDuration - head.WriteDuration
+
+			split = append(split, &head)
+			d = &tail
+		}
+	}
+	return split
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MaterializedDurationServiceTestSuite struct {
+	suite.Suite
+	TestUser           *models.User
+	TestDay1           time.Time
+	TestDay2           time.Time
+	DurationService    *mocks.DurationServiceMock
+	DurationRepository *mocks.DurationRepositoryMock
+}
+
+func (suite *MaterializedDurationServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: TestUserId}
+	suite.TestDay1 = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.TestDay2 = suite.TestDay1.Add(24 * time.Hour)
+}
+
+func (suite *MaterializedDurationServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.DurationService = new(mocks.DurationServiceMock)
+	suite.DurationRepository = new(mocks.DurationRepositoryMock)
+}
+
+func TestMaterializedDurationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(MaterializedDurationServiceTestSuite))
+}
+
+func (suite *MaterializedDurationServiceTestSuite) TestMaterializedDurationService_Get() {
+	sut := NewMaterializedDurationService(suite.DurationService, suite.DurationRepository)
+
+	from, to := suite.TestDay1.Add(12*time.Hour), suite.TestDay2.Add(12*time.Hour)
+
+	// day 1 is materialized already, day 2 is not
+	suite.DurationRepository.On("GetDaysWithin", suite.TestDay1, to, suite.TestUser).Return([]*models.DurationDay{
+		{UserID: TestUserId, Day: models.CustomTime(suite.TestDay1)},
+	}, nil)
+	suite.DurationService.On("Get", suite.TestDay2, suite.TestDay2.Add(24*time.Hour), suite.TestUser, (*models.Filters)(nil)).Return(models.Durations{
+		{UserID: TestUserId, Project: TestProject1, Time: models.CustomTime(suite.TestDay2.Add(11 * time.Hour)), Duration: 2 * time.Hour},
+	}, nil)
+	suite.DurationRepository.On("ReplaceDay", suite.TestUser, suite.TestDay2, models.DurationStrategyDefault, mock.Anything).Return(nil)
+	suite.DurationRepository.On("GetAllWithin", suite.TestDay1, to, suite.TestUser).Return([]*models.Duration{
+		{UserID: TestUserId, Project: TestProject1, Time: models.CustomTime(suite.TestDay1.Add(11 * time.Hour)), Duration: 2 * time.Hour},
+		{UserID: TestUserId, Project: TestProject1, Time: models.CustomTime(suite.TestDay2.Add(11 * time.Hour)), Duration: 2 * time.Hour},
+	}, nil)
+
+	result, err := sut.Get(from, to, suite.TestUser, nil)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.True(suite.T(), result[0].Time.T().Equal(from))
+	assert.Equal(suite.T(), time.Hour, result[0].Duration)
+	assert.True(suite.T(), result[1].Time.T().Equal(suite.TestDay2.Add(11*time.Hour)))
+	assert.Equal(suite.T(), time.Hour, result[1].Duration)
+	suite.DurationService.AssertNumberOfCalls(suite.T(), "Get", 1)
+	suite.DurationRepository.AssertNumberOfCalls(suite.T(), "ReplaceDay", 1)
+}
+
+func (suite *MaterializedDurationServiceTestSuite) TestMaterializedDurationService_Get_Filtered() {
+	sut := NewMaterializedDurationService(suite.DurationService, suite.DurationRepository)
+
+	from, to := suite.TestDay1, suite.TestDay2
+	filters := models.NewFiltersWith(models.SummaryProject, TestProject1)
+
+	suite.DurationService.On("Get", from, to, suite.TestUser, filters).Return(models.Durations{}, nil)
+
+	_, err := sut.Get(from, to, suite.TestUser, filters)
+
+	assert.Nil(suite.T(), err)
+	suite.DurationService.AssertNumberOfCalls(suite.T(), "Get", 1)
+	suite.DurationRepository.AssertNotCalled(suite.T(), "GetDaysWithin", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *MaterializedDurationServiceTestSuite) TestMaterializedDurationService_SplitDurationsByDay() {
+	durations := models.Durations{
+		{Time: models.CustomTime(suite.TestDay1.Add(23 * time.Hour)), Duration: 2 * time.Hour, WriteDuration: 90 * time.Minute, LinesChanged: 10, NumHeartbeats: 5},
+	}
+
+	result := splitDurationsByDay(durations)
+
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), time.Hour, result[0].Duration)
+	assert.Equal(suite.T(), time.Hour, result[0].WriteDuration)
+	assert.Equal(suite.T(), 10, result[0].LinesChanged)
+	assert.True(suite.T(), result[1].Time.T().Equal(suite.TestDay2))
+	assert.Equal(suite.T(), time.Hour, result[1].Duration)
+	assert.Equal(suite.T(), 30*time.Minute, result[1].WriteDuration)
+	assert.Equal(suite.T(), 0, result[1].NumHeartbeats)
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+)
+
+var ErrMigrationInProgress = errors.New("a migration is already in progress")
+
+// MigrationService replicates a user's settings and heartbeats from their account at another wakapi instance, e.g. when moving to a new self-hosted server.
+// The migration's state is persisted as key-value pair after every page of heartbeats, so that, when started again for the same instance,
+// an interrupted migration is resumed from its last page and a finished one only replicates heartbeats stored at the other instance since.
+type MigrationService struct {
+	config           *config.Config
+	userService      IUserService
+	heartbeatService IHeartbeatService
+	settingsService  ISettingsService
+	keyValueService  IKeyValueService
+	jobService       IJobService
+	lock             sync.Mutex
+	running          map[string]bool
+}
+
+func NewMigrationService(userService IUserService, heartbeatService IHeartbeatService, settingsService ISettingsService, keyValueService IKeyValueService, jobService IJobService) *MigrationService {
+	return &MigrationService{
+		config:           config.Get(),
+		userService:      userService,
+		heartbeatService: heartbeatService,
+		settingsService:  settingsService,
+		keyValueService:  keyValueService,
+		jobService:       jobService,
+		running:          map[string]bool{},
+	}
+}
+
+// Get returns the state of the user's latest migration or nil, if the user never migrated
+func (srv *MigrationService) Get(user *models.User) (*models.Migration, error) {
+	kv := srv.keyValueService.MustGetString(migrationKey(user))
+	if kv.Value == "" {
+		return nil, nil
+	}
+	var migration models.Migration
+	if err := json.Unmarshal([]byte(kv.Value), &migration); err != nil {
+		return nil, err
+	}
+	return &migration, nil
+}
+
+// Start asynchronously migrates the user's data from the other instance's api at the given url, authenticated by the user's api key at that instance
+func (srv *MigrationService) Start(user *models.User, apiUrl, apiKey string) (*models.Migration, error) {
+	srv.lock.Lock()
+	if srv.running[user.ID] {
+		srv.lock.Unlock()
+		return nil, ErrMigrationInProgress
+	}
+	srv.running[user.ID] = true
+	srv.lock.Unlock()
+
+	release := func() {
+		srv.lock.Lock()
+		defer srv.lock.Unlock()
+		delete(srv.running, user.ID)
+	}
+
+	migration, err := srv.Get(user)
+	if err != nil || migration == nil || migration.ApiUrl != apiUrl {
+		migration = &models.Migration{ApiUrl: apiUrl}
+	}
+	migration.StartedAt, migration.FinishedAt, migration.Error = time.Now(), nil, ""
+	if err := srv.save(user, migration); err != nil {
+		release()
+		return nil, err
+	}
+	result := *migration
+
+	go func() {
+		defer release()
+		err := srv.jobService.Track(models.JobMigration, user.ID, func() error {
+			return srv.migrate(user, migration, imports.NewWakapiImporter(apiUrl, apiKey))
+		})
+		if err != nil {
+			config.Log().Error("failed to migrate data of user '%s' from %s - %v", user.ID, apiUrl, err)
+			migration.Error = err.Error()
+			if err := srv.save(user, migration); err != nil {
+				config.Log().Error("failed to save migration state of user '%s' - %v", user.ID, err)
+			}
+		}
+	}()
+
+	return &result, nil
+}
+
+// migrate imports the user's settings, unless already done, and then replicates the user's heartbeats page by page, starting after the migration's checkpoint
+func (srv *MigrationService) migrate(user *models.User, migration *models.Migration, importer *imports.WakapiImporter) error {
+	if !migration.SettingsImported {
+		doc, err := importer.FetchSettings()
+		if err != nil {
+			return err
+		}
+		if _, err := srv.settingsService.Import(user, doc, models.SettingsImportMerge); err != nil {
+			return err
+		}
+		migration.SettingsImported = true
+		if err := srv.save(user, migration); err != nil {
+			return err
+		}
+	}
+
+	importedBefore := migration.Imported
+	for {
+		page, err := importer.FetchPage(user, migration.AfterId)
+		if err != nil {
+			return err
+		}
+
+		heartbeats := make([]*models.Heartbeat, 0, len(page.Data))
+		for _, h := range page.Data {
+			if h.Valid() {
+				heartbeats = append(heartbeats, h)
+			}
+		}
+		// duplicates, e.g. of a page replicated before an interruption already, are skipped on insert
+		for len(heartbeats) > 0 {
+			n := len(heartbeats)
+			if batchSize := srv.config.App.ImportBatchSize; batchSize > 0 && n > batchSize {
+				n = batchSize
+			}
+			if err := srv.heartbeatService.InsertBatch(heartbeats[:n]); err != nil {
+				return err
+			}
+			heartbeats = heartbeats[n:]
+		}
+
+		migration.Imported += len(page.Data)
+		if page.NextAfterId > migration.AfterId {
+			migration.AfterId = page.NextAfterId
+		}
+		if err := srv.save(user, migration); err != nil {
+			return err
+		}
+
+		if !page.HasMore || len(page.Data) == 0 {
+			break
+		}
+	}
+
+	if migration.Imported > importedBefore && !user.HasData {
+		user.HasData = true
+		if _, err := srv.userService.Update(user); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	migration.FinishedAt = &now
+	logbuch.Info("migrated %d heartbeats of user '%s' from %s", migration.Imported-importedBefore, user.ID, migration.ApiUrl)
+	return srv.save(user, migration)
+}
+
+func (srv *MigrationService) save(user *models.User, migration *models.Migration) error {
+	data, err := json.Marshal(migration)
+	if err != nil {
+		return err
+	}
+	return srv.keyValueService.PutString(&models.KeyStringValue{Key: migrationKey(user), Value: string(data)})
+}
+
+func migrationKey(user *models.User) string {
+	return fmt.Sprintf("%s_%s", config.KeyMigration, user.ID)
+}
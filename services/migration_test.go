@@ -0,0 +1,175 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationServiceTestSuite struct {
+	suite.Suite
+	TestUser         *models.User
+	Server           *httptest.Server
+	Remote           []*models.Heartbeat
+	FailAfterId      int64
+	UserService      *mocks.UserServiceMock
+	HeartbeatService *mocks.HeartbeatServiceMock
+	SettingsService  *mocks.SettingsServiceMock
+	KeyValueService  *mocks.KeyValueServiceMock
+	State            *models.KeyStringValue
+}
+
+func (suite *MigrationServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.App.ImportBatchSize = 2
+	config.Set(cfg)
+
+	suite.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Basic cmVtb3RlLWtleQ==" { // 'remote-key'
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/settings/export":
+			json.NewEncoder(w).Encode(&models.SettingsDocument{Version: 1})
+		case "/api/heartbeats/export":
+			afterId, _ := strconv.ParseUint(r.URL.Query().Get("after_id"), 10, 64)
+			if suite.FailAfterId >= 0 && afterId == uint64(suite.FailAfterId) {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			page := &models.HeartbeatPage{Data: []*models.Heartbeat{}, NextAfterId: afterId}
+			for _, h := range suite.Remote {
+				if h.ID > afterId && len(page.Data) < 3 {
+					page.Data = append(page.Data, h)
+				}
+			}
+			if len(page.Data) > 0 {
+				page.NextAfterId = page.Data[len(page.Data)-1].ID
+			}
+			page.HasMore = page.NextAfterId < suite.Remote[len(suite.Remote)-1].ID
+			json.NewEncoder(w).Encode(page)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func (suite *MigrationServiceTestSuite) TearDownSuite() {
+	suite.Server.Close()
+}
+
+func (suite *MigrationServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUser = &models.User{ID: TestUserId}
+	suite.FailAfterId = -1
+	suite.State = &models.KeyStringValue{Key: "migration_" + TestUserId}
+
+	t0 := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	suite.Remote = make([]*models.Heartbeat, 0)
+	for i := 1; i <= 7; i++ {
+		suite.Remote = append(suite.Remote, &models.Heartbeat{
+			ID:      uint64(i * 10),
+			Entity:  fmt.Sprintf("file%d.go", i),
+			Project: "wakapi",
+			Time:    models.CustomTime(t0.Add(time.Duration(i) * time.Minute)),
+		})
+	}
+
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.UserService.On("Update", suite.TestUser).Return(suite.TestUser, nil)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+	suite.HeartbeatService.On("InsertBatch", mock.Anything).Return(nil)
+	suite.SettingsService = new(mocks.SettingsServiceMock)
+	suite.SettingsService.On("Import", suite.TestUser, mock.Anything, models.SettingsImportMerge).Return(&models.SettingsImportReport{}, nil)
+
+	// the key-value mock keeps the latest migration state, so that it can be resumed from
+	suite.KeyValueService = new(mocks.KeyValueServiceMock)
+	suite.KeyValueService.On("MustGetString", "migration_"+TestUserId).Return(suite.State)
+	suite.KeyValueService.On("PutString", mock.Anything).Run(func(args mock.Arguments) {
+		suite.State.Value = args.Get(0).(*models.KeyStringValue).Value
+	}).Return(nil)
+}
+
+func TestMigrationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(MigrationServiceTestSuite))
+}
+
+func (suite *MigrationServiceTestSuite) TestMigrationService_Migrate() {
+	sut := NewMigrationService(suite.UserService, suite.HeartbeatService, suite.SettingsService, suite.KeyValueService, nil)
+	migration := &models.Migration{ApiUrl: suite.Server.URL + "/api"}
+
+	err := sut.migrate(suite.TestUser, migration, imports.NewWakapiImporter(migration.ApiUrl, "remote-key"))
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), migration.IsDone())
+	assert.True(suite.T(), migration.SettingsImported)
+	assert.Equal(suite.T(), 7, migration.Imported)
+	assert.Equal(suite.T(), uint64(70), migration.AfterId)
+	assert.True(suite.T(), suite.TestUser.HasData)
+	suite.SettingsService.AssertNumberOfCalls(suite.T(), "Import", 1)
+
+	// pages of 3 heartbeats, inserted in batches of 2
+	suite.HeartbeatService.AssertNumberOfCalls(suite.T(), "InsertBatch", 5)
+	first := suite.HeartbeatService.Calls[0].Arguments.Get(0).([]*models.Heartbeat)
+	assert.Len(suite.T(), first, 2)
+	assert.Equal(suite.T(), uint64(0), first[0].ID)
+	assert.Equal(suite.T(), "10", first[0].OriginId)
+	assert.Equal(suite.T(), imports.OriginWakapi, first[0].Origin)
+	assert.Equal(suite.T(), TestUserId, first[0].UserID)
+	assert.NotEmpty(suite.T(), first[0].Hash)
+
+	state, err := sut.Get(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), migration.AfterId, state.AfterId)
+	assert.True(suite.T(), state.IsDone())
+}
+
+func (suite *MigrationServiceTestSuite) TestMigrationService_Migrate_Resume() {
+	sut := NewMigrationService(suite.UserService, suite.HeartbeatService, suite.SettingsService, suite.KeyValueService, nil)
+	migration := &models.Migration{ApiUrl: suite.Server.URL + "/api"}
+	importer := imports.NewWakapiImporter(migration.ApiUrl, "remote-key")
+
+	// interrupted after the first page
+	suite.FailAfterId = 30
+	err := sut.migrate(suite.TestUser, migration, importer)
+	assert.NotNil(suite.T(), err)
+	assert.False(suite.T(), migration.IsDone())
+
+	state, _ := sut.Get(suite.TestUser)
+	assert.Equal(suite.T(), uint64(30), state.AfterId)
+	assert.Equal(suite.T(), 3, state.Imported)
+
+	// resumed from the checkpoint, without importing settings again
+	suite.FailAfterId = -1
+	err = sut.migrate(suite.TestUser, state, importer)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), state.IsDone())
+	assert.Equal(suite.T(), 7, state.Imported)
+	assert.Equal(suite.T(), uint64(70), state.AfterId)
+	suite.SettingsService.AssertNumberOfCalls(suite.T(), "Import", 1)
+	suite.HeartbeatService.AssertNumberOfCalls(suite.T(), "InsertBatch", 5)
+}
+
+func (suite *MigrationServiceTestSuite) TestMigrationService_Migrate_Unauthorized() {
+	sut := NewMigrationService(suite.UserService, suite.HeartbeatService, suite.SettingsService, suite.KeyValueService, nil)
+	migration := &models.Migration{ApiUrl: suite.Server.URL + "/api"}
+
+	err := sut.migrate(suite.TestUser, migration, imports.NewWakapiImporter(migration.ApiUrl, "wrong-key"))
+
+	assert.NotNil(suite.T(), err)
+	assert.False(suite.T(), migration.SettingsImported)
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "InsertBatch", mock.Anything)
+}
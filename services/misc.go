@@ -1,9 +1,10 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
 	"github.com/emvi/logbuch"
 	"github.com/muety/wakapi/config"
-	"runtime"
 	"strconv"
 	"time"
 
@@ -11,72 +12,95 @@ import (
 	"github.com/muety/wakapi/models"
 )
 
+// number of users to retrieve summaries for at once when counting total time
+const countTotalTimeBatchSize = 50
+
+const (
+	instanceStatsMinUsers     = 3  // number of users a language needs to be used by to be included in public instance stats
+	instanceStatsMaxLanguages = 10 // number of most used languages to include in public instance stats
+)
+
+var ErrInstanceStatsDisabled = errors.New("public instance stats are disabled")
+
 type MiscService struct {
 	config          *config.Config
 	userService     IUserService
 	summaryService  ISummaryService
 	keyValueService IKeyValueService
+	jobService      IJobService
 }
 
-func NewMiscService(userService IUserService, summaryService ISummaryService, keyValueService IKeyValueService) *MiscService {
+func NewMiscService(userService IUserService, summaryService ISummaryService, keyValueService IKeyValueService, jobService IJobService) *MiscService {
 	return &MiscService{
 		config:          config.Get(),
 		userService:     userService,
 		summaryService:  summaryService,
 		keyValueService: keyValueService,
+		jobService:      jobService,
 	}
 }
 
-type CountTotalTimeJob struct {
-	UserID  string
-	NumJobs int
-}
-
-type CountTotalTimeResult struct {
-	UserId string
-	Total  time.Duration
-}
-
 func (srv *MiscService) ScheduleCountTotalTime() {
 	// Run once initially
-	if err := srv.runCountTotalTime(); err != nil {
+	if err := srv.countTotalTime(); err != nil {
 		logbuch.Fatal("failed to run CountTotalTimeJob: %v", err)
 	}
 
 	s := gocron.NewScheduler(time.Local)
-	s.Every(1).Hour().Do(srv.runCountTotalTime)
+	s.Every(1).Hour().Do(srv.countTotalTime)
 	s.StartBlocking()
 }
 
+func (srv *MiscService) countTotalTime() error {
+	return srv.jobService.Track(models.JobCountTotalTime, "", srv.runCountTotalTime)
+}
+
 func (srv *MiscService) runCountTotalTime() error {
 	users, err := srv.userService.GetAll()
 	if err != nil {
 		return err
 	}
 
-	jobs := make(chan *CountTotalTimeJob, len(users))
-	results := make(chan *CountTotalTimeResult, len(users))
-
-	for _, u := range users {
-		jobs <- &CountTotalTimeJob{
-			UserID:  u.ID,
-			NumJobs: len(users),
+	var total time.Duration
+	var activeUsers int
+	languageTotals := make(map[string]time.Duration)
+	languageUsers := make(map[string]int)
+
+	for i := 0; i < len(users); i += countTotalTimeBatchSize {
+		end := i + countTotalTimeBatchSize
+		if end > len(users) {
+			end = len(users)
 		}
-	}
-	close(jobs)
 
-	for i := 0; i < runtime.NumCPU(); i++ {
-		go srv.countTotalTimeWorker(jobs, results)
-	}
+		summaries, err := srv.summaryService.RetrieveBatch(time.Time{}, time.Now(), users[i:end])
+		if err != nil {
+			config.Log().Error("failed to count total for users %d to %d: %v", i, end, err)
+			continue
+		}
+		for _, summary := range summaries {
+			total += summary.TotalTime()
+			if srv.config.App.PublicInstanceStats {
+				for _, item := range summary.Languages {
+					languageTotals[item.Key] += item.TotalFixed()
+					languageUsers[item.Key]++
+				}
+			}
+		}
 
-	// persist
-	var i int
-	var total time.Duration
-	for i = 0; i < len(users); i++ {
-		result := <-results
-		total += result.Total
+		if !srv.config.App.PublicInstanceStats {
+			continue
+		}
+		recentSummaries, err := srv.summaryService.RetrieveBatch(time.Now().AddDate(0, 0, -7), time.Now(), users[i:end])
+		if err != nil {
+			config.Log().Error("failed to count active users for users %d to %d: %v", i, end, err)
+			continue
+		}
+		for _, summary := range recentSummaries {
+			if summary.TotalTime() > 0 {
+				activeUsers++
+			}
+		}
 	}
-	close(results)
 
 	if err := srv.keyValueService.PutString(&models.KeyStringValue{
 		Key:   config.KeyLatestTotalTime,
@@ -87,23 +111,40 @@ func (srv *MiscService) runCountTotalTime() error {
 
 	if err := srv.keyValueService.PutString(&models.KeyStringValue{
 		Key:   config.KeyLatestTotalUsers,
-		Value: strconv.Itoa(i),
+		Value: strconv.Itoa(len(users)),
 	}); err != nil {
 		logbuch.Error("failed to save total users count: %v", err)
 	}
 
+	if srv.config.App.PublicInstanceStats {
+		stats := models.NewInstanceStats(total, len(users), activeUsers, languageTotals, languageUsers, instanceStatsMinUsers, instanceStatsMaxLanguages)
+		if data, err := json.Marshal(stats); err == nil {
+			if err := srv.keyValueService.PutString(&models.KeyStringValue{
+				Key:   config.KeyInstanceStats,
+				Value: string(data),
+			}); err != nil {
+				logbuch.Error("failed to save instance stats: %v", err)
+			}
+		}
+	}
+
 	return nil
 }
 
-func (srv *MiscService) countTotalTimeWorker(jobs <-chan *CountTotalTimeJob, results chan<- *CountTotalTimeResult) {
-	for job := range jobs {
-		if result, err := srv.summaryService.Aliased(time.Time{}, time.Now(), &models.User{ID: job.UserID}, srv.summaryService.Retrieve, nil, false); err != nil {
-			config.Log().Error("failed to count total for user %s: %v", job.UserID, err)
-		} else {
-			results <- &CountTotalTimeResult{
-				UserId: job.UserID,
-				Total:  result.TotalTime(),
-			}
-		}
+// GetInstanceStats returns the latest aggregated stats of the entire instance, as computed along with the total time count, if published by the admin
+func (srv *MiscService) GetInstanceStats() (*models.InstanceStats, error) {
+	if !srv.config.App.PublicInstanceStats {
+		return nil, ErrInstanceStatsDisabled
+	}
+
+	kv, err := srv.keyValueService.GetString(config.KeyInstanceStats)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats models.InstanceStats
+	if err := json.Unmarshal([]byte(kv.Value), &stats); err != nil {
+		return nil, err
 	}
+	return &stats, nil
 }
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+const telegramApiUrl = "https://api.telegram.org"
+
+// NotificationService decides, which channels to notify users about an event via, according to their preferences.
+// E-mails are sent by the notifying services themselves, all other channels are served from here.
+type NotificationService struct {
+	config     *config.Config
+	cache      *cache.Cache
+	repository repositories.INotificationPreferenceRepository
+	httpClient *http.Client
+}
+
+func NewNotificationService(notificationPreferenceRepository repositories.INotificationPreferenceRepository) *NotificationService {
+	return &NotificationService{
+		config:     config.Get(),
+		cache:      cache.New(24*time.Hour, 24*time.Hour),
+		repository: notificationPreferenceRepository,
+		httpClient: config.NewHttpClient(config.ProxyScopeNotifications, 10*time.Second),
+	}
+}
+
+func (srv *NotificationService) GetPreferences(user *models.User) (models.NotificationPreferences, error) {
+	if prefs, found := srv.cache.Get(user.ID); found {
+		return prefs.(models.NotificationPreferences), nil
+	}
+
+	prefs, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(user.ID, models.NotificationPreferences(prefs), cache.DefaultExpiration)
+	return prefs, nil
+}
+
+// UpdatePreferences stores the given preferences, while leaving all other combinations of event and channel untouched
+func (srv *NotificationService) UpdatePreferences(user *models.User, prefs []*models.NotificationPreference) (models.NotificationPreferences, error) {
+	for _, p := range prefs {
+		p.UserID = user.ID
+		if _, err := srv.repository.Upsert(p); err != nil {
+			return nil, err
+		}
+	}
+
+	srv.cache.Delete(user.ID)
+	return srv.GetPreferences(user)
+}
+
+// IsAvailable tells whether the user has configured everything needed to be notified via the given channel
+func (srv *NotificationService) IsAvailable(user *models.User, channel string) bool {
+	switch channel {
+	case models.NotificationChannelEmail:
+		return user.Email != "" && srv.config.Mail.Enabled
+	case models.NotificationChannelWebhook:
+		return user.NotificationWebhookUrl != ""
+	case models.NotificationChannelSlack:
+		return user.SlackWebhookUrl != ""
+	case models.NotificationChannelTelegram:
+		return user.TelegramChatId != "" && srv.config.App.TelegramBotToken != ""
+	}
+	return false
+}
+
+func (srv *NotificationService) IsEnabled(user *models.User, event, channel string) bool {
+	if !srv.IsAvailable(user, channel) {
+		return false
+	}
+	prefs, err := srv.GetPreferences(user)
+	if err != nil {
+		config.Log().Error("failed to fetch notification preferences for user '%s' - %v", user.ID, err)
+		return models.DefaultNotificationEnabled(event, channel)
+	}
+	return prefs.IsEnabled(event, channel)
+}
+
+// Notify sends the notification via every non-mail channel the user has enabled for its event
+func (srv *NotificationService) Notify(user *models.User, notification *models.Notification) error {
+	notification.User = user.ID
+
+	var failed []string
+	for _, channel := range models.NotificationChannels() {
+		if channel == models.NotificationChannelEmail || !srv.IsEnabled(user, notification.Event, channel) {
+			continue
+		}
+		if err := srv.send(user, channel, notification); err != nil {
+			config.Log().Error("failed to send '%s' notification via %s to user '%s' - %v", notification.Event, channel, user.ID, err)
+			failed = append(failed, channel)
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New(fmt.Sprintf("failed to notify via %s", strings.Join(failed, ", ")))
+	}
+	return nil
+}
+
+func (srv *NotificationService) send(user *models.User, channel string, notification *models.Notification) error {
+	switch channel {
+	case models.NotificationChannelWebhook:
+		return srv.post(user.NotificationWebhookUrl, notification)
+	case models.NotificationChannelSlack:
+		return srv.post(user.SlackWebhookUrl, map[string]string{"text": notification.String()})
+	case models.NotificationChannelTelegram:
+		url := fmt.Sprintf("%s/bot%s/sendMessage", telegramApiUrl, srv.config.App.TelegramBotToken)
+		return srv.post(url, map[string]string{"chat_id": user.TelegramChatId, "text": notification.String()})
+	}
+	return errors.New("unsupported channel")
+}
+
+func (srv *NotificationService) post(url string, payload interface{}) error {
+	data, _ := json.Marshal(payload)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wakapi/"+srv.config.Version)
+
+	res, err := srv.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return errors.New(fmt.Sprintf("got status %d", res.StatusCode))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type NotificationServiceTestSuite struct {
+	suite.Suite
+	TestUser                         *models.User
+	NotificationPreferenceRepository *mocks.NotificationPreferenceRepositoryMock
+}
+
+func (suite *NotificationServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.Mail.Enabled = true
+	config.Set(cfg)
+}
+
+func (suite *NotificationServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUser = &models.User{ID: "user1", Email: "john@example.org"}
+	suite.NotificationPreferenceRepository = new(mocks.NotificationPreferenceRepositoryMock)
+	suite.NotificationPreferenceRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.NotificationPreference{
+		{UserID: suite.TestUser.ID, Event: models.NotificationEventReport, Channel: models.NotificationChannelWebhook, Enabled: true},
+		{UserID: suite.TestUser.ID, Event: models.NotificationEventBudgetAlert, Channel: models.NotificationChannelEmail, Enabled: false},
+	}, nil)
+}
+
+func TestNotificationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(NotificationServiceTestSuite))
+}
+
+func (suite *NotificationServiceTestSuite) TestNotificationService_IsEnabled() {
+	sut := NewNotificationService(suite.NotificationPreferenceRepository)
+
+	assert.True(suite.T(), sut.IsEnabled(suite.TestUser, models.NotificationEventImportFinished, models.NotificationChannelEmail))
+	assert.False(suite.T(), sut.IsEnabled(suite.TestUser, models.NotificationEventBudgetAlert, models.NotificationChannelEmail))
+	assert.False(suite.T(), sut.IsEnabled(suite.TestUser, models.NotificationEventReport, models.NotificationChannelWebhook)) // no url configured
+
+	suite.TestUser.NotificationWebhookUrl = "https://example.org/hook"
+	suite.TestUser.Email = ""
+	assert.True(suite.T(), sut.IsEnabled(suite.TestUser, models.NotificationEventReport, models.NotificationChannelWebhook))
+	assert.False(suite.T(), sut.IsEnabled(suite.TestUser, models.NotificationEventImportFinished, models.NotificationChannelEmail)) // no address configured
+
+	suite.NotificationPreferenceRepository.AssertNumberOfCalls(suite.T(), "GetByUser", 1) // cached
+}
+
+func (suite *NotificationServiceTestSuite) TestNotificationService_Notify() {
+	var received models.Notification
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	sut := NewNotificationService(suite.NotificationPreferenceRepository)
+	suite.TestUser.NotificationWebhookUrl = server.URL
+
+	err := sut.Notify(suite.TestUser, &models.Notification{Event: models.NotificationEventReport, Title: "Weekly report"})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, calls)
+	assert.Equal(suite.T(), "Weekly report", received.Title)
+	assert.Equal(suite.T(), suite.TestUser.ID, received.User)
+
+	err = sut.Notify(suite.TestUser, &models.Notification{Event: models.NotificationEventBudgetAlert})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, calls) // webhook not enabled for budget alerts
+}
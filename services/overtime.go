@@ -0,0 +1,75 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+var ErrNoWorkdayTarget = errors.New("no workday target set")
+
+type OvertimeService struct {
+	config         *config.Config
+	summaryService ISummaryService
+	dayOffService  IDayOffService
+}
+
+func NewOvertimeService(summaryService ISummaryService, dayOffService IDayOffService) *OvertimeService {
+	return &OvertimeService{
+		config:         config.Get(),
+		summaryService: summaryService,
+		dayOffService:  dayOffService,
+	}
+}
+
+// GetOvertime compares the time worked on every day within the given range to the user's workday target.
+// Days off and days before the user's target start date have no target.
+func (srv *OvertimeService) GetOvertime(user *models.User, from, to time.Time) (*models.Overtime, error) {
+	if !user.HasWorkdayTarget() {
+		return nil, ErrNoWorkdayTarget
+	}
+
+	daysOff, err := srv.dayOffService.GetByUserMapped(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to = from.In(user.TZ()), to.In(user.TZ())
+	days := make([]*models.OvertimeDay, 0)
+
+	for _, interval := range utils.SplitRangeByDays(from, to) {
+		summary, err := srv.summaryService.Aliased(interval[0], interval[1], user, srv.summaryService.Retrieve, nil, false)
+		if err != nil {
+			return nil, err
+		}
+
+		date := utils.StartOfDay(interval[0])
+		dayOff, isOff := daysOff[date.Format(models.DayOffFormat)]
+
+		target := user.WorkdayTarget(date)
+		if isOff || date.Format(models.DayOffFormat) < user.WorkTargetSince {
+			target = 0
+		}
+
+		day := models.NewOvertimeDay(date, target, summary.TotalTime())
+		if isOff {
+			day.DayOff = dayOff.Kind
+		}
+		days = append(days, day)
+	}
+
+	return models.NewOvertime(from, to, days), nil
+}
+
+// GetBalance returns the user's overtime accumulated since their target start date (or the beginning of the current week, if not set)
+func (srv *OvertimeService) GetBalance(user *models.User) (*models.Overtime, error) {
+	now := time.Now().In(user.TZ())
+	from := utils.StartOfWeek(now)
+	if since, err := time.ParseInLocation(models.DayOffFormat, user.WorkTargetSince, user.TZ()); err == nil {
+		from = since
+	}
+	return srv.GetOvertime(user, from, now)
+}
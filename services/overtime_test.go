@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type OvertimeServiceTestSuite struct {
+	suite.Suite
+	TestUser       *models.User
+	SummaryService *mocks.SummaryServiceMock
+	DayOffService  *mocks.DayOffServiceMock
+}
+
+func (suite *OvertimeServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+}
+
+func (suite *OvertimeServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUser = &models.User{ID: "user1", Location: "Europe/Berlin", WorkdayTargetMin: 8 * 60, Workdays: models.DefaultWorkdays}
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.DayOffService = new(mocks.DayOffServiceMock)
+
+	// 9 hours of coding every single day
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, mock.Anything, false).Return(&models.Summary{
+		Projects: []*models.SummaryItem{{Type: models.SummaryProject, Key: "wakapi", Total: 9 * time.Hour / time.Second}},
+	}, nil)
+	suite.DayOffService.On("GetByUserMapped", suite.TestUser.ID).Return(models.NewDaysOff([]*models.DayOff{
+		{Day: "2022-10-26", Kind: models.DayOffVacation},
+	}), nil)
+}
+
+func TestOvertimeServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(OvertimeServiceTestSuite))
+}
+
+func (suite *OvertimeServiceTestSuite) TestOvertimeService_GetOvertime() {
+	sut := NewOvertimeService(suite.SummaryService, suite.DayOffService)
+
+	from := time.Date(2022, 10, 24, 0, 0, 0, 0, suite.TestUser.TZ()) // monday
+	to := from.AddDate(0, 0, 7)
+
+	result, err := sut.GetOvertime(suite.TestUser, from, to)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result.Days, 7)
+	assert.Equal(suite.T(), 32*time.Hour, result.Target) // four workdays, one day off
+	assert.Equal(suite.T(), 63*time.Hour, result.Actual)
+	assert.Equal(suite.T(), int64(31*3600), result.BalanceSeconds)
+	assert.True(suite.T(), result.IsOvertime())
+	assert.Equal(suite.T(), int64(3600), result.Days[0].BalanceSeconds)
+	assert.Equal(suite.T(), models.DayOffVacation, result.Days[2].DayOff)
+	assert.Equal(suite.T(), int64(0), result.Days[2].TargetSeconds)
+	assert.Equal(suite.T(), int64(0), result.Days[5].TargetSeconds) // saturday
+}
+
+func (suite *OvertimeServiceTestSuite) TestOvertimeService_GetOvertime_Since() {
+	sut := NewOvertimeService(suite.SummaryService, suite.DayOffService)
+
+	suite.TestUser.WorkdayTargetMin = 10 * 60
+	suite.TestUser.WorkTargetSince = "2022-10-25"
+
+	from := time.Date(2022, 10, 24, 0, 0, 0, 0, suite.TestUser.TZ())
+	to := from.AddDate(0, 0, 2)
+
+	result, err := sut.GetOvertime(suite.TestUser, from, to)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 10*time.Hour, result.Target) // monday is before the start date
+	assert.Equal(suite.T(), 8*time.Hour, result.BalanceAbs())
+	assert.True(suite.T(), result.IsOvertime())
+}
+
+func (suite *OvertimeServiceTestSuite) TestOvertimeService_GetOvertime_NoTarget() {
+	sut := NewOvertimeService(suite.SummaryService, suite.DayOffService)
+
+	suite.TestUser.WorkdayTargetMin = 0
+
+	_, err := sut.GetOvertime(suite.TestUser, time.Now().Add(-24*time.Hour), time.Now())
+
+	assert.ErrorIs(suite.T(), err, ErrNoWorkdayTarget)
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-co-op/gocron"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+)
+
+const budgetMonthFormat = "2006-01"
+
+type ProjectBudgetService struct {
+	config              *config.Config
+	repository          repositories.IProjectBudgetRepository
+	userService         IUserService
+	summaryService      ISummaryService
+	mailService         IMailService
+	notificationService INotificationService
+}
+
+func NewProjectBudgetService(projectBudgetRepository repositories.IProjectBudgetRepository, userService IUserService, summaryService ISummaryService, mailService IMailService, notificationService INotificationService) *ProjectBudgetService {
+	return &ProjectBudgetService{
+		config:              config.Get(),
+		repository:          projectBudgetRepository,
+		userService:         userService,
+		summaryService:      summaryService,
+		mailService:         mailService,
+		notificationService: notificationService,
+	}
+}
+
+// Schedule hourly checks all budgets and notifies their users, once a threshold was reached within the current month
+func (srv *ProjectBudgetService) Schedule() {
+	logbuch.Info("scheduling project budget checks")
+
+	s := gocron.NewScheduler(time.Local)
+	s.Every(1).Hour().Do(srv.checkAll)
+	s.StartBlocking()
+}
+
+func (srv *ProjectBudgetService) GetByUser(userId string) ([]*models.ProjectBudget, error) {
+	return srv.repository.GetByUser(userId)
+}
+
+// Set sets the monthly budget in hours for the given project or, if hours are zero, removes it
+func (srv *ProjectBudgetService) Set(userId, projectKey string, hours int) (*models.ProjectBudget, error) {
+	if hours <= 0 {
+		return nil, srv.repository.DeleteByUserAndProject(userId, projectKey)
+	}
+	return srv.repository.Upsert(&models.ProjectBudget{UserID: userId, ProjectKey: projectKey, Hours: hours})
+}
+
+// GetStatuses returns the consumption of all of the user's budgets within the current month
+func (srv *ProjectBudgetService) GetStatuses(user *models.User) ([]*models.BudgetStatus, error) {
+	budgets, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return srv.getStatuses(user, budgets)
+}
+
+// GetStatus returns the consumption of the project's budget within the current month or nil, if the project has no budget
+func (srv *ProjectBudgetService) GetStatus(user *models.User, projectKey string) (*models.BudgetStatus, error) {
+	statuses, err := srv.GetStatuses(user)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statuses {
+		if s.Project == projectKey {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (srv *ProjectBudgetService) getStatuses(user *models.User, budgets []*models.ProjectBudget) ([]*models.BudgetStatus, error) {
+	if len(budgets) == 0 {
+		return []*models.BudgetStatus{}, nil
+	}
+
+	now := time.Now().In(user.TZ())
+	summary, err := srv.summaryService.Aliased(utils.StartOfMonth(now), now, user, srv.summaryService.Retrieve, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*models.BudgetStatus, len(budgets))
+	for i, b := range budgets {
+		statuses[i] = models.NewBudgetStatus(b, now.Format(budgetMonthFormat), summary.TotalTimeByKey(models.SummaryProject, b.ProjectKey))
+	}
+	return statuses, nil
+}
+
+func (srv *ProjectBudgetService) checkAll() {
+	budgets, err := srv.repository.GetAll()
+	if err != nil {
+		config.Log().Error("failed to fetch project budgets - %v", err)
+		return
+	}
+
+	budgetsByUser := make(map[string][]*models.ProjectBudget)
+	for _, b := range budgets {
+		budgetsByUser[b.UserID] = append(budgetsByUser[b.UserID], b)
+	}
+
+	for userId, userBudgets := range budgetsByUser {
+		user, err := srv.userService.GetUserById(userId)
+		if err != nil {
+			config.Log().Error("failed to fetch user '%s' for project budget check - %v", userId, err)
+			continue
+		}
+		if err := srv.check(user, userBudgets); err != nil {
+			config.Log().Error("failed to check project budgets of user '%s' - %v", userId, err)
+		}
+	}
+}
+
+// check notifies the user about every budget, that reached a threshold, which they weren't notified about within the current month, yet
+func (srv *ProjectBudgetService) check(user *models.User, budgets []*models.ProjectBudget) error {
+	statuses, err := srv.getStatuses(user, budgets)
+	if err != nil {
+		return err
+	}
+
+	for i, status := range statuses {
+		budget := budgets[i]
+		if budget.NotifiedMonth != status.Month {
+			budget.NotifiedThreshold = 0 // new month, new budget
+		}
+		if status.Threshold <= budget.NotifiedThreshold {
+			continue
+		}
+
+		if srv.notificationService.IsEnabled(user, models.NotificationEventBudgetAlert, models.NotificationChannelEmail) {
+			if err := srv.mailService.SendBudgetAlert(user, status); err != nil {
+				return err
+			}
+			logbuch.Info("sent budget alert for project '%s' (%d %%) to user '%s'", status.Project, status.Threshold, user.ID)
+		}
+
+		// failures of other channels were logged and shouldn't cause the user to be alerted over and over again
+		srv.notificationService.Notify(user, &models.Notification{
+			Event: models.NotificationEventBudgetAlert,
+			Title: fmt.Sprintf("%d %% of budget for %s reached", status.Threshold, status.Project),
+			Text:  fmt.Sprintf("You have spent %s on project %s this month, which is %.0f %% of its monthly budget of %s.", utils.FmtWakatimeDuration(status.Used), status.Project, status.Percentage, utils.FmtWakatimeDuration(status.Budget)),
+			Link:  fmt.Sprintf("%s/summary?interval=month&project=%s", srv.config.Server.GetPublicUrl(), url.QueryEscape(status.Project)),
+		})
+
+		budget.NotifiedThreshold, budget.NotifiedMonth = status.Threshold, status.Month
+		if err := srv.repository.UpdateNotified(budget); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProjectBudgetServiceTestSuite struct {
+	suite.Suite
+	TestUser                *models.User
+	ProjectBudgetRepository *mocks.ProjectBudgetRepositoryMock
+	UserService             *mocks.UserServiceMock
+	SummaryService          *mocks.SummaryServiceMock
+	MailService             *mocks.MailServiceMock
+	NotificationService     *mocks.NotificationServiceMock
+}
+
+func (suite *ProjectBudgetServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: "user1", Email: "john@example.org"}
+}
+
+func (suite *ProjectBudgetServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.ProjectBudgetRepository = new(mocks.ProjectBudgetRepositoryMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.MailService = new(mocks.MailServiceMock)
+	suite.NotificationService = new(mocks.NotificationServiceMock)
+
+	suite.NotificationService.On("IsEnabled", suite.TestUser, models.NotificationEventBudgetAlert, models.NotificationChannelEmail).Return(true)
+	suite.NotificationService.On("Notify", suite.TestUser, mock.Anything).Return(nil)
+
+	suite.SummaryService.On("Aliased", mock.Anything, mock.Anything, suite.TestUser, mock.Anything, mock.Anything, false).Return(&models.Summary{
+		Projects: []*models.SummaryItem{
+			{Type: models.SummaryProject, Key: "wakapi", Total: 9 * time.Hour / time.Second},
+			{Type: models.SummaryProject, Key: "anchr", Total: 2 * time.Hour / time.Second},
+		},
+	}, nil)
+}
+
+func TestProjectBudgetServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ProjectBudgetServiceTestSuite))
+}
+
+func (suite *ProjectBudgetServiceTestSuite) TestProjectBudgetService_GetStatuses() {
+	sut := NewProjectBudgetService(suite.ProjectBudgetRepository, suite.UserService, suite.SummaryService, suite.MailService, suite.NotificationService)
+
+	suite.ProjectBudgetRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectBudget{
+		{UserID: suite.TestUser.ID, ProjectKey: "anchr", Hours: 10},
+		{UserID: suite.TestUser.ID, ProjectKey: "wakapi", Hours: 10},
+	}, nil)
+
+	result, err := sut.GetStatuses(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), 2*time.Hour, result[0].Used)
+	assert.Equal(suite.T(), int64(2*3600), result[0].UsedSeconds)
+	assert.Equal(suite.T(), 20.0, result[0].Percentage)
+	assert.Equal(suite.T(), 0, result[0].Threshold)
+	assert.Equal(suite.T(), 9*time.Hour, result[1].Used)
+	assert.Equal(suite.T(), 90.0, result[1].Percentage)
+	assert.Equal(suite.T(), 80, result[1].Threshold)
+}
+
+func (suite *ProjectBudgetServiceTestSuite) TestProjectBudgetService_Check_Notify() {
+	sut := NewProjectBudgetService(suite.ProjectBudgetRepository, suite.UserService, suite.SummaryService, suite.MailService, suite.NotificationService)
+
+	month := time.Now().Format(budgetMonthFormat)
+	budgets := []*models.ProjectBudget{
+		{ID: 1, UserID: suite.TestUser.ID, ProjectKey: "wakapi", Hours: 10},                                            // 90 %, not notified yet
+		{ID: 2, UserID: suite.TestUser.ID, ProjectKey: "anchr", Hours: 2, NotifiedThreshold: 80, NotifiedMonth: month}, // 100 %, notified about 80 % only
+	}
+
+	suite.MailService.On("SendBudgetAlert", suite.TestUser, mock.Anything).Return(nil)
+	suite.ProjectBudgetRepository.On("UpdateNotified", mock.Anything).Return(nil)
+
+	err := sut.check(suite.TestUser, budgets)
+
+	assert.Nil(suite.T(), err)
+	suite.MailService.AssertNumberOfCalls(suite.T(), "SendBudgetAlert", 2)
+	suite.NotificationService.AssertNumberOfCalls(suite.T(), "Notify", 2)
+	suite.ProjectBudgetRepository.AssertNumberOfCalls(suite.T(), "UpdateNotified", 2)
+	assert.Equal(suite.T(), 80, budgets[0].NotifiedThreshold)
+	assert.Equal(suite.T(), month, budgets[0].NotifiedMonth)
+	assert.Equal(suite.T(), 100, budgets[1].NotifiedThreshold)
+}
+
+func (suite *ProjectBudgetServiceTestSuite) TestProjectBudgetService_Check_AlreadyNotified() {
+	sut := NewProjectBudgetService(suite.ProjectBudgetRepository, suite.UserService, suite.SummaryService, suite.MailService, suite.NotificationService)
+
+	month := time.Now().Format(budgetMonthFormat)
+	budgets := []*models.ProjectBudget{
+		{ID: 1, UserID: suite.TestUser.ID, ProjectKey: "wakapi", Hours: 10, NotifiedThreshold: 80, NotifiedMonth: month},
+		{ID: 2, UserID: suite.TestUser.ID, ProjectKey: "anchr", Hours: 10}, // 20 %, below any threshold
+	}
+
+	err := sut.check(suite.TestUser, budgets)
+
+	assert.Nil(suite.T(), err)
+	suite.MailService.AssertNotCalled(suite.T(), "SendBudgetAlert", mock.Anything, mock.Anything)
+	suite.ProjectBudgetRepository.AssertNotCalled(suite.T(), "UpdateNotified", mock.Anything)
+}
+
+func (suite *ProjectBudgetServiceTestSuite) TestProjectBudgetService_Check_NewMonth() {
+	sut := NewProjectBudgetService(suite.ProjectBudgetRepository, suite.UserService, suite.SummaryService, suite.MailService, suite.NotificationService)
+
+	budgets := []*models.ProjectBudget{
+		{ID: 1, UserID: suite.TestUser.ID, ProjectKey: "wakapi", Hours: 10, NotifiedThreshold: 100, NotifiedMonth: "2000-01"},
+	}
+
+	suite.MailService.On("SendBudgetAlert", suite.TestUser, mock.Anything).Return(nil)
+	suite.ProjectBudgetRepository.On("UpdateNotified", mock.Anything).Return(nil)
+
+	err := sut.check(suite.TestUser, budgets)
+
+	assert.Nil(suite.T(), err)
+	suite.MailService.AssertNumberOfCalls(suite.T(), "SendBudgetAlert", 1)
+	assert.Equal(suite.T(), 80, budgets[0].NotifiedThreshold)
+	assert.Equal(suite.T(), time.Now().Format(budgetMonthFormat), budgets[0].NotifiedMonth)
+}
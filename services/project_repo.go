@@ -0,0 +1,178 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+// commit counts are cached for a while, because GitHub only allows for 60 unauthenticated requests per hour
+const commitCountCacheTime = 15 * time.Minute
+
+var githubLastPageRegex = regexp.MustCompile(`[?&]page=(\d+)[^>]*>;\s*rel="last"`)
+
+type ProjectRepoService struct {
+	config       *config.Config
+	cache        *cache.Cache
+	commitsCache *cache.Cache
+	repository   repositories.IProjectRepoRepository
+	httpClient   *http.Client
+}
+
+func NewProjectRepoService(projectRepoRepository repositories.IProjectRepoRepository) *ProjectRepoService {
+	return &ProjectRepoService{
+		config:       config.Get(),
+		repository:   projectRepoRepository,
+		cache:        cache.New(24*time.Hour, 24*time.Hour),
+		commitsCache: cache.New(commitCountCacheTime, commitCountCacheTime),
+		httpClient:   config.NewHttpClient(config.ProxyScopeIntegrations, 10*time.Second),
+	}
+}
+
+func (srv *ProjectRepoService) GetByUser(userId string) ([]*models.ProjectRepo, error) {
+	if repos, found := srv.cache.Get(userId); found {
+		return repos.([]*models.ProjectRepo), nil
+	}
+
+	repos, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Set(userId, repos, cache.DefaultExpiration)
+	return repos, nil
+}
+
+// GetByUserMapped returns the user's repositories, mapped by their project key
+func (srv *ProjectRepoService) GetByUserMapped(userId string) (map[string]*models.ProjectRepo, error) {
+	repos, err := srv.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	repoMap := make(map[string]*models.ProjectRepo, len(repos))
+	for _, r := range repos {
+		repoMap[r.ProjectKey] = r
+	}
+	return repoMap, nil
+}
+
+// Set links the given project to a repository or, if the url is empty, removes an existing link
+func (srv *ProjectRepoService) Set(userId, projectKey, repoUrl string) (*models.ProjectRepo, error) {
+	defer srv.cache.Delete(userId)
+
+	if repoUrl == "" {
+		return nil, srv.repository.DeleteByUserAndProject(userId, projectKey)
+	}
+
+	repo := &models.ProjectRepo{UserID: userId, ProjectKey: projectKey, Url: repoUrl}
+	if _, _, _, err := repo.Parse(); err != nil {
+		return nil, err
+	}
+	return srv.repository.Upsert(repo)
+}
+
+// CountCommits returns the number of commits pushed to the repository's default branch within the given interval
+func (srv *ProjectRepoService) CountCommits(repo *models.ProjectRepo, from, to time.Time) (int, error) {
+	from, to = from.Truncate(commitCountCacheTime), to.Truncate(commitCountCacheTime)
+
+	cacheKey := fmt.Sprintf("%s_%d_%d", repo.Url, from.Unix(), to.Unix())
+	if count, found := srv.commitsCache.Get(cacheKey); found {
+		return count.(int), nil
+	}
+
+	provider, host, path, err := repo.Parse()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	switch provider {
+	case models.RepoProviderGithub:
+		count, err = srv.countGithubCommits(path, from, to)
+	case models.RepoProviderGitlab:
+		count, err = srv.countGitlabCommits(host, path, from, to)
+	default:
+		err = fmt.Errorf("unsupported repository provider '%s'", provider)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	srv.commitsCache.SetDefault(cacheKey, count)
+	return count, nil
+}
+
+// see https://docs.github.com/en/rest/commits/commits#list-commits
+// requesting one commit per page, the number of the last page equals the number of commits
+func (srv *ProjectRepoService) countGithubCommits(path string, from, to time.Time) (int, error) {
+	query := url.Values{}
+	query.Set("since", from.UTC().Format(time.RFC3339))
+	query.Set("until", to.UTC().Format(time.RFC3339))
+	query.Set("per_page", "1")
+
+	res, err := srv.get(fmt.Sprintf("https://api.github.com/repos/%s/commits?%s", path, query.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if match := githubLastPageRegex.FindStringSubmatch(res.Header.Get("Link")); match != nil {
+		return strconv.Atoi(match[1])
+	}
+
+	// no pagination, i.e. zero or one commit
+	var commits []interface{}
+	if err := json.NewDecoder(res.Body).Decode(&commits); err != nil {
+		return 0, err
+	}
+	return len(commits), nil
+}
+
+// see https://docs.gitlab.com/ee/api/commits.html#list-repository-commits
+func (srv *ProjectRepoService) countGitlabCommits(host, path string, from, to time.Time) (int, error) {
+	query := url.Values{}
+	query.Set("since", from.UTC().Format(time.RFC3339))
+	query.Set("until", to.UTC().Format(time.RFC3339))
+	query.Set("per_page", "1")
+
+	res, err := srv.get(fmt.Sprintf("https://%s/api/v4/projects/%s/repository/commits?%s", host, url.PathEscape(path), query.Encode()))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	total := res.Header.Get("X-Total")
+	if total == "" {
+		// gitlab omits totals for more than 10,000 results
+		return 0, errors.New("gitlab did not report a total commit count")
+	}
+	return strconv.Atoi(total)
+}
+
+func (srv *ProjectRepoService) get(requestUrl string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := srv.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("got status %d from %s", res.StatusCode, req.URL.Host)
+	}
+	return res, nil
+}
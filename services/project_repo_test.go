@@ -0,0 +1,113 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// redirectTransport sends all requests to the test server, regardless of their original host
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Original-Host", req.URL.Host)
+	req.URL.Scheme, req.URL.Host = t.target.Scheme, t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+type ProjectRepoServiceTestSuite struct {
+	suite.Suite
+	Server   *httptest.Server
+	Requests []*http.Request
+}
+
+func (suite *ProjectRepoServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+
+	suite.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Requests = append(suite.Requests, r)
+
+		switch r.Header.Get("X-Original-Host") + r.URL.EscapedPath() {
+		case "api.github.com/repos/muety/wakapi/commits":
+			w.Header().Set("Link", `<https://api.github.com/repositories/1/commits?per_page=1&since=x&page=2>; rel="next", <https://api.github.com/repositories/1/commits?per_page=1&since=x&page=42>; rel="last"`)
+			w.Write([]byte(`[{"sha": "abc"}]`))
+		case "api.github.com/repos/muety/single/commits":
+			w.Write([]byte(`[{"sha": "abc"}]`))
+		case "api.github.com/repos/muety/empty/commits":
+			w.Write([]byte(`[]`))
+		case "gitlab.com/api/v4/projects/group%2Fproject/repository/commits":
+			w.Header().Set("X-Total", "17")
+			w.Write([]byte(`[{"id": "abc"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func (suite *ProjectRepoServiceTestSuite) TearDownSuite() {
+	suite.Server.Close()
+}
+
+func (suite *ProjectRepoServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.Requests = nil
+}
+
+func TestProjectRepoServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ProjectRepoServiceTestSuite))
+}
+
+func (suite *ProjectRepoServiceTestSuite) TestProjectRepoService_CountCommits() {
+	sut := suite.newService()
+	from, to := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]int{
+		"https://github.com/muety/wakapi":      42,
+		"https://github.com/muety/single":      1,
+		"https://github.com/muety/empty":       0,
+		"https://gitlab.com/group/project.git": 17,
+	}
+
+	for repoUrl, expected := range tests {
+		count, err := sut.CountCommits(&models.ProjectRepo{ProjectKey: "wakapi", Url: repoUrl}, from, to)
+		assert.Nil(suite.T(), err, repoUrl)
+		assert.Equal(suite.T(), expected, count, repoUrl)
+	}
+
+	assert.Equal(suite.T(), "2022-01-01T00:00:00Z", suite.Requests[0].URL.Query().Get("since"))
+	assert.Equal(suite.T(), "2022-01-08T00:00:00Z", suite.Requests[0].URL.Query().Get("until"))
+	assert.Equal(suite.T(), "1", suite.Requests[0].URL.Query().Get("per_page"))
+}
+
+func (suite *ProjectRepoServiceTestSuite) TestProjectRepoService_CountCommits_Cached() {
+	sut := suite.newService()
+	repo := &models.ProjectRepo{ProjectKey: "wakapi", Url: "https://github.com/muety/wakapi"}
+	from, to := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	sut.CountCommits(repo, from, to)
+	sut.CountCommits(repo, from, to.Add(time.Minute)) // same cache slot
+
+	assert.Len(suite.T(), suite.Requests, 1)
+}
+
+func (suite *ProjectRepoServiceTestSuite) TestProjectRepoService_CountCommits_Error() {
+	sut := suite.newService()
+
+	_, err := sut.CountCommits(&models.ProjectRepo{ProjectKey: "wakapi", Url: "https://github.com/muety/private"}, time.Now().Add(-time.Hour), time.Now())
+	assert.Error(suite.T(), err)
+}
+
+func (suite *ProjectRepoServiceTestSuite) newService() *ProjectRepoService {
+	target, _ := url.Parse(suite.Server.URL)
+	sut := NewProjectRepoService(nil)
+	sut.httpClient = &http.Client{Transport: &redirectTransport{target: target}}
+	return sut
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	// an editor plugin sends a heartbeat every two minutes per file at most, except for writes
+	quarantineMaxPerMinute = 600
+	// heartbeats of different entities may share a timestamp, e.g. when saving all files at once, but not by the dozens
+	quarantineMaxIdentical = 50
+	// nobody actively codes on more than a handful of machines at the very same time
+	quarantineMaxMachines = 5
+	// heartbeats are purged in chunks, as databases limit the number of ids per query
+	quarantinePurgePageSize = 500
+)
+
+// QuarantineService detects suspicious patterns among incoming heartbeats, e.g. sent by misbehaving or malicious clients, and flags them for admins to review.
+// Heartbeats are inspected per user and minute (of their timestamps), so that both floods sent at once and ones spread across many requests are caught.
+// Flagged heartbeats are kept, but their users are excluded from the leaderboard, until admins either accept or purge them.
+type QuarantineService struct {
+	config              *config.Config
+	eventBus            *hub.Hub
+	repository          repositories.IQuarantineRepository
+	heartbeatRepository repositories.IHeartbeatRepository
+	userService         IUserService
+	lock                sync.Mutex
+	windows             *cache.Cache // user id and minute -> heartbeats seen within that minute
+}
+
+type quarantineWindow struct {
+	count      int
+	timestamps map[int64]int
+	machines   map[string]bool
+	flagged    map[string]bool
+}
+
+func NewQuarantineService(quarantineRepository repositories.IQuarantineRepository, heartbeatRepository repositories.IHeartbeatRepository, userService IUserService) *QuarantineService {
+	srv := &QuarantineService{
+		config:              config.Get(),
+		eventBus:            config.EventBus(),
+		repository:          quarantineRepository,
+		heartbeatRepository: heartbeatRepository,
+		userService:         userService,
+		windows:             cache.New(1*time.Hour, 10*time.Minute),
+	}
+
+	sub1 := srv.eventBus.Subscribe(0, config.EventHeartbeatCreate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.Inspect(m.Fields[config.FieldPayload].(*models.Heartbeat))
+		}
+	}(&sub1)
+
+	return srv
+}
+
+// Inspect adds the given, freshly stored heartbeat to its user's statistics of the heartbeat's minute and quarantines that minute, if it turns out suspicious
+func (srv *QuarantineService) Inspect(heartbeat *models.Heartbeat) {
+	if !srv.config.App.QuarantineAnomalies {
+		return
+	}
+
+	minute := heartbeat.Time.T().Truncate(time.Minute)
+	reasons, count := srv.record(heartbeat, minute)
+
+	for _, reason := range reasons {
+		if err := srv.quarantine(heartbeat.UserID, reason, minute, count); err != nil {
+			config.Log().Error("failed to quarantine heartbeats of user '%s' - %v", heartbeat.UserID, err)
+		}
+	}
+}
+
+func (srv *QuarantineService) GetAll() ([]*models.Quarantine, error) {
+	return srv.repository.GetAll()
+}
+
+// Accept resolves the given quarantine, keeping its heartbeats
+func (srv *QuarantineService) Accept(id uint) (*models.Quarantine, error) {
+	quarantine, err := srv.repository.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := srv.repository.Delete(id); err != nil {
+		return nil, err
+	}
+	logbuch.Info("accepted quarantined heartbeats of user '%s' (%s)", quarantine.UserID, quarantine.Reason)
+	return quarantine, srv.release(quarantine.UserID)
+}
+
+// Purge resolves the given quarantine by deleting all of its user's heartbeats within its range and returns the number of deleted heartbeats.
+// Unlike deleting heartbeats via the api, purging can not be undone.
+func (srv *QuarantineService) Purge(id uint) (int, error) {
+	quarantine, err := srv.repository.GetById(id)
+	if err != nil {
+		return 0, err
+	}
+	user, err := srv.userService.GetUserById(quarantine.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	selection := &models.HeartbeatSelection{From: quarantine.StartTime.T(), To: quarantine.EndTime.T()}
+
+	var deleted int
+	for {
+		// deleted heartbeats disappear from the selection, so it is always the first page to be fetched
+		heartbeats, err := srv.heartbeatRepository.GetPageBySelection(user, selection, 0, quarantinePurgePageSize)
+		if err != nil {
+			return deleted, err
+		}
+		if len(heartbeats) == 0 {
+			break
+		}
+
+		ids := make([]uint64, len(heartbeats))
+		for i, hb := range heartbeats {
+			ids[i] = hb.ID
+		}
+		if err := srv.heartbeatRepository.DeleteByIds(user, ids); err != nil {
+			return deleted, err
+		}
+		deleted += len(ids)
+	}
+
+	if deleted > 0 {
+		srv.eventBus.Publish(hub.Message{
+			Name: config.EventHeartbeatDelete,
+			Fields: map[string]interface{}{
+				config.FieldPayload: &models.Interval{Start: selection.From, End: selection.To},
+				config.FieldUserId:  user.ID,
+			},
+		})
+	}
+
+	if err := srv.repository.Delete(id); err != nil {
+		return deleted, err
+	}
+	logbuch.Info("purged %d quarantined heartbeats of user '%s' (%s)", deleted, user.ID, quarantine.Reason)
+	return deleted, srv.release(user.ID)
+}
+
+// record adds the heartbeat to its minute's statistics and returns the reasons, for which the minute has just become suspicious, along with its number of heartbeats
+func (srv *QuarantineService) record(heartbeat *models.Heartbeat, minute time.Time) ([]string, int) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	key := fmt.Sprintf("%s__%d", heartbeat.UserID, minute.Unix())
+	var window *quarantineWindow
+	if w, ok := srv.windows.Get(key); ok {
+		window = w.(*quarantineWindow)
+	} else {
+		window = &quarantineWindow{
+			timestamps: make(map[int64]int),
+			machines:   make(map[string]bool),
+			flagged:    make(map[string]bool),
+		}
+		srv.windows.SetDefault(key, window)
+	}
+
+	window.count++
+	window.timestamps[heartbeat.Time.T().UnixNano()/int64(time.Millisecond)]++
+	window.machines[heartbeat.Machine] = true
+
+	suspicious := map[string]bool{
+		models.QuarantineReasonRate:       window.count > quarantineMaxPerMinute,
+		models.QuarantineReasonTimestamps: window.timestamps[heartbeat.Time.T().UnixNano()/int64(time.Millisecond)] > quarantineMaxIdentical,
+		models.QuarantineReasonMachines:   len(window.machines) > quarantineMaxMachines,
+	}
+
+	reasons := make([]string, 0)
+	for reason, ok := range suspicious {
+		if ok && !window.flagged[reason] {
+			window.flagged[reason] = true
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons, window.count
+}
+
+// quarantine flags the given minute of the user's heartbeats, extending the user's latest quarantine for the same reason, if it ends right before
+func (srv *QuarantineService) quarantine(userId, reason string, minute time.Time, count int) error {
+	latest, err := srv.repository.GetLatestByUserAndReason(userId, reason)
+	if err != nil {
+		return err
+	}
+
+	end := minute.Add(time.Minute)
+	if latest != nil && !latest.EndTime.T().Before(minute) && !latest.StartTime.T().After(end) {
+		if minute.Before(latest.StartTime.T()) {
+			latest.StartTime = models.CustomTime(minute)
+		}
+		if end.After(latest.EndTime.T()) {
+			latest.EndTime = models.CustomTime(end)
+		}
+		latest.NumHeartbeats += count
+	} else {
+		latest = &models.Quarantine{
+			UserID:        userId,
+			Reason:        reason,
+			StartTime:     models.CustomTime(minute),
+			EndTime:       models.CustomTime(end),
+			NumHeartbeats: count,
+		}
+	}
+	if _, err := srv.repository.Upsert(latest); err != nil {
+		return err
+	}
+
+	logbuch.Warn("quarantined heartbeats of user '%s' at %v (%s)", userId, minute, reason)
+
+	user, err := srv.userService.GetUserById(userId)
+	if err != nil {
+		return err
+	}
+	if !user.Quarantined {
+		user.Quarantined = true
+		_, err = srv.userService.Update(user)
+	}
+	return err
+}
+
+// release lifts the user's quarantine state, once none of the user's quarantines is pending anymore
+func (srv *QuarantineService) release(userId string) error {
+	count, err := srv.repository.CountByUser(userId)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	user, err := srv.userService.GetUserById(userId)
+	if err != nil {
+		return err
+	}
+	if user.Quarantined {
+		user.Quarantined = false
+		_, err = srv.userService.Update(user)
+	}
+	return err
+}
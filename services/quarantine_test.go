@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type QuarantineServiceTestSuite struct {
+	suite.Suite
+	TestUser             *models.User
+	QuarantineRepository *mocks.QuarantineRepositoryMock
+	UserService          *mocks.UserServiceMock
+}
+
+func (suite *QuarantineServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.App.QuarantineAnomalies = true
+	config.Set(cfg)
+}
+
+func (suite *QuarantineServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUser = &models.User{ID: TestUserId}
+	suite.QuarantineRepository = new(mocks.QuarantineRepositoryMock)
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.UserService.On("GetUserById", TestUserId).Return(suite.TestUser, nil)
+	suite.UserService.On("Update", suite.TestUser).Return(suite.TestUser, nil)
+}
+
+func TestQuarantineServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(QuarantineServiceTestSuite))
+}
+
+func (suite *QuarantineServiceTestSuite) TestQuarantineService_Inspect_Rate() {
+	suite.QuarantineRepository.On("GetLatestByUserAndReason", TestUserId, models.QuarantineReasonRate).Return((*models.Quarantine)(nil), nil)
+	suite.QuarantineRepository.On("Upsert", mock.Anything).Return(&models.Quarantine{}, nil)
+
+	sut := NewQuarantineService(suite.QuarantineRepository, nil, suite.UserService)
+
+	minute := time.Now().Truncate(time.Minute)
+	for i := 0; i < quarantineMaxPerMinute; i++ {
+		sut.Inspect(&models.Heartbeat{UserID: TestUserId, Machine: "m1", Time: models.CustomTime(minute.Add(time.Duration(i) * 10 * time.Millisecond))})
+	}
+	suite.QuarantineRepository.AssertNotCalled(suite.T(), "Upsert", mock.Anything)
+	assert.False(suite.T(), suite.TestUser.Quarantined)
+
+	sut.Inspect(&models.Heartbeat{UserID: TestUserId, Machine: "m1", Time: models.CustomTime(minute.Add(59 * time.Second))})
+	sut.Inspect(&models.Heartbeat{UserID: TestUserId, Machine: "m1", Time: models.CustomTime(minute.Add(59 * time.Second))}) // flagged only once per minute
+
+	suite.QuarantineRepository.AssertNumberOfCalls(suite.T(), "Upsert", 1)
+	quarantine := suite.QuarantineRepository.Calls[1].Arguments.Get(0).(*models.Quarantine)
+	assert.Equal(suite.T(), models.QuarantineReasonRate, quarantine.Reason)
+	assert.Equal(suite.T(), minute, quarantine.StartTime.T())
+	assert.Equal(suite.T(), minute.Add(time.Minute), quarantine.EndTime.T())
+	assert.True(suite.T(), suite.TestUser.Quarantined)
+}
+
+func (suite *QuarantineServiceTestSuite) TestQuarantineService_Inspect_TimestampsAndMachines() {
+	suite.QuarantineRepository.On("GetLatestByUserAndReason", TestUserId, mock.Anything).Return((*models.Quarantine)(nil), nil)
+	suite.QuarantineRepository.On("Upsert", mock.Anything).Return(&models.Quarantine{}, nil)
+
+	sut := NewQuarantineService(suite.QuarantineRepository, nil, suite.UserService)
+
+	t0 := time.Now().Truncate(time.Minute).Add(30 * time.Second)
+	for i := 0; i <= quarantineMaxIdentical; i++ {
+		sut.Inspect(&models.Heartbeat{UserID: TestUserId, Machine: fmt.Sprintf("m%d", i%(quarantineMaxMachines+1)), Time: models.CustomTime(t0)})
+	}
+
+	suite.QuarantineRepository.AssertNumberOfCalls(suite.T(), "Upsert", 2)
+	suite.QuarantineRepository.AssertCalled(suite.T(), "GetLatestByUserAndReason", TestUserId, models.QuarantineReasonTimestamps)
+	suite.QuarantineRepository.AssertCalled(suite.T(), "GetLatestByUserAndReason", TestUserId, models.QuarantineReasonMachines)
+	suite.QuarantineRepository.AssertNotCalled(suite.T(), "GetLatestByUserAndReason", TestUserId, models.QuarantineReasonRate)
+}
+
+func (suite *QuarantineServiceTestSuite) TestQuarantineService_Inspect_Extend() {
+	minute := time.Now().Truncate(time.Minute)
+	latest := &models.Quarantine{
+		ID:            1,
+		UserID:        TestUserId,
+		Reason:        models.QuarantineReasonMachines,
+		StartTime:     models.CustomTime(minute.Add(-time.Minute)),
+		EndTime:       models.CustomTime(minute),
+		NumHeartbeats: 10,
+	}
+	suite.QuarantineRepository.On("GetLatestByUserAndReason", TestUserId, models.QuarantineReasonMachines).Return(latest, nil)
+	suite.QuarantineRepository.On("Upsert", latest).Return(latest, nil)
+
+	sut := NewQuarantineService(suite.QuarantineRepository, nil, suite.UserService)
+
+	for i := 0; i <= quarantineMaxMachines; i++ {
+		sut.Inspect(&models.Heartbeat{UserID: TestUserId, Machine: fmt.Sprintf("m%d", i), Time: models.CustomTime(minute.Add(time.Duration(i) * time.Second))})
+	}
+
+	suite.QuarantineRepository.AssertNumberOfCalls(suite.T(), "Upsert", 1)
+	assert.Equal(suite.T(), minute.Add(-time.Minute), latest.StartTime.T())
+	assert.Equal(suite.T(), minute.Add(time.Minute), latest.EndTime.T())
+	assert.Equal(suite.T(), 10+quarantineMaxMachines+1, latest.NumHeartbeats)
+}
+
+func (suite *QuarantineServiceTestSuite) TestQuarantineService_Accept() {
+	suite.TestUser.Quarantined = true
+	quarantine := &models.Quarantine{ID: 1, UserID: TestUserId, Reason: models.QuarantineReasonRate}
+	suite.QuarantineRepository.On("GetById", uint(1)).Return(quarantine, nil)
+	suite.QuarantineRepository.On("Delete", uint(1)).Return(nil)
+	suite.QuarantineRepository.On("CountByUser", TestUserId).Return(int64(0), nil)
+
+	sut := NewQuarantineService(suite.QuarantineRepository, nil, suite.UserService)
+
+	result, err := sut.Accept(1)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), quarantine, result)
+	assert.False(suite.T(), suite.TestUser.Quarantined)
+	suite.UserService.AssertCalled(suite.T(), "Update", suite.TestUser)
+}
+
+func (suite *QuarantineServiceTestSuite) TestQuarantineService_Accept_Pending() {
+	suite.TestUser.Quarantined = true
+	suite.QuarantineRepository.On("GetById", uint(1)).Return(&models.Quarantine{ID: 1, UserID: TestUserId}, nil)
+	suite.QuarantineRepository.On("Delete", uint(1)).Return(nil)
+	suite.QuarantineRepository.On("CountByUser", TestUserId).Return(int64(1), nil)
+
+	sut := NewQuarantineService(suite.QuarantineRepository, nil, suite.UserService)
+
+	_, err := sut.Accept(1)
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), suite.TestUser.Quarantined)
+	suite.UserService.AssertNotCalled(suite.T(), "Update", mock.Anything)
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// QuotaService limits the number of heartbeats every user (i.e. api key) can send per hour and keeps track of violations since server start.
+// Quotas are counted in fixed windows, which reset at the start of every hour.
+type QuotaService struct {
+	config     *config.Config
+	lock       sync.Mutex
+	usage      map[string]*models.QuotaStatus
+	violations map[string]*models.QuotaViolation
+}
+
+func NewQuotaService() *QuotaService {
+	return &QuotaService{
+		config:     config.Get(),
+		usage:      map[string]*models.QuotaStatus{},
+		violations: map[string]*models.QuotaViolation{},
+	}
+}
+
+// GetLimit returns the number of heartbeats the user may send per hour (0 = unlimited), falling back to the server-wide default
+func (srv *QuotaService) GetLimit(user *models.User) int {
+	if user.HeartbeatsQuota < 0 {
+		return 0
+	}
+	if user.HeartbeatsQuota > 0 {
+		return user.HeartbeatsQuota
+	}
+	return srv.config.App.HeartbeatsQuotaPerHour
+}
+
+// Consume takes the given number of heartbeats from the user's quota, if they fit into it, or records a violation otherwise
+func (srv *QuotaService) Consume(user *models.User, n int) (*models.QuotaStatus, bool) {
+	return srv.consume(user, n, time.Now())
+}
+
+// GetStatus returns the user's consumption within the current hour without taking anything from their quota
+func (srv *QuotaService) GetStatus(user *models.User) *models.QuotaStatus {
+	return srv.getStatus(user, time.Now())
+}
+
+func (srv *QuotaService) consume(user *models.User, n int, now time.Time) (*models.QuotaStatus, bool) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	status := srv.status(user, now)
+	if !status.Allows(n) {
+		srv.recordViolation(user, status, n, now)
+		result := *status
+		return &result, false
+	}
+
+	status.Used += n
+	result := *status
+	return &result, true
+}
+
+func (srv *QuotaService) getStatus(user *models.User, now time.Time) *models.QuotaStatus {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	result := *srv.status(user, now)
+	return &result
+}
+
+func (srv *QuotaService) status(user *models.User, now time.Time) *models.QuotaStatus {
+	status, ok := srv.usage[user.ID]
+	if !ok || !now.Before(status.Reset) {
+		status = &models.QuotaStatus{Reset: now.Truncate(time.Hour).Add(time.Hour)}
+		srv.usage[user.ID] = status
+	}
+	status.Limit = srv.GetLimit(user)
+	return status
+}
+
+func (srv *QuotaService) recordViolation(user *models.User, status *models.QuotaStatus, n int, now time.Time) {
+	violation, ok := srv.violations[user.ID]
+	if !ok {
+		violation = &models.QuotaViolation{UserID: user.ID}
+		srv.violations[user.ID] = violation
+	}
+
+	// only count the first violation within every hour
+	if violation.LastViolation.Truncate(time.Hour) != now.Truncate(time.Hour) {
+		violation.Hours++
+		logbuch.Warn("user '%s' exceeded heartbeat quota of %d per hour", user.ID, status.Limit)
+	}
+	violation.Limit = status.Limit
+	violation.Rejected += n
+	violation.LastViolation = now
+}
+
+// GetViolations returns all users, who exceeded their quota since server start, most frequent violators first
+func (srv *QuotaService) GetViolations() []*models.QuotaViolation {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	violations := make([]*models.QuotaViolation, 0, len(srv.violations))
+	for _, v := range srv.violations {
+		copied := *v
+		violations = append(violations, &copied)
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Hours != violations[j].Hours {
+			return violations[i].Hours > violations[j].Hours
+		}
+		return violations[i].LastViolation.After(violations[j].LastViolation)
+	})
+	return violations
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type QuotaServiceTestSuite struct {
+	suite.Suite
+	TestUser *models.User
+	Now      time.Time
+}
+
+func (suite *QuotaServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.App.HeartbeatsQuotaPerHour = 100
+	config.Set(cfg)
+
+	suite.TestUser = &models.User{ID: "user1"}
+	suite.Now = time.Date(2022, 11, 10, 10, 30, 0, 0, time.UTC)
+}
+
+func TestQuotaServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(QuotaServiceTestSuite))
+}
+
+func (suite *QuotaServiceTestSuite) TestQuotaService_GetLimit() {
+	sut := NewQuotaService()
+
+	assert.Equal(suite.T(), 100, sut.GetLimit(&models.User{}))
+	assert.Equal(suite.T(), 10, sut.GetLimit(&models.User{HeartbeatsQuota: 10}))
+	assert.Equal(suite.T(), 0, sut.GetLimit(&models.User{HeartbeatsQuota: -1}))
+}
+
+func (suite *QuotaServiceTestSuite) TestQuotaService_Consume() {
+	sut := NewQuotaService()
+
+	status, ok := sut.consume(suite.TestUser, 60, suite.Now)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), 40, status.Remaining())
+	assert.Equal(suite.T(), time.Date(2022, 11, 10, 11, 0, 0, 0, time.UTC), status.Reset)
+
+	status, ok = sut.consume(suite.TestUser, 50, suite.Now.Add(1*time.Minute))
+	assert.False(suite.T(), ok)
+	assert.Equal(suite.T(), 40, status.Remaining()) // rejected heartbeats are not counted
+
+	status, ok = sut.consume(suite.TestUser, 40, suite.Now.Add(2*time.Minute))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), 0, status.Remaining())
+	assert.Equal(suite.T(), 100, sut.getStatus(suite.TestUser, suite.Now.Add(3*time.Minute)).Used)
+
+	// next hour
+	status, ok = sut.consume(suite.TestUser, 50, suite.Now.Add(30*time.Minute))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), 50, status.Remaining())
+}
+
+func (suite *QuotaServiceTestSuite) TestQuotaService_Consume_Unlimited() {
+	sut := NewQuotaService()
+
+	status, ok := sut.consume(&models.User{ID: "user2", HeartbeatsQuota: -1}, 1000, suite.Now)
+	assert.True(suite.T(), ok)
+	assert.True(suite.T(), status.IsUnlimited())
+	assert.Empty(suite.T(), sut.GetViolations())
+}
+
+func (suite *QuotaServiceTestSuite) TestQuotaService_GetViolations() {
+	sut := NewQuotaService()
+	user2 := &models.User{ID: "user2", HeartbeatsQuota: 10}
+
+	sut.consume(suite.TestUser, 101, suite.Now)
+	sut.consume(user2, 11, suite.Now)
+	sut.consume(user2, 20, suite.Now.Add(5*time.Minute))
+	sut.consume(user2, 11, suite.Now.Add(1*time.Hour))
+
+	violations := sut.GetViolations()
+	assert.Len(suite.T(), violations, 2)
+	assert.Equal(suite.T(), "user2", violations[0].UserID)
+	assert.Equal(suite.T(), 2, violations[0].Hours)
+	assert.Equal(suite.T(), 42, violations[0].Rejected)
+	assert.True(suite.T(), violations[0].IsRepeated())
+	assert.Equal(suite.T(), 1, violations[1].Hours)
+	assert.False(suite.T(), violations[1].IsRepeated())
+}
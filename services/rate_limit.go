@@ -0,0 +1,83 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// RateLimitService limits the number of api requests every client (i.e. api key, session or ip address) can send per minute.
+// Like heartbeat quotas, requests are counted in fixed windows, which reset at the start of every minute.
+type RateLimitService struct {
+	config *config.Config
+	lock   sync.Mutex
+	usage  map[string]*models.QuotaStatus
+	reset  time.Time // end of the current window, after which all counts are dropped
+}
+
+func NewRateLimitService() *RateLimitService {
+	return &RateLimitService{
+		config: config.Get(),
+		usage:  map[string]*models.QuotaStatus{},
+	}
+}
+
+func (srv *RateLimitService) IsEnabled() bool {
+	return srv.GetLimit() > 0
+}
+
+// GetLimit returns the number of requests every client may send per minute (0 = unlimited)
+func (srv *RateLimitService) GetLimit() int {
+	return srv.config.App.ApiRateLimitPerMin
+}
+
+// Consume counts another request of the given client, unless it exceeds their limit
+func (srv *RateLimitService) Consume(key string) (*models.QuotaStatus, bool) {
+	return srv.consume(key, time.Now())
+}
+
+// Get returns the client's current status without counting a request
+func (srv *RateLimitService) Get(key string) *models.QuotaStatus {
+	return srv.get(key, time.Now())
+}
+
+func (srv *RateLimitService) consume(key string, now time.Time) (*models.QuotaStatus, bool) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	status := srv.status(key, now)
+	if !status.Allows(1) {
+		result := *status
+		return &result, false
+	}
+
+	status.Used++
+	result := *status
+	return &result, true
+}
+
+func (srv *RateLimitService) get(key string, now time.Time) *models.QuotaStatus {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	result := *srv.status(key, now)
+	return &result
+}
+
+// status returns the client's entry within the current window, dropping all entries of previous windows at once, as they all expire together
+func (srv *RateLimitService) status(key string, now time.Time) *models.QuotaStatus {
+	if !now.Before(srv.reset) {
+		srv.usage = map[string]*models.QuotaStatus{}
+		srv.reset = now.Truncate(time.Minute).Add(time.Minute)
+	}
+
+	status, ok := srv.usage[key]
+	if !ok {
+		status = &models.QuotaStatus{Reset: srv.reset}
+		srv.usage[key] = status
+	}
+	status.Limit = srv.GetLimit()
+	return status
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitServiceTestSuite struct {
+	suite.Suite
+	Now time.Time
+}
+
+func (suite *RateLimitServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.App.ApiRateLimitPerMin = 2
+	config.Set(cfg)
+
+	suite.Now = time.Date(2022, 11, 10, 10, 30, 15, 0, time.UTC)
+}
+
+func TestRateLimitServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitServiceTestSuite))
+}
+
+func (suite *RateLimitServiceTestSuite) TestRateLimitService_Consume() {
+	sut := NewRateLimitService()
+	assert.True(suite.T(), sut.IsEnabled())
+
+	status, ok := sut.consume("key1", suite.Now)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), 1, status.Remaining())
+	assert.Equal(suite.T(), time.Date(2022, 11, 10, 10, 31, 0, 0, time.UTC), status.Reset)
+
+	_, ok = sut.consume("key1", suite.Now.Add(10*time.Second))
+	assert.True(suite.T(), ok)
+
+	status, ok = sut.consume("key1", suite.Now.Add(20*time.Second))
+	assert.False(suite.T(), ok)
+	assert.Equal(suite.T(), 2, status.Used) // rejected requests are not counted
+
+	// other clients are limited independently
+	_, ok = sut.consume("key2", suite.Now.Add(20*time.Second))
+	assert.True(suite.T(), ok)
+
+	// next minute
+	assert.Equal(suite.T(), 0, sut.get("key1", suite.Now.Add(45*time.Second)).Used)
+	status, ok = sut.consume("key1", suite.Now.Add(45*time.Second))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), 1, status.Remaining())
+	assert.Equal(suite.T(), time.Date(2022, 11, 10, 10, 32, 0, 0, time.UTC), status.Reset)
+}
+
+func (suite *RateLimitServiceTestSuite) TestRateLimitService_Disabled() {
+	config.Get().App.ApiRateLimitPerMin = 0
+	defer func() { config.Get().App.ApiRateLimitPerMin = 2 }()
+
+	sut := NewRateLimitService()
+	assert.False(suite.T(), sut.IsEnabled())
+
+	status, ok := sut.consume("key1", suite.Now)
+	assert.True(suite.T(), ok)
+	assert.True(suite.T(), status.IsUnlimited())
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+var ErrReassignmentInProgress = errors.New("a reassignment is already in progress")
+
+// ReassignmentService moves a user's heartbeats to another project or language in bulk, e.g. to clean up history after a project got renamed
+type ReassignmentService struct {
+	config              *config.Config
+	eventBus            *hub.Hub
+	heartbeatRepository repositories.IHeartbeatRepository
+	jobService          IJobService
+	undoService         IUndoService
+	jobLock             *sync.RWMutex
+	jobs                map[string]*models.ReassignmentJob
+}
+
+func NewReassignmentService(heartbeatRepository repositories.IHeartbeatRepository, jobService IJobService, undoService IUndoService) *ReassignmentService {
+	return &ReassignmentService{
+		config:              config.Get(),
+		eventBus:            config.EventBus(),
+		heartbeatRepository: heartbeatRepository,
+		jobService:          jobService,
+		undoService:         undoService,
+		jobLock:             &sync.RWMutex{},
+		jobs:                make(map[string]*models.ReassignmentJob),
+	}
+}
+
+// Count returns the number of the user's heartbeats a reassignment with the given selection would affect, to preview it before actually running it
+func (srv *ReassignmentService) Count(user *models.User, selection *models.HeartbeatSelection) (int64, error) {
+	return srv.heartbeatRepository.CountBySelection(user, selection)
+}
+
+// Reassign asynchronously applies the given reassignment to all of the user's matching heartbeats.
+// Progress can be polled using GetJob. Only one reassignment per user may run at a time. Unless disabled, it can be undone afterwards.
+func (srv *ReassignmentService) Reassign(user *models.User, reassignment *models.HeartbeatReassignment) (*models.ReassignmentJob, error) {
+	if err := reassignment.Validate(); err != nil {
+		return nil, err
+	}
+
+	srv.jobLock.Lock()
+	if job, ok := srv.jobs[user.ID]; ok && !job.IsDone() {
+		srv.jobLock.Unlock()
+		return nil, ErrReassignmentInProgress
+	}
+	job := models.NewReassignmentJob(user.ID)
+	srv.jobs[user.ID] = job
+	srv.jobLock.Unlock()
+
+	go func() {
+		err := srv.jobService.Track(models.JobReassignment, user.ID, func() error {
+			return srv.reassign(user, reassignment, job)
+		})
+		if err != nil {
+			config.Log().Error("failed to reassign heartbeats of user '%s' - %v", user.ID, err)
+		}
+
+		srv.jobLock.Lock()
+		defer srv.jobLock.Unlock()
+		now := time.Now()
+		job.FinishedAt = &now
+		if err != nil {
+			job.Error = err.Error()
+		}
+	}()
+
+	return srv.GetJob(user.ID), nil
+}
+
+// GetJob returns a snapshot of the user's latest reassignment or nil, if none was run since server start
+func (srv *ReassignmentService) GetJob(userId string) *models.ReassignmentJob {
+	srv.jobLock.RLock()
+	defer srv.jobLock.RUnlock()
+	if job, ok := srv.jobs[userId]; ok {
+		jobCopy := *job
+		return &jobCopy
+	}
+	return nil
+}
+
+func (srv *ReassignmentService) reassign(user *models.User, reassignment *models.HeartbeatReassignment, job *models.ReassignmentJob) error {
+	tombstone, err := srv.undoService.Begin(user, models.TombstoneReassignment)
+	if err != nil {
+		return err
+	}
+	if tombstone != nil {
+		srv.jobLock.Lock()
+		job.UndoId = &tombstone.ID
+		srv.jobLock.Unlock()
+	}
+
+	// one heartbeat per affected day is enough to have summaries recomputed
+	byDay := make(map[string]*models.Heartbeat)
+	defer func() {
+		for _, hb := range byDay {
+			srv.eventBus.Publish(hub.Message{
+				Name:   config.EventHeartbeatUpdate,
+				Fields: map[string]interface{}{config.FieldPayload: hb, config.FieldUserId: user.ID},
+			})
+		}
+	}()
+
+	var afterId uint64
+	for {
+		heartbeats, err := srv.heartbeatRepository.GetPageBySelection(user, &reassignment.HeartbeatSelection, afterId, heartbeatPageSize)
+		if err != nil {
+			return err
+		}
+		if len(heartbeats) == 0 {
+			break
+		}
+		afterId = heartbeats[len(heartbeats)-1].ID
+
+		var skipped int
+		originals := make([]*models.Heartbeat, 0, len(heartbeats))
+		for _, hb := range heartbeats {
+			original := *hb
+			if !reassignment.Apply(hb) {
+				continue
+			}
+			hb.User = user
+			// changing project or language changes the hash, which might then collide with an existing heartbeat's one
+			if _, err := srv.heartbeatRepository.Update(hb.Hashed()); err != nil {
+				skipped++
+				continue
+			}
+			originals = append(originals, &original)
+			byDay[hb.Time.T().Format(config.SimpleDateFormat)] = hb
+		}
+		if err := srv.undoService.Record(tombstone, originals); err != nil {
+			return err
+		}
+
+		srv.jobLock.Lock()
+		job.Updated += len(originals)
+		job.Skipped += skipped
+		srv.jobLock.Unlock()
+	}
+
+	if tombstone != nil && tombstone.NumHeartbeats == 0 {
+		if err := srv.undoService.Discard(tombstone); err != nil {
+			return err
+		}
+		srv.jobLock.Lock()
+		job.UndoId = nil
+		srv.jobLock.Unlock()
+	}
+
+	srv.jobLock.RLock()
+	defer srv.jobLock.RUnlock()
+	logbuch.Info("reassigned %d heartbeats of user '%s' (%d skipped)", job.Updated, user.ID, job.Skipped)
+	if job.Skipped > 0 {
+		return errors.New(fmt.Sprintf("%d heartbeats could not be reassigned", job.Skipped))
+	}
+	return nil
+}
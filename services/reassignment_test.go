@@ -0,0 +1,87 @@
+package services
+
+import (
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+	"time"
+)
+
+type ReassignmentServiceTestSuite struct {
+	suite.Suite
+	DB                  *gorm.DB
+	HeartbeatRepository *repositories.HeartbeatRepository
+	TestUser            *models.User
+}
+
+func (suite *ReassignmentServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{}) // undo disabled
+}
+
+func (suite *ReassignmentServiceTestSuite) BeforeTest(suiteName, testName string) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	// every connection to an in-memory database gets its own, empty one
+	sqlDb, _ := db.DB()
+	sqlDb.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&models.User{}, &models.Heartbeat{}, &models.HeartbeatCount{}, &models.SummaryInvalidation{}, &models.Duration{}, &models.DurationDay{}); err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	suite.DB = db
+	suite.HeartbeatRepository = repositories.NewHeartbeatRepository(db)
+	suite.TestUser = &models.User{ID: TestUserId}
+	suite.DB.Create(suite.TestUser)
+}
+
+func (suite *ReassignmentServiceTestSuite) AfterTest(suiteName, testName string) {
+	if sqlDb, err := suite.DB.DB(); err == nil {
+		sqlDb.Close()
+	}
+}
+
+func TestReassignmentServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ReassignmentServiceTestSuite))
+}
+
+func (suite *ReassignmentServiceTestSuite) TestReassignmentService_Reassign_Duplicates() {
+	jobService := NewJobService()
+	sut := NewReassignmentService(suite.HeartbeatRepository, jobService, NewUndoService(suite.HeartbeatRepository, nil, jobService))
+
+	t0 := time.Date(2022, 10, 14, 5, 0, 0, 0, time.UTC)
+	assert.Nil(suite.T(), suite.HeartbeatRepository.InsertBatch([]*models.Heartbeat{
+		(&models.Heartbeat{UserID: TestUserId, Entity: "main.go", Project: "wakapi", Time: models.CustomTime(t0)}).Hashed(),
+		(&models.Heartbeat{UserID: TestUserId, Entity: "main.go", Project: "wakapi-legacy", Time: models.CustomTime(t0)}).Hashed(),
+		(&models.Heartbeat{UserID: TestUserId, Entity: "main.go", Project: "wakapi-legacy", Time: models.CustomTime(t0.Add(time.Minute))}).Hashed(),
+	}))
+
+	reassignment := &models.HeartbeatReassignment{
+		HeartbeatSelection: models.HeartbeatSelection{Project: "wakapi-legacy"},
+		NewProject:         "wakapi",
+	}
+	job := models.NewReassignmentJob(TestUserId)
+
+	// the first legacy heartbeat would duplicate the existing one in the target project
+	err := sut.reassign(suite.TestUser, reassignment, job)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 1, job.Updated)
+	assert.Equal(suite.T(), 1, job.Skipped)
+
+	heartbeats, err := suite.HeartbeatRepository.GetAllWithin(t0, t0.Add(time.Hour), suite.TestUser)
+	assert.Nil(suite.T(), err)
+	projects := make(map[string]int)
+	for _, h := range heartbeats {
+		projects[h.Project]++
+	}
+	assert.Equal(suite.T(), map[string]int{"wakapi": 2, "wakapi-legacy": 1}, projects)
+}
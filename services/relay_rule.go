@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+// RelayRuleService decides, which of a user's heartbeats are relayed to upstream services, according to the user's relay rules
+type RelayRuleService struct {
+	config           *config.Config
+	cache            *cache.Cache
+	repository       repositories.IRelayRuleRepository
+	projectLabelSrvc IProjectLabelService
+}
+
+func NewRelayRuleService(relayRuleRepository repositories.IRelayRuleRepository, projectLabelService IProjectLabelService) *RelayRuleService {
+	return &RelayRuleService{
+		config:           config.Get(),
+		cache:            cache.New(1*time.Hour, 1*time.Hour),
+		repository:       relayRuleRepository,
+		projectLabelSrvc: projectLabelService,
+	}
+}
+
+func (srv *RelayRuleService) GetByUser(userId string) (models.RelayRules, error) {
+	if rules, found := srv.cache.Get(userId); found {
+		return rules.(models.RelayRules), nil
+	}
+
+	rules, err := srv.repository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.SetDefault(userId, models.RelayRules(rules))
+	return rules, nil
+}
+
+func (srv *RelayRuleService) Create(rule *models.RelayRule) (*models.RelayRule, error) {
+	result, err := srv.repository.Insert(rule)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Delete(rule.UserID)
+	return result, nil
+}
+
+func (srv *RelayRuleService) Delete(userId string, id uint) error {
+	rule, err := srv.repository.GetById(id)
+	if err != nil {
+		return err
+	}
+	if rule.UserID != userId {
+		return errors.New("relay rule does not belong to user")
+	}
+	srv.cache.Delete(userId)
+	return srv.repository.Delete(id)
+}
+
+// Accepts tells whether the heartbeat passes all of the user's relay rules, i.e. whether it may be relayed
+func (srv *RelayRuleService) Accepts(user *models.User, heartbeat *models.Heartbeat) bool {
+	rules, err := srv.GetByUser(user.ID)
+	if err != nil {
+		config.Log().Error("failed to fetch relay rules for user '%s' - %v", user.ID, err)
+		return false // better not relay than leak private projects
+	}
+	if len(rules) == 0 {
+		return true
+	}
+
+	var labels []string
+	labelsByProject, err := srv.projectLabelSrvc.GetByUserGrouped(user.ID)
+	if err != nil {
+		config.Log().Error("failed to fetch project labels for user '%s' - %v", user.ID, err)
+		return false
+	}
+	for _, l := range labelsByProject[heartbeat.Project] {
+		labels = append(labels, l.Label)
+	}
+
+	return rules.Accepts(heartbeat, user, labels)
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RelayRuleServiceTestSuite struct {
+	suite.Suite
+	TestUser            *models.User
+	RelayRuleRepository *mocks.RelayRuleRepositoryMock
+	ProjectLabelService *mocks.ProjectLabelServiceMock
+}
+
+func (suite *RelayRuleServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: "user1", Location: "UTC", Workdays: models.DefaultWorkdays}
+}
+
+func (suite *RelayRuleServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.RelayRuleRepository = new(mocks.RelayRuleRepositoryMock)
+	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+}
+
+func TestRelayRuleServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(RelayRuleServiceTestSuite))
+}
+
+func (suite *RelayRuleServiceTestSuite) TestRelayRuleService_Accepts() {
+	sut := NewRelayRuleService(suite.RelayRuleRepository, suite.ProjectLabelService)
+
+	suite.RelayRuleRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.RelayRule{
+		{ID: 1, UserID: suite.TestUser.ID, Type: models.RelayRuleLabel, Value: "private"},
+	}, nil)
+	suite.ProjectLabelService.On("GetByUserGrouped", suite.TestUser.ID).Return(map[string][]*models.ProjectLabel{
+		"dotfiles": {{ProjectKey: "dotfiles", Label: "private"}},
+		"wakapi":   {{ProjectKey: "wakapi", Label: "oss"}},
+	}, nil)
+
+	assert.True(suite.T(), sut.Accepts(suite.TestUser, &models.Heartbeat{Project: "wakapi"}))
+	assert.True(suite.T(), sut.Accepts(suite.TestUser, &models.Heartbeat{Project: "unlabeled"}))
+	assert.False(suite.T(), sut.Accepts(suite.TestUser, &models.Heartbeat{Project: "dotfiles"}))
+
+	// rules are cached
+	suite.RelayRuleRepository.AssertNumberOfCalls(suite.T(), "GetByUser", 1)
+}
+
+func (suite *RelayRuleServiceTestSuite) TestRelayRuleService_Accepts_NoRules() {
+	sut := NewRelayRuleService(suite.RelayRuleRepository, suite.ProjectLabelService)
+
+	suite.RelayRuleRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.RelayRule{}, nil)
+
+	assert.True(suite.T(), sut.Accepts(suite.TestUser, &models.Heartbeat{Project: "dotfiles"}))
+	suite.ProjectLabelService.AssertNotCalled(suite.T(), "GetByUserGrouped", suite.TestUser.ID)
+}
+
+func (suite *RelayRuleServiceTestSuite) TestRelayRuleService_Accepts_Error() {
+	sut := NewRelayRuleService(suite.RelayRuleRepository, suite.ProjectLabelService)
+
+	suite.RelayRuleRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.RelayRule{}, errors.New("db error"))
+
+	assert.False(suite.T(), sut.Accepts(suite.TestUser, &models.Heartbeat{Project: "wakapi"}))
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+// relay targets get disabled after this many failed requests within 24 hours
+const maxRelayFailuresPerDay = 100
+
+// RelayTargetService manages the upstream services, that a user's heartbeats are relayed to, and keeps track of every target's delivery metrics.
+// Besides the stored targets, the user's wakatime integration is treated as an implicit target, whose failures are still handled by resetting the user's api key.
+type RelayTargetService struct {
+	config              *config.Config
+	cache               *cache.Cache
+	failureCache        *cache.Cache
+	eventBus            *hub.Hub
+	repository          repositories.IRelayTargetRepository
+	notificationService INotificationService
+	lock                sync.RWMutex
+	stats               map[string]*models.RelayStats
+}
+
+func NewRelayTargetService(relayTargetRepository repositories.IRelayTargetRepository, notificationService INotificationService) *RelayTargetService {
+	return &RelayTargetService{
+		config:              config.Get(),
+		cache:               cache.New(1*time.Hour, 1*time.Hour),
+		failureCache:        cache.New(24*time.Hour, 1*time.Hour),
+		eventBus:            config.EventBus(),
+		repository:          relayTargetRepository,
+		notificationService: notificationService,
+		stats:               map[string]*models.RelayStats{},
+	}
+}
+
+// GetByUser returns the user's stored relay targets, excluding the implicit wakatime target
+func (srv *RelayTargetService) GetByUser(user *models.User) ([]*models.RelayTarget, error) {
+	if targets, found := srv.cache.Get(user.ID); found {
+		return targets.([]*models.RelayTarget), nil
+	}
+
+	targets, err := srv.repository.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.SetDefault(user.ID, targets)
+	return targets, nil
+}
+
+// GetActive returns all targets to relay the user's heartbeats to, including the wakatime integration, if configured
+func (srv *RelayTargetService) GetActive(user *models.User) ([]*models.RelayTarget, error) {
+	targets := make([]*models.RelayTarget, 0)
+	if user.WakatimeApiKey != "" {
+		targets = append(targets, models.NewWakatimeRelayTarget(user, config.WakatimeApiUrl))
+	}
+
+	stored, err := srv.GetByUser(user)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range stored {
+		if t.Enabled {
+			targets = append(targets, t)
+		}
+	}
+	return targets, nil
+}
+
+func (srv *RelayTargetService) Create(target *models.RelayTarget) (*models.RelayTarget, error) {
+	target.Enabled = true
+	result, err := srv.repository.Insert(target)
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.Delete(target.UserID)
+	return result, nil
+}
+
+// SetEnabled (re-)enables or disables one of the user's relay targets, whereby re-enabling also resets its failure count
+func (srv *RelayTargetService) SetEnabled(user *models.User, id uint, enabled bool) (*models.RelayTarget, error) {
+	target, err := srv.getOwned(user, id)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		srv.failureCache.Delete(relayStatsKey(user, target))
+	}
+	srv.cache.Delete(user.ID)
+	return srv.repository.UpdateEnabled(target, enabled)
+}
+
+func (srv *RelayTargetService) Delete(user *models.User, id uint) error {
+	target, err := srv.getOwned(user, id)
+	if err != nil {
+		return err
+	}
+
+	srv.lock.Lock()
+	delete(srv.stats, relayStatsKey(user, target))
+	srv.lock.Unlock()
+
+	srv.cache.Delete(user.ID)
+	return srv.repository.Delete(id)
+}
+
+// GetWithStats returns all of the user's targets, including disabled ones and the wakatime integration, along with their delivery metrics since the server was started
+func (srv *RelayTargetService) GetWithStats(user *models.User) ([]*models.RelayTarget, error) {
+	stored, err := srv.GetByUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*models.RelayTarget, 0, len(stored)+1)
+	if user.WakatimeApiKey != "" {
+		targets = append(targets, models.NewWakatimeRelayTarget(user, config.WakatimeApiUrl))
+	}
+	for _, t := range stored {
+		target := *t // cached targets must not be altered
+		targets = append(targets, &target)
+	}
+
+	srv.lock.RLock()
+	defer srv.lock.RUnlock()
+
+	for _, t := range targets {
+		t.Stats = &models.RelayStats{}
+		if stats, ok := srv.stats[relayStatsKey(user, t)]; ok {
+			statsCopy := *stats
+			t.Stats = &statsCopy
+		}
+	}
+	return targets, nil
+}
+
+// RecordDelivery updates the target's metrics by the outcome of a relayed request and disables the target once it failed too often within a day
+func (srv *RelayTargetService) RecordDelivery(user *models.User, target *models.RelayTarget, deliveryErr error) {
+	key := relayStatsKey(user, target)
+	now := time.Now()
+
+	srv.lock.Lock()
+	stats, ok := srv.stats[key]
+	if !ok {
+		stats = &models.RelayStats{}
+		srv.stats[key] = stats
+	}
+	if deliveryErr == nil {
+		stats.Delivered++
+		stats.ConsecutiveFailures = 0
+		stats.LastDelivery = &now
+	} else {
+		stats.Failed++
+		stats.ConsecutiveFailures++
+		stats.LastFailure = &now
+		stats.LastError = deliveryErr.Error()
+	}
+	srv.lock.Unlock()
+
+	if deliveryErr == nil {
+		return
+	}
+
+	logbuch.Warn("failed to relay request for user %s to '%s' - %v", user.ID, target.Name, deliveryErr)
+
+	// TODO: use leaky bucket instead of expiring cache?
+	if _, found := srv.failureCache.Get(key); !found {
+		srv.failureCache.SetDefault(key, 0)
+	}
+	n, _ := srv.failureCache.IncrementInt(key, 1)
+	if n == maxRelayFailuresPerDay {
+		srv.onTooManyFailures(user, target, n)
+	} else if n%10 == 0 {
+		logbuch.Warn("%d / %d failed heartbeat relaying attempts to '%s' for user %s within last 24 hours", n, maxRelayFailuresPerDay, target.Name, user.ID)
+	}
+}
+
+func (srv *RelayTargetService) onTooManyFailures(user *models.User, target *models.RelayTarget, n int) {
+	if target.IsImplicit() {
+		srv.eventBus.Publish(hub.Message{
+			Name:   config.EventWakatimeFailure,
+			Fields: map[string]interface{}{config.FieldUser: user, config.FieldPayload: n},
+		})
+		return
+	}
+
+	logbuch.Warn("disabling relay target '%s' for user %s, because of too many failures (%d)", target.Name, user.ID, n)
+
+	if _, err := srv.SetEnabled(user, target.ID, false); err != nil {
+		config.Log().Error("failed to disable relay target %d of user '%s' - %v", target.ID, user.ID, err)
+	}
+
+	srv.notificationService.Notify(user, &models.Notification{
+		Event: models.NotificationEventWakatimeFailure,
+		Title: "Relay connection failure",
+		Text:  fmt.Sprintf("Wakapi failed to forward heartbeats to %s (%s) %d times in a row, so the relay target was disabled. Please check its API key and re-enable it in your settings.", target.Name, target.ApiUrl, n),
+		Link:  srv.config.Server.GetPublicUrl() + "/settings#integrations",
+	})
+}
+
+func (srv *RelayTargetService) getOwned(user *models.User, id uint) (*models.RelayTarget, error) {
+	target, err := srv.repository.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if target.UserID != user.ID {
+		return nil, errors.New("relay target does not belong to user")
+	}
+	return target, nil
+}
+
+func relayStatsKey(user *models.User, target *models.RelayTarget) string {
+	return fmt.Sprintf("%s/%d", user.ID, target.ID)
+}
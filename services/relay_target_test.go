@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type RelayTargetServiceTestSuite struct {
+	suite.Suite
+	TestUser              *models.User
+	RelayTargetRepository *mocks.RelayTargetRepositoryMock
+	NotificationService   *mocks.NotificationServiceMock
+}
+
+func (suite *RelayTargetServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: "user1", WakatimeApiKey: "wakatime-key"}
+}
+
+func (suite *RelayTargetServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.RelayTargetRepository = new(mocks.RelayTargetRepositoryMock)
+	suite.NotificationService = new(mocks.NotificationServiceMock)
+}
+
+func TestRelayTargetServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(RelayTargetServiceTestSuite))
+}
+
+func (suite *RelayTargetServiceTestSuite) TestRelayTargetService_GetActive() {
+	sut := NewRelayTargetService(suite.RelayTargetRepository, suite.NotificationService)
+
+	suite.RelayTargetRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.RelayTarget{
+		{ID: 1, UserID: suite.TestUser.ID, Name: "work", ApiUrl: "https://wakapi.example.org/api/compat/wakatime/v1", ApiKey: "key1", Enabled: true},
+		{ID: 2, UserID: suite.TestUser.ID, Name: "old", ApiUrl: "https://old.example.org/api/compat/wakatime/v1", ApiKey: "key2", Enabled: false},
+	}, nil)
+
+	result, err := sut.GetActive(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.True(suite.T(), result[0].IsImplicit())
+	assert.Equal(suite.T(), config.WakatimeApiUrl, result[0].ApiUrl)
+	assert.Equal(suite.T(), "wakatime-key", result[0].ApiKey)
+	assert.Equal(suite.T(), uint(1), result[1].ID)
+
+	// stored targets are cached
+	sut.GetActive(suite.TestUser)
+	suite.RelayTargetRepository.AssertNumberOfCalls(suite.T(), "GetByUser", 1)
+}
+
+func (suite *RelayTargetServiceTestSuite) TestRelayTargetService_RecordDelivery() {
+	sut := NewRelayTargetService(suite.RelayTargetRepository, suite.NotificationService)
+
+	target := &models.RelayTarget{ID: 1, UserID: suite.TestUser.ID, Name: "work", ApiUrl: "https://wakapi.example.org/api/compat/wakatime/v1", ApiKey: "key1", Enabled: true}
+	suite.RelayTargetRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.RelayTarget{target}, nil)
+
+	sut.RecordDelivery(suite.TestUser, target, nil)
+	sut.RecordDelivery(suite.TestUser, target, errors.New("got status 500"))
+	sut.RecordDelivery(suite.TestUser, target, errors.New("got status 502"))
+
+	result, err := sut.GetWithStats(suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), 0, result[0].Stats.Delivered) // wakatime is tracked separately
+	assert.Equal(suite.T(), 1, result[1].Stats.Delivered)
+	assert.Equal(suite.T(), 2, result[1].Stats.Failed)
+	assert.Equal(suite.T(), 2, result[1].Stats.ConsecutiveFailures)
+	assert.Equal(suite.T(), "got status 502", result[1].Stats.LastError)
+	assert.NotNil(suite.T(), result[1].Stats.LastDelivery)
+	assert.Nil(suite.T(), target.Stats) // cached target is left untouched
+}
+
+func (suite *RelayTargetServiceTestSuite) TestRelayTargetService_RecordDelivery_DisablesAfterTooManyFailures() {
+	sut := NewRelayTargetService(suite.RelayTargetRepository, suite.NotificationService)
+
+	target := &models.RelayTarget{ID: 1, UserID: suite.TestUser.ID, Name: "work", ApiUrl: "https://wakapi.example.org/api/compat/wakatime/v1", ApiKey: "key1", Enabled: true}
+	suite.RelayTargetRepository.On("GetById", uint(1)).Return(target, nil)
+	suite.RelayTargetRepository.On("UpdateEnabled", target, false).Return(target, nil)
+	suite.NotificationService.On("Notify", suite.TestUser, mock.Anything).Return(nil)
+
+	for i := 0; i < maxRelayFailuresPerDay-1; i++ {
+		sut.RecordDelivery(suite.TestUser, target, errors.New("got status 401"))
+	}
+	suite.RelayTargetRepository.AssertNotCalled(suite.T(), "UpdateEnabled", target, false)
+
+	sut.RecordDelivery(suite.TestUser, target, errors.New("got status 401"))
+	suite.RelayTargetRepository.AssertCalled(suite.T(), "UpdateEnabled", target, false)
+	suite.NotificationService.AssertNumberOfCalls(suite.T(), "Notify", 1)
+	assert.Equal(suite.T(), models.NotificationEventWakatimeFailure, suite.NotificationService.Calls[0].Arguments.Get(1).(*models.Notification).Event)
+}
+
+func (suite *RelayTargetServiceTestSuite) TestRelayTargetService_Delete_ForeignTarget() {
+	sut := NewRelayTargetService(suite.RelayTargetRepository, suite.NotificationService)
+
+	suite.RelayTargetRepository.On("GetById", uint(1)).Return(&models.RelayTarget{ID: 1, UserID: "user2"}, nil)
+
+	err := sut.Delete(suite.TestUser, 1)
+
+	assert.Error(suite.T(), err)
+	suite.RelayTargetRepository.AssertNotCalled(suite.T(), "Delete", uint(1))
+}
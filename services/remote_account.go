@@ -0,0 +1,133 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	wakatime "github.com/muety/wakapi/models/compat/wakatime/v1"
+	"github.com/muety/wakapi/repositories"
+	"github.com/patrickmn/go-cache"
+)
+
+// RemoteAccountService pulls summaries from the user's accounts at other Wakapi instances or at WakaTime and combines them with local ones.
+// Remote summaries are cached for a couple of minutes to not hit the remote apis on every request.
+type RemoteAccountService struct {
+	config     *config.Config
+	cache      *cache.Cache
+	repository repositories.IRemoteAccountRepository
+	httpClient *http.Client
+}
+
+func NewRemoteAccountService(remoteAccountRepository repositories.IRemoteAccountRepository) *RemoteAccountService {
+	return &RemoteAccountService{
+		config:     config.Get(),
+		cache:      cache.New(15*time.Minute, 15*time.Minute),
+		repository: remoteAccountRepository,
+		httpClient: config.NewHttpClient(config.ProxyScopeImports, 10*time.Second),
+	}
+}
+
+func (srv *RemoteAccountService) GetByUser(user *models.User) ([]*models.RemoteAccount, error) {
+	return srv.repository.GetByUser(user.ID)
+}
+
+func (srv *RemoteAccountService) Create(account *models.RemoteAccount) (*models.RemoteAccount, error) {
+	account.Enabled = true
+	return srv.repository.Insert(account)
+}
+
+func (srv *RemoteAccountService) SetEnabled(user *models.User, id uint, enabled bool) (*models.RemoteAccount, error) {
+	account, err := srv.getOwned(user, id)
+	if err != nil {
+		return nil, err
+	}
+	return srv.repository.UpdateEnabled(account, enabled)
+}
+
+func (srv *RemoteAccountService) Delete(user *models.User, id uint) error {
+	if _, err := srv.getOwned(user, id); err != nil {
+		return err
+	}
+	return srv.repository.Delete(id)
+}
+
+// Combine adds the summaries of all of the user's enabled remote accounts for the same range to the given local summary.
+// Accounts, which can't be reached, are skipped, so that the local summary is always served.
+func (srv *RemoteAccountService) Combine(user *models.User, summary *models.Summary) (*models.Summary, error) {
+	accounts, err := srv.GetByUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, account := range accounts {
+		if !account.Enabled {
+			continue
+		}
+		remote, err := srv.FetchSummary(account, summary.FromTime.T(), summary.ToTime.T(), user.TZ())
+		if err != nil {
+			config.Log().Warn("failed to fetch summary from remote account %d of user '%s' - %v", account.ID, user.ID, err)
+			continue
+		}
+		summary = summary.Combined(remote)
+	}
+
+	return summary, nil
+}
+
+// FetchSummary retrieves the account's summaries for all days within the given range from the remote api and adds them up
+func (srv *RemoteAccountService) FetchSummary(account *models.RemoteAccount, from, to time.Time, tz *time.Location) (*models.Summary, error) {
+	start := from.In(tz).Format(config.SimpleDateFormat)
+	end := to.In(tz).Add(-1 * time.Second).Format(config.SimpleDateFormat) // remote end date is inclusive
+
+	cacheKey := fmt.Sprintf("%d--%s--%s", account.ID, start, end)
+	if cached, ok := srv.cache.Get(cacheKey); ok {
+		return cached.(*models.Summary), nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, account.ApiUrl+config.WakatimeApiSummariesUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := req.URL.Query()
+	q.Add("start", start)
+	q.Add("end", end)
+	q.Add("timezone", tz.String())
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("Authorization", fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(account.ApiKey))))
+
+	res, err := srv.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return nil, errors.New(fmt.Sprintf("got status %d from remote api", res.StatusCode))
+	}
+
+	var summariesData wakatime.SummariesViewModel
+	if err := json.NewDecoder(res.Body).Decode(&summariesData); err != nil {
+		return nil, err
+	}
+
+	summary := summariesData.ToSummary()
+	srv.cache.SetDefault(cacheKey, summary)
+	return summary, nil
+}
+
+func (srv *RemoteAccountService) getOwned(user *models.User, id uint) (*models.RemoteAccount, error) {
+	account, err := srv.repository.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if account.UserID != user.ID {
+		return nil, errors.New("remote account not found")
+	}
+	return account, nil
+}
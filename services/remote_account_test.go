@@ -0,0 +1,91 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RemoteAccountServiceTestSuite struct {
+	suite.Suite
+	TestUser                *models.User
+	Server                  *httptest.Server
+	Requests                []*http.Request
+	RemoteAccountRepository *mocks.RemoteAccountRepositoryMock
+}
+
+func (suite *RemoteAccountServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+
+	suite.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Requests = append(suite.Requests, r)
+
+		if r.URL.Path != "/api/v1/users/current/summaries" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Basic c2VjcmV0" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data": [
+			{"projects": [{"name": "work", "total_seconds": 3600}], "languages": [{"name": "Java", "total_seconds": 3600}]},
+			{"projects": [{"name": "work", "total_seconds": 1800}], "languages": [{"name": "Java", "total_seconds": 1800}]}
+		]}`))
+	}))
+}
+
+func (suite *RemoteAccountServiceTestSuite) TearDownSuite() {
+	suite.Server.Close()
+}
+
+func (suite *RemoteAccountServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUser = &models.User{ID: "johndoe", Location: "Europe/Berlin"}
+	suite.Requests = nil
+	suite.RemoteAccountRepository = new(mocks.RemoteAccountRepositoryMock)
+	suite.RemoteAccountRepository.On("GetByUser", suite.TestUser.ID).Return([]*models.RemoteAccount{
+		{ID: 1, UserID: suite.TestUser.ID, Name: "work", ApiUrl: suite.Server.URL + "/api/v1", ApiKey: "secret", Enabled: true},
+		{ID: 2, UserID: suite.TestUser.ID, Name: "broken", ApiUrl: suite.Server.URL + "/api/v1", ApiKey: "wrong", Enabled: true},
+		{ID: 3, UserID: suite.TestUser.ID, Name: "disabled", ApiUrl: suite.Server.URL + "/api/v1", ApiKey: "secret", Enabled: false},
+	}, nil)
+}
+
+func TestRemoteAccountServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(RemoteAccountServiceTestSuite))
+}
+
+func (suite *RemoteAccountServiceTestSuite) TestRemoteAccountService_Combine() {
+	sut := NewRemoteAccountService(suite.RemoteAccountRepository)
+
+	from := time.Date(2023, 5, 1, 0, 0, 0, 0, suite.TestUser.TZ())
+	to := from.AddDate(0, 0, 2)
+	local := &models.Summary{
+		UserID:   suite.TestUser.ID,
+		FromTime: models.CustomTime(from),
+		ToTime:   models.CustomTime(to),
+		Projects: []*models.SummaryItem{{Type: models.SummaryProject, Key: "wakapi", Total: 600}},
+	}
+
+	result, err := sut.Combine(suite.TestUser, local)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), suite.Requests, 2) // disabled account is skipped
+	assert.Equal(suite.T(), "2023-05-01", suite.Requests[0].URL.Query().Get("start"))
+	assert.Equal(suite.T(), "2023-05-02", suite.Requests[0].URL.Query().Get("end"))
+	assert.Len(suite.T(), result.Projects, 2)
+	assert.Equal(suite.T(), "work", result.Projects[0].Key)
+	assert.Equal(suite.T(), 90*time.Minute, result.Projects[0].TotalFixed())
+	assert.Equal(suite.T(), 100*time.Minute, result.TotalTime())
+
+	// served from cache
+	_, err = sut.Combine(suite.TestUser, local)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), suite.Requests, 3)
+}
@@ -1,11 +1,15 @@
 package services
 
 import (
+	"bytes"
+	"fmt"
 	"github.com/emvi/logbuch"
 	"github.com/go-co-op/gocron"
 	"github.com/leandro-lugaresi/hub"
 	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
 	"math/rand"
 	"sync"
 	"time"
@@ -17,33 +21,51 @@ var reportLock = sync.Mutex{}
 // to avoid all mails being sent at once, but distributed over 2*offsetIntervalMin minutes
 const offsetIntervalMin = 15
 
+// validity of download links to report pdfs, which are kept in storage for the same period of time
+const reportPdfLinkExpiry = 7 * 24 * time.Hour
+
 type ReportService struct {
-	config         *config.Config
-	eventBus       *hub.Hub
-	summaryService ISummaryService
-	userService    IUserService
-	mailService    IMailService
-	scheduler      *gocron.Scheduler
-	rand           *rand.Rand
+	config              *config.Config
+	eventBus            *hub.Hub
+	summaryService      ISummaryService
+	userService         IUserService
+	mailService         IMailService
+	notificationService INotificationService
+	storageService      IStorageService
+	jobService          IJobService
+	overtimeService     IOvertimeService
+	archiveRepository   repositories.IArchivedReportRepository
+	scheduler           *gocron.Scheduler
+	rand                *rand.Rand
 }
 
-func NewReportService(summaryService ISummaryService, userService IUserService, mailService IMailService) *ReportService {
+func NewReportService(summaryService ISummaryService, userService IUserService, mailService IMailService, notificationService INotificationService, storageService IStorageService, jobService IJobService, overtimeService IOvertimeService, archivedReportRepository repositories.IArchivedReportRepository) *ReportService {
 	srv := &ReportService{
-		config:         config.Get(),
-		eventBus:       config.EventBus(),
-		summaryService: summaryService,
-		userService:    userService,
-		mailService:    mailService,
-		scheduler:      gocron.NewScheduler(time.Local),
-		rand:           rand.New(rand.NewSource(time.Now().Unix())),
+		config:              config.Get(),
+		eventBus:            config.EventBus(),
+		summaryService:      summaryService,
+		userService:         userService,
+		mailService:         mailService,
+		notificationService: notificationService,
+		storageService:      storageService,
+		jobService:          jobService,
+		overtimeService:     overtimeService,
+		archiveRepository:   archivedReportRepository,
+		scheduler:           gocron.NewScheduler(time.Local),
+		rand:                rand.New(rand.NewSource(time.Now().Unix())),
 	}
 
 	srv.scheduler.StartAsync()
 
-	sub := srv.eventBus.Subscribe(0, config.EventUserUpdate)
+	sub := srv.eventBus.Subscribe(0, config.EventUserUpdate, config.EventUserDelete)
 	go func(sub *hub.Subscription) {
 		for m := range sub.Receiver {
-			srv.SyncSchedule(m.Fields[config.FieldPayload].(*models.User))
+			user := m.Fields[config.FieldPayload].(*models.User)
+			if m.Name == config.EventUserDelete {
+				// unschedule without modifying the original user object
+				user = &models.User{ID: user.ID, ReportsWeekly: false}
+			}
+			srv.SyncSchedule(user)
 		}
 	}(&sub)
 
@@ -73,6 +95,7 @@ func (srv *ReportService) SyncSchedule(u *models.User) bool {
 	// unschedule
 	if !u.ReportsWeekly {
 		_ = srv.scheduler.RemoveByTag(u.ID)
+		_ = srv.scheduler.RemoveByTag(monthlyReportTag(u.ID))
 		logbuch.Info("disabled scheduled reports for user %s", u.ID)
 		return false
 	}
@@ -95,15 +118,31 @@ func (srv *ReportService) SyncSchedule(u *models.User) bool {
 		}
 	}
 
+	// monthly reports are only archived, not sent
+	if job := srv.getJobByTag(monthlyReportTag(u.ID)); job == nil {
+		t, _ := time.ParseInLocation("15:04", srv.config.App.GetWeeklyReportTime(), u.TZ())
+		t = t.Add(time.Duration(srv.rand.Intn(offsetIntervalMin*60)) * time.Second)
+		if _, err := srv.scheduler.
+			SingletonMode().
+			Every(1).
+			Month(1).
+			At(t).
+			Tag(monthlyReportTag(u.ID)).
+			Do(srv.RunMonthly, u); err != nil {
+			config.Log().Error("failed to schedule monthly report job for user '%s' - %v", u.ID, err)
+		}
+	}
+
 	return u.ReportsWeekly
 }
 
 func (srv *ReportService) Run(user *models.User, duration time.Duration) error {
-	if user.Email == "" {
-		logbuch.Warn("not generating report for '%s' as no e-mail address is set")
-		return nil
-	}
+	return srv.jobService.Track(models.JobReport, user.ID, func() error {
+		return srv.run(user, duration)
+	})
+}
 
+func (srv *ReportService) run(user *models.User, duration time.Duration) error {
 	if !srv.SyncSchedule(user) {
 		logbuch.Info("reports for user '%s' were turned off in the meanwhile since last report job ran")
 		return nil
@@ -125,13 +164,161 @@ func (srv *ReportService) Run(user *models.User, duration time.Duration) error {
 		Summary: summary,
 	}
 
-	if err := srv.mailService.SendReport(user, report); err != nil {
-		config.Log().Error("failed to send report for '%s' - %v", user.ID, err)
+	// overtime is computed for whole days, including today
+	if user.HasWorkdayTarget() {
+		if overtime, err := srv.overtimeService.GetOvertime(user, utils.StartOfDay(end).AddDate(0, 0, -6), end); err != nil {
+			config.Log().Error("failed to compute overtime for report of '%s' - %v", user.ID, err)
+		} else {
+			report.Overtime = overtime
+		}
+	}
+
+	if _, err := srv.archiveRepository.Insert(models.NewArchivedReport(report, models.ReportIntervalWeek)); err != nil {
+		config.Log().Error("failed to archive report for '%s' - %v", user.ID, err)
+	}
+
+	// a missing pdf should not prevent the report mail from being sent
+	if url, err := srv.storePdf(report); err != nil {
+		config.Log().Error("failed to store report pdf for '%s' - %v", user.ID, err)
+	} else {
+		report.PdfUrl = url
+	}
+
+	if srv.notificationService.IsEnabled(user, models.NotificationEventReport, models.NotificationChannelEmail) {
+		if err := srv.mailService.SendReport(user, report); err != nil {
+			config.Log().Error("failed to send report for '%s' - %v", user.ID, err)
+			return err
+		}
+		logbuch.Info("sent report to user '%s'", user.ID)
+	}
+
+	return srv.notificationService.Notify(user, newReportNotification(report, srv.config.Server.GetPublicUrl()))
+}
+
+// RunMonthly generates the report for the past calendar month and adds it to the user's archive
+func (srv *ReportService) RunMonthly(user *models.User) error {
+	return srv.jobService.Track(models.JobReport, user.ID, func() error {
+		end := utils.StartOfMonth(time.Now().In(user.TZ()))
+		start := end.AddDate(0, -1, 0)
+		_, err := srv.Archive(user, start, end, models.ReportIntervalMonth)
 		return err
+	})
+}
+
+// Archive generates a report for the given period without sending it and adds it to the user's archive
+func (srv *ReportService) Archive(user *models.User, start, end time.Time, interval string) (*models.ArchivedReport, error) {
+	summary, err := srv.summaryService.Aliased(start, end, user, srv.summaryService.Retrieve, nil, false)
+	if err != nil {
+		config.Log().Error("failed to generate %sly report for '%s' - %v", interval, user.ID, err)
+		return nil, err
 	}
 
-	logbuch.Info("sent report to user '%s'", user.ID)
-	return nil
+	report := &models.Report{
+		From:    start,
+		To:      end,
+		User:    user,
+		Summary: summary,
+	}
+
+	if user.HasWorkdayTarget() {
+		if overtime, err := srv.overtimeService.GetOvertime(user, start, end.Add(-time.Second)); err != nil {
+			config.Log().Error("failed to compute overtime for %sly report of '%s' - %v", interval, user.ID, err)
+		} else {
+			report.Overtime = overtime
+		}
+	}
+
+	return srv.archiveRepository.Insert(models.NewArchivedReport(report, interval))
+}
+
+func (srv *ReportService) GetArchive(user *models.User) ([]*models.ArchivedReport, error) {
+	return srv.archiveRepository.GetByUser(user.ID)
+}
+
+// GetArchived returns a single report from the user's archive or nil, if it does not exist or belongs to someone else
+func (srv *ReportService) GetArchived(user *models.User, id uint) (*models.ArchivedReport, error) {
+	report, err := srv.archiveRepository.GetById(id)
+	if err != nil || report.UserID != user.ID {
+		return nil, err
+	}
+	return report, nil
+}
+
+// RenderPdf renders the given report as printable document, just like the one attached to report mails
+func (srv *ReportService) RenderPdf(report *models.Report) []byte {
+	return renderReportPdf(report)
+}
+
+func newReportNotification(report *models.Report, publicUrl string) *models.Notification {
+	text := fmt.Sprintf("Total: %s", utils.FmtWakatimeDuration(report.Summary.TotalTime()))
+	for i, p := range report.Summary.Projects {
+		if i == 3 {
+			break
+		}
+		text += fmt.Sprintf("\n%s: %s", p.Key, utils.FmtWakatimeDuration(p.TotalFixed()))
+	}
+
+	link := publicUrl + "/summary?interval=week"
+	if report.PdfUrl != "" {
+		link = report.PdfUrl
+	}
+
+	return &models.Notification{
+		Event: models.NotificationEventReport,
+		Title: fmt.Sprintf("Your stats from %s to %s", report.From.Format(config.SimpleDateFormat), report.To.Format(config.SimpleDateFormat)),
+		Text:  text,
+		Link:  link,
+	}
+}
+
+func (srv *ReportService) storePdf(report *models.Report) (string, error) {
+	data := renderReportPdf(report)
+	key := fmt.Sprintf("reports/%s/report-%s.pdf", report.User.ID, report.To.Format("20060102"))
+	if err := srv.storageService.Put(key, bytes.NewReader(data), int64(len(data)), "application/pdf"); err != nil {
+		return "", err
+	}
+	return srv.storageService.GetDownloadUrl(key, reportPdfLinkExpiry)
+}
+
+func renderReportPdf(report *models.Report) []byte {
+	pdf := utils.NewTextPdf()
+	pdf.AddLine(fmt.Sprintf("Your Stats from %s to %s", report.From.Format(config.SimpleDateFormat), report.To.Format(config.SimpleDateFormat)), 18, true)
+	pdf.AddSpace(10)
+	pdf.AddLine(fmt.Sprintf("Total: %s", utils.FmtWakatimeDuration(report.Summary.TotalTime())), 12, false)
+	if len(report.Summary.ManualProjects) > 0 {
+		pdf.AddLine(fmt.Sprintf("Manually added: %s", utils.FmtWakatimeDuration(report.Summary.TotalManualTime())), 12, false)
+	}
+	if o := report.Overtime; o != nil {
+		kind := "Overtime"
+		if !o.IsOvertime() {
+			kind = "Undertime"
+		}
+		pdf.AddLine(fmt.Sprintf("%s: %s (target: %s)", kind, utils.FmtWakatimeDuration(o.BalanceAbs()), utils.FmtWakatimeDuration(o.Target)), 12, false)
+	}
+
+	sections := []struct {
+		title string
+		items models.SummaryItems
+	}{
+		{"Projects", report.Summary.Projects},
+		{"Languages", report.Summary.Languages},
+		{"Editors", report.Summary.Editors},
+		{"Operating Systems", report.Summary.OperatingSystems},
+		{"Machines", report.Summary.Machines},
+	}
+	for _, section := range sections {
+		pdf.AddSpace(15)
+		pdf.AddLine(section.title, 14, true)
+		for _, item := range section.items {
+			pdf.AddLine(fmt.Sprintf("%s: %s", item.Key, utils.FmtWakatimeDuration(item.TotalFixed())), 11, false)
+		}
+	}
+
+	return pdf.Bytes()
+}
+
+func monthlyReportTag(userId string) string {
+	return userId + "-monthly"
 }
 
 func (srv *ReportService) getJobByTag(tag string) *gocron.Job {
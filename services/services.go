@@ -2,16 +2,29 @@ package services
 
 import (
 	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/services/imports"
+	"io"
 	"time"
 )
 
 type IAggregationService interface {
 	Schedule()
 	Run(map[string]bool) error
+	Regenerate(*models.User, time.Time, time.Time) (*models.RegenerationJob, error)
+	GetRegenerationJob(string) *models.RegenerationJob
+	RegenerateDay(*models.User, time.Time, time.Time) error
+	WithUserLock(string, func() error) error
+}
+
+type IJobService interface {
+	Start(string, string) func(error)
+	Track(string, string, func() error) error
+	GetAll() []*models.JobStatus
 }
 
 type IMiscService interface {
 	ScheduleCountTotalTime()
+	GetInstanceStats() (*models.InstanceStats, error)
 }
 
 type IAliasService interface {
@@ -26,18 +39,35 @@ type IAliasService interface {
 	GetAliasOrDefault(string, uint8, string) (string, error)
 }
 
+type IHeartbeatScriptService interface {
+	Validate(string) error
+	Apply(*models.User, *models.Heartbeat) (bool, error)
+}
+
 type IHeartbeatService interface {
 	Insert(*models.Heartbeat) error
 	InsertBatch([]*models.Heartbeat) error
+	GetById(uint64) (*models.Heartbeat, error)
+	Update(*models.Heartbeat) (*models.Heartbeat, error)
+	Delete(*models.Heartbeat) (*models.Tombstone, error)
+	CountPending() int64
 	Count() (int64, error)
 	CountByUser(*models.User) (int64, error)
 	CountByUsers([]*models.User) ([]*models.CountByUser, error)
 	GetAllWithin(time.Time, time.Time, *models.User) ([]*models.Heartbeat, error)
+	GetAllWithinOrdered(time.Time, time.Time, *models.User, *models.Ordering) ([]*models.Heartbeat, error)
+	StreamAllWithin(time.Time, time.Time, *models.User, func([]*models.Heartbeat) error) error
+	GetPageByUser(*models.User, uint64, int) ([]*models.Heartbeat, error)
 	GetFirstByUsers() ([]*models.TimeByUser, error)
+	GetFirstByUser(*models.User) (*models.Heartbeat, error)
 	GetLatestByUser(*models.User) (*models.Heartbeat, error)
 	GetLatestByOriginAndUser(string, *models.User) (*models.Heartbeat, error)
 	GetEntitySetByUser(uint8, *models.User) ([]string, error)
+	GetProjectActivityByUser(*models.User, *models.Ordering) ([]*models.ProjectActivity, error)
+	GetMachineActivityByUser(*models.User) ([]*models.MachineActivity, error)
+	GetOriginActivityByUser(*models.User) ([]*models.OriginActivity, error)
 	DeleteBefore(time.Time) error
+	DeleteOldestByUser(*models.User, int64) (int64, error)
 }
 
 type IDiagnosticsService interface {
@@ -51,6 +81,30 @@ type IKeyValueService interface {
 	DeleteString(string) error
 }
 
+type IMaintenanceService interface {
+	Get() *models.Maintenance
+	IsEnabled() bool
+	Enable(string) (*models.Maintenance, error)
+	Disable() (*models.Maintenance, error)
+}
+
+type IDemoService interface {
+	Schedule()
+	IsEnabled() bool
+	IsDemoUser(*models.User) bool
+	GetUser() (*models.User, error)
+	Reset() (*models.User, error)
+}
+
+type IImportService interface {
+	Get(*models.User, string) (*models.ImportJob, error)
+	GetAll(*models.User) ([]*models.ImportJob, error)
+	Run(*models.User, string, imports.HeartbeatImporter, time.Time, time.Time) (*models.ImportJob, error)
+	Begin(*models.User, string, time.Time, time.Time) (*models.ImportJob, error)
+	Update(*models.User, *models.ImportJob, func(*models.ImportJob))
+	Finish(*models.User, *models.ImportJob, error)
+}
+
 type ILanguageMappingService interface {
 	GetById(uint) (*models.LanguageMapping, error)
 	GetByUser(string) ([]*models.LanguageMapping, error)
@@ -68,30 +122,314 @@ type IProjectLabelService interface {
 	Delete(*models.ProjectLabel) error
 }
 
+type ITogglService interface {
+	GetTimeEntries(time.Time, time.Time, *models.User) ([]*models.TogglTimeEntry, error)
+}
+
+type IYearReviewService interface {
+	GetReview(*models.User, int) (*models.YearReview, error)
+	RenderSvg(*models.YearReview) []byte
+	RenderPdf(*models.YearReview) []byte
+}
+
+type IAchievementService interface {
+	GetByUser(string) ([]*models.Achievement, error)
+	Evaluate(*models.User) ([]*models.Achievement, error)
+}
+
+type IDayOffService interface {
+	GetByUser(string) ([]*models.DayOff, error)
+	GetByUserMapped(string) (models.DaysOff, error)
+	Set(string, time.Time, time.Time, string) error
+}
+
+type ITimesheetService interface {
+	GetWeek(time.Time, *models.User) (*models.Timesheet, error)
+}
+
+type IOvertimeService interface {
+	GetOvertime(*models.User, time.Time, time.Time) (*models.Overtime, error)
+	GetBalance(*models.User) (*models.Overtime, error)
+}
+
+type IRemoteAccountService interface {
+	GetByUser(*models.User) ([]*models.RemoteAccount, error)
+	Create(*models.RemoteAccount) (*models.RemoteAccount, error)
+	SetEnabled(*models.User, uint, bool) (*models.RemoteAccount, error)
+	Delete(*models.User, uint) error
+	Combine(*models.User, *models.Summary) (*models.Summary, error)
+	FetchSummary(*models.RemoteAccount, time.Time, time.Time, *time.Location) (*models.Summary, error)
+}
+
+type IComparisonService interface {
+	Compare([]string, time.Time, time.Time, *models.User) (*models.Comparison, error)
+}
+
+type ILeaderboardService interface {
+	Schedule()
+	Generate() error
+	GetLeaderboard() ([]*models.LeaderboardItem, error)
+}
+
+type IProjectRepoService interface {
+	GetByUser(string) ([]*models.ProjectRepo, error)
+	GetByUserMapped(string) (map[string]*models.ProjectRepo, error)
+	Set(string, string, string) (*models.ProjectRepo, error)
+	CountCommits(*models.ProjectRepo, time.Time, time.Time) (int, error)
+}
+
+type IManualTimeEntryService interface {
+	GetById(uint) (*models.ManualTimeEntry, error)
+	GetByUser(string) ([]*models.ManualTimeEntry, error)
+	GetByUserWithin(string, time.Time, time.Time) ([]*models.ManualTimeEntry, error)
+	Create(*models.ManualTimeEntry) (*models.ManualTimeEntry, error)
+	Delete(*models.ManualTimeEntry) error
+}
+
+type IDoctorService interface {
+	Check(int, bool) (*models.DoctorReport, error)
+	CheckAsync(int, bool) error
+	GetLastReport() *models.DoctorReport
+}
+
 type IMailService interface {
 	SendPasswordReset(*models.User, string) error
 	SendWakatimeFailureNotification(*models.User, int) error
 	SendImportNotification(*models.User, time.Duration, int) error
 	SendReport(*models.User, *models.Report) error
+	SendBudgetAlert(*models.User, *models.BudgetStatus) error
+	SendInactivityAlert(*models.User, *models.InactivityAlert) error
+	SendCredentials(*models.User, string) error
+	SendTestMail(*models.User) error
+}
+
+type IClockSkewService interface {
+	Record(*models.User, string, []*models.Heartbeat, time.Time)
+	Get(*models.User, string) *models.ClockSkew
+	GetByUser(*models.User) []*models.ClockSkew
+	Correct(*models.User, string, []*models.Heartbeat)
+}
+
+type IQuotaService interface {
+	GetLimit(*models.User) int
+	Consume(*models.User, int) (*models.QuotaStatus, bool)
+	GetStatus(*models.User) *models.QuotaStatus
+	GetViolations() []*models.QuotaViolation
+}
+
+type ILanguageMetaService interface {
+	GetAll() []*models.LanguageMeta
+	Get(string) *models.LanguageMeta
+	GetColors() map[string]string
+	GetOverrides() []*models.LanguageMeta
+	PutOverride(*models.LanguageMeta) (*models.LanguageMeta, error)
+	DeleteOverride(string) error
+}
+
+type IRateLimitService interface {
+	IsEnabled() bool
+	GetLimit() int
+	Consume(string) (*models.QuotaStatus, bool)
+	Get(string) *models.QuotaStatus
+}
+
+type IStorageQuotaService interface {
+	Schedule()
+	GetLimit(*models.User) int64
+	GetPolicy(*models.User) string
+	GetUsage(*models.User) (*models.StorageQuotaUsage, error)
+	Allows(*models.User, int) (bool, error)
+	Prune(*models.User) (int64, error)
+}
+
+type IUserBatchService interface {
+	Create([]*models.UserBatchEntry, bool) *models.UserBatchReport
+}
+
+type IInactivityService interface {
+	Schedule()
+	GetAlerts(*models.User, time.Time) ([]*models.InactivityAlert, error)
+}
+
+type IProjectBudgetService interface {
+	Schedule()
+	GetByUser(string) ([]*models.ProjectBudget, error)
+	Set(string, string, int) (*models.ProjectBudget, error)
+	GetStatuses(*models.User) ([]*models.BudgetStatus, error)
+	GetStatus(*models.User, string) (*models.BudgetStatus, error)
+}
+
+type INotificationService interface {
+	GetPreferences(*models.User) (models.NotificationPreferences, error)
+	UpdatePreferences(*models.User, []*models.NotificationPreference) (models.NotificationPreferences, error)
+	IsAvailable(*models.User, string) bool
+	IsEnabled(*models.User, string, string) bool
+	Notify(*models.User, *models.Notification) error
+}
+
+type IRelayTargetService interface {
+	GetByUser(*models.User) ([]*models.RelayTarget, error)
+	GetActive(*models.User) ([]*models.RelayTarget, error)
+	Create(*models.RelayTarget) (*models.RelayTarget, error)
+	SetEnabled(*models.User, uint, bool) (*models.RelayTarget, error)
+	Delete(*models.User, uint) error
+	GetWithStats(*models.User) ([]*models.RelayTarget, error)
+	RecordDelivery(*models.User, *models.RelayTarget, error)
+}
+
+type IRelayRuleService interface {
+	GetByUser(string) (models.RelayRules, error)
+	Create(*models.RelayRule) (*models.RelayRule, error)
+	Delete(string, uint) error
+	Accepts(*models.User, *models.Heartbeat) bool
+}
+
+type IGoalService interface {
+	GetByUser(string) ([]*models.Goal, error)
+	Create(*models.Goal) (*models.Goal, error)
+	Delete(string, uint) error
+	GetProgresses(*models.User) ([]*models.GoalProgress, error)
+	GetProgress(*models.User, uint) (*models.GoalProgress, error)
+}
+
+type IMigrationService interface {
+	Get(*models.User) (*models.Migration, error)
+	Start(*models.User, string, string) (*models.Migration, error)
+}
+
+type ISettingsService interface {
+	Export(*models.User) (*models.SettingsDocument, error)
+	Import(*models.User, *models.SettingsDocument, string) (*models.SettingsImportReport, error)
+}
+
+type IStatsCacheService interface {
+	IsEnabled() bool
+	GetOrCompute(*models.User, string, *models.Filters, func() (*models.Summary, error)) (*models.Summary, error)
+	Invalidate(string)
+}
+
+type IEmbedTokenService interface {
+	GetByUser(string) ([]*models.EmbedToken, error)
+	Create(*models.User, string, []string, []string) (*models.EmbedToken, error)
+	Delete(*models.User, uint) error
+	Authorize(string, string, string) (*models.EmbedToken, error)
+}
+
+type IFilterSetService interface {
+	GetByUser(string) ([]*models.FilterSet, error)
+	GetByUserAndName(string, string) (*models.FilterSet, error)
+	Create(*models.FilterSet) (*models.FilterSet, error)
+	Delete(string, string) error
+}
+
+type IStorageService interface {
+	Put(string, io.Reader, int64, string) error
+	Open(string) (io.ReadCloser, error)
+	Delete(string) error
+	DeleteBefore(string, time.Time) (int, error)
+	GetDownloadUrl(string, time.Duration) (string, error)
+	ResolveDownloadToken(string) (string, error)
+}
+
+type IAvatarService interface {
+	Upload(*models.User, io.Reader) (*models.User, error)
+	Remove(*models.User) (*models.User, error)
+	Open(string) (io.ReadCloser, error)
+}
+
+type IBackupService interface {
+	Schedule()
+	Run() error
+}
+
+type IExportService interface {
+	Schedule()
+	ExportHeartbeats(*models.User) (*models.ExportJob, error)
+	GetExportJob(string) *models.ExportJob
+}
+
+type IReassignmentService interface {
+	Count(*models.User, *models.HeartbeatSelection) (int64, error)
+	Reassign(*models.User, *models.HeartbeatReassignment) (*models.ReassignmentJob, error)
+	GetJob(string) *models.ReassignmentJob
+}
+
+type IUndoService interface {
+	Schedule()
+	IsEnabled() bool
+	Begin(*models.User, string) (*models.Tombstone, error)
+	Record(*models.Tombstone, []*models.Heartbeat) error
+	Discard(*models.Tombstone) error
+	GetByUser(*models.User) ([]*models.Tombstone, error)
+	Undo(*models.User, uint) (*models.Tombstone, error)
 }
 
 type IDurationService interface {
 	Get(time.Time, time.Time, *models.User, *models.Filters) (models.Durations, error)
 }
 
+type IQuarantineService interface {
+	Inspect(*models.Heartbeat)
+	GetAll() ([]*models.Quarantine, error)
+	Accept(uint) (*models.Quarantine, error)
+	Purge(uint) (int, error)
+}
+
+type ISessionService interface {
+	Get(time.Time, time.Time, *models.User, time.Duration) ([]*models.Session, error)
+}
+
 type ISummaryService interface {
 	Aliased(time.Time, time.Time, *models.User, SummaryRetriever, *models.Filters, bool) (*models.Summary, error)
 	Retrieve(time.Time, time.Time, *models.User, *models.Filters) (*models.Summary, error)
+	RetrieveBatch(time.Time, time.Time, []*models.User) (map[string]*models.Summary, error)
 	Summarize(time.Time, time.Time, *models.User, *models.Filters) (*models.Summary, error)
 	GetLatestByUser() ([]*models.TimeByUser, error)
 	DeleteByUser(string) error
+	DeleteByUserWithin(string, time.Time, time.Time) error
+	ReplaceWithin(string, time.Time, time.Time, *models.Summary) error
 	Insert(*models.Summary) error
+	InsertBatch([]*models.Summary) error
+}
+
+type ITicketService interface {
+	GetWorklog(time.Time, time.Time, *models.User) ([]*models.WorklogEntry, error)
+}
+
+type IGoogleCalendarService interface {
+	Schedule()
+	AuthCodeUrl(string) string
+	Connect(*models.User, string) error
+	Disconnect(*models.User) error
+	Run(*models.User) error
+	Sync(*models.User) ([]*models.CalendarEvent, error)
+}
+
+type IJiraService interface {
+	Schedule()
+	Run(*models.User) error
+	Sync(*models.User, bool) ([]*models.JiraWorklog, error)
+	GetLog(*models.User) ([]*models.JiraWorklog, error)
+	ValidateCredentials(string, string, string) error
+}
+
+type ICodeStatsService interface {
+	Schedule()
+	Run(*models.User) error
+	Export(*models.User) (int, error)
+	GetExport(*models.User) (*models.CodeStatsExport, error)
+	StartExport(*models.User) error
 }
 
 type IReportService interface {
 	Schedule()
 	SyncSchedule(user *models.User) bool
 	Run(*models.User, time.Duration) error
+	RunMonthly(*models.User) error
+	Archive(*models.User, time.Time, time.Time, string) (*models.ArchivedReport, error)
+	GetArchive(*models.User) ([]*models.ArchivedReport, error)
+	GetArchived(*models.User, uint) (*models.ArchivedReport, error)
+	RenderPdf(*models.Report) []byte
 }
 
 type IUserService interface {
@@ -106,7 +444,11 @@ type IUserService interface {
 	CreateOrGet(*models.Signup, bool) (*models.User, bool, error)
 	Update(*models.User) (*models.User, error)
 	Delete(*models.User) error
+	Merge(*models.UserMerge) (*models.UserMergeReport, error)
 	ResetApiKey(*models.User) (*models.User, error)
+	SetDeactivated(*models.User, bool) (*models.User, error)
+	SetPassword(*models.User, string) (*models.User, error)
+	SetAdmin(*models.User, bool) (*models.User, error)
 	SetWakatimeApiCredentials(*models.User, string, string) (*models.User, error)
 	MigrateMd5Password(*models.User, *models.Login) (*models.User, error)
 	GenerateResetToken(*models.User) (*models.User, error)
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+const (
+	SessionDefaultIdle = 15 * time.Minute // breaks up to this long don't end a session, unless requested otherwise
+	sessionMinBreak    = time.Minute      // shorter gaps between durations are not reported as breaks
+)
+
+type SessionService struct {
+	config          *config.Config
+	durationService IDurationService
+	aliasService    IAliasService
+}
+
+func NewSessionService(durationService IDurationService, aliasService IAliasService) *SessionService {
+	return &SessionService{
+		config:          config.Get(),
+		durationService: durationService,
+		aliasService:    aliasService,
+	}
+}
+
+// Get merges the user's durations within the given interval into coding sessions, regardless of projects, languages, etc.
+// A session ends as soon as there is no activity for longer than the given idle time.
+func (srv *SessionService) Get(from, to time.Time, user *models.User, idle time.Duration) ([]*models.Session, error) {
+	durations, err := srv.durationService.Get(from, to, user, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*models.Session, 0)
+	var current *models.Session
+	var projectTimes map[string]time.Duration
+
+	closeSession := func() {
+		if current == nil {
+			return
+		}
+		for p := range projectTimes {
+			current.Projects = append(current.Projects, p)
+		}
+		sort.Slice(current.Projects, func(i, j int) bool {
+			if projectTimes[current.Projects[i]] != projectTimes[current.Projects[j]] {
+				return projectTimes[current.Projects[i]] > projectTimes[current.Projects[j]]
+			}
+			return current.Projects[i] < current.Projects[j]
+		})
+		sessions = append(sessions, current)
+	}
+
+	for _, d := range durations {
+		start, end := d.Time.T(), d.Time.T().Add(d.Duration)
+
+		if current == nil || start.After(current.End.Add(idle)) {
+			closeSession()
+			current = &models.Session{Start: start, End: end, Projects: []string{}, Breaks: []*models.SessionBreak{}}
+			projectTimes = make(map[string]time.Duration)
+		} else {
+			if start.Sub(current.End) >= sessionMinBreak {
+				current.Breaks = append(current.Breaks, &models.SessionBreak{Start: current.End, End: start})
+			}
+			if end.After(current.End) {
+				current.End = end
+			}
+		}
+		current.CodingSeconds += d.Duration.Seconds()
+
+		if d.Project != "" {
+			project, err := srv.aliasService.GetAliasOrDefault(user.ID, models.SummaryProject, d.Project)
+			if err != nil {
+				return nil, err
+			}
+			projectTimes[project] += d.Duration
+		}
+	}
+	closeSession()
+
+	return sessions, nil
+}
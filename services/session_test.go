@@ -0,0 +1,88 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type SessionServiceTestSuite struct {
+	suite.Suite
+	TestUser        *models.User
+	DurationService *mocks.DurationServiceMock
+	AliasService    *mocks.AliasServiceMock
+}
+
+func (suite *SessionServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: TestUserId}
+}
+
+func (suite *SessionServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.DurationService = new(mocks.DurationServiceMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	for _, p := range []string{"wakapi", "mailwhale"} {
+		suite.AliasService.On("GetAliasOrDefault", suite.TestUser.ID, models.SummaryProject, p).Return(p, nil)
+	}
+}
+
+func TestSessionServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionServiceTestSuite))
+}
+
+func (suite *SessionServiceTestSuite) TestSessionService_Get() {
+	t0 := time.Now().Truncate(time.Second).Add(-5 * time.Hour)
+	suite.DurationService.On("Get", mock.Anything, mock.Anything, suite.TestUser, mock.Anything).Return(models.Durations{
+		{Time: models.CustomTime(t0), Duration: 20 * time.Minute, Project: "wakapi"},
+		{Time: models.CustomTime(t0.Add(25 * time.Minute)), Duration: 20 * time.Minute, Project: "mailwhale"}, // short break
+		{Time: models.CustomTime(t0.Add(45 * time.Minute)), Duration: 5 * time.Minute, Project: "wakapi"},
+		{Time: models.CustomTime(t0.Add(2 * time.Hour)), Duration: 10 * time.Minute, Project: "mailwhale"}, // new session
+	}, nil)
+
+	sut := NewSessionService(suite.DurationService, suite.AliasService)
+
+	result, err := sut.Get(t0, time.Now(), suite.TestUser, 10*time.Minute)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.Equal(suite.T(), t0, result[0].Start)
+	assert.Equal(suite.T(), t0.Add(50*time.Minute), result[0].End)
+	assert.Equal(suite.T(), float64(45*60), result[0].CodingSeconds)
+	assert.Equal(suite.T(), []string{"wakapi", "mailwhale"}, result[0].Projects)
+	assert.Len(suite.T(), result[0].Breaks, 1)
+	assert.Equal(suite.T(), t0.Add(20*time.Minute), result[0].Breaks[0].Start)
+	assert.Equal(suite.T(), t0.Add(25*time.Minute), result[0].Breaks[0].End)
+	assert.Equal(suite.T(), "Coding: wakapi, mailwhale", result[0].Title(models.CalendarTitlesProjects))
+	assert.Equal(suite.T(), "Focus time", result[0].Title(models.CalendarTitlesGeneric))
+	assert.Equal(suite.T(), []string{"mailwhale"}, result[1].Projects)
+	assert.Empty(suite.T(), result[1].Breaks)
+
+	list := models.NewSessionList(t0, time.Now(), 10*time.Minute, result)
+	assert.Equal(suite.T(), float64(55*60), list.TotalSeconds)
+	assert.Equal(suite.T(), 10, list.IdleMinutes)
+}
+
+func (suite *SessionServiceTestSuite) TestSessionService_Get_Idle() {
+	t0 := time.Now().Truncate(time.Second).Add(-5 * time.Hour)
+	suite.DurationService.On("Get", mock.Anything, mock.Anything, suite.TestUser, mock.Anything).Return(models.Durations{
+		{Time: models.CustomTime(t0), Duration: 20 * time.Minute, Project: "wakapi"},
+		{Time: models.CustomTime(t0.Add(50 * time.Minute)), Duration: 10 * time.Minute, Project: "wakapi"}, // 30 minutes break
+	}, nil)
+
+	sut := NewSessionService(suite.DurationService, suite.AliasService)
+
+	result, err := sut.Get(t0, time.Now(), suite.TestUser, SessionDefaultIdle)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+
+	result, err = sut.Get(t0, time.Now(), suite.TestUser, time.Hour)
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 1)
+	assert.Len(suite.T(), result[0].Breaks, 1)
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// SettingsService exports a user's configuration as a portable document and applies such documents, e.g. to replicate a setup on another instance
+type SettingsService struct {
+	config                 *config.Config
+	aliasService           IAliasService
+	languageMappingService ILanguageMappingService
+	projectLabelService    IProjectLabelService
+	relayRuleService       IRelayRuleService
+	goalService            IGoalService
+}
+
+func NewSettingsService(aliasService IAliasService, languageMappingService ILanguageMappingService, projectLabelService IProjectLabelService, relayRuleService IRelayRuleService, goalService IGoalService) *SettingsService {
+	return &SettingsService{
+		config:                 config.Get(),
+		aliasService:           aliasService,
+		languageMappingService: languageMappingService,
+		projectLabelService:    projectLabelService,
+		relayRuleService:       relayRuleService,
+		goalService:            goalService,
+	}
+}
+
+func (srv *SettingsService) Export(user *models.User) (*models.SettingsDocument, error) {
+	aliases, err := srv.aliasService.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	mappings, err := srv.languageMappingService.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := srv.projectLabelService.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := srv.relayRuleService.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	goals, err := srv.goalService.GetByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewSettingsDocument(aliases, mappings, labels, rules, goals), nil
+}
+
+// Import applies the given document to the user's configuration. In merge mode, entries already present are skipped and language mappings of
+// existing extensions are overwritten. In replace mode, all existing entries of every section are removed first, even if the document leaves the section empty.
+func (srv *SettingsService) Import(user *models.User, doc *models.SettingsDocument, mode string) (*models.SettingsImportReport, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	report := &models.SettingsImportReport{}
+	replace := mode == models.SettingsImportReplace
+
+	if err := srv.importAliases(user, doc, replace, report); err != nil {
+		return report, err
+	}
+	if err := srv.importLanguageMappings(user, doc, replace, report); err != nil {
+		return report, err
+	}
+	if err := srv.importProjectLabels(user, doc, replace, report); err != nil {
+		return report, err
+	}
+	if err := srv.importRelayRules(user, doc, replace, report); err != nil {
+		return report, err
+	}
+	if err := srv.importGoals(user, doc, replace, report); err != nil {
+		return report, err
+	}
+
+	logbuch.Info("imported settings for user '%s' (%d created, %d skipped, %d deleted)", user.ID, report.Created, report.Skipped, report.Deleted)
+	return report, nil
+}
+
+func (srv *SettingsService) importAliases(user *models.User, doc *models.SettingsDocument, replace bool, report *models.SettingsImportReport) error {
+	existing, err := srv.aliasService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if replace && len(existing) > 0 {
+		if err := srv.aliasService.DeleteMulti(existing); err != nil {
+			return err
+		}
+		report.Deleted += len(existing)
+		existing = nil
+	}
+
+outer:
+	for _, a := range doc.ToAliases(user.ID) {
+		for _, e := range existing {
+			if e.Type == a.Type && e.Key == a.Key && e.Value == a.Value {
+				report.Skipped++
+				continue outer
+			}
+		}
+		if _, err := srv.aliasService.Create(a); err != nil {
+			return err
+		}
+		existing = append(existing, a)
+		report.Created++
+	}
+	return nil
+}
+
+func (srv *SettingsService) importLanguageMappings(user *models.User, doc *models.SettingsDocument, replace bool, report *models.SettingsImportReport) error {
+	existing, err := srv.languageMappingService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	byExtension := make(map[string]*models.LanguageMapping, len(existing))
+	for _, e := range existing {
+		if language, found := doc.LanguageMappings[e.Extension]; replace || (found && language != e.Language) {
+			if err := srv.languageMappingService.Delete(e); err != nil {
+				return err
+			}
+			report.Deleted++
+			continue
+		}
+		byExtension[e.Extension] = e
+	}
+
+	for _, m := range doc.ToLanguageMappings(user.ID) {
+		if _, found := byExtension[m.Extension]; found {
+			report.Skipped++
+			continue
+		}
+		if _, err := srv.languageMappingService.Create(m); err != nil {
+			return err
+		}
+		report.Created++
+	}
+	return nil
+}
+
+func (srv *SettingsService) importProjectLabels(user *models.User, doc *models.SettingsDocument, replace bool, report *models.SettingsImportReport) error {
+	existing, err := srv.projectLabelService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if replace {
+		for _, e := range existing {
+			if err := srv.projectLabelService.Delete(e); err != nil {
+				return err
+			}
+			report.Deleted++
+		}
+		existing = nil
+	}
+
+outer:
+	for _, l := range doc.ToProjectLabels(user.ID) {
+		for _, e := range existing {
+			if e.ProjectKey == l.ProjectKey && e.Label == l.Label {
+				report.Skipped++
+				continue outer
+			}
+		}
+		if _, err := srv.projectLabelService.Create(l); err != nil {
+			return err
+		}
+		existing = append(existing, l)
+		report.Created++
+	}
+	return nil
+}
+
+func (srv *SettingsService) importRelayRules(user *models.User, doc *models.SettingsDocument, replace bool, report *models.SettingsImportReport) error {
+	existing, err := srv.relayRuleService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if replace {
+		for _, e := range existing {
+			if err := srv.relayRuleService.Delete(user.ID, e.ID); err != nil {
+				return err
+			}
+			report.Deleted++
+		}
+		existing = nil
+	}
+
+outer:
+	for _, r := range doc.ToRelayRules(user.ID) {
+		for _, e := range existing {
+			if e.Type == r.Type && e.Value == r.Value {
+				report.Skipped++
+				continue outer
+			}
+		}
+		if _, err := srv.relayRuleService.Create(r); err != nil {
+			return err
+		}
+		existing = append(existing, r)
+		report.Created++
+	}
+	return nil
+}
+
+func (srv *SettingsService) importGoals(user *models.User, doc *models.SettingsDocument, replace bool, report *models.SettingsImportReport) error {
+	existing, err := srv.goalService.GetByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if replace {
+		for _, e := range existing {
+			if err := srv.goalService.Delete(user.ID, e.ID); err != nil {
+				return err
+			}
+			report.Deleted++
+		}
+		existing = nil
+	}
+
+outer:
+	for _, g := range doc.ToGoals(user.ID) {
+		for _, e := range existing {
+			if e.Title == g.Title && e.Interval == g.Interval && e.Minutes == g.Minutes && e.Language == g.Language && e.Editor == g.Editor {
+				report.Skipped++
+				continue outer
+			}
+		}
+		if _, err := srv.goalService.Create(g); err != nil {
+			return err
+		}
+		existing = append(existing, g)
+		report.Created++
+	}
+	return nil
+}
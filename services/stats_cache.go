@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/patrickmn/go-cache"
+)
+
+// StatsCacheService caches summaries served by the wakatime-compatible stats endpoint, which is polled frequently by
+// consumers like github-readme-stats. Entries are keyed by user, range and filters and live until their ttl expires or
+// until anything they depend on changes, i.e. new heartbeats arrive or aliases, mappings, labels or manual entries are modified.
+type StatsCacheService struct {
+	config   *config.Config
+	eventBus *hub.Hub
+	cache    *cache.Cache
+	versions map[string]int64
+	lock     sync.RWMutex
+}
+
+func NewStatsCacheService() *StatsCacheService {
+	srv := &StatsCacheService{
+		config:   config.Get(),
+		eventBus: config.EventBus(),
+		versions: map[string]int64{},
+	}
+	ttl := srv.config.App.GetStatsCacheTTL()
+	srv.cache = cache.New(ttl, ttl)
+
+	sub1 := srv.eventBus.Subscribe(0, config.EventHeartbeatCreate)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.Invalidate(m.Fields[config.FieldPayload].(*models.Heartbeat).UserID)
+		}
+	}(&sub1)
+
+	sub2 := srv.eventBus.Subscribe(0, config.EventHeartbeatUpdate, config.EventHeartbeatDelete, config.EventUserUpdate, config.TopicAlias, config.TopicLanguageMapping, config.TopicProjectLabel, config.TopicManualTimeEntry)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.Invalidate(m.Fields[config.FieldUserId].(string))
+		}
+	}(&sub2)
+
+	return srv
+}
+
+func (srv *StatsCacheService) IsEnabled() bool {
+	return srv.config.App.GetStatsCacheTTL() > 0
+}
+
+// GetOrCompute returns the cached summary for the given user, range and filters or computes and caches it, if none is present.
+// The range is identified by its key (e.g. 'last_7_days') along with the day it was resolved at, since relative ranges move along with the current time.
+func (srv *StatsCacheService) GetOrCompute(user *models.User, rangeKey string, filters *models.Filters, compute func() (*models.Summary, error)) (*models.Summary, error) {
+	if !srv.IsEnabled() {
+		return compute()
+	}
+
+	key := srv.key(user, rangeKey, filters)
+	if cached, found := srv.cache.Get(key); found {
+		return cached.(*models.Summary), nil
+	}
+
+	summary, err := compute()
+	if err != nil {
+		return nil, err
+	}
+	srv.cache.SetDefault(key, summary)
+	return summary, nil
+}
+
+// Invalidate drops all cached entries of the given user. Instead of looking up the user's entries, the user's version is
+// increased, so that old entries are no longer hit and expire eventually.
+func (srv *StatsCacheService) Invalidate(userId string) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	srv.versions[userId]++
+}
+
+func (srv *StatsCacheService) key(user *models.User, rangeKey string, filters *models.Filters) string {
+	srv.lock.RLock()
+	version := srv.versions[user.ID]
+	srv.lock.RUnlock()
+
+	day := time.Now().In(user.TZ()).Format(config.SimpleDateFormat)
+	return fmt.Sprintf("%s__%d__%s__%s__%s", user.ID, version, rangeKey, day, filters.Hash())
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type StatsCacheServiceTestSuite struct {
+	suite.Suite
+	TestUser *models.User
+}
+
+func (suite *StatsCacheServiceTestSuite) SetupSuite() {
+	suite.TestUser = &models.User{ID: "user1", Location: "UTC"}
+}
+
+func (suite *StatsCacheServiceTestSuite) BeforeTest(suiteName, testName string) {
+	cfg := &config.Config{}
+	cfg.App.StatsCacheTTLMin = 10
+	config.Set(cfg)
+}
+
+func TestStatsCacheServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(StatsCacheServiceTestSuite))
+}
+
+func (suite *StatsCacheServiceTestSuite) TestStatsCacheService_GetOrCompute() {
+	sut := NewStatsCacheService()
+
+	calls := 0
+	compute := func() (*models.Summary, error) {
+		calls++
+		return &models.Summary{UserID: suite.TestUser.ID}, nil
+	}
+
+	filters := &models.Filters{}
+	s1, err := sut.GetOrCompute(suite.TestUser, "last_7_days", filters, compute)
+	assert.Nil(suite.T(), err)
+	s2, _ := sut.GetOrCompute(suite.TestUser, "last_7_days", filters, compute)
+	assert.Same(suite.T(), s1, s2)
+	assert.Equal(suite.T(), 1, calls)
+
+	sut.GetOrCompute(suite.TestUser, "last_30_days", filters, compute)
+	sut.GetOrCompute(suite.TestUser, "last_7_days", models.NewFiltersWith(models.SummaryProject, "wakapi"), compute)
+	assert.Equal(suite.T(), 3, calls)
+
+	sut.Invalidate(suite.TestUser.ID)
+	s3, _ := sut.GetOrCompute(suite.TestUser, "last_7_days", filters, compute)
+	assert.NotSame(suite.T(), s1, s3)
+	assert.Equal(suite.T(), 4, calls)
+}
+
+func (suite *StatsCacheServiceTestSuite) TestStatsCacheService_GetOrCompute_Disabled() {
+	config.Get().App.StatsCacheTTLMin = -1
+	sut := NewStatsCacheService()
+
+	calls := 0
+	compute := func() (*models.Summary, error) {
+		calls++
+		return &models.Summary{}, nil
+	}
+
+	sut.GetOrCompute(suite.TestUser, "today", &models.Filters{}, compute)
+	sut.GetOrCompute(suite.TestUser, "today", &models.Filters{}, compute)
+	assert.False(suite.T(), sut.IsEnabled())
+	assert.Equal(suite.T(), 2, calls)
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+const localDownloadCookieName = "storage_download"
+
+// LocalStorageService stores files in a directory on the local file system.
+// Download links point to wakapi itself and carry a signed, expiring token instead of the plain key.
+type LocalStorageService struct {
+	config *conf.Config
+	root   string
+}
+
+type localDownloadToken struct {
+	Key     string
+	Expires int64
+}
+
+func NewLocalStorageService(config conf.LocalStorageConfig) *LocalStorageService {
+	return &LocalStorageService{
+		config: conf.Get(),
+		root:   config.Path,
+	}
+}
+
+func (s *LocalStorageService) Put(key string, data io.Reader, size int64, contentType string) error {
+	file, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, data)
+	return err
+}
+
+func (s *LocalStorageService) Open(key string) (io.ReadCloser, error) {
+	file, err := s.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(file)
+}
+
+func (s *LocalStorageService) Delete(key string) error {
+	file, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteBefore deletes all files with the given key prefix, which were last modified before the given time
+func (s *LocalStorageService) DeleteBefore(prefix string, t time.Time) (int, error) {
+	dir, err := s.resolve(prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !info.ModTime().Before(t) {
+			return nil
+		}
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func (s *LocalStorageService) GetDownloadUrl(key string, expiry time.Duration) (string, error) {
+	key, err := normalizeKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.config.Security.SecureCookie.Encode(localDownloadCookieName, &localDownloadToken{
+		Key:     key,
+		Expires: time.Now().Add(expiry).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/api/storage/download?token=%s", s.config.Server.GetPublicUrl(), url.QueryEscape(token)), nil
+}
+
+// ResolveDownloadToken returns the key a download link was generated for, if its token is valid and not yet expired
+func (s *LocalStorageService) ResolveDownloadToken(token string) (string, error) {
+	var payload localDownloadToken
+	if err := s.config.Security.SecureCookie.Decode(localDownloadCookieName, token, &payload); err != nil {
+		return "", err
+	}
+	if time.Now().Unix() > payload.Expires {
+		return "", fmt.Errorf("download link expired")
+	}
+	return payload.Key, nil
+}
+
+func (s *LocalStorageService) resolve(key string) (string, error) {
+	key, err := normalizeKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
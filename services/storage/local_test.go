@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"github.com/gorilla/securecookie"
+	conf "github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type LocalStorageServiceTestSuite struct {
+	suite.Suite
+	Root string
+}
+
+func (suite *LocalStorageServiceTestSuite) SetupSuite() {
+	cfg := &conf.Config{}
+	cfg.Server.PublicUrl = "https://wakapi.example.org"
+	cfg.Security.SecureCookie = securecookie.New(securecookie.GenerateRandomKey(64), securecookie.GenerateRandomKey(32))
+	conf.Set(cfg)
+}
+
+func (suite *LocalStorageServiceTestSuite) SetupTest() {
+	root, _ := ioutil.TempDir("", "wakapi-storage-test-*")
+	suite.Root = root
+}
+
+func (suite *LocalStorageServiceTestSuite) TearDownTest() {
+	os.RemoveAll(suite.Root)
+}
+
+func TestLocalStorageServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(LocalStorageServiceTestSuite))
+}
+
+func (suite *LocalStorageServiceTestSuite) TestLocalStorageService_ResolveDownloadToken() {
+	sut := NewLocalStorageService(conf.LocalStorageConfig{Path: suite.Root})
+
+	link, err := sut.GetDownloadUrl("exports/user/heartbeats.json", time.Hour)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), strings.HasPrefix(link, "https://wakapi.example.org/api/storage/download?token="))
+
+	key, err := sut.ResolveDownloadToken(tokenFromUrl(link))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "exports/user/heartbeats.json", key)
+}
+
+func (suite *LocalStorageServiceTestSuite) TestLocalStorageService_ResolveDownloadToken_Expired() {
+	sut := NewLocalStorageService(conf.LocalStorageConfig{Path: suite.Root})
+
+	link, _ := sut.GetDownloadUrl("exports/user/heartbeats.json", -1*time.Minute)
+	key, err := sut.ResolveDownloadToken(tokenFromUrl(link))
+	assert.NotNil(suite.T(), err)
+	assert.Empty(suite.T(), key)
+}
+
+func (suite *LocalStorageServiceTestSuite) TestLocalStorageService_ResolveDownloadToken_Tampered() {
+	sut := NewLocalStorageService(conf.LocalStorageConfig{Path: suite.Root})
+
+	link, _ := sut.GetDownloadUrl("exports/user/heartbeats.json", time.Hour)
+	token := tokenFromUrl(link)
+
+	// flip a single character of the token
+	tampered := []byte(token)
+	if tampered[10] == 'A' {
+		tampered[10] = 'B'
+	} else {
+		tampered[10] = 'A'
+	}
+
+	key, err := sut.ResolveDownloadToken(string(tampered))
+	assert.NotNil(suite.T(), err)
+	assert.Empty(suite.T(), key)
+
+	// token for a different key, signed with a different secret
+	forged, _ := securecookie.New(securecookie.GenerateRandomKey(64), securecookie.GenerateRandomKey(32)).
+		Encode(localDownloadCookieName, &localDownloadToken{Key: "exports/other/heartbeats.json", Expires: time.Now().Add(time.Hour).Unix()})
+	key, err = sut.ResolveDownloadToken(forged)
+	assert.NotNil(suite.T(), err)
+	assert.Empty(suite.T(), key)
+}
+
+func (suite *LocalStorageServiceTestSuite) TestLocalStorageService_DeleteBefore() {
+	sut := NewLocalStorageService(conf.LocalStorageConfig{Path: suite.Root})
+
+	for _, key := range []string{"exports/user/old.json", "exports/user/new.json", "exports2/old.json"} {
+		assert.Nil(suite.T(), sut.Put(key, strings.NewReader("[]"), 2, "application/json"))
+	}
+
+	past := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(filepath.Join(suite.Root, "exports", "user", "old.json"), past, past)
+	os.Chtimes(filepath.Join(suite.Root, "exports2", "old.json"), past, past)
+
+	n, err := sut.DeleteBefore("exports/", time.Now().Add(-1*time.Hour))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, n)
+	assert.NoFileExists(suite.T(), filepath.Join(suite.Root, "exports", "user", "old.json"))
+	assert.FileExists(suite.T(), filepath.Join(suite.Root, "exports", "user", "new.json"))
+	assert.FileExists(suite.T(), filepath.Join(suite.Root, "exports2", "old.json"))
+
+	n, err = sut.DeleteBefore("../", time.Now())
+	assert.Equal(suite.T(), ErrInvalidKey, err)
+	assert.Zero(suite.T(), n)
+}
+
+func tokenFromUrl(link string) string {
+	u, _ := url.Parse(link)
+	return u.Query().Get("token")
+}
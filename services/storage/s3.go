@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	conf "github.com/muety/wakapi/config"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3Service         = "s3"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3DateFormat      = "20060102T150405Z"
+	s3ShortDateFormat = "20060102"
+	s3MaxLinkExpiry   = 7 * 24 * time.Hour
+)
+
+type s3ListBucketResult struct {
+	Contents []struct {
+		Key          string
+		LastModified time.Time
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// S3StorageService stores files in an s3-compatible object storage, talking to its rest api directly.
+// Requests are authenticated using aws signature version 4, download links are presigned urls.
+type S3StorageService struct {
+	config     conf.S3StorageConfig
+	httpClient *http.Client
+}
+
+func NewS3StorageService(config conf.S3StorageConfig) *S3StorageService {
+	return &S3StorageService{
+		config:     config,
+		httpClient: conf.NewHttpClient(conf.ProxyScopeStorage, 5*time.Minute),
+	}
+}
+
+func (s *S3StorageService) Put(key string, data io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *S3StorageService) Open(key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *S3StorageService) Delete(key string) error {
+	req, err := s.newRequest(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// DeleteBefore deletes all objects with the given key prefix, which were last modified before the given time
+func (s *S3StorageService) DeleteBefore(prefix string, t time.Time) (int, error) {
+	normalizedPrefix, err := normalizeKey(prefix)
+	if err != nil {
+		return 0, err
+	}
+	if strings.HasSuffix(prefix, "/") {
+		// keep trailing slash to not match sibling keys, e.g. 'exports2/...' for prefix 'exports/'
+		normalizedPrefix += "/"
+	}
+	prefix = normalizedPrefix
+
+	var count int
+	var continuationToken string
+	for {
+		result, err := s.list(prefix, continuationToken)
+		if err != nil {
+			return count, err
+		}
+		for _, o := range result.Contents {
+			if !o.LastModified.Before(t) {
+				continue
+			}
+			if err := s.Delete(o.Key); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if !result.IsTruncated {
+			return count, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// GetDownloadUrl returns a presigned url, which allows to download the object directly from the object storage without further authentication
+// Presigned urls are valid for at most seven days.
+func (s *S3StorageService) GetDownloadUrl(key string, expiry time.Duration) (string, error) {
+	if expiry > s3MaxLinkExpiry {
+		expiry = s3MaxLinkExpiry
+	}
+	return s.presign(key, expiry, time.Now().UTC())
+}
+
+func (s *S3StorageService) presign(key string, expiry time.Duration, now time.Time) (string, error) {
+	u, err := s.objectUrl(key)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.config.AccessKeyId+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format(s3DateFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	u.RawQuery = canonicalQuery(query)
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		u.RawQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+
+	u.RawQuery += "&X-Amz-Signature=" + s.sign(now, canonicalRequest)
+	return u.String(), nil
+}
+
+// list fetches a single page of objects with the given key prefix using the ListObjectsV2 api
+func (s *S3StorageService) list(prefix, continuationToken string) (*s3ListBucketResult, error) {
+	u, err := s.bucketUrl()
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("prefix", prefix)
+	if continuationToken != "" {
+		query.Set("continuation-token", continuationToken)
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var result s3ListBucketResult
+	if err := xml.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *S3StorageService) ResolveDownloadToken(token string) (string, error) {
+	return "", errors.New("not supported by s3 storage")
+}
+
+func (s *S3StorageService) newRequest(method, key string, body io.Reader) (*http.Request, error) {
+	u, err := s.objectUrl(key)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequest(method, u.String(), body)
+}
+
+func (s *S3StorageService) do(req *http.Request) (*http.Response, error) {
+	s.signRequest(req, time.Now().UTC())
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		res.Body.Close()
+		return nil, fmt.Errorf("s3 request failed with status %d - %s", res.StatusCode, string(body))
+	}
+	return res, nil
+}
+
+func (s *S3StorageService) signRequest(req *http.Request, now time.Time) {
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", now.Format(s3DateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(req.Header.Get(h)) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		s3UnsignedPayload,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKeyId, s.scope(now), strings.Join(signedHeaders, ";"), s.sign(now, canonicalRequest),
+	))
+}
+
+func (s *S3StorageService) sign(now time.Time, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3DateFormat),
+		s.scope(now),
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+s.config.SecretAccessKey), now.Format(s3ShortDateFormat))
+	key = hmacSha256(key, s.config.Region)
+	key = hmacSha256(key, s3Service)
+	key = hmacSha256(key, "aws4_request")
+	return hex.EncodeToString(hmacSha256(key, stringToSign))
+}
+
+func (s *S3StorageService) scope(now time.Time) string {
+	return fmt.Sprintf("%s/%s/%s/aws4_request", now.Format(s3ShortDateFormat), s.config.Region, s3Service)
+}
+
+func (s *S3StorageService) objectUrl(key string) (*url.URL, error) {
+	key, err := normalizeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.bucketUrl()
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	u.RawPath = uriEncode(u.Path, false)
+	return u, nil
+}
+
+func (s *S3StorageService) bucketUrl() (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSuffix(s.config.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.PathStyle {
+		u.Path += "/" + s.config.Bucket
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path += "/"
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	return u, nil
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by key and with spaces as %20, as required by aws signatures
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything except unreserved characters (and optionally slashes), see https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func uriEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' || (b == '/' && !encodeSlash) {
+			sb.WriteByte(b)
+		} else {
+			sb.WriteString(fmt.Sprintf("%%%02X", b))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	conf "github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/services"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+func NewStorageService() services.IStorageService {
+	config := conf.Get()
+
+	if config.Storage.Provider == conf.StorageProviderS3 {
+		return NewS3StorageService(config.Storage.S3)
+	}
+	return NewLocalStorageService(config.Storage.Local)
+}
+
+// normalizeKey cleans the given object key and makes sure it does not point outside the storage root
+func normalizeKey(key string) (string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if cleaned == "" || cleaned == "." || strings.Contains(key, "..") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
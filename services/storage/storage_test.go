@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+		err error
+	}{
+		{"exports/user/heartbeats.json", "exports/user/heartbeats.json", nil},
+		{"/exports//user/./heartbeats.json", "exports/user/heartbeats.json", nil},
+		{"exports/", "exports", nil},
+		{"../etc/passwd", "", ErrInvalidKey},
+		{"exports/../../etc/passwd", "", ErrInvalidKey},
+		{"exports/user/../other/heartbeats.json", "", ErrInvalidKey},
+		{"/..", "", ErrInvalidKey},
+		{"..", "", ErrInvalidKey},
+		{"", "", ErrInvalidKey},
+		{"/", "", ErrInvalidKey},
+		{".", "", ErrInvalidKey},
+	}
+
+	for _, test := range tests {
+		out, err := normalizeKey(test.in)
+		assert.Equal(t, test.err, err, test.in)
+		assert.Equal(t, test.out, out, test.in)
+	}
+}
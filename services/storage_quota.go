@@ -0,0 +1,115 @@
+package services
+
+import (
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-co-op/gocron"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+// StorageQuotaService limits the number of heartbeats every user can store in total, as a measure of the storage they take up.
+// Once a user exceeds their quota, either their new heartbeats are rejected or their oldest ones are pruned periodically, while summaries are retained.
+type StorageQuotaService struct {
+	config           *config.Config
+	userService      IUserService
+	heartbeatService IHeartbeatService
+	jobService       IJobService
+}
+
+func NewStorageQuotaService(userService IUserService, heartbeatService IHeartbeatService, jobService IJobService) *StorageQuotaService {
+	return &StorageQuotaService{
+		config:           config.Get(),
+		userService:      userService,
+		heartbeatService: heartbeatService,
+		jobService:       jobService,
+	}
+}
+
+func (srv *StorageQuotaService) Schedule() {
+	logbuch.Info("scheduling hourly storage quota enforcement")
+
+	s := gocron.NewScheduler(time.Local)
+	s.Every(1).Hour().Do(srv.pruneAll)
+	s.StartBlocking()
+}
+
+// GetLimit returns the number of heartbeats the user may store at most (0 = unlimited), falling back to the server-wide default
+func (srv *StorageQuotaService) GetLimit(user *models.User) int64 {
+	if user.StorageQuota < 0 {
+		return 0
+	}
+	if user.StorageQuota > 0 {
+		return user.StorageQuota
+	}
+	return srv.config.App.StorageQuotaHeartbeats
+}
+
+// GetPolicy returns what to do once the user exceeds their quota, falling back to the server-wide default
+func (srv *StorageQuotaService) GetPolicy(user *models.User) string {
+	if user.StorageQuotaPolicy == config.StorageQuotaPolicyReject || user.StorageQuotaPolicy == config.StorageQuotaPolicyPrune {
+		return user.StorageQuotaPolicy
+	}
+	return srv.config.App.GetStorageQuotaPolicy()
+}
+
+func (srv *StorageQuotaService) GetUsage(user *models.User) (*models.StorageQuotaUsage, error) {
+	usage := &models.StorageQuotaUsage{Limit: srv.GetLimit(user), Policy: srv.GetPolicy(user)}
+	count, err := srv.heartbeatService.CountByUser(user)
+	if err != nil {
+		return nil, err
+	}
+	usage.Used = count
+	return usage, nil
+}
+
+// Allows tells whether another n heartbeats of the user may be stored, which is always the case, if their oldest heartbeats are pruned instead
+func (srv *StorageQuotaService) Allows(user *models.User, n int) (bool, error) {
+	if srv.GetLimit(user) == 0 || srv.GetPolicy(user) == config.StorageQuotaPolicyPrune {
+		return true, nil
+	}
+	usage, err := srv.GetUsage(user)
+	if err != nil {
+		return false, err
+	}
+	return !usage.Exceeds(int64(n)), nil
+}
+
+// Prune deletes the user's oldest heartbeats in excess of their quota, if the user's policy is to prune, and returns the number of deleted heartbeats
+func (srv *StorageQuotaService) Prune(user *models.User) (int64, error) {
+	if srv.GetPolicy(user) != config.StorageQuotaPolicyPrune {
+		return 0, nil
+	}
+
+	usage, err := srv.GetUsage(user)
+	if err != nil || usage.Excess() == 0 {
+		return 0, err
+	}
+
+	deleted, err := srv.heartbeatService.DeleteOldestByUser(user, usage.Excess())
+	if err != nil {
+		return 0, err
+	}
+	logbuch.Info("pruned %d heartbeats of user '%s' exceeding storage quota of %d", deleted, user.ID, usage.Limit)
+	return deleted, nil
+}
+
+func (srv *StorageQuotaService) pruneAll() {
+	srv.jobService.Track(models.JobStoragePrune, "", func() error {
+		users, err := srv.userService.GetAll()
+		if err != nil {
+			return err
+		}
+
+		for _, u := range users {
+			if srv.GetLimit(u) == 0 {
+				continue
+			}
+			if _, err := srv.Prune(u); err != nil {
+				config.Log().Error("failed to prune heartbeats of user '%s' - %v", u.ID, err)
+			}
+		}
+		return nil
+	})
+}
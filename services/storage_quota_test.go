@@ -0,0 +1,106 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type StorageQuotaServiceTestSuite struct {
+	suite.Suite
+	TestUser         *models.User
+	UserService      *mocks.UserServiceMock
+	HeartbeatService *mocks.HeartbeatServiceMock
+}
+
+func (suite *StorageQuotaServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.App.StorageQuotaHeartbeats = 1000
+	config.Set(cfg)
+}
+
+func (suite *StorageQuotaServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUser = &models.User{ID: TestUserId}
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.HeartbeatService = new(mocks.HeartbeatServiceMock)
+}
+
+func TestStorageQuotaServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(StorageQuotaServiceTestSuite))
+}
+
+func (suite *StorageQuotaServiceTestSuite) TestStorageQuotaService_GetLimitAndPolicy() {
+	sut := NewStorageQuotaService(suite.UserService, suite.HeartbeatService, nil)
+
+	assert.Equal(suite.T(), int64(1000), sut.GetLimit(&models.User{}))
+	assert.Equal(suite.T(), int64(10), sut.GetLimit(&models.User{StorageQuota: 10}))
+	assert.Equal(suite.T(), int64(0), sut.GetLimit(&models.User{StorageQuota: -1}))
+
+	assert.Equal(suite.T(), config.StorageQuotaPolicyReject, sut.GetPolicy(&models.User{}))
+	assert.Equal(suite.T(), config.StorageQuotaPolicyPrune, sut.GetPolicy(&models.User{StorageQuotaPolicy: config.StorageQuotaPolicyPrune}))
+	assert.Equal(suite.T(), config.StorageQuotaPolicyReject, sut.GetPolicy(&models.User{StorageQuotaPolicy: "archive"}))
+}
+
+func (suite *StorageQuotaServiceTestSuite) TestStorageQuotaService_Allows() {
+	suite.HeartbeatService.On("CountByUser", suite.TestUser).Return(int64(990), nil)
+
+	sut := NewStorageQuotaService(suite.UserService, suite.HeartbeatService, nil)
+
+	ok, err := sut.Allows(suite.TestUser, 10)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), ok)
+
+	ok, err = sut.Allows(suite.TestUser, 11)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), ok)
+
+	// oldest heartbeats are pruned instead
+	suite.TestUser.StorageQuotaPolicy = config.StorageQuotaPolicyPrune
+	ok, err = sut.Allows(suite.TestUser, 11)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), ok)
+
+	suite.TestUser.StorageQuotaPolicy = ""
+	suite.TestUser.StorageQuota = -1
+	ok, err = sut.Allows(suite.TestUser, 11)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), ok)
+}
+
+func (suite *StorageQuotaServiceTestSuite) TestStorageQuotaService_GetUsage() {
+	suite.HeartbeatService.On("CountByUser", suite.TestUser).Return(int64(1200), nil)
+
+	sut := NewStorageQuotaService(suite.UserService, suite.HeartbeatService, nil)
+
+	usage, err := sut.GetUsage(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(1000), usage.Limit)
+	assert.Equal(suite.T(), int64(1200), usage.Used)
+	assert.Equal(suite.T(), int64(200), usage.Excess())
+	assert.Equal(suite.T(), 100, usage.Percentage())
+	assert.Equal(suite.T(), config.StorageQuotaPolicyReject, usage.Policy)
+}
+
+func (suite *StorageQuotaServiceTestSuite) TestStorageQuotaService_Prune() {
+	suite.HeartbeatService.On("CountByUser", suite.TestUser).Return(int64(1200), nil)
+	suite.HeartbeatService.On("DeleteOldestByUser", suite.TestUser, int64(200)).Return(int64(201), nil)
+
+	sut := NewStorageQuotaService(suite.UserService, suite.HeartbeatService, nil)
+
+	// heartbeats are only pruned, if that's the user's policy
+	deleted, err := sut.Prune(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Zero(suite.T(), deleted)
+	suite.HeartbeatService.AssertNotCalled(suite.T(), "DeleteOldestByUser", mock.Anything, mock.Anything)
+
+	suite.TestUser.StorageQuotaPolicy = config.StorageQuotaPolicyPrune
+	deleted, err = sut.Prune(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(201), deleted)
+	suite.HeartbeatService.AssertCalled(suite.T(), "DeleteOldestByUser", suite.TestUser, int64(200))
+}
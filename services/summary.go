@@ -15,34 +15,39 @@ import (
 )
 
 type SummaryService struct {
-	config              *config.Config
-	cache               *cache.Cache
-	eventBus            *hub.Hub
-	repository          repositories.ISummaryRepository
-	durationService     IDurationService
-	aliasService        IAliasService
-	projectLabelService IProjectLabelService
+	config                 *config.Config
+	cache                  *cache.Cache
+	eventBus               *hub.Hub
+	repository             repositories.ISummaryRepository
+	durationService        IDurationService
+	aliasService           IAliasService
+	projectLabelService    IProjectLabelService
+	manualTimeEntryService IManualTimeEntryService
 }
 
-type SummaryRetriever func(f, t time.Time, u *models.User, filters *models.Filters) (*models.Summary, error)
+// SummaryRetriever is an alias rather than a distinct type, so that mocks can implement ISummaryService without importing this package
+type SummaryRetriever = func(f, t time.Time, u *models.User, filters *models.Filters) (*models.Summary, error)
 
-func NewSummaryService(summaryRepo repositories.ISummaryRepository, durationService IDurationService, aliasService IAliasService, projectLabelService IProjectLabelService) *SummaryService {
+func NewSummaryService(summaryRepo repositories.ISummaryRepository, durationService IDurationService, aliasService IAliasService, projectLabelService IProjectLabelService, manualTimeEntryService IManualTimeEntryService) *SummaryService {
 	srv := &SummaryService{
-		config:              config.Get(),
-		cache:               cache.New(24*time.Hour, 24*time.Hour),
-		eventBus:            config.EventBus(),
-		repository:          summaryRepo,
-		durationService:     durationService,
-		aliasService:        aliasService,
-		projectLabelService: projectLabelService,
-	}
-
-	sub1 := srv.eventBus.Subscribe(0, config.TopicProjectLabel)
+		config:                 config.Get(),
+		cache:                  cache.New(24*time.Hour, 24*time.Hour),
+		eventBus:               config.EventBus(),
+		repository:             summaryRepo,
+		durationService:        durationService,
+		aliasService:           aliasService,
+		projectLabelService:    projectLabelService,
+		manualTimeEntryService: manualTimeEntryService,
+	}
+
+	// retrieved summaries depend on the user's aliases, language mappings, labels and manual entries
+	sub1 := srv.eventBus.Subscribe(0, config.TopicAlias, config.TopicLanguageMapping, config.TopicProjectLabel, config.TopicManualTimeEntry)
 	go func(sub *hub.Subscription) {
 		for m := range sub.Receiver {
 			userId := m.Fields[config.FieldUserId].(string)
 			for key := range srv.cache.Items() {
-				if strings.HasSuffix(key, fmt.Sprintf("__%s__--aliased", userId)) {
+				// keys are composed like <from>__<to>__<user>__<filters>__--aliased
+				if strings.HasSuffix(key, "__--aliased") && strings.Contains(key, fmt.Sprintf("__%s__", userId)) {
 					srv.cache.Delete(key)
 				}
 			}
@@ -86,6 +91,7 @@ func (srv *SummaryService) Aliased(from, to time.Time, user *models.User, f Summ
 
 	// Post-process summary and cache it
 	summary := s.WithResolvedAliases(resolveAliases)
+	summary = srv.withManualEntries(summary, from, to, filters, resolveAliases)
 	summary = srv.withProjectLabels(summary)
 	summary.FillBy(models.SummaryProject, models.SummaryLabel) // first fill up labels from projects
 	summary.FillMissing()                                      // then, full up types which are entirely missing
@@ -131,6 +137,53 @@ func (srv *SummaryService) Retrieve(from, to time.Time, user *models.User, filte
 	return summary.Sorted(), nil
 }
 
+// RetrieveBatch is equivalent to calling Retrieve without filters for every given user and adding their manual time entries, except that persisted summaries are fetched for all users in a single query.
+// Users, for whom no summary could be computed, are logged and left out of the result instead of failing the whole batch.
+func (srv *SummaryService) RetrieveBatch(from, to time.Time, users []*models.User) (map[string]*models.Summary, error) {
+	persisted, err := srv.repository.GetByUsersWithin(users, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	summariesByUser := make(map[string][]*models.Summary, len(users))
+	for _, s := range persisted {
+		summariesByUser[s.UserID] = append(summariesByUser[s.UserID], s)
+	}
+
+	// aliases don't affect totals, so manual entries' projects are taken as they are
+	resolveIdentity := func(t uint8, k string) string { return k }
+
+	results := make(map[string]*models.Summary, len(users))
+	for _, u := range users {
+		summary, err := srv.retrieveFrom(summariesByUser[u.ID], from, to, u)
+		if err != nil {
+			config.Log().Error("failed to retrieve summary for user '%s' - %v", u.ID, err)
+			continue
+		}
+		results[u.ID] = srv.withManualEntries(summary, from, to, nil, resolveIdentity).Sorted()
+	}
+
+	return results, nil
+}
+
+// retrieveFrom completes the given persisted summaries by generating missing slots from durations, just like for single retrieval, and merges them
+func (srv *SummaryService) retrieveFrom(summaries []*models.Summary, from, to time.Time, user *models.User) (*models.Summary, error) {
+	if summaries == nil {
+		summaries = make([]*models.Summary, 0)
+	}
+
+	missingIntervals := srv.getMissingIntervals(from, to, summaries)
+	for _, interval := range missingIntervals {
+		s, err := srv.Summarize(interval.Start, interval.End, user, nil)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+
+	return srv.mergeSummaries(summaries)
+}
+
 func (srv *SummaryService) Summarize(from, to time.Time, user *models.User, filters *models.Filters) (*models.Summary, error) {
 	// Initialize and fetch data
 	durations, err := srv.durationService.Get(from, to, user, filters)
@@ -143,11 +196,18 @@ func (srv *SummaryService) Summarize(from, to time.Time, user *models.User, filt
 		types = append(types, models.SummaryBranch)
 	}
 
+	// browsing activity is additionally broken down by domain and, if the user wishes so, left out of all other types
+	browsingDurations, otherDurations := durations.SplitBrowsing()
+	if !user.ExcludeBrowsing {
+		otherDurations = durations
+	}
+
 	typedAggregations := make(chan models.SummaryItemContainer)
 	defer close(typedAggregations)
 	for _, t := range types {
-		go srv.aggregateBy(durations, t, typedAggregations)
+		go srv.aggregateBy(otherDurations, t, typedAggregations)
 	}
+	go srv.aggregateBy(browsingDurations, models.SummaryDomain, typedAggregations)
 
 	// Aggregate durations (formerly raw heartbeats) by types in parallel and collect them
 	var projectItems []*models.SummaryItem
@@ -156,8 +216,11 @@ func (srv *SummaryService) Summarize(from, to time.Time, user *models.User, filt
 	var osItems []*models.SummaryItem
 	var machineItems []*models.SummaryItem
 	var branchItems []*models.SummaryItem
+	var ticketItems []*models.SummaryItem
+	var originItems []*models.SummaryItem
+	var domainItems []*models.SummaryItem
 
-	for i := 0; i < len(types); i++ {
+	for i := 0; i < len(types)+1; i++ {
 		item := <-typedAggregations
 		switch item.Type {
 		case models.SummaryProject:
@@ -172,6 +235,12 @@ func (srv *SummaryService) Summarize(from, to time.Time, user *models.User, filt
 			machineItems = item.Items
 		case models.SummaryBranch:
 			branchItems = item.Items
+		case models.SummaryTicket:
+			ticketItems = item.Items
+		case models.SummaryOrigin:
+			originItems = item.Items
+		case models.SummaryDomain:
+			domainItems = item.Items
 		}
 	}
 
@@ -190,7 +259,10 @@ func (srv *SummaryService) Summarize(from, to time.Time, user *models.User, filt
 		OperatingSystems: osItems,
 		Machines:         machineItems,
 		Branches:         branchItems,
-		NumHeartbeats:    durations.TotalNumHeartbeats(),
+		Tickets:          ticketItems,
+		Origins:          originItems,
+		Domains:          domainItems,
+		NumHeartbeats:    otherDurations.TotalNumHeartbeats(),
 	}
 
 	return summary.Sorted(), nil
@@ -207,27 +279,53 @@ func (srv *SummaryService) DeleteByUser(userId string) error {
 	return srv.repository.DeleteByUser(userId)
 }
 
+func (srv *SummaryService) DeleteByUserWithin(userId string, from, to time.Time) error {
+	srv.invalidateUserCache(userId)
+	return srv.repository.DeleteByUserWithin(userId, from, to)
+}
+
+// ReplaceWithin replaces all of the user's summaries within the given range by the given one atomically
+func (srv *SummaryService) ReplaceWithin(userId string, from, to time.Time, summary *models.Summary) error {
+	srv.invalidateUserCache(userId)
+	return srv.repository.ReplaceByUserWithin(userId, from, to, []*models.Summary{summary})
+}
+
 func (srv *SummaryService) Insert(summary *models.Summary) error {
 	srv.invalidateUserCache(summary.UserID)
 	return srv.repository.Insert(summary)
 }
 
+func (srv *SummaryService) InsertBatch(summaries []*models.Summary) error {
+	userIds := make(map[string]bool)
+	for _, s := range summaries {
+		userIds[s.UserID] = true
+	}
+	for uid := range userIds {
+		srv.invalidateUserCache(uid)
+	}
+	return srv.repository.InsertBatch(summaries)
+}
+
 // Private summary generation and utility methods
 
 func (srv *SummaryService) aggregateBy(durations []*models.Duration, summaryType uint8, c chan models.SummaryItemContainer) {
-	mapping := make(map[string]time.Duration)
+	mapping := make(map[string]*models.SummaryItem)
 
 	for _, d := range durations {
-		mapping[d.GetKey(summaryType)] += d.Duration
+		key := d.GetKey(summaryType)
+		if _, ok := mapping[key]; !ok {
+			mapping[key] = &models.SummaryItem{Key: key, Type: summaryType}
+		}
+		mapping[key].Total += d.Duration
+		mapping[key].Write += d.WriteDuration
+		mapping[key].Lines += d.LinesChanged
 	}
 
-	items := make([]*models.SummaryItem, 0)
-	for k, v := range mapping {
-		items = append(items, &models.SummaryItem{
-			Key:   k,
-			Total: v / time.Second,
-			Type:  summaryType,
-		})
+	items := make([]*models.SummaryItem, 0, len(mapping))
+	for _, item := range mapping {
+		item.Total /= time.Second
+		item.Write /= time.Second
+		items = append(items, item)
 	}
 
 	sort.Slice(items, func(i, j int) bool {
@@ -259,12 +357,21 @@ func (srv *SummaryService) withProjectLabels(summary *models.Summary) *models.Su
 
 	var totalLabelTime time.Duration
 	labelMap := make(map[string]*models.SummaryItem, 0)
+	countedProjects := make(map[string]map[string]bool) // label -> projects, to count projects with multiple child labels only once for their parents
 	for _, l := range allLabels {
 		if p, ok := mappedProjects[l.ProjectKey]; ok {
-			if _, ok2 := labelMap[l.Label]; !ok2 {
-				labelMap[l.Label] = newEntry(l.Label, 0)
+			// time of nested labels rolls up into all of their parents
+			for _, label := range append(models.LabelAncestors(l.Label), l.Label) {
+				if _, ok2 := labelMap[label]; !ok2 {
+					labelMap[label] = newEntry(label, 0)
+					countedProjects[label] = make(map[string]bool)
+				}
+				if countedProjects[label][p.Key] {
+					continue
+				}
+				countedProjects[label][p.Key] = true
+				labelMap[label].Total += p.Total
 			}
-			labelMap[l.Label].Total += p.Total
 			totalLabelTime += p.Total
 		}
 	}
@@ -280,6 +387,22 @@ func (srv *SummaryService) withProjectLabels(summary *models.Summary) *models.Su
 	return summary
 }
 
+func (srv *SummaryService) withManualEntries(summary *models.Summary, from, to time.Time, filters *models.Filters, resolve models.AliasResolver) *models.Summary {
+	allEntries, err := srv.manualTimeEntryService.GetByUserWithin(summary.UserID, from, to)
+	if err != nil {
+		logbuch.Error("failed to retrieve manual time entries for user summary ('%s', '%s', '%s')", summary.UserID, from.String(), to.String())
+		return summary
+	}
+
+	entries := make([]*models.ManualTimeEntry, 0, len(allEntries))
+	for _, e := range allEntries {
+		if e.MatchFilters(filters) {
+			entries = append(entries, e)
+		}
+	}
+	return summary.WithManualEntries(entries, resolve)
+}
+
 func (srv *SummaryService) mergeSummaries(summaries []*models.Summary) (*models.Summary, error) {
 	if len(summaries) < 1 {
 		return nil, errors.New("no summaries given")
@@ -297,6 +420,9 @@ func (srv *SummaryService) mergeSummaries(summaries []*models.Summary) (*models.
 		Machines:         make([]*models.SummaryItem, 0),
 		Labels:           make([]*models.SummaryItem, 0),
 		Branches:         make([]*models.SummaryItem, 0),
+		Tickets:          make([]*models.SummaryItem, 0),
+		Origins:          make([]*models.SummaryItem, 0),
+		Domains:          make([]*models.SummaryItem, 0),
 	}
 
 	var processed = map[time.Time]bool{}
@@ -327,6 +453,9 @@ func (srv *SummaryService) mergeSummaries(summaries []*models.Summary) (*models.
 		finalSummary.Machines = srv.mergeSummaryItems(finalSummary.Machines, s.Machines)
 		finalSummary.Labels = srv.mergeSummaryItems(finalSummary.Labels, s.Labels)
 		finalSummary.Branches = srv.mergeSummaryItems(finalSummary.Branches, s.Branches)
+		finalSummary.Tickets = srv.mergeSummaryItems(finalSummary.Tickets, s.Tickets)
+		finalSummary.Origins = srv.mergeSummaryItems(finalSummary.Origins, s.Origins)
+		finalSummary.Domains = srv.mergeSummaryItems(finalSummary.Domains, s.Domains)
 		finalSummary.NumHeartbeats += s.NumHeartbeats
 
 		processed[hash] = true
@@ -351,13 +480,15 @@ func (srv *SummaryService) mergeSummaryItems(existing []*models.SummaryItem, new
 			items[item.Key] = item
 		} else {
 			(*it).Total += item.Total
+			(*it).Write += item.Write
+			(*it).Lines += item.Lines
 		}
 	}
 
 	var i int
 	itemList := make([]*models.SummaryItem, len(items))
 	for k, v := range items {
-		itemList[i] = &models.SummaryItem{Key: k, Total: v.Total, Type: v.Type}
+		itemList[i] = &models.SummaryItem{Key: k, Total: v.Total, Write: v.Write, Lines: v.Lines, Type: v.Type}
 		i++
 	}
 
@@ -451,13 +582,20 @@ func (srv *SummaryService) getProjectLabelsReverseResolver(user *models.User) mo
 		var labels []*models.ProjectLabel
 		allLabels, err := srv.projectLabelService.GetByUserGroupedInverted(user.ID)
 		if err == nil {
-			if l, ok := allLabels[k]; ok {
-				labels = l
+			// filtering by a parent label includes projects with any of its child labels
+			for label, l := range allLabels {
+				if models.LabelIncludes(k, label) {
+					labels = append(labels, l...)
+				}
 			}
 		}
 		projectStrings := make([]string, 0, len(labels))
+		seen := make(map[string]bool, len(labels))
 		for _, l := range labels {
-			projectStrings = append(projectStrings, l.ProjectKey)
+			if !seen[l.ProjectKey] {
+				projectStrings = append(projectStrings, l.ProjectKey)
+				seen[l.ProjectKey] = true
+			}
 		}
 		return projectStrings
 	}
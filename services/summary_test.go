@@ -1,6 +1,8 @@
 package services
 
 import (
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/mocks"
 	"github.com/muety/wakapi/models"
 	"github.com/stretchr/testify/assert"
@@ -20,14 +22,15 @@ const (
 
 type SummaryServiceTestSuite struct {
 	suite.Suite
-	TestUser            *models.User
-	TestStartTime       time.Time
-	TestDurations       []*models.Duration
-	TestLabels          []*models.ProjectLabel
-	SummaryRepository   *mocks.SummaryRepositoryMock
-	DurationService     *mocks.DurationServiceMock
-	AliasService        *mocks.AliasServiceMock
-	ProjectLabelService *mocks.ProjectLabelServiceMock
+	TestUser               *models.User
+	TestStartTime          time.Time
+	TestDurations          []*models.Duration
+	TestLabels             []*models.ProjectLabel
+	SummaryRepository      *mocks.SummaryRepositoryMock
+	DurationService        *mocks.DurationServiceMock
+	AliasService           *mocks.AliasServiceMock
+	ProjectLabelService    *mocks.ProjectLabelServiceMock
+	ManualTimeEntryService *mocks.ManualTimeEntryServiceMock
 }
 
 func (suite *SummaryServiceTestSuite) SetupSuite() {
@@ -93,6 +96,7 @@ func (suite *SummaryServiceTestSuite) BeforeTest(suiteName, testName string) {
 	suite.DurationService = new(mocks.DurationServiceMock)
 	suite.AliasService = new(mocks.AliasServiceMock)
 	suite.ProjectLabelService = new(mocks.ProjectLabelServiceMock)
+	suite.ManualTimeEntryService = new(mocks.ManualTimeEntryServiceMock)
 }
 
 func TestSummaryServiceTestSuite(t *testing.T) {
@@ -100,7 +104,7 @@ func TestSummaryServiceTestSuite(t *testing.T) {
 }
 
 func (suite *SummaryServiceTestSuite) TestSummaryService_Summarize() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ManualTimeEntryService)
 
 	var (
 		from   time.Time
@@ -163,7 +167,7 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Summarize() {
 }
 
 func (suite *SummaryServiceTestSuite) TestSummaryService_Retrieve() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ManualTimeEntryService)
 
 	var (
 		summaries []*models.Summary
@@ -322,7 +326,7 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Retrieve() {
 }
 
 func (suite *SummaryServiceTestSuite) TestSummaryService_Retrieve_DuplicateSummaries() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ManualTimeEntryService)
 
 	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
 
@@ -369,8 +373,57 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Retrieve_DuplicateSumma
 	suite.DurationService.AssertNumberOfCalls(suite.T(), "Get", 2)
 }
 
+func (suite *SummaryServiceTestSuite) TestSummaryService_RetrieveBatch() {
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ManualTimeEntryService)
+
+	otherUser := &models.User{ID: "otheruser"}
+	failingUser := &models.User{ID: "failinguser"}
+	users := []*models.User{suite.TestUser, failingUser, otherUser}
+
+	from, to := suite.TestStartTime.Add(-12*time.Hour), suite.TestStartTime.Add(12*time.Hour)
+	summaries := []*models.Summary{
+		{
+			ID:       uint(rand.Uint32()),
+			UserID:   TestUserId,
+			FromTime: models.CustomTime(from),
+			ToTime:   models.CustomTime(to),
+			Projects: []*models.SummaryItem{
+				{
+					Type:  models.SummaryProject,
+					Key:   TestProject1,
+					Total: 45 * time.Minute / time.Second, // hack
+				},
+			},
+			Languages:        []*models.SummaryItem{},
+			Editors:          []*models.SummaryItem{},
+			OperatingSystems: []*models.SummaryItem{},
+			Machines:         []*models.SummaryItem{},
+		},
+	}
+
+	suite.SummaryRepository.On("GetByUsersWithin", users, from, to).Return(summaries, nil)
+	suite.DurationService.On("Get", from, to, otherUser, mock.Anything).Return(models.Durations(suite.TestDurations), nil)
+	suite.DurationService.On("Get", from, to, failingUser, mock.Anything).Return(models.Durations{}, assert.AnError)
+	suite.ManualTimeEntryService.On("GetByUserWithin", TestUserId, from, to).Return([]*models.ManualTimeEntry{
+		{UserID: TestUserId, Project: TestProject2, Duration: 10 * time.Minute},
+	}, nil)
+	suite.ManualTimeEntryService.On("GetByUserWithin", otherUser.ID, from, to).Return([]*models.ManualTimeEntry{}, nil)
+
+	result, err := sut.RetrieveBatch(from, to, users)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 2) // failing user is skipped, but doesn't fail the others
+	assert.NotContains(suite.T(), result, failingUser.ID)
+	assert.Equal(suite.T(), 55*time.Minute, result[TestUserId].TotalTime()) // including manual entries
+	assert.Equal(suite.T(), otherUser.ID, result[otherUser.ID].UserID)
+	assert.Equal(suite.T(), 185*time.Second, result[otherUser.ID].TotalTime())
+	suite.SummaryRepository.AssertNumberOfCalls(suite.T(), "GetByUsersWithin", 1)
+	suite.SummaryRepository.AssertNotCalled(suite.T(), "GetByUserWithin", mock.Anything, mock.Anything, mock.Anything)
+	suite.DurationService.AssertNumberOfCalls(suite.T(), "Get", 2)
+}
+
 func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ManualTimeEntryService)
 
 	suite.AliasService.On("InitializeUser", suite.TestUser.ID).Return(nil)
 	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
@@ -402,6 +455,7 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased() {
 	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject2).Return(TestProject2, nil)
 	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, mock.Anything).Return("", nil)
 	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return(suite.TestLabels, nil).Once()
+	suite.ManualTimeEntryService.On("GetByUserWithin", TestUserId, from, to).Return([]*models.ManualTimeEntry{}, nil)
 
 	result, err = sut.Aliased(from, to, suite.TestUser, sut.Summarize, nil, false)
 
@@ -414,7 +468,7 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased() {
 }
 
 func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased_ProjectLabels() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ManualTimeEntryService)
 
 	var (
 		from   time.Time
@@ -443,6 +497,7 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased_ProjectLabels()
 	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject1).Return(TestProject1, nil)
 	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject2).Return(TestProject1, nil)
 	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, mock.Anything).Return("", nil)
+	suite.ManualTimeEntryService.On("GetByUserWithin", TestUserId, from, to).Return([]*models.ManualTimeEntry{}, nil)
 
 	result, err = sut.Aliased(from, to, suite.TestUser, sut.Summarize, nil, false)
 
@@ -452,8 +507,87 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased_ProjectLabels()
 	assert.Equal(suite.T(), 6, result.NumHeartbeats)
 }
 
+func (suite *SummaryServiceTestSuite) TestSummaryService_NestedProjectLabels() {
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ManualTimeEntryService)
+
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{
+		{UserID: TestUserId, ProjectKey: TestProject1, Label: "work/client-a"},
+		{UserID: TestUserId, ProjectKey: TestProject1, Label: "work/client-b"},
+		{UserID: TestUserId, ProjectKey: TestProject2, Label: "work/client-b"},
+	}, nil).Once()
+
+	summary := &models.Summary{
+		UserID: TestUserId,
+		Projects: []*models.SummaryItem{
+			{Type: models.SummaryProject, Key: TestProject1, Total: 60},
+			{Type: models.SummaryProject, Key: TestProject2, Total: 30},
+		},
+	}
+
+	result := sut.withProjectLabels(summary)
+
+	assert.Len(suite.T(), result.Labels, 3)
+	assert.Equal(suite.T(), 60*time.Second, result.TotalTimeByKey(models.SummaryLabel, "work/client-a"))
+	assert.Equal(suite.T(), 90*time.Second, result.TotalTimeByKey(models.SummaryLabel, "work/client-b"))
+	assert.Equal(suite.T(), 90*time.Second, result.TotalTimeByKey(models.SummaryLabel, "work")) // project 1 counted only once
+}
+
+func (suite *SummaryServiceTestSuite) TestSummaryService_NestedProjectLabels_Filters() {
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ManualTimeEntryService)
+
+	suite.ProjectLabelService.On("GetByUserGroupedInverted", suite.TestUser.ID).Return(map[string][]*models.ProjectLabel{
+		"work/client-a": {{ProjectKey: TestProject1, Label: "work/client-a"}},
+		"work/client-b": {{ProjectKey: TestProject1, Label: "work/client-b"}, {ProjectKey: TestProject2, Label: "work/client-b"}},
+		"workshop":      {{ProjectKey: TestProject3, Label: "workshop"}},
+	}, nil)
+
+	resolve := sut.getProjectLabelsReverseResolver(suite.TestUser)
+
+	assert.ElementsMatch(suite.T(), []string{TestProject1, TestProject2}, resolve("work"))
+	assert.ElementsMatch(suite.T(), []string{TestProject1}, resolve("work/client-a"))
+	assert.Empty(suite.T(), resolve("client-a"))
+}
+
+func (suite *SummaryServiceTestSuite) TestSummaryService_Aliased_ManualEntries() {
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ManualTimeEntryService)
+
+	from, to := suite.TestStartTime, suite.TestStartTime.Add(1*time.Hour)
+	durations := filterDurations(from, to, suite.TestDurations)
+
+	suite.DurationService.On("Get", from, to, suite.TestUser, mock.Anything).Return(durations, nil)
+	suite.AliasService.On("InitializeUser", TestUserId).Return(nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject1).Return(TestProject1, nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, TestProject2).Return(TestProject2, nil)
+	suite.AliasService.On("GetAliasOrDefault", TestUserId, mock.Anything, mock.Anything).Return("", nil)
+	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
+	suite.ManualTimeEntryService.On("GetByUserWithin", TestUserId, from, to).Return([]*models.ManualTimeEntry{
+		{
+			UserID:   TestUserId,
+			Project:  TestProject1,
+			Date:     models.CustomTime(from),
+			Duration: 30 * time.Minute,
+		},
+		{
+			UserID:   TestUserId,
+			Project:  TestProject2,
+			Date:     models.CustomTime(from),
+			Duration: 15 * time.Minute,
+		},
+	}, nil)
+
+	result, err := sut.Aliased(from, to, suite.TestUser, sut.Summarize, nil, false)
+
+	assert.Nil(suite.T(), err)
+	assert.NotNil(suite.T(), result)
+	assert.Equal(suite.T(), 185*time.Second+30*time.Minute, result.TotalTimeByKey(models.SummaryProject, TestProject1))
+	assert.Equal(suite.T(), 15*time.Minute, result.TotalTimeByKey(models.SummaryProject, TestProject2))
+	assert.Equal(suite.T(), 45*time.Minute, result.TotalTimeByKey(models.SummaryLanguage, models.UnknownSummaryKey))
+	assert.Equal(suite.T(), 45*time.Minute, result.TotalManualTime())
+	assert.Len(suite.T(), result.ManualProjects, 2)
+}
+
 func (suite *SummaryServiceTestSuite) TestSummaryService_Filters() {
-	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService)
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ManualTimeEntryService)
 
 	suite.AliasService.On("InitializeUser", suite.TestUser.ID).Return(nil)
 	suite.ProjectLabelService.On("GetByUser", suite.TestUser.ID).Return([]*models.ProjectLabel{}, nil)
@@ -474,6 +608,7 @@ func (suite *SummaryServiceTestSuite) TestSummaryService_Filters() {
 		suite.TestLabels[0].Label: suite.TestLabels[0:1],
 		suite.TestLabels[1].Label: suite.TestLabels[1:2],
 	}, nil).Once()
+	suite.ManualTimeEntryService.On("GetByUserWithin", TestUserId, from, to).Return([]*models.ManualTimeEntry{}, nil)
 
 	result, _ := sut.Aliased(from, to, suite.TestUser, sut.Summarize, filters, false)
 	assert.NotNil(suite.T(), result.Branches) // project filters were applied -> include branches
@@ -512,3 +647,24 @@ func assertNumAllItems(t *testing.T, expected int, summary *models.Summary, exce
 		assert.Len(t, summary.Machines, expected)
 	}
 }
+
+func (suite *SummaryServiceTestSuite) TestSummaryService_InvalidatesCacheOnAliasChange() {
+	sut := NewSummaryService(suite.SummaryRepository, suite.DurationService, suite.AliasService, suite.ProjectLabelService, suite.ManualTimeEntryService)
+
+	cacheKey := sut.getHash("from", "to", TestUserId, (*models.Filters)(nil).Hash(), "--aliased")
+	otherCacheKey := sut.getHash("from", "to", "otheruser", (*models.Filters)(nil).Hash(), "--aliased")
+	sut.cache.SetDefault(cacheKey, &models.Summary{})
+	sut.cache.SetDefault(otherCacheKey, &models.Summary{})
+
+	config.EventBus().Publish(hub.Message{
+		Name:   config.EventAliasCreate,
+		Fields: map[string]interface{}{config.FieldPayload: &models.Alias{UserID: TestUserId}, config.FieldUserId: TestUserId},
+	})
+
+	assert.Eventually(suite.T(), func() bool {
+		_, found := sut.cache.Get(cacheKey)
+		return !found
+	}, time.Second, 10*time.Millisecond)
+	_, found := sut.cache.Get(otherCacheKey)
+	assert.True(suite.T(), found) // other users' summaries are kept
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+type TicketService struct {
+	config         *config.Config
+	summaryService ISummaryService
+}
+
+func NewTicketService(summaryService ISummaryService) *TicketService {
+	return &TicketService{
+		config:         config.Get(),
+		summaryService: summaryService,
+	}
+}
+
+// GetWorklog returns the time spent per ticket and day within the given range, split at midnight in the time zone of the given times.
+// Time without any ticket (i.e. on branches without issue key) is left out.
+func (srv *TicketService) GetWorklog(from, to time.Time, user *models.User) ([]*models.WorklogEntry, error) {
+	entries := make([]*models.WorklogEntry, 0)
+
+	for _, interval := range utils.SplitRangeByDays(from, to) {
+		summary, err := srv.summaryService.Aliased(interval[0], interval[1], user, srv.summaryService.Retrieve, nil, false)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range summary.Tickets {
+			if item.Key == models.UnknownSummaryKey || item.Total <= 0 {
+				continue
+			}
+			entries = append(entries, &models.WorklogEntry{
+				Date:             utils.StartOfDay(interval[0]),
+				Ticket:           item.Key,
+				TimeSpentSeconds: int64(item.TotalFixed().Seconds()),
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Date.Equal(entries[j].Date) {
+			return entries[i].Date.Before(entries[j].Date)
+		}
+		return entries[i].Ticket < entries[j].Ticket
+	})
+
+	return entries, nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/utils"
+)
+
+type TimesheetService struct {
+	config          *config.Config
+	durationService IDurationService
+	aliasService    IAliasService
+}
+
+func NewTimesheetService(durationService IDurationService, aliasService IAliasService) *TimesheetService {
+	return &TimesheetService{
+		config:          config.Get(),
+		durationService: durationService,
+		aliasService:    aliasService,
+	}
+}
+
+// GetWeek returns the time spent per project on every day of the week, which contains the given date (in the user's time zone).
+// It is computed from durations, which are split at midnight, if they span multiple days.
+func (srv *TimesheetService) GetWeek(date time.Time, user *models.User) (*models.Timesheet, error) {
+	from := utils.StartOfWeek(date.In(user.TZ()))
+	to := from.AddDate(0, 0, 7)
+
+	days := make([]time.Time, 7)
+	for i := range days {
+		days[i] = from.AddDate(0, 0, i)
+	}
+
+	durations, err := srv.durationService.Get(from, to, user, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	projectDurations := make(map[string][]time.Duration)
+
+	for _, d := range durations {
+		project, err := srv.aliasService.GetAliasOrDefault(user.ID, models.SummaryProject, d.Project)
+		if err != nil {
+			return nil, err
+		}
+		if project == "" {
+			project = models.UnknownSummaryKey
+		}
+		if _, ok := projectDurations[project]; !ok {
+			projectDurations[project] = make([]time.Duration, len(days))
+		}
+
+		start, end := d.Time.T(), d.Time.T().Add(d.Duration)
+		for i, dayStart := range days {
+			overlapStart, overlapEnd := start, end
+			if dayStart.After(overlapStart) {
+				overlapStart = dayStart
+			}
+			if dayEnd := dayStart.AddDate(0, 0, 1); dayEnd.Before(overlapEnd) {
+				overlapEnd = dayEnd
+			}
+			if overlapEnd.After(overlapStart) {
+				projectDurations[project][i] += overlapEnd.Sub(overlapStart)
+			}
+		}
+	}
+
+	sheet := models.NewTimesheet(from, to, days, projectDurations)
+	sort.Slice(sheet.Rows, func(i, j int) bool {
+		if sheet.Rows[i].Total != sheet.Rows[j].Total {
+			return sheet.Rows[i].Total > sheet.Rows[j].Total
+		}
+		return sheet.Rows[i].Project < sheet.Rows[j].Project
+	})
+
+	return sheet, nil
+}
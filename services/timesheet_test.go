@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type TimesheetServiceTestSuite struct {
+	suite.Suite
+	TestUser        *models.User
+	DurationService *mocks.DurationServiceMock
+	AliasService    *mocks.AliasServiceMock
+}
+
+func (suite *TimesheetServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: "johndoe", Location: "UTC"}
+}
+
+func (suite *TimesheetServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.DurationService = new(mocks.DurationServiceMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	suite.AliasService.On("GetAliasOrDefault", suite.TestUser.ID, models.SummaryProject, "wakapi").Return("wakapi", nil)
+	suite.AliasService.On("GetAliasOrDefault", suite.TestUser.ID, models.SummaryProject, "wakapi-fork").Return("wakapi", nil)
+	suite.AliasService.On("GetAliasOrDefault", suite.TestUser.ID, models.SummaryProject, "anchr").Return("anchr", nil)
+}
+
+func TestTimesheetServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(TimesheetServiceTestSuite))
+}
+
+func (suite *TimesheetServiceTestSuite) TestTimesheetService_GetWeek() {
+	monday := time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC)
+	durations := models.Durations{
+		{Time: models.CustomTime(monday.Add(9 * time.Hour)), Duration: 90 * time.Minute, Project: "wakapi"},
+		{Time: models.CustomTime(monday.Add(11 * time.Hour)), Duration: 30 * time.Minute, Project: "wakapi-fork"}, // aliased
+		{Time: models.CustomTime(monday.Add(14 * time.Hour)), Duration: 15 * time.Minute, Project: "anchr"},
+		// across midnight from tuesday to wednesday
+		{Time: models.CustomTime(monday.Add(47 * time.Hour)), Duration: 2 * time.Hour, Project: "wakapi"},
+	}
+	suite.DurationService.On("Get", mock.Anything, mock.Anything, suite.TestUser, mock.Anything).Return(durations, nil)
+
+	sut := NewTimesheetService(suite.DurationService, suite.AliasService)
+
+	result, err := sut.GetWeek(monday.AddDate(0, 0, 3), suite.TestUser) // any day of the week
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), monday, result.From.UTC())
+	assert.Equal(suite.T(), monday.AddDate(0, 0, 7), result.To)
+	assert.Len(suite.T(), result.Days, 7)
+	assert.Len(suite.T(), result.Rows, 2)
+
+	assert.Equal(suite.T(), "wakapi", result.Rows[0].Project)
+	assert.Equal(suite.T(), []float64{2, 1, 1, 0, 0, 0, 0}, result.Rows[0].Hours)
+	assert.Equal(suite.T(), 4.0, result.Rows[0].Total)
+	assert.Equal(suite.T(), "anchr", result.Rows[1].Project)
+	assert.Equal(suite.T(), []float64{0.25, 0, 0, 0, 0, 0, 0}, result.Rows[1].Hours)
+
+	assert.Equal(suite.T(), []float64{2.25, 1, 1, 0, 0, 0, 0}, result.Totals)
+	assert.Equal(suite.T(), 4.25, result.Total)
+}
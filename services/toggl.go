@@ -0,0 +1,78 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+)
+
+const togglCreatedWith = "wakapi"
+
+type TogglService struct {
+	config          *config.Config
+	durationService IDurationService
+	aliasService    IAliasService
+}
+
+func NewTogglService(durationService IDurationService, aliasService IAliasService) *TogglService {
+	return &TogglService{
+		config:          config.Get(),
+		durationService: durationService,
+		aliasService:    aliasService,
+	}
+}
+
+// GetTimeEntries merges the user's durations within the given range into time entries, one per uninterrupted block of work on a project and branch.
+// Durations are considered uninterrupted, if they are at most HeartbeatDiffThreshold apart, i.e. the same timeout as used for computing durations from heartbeats.
+// The branch (if any) is used as the entry's description.
+func (srv *TogglService) GetTimeEntries(from, to time.Time, user *models.User) ([]*models.TogglTimeEntry, error) {
+	durations, err := srv.durationService.Get(from, to, user, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*models.TogglTimeEntry, 0)
+	latest := make(map[string]*models.TogglTimeEntry) // latest entry per project and branch
+
+	for _, d := range durations {
+		project, err := srv.aliasService.GetAliasOrDefault(user.ID, models.SummaryProject, d.Project)
+		if err != nil {
+			return nil, err
+		}
+		if project == "" {
+			project = models.UnknownSummaryKey
+		}
+
+		start, stop := d.Time.T(), d.Time.T().Add(d.Duration)
+		key := project + "__" + d.Branch
+
+		if e, ok := latest[key]; ok && !start.After(e.Stop.Add(HeartbeatDiffThreshold)) {
+			if stop.After(e.Stop) {
+				e.Stop = stop
+			}
+			continue
+		}
+
+		e := &models.TogglTimeEntry{
+			Description: d.Branch,
+			Project:     project,
+			Start:       start,
+			Stop:        stop,
+			CreatedWith: togglCreatedWith,
+		}
+		latest[key] = e
+		entries = append(entries, e)
+	}
+
+	for _, e := range entries {
+		e.Duration = int64(e.Stop.Sub(e.Start).Seconds())
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Start.Before(entries[j].Start)
+	})
+
+	return entries, nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type TogglServiceTestSuite struct {
+	suite.Suite
+	TestUser        *models.User
+	TestStartTime   time.Time
+	DurationService *mocks.DurationServiceMock
+	AliasService    *mocks.AliasServiceMock
+}
+
+func (suite *TogglServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: "johndoe"}
+	suite.TestStartTime = time.Date(2022, 1, 10, 9, 0, 0, 0, time.UTC)
+}
+
+func (suite *TogglServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.DurationService = new(mocks.DurationServiceMock)
+	suite.AliasService = new(mocks.AliasServiceMock)
+	suite.AliasService.On("GetAliasOrDefault", suite.TestUser.ID, models.SummaryProject, "wakapi").Return("wakapi", nil)
+	suite.AliasService.On("GetAliasOrDefault", suite.TestUser.ID, models.SummaryProject, "wakapi-fork").Return("wakapi", nil)
+	suite.AliasService.On("GetAliasOrDefault", suite.TestUser.ID, models.SummaryProject, "").Return("", nil)
+}
+
+func TestTogglServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(TogglServiceTestSuite))
+}
+
+func (suite *TogglServiceTestSuite) TestTogglService_GetTimeEntries() {
+	t0 := suite.TestStartTime
+	durations := models.Durations{
+		// two languages on the same branch, interleaved -> one entry
+		{Time: models.CustomTime(t0), Duration: 10 * time.Minute, Project: "wakapi", Branch: "master", Language: "Go"},
+		{Time: models.CustomTime(t0.Add(10 * time.Minute)), Duration: 5 * time.Minute, Project: "wakapi", Branch: "master", Language: "HTML"},
+		{Time: models.CustomTime(t0.Add(16 * time.Minute)), Duration: 4 * time.Minute, Project: "wakapi-fork", Branch: "master", Language: "Go"}, // aliased
+		// other branch -> new entry
+		{Time: models.CustomTime(t0.Add(20 * time.Minute)), Duration: 10 * time.Minute, Project: "wakapi", Branch: "feature", Language: "Go"},
+		// break of more than the timeout -> new entry
+		{Time: models.CustomTime(t0.Add(60 * time.Minute)), Duration: 30 * time.Second, Project: "wakapi", Branch: "master", Language: "Go"},
+		// no project
+		{Time: models.CustomTime(t0.Add(90 * time.Minute)), Duration: 2 * time.Minute, Project: "", Branch: "", Language: "Go"},
+	}
+	suite.DurationService.On("Get", mock.Anything, mock.Anything, suite.TestUser, mock.Anything).Return(durations, nil)
+
+	sut := NewTogglService(suite.DurationService, suite.AliasService)
+
+	result, err := sut.GetTimeEntries(t0, t0.Add(24*time.Hour), suite.TestUser)
+
+	assert.Nil(suite.T(), err)
+	assert.Len(suite.T(), result, 4)
+
+	assert.Equal(suite.T(), "wakapi", result[0].Project)
+	assert.Equal(suite.T(), "master", result[0].Description)
+	assert.Equal(suite.T(), t0, result[0].Start)
+	assert.Equal(suite.T(), t0.Add(20*time.Minute), result[0].Stop)
+	assert.Equal(suite.T(), int64(1200), result[0].Duration)
+	assert.Equal(suite.T(), "00:20:00", result[0].FmtDuration())
+	assert.Equal(suite.T(), "wakapi", result[0].CreatedWith)
+
+	assert.Equal(suite.T(), "feature", result[1].Description)
+	assert.Equal(suite.T(), int64(600), result[1].Duration)
+
+	assert.Equal(suite.T(), "master", result[2].Description)
+	assert.Equal(suite.T(), t0.Add(60*time.Minute), result[2].Start)
+	assert.Equal(suite.T(), int64(30), result[2].Duration)
+
+	assert.Equal(suite.T(), models.UnknownSummaryKey, result[3].Project)
+	assert.Equal(suite.T(), "", result[3].Description)
+}
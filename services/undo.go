@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/emvi/logbuch"
+	"github.com/go-co-op/gocron"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+)
+
+var ErrUndoInProgress = errors.New("this operation is already being undone")
+
+// UndoService keeps copies of heartbeats affected by destructive operations, i.e. deletions and reassignments, for a configurable window,
+// during which these operations can be undone
+type UndoService struct {
+	config              *config.Config
+	eventBus            *hub.Hub
+	heartbeatRepository repositories.IHeartbeatRepository
+	tombstoneRepository repositories.ITombstoneRepository
+	jobService          IJobService
+	undoLock            *sync.Mutex
+	undoing             map[uint]bool
+}
+
+func NewUndoService(heartbeatRepository repositories.IHeartbeatRepository, tombstoneRepository repositories.ITombstoneRepository, jobService IJobService) *UndoService {
+	return &UndoService{
+		config:              config.Get(),
+		eventBus:            config.EventBus(),
+		heartbeatRepository: heartbeatRepository,
+		tombstoneRepository: tombstoneRepository,
+		jobService:          jobService,
+		undoLock:            &sync.Mutex{},
+		undoing:             make(map[uint]bool),
+	}
+}
+
+func (srv *UndoService) Schedule() {
+	s := gocron.NewScheduler(time.Local)
+	s.Every(1).Hour().Do(srv.purge)
+	s.StartBlocking()
+}
+
+func (srv *UndoService) IsEnabled() bool {
+	return srv.config.App.GetUndoWindow() > 0
+}
+
+// Begin creates a tombstone for an operation of the given kind, which is about to be run, or returns nil if undo is disabled
+func (srv *UndoService) Begin(user *models.User, kind string) (*models.Tombstone, error) {
+	if !srv.IsEnabled() {
+		return nil, nil
+	}
+	return srv.tombstoneRepository.Insert(models.NewTombstone(user.ID, kind, srv.config.App.GetUndoWindow()))
+}
+
+// Record keeps copies of the given heartbeats as they are now, i.e. before they get changed or deleted by the tombstone's operation
+func (srv *UndoService) Record(tombstone *models.Tombstone, heartbeats []*models.Heartbeat) error {
+	if tombstone == nil {
+		return nil
+	}
+	items := make([]*models.TombstoneItem, len(heartbeats))
+	for i, hb := range heartbeats {
+		items[i] = models.NewTombstoneItem(tombstone, hb)
+	}
+	if err := srv.tombstoneRepository.InsertItems(tombstone, items); err != nil {
+		return err
+	}
+	tombstone.NumHeartbeats += len(items)
+	return nil
+}
+
+// Discard drops a tombstone, e.g. because its operation failed before changing anything
+func (srv *UndoService) Discard(tombstone *models.Tombstone) error {
+	if tombstone == nil {
+		return nil
+	}
+	return srv.tombstoneRepository.Delete(tombstone.ID)
+}
+
+// GetByUser returns all of the user's operations, which can still be undone, latest first
+func (srv *UndoService) GetByUser(user *models.User) ([]*models.Tombstone, error) {
+	return srv.tombstoneRepository.GetByUser(user.ID)
+}
+
+// Undo asynchronously restores all heartbeats affected by the given operation to their previous state and drops its tombstone afterwards.
+// Returns nil, if the operation does not exist, belongs to another user or cannot be undone anymore.
+func (srv *UndoService) Undo(user *models.User, id uint) (*models.Tombstone, error) {
+	tombstone, err := srv.tombstoneRepository.GetById(id)
+	if err != nil || tombstone.UserID != user.ID || tombstone.IsExpired() {
+		return nil, nil
+	}
+
+	srv.undoLock.Lock()
+	if srv.undoing[id] {
+		srv.undoLock.Unlock()
+		return nil, ErrUndoInProgress
+	}
+	srv.undoing[id] = true
+	srv.undoLock.Unlock()
+
+	go func() {
+		defer func() {
+			srv.undoLock.Lock()
+			delete(srv.undoing, id)
+			srv.undoLock.Unlock()
+		}()
+
+		if err := srv.jobService.Track(models.JobUndo, user.ID, func() error {
+			return srv.restore(user, tombstone)
+		}); err != nil {
+			config.Log().Error("failed to undo %s %d of user '%s' - %v", tombstone.Kind, id, user.ID, err)
+		}
+	}()
+
+	return tombstone, nil
+}
+
+// restore is safe to be retried after failing half-way, as restored heartbeats are skipped on re-insertion and updates are idempotent
+func (srv *UndoService) restore(user *models.User, tombstone *models.Tombstone) error {
+	// one heartbeat per affected day is enough to have summaries recomputed
+	byDay := make(map[string]*models.Heartbeat)
+	defer func() {
+		for _, hb := range byDay {
+			srv.eventBus.Publish(hub.Message{
+				Name:   config.EventHeartbeatUpdate,
+				Fields: map[string]interface{}{config.FieldPayload: hb, config.FieldUserId: user.ID},
+			})
+		}
+	}()
+
+	var afterId uint64
+	for {
+		items, err := srv.tombstoneRepository.GetItemsPage(tombstone.ID, afterId, heartbeatPageSize)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			break
+		}
+		afterId = items[len(items)-1].ID
+
+		heartbeats := make([]*models.Heartbeat, len(items))
+		for i, item := range items {
+			heartbeats[i] = item.ToHeartbeat()
+			heartbeats[i].User = user
+		}
+
+		switch tombstone.Kind {
+		case models.TombstoneHeartbeatDeletion:
+			if err := srv.heartbeatRepository.InsertBatch(heartbeats); err != nil {
+				return err
+			}
+			for _, hb := range heartbeats {
+				srv.eventBus.Publish(hub.Message{
+					Name:   config.EventHeartbeatCreate,
+					Fields: map[string]interface{}{config.FieldPayload: hb},
+				})
+			}
+		case models.TombstoneReassignment:
+			for _, hb := range heartbeats {
+				if _, err := srv.heartbeatRepository.Update(hb); err != nil {
+					return err
+				}
+				byDay[hb.Time.T().Format(config.SimpleDateFormat)] = hb
+			}
+		}
+	}
+
+	logbuch.Info("undid %s %d of user '%s' (%d heartbeats)", tombstone.Kind, tombstone.ID, user.ID, tombstone.NumHeartbeats)
+	return srv.tombstoneRepository.Delete(tombstone.ID)
+}
+
+func (srv *UndoService) purge() {
+	if err := srv.tombstoneRepository.DeleteExpired(time.Now()); err != nil {
+		config.Log().Error("failed to purge expired tombstones - %v", err)
+	}
+}
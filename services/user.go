@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"github.com/emvi/logbuch"
 	"github.com/leandro-lugaresi/hub"
@@ -14,20 +15,24 @@ import (
 )
 
 type UserService struct {
-	config      *config.Config
-	cache       *cache.Cache
-	eventBus    *hub.Hub
-	mailService IMailService
-	repository  repositories.IUserRepository
+	config              *config.Config
+	cache               *cache.Cache
+	keyCache            *cache.Cache // api key -> user id, to save a database query on every single heartbeat request
+	eventBus            *hub.Hub
+	mailService         IMailService
+	notificationService INotificationService
+	repository          repositories.IUserRepository
 }
 
-func NewUserService(mailService IMailService, userRepo repositories.IUserRepository) *UserService {
+func NewUserService(mailService IMailService, notificationService INotificationService, userRepo repositories.IUserRepository) *UserService {
 	srv := &UserService{
-		config:      config.Get(),
-		eventBus:    config.EventBus(),
-		cache:       cache.New(1*time.Hour, 2*time.Hour),
-		mailService: mailService,
-		repository:  userRepo,
+		config:              config.Get(),
+		eventBus:            config.EventBus(),
+		cache:               cache.New(1*time.Hour, 2*time.Hour),
+		keyCache:            cache.New(24*time.Hour, 24*time.Hour),
+		mailService:         mailService,
+		notificationService: notificationService,
+		repository:          userRepo,
 	}
 
 	sub1 := srv.eventBus.Subscribe(0, config.EventWakatimeFailure)
@@ -42,16 +47,30 @@ func NewUserService(mailService IMailService, userRepo repositories.IUserReposit
 				logbuch.Error("failed to set wakatime api key for user %s", user.ID)
 			}
 
-			if user.Email != "" {
+			if notificationService.IsEnabled(user, models.NotificationEventWakatimeFailure, models.NotificationChannelEmail) {
 				if err := mailService.SendWakatimeFailureNotification(user, n); err != nil {
 					logbuch.Error("failed to send wakatime failure notification mail to user %s", user.ID)
 				} else {
 					logbuch.Info("sent wakatime connection failure mail to %s", user.ID)
 				}
 			}
+
+			notificationService.Notify(user, &models.Notification{
+				Event: models.NotificationEventWakatimeFailure,
+				Title: "WakaTime connection failure",
+				Text:  fmt.Sprintf("Wakapi failed to forward heartbeats to WakaTime %d times in a row, so the connection was disabled. Please check your WakaTime API key and re-enable the connection in your settings.", n),
+				Link:  srv.config.Server.GetPublicUrl() + "/settings#integrations",
+			})
 		}
 	}(&sub1)
 
+	sub2 := srv.eventBus.Subscribe(0, config.EventApiKeyRevoke)
+	go func(sub *hub.Subscription) {
+		for m := range sub.Receiver {
+			srv.keyCache.Delete(m.Fields[config.FieldPayload].(string))
+		}
+	}(&sub2)
+
 	return srv
 }
 
@@ -70,8 +89,12 @@ func (srv *UserService) GetUserById(userId string) (*models.User, error) {
 }
 
 func (srv *UserService) GetUserByKey(key string) (*models.User, error) {
-	if u, ok := srv.cache.Get(key); ok {
-		return u.(*models.User), nil
+	if userId, ok := srv.keyCache.Get(key); ok {
+		// users are cached by id until changed, so the key is double-checked in case the mapping is outdated
+		if u, err := srv.GetUserById(userId.(string)); err == nil && u.ApiKey == key {
+			return u, nil
+		}
+		srv.keyCache.Delete(key)
 	}
 
 	u, err := srv.repository.GetByApiKey(key)
@@ -80,6 +103,7 @@ func (srv *UserService) GetUserByKey(key string) (*models.User, error) {
 	}
 
 	srv.cache.SetDefault(u.ID, u)
+	srv.keyCache.SetDefault(key, u.ID)
 	return u, nil
 }
 
@@ -144,14 +168,34 @@ func (srv *UserService) CreateOrGet(signup *models.Signup, isAdmin bool) (*model
 
 func (srv *UserService) Update(user *models.User) (*models.User, error) {
 	srv.cache.Flush()
-	srv.notifyUpdate(user)
+	srv.notify(config.EventUserUpdate, user)
 	return srv.repository.Update(user)
 }
 
 func (srv *UserService) ResetApiKey(user *models.User) (*models.User, error) {
 	srv.cache.Flush()
+	oldKey := user.ApiKey
 	user.ApiKey = uuid.NewV4().String()
-	return srv.Update(user)
+	u, err := srv.Update(user)
+	if err == nil {
+		srv.revokeApiKey(oldKey)
+	}
+	return u, err
+}
+
+// SetDeactivated (de-)activates the user, whereby deactivation also revokes the user's api key
+func (srv *UserService) SetDeactivated(user *models.User, deactivated bool) (*models.User, error) {
+	srv.cache.Flush()
+	oldKey := user.ApiKey
+	if deactivated && !user.Deactivated {
+		user.ApiKey = uuid.NewV4().String()
+	}
+	user.Deactivated = deactivated
+	u, err := srv.Update(user)
+	if err == nil && user.ApiKey != oldKey {
+		srv.revokeApiKey(oldKey)
+	}
+	return u, err
 }
 
 func (srv *UserService) SetWakatimeApiCredentials(user *models.User, apiKey string, apiUrl string) (*models.User, error) {
@@ -181,6 +225,29 @@ func (srv *UserService) MigrateMd5Password(user *models.User, login *models.Logi
 	return srv.repository.UpdateField(user, "password", user.Password)
 }
 
+// SetPassword validates, hashes and stores the given password and invalidates pending password resets
+func (srv *UserService) SetPassword(user *models.User, password string) (*models.User, error) {
+	if !models.ValidatePassword(password) {
+		return nil, errors.New("password must be at least 6 characters long")
+	}
+
+	hash, err := utils.HashBcrypt(password, srv.config.Security.PasswordSalt)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = hash
+	user.ResetToken = ""
+	return srv.Update(user)
+}
+
+// SetAdmin grants or revokes admin privileges, which are deliberately not touched by Update
+func (srv *UserService) SetAdmin(user *models.User, isAdmin bool) (*models.User, error) {
+	srv.cache.Flush()
+	user.IsAdmin = isAdmin
+	return srv.repository.UpdateField(user, "is_admin", isAdmin)
+}
+
 func (srv *UserService) GenerateResetToken(user *models.User) (*models.User, error) {
 	return srv.repository.UpdateField(user, "reset_token", uuid.NewV4())
 }
@@ -188,19 +255,63 @@ func (srv *UserService) GenerateResetToken(user *models.User) (*models.User, err
 func (srv *UserService) Delete(user *models.User) error {
 	srv.cache.Flush()
 
-	user.ReportsWeekly = false
-	srv.notifyUpdate(user)
+	if err := srv.repository.Delete(user); err != nil {
+		return err
+	}
+	srv.notify(config.EventUserDelete, user)
+	srv.revokeApiKey(user.ApiKey)
+	return nil
+}
+
+// Merge moves all data of one user to another one, e.g. to consolidate an account created via sso with a legacy one, and deletes it afterwards.
+// Days affected by moved heartbeats are recomputed during the next aggregation run. In a dry run, nothing is changed, but the report tells what would be done.
+func (srv *UserService) Merge(merge *models.UserMerge) (*models.UserMergeReport, error) {
+	if err := merge.Validate(); err != nil {
+		return nil, err
+	}
+
+	source, err := srv.repository.GetById(merge.SourceId)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("user '%s' not found", merge.SourceId))
+	}
+	target, err := srv.repository.GetById(merge.TargetId)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("user '%s' not found", merge.TargetId))
+	}
+
+	report, err := srv.repository.Merge(merge)
+	if err != nil || report.DryRun {
+		return report, err
+	}
 
-	return srv.repository.Delete(user)
+	srv.FlushCache()
+	srv.notify(config.EventUserDelete, source)
+	srv.notify(config.EventUserUpdate, target)
+	srv.revokeApiKey(source.ApiKey)
+	if report.ApiKeyAdopted {
+		srv.revokeApiKey(target.ApiKey)
+	}
+
+	logbuch.Info("merged user '%s' into '%s' (%d heartbeats, %d summaries moved)", source.ID, target.ID, report.Heartbeats, report.Summaries)
+	return report, nil
 }
 
 func (srv *UserService) FlushCache() {
 	srv.cache.Flush()
+	srv.keyCache.Flush()
+}
+
+// revokeApiKey announces that the given key is no longer valid, so that it is dropped from caches
+func (srv *UserService) revokeApiKey(key string) {
+	srv.eventBus.Publish(hub.Message{
+		Name:   config.EventApiKeyRevoke,
+		Fields: map[string]interface{}{config.FieldPayload: key},
+	})
 }
 
-func (srv *UserService) notifyUpdate(user *models.User) {
+func (srv *UserService) notify(event string, user *models.User) {
 	srv.eventBus.Publish(hub.Message{
-		Name:   config.EventUserUpdate,
-		Fields: map[string]interface{}{config.FieldPayload: user},
+		Name:   event,
+		Fields: map[string]interface{}{config.FieldPayload: user, config.FieldUserId: user.ID},
 	})
 }
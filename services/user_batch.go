@@ -0,0 +1,98 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/emvi/logbuch"
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	uuid "github.com/satori/go.uuid"
+)
+
+// UserBatchService creates users in bulk, e.g. for onboarding a class or team, and mails them their initial credentials
+type UserBatchService struct {
+	config      *config.Config
+	userService IUserService
+	mailService IMailService
+}
+
+func NewUserBatchService(userService IUserService, mailService IMailService) *UserBatchService {
+	return &UserBatchService{
+		config:      config.Get(),
+		userService: userService,
+		mailService: mailService,
+	}
+}
+
+// Create creates a user with a random password for every entry. Existing users are skipped and left untouched.
+// If sendMail is set, users with an e-mail address get their credentials mailed. For all others, the generated password is included in the report instead.
+func (srv *UserBatchService) Create(entries []*models.UserBatchEntry, sendMail bool) *models.UserBatchReport {
+	report := &models.UserBatchReport{Results: make([]*models.UserBatchResult, 0, len(entries))}
+	for _, e := range entries {
+		report.Add(srv.create(e, sendMail))
+	}
+	logbuch.Info("batch-created %d users (%d skipped, %d failed)", report.Created, report.Skipped, report.Failed)
+	return report
+}
+
+func (srv *UserBatchService) create(entry *models.UserBatchEntry, sendMail bool) *models.UserBatchResult {
+	result := &models.UserBatchResult{Username: entry.Username, Status: models.UserBatchStatusFailed}
+
+	if err := entry.Validate(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if entry.ApiKey != "" {
+		if _, err := srv.userService.GetUserByKey(entry.ApiKey); err == nil {
+			result.Error = "api key already in use"
+			return result
+		}
+	}
+
+	password := GeneratePassword()
+	user, created, err := srv.userService.CreateOrGet(&models.Signup{
+		Username:       entry.Username,
+		Email:          entry.Email,
+		Password:       password,
+		PasswordRepeat: password,
+		Location:       "Local",
+	}, false)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if !created {
+		result.Status = models.UserBatchStatusSkipped
+		result.Error = "user already exists"
+		return result
+	}
+
+	if entry.ApiKey != "" {
+		user.ApiKey = entry.ApiKey
+		if _, err := srv.userService.Update(user); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+
+	result.Status = models.UserBatchStatusCreated
+
+	if sendMail && user.Email != "" && srv.config.Mail.Enabled {
+		if err := srv.mailService.SendCredentials(user, password); err != nil {
+			config.Log().Error("failed to send credentials to user '%s' - %v", user.ID, err)
+		} else {
+			result.MailSent = true
+		}
+	}
+	if !result.MailSent {
+		result.Password = password
+	}
+
+	return result
+}
+
+// GeneratePassword returns a random 16 characters password
+func GeneratePassword() string {
+	return strings.ReplaceAll(uuid.NewV4().String(), "-", "")[:16]
+}
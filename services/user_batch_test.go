@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type UserBatchServiceTestSuite struct {
+	suite.Suite
+	UserService *mocks.UserServiceMock
+	MailService *mocks.MailServiceMock
+}
+
+func (suite *UserBatchServiceTestSuite) SetupSuite() {
+	cfg := &config.Config{}
+	cfg.Mail.Enabled = true
+	config.Set(cfg)
+}
+
+func (suite *UserBatchServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.UserService = new(mocks.UserServiceMock)
+	suite.MailService = new(mocks.MailServiceMock)
+}
+
+func TestUserBatchServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(UserBatchServiceTestSuite))
+}
+
+func (suite *UserBatchServiceTestSuite) TestUserBatchService_Create() {
+	sut := NewUserBatchService(suite.UserService, suite.MailService)
+
+	alice := &models.User{ID: "alice", Email: "alice@example.org"}
+	bob := &models.User{ID: "bob"}
+	apiKey := "1b2c8c4e-0f2a-4c7e-9d8e-5e3a6f1b2c3d"
+
+	suite.UserService.On("CreateOrGet", mock.MatchedBy(func(s *models.Signup) bool { return s.Username == "alice" }), false).Return(alice, true, nil)
+	suite.UserService.On("CreateOrGet", mock.MatchedBy(func(s *models.Signup) bool { return s.Username == "bob" }), false).Return(bob, true, nil)
+	suite.UserService.On("CreateOrGet", mock.MatchedBy(func(s *models.Signup) bool { return s.Username == "carol" }), false).Return(&models.User{ID: "carol"}, false, nil)
+	suite.UserService.On("GetUserByKey", apiKey).Return((*models.User)(nil), errors.New("not found"))
+	suite.UserService.On("Update", bob).Return(bob, nil)
+	suite.MailService.On("SendCredentials", alice, mock.Anything).Return(nil)
+
+	report := sut.Create([]*models.UserBatchEntry{
+		{Username: "alice", Email: "alice@example.org"},
+		{Username: "bob", ApiKey: apiKey},
+		{Username: "carol"},
+		{Username: "dave", Email: "invalid"},
+	}, true)
+
+	assert.Equal(suite.T(), 2, report.Created)
+	assert.Equal(suite.T(), 1, report.Skipped)
+	assert.Equal(suite.T(), 1, report.Failed)
+
+	assert.True(suite.T(), report.Results[0].MailSent)
+	assert.Empty(suite.T(), report.Results[0].Password)
+	assert.False(suite.T(), report.Results[1].MailSent)
+	assert.Len(suite.T(), report.Results[1].Password, 16)
+	assert.Equal(suite.T(), apiKey, bob.ApiKey)
+	assert.Equal(suite.T(), models.UserBatchStatusSkipped, report.Results[2].Status)
+	assert.Equal(suite.T(), models.UserBatchStatusFailed, report.Results[3].Status)
+	suite.MailService.AssertNumberOfCalls(suite.T(), "SendCredentials", 1)
+}
+
+func (suite *UserBatchServiceTestSuite) TestUserBatchService_Create_DuplicateApiKey() {
+	sut := NewUserBatchService(suite.UserService, suite.MailService)
+
+	apiKey := "1b2c8c4e-0f2a-4c7e-9d8e-5e3a6f1b2c3d"
+	suite.UserService.On("GetUserByKey", apiKey).Return(&models.User{ID: "alice"}, nil)
+
+	report := sut.Create([]*models.UserBatchEntry{{Username: "bob", ApiKey: apiKey}}, false)
+
+	assert.Equal(suite.T(), 1, report.Failed)
+	suite.UserService.AssertNotCalled(suite.T(), "CreateOrGet", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+const testApiKey = "f0ab5b33-3a2d-4a3c-9fc1-5c6ba0d6a8f0"
+
+type UserServiceTestSuite struct {
+	suite.Suite
+	TestUser            *models.User
+	UserRepository      *mocks.UserRepositoryMock
+	MailService         *mocks.MailServiceMock
+	NotificationService *mocks.NotificationServiceMock
+}
+
+func (suite *UserServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+}
+
+func (suite *UserServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.TestUser = &models.User{ID: TestUserId, ApiKey: testApiKey}
+	suite.UserRepository = new(mocks.UserRepositoryMock)
+	suite.MailService = new(mocks.MailServiceMock)
+	suite.NotificationService = new(mocks.NotificationServiceMock)
+}
+
+func TestUserServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(UserServiceTestSuite))
+}
+
+func (suite *UserServiceTestSuite) TestUserService_GetUserByKey_Cached() {
+	sut := NewUserService(suite.MailService, suite.NotificationService, suite.UserRepository)
+
+	suite.UserRepository.On("GetByApiKey", testApiKey).Return(suite.TestUser, nil)
+
+	for i := 0; i < 3; i++ {
+		u, err := sut.GetUserByKey(testApiKey)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), TestUserId, u.ID)
+	}
+
+	suite.UserRepository.AssertNumberOfCalls(suite.T(), "GetByApiKey", 1)
+}
+
+func (suite *UserServiceTestSuite) TestUserService_GetUserByKey_Rotated() {
+	sut := NewUserService(suite.MailService, suite.NotificationService, suite.UserRepository)
+
+	suite.UserRepository.On("GetByApiKey", testApiKey).Return(suite.TestUser, nil).Once()
+	suite.UserRepository.On("Update", suite.TestUser).Return(suite.TestUser, nil)
+
+	_, err := sut.GetUserByKey(testApiKey)
+	assert.Nil(suite.T(), err)
+
+	_, err = sut.ResetApiKey(suite.TestUser)
+	assert.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), testApiKey, suite.TestUser.ApiKey)
+
+	// regardless of whether the revocation was processed yet, the outdated key must not resolve from cache anymore
+	suite.UserRepository.On("GetById", TestUserId).Return(suite.TestUser, nil)
+	suite.UserRepository.On("GetByApiKey", testApiKey).Return(&models.User{}, errors.New("record not found"))
+
+	_, err = sut.GetUserByKey(testApiKey)
+	assert.Error(suite.T(), err)
+	suite.UserRepository.AssertNumberOfCalls(suite.T(), "GetByApiKey", 2)
+}
+
+func (suite *UserServiceTestSuite) TestUserService_Merge() {
+	sut := NewUserService(suite.MailService, suite.NotificationService, suite.UserRepository)
+
+	legacyUser := &models.User{ID: "legacy", ApiKey: "6d5a7c3e-9a1b-4f7e-8c2d-3b4a5c6d7e8f"}
+	merge := &models.UserMerge{SourceId: legacyUser.ID, TargetId: TestUserId, KeepApiKey: true}
+
+	suite.UserRepository.On("GetById", legacyUser.ID).Return(legacyUser, nil)
+	suite.UserRepository.On("GetById", TestUserId).Return(suite.TestUser, nil)
+	suite.UserRepository.On("GetByApiKey", legacyUser.ApiKey).Return(legacyUser, nil).Once()
+	suite.UserRepository.On("Merge", merge).Return(&models.UserMergeReport{SourceId: legacyUser.ID, TargetId: TestUserId, Heartbeats: 10, ApiKeyAdopted: true}, nil)
+
+	u, err := sut.GetUserByKey(legacyUser.ApiKey)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), legacyUser.ID, u.ID)
+
+	report, err := sut.Merge(merge)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(10), report.Heartbeats)
+
+	// the adopted key must not resolve to the merged user from cache anymore
+	suite.UserRepository.On("GetByApiKey", legacyUser.ApiKey).Return(suite.TestUser, nil)
+
+	u, err = sut.GetUserByKey(legacyUser.ApiKey)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), TestUserId, u.ID)
+}
+
+func (suite *UserServiceTestSuite) TestUserService_Merge_Invalid() {
+	sut := NewUserService(suite.MailService, suite.NotificationService, suite.UserRepository)
+
+	_, err := sut.Merge(&models.UserMerge{SourceId: TestUserId, TargetId: TestUserId})
+	assert.NotNil(suite.T(), err)
+
+	suite.UserRepository.On("GetById", "unknown").Return(&models.User{}, errors.New("record not found"))
+
+	_, err = sut.Merge(&models.UserMerge{SourceId: "unknown", TargetId: TestUserId})
+	assert.NotNil(suite.T(), err)
+	suite.UserRepository.AssertNotCalled(suite.T(), "Merge", mock.Anything)
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"github.com/muety/wakapi/repositories"
+	"github.com/muety/wakapi/utils"
+	"github.com/patrickmn/go-cache"
+)
+
+const yearReviewTopItems = 5
+
+var ErrYearReviewInvalidYear = errors.New("year must not be in the future")
+
+type YearReviewService struct {
+	config            *config.Config
+	cache             *cache.Cache
+	summaryService    ISummaryService
+	summaryRepository repositories.ISummaryRepository
+	durationService   IDurationService
+	dayOffService     IDayOffService
+	languageService   ILanguageMetaService
+}
+
+func NewYearReviewService(summaryService ISummaryService, summaryRepository repositories.ISummaryRepository, durationService IDurationService, dayOffService IDayOffService, languageService ILanguageMetaService) *YearReviewService {
+	return &YearReviewService{
+		config:            config.Get(),
+		cache:             cache.New(6*time.Hour, 6*time.Hour),
+		summaryService:    summaryService,
+		summaryRepository: summaryRepository,
+		durationService:   durationService,
+		dayOffService:     dayOffService,
+		languageService:   languageService,
+	}
+}
+
+// GetReview computes the user's annual recap from their persisted (daily) summaries, except for the hour-of-day profile, which requires durations
+func (srv *YearReviewService) GetReview(user *models.User, year int) (*models.YearReview, error) {
+	now := time.Now().In(user.TZ())
+	if year > now.Year() {
+		return nil, ErrYearReviewInvalidYear
+	}
+
+	cacheKey := fmt.Sprintf("%s_%d", user.ID, year)
+	if review, found := srv.cache.Get(cacheKey); found {
+		return review.(*models.YearReview), nil
+	}
+
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, user.TZ())
+	to := from.AddDate(1, 0, 0)
+	if to.After(now) {
+		to = now
+	}
+
+	review := &models.YearReview{Year: year}
+
+	// totals and top entities, with aliases resolved
+	summary, err := srv.summaryService.Aliased(from, to, user, srv.summaryService.Retrieve, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	summary = summary.Sorted()
+	review.Total = summary.TotalTime()
+	review.TopProjects = models.NewYearReviewItems(summary.Projects, yearReviewTopItems)
+	review.TopLanguages = models.NewYearReviewItems(summary.Languages, yearReviewTopItems)
+
+	// busiest day and longest streak
+	daysOff, err := srv.dayOffService.GetByUserMapped(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	dailySummaries, err := srv.summaryRepository.GetByUserWithin(user, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	streak := models.NewStreakCounter(daysOff)
+	var lastDay string
+	for _, s := range dailySummaries {
+		total := s.TotalTime()
+		if total <= 0 {
+			continue
+		}
+		day := utils.StartOfDay(s.FromTime.T().In(user.TZ()))
+		if total > review.BusiestDayTotal {
+			review.BusiestDay, review.BusiestDayTotal = day, total
+		}
+		if d := day.Format(models.DayOffFormat); d != lastDay {
+			lastDay = d
+			review.ActiveDays++
+			streak.Add(day)
+		}
+	}
+	review.LongestStreak, review.LongestStreakStart, review.LongestStreakEnd = streak.Longest, streak.LongestStart, streak.LongestEnd
+
+	// hour-of-day profile, month by month to limit memory usage
+	for monthStart := from; monthStart.Before(to); monthStart = monthStart.AddDate(0, 1, 0) {
+		monthEnd := monthStart.AddDate(0, 1, 0)
+		if monthEnd.After(to) {
+			monthEnd = to
+		}
+		durations, err := srv.durationService.Get(monthStart, monthEnd, user, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range durations {
+			addToHoursOfDay(&review.HoursOfDay, d.Time.T().In(user.TZ()), d.Duration)
+		}
+	}
+
+	review.Fill()
+	srv.cache.SetDefault(cacheKey, review)
+	return review, nil
+}
+
+// RenderSvg renders the review as a shareable image
+func (srv *YearReviewService) RenderSvg(review *models.YearReview) []byte {
+	var buf bytes.Buffer
+	line := func(y int, size int, bold bool, color, text string) {
+		weight := "normal"
+		if bold {
+			weight = "bold"
+		}
+		fmt.Fprintf(&buf, `<text x="40" y="%d" font-size="%d" font-weight="%s" fill="%s">%s</text>`, y, size, weight, color, html.EscapeString(text))
+	}
+
+	fmt.Fprint(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="600" height="680" viewBox="0 0 600 680" font-family="sans-serif">`)
+	fmt.Fprint(&buf, `<rect width="600" height="680" rx="16" fill="#1a202c"/>`)
+	line(70, 32, true, "#ffffff", fmt.Sprintf("My %d in Code", review.Year))
+	line(120, 20, false, "#a0aec0", fmt.Sprintf("%s of coding on %d days", utils.FmtWakatimeDuration(review.Total), review.ActiveDays))
+
+	y := 180
+	sections := []struct {
+		title     string
+		items     []*models.YearReviewItem
+		languages bool
+	}{
+		{"Top Projects", review.TopProjects, false},
+		{"Top Languages", review.TopLanguages, true},
+	}
+	for _, section := range sections {
+		line(y, 20, true, "#2f855a", section.title)
+		y += 30
+		for i, item := range section.items {
+			// languages are marked with their color, as in the dashboard's charts
+			if section.languages {
+				if color := srv.languageService.Get(item.Key).Color; color != "" {
+					fmt.Fprintf(&buf, `<circle cx="28" cy="%d" r="5" fill="%s"/>`, y-5, color)
+				}
+			}
+			line(y, 16, false, "#e2e8f0", fmt.Sprintf("%d. %s (%s)", i+1, item.Key, utils.FmtWakatimeDuration(item.Total)))
+			y += 24
+		}
+		y += 20
+	}
+
+	if review.BusiestDayTotal > 0 {
+		line(y, 16, false, "#e2e8f0", fmt.Sprintf("Busiest day: %s (%s)", review.BusiestDay.Format(config.SimpleDateFormat), utils.FmtWakatimeDuration(review.BusiestDayTotal)))
+		y += 24
+	}
+	line(y, 16, false, "#e2e8f0", fmt.Sprintf("Longest streak: %d days", review.LongestStreak))
+	y += 24
+	line(y, 16, false, "#e2e8f0", fmt.Sprintf("Most productive hour: %02d:00 - %02d:00", review.MostProductiveHour, (review.MostProductiveHour+1)%24))
+	y += 40
+
+	// hour-of-day profile as bar chart
+	maxHour := review.HoursOfDay[review.MostProductiveHour]
+	for h, d := range review.HoursOfDay {
+		height := 0
+		if maxHour > 0 {
+			height = int(80 * d / maxHour)
+		}
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="18" height="%d" fill="#2f855a"/>`, 40+h*22, y+80-height, height)
+	}
+	fmt.Fprintf(&buf, `<text x="40" y="%d" font-size="12" fill="#a0aec0">0h</text><text x="538" y="%d" font-size="12" fill="#a0aec0">23h</text>`, y+100, y+100)
+
+	fmt.Fprint(&buf, `</svg>`)
+	return buf.Bytes()
+}
+
+// RenderPdf renders the review as a printable document
+func (srv *YearReviewService) RenderPdf(review *models.YearReview) []byte {
+	pdf := utils.NewTextPdf()
+	pdf.AddLine(fmt.Sprintf("Your %d in Code", review.Year), 18, true)
+	pdf.AddSpace(10)
+	pdf.AddLine(fmt.Sprintf("Total: %s on %d days", utils.FmtWakatimeDuration(review.Total), review.ActiveDays), 12, false)
+	if review.BusiestDayTotal > 0 {
+		pdf.AddLine(fmt.Sprintf("Busiest day: %s (%s)", review.BusiestDay.Format(config.SimpleDateFormat), utils.FmtWakatimeDuration(review.BusiestDayTotal)), 12, false)
+	}
+	pdf.AddLine(fmt.Sprintf("Longest streak: %d days", review.LongestStreak), 12, false)
+	pdf.AddLine(fmt.Sprintf("Most productive hour: %02d:00 - %02d:00", review.MostProductiveHour, (review.MostProductiveHour+1)%24), 12, false)
+
+	sections := []struct {
+		title string
+		items []*models.YearReviewItem
+	}{
+		{"Top Projects", review.TopProjects},
+		{"Top Languages", review.TopLanguages},
+	}
+	for _, section := range sections {
+		pdf.AddSpace(15)
+		pdf.AddLine(section.title, 14, true)
+		for i, item := range section.items {
+			pdf.AddLine(fmt.Sprintf("%d. %s: %s", i+1, item.Key, utils.FmtWakatimeDuration(item.Total)), 11, false)
+		}
+	}
+
+	pdf.AddSpace(15)
+	pdf.AddLine("Hours of the Day", 14, true)
+	for h, d := range review.HoursOfDay {
+		pdf.AddLine(fmt.Sprintf("%02d:00: %s", h, utils.FmtWakatimeDuration(d)), 11, false)
+	}
+
+	return pdf.Bytes()
+}
+
+// addToHoursOfDay distributes the given duration over the hours of the day it spans
+func addToHoursOfDay(hours *[24]time.Duration, start time.Time, duration time.Duration) {
+	end := start.Add(duration)
+	for t := start; t.Before(end); {
+		next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(time.Hour)
+		if next.After(end) {
+			next = end
+		}
+		hours[t.Hour()] += next.Sub(t)
+		t = next
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/mocks"
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type YearReviewServiceTestSuite struct {
+	suite.Suite
+	TestUser            *models.User
+	SummaryService      *mocks.SummaryServiceMock
+	SummaryRepository   *mocks.SummaryRepositoryMock
+	DurationService     *mocks.DurationServiceMock
+	DayOffService       *mocks.DayOffServiceMock
+	LanguageMetaService *LanguageMetaService
+}
+
+func (suite *YearReviewServiceTestSuite) SetupSuite() {
+	config.Set(&config.Config{})
+	suite.TestUser = &models.User{ID: "johndoe", Location: "UTC"}
+}
+
+func (suite *YearReviewServiceTestSuite) BeforeTest(suiteName, testName string) {
+	suite.SummaryService = new(mocks.SummaryServiceMock)
+	suite.SummaryRepository = new(mocks.SummaryRepositoryMock)
+	suite.DurationService = new(mocks.DurationServiceMock)
+	suite.DayOffService = new(mocks.DayOffServiceMock)
+
+	keyValueService := new(mocks.KeyValueServiceMock)
+	keyValueService.On("MustGetString", config.KeyLanguageOverrides).Return(&models.KeyStringValue{Key: config.KeyLanguageOverrides})
+	suite.LanguageMetaService = NewLanguageMetaService(keyValueService)
+}
+
+func TestYearReviewServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(YearReviewServiceTestSuite))
+}
+
+func (suite *YearReviewServiceTestSuite) TestYearReviewService_GetReview() {
+	jan1 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(offset int, total time.Duration) *models.Summary {
+		return &models.Summary{
+			FromTime: models.CustomTime(jan1.AddDate(0, 0, offset)),
+			ToTime:   models.CustomTime(jan1.AddDate(0, 0, offset+1)),
+			Projects: []*models.SummaryItem{{Type: models.SummaryProject, Key: "wakapi", Total: total / time.Second}},
+		}
+	}
+
+	suite.SummaryService.On("Aliased", jan1, jan1.AddDate(1, 0, 0), suite.TestUser, mock.Anything, mock.Anything, false).Return(&models.Summary{
+		Projects: []*models.SummaryItem{
+			{Type: models.SummaryProject, Key: "anchr", Total: 1 * time.Hour / time.Second},
+			{Type: models.SummaryProject, Key: "wakapi", Total: 9 * time.Hour / time.Second},
+		},
+		Languages: []*models.SummaryItem{
+			{Type: models.SummaryLanguage, Key: "Go", Total: 10 * time.Hour / time.Second},
+		},
+	}, nil)
+	suite.SummaryRepository.On("GetByUserWithin", suite.TestUser, jan1, jan1.AddDate(1, 0, 0)).Return([]*models.Summary{
+		day(0, 1*time.Hour),
+		day(1, 4*time.Hour), // busiest
+		day(2, 1*time.Hour),
+		day(5, 1*time.Hour), // vacation in between
+		day(6, 1*time.Hour),
+		day(10, 2*time.Hour),
+	}, nil)
+	suite.DayOffService.On("GetByUserMapped", suite.TestUser.ID).Return(models.NewDaysOff([]*models.DayOff{
+		{Day: "2021-01-04", Kind: models.DayOffVacation},
+		{Day: "2021-01-05", Kind: models.DayOffVacation},
+	}), nil)
+	suite.DurationService.On("Get", jan1, jan1.AddDate(0, 1, 0), suite.TestUser, mock.Anything).Return(models.Durations{
+		{Time: models.CustomTime(jan1.Add(9*time.Hour + 30*time.Minute)), Duration: time.Hour},
+		{Time: models.CustomTime(jan1.Add(22 * time.Hour)), Duration: 15 * time.Minute},
+	}, nil)
+	suite.DurationService.On("Get", mock.Anything, mock.Anything, suite.TestUser, mock.Anything).Return(models.Durations{}, nil)
+
+	sut := NewYearReviewService(suite.SummaryService, suite.SummaryRepository, suite.DurationService, suite.DayOffService, suite.LanguageMetaService)
+
+	result, err := sut.GetReview(suite.TestUser, 2021)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2021, result.Year)
+	assert.Equal(suite.T(), int64(10*3600), result.TotalSeconds)
+	assert.Equal(suite.T(), 6, result.ActiveDays)
+	assert.Len(suite.T(), result.TopProjects, 2)
+	assert.Equal(suite.T(), "wakapi", result.TopProjects[0].Key)
+	assert.Equal(suite.T(), "Go", result.TopLanguages[0].Key)
+	assert.Equal(suite.T(), jan1.AddDate(0, 0, 1), result.BusiestDay)
+	assert.Equal(suite.T(), 4*time.Hour, result.BusiestDayTotal)
+	assert.Equal(suite.T(), 5, result.LongestStreak)
+	assert.Equal(suite.T(), jan1, result.LongestStreakStart)
+	assert.Equal(suite.T(), jan1.AddDate(0, 0, 6), result.LongestStreakEnd)
+	assert.Equal(suite.T(), 30*time.Minute, result.HoursOfDay[9])
+	assert.Equal(suite.T(), 30*time.Minute, result.HoursOfDay[10])
+	assert.Equal(suite.T(), int64(15*60), result.HoursOfDaySeconds[22])
+	suite.DurationService.AssertNumberOfCalls(suite.T(), "Get", 12)
+
+	assert.Contains(suite.T(), string(sut.RenderSvg(result)), "wakapi")
+	assert.NotEmpty(suite.T(), sut.RenderPdf(result))
+}
+
+func (suite *YearReviewServiceTestSuite) TestYearReviewService_GetReview_Future() {
+	sut := NewYearReviewService(suite.SummaryService, suite.SummaryRepository, suite.DurationService, suite.DayOffService, suite.LanguageMetaService)
+
+	_, err := sut.GetReview(suite.TestUser, time.Now().Year()+1)
+
+	assert.ErrorIs(suite.T(), err, ErrYearReviewInvalidYear)
+}
@@ -16,7 +16,8 @@ function copyApiKey(event) {
 }
 
 window.addEventListener('load', () => {
-    const baseUrl = location.href.substring(0, location.href.lastIndexOf('/'))
+    // resolved against the page's <base> tag, which includes the base path, if running under a sub-path
+    const baseUrl = document.baseURI.replace(/\/$/, '')
     document.querySelectorAll('.with-url-src').forEach(e => {
         e.setAttribute('src', e.getAttribute('src').replace('%s', baseUrl))
     })
@@ -53,3 +53,20 @@ func ParseUserAgent(ua string) (string, string, error) {
 	}
 	return groups[0][1], groups[0][2], nil
 }
+
+// ParseAgentVersion returns the version of wakatime-cli or, for browser extensions, of the extension a heartbeat was sent by or an empty string, if unknown
+func ParseAgentVersion(ua string) string {
+	if groups := regexp.MustCompile(`(?i)^wakatime\/v?([\d.]+)\s`).FindStringSubmatch(ua); len(groups) == 2 {
+		return groups[1]
+	}
+	if groups := regexp.MustCompile(`(?i)^\S+\s\S+-wakatime\/v?(\S+)$`).FindStringSubmatch(ua); len(groups) == 2 && IsBrowserUserAgent(ua) {
+		return groups[1]
+	}
+	return ""
+}
+
+// IsBrowserUserAgent returns whether the user agent is the one of a wakatime browser extension, e.g. 'Chrome/104.0.0.0 chrome-wakatime/3.0.0'
+func IsBrowserUserAgent(ua string) bool {
+	re := regexp.MustCompile(`(?i)^(?:chrome|firefox|edge|opera|brave|safari)\/\S+\s\S+-wakatime\/\S+$`)
+	return re.MatchString(ua)
+}
@@ -59,6 +59,22 @@ func TestCommon_ParseUserAgent(t *testing.T) {
 	}
 }
 
+func TestCommon_IsBrowserUserAgent(t *testing.T) {
+	assert.True(t, IsBrowserUserAgent("Chrome/104.0.0.0 chrome-wakatime/3.0.0"))
+	assert.True(t, IsBrowserUserAgent("Firefox/103.0 firefox-wakatime/3.0.1"))
+	assert.False(t, IsBrowserUserAgent("wakatime/13.0.4 (Linux-5.4.64-x86_64-with-glibc2.2.5) Python3.7.6.final.0 emacs-wakatime/1.0.2"))
+	assert.False(t, IsBrowserUserAgent(""))
+}
+
+func TestCommon_ParseAgentVersion(t *testing.T) {
+	assert.Equal(t, "13.0.7", ParseAgentVersion("wakatime/13.0.7 (Linux-4.15.0-96-generic-x86_64-with-glibc2.4) Python3.8.0.final.0 GoLand/2019.3.4 GoLand-wakatime/11.0.1"))
+	assert.Equal(t, "1.35.4", ParseAgentVersion("wakatime/v1.35.4 (linux-5.15.0-48-generic-x86_64) go1.19.1 vscode/1.71.2 vscode-wakatime/18.1.8"))
+	assert.Equal(t, "3.0.0", ParseAgentVersion("Chrome/104.0.0.0 chrome-wakatime/3.0.0"))
+	assert.Equal(t, "", ParseAgentVersion("wakatime/unset (linux-5.15.0-48-generic-x86_64) go1.19.1 vscode/1.71.2 vscode-wakatime/18.1.8"))
+	assert.Equal(t, "", ParseAgentVersion("curl/7.68.0"))
+	assert.Equal(t, "", ParseAgentVersion(""))
+}
+
 func checkErr(expected, actual error) bool {
 	return (expected == nil && actual == nil) || (expected != nil && actual != nil)
 }
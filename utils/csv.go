@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"encoding/csv"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/muety/wakapi/config"
+)
+
+const CsvContentType = "text/csv"
+
+// csv records are flushed to the client in chunks of this size, instead of buffering the whole response
+const csvFlushInterval = 500
+
+// AcceptsCsv tells whether the client requested a csv representation via the Accept header
+func AcceptsCsv(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(part))
+		if mediaType == CsvContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// CsvResponse streams csv records to the client, writing the header row first
+type CsvResponse struct {
+	writer  *csv.Writer
+	flusher http.Flusher
+	request *http.Request
+	count   int
+}
+
+func NewCsvResponse(w http.ResponseWriter, r *http.Request, status int, header []string) *CsvResponse {
+	w.Header().Set("Content-Type", CsvContentType+"; charset=utf-8")
+	w.WriteHeader(status)
+
+	res := &CsvResponse{writer: csv.NewWriter(w), request: r}
+	if flusher, ok := w.(http.Flusher); ok {
+		res.flusher = flusher
+	}
+	res.Write(header)
+	return res
+}
+
+func (res *CsvResponse) Write(record []string) {
+	if err := res.writer.Write(record); err != nil {
+		config.Log().Request(res.request).Error("error while writing csv response: %v", err)
+		return
+	}
+	if res.count++; res.count%csvFlushInterval == 0 {
+		res.flush()
+	}
+}
+
+// Close flushes all remaining records
+func (res *CsvResponse) Close() {
+	res.flush()
+	if err := res.writer.Error(); err != nil {
+		config.Log().Request(res.request).Error("error while writing csv response: %v", err)
+	}
+}
+
+func (res *CsvResponse) flush() {
+	res.writer.Flush()
+	if res.flusher != nil {
+		res.flusher.Flush()
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCsv_AcceptsCsv(t *testing.T) {
+	tests := map[string]bool{
+		"":                                 false,
+		"application/json":                 false,
+		"text/csv":                         true,
+		"application/json, text/csv;q=0.9": true,
+		"text/csv; charset=utf-8":          true,
+	}
+
+	for accept, expected := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+		r.Header.Set("Accept", accept)
+		assert.Equal(t, expected, AcceptsCsv(r), accept)
+	}
+}
+
+func TestCsv_CsvResponse(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+	w := httptest.NewRecorder()
+
+	res := NewCsvResponse(w, r, http.StatusOK, []string{"key", "total_seconds"})
+	res.Write([]string{"wakapi", "120"})
+	res.Write([]string{"hello, world", "60"})
+	res.Close()
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "key,total_seconds\nwakapi,120\n\"hello, world\",60\n", w.Body.String())
+}
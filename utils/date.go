@@ -5,6 +5,9 @@ import (
 	"time"
 )
 
+// Day boundaries are always computed from calendar dates (using time.Date and AddDate) instead of adding multiples of 24 hours,
+// as days in time zones with daylight saving time may have 23 or 25 hours.
+
 func StartOfDay(date time.Time) time.Time {
 	return FloorDate(date)
 }
@@ -15,7 +18,7 @@ func StartOfToday(tz *time.Location) time.Time {
 
 func EndOfDay(date time.Time) time.Time {
 	floored := FloorDate(date)
-	if floored == date {
+	if floored.Equal(date) {
 		date = date.Add(1 * time.Second)
 	}
 	return CeilDate(date)
@@ -29,9 +32,10 @@ func StartOfThisWeek(tz *time.Location) time.Time {
 	return StartOfWeek(time.Now().In(tz))
 }
 
+// StartOfWeek returns the start of the monday of the date's (iso) week
 func StartOfWeek(date time.Time) time.Time {
-	year, week := date.ISOWeek()
-	return firstDayOfISOWeek(year, week, date.Location())
+	floored := FloorDate(date)
+	return FloorDate(floored.AddDate(0, 0, -((int(floored.Weekday()) + 6) % 7)))
 }
 
 func StartOfThisMonth(tz *time.Location) time.Time {
@@ -42,6 +46,19 @@ func StartOfMonth(date time.Time) time.Time {
 	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
 }
 
+func StartOfQuarter(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month()-(date.Month()-1)%3, 1, 0, 0, 0, 0, date.Location())
+}
+
+// StartOfFiscalYear returns the start of the fiscal year the date is in, given the month in which fiscal years start
+func StartOfFiscalYear(date time.Time, startMonth time.Month) time.Time {
+	year := date.Year()
+	if date.Month() < startMonth {
+		year--
+	}
+	return time.Date(year, startMonth, 1, 0, 0, 0, 0, date.Location())
+}
+
 func StartOfThisYear(tz *time.Location) time.Time {
 	return StartOfYear(time.Now().In(tz))
 }
@@ -50,7 +67,8 @@ func StartOfYear(date time.Time) time.Time {
 	return time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, date.Location())
 }
 
-// FloorDate rounds date down to the start of the day and keeps the time zone
+// FloorDate rounds date down to the start of the day and keeps the time zone.
+// If midnight doesn't exist on that day (i.e. clocks are turned forward at midnight), this is the day's first existing instant.
 func FloorDate(date time.Time) time.Time {
 	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 }
@@ -63,10 +81,10 @@ func FloorDateHour(date time.Time) time.Time {
 // CeilDate rounds date up to the start of next day if date is not already a start (00:00:00)
 func CeilDate(date time.Time) time.Time {
 	floored := FloorDate(date)
-	if floored == date {
+	if floored.Equal(date) {
 		return floored
 	}
-	return floored.AddDate(0, 0, 1)
+	return FloorDate(floored.AddDate(0, 0, 1))
 }
 
 // SetLocation resets the time zone information of a date without converting it, i.e. 19:00 UTC will result in 19:00 CET, for instance
@@ -74,21 +92,21 @@ func SetLocation(date time.Time, tz *time.Location) time.Time {
 	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, tz)
 }
 
-// WithOffset adds the time zone difference between Local and tz to a date, i.e. 19:00 UTC will result in 21:00 CET (or 22:00 CEST), for instance
+// WithOffset adds the time zone difference between Local and tz to a date, i.e. 19:00 UTC will result in 21:00 CET (or 22:00 CEST), for instance.
+// The difference is the one in effect at the given date, not the current one.
 func WithOffset(date time.Time, tz *time.Location) time.Time {
-	now := time.Now()
-	_, localOffset := now.Zone()
-	_, targetOffset := now.In(tz).Zone()
+	_, localOffset := date.In(time.Local).Zone()
+	_, targetOffset := date.In(tz).Zone()
 	dateTz := date.Add(time.Duration((targetOffset - localOffset) * int(time.Second)))
 	return time.Date(dateTz.Year(), dateTz.Month(), dateTz.Day(), dateTz.Hour(), dateTz.Minute(), dateTz.Second(), dateTz.Nanosecond(), dateTz.Location()).In(tz)
 }
 
-// SplitRangeByDays creates a slice of intervals between from and to, each of which is at max of 24 hours length and has its split at midnight
+// SplitRangeByDays creates a slice of intervals between from and to, each of which covers at most a single day (i.e. 23 to 25 hours on DST transitions) and has its split at midnight
 func SplitRangeByDays(from time.Time, to time.Time) [][]time.Time {
 	intervals := make([][]time.Time, 0)
 
 	for t1 := from; t1.Before(to); {
-		t2 := StartOfDay(t1).AddDate(0, 0, 1)
+		t2 := FloorDate(StartOfDay(t1).AddDate(0, 0, 1))
 		if t2.After(to) {
 			t2 = to
 		}
@@ -112,22 +130,3 @@ func LocalTZOffset() time.Duration {
 	_, offset := time.Now().Zone()
 	return time.Duration(offset * int(time.Second))
 }
-
-// https://stackoverflow.com/a/18632496
-func firstDayOfISOWeek(year int, week int, timezone *time.Location) time.Time {
-	date := time.Date(year, 0, 0, 0, 0, 0, 0, timezone)
-	isoYear, isoWeek := date.ISOWeek()
-	for date.Weekday() != time.Monday { // iterate back to Monday
-		date = date.AddDate(0, 0, -1)
-		isoYear, isoWeek = date.ISOWeek()
-	}
-	for isoYear < year { // iterate forward to the first day of the first week
-		date = date.AddDate(0, 0, 1)
-		isoYear, isoWeek = date.ISOWeek()
-	}
-	for isoWeek < week { // iterate forward to the first day of the given week
-		date = date.AddDate(0, 0, 1)
-		isoYear, isoWeek = date.ISOWeek()
-	}
-	return date
-}
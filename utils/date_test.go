@@ -146,3 +146,44 @@ func TestDate_SplitRangeByDays(t *testing.T) {
 
 	assert.Len(t, result4, 0)
 }
+
+func TestDate_DaylightSavingTime(t *testing.T) {
+	// clocks turned forward, day has 23 hours
+	d1 := time.Date(2023, 3, 26, 12, 0, 0, 0, tzCet)
+	assert.Equal(t, time.Date(2023, 3, 26, 0, 0, 0, 0, tzCet), StartOfDay(d1))
+	assert.Equal(t, time.Date(2023, 3, 27, 0, 0, 0, 0, tzCet), EndOfDay(d1))
+	assert.Equal(t, 23*time.Hour, EndOfDay(d1).Sub(StartOfDay(d1)))
+	assert.Equal(t, time.Date(2023, 3, 20, 0, 0, 0, 0, tzCet), StartOfWeek(d1))
+
+	// clocks turned back, day has 25 hours
+	d2 := time.Date(2023, 10, 29, 23, 30, 0, 0, tzCet)
+	assert.Equal(t, 25*time.Hour, EndOfDay(d2).Sub(StartOfDay(d2)))
+
+	result := SplitRangeByDays(time.Date(2023, 10, 28, 12, 0, 0, 0, tzCet), time.Date(2023, 10, 30, 12, 0, 0, 0, tzCet))
+	assert.Len(t, result, 3)
+	assert.Equal(t, time.Date(2023, 10, 29, 0, 0, 0, 0, tzCet), result[1][0])
+	assert.Equal(t, time.Date(2023, 10, 30, 0, 0, 0, 0, tzCet), result[1][1])
+}
+
+func TestDate_HalfHourOffset(t *testing.T) {
+	tzIst, _ := time.LoadLocation("Asia/Kolkata") // utc+5:30
+
+	d := time.Date(2023, 5, 10, 3, 0, 0, 0, time.UTC) // already 08:30 in india
+	assert.Equal(t, time.Date(2023, 5, 10, 0, 0, 0, 0, tzIst), StartOfDay(d.In(tzIst)))
+	assert.Equal(t, time.Date(2023, 5, 9, 18, 30, 0, 0, time.UTC), StartOfDay(d.In(tzIst)).UTC())
+}
+
+func TestDate_StartOfQuarter(t *testing.T) {
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, tzCet), StartOfQuarter(time.Date(2023, 3, 31, 23, 59, 0, 0, tzCet)))
+	assert.Equal(t, time.Date(2023, 4, 1, 0, 0, 0, 0, tzCet), StartOfQuarter(time.Date(2023, 4, 1, 0, 0, 0, 0, tzCet)))
+	assert.Equal(t, time.Date(2023, 10, 1, 0, 0, 0, 0, tzPst), StartOfQuarter(time.Date(2023, 12, 24, 18, 0, 0, 0, tzPst)))
+}
+
+func TestDate_WithOffset(t *testing.T) {
+	// offset in effect at the given date, regardless of the current one
+	for _, d := range []time.Time{time.Date(2023, 7, 1, 19, 0, 0, 0, time.Local), time.Date(2023, 1, 1, 19, 0, 0, 0, time.Local)} {
+		_, localOffset := d.Zone()
+		_, targetOffset := d.In(tzCet).Zone()
+		assert.Equal(t, time.Duration(targetOffset-localOffset)*time.Second, WithOffset(d, tzCet).Sub(d))
+	}
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// SelectFields reduces a response object to the top-level sections listed in the request's comma-separated 'fields' query parameter, if given.
+// For responses wrapped in a 'data' envelope (like WakaTime's), sections are selected within data, or within each of its items, if it is a list, while the envelope is kept.
+func SelectFields(r *http.Request, object interface{}) interface{} {
+	fieldsParam := strings.TrimSpace(r.URL.Query().Get("fields"))
+	if fieldsParam == "" {
+		return object
+	}
+
+	fields := make(map[string]bool)
+	for _, f := range strings.Split(fieldsParam, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields[f] = true
+		}
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		return object
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return object
+	}
+
+	if raw, ok := sections["data"]; ok {
+		sections["data"] = selectRawFields(raw, fields)
+		return sections
+	}
+	return selectFields(sections, fields)
+}
+
+func selectRawFields(raw json.RawMessage, fields map[string]bool) json.RawMessage {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err == nil {
+		if result, err := json.Marshal(selectFields(object, fields)); err == nil {
+			return result
+		}
+		return raw
+	}
+
+	var list []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for i := range list {
+			list[i] = selectFields(list[i], fields)
+		}
+		if result, err := json.Marshal(list); err == nil {
+			return result
+		}
+	}
+	return raw
+}
+
+func selectFields(sections map[string]json.RawMessage, fields map[string]bool) map[string]json.RawMessage {
+	selected := make(map[string]json.RawMessage, len(fields))
+	for k, v := range sections {
+		if fields[k] {
+			selected[k] = v
+		}
+	}
+	return selected
+}
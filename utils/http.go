@@ -2,10 +2,20 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/muety/wakapi/config"
+	"github.com/muety/wakapi/models"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// ErrBodyTooLarge is returned when reading a request body beyond the size it was limited to
+var ErrBodyTooLarge = errors.New("request body too large")
+
 func RespondJSON(w http.ResponseWriter, r *http.Request, status int, object interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -13,3 +23,87 @@ func RespondJSON(w http.ResponseWriter, r *http.Request, status int, object inte
 		config.Log().Request(r).Error("error while writing json response: %v", err)
 	}
 }
+
+// RespondNegotiated responds with messagepack, if requested by the client via the Accept header, and with json otherwise
+func RespondNegotiated(w http.ResponseWriter, r *http.Request, status int, object interface{}) {
+	if !AcceptsMsgpack(r) {
+		RespondJSON(w, r, status, object)
+		return
+	}
+
+	data, err := MsgpackMarshal(object)
+	if err != nil {
+		config.Log().Request(r).Error("error while encoding messagepack response: %v", err)
+		RespondError(w, r, http.StatusInternalServerError, config.ErrInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", MsgpackContentType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// RespondError responds with an error in the api's uniform json format, or in WakaTime's format for requests to the WakaTime-compatible endpoints
+func RespondError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	RespondErrorDetails(w, r, status, message, nil)
+}
+
+// RespondErrorDetails is like RespondError, but additionally includes details, e.g. the invalid fields of a request
+func RespondErrorDetails(w http.ResponseWriter, r *http.Request, status int, message string, details interface{}) {
+	if r.URL != nil && strings.Contains(r.URL.Path, "/compat/wakatime/") {
+		RespondJSON(w, r, status, &models.WakatimeApiError{Error: message})
+		return
+	}
+	RespondJSON(w, r, status, models.NewApiError(status, message, details).WithRequestId(r.Header.Get(config.HeaderRequestId)))
+}
+
+// SetRateLimitHeaders announces the client's remaining quota via the de-facto standard X-RateLimit-* headers and, once exceeded, when to retry
+func SetRateLimitHeaders(w http.ResponseWriter, status *models.QuotaStatus, exceeded bool) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(status.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining()))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(status.Reset.Unix(), 10))
+	if exceeded {
+		w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(status.Reset).Seconds())+1))
+	}
+}
+
+// LimitBody limits the request body to the given number of bytes (0 = unlimited), like http.MaxBytesReader, but reading beyond fails with ErrBodyTooLarge,
+// so that callers can tell oversized bodies apart from malformed ones. Returns false, if the announced content length already exceeds the limit.
+func LimitBody(r *http.Request, maxBytes int64) bool {
+	if maxBytes <= 0 {
+		return true
+	}
+	if r.ContentLength > maxBytes {
+		return false
+	}
+	r.Body = &limitedBody{ReadCloser: r.Body, remaining: maxBytes}
+	return true
+}
+
+func RespondBodyTooLarge(w http.ResponseWriter, r *http.Request, maxBytes int64) {
+	RespondErrorDetails(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds maximum size of %d bytes", maxBytes), map[string]int64{"max_body_size": maxBytes})
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	// sticky, as bodies are commonly read multiple times, e.g. by the relay middleware first
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		return n, err
+	}
+	n, b.remaining, b.exceeded = int(b.remaining), 0, true
+	return n, ErrBodyTooLarge
+}
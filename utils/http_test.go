@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/muety/wakapi/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttp_RespondError(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/summary", nil)
+	r.Header.Set(config.HeaderRequestId, "abc-123")
+	w := httptest.NewRecorder()
+
+	RespondErrorDetails(w, r, http.StatusNotFound, "user not found", map[string]string{"user": "johndoe"})
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code":"not_found","message":"user not found","details":{"user":"johndoe"},"request_id":"abc-123"}`, w.Body.String())
+}
+
+func TestHttp_RespondError_Wakatime(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/compat/wakatime/v1/users/current/stats", nil)
+	w := httptest.NewRecorder()
+
+	RespondError(w, r, http.StatusUnauthorized, config.ErrUnauthorized)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"401 unauthorized"}`, w.Body.String())
+}
+
+func TestHttp_SelectFields(t *testing.T) {
+	type test struct {
+		url      string
+		object   interface{}
+		expected string
+	}
+
+	summary := map[string]interface{}{"from": "2022-10-24", "languages": []string{"Go"}, "projects": []string{"wakapi"}}
+	envelope := map[string]interface{}{
+		"start": "2022-10-24",
+		"data":  []interface{}{summary, summary},
+	}
+
+	tests := []test{
+		{url: "/api/summary", object: summary, expected: `{"from":"2022-10-24","languages":["Go"],"projects":["wakapi"]}`},
+		{url: "/api/summary?fields=languages,%20unknown", object: summary, expected: `{"languages":["Go"]}`},
+		{url: "/api/summaries?fields=languages,projects", object: envelope, expected: `{"start":"2022-10-24","data":[{"languages":["Go"],"projects":["wakapi"]},{"languages":["Go"],"projects":["wakapi"]}]}`},
+		{url: "/api/stats?fields=projects", object: map[string]interface{}{"data": summary}, expected: `{"data":{"projects":["wakapi"]}}`},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		w := httptest.NewRecorder()
+		RespondJSON(w, r, http.StatusOK, SelectFields(r, tt.object))
+		assert.JSONEq(t, tt.expected, w.Body.String(), tt.url)
+	}
+}
+
+func TestHttp_LimitBody(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/heartbeats", strings.NewReader("[1,2,3]"))
+	assert.False(t, LimitBody(r, 4))
+
+	r.ContentLength = -1
+	assert.True(t, LimitBody(r, 4))
+
+	data, err := ioutil.ReadAll(r.Body)
+	assert.Equal(t, ErrBodyTooLarge, err)
+	assert.Equal(t, "[1,2", string(data))
+
+	// bodies are commonly read more than once, which must not succeed either
+	_, err = ioutil.ReadAll(r.Body)
+	assert.Equal(t, ErrBodyTooLarge, err)
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+)
+
+// CropSquare returns the largest centered square section of the given image
+func CropSquare(img image.Image) image.Image {
+	b := img.Bounds()
+	size := b.Dx()
+	if b.Dy() < size {
+		size = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-size)/2
+	y0 := b.Min.Y + (b.Dy()-size)/2
+
+	cropped := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			cropped.Set(x, y, img.At(x0+x, y0+y))
+		}
+	}
+	return cropped
+}
+
+// Resize scales the given image to the given dimensions by averaging over the source pixels covered by each target pixel (box filter).
+// Meant for downscaling, upscaling results in nearest neighbour interpolation.
+func Resize(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	scaleX, scaleY := float64(b.Dx())/float64(width), float64(b.Dy())/float64(height)
+
+	for y := 0; y < height; y++ {
+		sy0 := b.Min.Y + int(float64(y)*scaleY)
+		sy1 := b.Min.Y + int(float64(y+1)*scaleY)
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			sx0 := b.Min.X + int(float64(x)*scaleX)
+			sx1 := b.Min.X + int(float64(x+1)*scaleX)
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCropSquare(t *testing.T) {
+	tests := []struct {
+		in     image.Rectangle
+		size   int
+		marked image.Point // pixel of the source image, which should end up at (0, 0)
+	}{
+		{image.Rect(0, 0, 30, 10), 10, image.Pt(10, 0)},
+		{image.Rect(0, 0, 10, 30), 10, image.Pt(0, 10)},
+		{image.Rect(0, 0, 20, 20), 20, image.Pt(0, 0)},
+		{image.Rect(5, 5, 35, 15), 10, image.Pt(15, 5)},
+	}
+
+	for _, test := range tests {
+		img := image.NewRGBA(test.in)
+		img.Set(test.marked.X, test.marked.Y, color.RGBA{R: 255, A: 255})
+
+		result := CropSquare(img)
+		assert.Equal(t, image.Rect(0, 0, test.size, test.size), result.Bounds())
+		assert.Equal(t, color.RGBA{R: 255, A: 255}, color.RGBAModel.Convert(result.At(0, 0)))
+	}
+}
+
+func TestResize(t *testing.T) {
+	// left half black, right half white
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			if x < 20 {
+				img.Set(x, y, color.Black)
+			} else {
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+
+	result := Resize(img, 4, 4)
+	assert.Equal(t, image.Rect(0, 0, 4, 4), result.Bounds())
+	assert.Equal(t, color.RGBA{A: 255}, color.RGBAModel.Convert(result.At(0, 0)))
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, color.RGBAModel.Convert(result.At(3, 3)))
+
+	// target pixels covering both halves are averaged (up to the 8 bit precision of the result)
+	result = Resize(img, 1, 1)
+	r, g, b, a := result.At(0, 0).RGBA()
+	assert.InDelta(t, 0xffff/2, r, 0x101)
+	assert.InDelta(t, 0xffff/2, g, 0x101)
+	assert.InDelta(t, 0xffff/2, b, 0x101)
+	assert.Equal(t, uint32(0xffff), a)
+}
+
+func TestResize_Upscale(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(1, 1, color.White)
+
+	result := Resize(img, 8, 8)
+	assert.Equal(t, image.Rect(0, 0, 8, 8), result.Bounds())
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, color.RGBAModel.Convert(result.At(7, 7)))
+	assert.Equal(t, color.RGBA{}, color.RGBAModel.Convert(result.At(0, 0)))
+}
@@ -0,0 +1,313 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Minimal MessagePack (https://github.com/msgpack/msgpack/blob/master/spec.md) support.
+// Values are transcoded from and to their json representation, so that json struct tags and custom (un-)marshalers apply just the same.
+
+const MsgpackContentType = "application/msgpack"
+
+// same as encoding/json
+const msgpackMaxDepth = 10000
+
+var (
+	ErrMsgpackUnsupported = errors.New("unsupported messagepack type")
+	ErrMsgpackTooDeep     = errors.New("exceeded max depth of messagepack value")
+)
+
+// IsMsgpack tells whether the request body is encoded as messagepack
+func IsMsgpack(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == MsgpackContentType || mediaType == "application/x-msgpack"
+}
+
+// AcceptsMsgpack tells whether the client prefers messagepack-encoded responses
+func AcceptsMsgpack(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(part))
+		if mediaType == MsgpackContentType || mediaType == "application/x-msgpack" {
+			return true
+		}
+	}
+	return false
+}
+
+func MsgpackMarshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return JsonToMsgpack(data)
+}
+
+func MsgpackUnmarshal(data []byte, v interface{}) error {
+	data, err := MsgpackToJson(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func JsonToMsgpack(data []byte) ([]byte, error) {
+	var value interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := encodeMsgpack(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func MsgpackToJson(data []byte) ([]byte, error) {
+	reader := bytes.NewReader(data)
+	value, err := decodeMsgpack(reader, 0)
+	if err != nil {
+		return nil, err
+	}
+	if reader.Len() > 0 {
+		return nil, errors.New("trailing data after messagepack value")
+	}
+	return json.Marshal(value)
+}
+
+func encodeMsgpack(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			encodeMsgpackInt(buf, i)
+		} else if f, err := v.Float64(); err == nil {
+			buf.WriteByte(0xcb)
+			binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		} else {
+			return err
+		}
+	case string:
+		writeMsgpackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []interface{}:
+		writeMsgpackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := encodeMsgpack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		writeMsgpackHeader(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := encodeMsgpack(buf, k); err != nil {
+				return err
+			}
+			if err := encodeMsgpack(buf, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return ErrMsgpackUnsupported
+	}
+	return nil
+}
+
+func encodeMsgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 127, i < 0 && i >= -32:
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+// writeMsgpackHeader writes the type and length prefix of a string, array or map, using the fix type for lengths below fixLimit
+// and the 8-, 16- or 32-bit variant otherwise (code8 is 0 for types without 8-bit variant)
+func writeMsgpackHeader(buf *bytes.Buffer, length int, fixCode byte, fixLimit int, code8, code16, code32 byte) {
+	switch {
+	case length < fixLimit:
+		buf.WriteByte(fixCode | byte(length))
+	case code8 != 0 && length <= math.MaxUint8:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(length))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(length))
+	}
+}
+
+func decodeMsgpack(r *bytes.Reader, depth int) (interface{}, error) {
+	if depth > msgpackMaxDepth {
+		return nil, ErrMsgpackTooDeep
+	}
+
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code >= 0x80 && code <= 0x8f:
+		return decodeMsgpackMap(r, int(code&0x0f), depth)
+	case code >= 0x90 && code <= 0x9f:
+		return decodeMsgpackArray(r, int(code&0x0f), depth)
+	case code >= 0xa0 && code <= 0xbf:
+		return readMsgpackString(r, int(code&0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9: // bin 8, str 8
+		n, err := readMsgpackUint(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, int(n))
+	case 0xc5, 0xda: // bin 16, str 16
+		n, err := readMsgpackUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, int(n))
+	case 0xc6, 0xdb: // bin 32, str 32
+		n, err := readMsgpackUint(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(r, int(n))
+	case 0xca:
+		n, err := readMsgpackUint(r, 4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := readMsgpackUint(r, 8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return readMsgpackUint(r, 1<<(code-0xcc))
+	case 0xd0:
+		n, err := readMsgpackUint(r, 1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := readMsgpackUint(r, 2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := readMsgpackUint(r, 4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := readMsgpackUint(r, 8)
+		return int64(n), err
+	case 0xdc, 0xdd:
+		n, err := readMsgpackUint(r, 2<<(code-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackArray(r, int(n), depth)
+	case 0xde, 0xdf:
+		n, err := readMsgpackUint(r, 2<<(code-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackMap(r, int(n), depth)
+	}
+
+	return nil, ErrMsgpackUnsupported
+}
+
+func decodeMsgpackArray(r *bytes.Reader, length, depth int) ([]interface{}, error) {
+	if length > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	values := make([]interface{}, length)
+	for i := range values {
+		v, err := decodeMsgpack(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func decodeMsgpackMap(r *bytes.Reader, length, depth int) (map[string]interface{}, error) {
+	if length > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	values := make(map[string]interface{}, length)
+	for i := 0; i < length; i++ {
+		k, err := decodeMsgpack(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := decodeMsgpack(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		values[fmt.Sprint(k)] = v
+	}
+	return values, nil
+}
+
+func readMsgpackString(r *bytes.Reader, length int) (string, error) {
+	if length > r.Len() {
+		return "", io.ErrUnexpectedEOF
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func readMsgpackUint(r *bytes.Reader, size int) (uint64, error) {
+	b := make([]byte, 8)
+	if _, err := io.ReadFull(r, b[8-size:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMsgpack_Marshal(t *testing.T) {
+	// example from https://msgpack.org
+	data, err := MsgpackMarshal(map[string]interface{}{"compact": true, "schema": 0})
+	assert.Nil(t, err)
+	assert.Equal(t, append(append([]byte{0x82, 0xa7}, "compact"...), append(append([]byte{0xc3, 0xa6}, "schema"...), 0x00)...), data)
+}
+
+func TestMsgpack_RoundTrip(t *testing.T) {
+	type item struct {
+		Key   string   `json:"key"`
+		Time  float64  `json:"time"`
+		Count int64    `json:"count"`
+		Neg   int      `json:"neg"`
+		Write bool     `json:"is_write"`
+		Tags  []string `json:"tags"`
+		Ptr   *string  `json:"ptr"`
+	}
+
+	in := []item{
+		{Key: "wakapi", Time: 1666170000.123, Count: 1 << 40, Neg: -200, Write: true, Tags: []string{"a", strings.Repeat("b", 300)}},
+		{Key: strings.Repeat("c", 70000), Count: 70000, Neg: -5},
+	}
+
+	data, err := MsgpackMarshal(in)
+	assert.Nil(t, err)
+
+	var out []item
+	assert.Nil(t, MsgpackUnmarshal(data, &out))
+	assert.Equal(t, in, out)
+}
+
+func TestMsgpack_Unmarshal_Invalid(t *testing.T) {
+	var out interface{}
+	assert.Error(t, MsgpackUnmarshal([]byte{0x92, 0x01}, &out))                   // truncated array
+	assert.Error(t, MsgpackUnmarshal([]byte{0x01, 0x02}, &out))                   // trailing data
+	assert.Error(t, MsgpackUnmarshal([]byte{0xdd, 0xff, 0xff, 0xff, 0xff}, &out)) // length exceeding data
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pdfPageWidth  = 595 // a4 in points
+	pdfPageHeight = 842
+	pdfMargin     = 50
+)
+
+// TextPdf renders lines of plain text to a minimal, multi-page pdf document using the standard Helvetica fonts, which every pdf reader has built in.
+// It only supports latin characters, others are replaced by question marks.
+type TextPdf struct {
+	pages [][]*pdfLine
+	y     float64
+}
+
+type pdfLine struct {
+	text string
+	size float64
+	bold bool
+	y    float64
+}
+
+func NewTextPdf() *TextPdf {
+	return &TextPdf{}
+}
+
+// AddLine appends a line of text in the given font size, starting a new page if the current one is full
+func (p *TextPdf) AddLine(text string, size float64, bold bool) {
+	lineHeight := size * 1.5
+	if len(p.pages) == 0 || p.y-lineHeight < pdfMargin {
+		p.pages = append(p.pages, []*pdfLine{})
+		p.y = pdfPageHeight - pdfMargin
+	}
+	p.y -= lineHeight
+	p.pages[len(p.pages)-1] = append(p.pages[len(p.pages)-1], &pdfLine{text: text, size: size, bold: bold, y: p.y})
+}
+
+// AddSpace appends vertical space of the given height in points
+func (p *TextPdf) AddSpace(height float64) {
+	p.y -= height
+}
+
+func (p *TextPdf) Bytes() []byte {
+	if len(p.pages) == 0 {
+		p.pages = append(p.pages, []*pdfLine{})
+	}
+
+	var buf bytes.Buffer
+	offsets := make([]int, 0)
+	writeObject := func(content string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), content)
+	}
+
+	// objects 1 - 4 are catalog, page tree and fonts, followed by a page and a content stream object per page
+	kids := make([]string, len(p.pages))
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, lines := range p.pages {
+		var content bytes.Buffer
+		for _, l := range lines {
+			font := "F1"
+			if l.bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&content, "BT /%s %.1f Tf %d %.1f Td (%s) Tj ET\n", font, l.size, pdfMargin, l.y, pdfEscape(l.text))
+		}
+		writeObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 6+2*i))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+// pdfEscape encodes the given text as latin-1 and escapes characters with special meaning in pdf strings
+func pdfEscape(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			sb.WriteByte('\\')
+			sb.WriteByte(byte(r))
+		case r < 32:
+			sb.WriteByte(' ')
+		case r < 256:
+			sb.WriteByte(byte(r))
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestTextPdf_Bytes(t *testing.T) {
+	pdf := NewTextPdf()
+	pdf.AddLine("Projects (all)", 14, true)
+	pdf.AddLine("wakapi: 1 hr 5 mins", 11, false)
+
+	result := string(pdf.Bytes())
+	assert.True(t, strings.HasPrefix(result, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(result, "%%EOF\n"))
+	assert.Contains(t, result, "/Count 1")
+	assert.Contains(t, result, "(Projects \\(all\\)) Tj")
+	assert.Contains(t, result, "(wakapi: 1 hr 5 mins) Tj")
+}
+
+func TestTextPdf_Bytes_Paging(t *testing.T) {
+	pdf := NewTextPdf()
+	for i := 0; i < 100; i++ {
+		pdf.AddLine("line", 12, false)
+	}
+	assert.Contains(t, string(pdf.Bytes()), "/Count 3")
+}
+
+func TestPdfEscape(t *testing.T) {
+	assert.Equal(t, "a\\\\b\\(c\\) d", pdfEscape("a\\b(c)\nd"))
+	assert.Equal(t, "K\xf6ln ?", pdfEscape("Köln 日"))
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"errors"
+	"github.com/muety/wakapi/config"
 	"github.com/muety/wakapi/models"
 	"net/http"
 	"time"
@@ -30,32 +31,52 @@ func ResolveIntervalRawTZ(interval string, tz *time.Location) (err error, from,
 }
 
 func ResolveIntervalTZ(interval *models.IntervalKey, tz *time.Location) (err error, from, to time.Time) {
-	now := time.Now().In(tz)
+	fiscalYearStart := time.January
+	if cfg := config.Get(); cfg != nil {
+		fiscalYearStart = cfg.App.GetFiscalYearStart()
+	}
+	return resolveIntervalAt(interval, time.Now().In(tz), fiscalYearStart)
+}
+
+// resolveIntervalAt resolves the interval relative to the given point in time and in its time zone
+func resolveIntervalAt(interval *models.IntervalKey, now time.Time, fiscalYearStart time.Month) (err error, from, to time.Time) {
 	to = now
+	today := StartOfDay(now)
+	thisWeek, thisMonth, thisQuarter := StartOfWeek(now), StartOfMonth(now), StartOfQuarter(now)
 
 	switch interval {
 	case models.IntervalToday:
-		from = StartOfToday(tz)
+		from = today
 	case models.IntervalYesterday:
-		from = StartOfToday(tz).Add(-24 * time.Hour)
-		to = StartOfToday(tz)
+		from = FloorDate(today.AddDate(0, 0, -1))
+		to = today
 	case models.IntervalThisWeek:
-		from = StartOfThisWeek(tz)
+		from = thisWeek
 	case models.IntervalLastWeek:
-		from = StartOfThisWeek(tz).AddDate(0, 0, -7)
-		to = StartOfThisWeek(tz)
+		from = FloorDate(thisWeek.AddDate(0, 0, -7))
+		to = thisWeek
 	case models.IntervalThisMonth:
-		from = StartOfThisMonth(tz)
+		from = thisMonth
 	case models.IntervalLastMonth:
-		from = StartOfThisMonth(tz).AddDate(0, -1, 0)
-		to = StartOfThisMonth(tz)
+		from = FloorDate(thisMonth.AddDate(0, -1, 0))
+		to = thisMonth
+	case models.IntervalThisQuarter:
+		from = thisQuarter
+	case models.IntervalLastQuarter:
+		from = FloorDate(thisQuarter.AddDate(0, -3, 0))
+		to = thisQuarter
 	case models.IntervalThisYear:
-		from = StartOfThisYear(tz)
+		from = StartOfYear(now)
+	case models.IntervalThisFiscalYear:
+		from = StartOfFiscalYear(now, fiscalYearStart)
+	case models.IntervalLastFiscalYear:
+		from = FloorDate(StartOfFiscalYear(now, fiscalYearStart).AddDate(-1, 0, 0))
+		to = StartOfFiscalYear(now, fiscalYearStart)
 	case models.IntervalPast7Days:
 		from = now.AddDate(0, 0, -7)
 	case models.IntervalPast7DaysYesterday:
-		from = StartOfToday(tz).AddDate(0, 0, -1).AddDate(0, 0, -7)
-		to = StartOfToday(tz).AddDate(0, 0, -1)
+		from = FloorDate(today.AddDate(0, 0, -8))
+		to = FloorDate(today.AddDate(0, 0, -1))
 	case models.IntervalPast14Days:
 		from = now.AddDate(0, 0, -14)
 	case models.IntervalPast30Days:
@@ -130,6 +151,9 @@ func ParseSummaryFilters(r *http.Request) *models.Filters {
 	if q := r.URL.Query().Get("branch"); q != "" {
 		filters.With(models.SummaryBranch, q)
 	}
+	if q := r.URL.Query().Get("origin"); q != "" {
+		filters.With(models.SummaryOrigin, q)
+	}
 	return filters
 }
 
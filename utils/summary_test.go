@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/muety/wakapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummary_ResolveIntervalAt(t *testing.T) {
+	type test struct {
+		interval *models.IntervalKey
+		now      time.Time
+		from     time.Time
+		to       time.Time
+	}
+
+	tzIst, _ := time.LoadLocation("Asia/Kolkata")
+
+	tests := []test{
+		// day after clocks were turned forward
+		{models.IntervalYesterday, time.Date(2023, 3, 27, 10, 0, 0, 0, tzCet), time.Date(2023, 3, 26, 0, 0, 0, 0, tzCet), time.Date(2023, 3, 27, 0, 0, 0, 0, tzCet)},
+		// day after clocks were turned back
+		{models.IntervalYesterday, time.Date(2023, 10, 30, 0, 30, 0, 0, tzCet), time.Date(2023, 10, 29, 0, 0, 0, 0, tzCet), time.Date(2023, 10, 30, 0, 0, 0, 0, tzCet)},
+		{models.IntervalToday, time.Date(2023, 5, 10, 8, 30, 0, 0, tzIst), time.Date(2023, 5, 10, 0, 0, 0, 0, tzIst), time.Date(2023, 5, 10, 8, 30, 0, 0, tzIst)},
+		{models.IntervalLastWeek, time.Date(2023, 4, 1, 10, 0, 0, 0, tzCet), time.Date(2023, 3, 20, 0, 0, 0, 0, tzCet), time.Date(2023, 3, 27, 0, 0, 0, 0, tzCet)},
+		{models.IntervalLastMonth, time.Date(2023, 4, 1, 10, 0, 0, 0, tzPst), time.Date(2023, 3, 1, 0, 0, 0, 0, tzPst), time.Date(2023, 4, 1, 0, 0, 0, 0, tzPst)},
+		{models.IntervalThisQuarter, time.Date(2023, 5, 10, 10, 0, 0, 0, tzCet), time.Date(2023, 4, 1, 0, 0, 0, 0, tzCet), time.Date(2023, 5, 10, 10, 0, 0, 0, tzCet)},
+		{models.IntervalLastQuarter, time.Date(2023, 5, 10, 10, 0, 0, 0, tzCet), time.Date(2023, 1, 1, 0, 0, 0, 0, tzCet), time.Date(2023, 4, 1, 0, 0, 0, 0, tzCet)},
+		{models.IntervalLastQuarter, time.Date(2023, 1, 15, 10, 0, 0, 0, tzCet), time.Date(2022, 10, 1, 0, 0, 0, 0, tzCet), time.Date(2023, 1, 1, 0, 0, 0, 0, tzCet)},
+		{models.IntervalPast7DaysYesterday, time.Date(2023, 3, 28, 10, 0, 0, 0, tzCet), time.Date(2023, 3, 20, 0, 0, 0, 0, tzCet), time.Date(2023, 3, 27, 0, 0, 0, 0, tzCet)},
+	}
+
+	for _, tt := range tests {
+		err, from, to := resolveIntervalAt(tt.interval, tt.now, time.January)
+		assert.Nil(t, err)
+		assert.True(t, tt.from.Equal(from), "%v: expected from %v, got %v", *tt.interval, tt.from, from)
+		assert.True(t, tt.to.Equal(to), "%v: expected to %v, got %v", *tt.interval, tt.to, to)
+	}
+}
+
+func TestSummary_ResolveIntervalAt_FiscalYear(t *testing.T) {
+	now := time.Date(2023, 2, 10, 10, 0, 0, 0, tzCet)
+
+	// fiscal year starting in april
+	err, from, to := resolveIntervalAt(models.IntervalThisFiscalYear, now, time.April)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, 4, 1, 0, 0, 0, 0, tzCet), from)
+	assert.Equal(t, now, to)
+
+	err, from, to = resolveIntervalAt(models.IntervalLastFiscalYear, now, time.April)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2021, 4, 1, 0, 0, 0, 0, tzCet), from)
+	assert.Equal(t, time.Date(2022, 4, 1, 0, 0, 0, 0, tzCet), to)
+
+	// calendar year by default
+	err, from, _ = resolveIntervalAt(models.IntervalThisFiscalYear, now, time.January)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, tzCet), from)
+
+	err, from, _ = resolveIntervalAt(models.IntervalThisYear, now, time.April)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, tzCet), from)
+}
+
+func TestSummary_ParseInterval(t *testing.T) {
+	for _, alias := range []string{"last_week", "quarter", "this_quarter", "last_quarter", "year_to_date", "fiscal_year", "last_fiscal_year"} {
+		interval, err := ParseInterval(alias)
+		assert.Nil(t, err)
+		assert.True(t, interval.HasAlias(alias))
+	}
+	_, err := ParseInterval("next_quarter")
+	assert.NotNil(t, err)
+}